@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -22,7 +29,7 @@ import (
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "emcee [spec-path-or-url]",
+	Use:   "emcee [spec-path-or-url...]",
 	Short: "Creates an MCP server for an OpenAPI specification",
 	Long: `emcee is a CLI tool that provides an Model Context Protocol (MCP) stdio transport for a given OpenAPI specification.
 It takes an OpenAPI specification path or URL as input and processes JSON-RPC requests from stdin, making corresponding API calls and returning JSON-RPC responses to stdout.
@@ -30,46 +37,97 @@ It takes an OpenAPI specification path or URL as input and processes JSON-RPC re
 The spec-path-or-url argument can be:
 - A local file path (e.g. ./openapi.json)
 - An HTTP(S) URL (e.g. https://api.example.com/openapi.json)
+- A file in a Git repository at a branch, tag, or commit,
+  fetched using git (e.g. git+https://github.com/org/repo.git#v1.2.0:openapi.yaml)
 - "-" to read from stdin
 
+Any of these can instead be an HTTP Archive (HAR) file saved from a browser's developer tools,
+whose API calls are turned into a spec, for APIs that don't have one,
+or an AsyncAPI document for an event-driven API whose messages are sent over HTTP.
+
+Several specs can be given to serve the tools of each from one server.
+They're loaded concurrently, and a spec that can't be loaded is skipped with a warning.
+
 By default, a GET request with no additional headers is made to the spec URL to download the OpenAPI specification.
 
 If additional authentication is required to download the specification, you can first download it to a local file using your preferred HTTP client with the necessary authentication headers, and then provide the local file path to emcee.
 
-Authentication values can be provided directly or as 1Password secret references (e.g. op://vault/item/field). When using 1Password references:
-- The 1Password CLI (op) must be installed and available in your PATH
-- You must be signed in to 1Password
-- The reference must be in the format op://vault/item/field
-- The secret will be securely retrieved at runtime using the 1Password CLI
+Authentication values can be provided directly or as secret references:
+- env://VAR_NAME reads the secret from an environment variable
+- file:///path/to/secret reads the secret from a file, ignoring surrounding whitespace
+- op://vault/item/field reads the secret using the 1Password CLI (op),
+  which must be installed, available in your PATH, and signed in
+- vault://mount/path#field reads the secret from HashiCorp Vault,
+  using the server and token in VAULT_ADDR and VAULT_TOKEN
+- keyring://service/account reads the secret from the macOS Keychain,
+  Windows Credential Manager, or Secret Service on Linux
 `,
-	Args: cobra.ExactArgs(1),
+	Args: cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Set up context and signal handling
-		ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		// On a signal, the server stops accepting requests, and shuts down once those in flight finish
+		signaled, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
 		defer cancel()
 
 		// Set up error group
-		g, ctx := errgroup.WithContext(ctx)
+		g, ctx := errgroup.WithContext(signaled)
+
+		// Set up standard streams so that log output never interleaves with protocol output
+		stdio := internal.NewStdio()
+		log.SetOutput(stdio.Stderr())
 
 		// Set up logger
-		var logger *slog.Logger
-		switch {
-		case silent:
-			logger = slog.New(slog.NewTextHandler(io.Discard, nil))
-		case verbose:
-			logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-				Level: slog.LevelDebug,
-			}))
-		default:
-			logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-				Level: slog.LevelInfo,
-			}))
+		logger, err := newLogger(stdio.Stderr())
+		if err != nil {
+			return err
+		}
+
+		if len(args) > 1 {
+			switch {
+			case slices.Contains(args, "-"):
+				return fmt.Errorf("a spec read from stdin can't be combined with other specs")
+			case reloadInterval > 0:
+				return fmt.Errorf("--reload-interval can't be used with more than one spec")
+			case canarySpec != "":
+				return fmt.Errorf("--canary-spec can't be used with more than one spec")
+			case configResource:
+				return fmt.Errorf("--config-resource can't be used with more than one spec")
+			}
+		}
+		if configResource {
+			switch {
+			case configPath == "":
+				return fmt.Errorf("--config-resource requires --config")
+			case configToken == "":
+				return fmt.Errorf("--config-resource requires --config-token")
+			case args[0] == "-":
+				return fmt.Errorf("--config-resource can't be used with a spec read from stdin")
+			}
 		}
 
 		g.Go(func() error {
-			// Read OpenAPI specification data
+			// Load configuration file, which also determines how large specs are filtered
+			var config *internal.Config
+			if configPath != "" {
+				var err error
+				if config, err = internal.LoadConfig(configPath); err != nil {
+					return err
+				}
+			}
+
+			// Read OpenAPI specification data from stdin; specs from files and URLs are read when tools are registered
 			var specData []byte
-			if args[0] == "-" {
+			if args[0] == "-" && listen != "" {
+				// Clients connect to the listener, so stdin is free for the spec
+				logger.Info("reading spec from stdin")
+				var err error
+				if specData, err = io.ReadAll(os.Stdin); err != nil {
+					return fmt.Errorf("error reading OpenAPI spec from stdin: %w", err)
+				}
+				if specData, err = prepareSpec(specData); err != nil {
+					return err
+				}
+			} else if args[0] == "-" {
 				logger.Info("reading spec from stdin")
 
 				// When reading the OpenAPI spec from stdin, we need to read RPC input from /dev/tty
@@ -79,170 +137,865 @@ Authentication values can be provided directly or as 1Password secret references
 				if err != nil {
 					return fmt.Errorf("error opening /dev/tty: %w", err)
 				}
-				defer tty.Close()
 
 				// Read spec from original stdin
 				specData, err = io.ReadAll(origStdin)
 				if err != nil {
+					tty.Close()
 					return fmt.Errorf("error reading OpenAPI spec from stdin: %w", err)
 				}
-				// Redirect SDK stdio transport to use /dev/tty for input
-				os.Stdin = tty
-			} else if strings.HasPrefix(args[0], "http://") || strings.HasPrefix(args[0], "https://") {
-				logger.Info("reading spec from URL", "url", args[0])
+				if specData, err = prepareSpec(specData); err != nil {
+					tty.Close()
+					return err
+				}
+				// Read RPC input from /dev/tty
+				stdio.In = tty
+			}
 
-				// Create HTTP request
-				req, err := http.NewRequest(http.MethodGet, args[0], nil)
-				if err != nil {
-					return fmt.Errorf("error creating request: %w", err)
-				}
-
-				// Make HTTP request
-				client := http.DefaultClient
-				if insecure {
-					if base, ok := http.DefaultTransport.(*http.Transport); ok && base != nil {
-						transport := base.Clone()
-						if transport.TLSClientConfig == nil {
-							transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-						} else {
-							transport.TLSClientConfig = transport.TLSClientConfig.Clone()
-							transport.TLSClientConfig.InsecureSkipVerify = true
+			// Build HTTP client with optional auth header
+			var cache *internal.CacheOptions
+			if cacheDir != "" || cacheTTL != 0 {
+				cache = &internal.CacheOptions{Dir: cacheDir, TTL: cacheTTL}
+			}
+			// Retry flags override the configuration file's retry policy
+			var retryPolicy internal.RetryPolicy
+			var rateLimits *internal.RateLimits
+			if config != nil {
+				if config.Retry != nil {
+					retryPolicy = *config.Retry
+				}
+				rateLimits = config.RateLimits
+			}
+			if cmd.Flags().Changed("retry-status") {
+				retryPolicy.StatusCodes = retryStatuses
+			}
+			if cmd.Flags().Changed("retry-unsafe-methods") {
+				retryPolicy.UnsafeMethods = retryUnsafeMethods
+			}
+			client, err := internal.RetryableClient(internal.RetryableClientOptions{
+				Retries:     retries,
+				Timeout:     timeout,
+				RPS:         rps,
+				Logger:      logger,
+				Insecure:    insecure,
+				Proxy:       proxy,
+				Cookies:     cookieJar,
+				Cache:       cache,
+				RetryPolicy: retryPolicy,
+				RateLimits:  rateLimits,
+			})
+			if err != nil {
+				return fmt.Errorf("error creating client: %w", err)
+			}
+			var auth internal.AuthProvider
+			switch {
+			case bearerAuth != "":
+				auth, err = authProvider(ctx, "bearer", bearerAuth, func(token string) string { return "Bearer " + token }, logger)
+			case basicAuth != "":
+				auth, err = authProvider(ctx, "basic", basicAuth, func(credentials string) string {
+					if strings.Contains(credentials, ":") {
+						credentials = base64.StdEncoding.EncodeToString([]byte(credentials))
+					}
+					return "Basic " + credentials
+				}, logger)
+			case rawAuth != "":
+				auth, err = authProvider(ctx, "raw", rawAuth, nil, logger)
+			case negotiate:
+				// Credentials are read up front, so that a missing ticket is reported at startup
+				negotiateAuth := &internal.NegotiateAuth{}
+				if err = negotiateAuth.Refresh(ctx); err == nil {
+					auth = negotiateAuth
+				}
+			}
+			if err != nil {
+				return err
+			}
+			// Access tokens are obtained from a refresh token, whose rotations are saved to the state file
+			var oauth2 *internal.OAuth2Auth
+			if oauth2RefreshToken != "" || oauth2StatePath != "" {
+				for _, secret := range []*string{&oauth2RefreshToken, &oauth2ClientSecret} {
+					if internal.IsSecretReference(*secret) {
+						if *secret, _, err = internal.ResolveSecretReference(ctx, *secret); err != nil {
+							return fmt.Errorf("error resolving OAuth 2.0 credentials: %w", err)
 						}
-						client = &http.Client{Transport: transport}
-					} else {
-						client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
 					}
 				}
-				resp, err := client.Do(req)
-				if err != nil {
-					return fmt.Errorf("error downloading spec: %w", err)
+				oauth2 = &internal.OAuth2Auth{
+					TokenURL:     oauth2TokenURL,
+					ClientID:     oauth2ClientID,
+					ClientSecret: oauth2ClientSecret,
+					RefreshToken: oauth2RefreshToken,
+					Scopes:       oauth2Scopes,
+					StatePath:    oauth2StatePath,
+					Client:       client,
 				}
-				if resp.Body == nil {
-					return fmt.Errorf("no response body from %s", args[0])
+			} else if oauth2TokenURL != "" || oauth2ClientID != "" || oauth2ClientSecret != "" || len(oauth2Scopes) > 0 {
+				return fmt.Errorf("OAuth 2.0 settings require --oauth2-refresh-token or --oauth2-state")
+			}
+			if internal.IsSecretReference(apiKey) {
+				if apiKey, _, err = internal.ResolveSecretReference(ctx, apiKey); err != nil {
+					return fmt.Errorf("error resolving API key: %w", err)
 				}
-				defer resp.Body.Close()
-
-				// Read spec from response body
-				specData, err = io.ReadAll(resp.Body)
-				if err != nil {
-					return fmt.Errorf("error reading spec from %s: %w", args[0], err)
+				logger.Debug("resolved API key from secret reference")
+			}
+			if internal.IsSecretReference(configToken) {
+				if configToken, _, err = internal.ResolveSecretReference(ctx, configToken); err != nil {
+					return fmt.Errorf("error resolving config token: %w", err)
 				}
-			} else {
-				logger.Info("reading spec from file", "file", args[0])
-
-				// Clean the file path to remove any . or .. segments and ensure consistent separators
-				cleanPath := filepath.Clean(args[0])
+			}
 
-				// Check if file exists and is readable before attempting to read
-				info, err := os.Stat(cleanPath)
+			// Create SDK server and register tools from OpenAPI
+			impl := &mcp.Implementation{Name: cmd.Name(), Version: version}
+			var serverOpts mcp.ServerOptions
+			var opts []internal.RegisterToolsOption
+			// Argument values are completed from enums, examples, and lookups
+			catalog := internal.NewEnumCatalog(maxEnumValues)
+			serverOpts.CompletionHandler = catalog.Complete
+			opts = append(opts, internal.WithEnumCatalog(catalog))
+			// Webhooks and callbacks are received over HTTP, and their events are resources clients can subscribe to
+			var receiver *internal.WebhookReceiver
+			if webhookListen != "" {
+				receiver, err = internal.NewWebhookReceiver(webhookListen, webhookURL, logger)
 				if err != nil {
-					if os.IsNotExist(err) {
-						return fmt.Errorf("spec file does not exist: %s", cleanPath)
-					}
-					return fmt.Errorf("error accessing spec file %s: %w", cleanPath, err)
+					return err
 				}
-
-				// Ensure it's a regular file, not a directory
-				if info.IsDir() {
-					return fmt.Errorf("specified path is a directory, not a file: %s", cleanPath)
+				defer receiver.Close()
+				opts = append(opts, internal.WithWebhooks(receiver))
+				webhooksCtx, stopWebhooks := context.WithCancel(ctx)
+				defer stopWebhooks()
+				g.Go(func() error { return receiver.Serve(webhooksCtx) })
+				logger.Info("receiving webhooks", "address", webhookListen)
+			} else if webhookURL != "" {
+				return fmt.Errorf("--webhook-url requires --webhook-listen")
+			}
+			// Resources read from GET operations are polled for changes while clients are subscribed to them
+			if pollInterval > 0 && !resourceTemplates {
+				return fmt.Errorf("--poll-interval requires --resource-templates")
+			}
+			if receiver != nil || pollInterval > 0 {
+				subscriptions := internal.NewSubscriptions(receiver, pollInterval, logger)
+				defer subscriptions.Close()
+				serverOpts.SubscribeHandler = subscriptions.Subscribe
+				serverOpts.UnsubscribeHandler = subscriptions.Unsubscribe
+				opts = append(opts, internal.WithSubscriptions(subscriptions))
+			}
+			server := mcp.NewServer(impl, &serverOpts)
+			if config != nil {
+				opts = append(opts, internal.WithConfig(config))
+			}
+			if noAnnotations {
+				opts = append(opts, internal.WithoutAnnotations())
+			}
+			if noOutputSchema {
+				opts = append(opts, internal.WithoutOutputSchemas())
+			}
+			if schemaResources {
+				opts = append(opts, internal.WithSchemaResources())
+			}
+			if resourceTemplates {
+				opts = append(opts, internal.WithResourceTemplates())
+			}
+			if prompts {
+				opts = append(opts, internal.WithPrompts())
+			}
+			if toolPrefix != "" {
+				opts = append(opts, internal.WithToolPrefix(toolPrefix))
+			}
+			if toolNameFormat != "" {
+				format, err := internal.ParseToolNameFormat(toolNameFormat)
+				if err != nil {
+					return err
 				}
-
-				// Check file size to prevent loading extremely large files
-				if info.Size() > 100*1024*1024 { // 100MB limit
-					return fmt.Errorf("spec file too large (max 100MB): %s", cleanPath)
+				opts = append(opts, internal.WithToolNameFormat(format))
+			}
+			if richDescriptions {
+				opts = append(opts, internal.WithRichDescriptions())
+			}
+			if includeDeprecated {
+				opts = append(opts, internal.WithDeprecatedOperations())
+			}
+			if includeInternal {
+				opts = append(opts, internal.WithInternalOperations())
+			}
+			if allowRemoteRefs {
+				opts = append(opts, internal.WithRemoteRefs())
+			}
+			if xmlToJSON {
+				opts = append(opts, internal.WithXMLToJSON())
+			}
+			if requestIDHeader != "" {
+				opts = append(opts, internal.WithRequestIDHeader(requestIDHeader))
+			}
+			if downloadThreshold > 0 {
+				opts = append(opts, internal.WithDownloadLinks(downloadThreshold))
+			}
+			if len(serverVars) > 0 {
+				vars, err := serverVariables()
+				if err != nil {
+					return err
 				}
-
-				// Read spec from file
-				specData, err = os.ReadFile(cleanPath)
+				opts = append(opts, internal.WithServerVariables(vars))
+			}
+			if queryObjectStyle != "" {
+				style, err := internal.ParseQueryObjectStyle(queryObjectStyle)
 				if err != nil {
-					return fmt.Errorf("error reading spec file %s: %w", cleanPath, err)
+					return err
 				}
+				opts = append(opts, internal.WithQueryObjectStyle(style))
 			}
-
-			// Build HTTP client with optional auth header
-			client, err := internal.RetryableClient(internal.RetryableClientOptions{
-				Retries:  retries,
-				Timeout:  timeout,
-				RPS:      rps,
-				Logger:   logger,
-				Insecure: insecure,
-			})
-			if err != nil {
-				return fmt.Errorf("error creating client: %w", err)
+			if coerceArguments {
+				opts = append(opts, internal.WithArgumentCoercion())
+			}
+			if trailingSlashes {
+				opts = append(opts, internal.WithTrailingSlashes())
+			}
+			if rawPaths {
+				opts = append(opts, internal.WithRawPaths())
+			}
+			if dryRun {
+				opts = append(opts, internal.WithDryRun())
+			}
+			if dryRunArgument {
+				opts = append(opts, internal.WithDryRunArgument())
 			}
-			if bearerAuth != "" {
-				resolvedAuth, wasSecret, err := internal.ResolveSecretReference(ctx, bearerAuth)
+			if maxTimeout > 0 {
+				opts = append(opts, internal.WithTimeoutArgument(maxTimeout))
+			}
+			if confirmDestructive {
+				opts = append(opts, internal.WithConfirmation())
+			}
+			// Clients that support elicitation are asked for the required arguments their calls are missing
+			var elicitor *internal.Elicitor
+			if elicitArguments {
+				elicitor = internal.NewElicitor()
+				opts = append(opts, internal.WithElicitation(elicitor))
+			}
+			if auditLogPath != "" {
+				auditLog, err := internal.OpenAuditLog(auditLogPath)
 				if err != nil {
-					return fmt.Errorf("error resolving bearer auth: %w", err)
+					return err
 				}
-				if wasSecret {
-					logger.Debug("resolved bearer auth from 1Password")
+				defer auditLog.Close()
+				opts = append(opts, internal.WithAuditLog(auditLog))
+			}
+			if canarySpec != "" {
+				canaryData, err := readSpec(ctx, canarySpec, config, logger)
+				if err != nil {
+					return fmt.Errorf("error reading canary spec: %w", err)
 				}
-				headers := http.Header{}
-				headers.Add("Authorization", "Bearer "+resolvedAuth)
-				client.Transport = &internal.HeaderTransport{Base: client.Transport, Headers: headers}
-			} else if basicAuth != "" {
-				resolvedAuth, wasSecret, err := internal.ResolveSecretReference(ctx, basicAuth)
+				opts = append(opts, internal.WithCanary(internal.CanaryOptions{
+					Spec:    canaryData,
+					Percent: canaryPercent,
+					Tools:   canaryTools,
+				}))
+			}
+			if notFoundTTL > 0 {
+				opts = append(opts, internal.WithNotFoundCache(internal.NotFoundCacheOptions{
+					TTL:   notFoundTTL,
+					Tools: notFoundTools,
+				}))
+			}
+			if maxResponseBytes > 0 {
+				strategy, err := internal.ParseResponseLimitStrategy(responseLimitStrategy)
 				if err != nil {
-					return fmt.Errorf("error resolving basic auth: %w", err)
+					return err
 				}
-				if wasSecret {
-					logger.Debug("resolved basic auth from 1Password")
+				opts = append(opts, internal.WithResponseLimit(internal.ResponseLimitOptions{
+					MaxBytes: maxResponseBytes,
+					Strategy: strategy,
+					Fields:   responseFields,
+				}))
+			}
+			if len(secretArgs) > 0 {
+				opts = append(opts, internal.WithSecretArguments(secretArgs...))
+			}
+			for _, header := range headers {
+				name, value, ok := strings.Cut(header, ":")
+				name = strings.TrimSpace(name)
+				if !ok || name == "" || strings.ContainsAny(name, " \t") {
+					return fmt.Errorf("invalid header %q (expected 'Name: Value')", header)
 				}
-				var value string
-				if strings.Contains(resolvedAuth, ":") {
-					value = base64.StdEncoding.EncodeToString([]byte(resolvedAuth))
-				} else {
-					value = resolvedAuth
+				opts = append(opts, internal.WithHeader(name, strings.TrimSpace(value)))
+			}
+			if auth != nil {
+				opts = append(opts, internal.WithAuthProvider(auth))
+			}
+			if apiKey != "" {
+				opts = append(opts, internal.WithAPIKey(apiKey))
+			}
+			if oauth2 != nil {
+				opts = append(opts, internal.WithOAuth2(oauth2))
+			}
+			// Tokens saved by emcee login are used for APIs without other credentials,
+			// so the keyring is only read once a login has been saved
+			if internal.HasOAuth2Logins() {
+				opts = append(opts, internal.WithOAuth2Login())
+			}
+			// The APIs behind the tools are probed by health/check requests, and at startup with --healthcheck
+			health := internal.NewHealthChecker(healthPath)
+			opts = append(opts, internal.WithHealthChecker(health))
+			opts = append(opts, internal.WithLogger(logger))
+			var configEditor *internal.ConfigEditor
+			switch {
+			case args[0] == "-":
+				if reloadInterval > 0 {
+					return fmt.Errorf("--reload-interval can't be used with a spec read from stdin")
 				}
-				headers := http.Header{}
-				headers.Add("Authorization", "Basic "+value)
-				client.Transport = &internal.HeaderTransport{Base: client.Transport, Headers: headers}
-			} else if rawAuth != "" {
-				resolvedAuth, wasSecret, err := internal.ResolveSecretReference(ctx, rawAuth)
+				if err := internal.RegisterTools(server, specData, client, opts...); err != nil {
+					return fmt.Errorf("error registering tools: %w", err)
+				}
+			case reloadInterval > 0 || configResource:
+				specData, err := readSpec(ctx, args[0], config, logger)
 				if err != nil {
-					return fmt.Errorf("error resolving raw auth: %w", err)
+					return err
+				}
+				reloader := internal.NewReloader(server, client, append(opts, internal.WithSpecLocation(args[0]))...)
+				if err := reloader.Load(specData); err != nil {
+					return fmt.Errorf("error registering tools: %w", err)
+				}
+				if reloadInterval > 0 {
+					g.Go(func() error {
+						reloader.Watch(ctx, reloadInterval, func(ctx context.Context) ([]byte, error) {
+							return readSpec(ctx, args[0], config, slog.New(slog.DiscardHandler))
+						})
+						return nil
+					})
+				}
+				// The tool filters can be read, and replaced by an operator, while the server runs
+				if configResource {
+					configEditor = internal.NewConfigEditor(reloader, configPath, config, configToken)
+					configEditor.AddResource(server)
+				}
+			default:
+				read := func(ctx context.Context, source string) ([]byte, error) {
+					return readSpec(ctx, source, config, logger)
 				}
-				if wasSecret {
-					logger.Debug("resolved raw auth from 1Password")
+				if err := internal.LoadSpecs(ctx, server, client, args, read, startupTimeout, opts...); err != nil {
+					return err
 				}
-				headers := http.Header{}
-				headers.Add("Authorization", resolvedAuth)
-				client.Transport = &internal.HeaderTransport{Base: client.Transport, Headers: headers}
 			}
 
-			// Create SDK server and register tools from OpenAPI
-			impl := &mcp.Implementation{Name: cmd.Name(), Version: version}
-			server := mcp.NewServer(impl, nil)
-			var opts []internal.RegisterToolsOption
-			if noAnnotations {
-				opts = append(opts, internal.WithoutAnnotations())
+			if healthCheck {
+				report := health.Check(ctx)
+				if err := report.Err(); err != nil {
+					return err
+				}
+				for _, api := range report.APIs {
+					logger.Info("API is healthy", "url", api.URL, "status", api.Status, "latency_ms", api.LatencyMS)
+				}
 			}
-			if err := internal.RegisterTools(server, specData, client, opts...); err != nil {
-				return fmt.Errorf("error registering tools: %w", err)
+
+			// Leave out features that clients of earlier protocol revisions don't know about
+			internal.NegotiateProtocol(server)
+
+			batchCaller := internal.NewBatchCaller(server)
+			readOnlyTools := internal.NewReadOnlyTools(server)
+			configure := func(s *internal.Stdio) {
+				// Handle experimental extension methods in the transport
+				s.Methods = map[string]internal.MethodHandler{
+					internal.CallBatchMethod: batchCaller.Handle,
+					internal.HealthMethod:    health.Handle,
+				}
+				if configEditor != nil {
+					s.Methods[internal.ConfigUpdateMethod] = configEditor.Handle
+				}
+				// Answer retransmitted calls to read-only tools without calling the API again
+				s.Replayable = readOnlyTools.Replayable
+				s.Elicitor = elicitor
+				// Refuse oversized messages, and hold up requests while a client isn't reading its output
+				s.MaxMessageBytes = maxMessageBytes
+				s.MaxPendingWrites = maxPendingWrites
+			}
+
+			// Serve each client that connects to the socket or pipe as its own session
+			if listen != "" {
+				ln, err := internal.Listen(listen)
+				if err != nil {
+					return err
+				}
+				defer ln.Close()
+				logger.Info("listening for MCP clients", "address", listen)
+				return internal.Serve(ctx, server, ln, configure, shutdownTimeout)
 			}
 
-			// Run over stdio; when spec was from stdin, we redirected os.Stdin to /dev/tty above.
-			return server.Run(ctx, &mcp.StdioTransport{})
+			// Run over stdio; when spec was from stdin, input was redirected to /dev/tty above.
+			configure(stdio)
+			return internal.Run(ctx, server, stdio.Transport(), shutdownTimeout)
 		})
 
-		return g.Wait()
+		// Shutting down on a signal after requests in flight finish is a clean exit
+		err = g.Wait()
+		if signaled.Err() != nil && (err == nil || errors.Is(err, context.Canceled)) {
+			logger.Info("shut down")
+			return nil
+		}
+		return err
+	},
+}
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools spec-path-or-url",
+	Short: "Prints the tools generated for an OpenAPI specification",
+	Long: `Prints the tools emcee generates for an OpenAPI specification, without serving them or calling the API.
+Each tool is listed with the method and path of the operation it calls, its description, and its input schema.
+
+Use it to check which operations a configuration file disables, and how tools are named.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		logger := slog.New(slog.DiscardHandler)
+
+		var config *internal.Config
+		var opts []internal.RegisterToolsOption
+		if configPath != "" {
+			var err error
+			if config, err = internal.LoadConfig(configPath); err != nil {
+				return err
+			}
+			opts = append(opts, internal.WithConfig(config))
+		}
+		if toolPrefix != "" {
+			opts = append(opts, internal.WithToolPrefix(toolPrefix))
+		}
+		if toolNameFormat != "" {
+			format, err := internal.ParseToolNameFormat(toolNameFormat)
+			if err != nil {
+				return err
+			}
+			opts = append(opts, internal.WithToolNameFormat(format))
+		}
+		if richDescriptions {
+			opts = append(opts, internal.WithRichDescriptions())
+		}
+		if includeDeprecated {
+			opts = append(opts, internal.WithDeprecatedOperations())
+		}
+		if includeInternal {
+			opts = append(opts, internal.WithInternalOperations())
+		}
+		if allowRemoteRefs {
+			opts = append(opts, internal.WithRemoteRefs())
+		}
+		if len(serverVars) > 0 {
+			vars, err := serverVariables()
+			if err != nil {
+				return err
+			}
+			opts = append(opts, internal.WithServerVariables(vars))
+		}
+
+		var specData []byte
+		var err error
+		if args[0] == "-" {
+			specData, err = io.ReadAll(cmd.InOrStdin())
+			if err == nil {
+				specData, err = prepareSpec(specData)
+			}
+		} else {
+			specData, err = readSpec(ctx, args[0], config, logger)
+			opts = append(opts, internal.WithSpecLocation(args[0]))
+		}
+		if err != nil {
+			return err
+		}
+		tools, err := internal.ListTools(ctx, specData, opts...)
+		if err != nil {
+			return fmt.Errorf("error generating tools: %w", err)
+		}
+
+		out := cmd.OutOrStdout()
+		switch toolsFormat {
+		case "json":
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(tools)
+		case "table":
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tMETHOD\tPATH\tARGUMENTS\tDESCRIPTION")
+			for _, tool := range tools {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", tool.Name, tool.Method, tool.Path, toolArguments(tool), firstLine(tool.Description))
+			}
+			return w.Flush()
+		default:
+			return fmt.Errorf("invalid format %q (expected table or json)", toolsFormat)
+		}
+	},
+}
+
+var harCmd = &cobra.Command{
+	Use:   "har path",
+	Short: "Prints an OpenAPI specification generated from a HAR capture",
+	Long: `Prints an OpenAPI specification for the API calls in an HTTP Archive (HAR) file, as saved by a browser's developer tools.
+Pass "-" to read the file from stdin.
+
+emcee serves HAR files given in place of a spec as they are. Use this to save the generated spec and refine it.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if args[0] == "-" {
+			data, err = io.ReadAll(cmd.InOrStdin())
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("error reading HAR file: %w", err)
+		}
+		specData, err := internal.SpecFromHAR(data)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(cmd.OutOrStdout(), "%s\n", specData)
+		return err
+	},
+}
+
+var serveSpecCmd = &cobra.Command{
+	Use:   "serve-spec dir",
+	Short: "Serves the spec files in a directory over HTTP",
+	Long: `Serves the spec files in a directory, and the files their $refs point to, over HTTP on localhost,
+so that a spec split across files can be given to emcee as a URL, with --allow-remote-refs.
+Files are read for each request, so edits are served as soon as they're saved. Hidden files aren't served.
+
+Serves until interrupted.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		specServer, err := internal.NewSpecServer(args[0], nil)
+		if err != nil {
+			return err
+		}
+		specs, err := specServer.Specs()
+		if err != nil {
+			return err
+		}
+		ln, err := net.Listen("tcp", specListen)
+		if err != nil {
+			return fmt.Errorf("error listening on %s: %w", specListen, err)
+		}
+
+		base := "http://" + ln.Addr().String()
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "Serving %s at %s\n", args[0], base)
+		if len(specs) == 0 {
+			fmt.Fprintln(out, "No specs found")
+		}
+		for _, spec := range specs {
+			fmt.Fprintf(out, "\n  emcee --allow-remote-refs %s%s\n", base, spec)
+		}
+
+		ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		srv := &http.Server{Handler: specServer, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			<-ctx.Done()
+			_ = srv.Close()
+		}()
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("error serving specs: %w", err)
+		}
+		return nil
+	},
+}
+
+var loginCmd = &cobra.Command{
+	Use:   "login spec-path-or-url",
+	Short: "Logs in to an API with OAuth 2.0 in a browser",
+	Long: `Logs in to the API of an OpenAPI specification with the OAuth 2.0 authorization code flow with PKCE.
+The provider's authorization page is opened in a browser, and the code it redirects back with
+is received on a localhost callback and exchanged for tokens.
+
+The refresh token is saved to the macOS Keychain, Windows Credential Manager, or Secret Service on Linux,
+for the API's server URL. When emcee serves the spec without other credentials, it uses the saved tokens,
+renewing access tokens as they expire.
+
+The authorization and token URLs, and the scopes requested, are those of the spec's oauth2 security scheme,
+unless they're passed as flags. The client must be registered with the provider,
+with the redirect URI http://127.0.0.1/callback on any port, or the one for --listen.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		var opts []internal.RegisterToolsOption
+		if len(serverVars) > 0 {
+			vars, err := serverVariables()
+			if err != nil {
+				return err
+			}
+			opts = append(opts, internal.WithServerVariables(vars))
+		}
+		if allowRemoteRefs {
+			opts = append(opts, internal.WithRemoteRefs())
+		}
+		if args[0] != "-" {
+			opts = append(opts, internal.WithSpecLocation(args[0]))
+		}
+		specData, err := readSpec(ctx, args[0], nil, slog.New(slog.DiscardHandler))
+		if err != nil {
+			return err
+		}
+		if internal.IsSecretReference(loginClientSecret) {
+			if loginClientSecret, _, err = internal.ResolveSecretReference(ctx, loginClientSecret); err != nil {
+				return fmt.Errorf("error resolving OAuth 2.0 client secret: %w", err)
+			}
+		}
+
+		login := internal.OAuth2LoginOptions{
+			ClientID:         loginClientID,
+			ClientSecret:     loginClientSecret,
+			Scopes:           loginScopes,
+			AuthorizationURL: loginAuthorizationURL,
+			TokenURL:         loginTokenURL,
+			Listen:           loginListen,
+			Out:              cmd.ErrOrStderr(),
+		}
+		if loginNoBrowser {
+			login.Open = func(context.Context, string) error { return nil }
+		}
+		account, err := internal.LoginOAuth2(ctx, specData, login, opts...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Logged in to %s\n", account)
+		return nil
+	},
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Creates and checks configuration files",
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init [path]",
+	Short: "Creates a configuration file listing every setting",
+	Long: `Creates a configuration file listing every setting, with examples, at path (default emcee.yaml).
+Settings are left empty, so the file has no effect until it's edited. Pass "-" to print it instead.`,
+	Args:          cobra.MaximumNArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := "emcee.yaml"
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "-" {
+			_, err := io.WriteString(cmd.OutOrStdout(), internal.ConfigTemplate)
+			return err
+		}
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if configForce {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		f, err := os.OpenFile(name, flags, 0o644)
+		if err != nil {
+			if os.IsExist(err) {
+				return fmt.Errorf("%s already exists (pass --force to overwrite it)", name)
+			}
+			return fmt.Errorf("error creating config file: %w", err)
+		}
+		if _, err := io.WriteString(f, internal.ConfigTemplate); err != nil {
+			f.Close()
+			return fmt.Errorf("error writing config file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("error writing config file: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Created %s\n", name)
+		return nil
 	},
 }
 
+var configValidateCmd = &cobra.Command{
+	Use:   "validate path",
+	Short: "Checks a configuration file",
+	Long: `Checks that a configuration file is well-formed, with no unknown fields.
+With --spec, also checks that the operations, endpoints, and paths it names are in the spec,
+and that the spec's operations can satisfy its settings.`,
+	Args:          cobra.ExactArgs(1),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := internal.LoadConfig(args[0])
+		if err != nil {
+			return err
+		}
+		if configSpec != "" {
+			specData, err := readSpec(cmd.Context(), configSpec, config, slog.New(slog.DiscardHandler))
+			if err != nil {
+				return err
+			}
+			problems, err := config.Lint(cmd.Context(), specData)
+			if err != nil {
+				return fmt.Errorf("error parsing spec: %w", err)
+			}
+			if len(problems) > 0 {
+				for _, problem := range problems {
+					fmt.Fprintln(cmd.ErrOrStderr(), problem)
+				}
+				return fmt.Errorf("%s has %d problem(s)", args[0], len(problems))
+			}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", args[0])
+		return nil
+	},
+}
+
+// toolArguments lists the arguments of a tool for a table, with required arguments marked with an asterisk.
+func toolArguments(tool internal.ToolSummary) string {
+	if tool.InputSchema == nil {
+		return ""
+	}
+	names := make([]string, 0, len(tool.InputSchema.Properties))
+	for name := range tool.InputSchema.Properties {
+		if slices.Contains(tool.InputSchema.Required, name) {
+			name += "*"
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ",")
+}
+
+// firstLine returns the first line of a description, for a table.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// serverVariables returns the values given with --server-var, by variable name.
+func serverVariables() (map[string]string, error) {
+	vars := make(map[string]string, len(serverVars))
+	for _, pair := range serverVars {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid server variable %q (expected name=value)", pair)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
+
 var (
 	bearerAuth string
 	basicAuth  string
 	rawAuth    string
+	negotiate  bool
+	apiKey     string
+	headers    []string
+	cookieJar  bool
+
+	oauth2RefreshToken string
+	oauth2TokenURL     string
+	oauth2ClientID     string
+	oauth2ClientSecret string
+	oauth2Scopes       []string
+	oauth2StatePath    string
+
+	retries    int
+	timeout    time.Duration
+	maxTimeout time.Duration
+
+	retryStatuses      []int
+	retryUnsafeMethods bool
 
-	retries  int
-	timeout  time.Duration
 	rps      int
 	insecure bool
+	proxy    string
+	cacheDir string
+	cacheTTL time.Duration
+
+	verbose        bool
+	silent         bool
+	logFormat      string
+	noAnnotations  bool
+	noOutputSchema bool
+	toolPrefix     string
+	toolNameFormat string
+
+	richDescriptions bool
+
+	includeDeprecated bool
+	includeInternal   bool
+	allowRemoteRefs   bool
+	overlayPaths      []string
+
+	schemaResources   bool
+	resourceTemplates bool
+	prompts           bool
 
-	verbose       bool
-	silent        bool
-	noAnnotations bool
+	coerceArguments bool
+	serverVars      []string
+	maxEnumValues   int
+
+	queryObjectStyle string
+	trailingSlashes  bool
+	rawPaths         bool
+
+	dryRun         bool
+	dryRunArgument bool
+
+	confirmDestructive bool
+	elicitArguments    bool
+	auditLogPath       string
+
+	canarySpec    string
+	canaryPercent float64
+	canaryTools   []string
+
+	notFoundTTL   time.Duration
+	notFoundTools []string
+
+	xmlToJSON         bool
+	requestIDHeader   string
+	downloadThreshold int
+
+	maxResponseBytes      int
+	responseLimitStrategy string
+	responseFields        []string
+
+	secretArgs []string
+
+	configPath     string
+	configResource bool
+	configToken    string
+
+	toolsFormat string
+	configForce bool
+	configSpec  string
+
+	specListen string
+
+	loginClientID         string
+	loginClientSecret     string
+	loginScopes           []string
+	loginAuthorizationURL string
+	loginTokenURL         string
+	loginListen           string
+	loginNoBrowser        bool
+
+	reloadInterval  time.Duration
+	listen          string
+	startupTimeout  time.Duration
+	shutdownTimeout time.Duration
+
+	maxMessageBytes  int
+	maxPendingWrites int
+
+	webhookListen string
+	webhookURL    string
+	pollInterval  time.Duration
+
+	healthCheck bool
+	healthPath  string
 
 	version = "dev"
 	commit  = "none"
@@ -250,25 +1003,298 @@ var (
 )
 
 func init() {
+	// Flags for reading specs and generating tools are shared by the subcommands that do so
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to a YAML or JSON configuration file")
+	rootCmd.PersistentFlags().StringVar(&toolPrefix, "tool-prefix", "", "Prefix prepended to every generated tool name (e.g. myapi_)")
+	rootCmd.PersistentFlags().StringVar(&toolNameFormat, "tool-name-format", "", "Template for generated tool names, with the placeholders {operationId}, {tag}, {method}, and {path} (e.g. {tag}_{operationId})")
+	rootCmd.PersistentFlags().BoolVar(&richDescriptions, "rich-descriptions", false, "Append the examples, defaults, and allowed values of arguments, and a summary of the response, to tool descriptions")
+	rootCmd.PersistentFlags().BoolVar(&includeDeprecated, "include-deprecated", false, "Generate tools for operations marked deprecated, noting in their descriptions that they're deprecated")
+	rootCmd.PersistentFlags().BoolVar(&includeInternal, "include-internal", false, "Generate tools for operations marked x-internal")
+	rootCmd.PersistentFlags().BoolVar(&allowRemoteRefs, "allow-remote-refs", false, "Fetch $refs to URLs, and relative $refs in specs read from URLs, to resolve specs split across files")
+	rootCmd.PersistentFlags().StringArrayVar(&overlayPaths, "overlay", nil, "OpenAPI Overlay file whose actions patch the spec before tools are generated (repeatable, applied in order)")
+	rootCmd.PersistentFlags().StringArrayVar(&serverVars, "server-var", nil, "Value for a variable in the spec's server URL, as name=value (repeatable)")
+
 	rootCmd.Flags().StringVar(&bearerAuth, "bearer-auth", "", "Bearer token value (will be prefixed with 'Bearer ')")
 	rootCmd.Flags().StringVar(&basicAuth, "basic-auth", "", "Basic auth value (either user:pass or base64 encoded, will be prefixed with 'Basic ')")
 	rootCmd.Flags().StringVar(&rawAuth, "raw-auth", "", "Raw value for Authorization header")
-	rootCmd.MarkFlagsMutuallyExclusive("bearer-auth", "basic-auth", "raw-auth")
+	rootCmd.Flags().BoolVar(&negotiate, "negotiate", false, "Authenticate with SPNEGO (Negotiate) using Kerberos tickets from the credential cache (KRB5CCNAME), for APIs behind Windows Integrated Authentication")
+	rootCmd.Flags().StringVar(&oauth2RefreshToken, "oauth2-refresh-token", "", "OAuth 2.0 refresh token, exchanged for access tokens that are renewed when they expire or the API rejects them")
+	rootCmd.Flags().StringVar(&oauth2TokenURL, "oauth2-token-url", "", "OAuth 2.0 token endpoint (default the refreshUrl or tokenUrl of the spec's oauth2 security scheme)")
+	rootCmd.Flags().StringVar(&oauth2ClientID, "oauth2-client-id", "", "OAuth 2.0 client ID sent with token requests")
+	rootCmd.Flags().StringVar(&oauth2ClientSecret, "oauth2-client-secret", "", "OAuth 2.0 client secret sent with token requests")
+	rootCmd.Flags().StringSliceVar(&oauth2Scopes, "oauth2-scope", nil, "OAuth 2.0 scope requested for access tokens (repeatable)")
+	rootCmd.Flags().StringVar(&oauth2StatePath, "oauth2-state", "", "File the latest OAuth 2.0 refresh token is saved to when the provider rotates it, and read from at startup")
+	rootCmd.MarkFlagsMutuallyExclusive("bearer-auth", "basic-auth", "raw-auth", "negotiate", "oauth2-refresh-token")
+	rootCmd.Flags().StringVar(&apiKey, "api-key", "", "API key, sent in the header, query parameter, or cookie named by the spec's apiKey security scheme")
+	rootCmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "Header added to every API request, as 'Name: Value' (repeatable)")
+	rootCmd.Flags().StringVar(&requestIDHeader, "request-id-header", internal.DefaultRequestIDHeader, "Header that carries the correlation ID of each tool call, as logged, to the API (empty to not send it)")
+	rootCmd.Flags().BoolVar(&cookieJar, "cookie-jar", false, "Keep cookies set by the API, like a session cookie from a login endpoint, and send them with later requests")
 
 	rootCmd.Flags().IntVar(&retries, "retries", 3, "Maximum number of retries for failed requests")
+	rootCmd.Flags().IntSliceVar(&retryStatuses, "retry-status", nil, "Response status that is retried, instead of 429 and 5XX statuses other than 501 (repeatable)")
+	rootCmd.Flags().BoolVar(&retryUnsafeMethods, "retry-unsafe-methods", false, "Also retry requests with methods that aren't idempotent, like POST and PATCH, which may repeat their effects")
 	rootCmd.Flags().DurationVar(&timeout, "timeout", 60*time.Second, "HTTP request timeout")
+	rootCmd.Flags().DurationVar(&maxTimeout, "max-timeout", 0, "Add a _timeoutSeconds argument to every tool, which gives a call up to this long instead of --timeout (e.g. 5m; 0 to disable)")
 	rootCmd.Flags().IntVarP(&rps, "rps", "r", 0, "Maximum requests per second (0 for no limit)")
 	rootCmd.Flags().BoolVar(&insecure, "insecure", false, "Allow insecure TLS connections (skip certificate verification)")
+	rootCmd.Flags().StringVar(&cacheDir, "cache-dir", "", "Directory to cache GET responses in, honoring Cache-Control, ETag, and Last-Modified (default in memory when --cache-ttl is set)")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", 0, "Cache GET responses without Cache-Control or Expires headers for this long (e.g. 5m; 0 to cache only responses that allow it)")
+	rootCmd.Flags().StringVar(&proxy, "proxy", "", "Proxy URL for API and spec requests, with the scheme http, https, or socks5 (default from HTTP_PROXY, HTTPS_PROXY, and NO_PROXY)")
 
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable debug level logging to stderr")
 	rootCmd.Flags().BoolVarP(&silent, "silent", "s", false, "Disable all logging")
 	rootCmd.MarkFlagsMutuallyExclusive("verbose", "silent")
+	rootCmd.Flags().StringVar(&logFormat, "log-format", "text", "Format of log output to stderr: text, or json for one JSON object per line")
 
 	rootCmd.Flags().BoolVar(&noAnnotations, "no-annotations", false, "Disable generated tool annotations")
+	rootCmd.Flags().BoolVar(&noOutputSchema, "no-output-schema", false, "Disable output schemas and structured content derived from response schemas")
+	rootCmd.Flags().BoolVar(&schemaResources, "schema-resources", false, "Expose each tool's input and output schemas as resources at emcee://tools/{name}/schema")
+	rootCmd.Flags().BoolVar(&resourceTemplates, "resource-templates", false, "Expose GET operations with path parameters as resource templates (e.g. api://pets/{petId})")
+	rootCmd.Flags().BoolVar(&prompts, "prompts", false, "Generate a prompt for each tag in the spec that walks the model through its operations")
+	rootCmd.Flags().StringVar(&queryObjectStyle, "query-object-style", "", "Serialization of object-valued query parameters without a style in the spec: bracket (filter[name]=x) or dot (filter.name=x) (default bracket)")
+	rootCmd.Flags().IntVar(&maxEnumValues, "max-enum-values", 0, "List enums with more values than this as resources with completions, instead of in input schemas (0 for no limit)")
+	rootCmd.Flags().BoolVar(&trailingSlashes, "keep-trailing-slashes", false, "Keep trailing slashes of paths in the spec (e.g. /pets/), which are otherwise removed")
+	rootCmd.Flags().BoolVar(&rawPaths, "raw-paths", false, "Send paths as the spec writes them, and percent-encoded path parameter values (e.g. a%2Fb) without escaping them again")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Return the HTTP request each tool call would send, with credentials redacted, instead of sending it")
+	rootCmd.Flags().BoolVar(&dryRunArgument, "dry-run-arg", false, "Add a _dryRun argument to every tool, which returns the HTTP request a call would send instead of sending it")
+	rootCmd.Flags().StringVar(&auditLogPath, "audit-log", "", "Append a JSON line for every tool call, with its arguments (secrets redacted), URL, status, latency, and response size, to this file")
+	rootCmd.Flags().BoolVar(&confirmDestructive, "confirm-destructive", false, "Preview POST, PUT, PATCH, and DELETE requests, and send them only when the call is repeated with the returned confirmation token")
+	rootCmd.Flags().BoolVar(&elicitArguments, "elicit-arguments", false, "Ask clients that support elicitation for the required arguments a tool call is missing, instead of failing the call")
+	rootCmd.Flags().BoolVar(&coerceArguments, "coerce-arguments", false, "Normalize humanized numbers and dates in tool arguments (e.g. \"1,5\" or \"March 3rd 2025\")")
+
+	rootCmd.Flags().StringVar(&canarySpec, "canary-spec", "", "Path or URL of a new spec version to route a share of tool calls to")
+	rootCmd.Flags().Float64Var(&canaryPercent, "canary-percent", 0, "Percentage of tool calls (0-100) routed to the canary spec's server")
+	rootCmd.Flags().StringSliceVar(&canaryTools, "canary-tool", nil, "Tool whose calls are always routed to the canary spec's server (repeatable)")
+
+	rootCmd.Flags().DurationVar(&notFoundTTL, "not-found-ttl", 0, "Reuse 404 responses from read-only tools for identical calls within this duration (e.g. 30s; 0 to disable)")
+	rootCmd.Flags().StringSliceVar(&notFoundTools, "not-found-tool", nil, "Tool whose 404 responses are cached, instead of all read-only tools (repeatable)")
+
+	rootCmd.Flags().BoolVar(&xmlToJSON, "xml-to-json", false, "Convert XML responses to JSON, with attributes prefixed with @ and repeated elements as arrays")
+	rootCmd.Flags().IntVar(&downloadThreshold, "download-threshold", 0, "Return file downloads, and binary responses larger than this many bytes, as links to resources instead of inline (0 to disable)")
+	rootCmd.Flags().IntVar(&maxResponseBytes, "max-response-bytes", 0, "Shorten tool results with more text than this many bytes, using --response-limit-strategy (0 for no limit)")
+	rootCmd.Flags().StringVar(&responseLimitStrategy, "response-limit-strategy", "truncate", "How results over --max-response-bytes are shortened: truncate (with a notice), fields (keep only --response-field fields), or resource (store as a resource and return its URI)")
+	rootCmd.Flags().StringSliceVar(&responseFields, "response-field", nil, "Field of JSON responses kept by the fields strategy, as a dotted path like owner.name (repeatable)")
+
+	rootCmd.Flags().StringSliceVar(&secretArgs, "secret-arg", nil, "Tool argument whose value is redacted from logs, as name or tool.name, or a glob pattern like *token* (repeatable)")
+
+	rootCmd.Flags().BoolVar(&configResource, "config-resource", false, "Expose the tool filters of --config as a resource, which clients with --config-token can replace with config/update requests, saving them to the file (experimental)")
+	rootCmd.Flags().StringVar(&configToken, "config-token", "", "Token that config/update requests must carry to replace the tool filters (may be a secret reference)")
+	rootCmd.Flags().DurationVar(&reloadInterval, "reload-interval", 0, "Check the spec file or URL for changes at this interval, and reload tools when it changes (e.g. 5s; 0 to disable)")
+	rootCmd.Flags().StringVar(&listen, "listen", "", "Serve MCP clients that connect to a Unix domain socket (unix:///path/to/sock) or Windows named pipe (npipe:////./pipe/name), instead of over stdio")
+	rootCmd.Flags().IntVar(&maxMessageBytes, "max-message-bytes", internal.DefaultMaxMessageBytes, "Answer messages from clients larger than this many bytes with an error, without reading them in full (0 for no limit)")
+	rootCmd.Flags().IntVar(&maxPendingWrites, "max-pending-writes", internal.DefaultMaxPendingWrites, "Hold up requests while this many messages are waiting to be written to a client that isn't reading its output (0 for no limit)")
+	rootCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", internal.DefaultShutdownTimeout, "On SIGINT or SIGTERM, give requests in flight this long to finish before canceling them")
+	rootCmd.Flags().DurationVar(&pollInterval, "poll-interval", 0, "Let clients subscribe to resources read from GET operations, reading them at this interval to notify subscribers of changes (e.g. 30s; requires --resource-templates)")
+	rootCmd.Flags().StringVar(&webhookListen, "webhook-listen", "", "Receive the webhooks and callbacks the spec declares at this address (e.g. localhost:8081), exposing their events as resources clients can subscribe to")
+	rootCmd.Flags().StringVar(&webhookURL, "webhook-url", "", "Public URL that reaches --webhook-listen, like a tunnel, given to the API for sending callbacks")
+	rootCmd.Flags().BoolVar(&healthCheck, "healthcheck", false, "At startup, probe each API's health endpoint with the credentials tool calls send, and exit if it's unreachable, rejects them, or is failing")
+	rootCmd.Flags().StringVar(&healthPath, "health-path", internal.DefaultHealthPath, "Path of each API's health endpoint, relative to its server URL, probed by --healthcheck and health/check requests")
+	rootCmd.Flags().DurationVar(&startupTimeout, "startup-timeout", 0, "Start serving after this long even if some specs are still loading, adding their tools when ready (e.g. 10s; 0 to wait for all)")
+
+	toolsCmd.Flags().StringVar(&toolsFormat, "format", "table", "Output format: table or json")
+	rootCmd.AddCommand(toolsCmd)
+	rootCmd.AddCommand(harCmd)
+	serveSpecCmd.Flags().StringVar(&specListen, "listen", "localhost:8080", "Address to serve the specs at (e.g. localhost:0 for any free port)")
+	rootCmd.AddCommand(serveSpecCmd)
+	loginCmd.Flags().StringVar(&loginClientID, "client-id", "", "OAuth 2.0 client ID, as registered with the provider")
+	loginCmd.Flags().StringVar(&loginClientSecret, "client-secret", "", "OAuth 2.0 client secret, for providers that require one (can be a secret reference)")
+	loginCmd.Flags().StringSliceVar(&loginScopes, "scope", nil, "Scope to request (repeatable; default the scopes the spec's security requirements name)")
+	loginCmd.Flags().StringVar(&loginAuthorizationURL, "authorization-url", "", "Authorization endpoint (default the authorizationUrl of the spec's authorization code flow)")
+	loginCmd.Flags().StringVar(&loginTokenURL, "token-url", "", "Token endpoint (default the tokenUrl of the spec's authorization code flow)")
+	loginCmd.Flags().StringVar(&loginListen, "listen", internal.DefaultOAuth2CallbackAddress, "Address to receive the callback at, whose redirect URI must be registered with the provider (e.g. localhost:8085)")
+	loginCmd.Flags().BoolVar(&loginNoBrowser, "no-browser", false, "Print the authorization URL without opening a browser, to open it yourself")
+	loginCmd.MarkFlagRequired("client-id")
+	rootCmd.AddCommand(loginCmd)
+	configInitCmd.Flags().BoolVar(&configForce, "force", false, "Overwrite the file if it exists")
+	configValidateCmd.Flags().StringVar(&configSpec, "spec", "", "Path or URL of the spec the configuration is for")
+	configCmd.AddCommand(configInitCmd, configValidateCmd)
+	rootCmd.AddCommand(configCmd)
 
 	rootCmd.Version = fmt.Sprintf("%s (commit: %s, built at: %s)", version, commit, date)
 }
 
+// authProvider returns an AuthProvider that sets the Authorization header to the value of an auth flag,
+// converted by format. Secret references are resolved up front, so that mistakes are reported at startup,
+// and resolved again if the API rejects the secret.
+func authProvider(ctx context.Context, kind, value string, format func(string) string, logger *slog.Logger) (internal.AuthProvider, error) {
+	if !internal.IsSecretReference(value) {
+		if format != nil {
+			value = format(value)
+		}
+		return internal.HeaderAuth{Name: "Authorization", Value: value}, nil
+	}
+	auth := &internal.SecretHeaderAuth{Name: "Authorization", Reference: value, Format: format}
+	if err := auth.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("error resolving %s auth: %w", kind, err)
+	}
+	logger.Debug("resolved " + kind + " auth from secret reference")
+	return auth, nil
+}
+
+// maxSpecFileSize is the size above which spec files are filtered as they're read, rather than loaded whole.
+const maxSpecFileSize = 100 * 1024 * 1024 // 100MB
+
+// newLogger returns a logger writing to w in the format given by --log-format,
+// at the level given by --verbose and --silent.
+func newLogger(w io.Writer) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+	handler, err := internal.NewLogHandler(w, logFormat, level)
+	if err != nil {
+		return nil, err
+	}
+	if silent {
+		handler = slog.DiscardHandler
+	}
+	return slog.New(handler), nil
+}
+
+// readSpec reads an OpenAPI specification from a URL or local file path, and applies the --overlay files to it.
+func readSpec(ctx context.Context, source string, config *internal.Config, logger *slog.Logger) ([]byte, error) {
+	specData, err := readSpecSource(ctx, source, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	return prepareSpec(specData)
+}
+
+// prepareSpec generates a spec from a HAR capture or AsyncAPI document, if specData is one,
+// and applies the --overlay files to the spec, in order.
+// The files are read each time, so that a reloaded spec gets their latest changes.
+func prepareSpec(specData []byte) ([]byte, error) {
+	var err error
+	switch {
+	case internal.IsHAR(specData):
+		specData, err = internal.SpecFromHAR(specData)
+	case internal.IsAsyncAPI(specData):
+		specData, err = internal.SpecFromAsyncAPI(specData)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(overlayPaths) == 0 {
+		return specData, nil
+	}
+	overlays := make([]*internal.Overlay, 0, len(overlayPaths))
+	for _, name := range overlayPaths {
+		o, err := internal.LoadOverlay(name)
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, o)
+	}
+	return internal.ApplyOverlays(specData, overlays...)
+}
+
+// readSpecSource reads an OpenAPI specification from a URL or local file path.
+// Files larger than maxSpecFileSize must be JSON,
+// and are reduced to the paths that config doesn't disable and the components they refer to.
+func readSpecSource(ctx context.Context, source string, config *internal.Config, logger *slog.Logger) ([]byte, error) {
+	var specData []byte
+	if internal.IsGitSpec(source) {
+		spec, err := internal.ParseGitSpec(source)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("reading spec from git", "repository", spec.Repository, "ref", spec.Ref, "path", spec.Path)
+		specData, err = spec.Read(ctx, internal.GitSpecOptions{Proxy: proxy, Insecure: insecure})
+		if err != nil {
+			return nil, fmt.Errorf("error reading spec from git: %w", err)
+		}
+	} else if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		logger.Info("reading spec from URL", "url", source)
+
+		// Create HTTP request
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+
+		// Make HTTP request
+		client := http.DefaultClient
+		if insecure || proxy != "" {
+			transport := &http.Transport{}
+			if base, ok := http.DefaultTransport.(*http.Transport); ok && base != nil {
+				transport = base.Clone()
+			}
+			if insecure {
+				if transport.TLSClientConfig == nil {
+					transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+				} else {
+					transport.TLSClientConfig = transport.TLSClientConfig.Clone()
+					transport.TLSClientConfig.InsecureSkipVerify = true
+				}
+			}
+			if proxy != "" {
+				proxyURL, err := internal.ParseProxyURL(proxy)
+				if err != nil {
+					return nil, err
+				}
+				transport.Proxy = http.ProxyURL(proxyURL)
+			}
+			client = &http.Client{Transport: transport}
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading spec: %w", err)
+		}
+		if resp.Body == nil {
+			return nil, fmt.Errorf("no response body from %s", source)
+		}
+		defer resp.Body.Close()
+
+		// Read spec from response body
+		specData, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading spec from %s: %w", source, err)
+		}
+	} else {
+		logger.Info("reading spec from file", "file", source)
+
+		// Clean the file path to remove any . or .. segments and ensure consistent separators
+		cleanPath := filepath.Clean(source)
+
+		// Check if file exists and is readable before attempting to read
+		info, err := os.Stat(cleanPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("spec file does not exist: %s", cleanPath)
+			}
+			return nil, fmt.Errorf("error accessing spec file %s: %w", cleanPath, err)
+		}
+
+		// Ensure it's a regular file, not a directory
+		if info.IsDir() {
+			return nil, fmt.Errorf("specified path is a directory, not a file: %s", cleanPath)
+		}
+
+		// Filter extremely large files as they're read, rather than loading them whole
+		if info.Size() > maxSpecFileSize {
+			logger.Info("filtering large spec file", "file", cleanPath, "size", info.Size())
+			f, err := os.Open(cleanPath)
+			if err != nil {
+				return nil, fmt.Errorf("error opening spec file %s: %w", cleanPath, err)
+			}
+			defer f.Close()
+			specData, err = internal.FilterSpec(f, config)
+			if err != nil {
+				return nil, fmt.Errorf("spec file too large to load whole (max 100MB), and couldn't be filtered: %s: %w", cleanPath, err)
+			}
+			logger.Info("filtered large spec file", "file", cleanPath, "size", len(specData))
+			return specData, nil
+		}
+
+		// Read spec from file
+		specData, err = os.ReadFile(cleanPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading spec file %s: %w", cleanPath, err)
+		}
+	}
+	return specData, nil
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// idempotencyKeyHeader is the header with which clients make retries of a POST request safe,
+// so that operations that accept it are idempotent.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// toolAnnotations derives MCP ToolAnnotations for an operation from REST conventions:
+// the safety and idempotency of its HTTP method, and whether it accepts an Idempotency-Key header.
+// The operation's x-mcp-annotations extension overrides what's derived,
+// and operations the config file lists as destructive are always annotated as destructive.
+func toolAnnotations(method, path string, item *v3.PathItem, op *v3.Operation, ext toolExtensions, config *Config) *mcp.ToolAnnotations {
+	title := op.Summary
+	if title == "" {
+		title = fmt.Sprintf("%s %s", method, path)
+	}
+	ann := &mcp.ToolAnnotations{
+		Title:         title,
+		OpenWorldHint: boolPtr(true),
+	}
+	switch method {
+	case "GET", "QUERY":
+		ann.ReadOnlyHint = true
+		ann.IdempotentHint = true
+	case "POST", "PATCH":
+		ann.DestructiveHint = boolPtr(true)
+		ann.IdempotentHint = acceptsIdempotencyKey(item, op)
+	case "PUT", "DELETE":
+		ann.IdempotentHint = true
+		ann.DestructiveHint = boolPtr(true)
+	}
+
+	if hints := ext.annotations; hints != nil {
+		ann.Title = cmp.Or(hints.title, ann.Title)
+		if v, ok := hints.values["readOnlyHint"]; ok {
+			ann.ReadOnlyHint = v
+			if v {
+				// Destructiveness only applies to tools that make changes
+				ann.DestructiveHint = nil
+			}
+		}
+		if v, ok := hints.values["destructiveHint"]; ok {
+			ann.DestructiveHint = boolPtr(v)
+		}
+		if v, ok := hints.values["idempotentHint"]; ok {
+			ann.IdempotentHint = v
+		}
+		if v, ok := hints.values["openWorldHint"]; ok {
+			ann.OpenWorldHint = boolPtr(v)
+		}
+	}
+
+	if config.marksDestructive(op.OperationId) {
+		ann.ReadOnlyHint = false
+		ann.DestructiveHint = boolPtr(true)
+	}
+	return ann
+}
+
+// acceptsIdempotencyKey reports whether an operation, or the path it's on, has an Idempotency-Key header parameter.
+func acceptsIdempotencyKey(item *v3.PathItem, op *v3.Operation) bool {
+	var params []*v3.Parameter
+	if item != nil {
+		params = append(params, item.Parameters...)
+	}
+	params = append(params, op.Parameters...)
+	for _, param := range params {
+		if param != nil && param.In == "header" && strings.EqualFold(param.Name, idempotencyKeyHeader) {
+			return true
+		}
+	}
+	return false
+}
+
+// annotationHints are the annotations set by an operation's x-mcp-annotations extension.
+type annotationHints struct {
+	title  string
+	values map[string]bool // by hint name, like readOnlyHint
+}
+
+// parseAnnotationHints parses the value of an x-mcp-annotations extension:
+// a mapping of readOnlyHint, destructiveHint, idempotentHint, and openWorldHint to booleans,
+// and optionally a title.
+func parseAnnotationHints(node *yaml.Node) (*annotationHints, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid x-mcp-annotations: expected an object")
+	}
+	hints := &annotationHints{values: make(map[string]bool)}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch key {
+		case "title":
+			if value.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("invalid x-mcp-annotations: expected title to be a string")
+			}
+			hints.title = value.Value
+		case "readOnlyHint", "destructiveHint", "idempotentHint", "openWorldHint":
+			v, err := strconv.ParseBool(value.Value)
+			if value.Kind != yaml.ScalarNode || err != nil {
+				return nil, fmt.Errorf("invalid x-mcp-annotations: expected %s to be a boolean", key)
+			}
+			hints.values[key] = v
+		default:
+			return nil, fmt.Errorf("invalid x-mcp-annotations: unknown annotation %q", key)
+		}
+	}
+	return hints, nil
+}
+
+func boolPtr(v bool) *bool { return &v }
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolAnnotations(t *testing.T) {
+	spec := `
+openapi: 3.1.0
+info: {title: Pet API, version: 1.0.0}
+servers: [{url: https://api.example.com}]
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List pets
+      responses: {"200": {description: OK}}
+    post:
+      operationId: createPet
+      responses: {"201": {description: Created}}
+  /orders:
+    parameters:
+      - {name: Idempotency-Key, in: header, schema: {type: string}}
+    post:
+      operationId: createOrder
+      responses: {"201": {description: Created}}
+  /pets/search:
+    post:
+      operationId: searchPets
+      x-mcp-annotations:
+        title: Search pets
+        readOnlyHint: true
+        idempotentHint: true
+        openWorldHint: false
+      responses: {"200": {description: OK}}
+  /pets/{petId}:
+    parameters:
+      - {name: petId, in: path, required: true, schema: {type: string}}
+    put:
+      operationId: replacePet
+      responses: {"200": {description: OK}}
+    patch:
+      operationId: updatePet
+      responses: {"200": {description: OK}}
+    delete:
+      operationId: deletePet
+      responses: {"204": {description: Deleted}}
+`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	listAnnotations := func(t *testing.T, opts ...RegisterToolsOption) map[string]*mcp.ToolAnnotations {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), nil, opts...))
+		tools, err := connectTestClient(t, ctx, server).ListTools(ctx, nil)
+		require.NoError(t, err)
+		annotations := make(map[string]*mcp.ToolAnnotations)
+		for _, tool := range tools.Tools {
+			annotations[tool.Name] = tool.Annotations
+		}
+		return annotations
+	}
+
+	annotations := listAnnotations(t)
+
+	for _, tt := range []struct {
+		tool                            string
+		title                           string
+		readOnly, idempotent, openWorld bool
+		destructive                     *bool
+	}{
+		{tool: "listPets", title: "List pets", readOnly: true, idempotent: true, openWorld: true},
+		{tool: "createPet", title: "POST /pets", destructive: boolPtr(true), openWorld: true},
+		{tool: "createOrder", title: "POST /orders", idempotent: true, destructive: boolPtr(true), openWorld: true},
+		{tool: "searchPets", title: "Search pets", readOnly: true, idempotent: true},
+		{tool: "replacePet", title: "PUT /pets/{petId}", idempotent: true, destructive: boolPtr(true), openWorld: true},
+		{tool: "updatePet", title: "PATCH /pets/{petId}", destructive: boolPtr(true), openWorld: true},
+		{tool: "deletePet", title: "DELETE /pets/{petId}", idempotent: true, destructive: boolPtr(true), openWorld: true},
+	} {
+		t.Run(tt.tool, func(t *testing.T) {
+			ann := annotations[tt.tool]
+			require.NotNil(t, ann)
+			assert.Equal(t, tt.title, ann.Title)
+			assert.Equal(t, tt.readOnly, ann.ReadOnlyHint, "readOnlyHint")
+			assert.Equal(t, tt.idempotent, ann.IdempotentHint, "idempotentHint")
+			assert.Equal(t, tt.destructive, ann.DestructiveHint, "destructiveHint")
+			require.NotNil(t, ann.OpenWorldHint)
+			assert.Equal(t, tt.openWorld, *ann.OpenWorldHint, "openWorldHint")
+		})
+	}
+
+	// Operations the config file lists as destructive are annotated as destructive, whatever the spec says
+	search := listAnnotations(t, WithConfig(&Config{DestructiveOperations: []string{"searchPets"}}))["searchPets"]
+	assert.False(t, search.ReadOnlyHint)
+	assert.Equal(t, boolPtr(true), search.DestructiveHint)
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, value := range []string{`readOnlyHint: maybe`, `safe: true`} {
+			_, err := ListTools(ctx, []byte(`
+openapi: 3.1.0
+info: {title: Pet API, version: 1.0.0}
+servers: [{url: https://api.example.com}]
+paths:
+  /pets/search:
+    post:
+      operationId: searchPets
+      x-mcp-annotations: {`+value+`}
+      responses: {"200": {description: OK}}
+`))
+			assert.ErrorContains(t, err, "invalid x-mcp-annotations", value)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		for name, ann := range listAnnotations(t, WithoutAnnotations()) {
+			assert.Nil(t, ann, name)
+		}
+	})
+}
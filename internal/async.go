@@ -0,0 +1,239 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// asyncOperationHeaders are response headers used by Azure-style APIs
+// to point at a URL for polling the status of a long-running operation.
+var asyncOperationHeaders = []string{"Azure-AsyncOperation", "Operation-Location"}
+
+// asyncOperationToolName is the operationId of the generated tool for checking long-running operations.
+const asyncOperationToolName = "checkOperationStatus"
+
+// maxAsyncOperations bounds how many status URLs are tracked across sessions.
+// When more are tracked, the oldest is forgotten.
+const maxAsyncOperations = 1000
+
+// terminalAsyncStatuses are the statuses of long-running operations that have finished, in lowercase.
+var terminalAsyncStatuses = []string{"succeeded", "failed", "canceled", "cancelled"}
+
+// asyncOperations tracks status URLs returned by long-running operations
+// and exposes a tool for polling them.
+// Only URLs returned by the API can be polled, so the tool can't be used to make arbitrary requests,
+// and only by the client session they were returned to.
+// URLs are only tracked on the scheme and host of the operation's request, since they're polled with its credentials,
+// and are forgotten once the operation has finished.
+type asyncOperations struct {
+	server *mcp.Server
+	client *http.Client
+	cfg    *registerToolsConfig
+	name   string
+
+	once    sync.Once
+	mu      sync.Mutex
+	urls    map[string]asyncOperation // by session and URL
+	tracked []string                  // keys of urls, oldest first
+}
+
+// asyncOperation is a long-running operation whose status URL is tracked.
+type asyncOperation struct {
+	// fields removes fields from the status responses as from the operation's.
+	fields *responseFilter
+	// auth is the provider of the credentials the configuration sets for the operation, if it sets any.
+	auth AuthProvider
+}
+
+func newAsyncOperations(server *mcp.Server, client *http.Client, cfg *registerToolsConfig, name string) *asyncOperations {
+	return &asyncOperations{
+		server: server,
+		client: client,
+		cfg:    cfg,
+		name:   name,
+		urls:   make(map[string]asyncOperation),
+	}
+}
+
+// track records the status URL of a long-running operation, if the response to req has one on the same scheme and host,
+// registering the status tool on first use. Fields are removed from the status responses as from the operation's.
+// It returns a note instructing the model how to poll the operation.
+func (a *asyncOperations) track(session string, req *http.Request, resp *http.Response, fields *responseFilter) (string, bool) {
+	var statusURL string
+	for _, header := range asyncOperationHeaders {
+		if v := resp.Header.Get(header); v != "" {
+			statusURL = v
+			break
+		}
+	}
+	if statusURL == "" {
+		return "", false
+	}
+	ref, err := url.Parse(statusURL)
+	if err != nil {
+		return "", false
+	}
+	ref = req.URL.ResolveReference(ref)
+	// Status URLs elsewhere would be sent the API's credentials
+	if ref.Scheme != req.URL.Scheme || ref.Host != req.URL.Host {
+		a.cfg.logger.Warn("ignoring status URL of a long-running operation on another host", "url", ref.Redacted())
+		return "", false
+	}
+	statusURL = ref.String()
+	auth, _ := req.Context().Value(operationAuthKey{}).(AuthProvider)
+
+	key := session + "\x00" + statusURL
+	a.mu.Lock()
+	if _, ok := a.urls[key]; !ok {
+		a.tracked = append(a.tracked, key)
+	}
+	a.urls[key] = asyncOperation{fields: fields, auth: auth}
+	if len(a.tracked) > maxAsyncOperations {
+		evicted := a.tracked[:len(a.tracked)-maxAsyncOperations]
+		for _, k := range evicted {
+			delete(a.urls, k)
+		}
+		a.tracked = append([]string(nil), a.tracked[len(evicted):]...)
+	}
+	a.mu.Unlock()
+	a.register()
+
+	note := fmt.Sprintf("The operation is running asynchronously. Call %s with {\"url\": %q} to check its status.", a.name, statusURL)
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		note += fmt.Sprintf(" The API suggests waiting %s seconds before checking.", retryAfter)
+	}
+	return note, true
+}
+
+// register adds the status tool to the server. It is safe to call more than once.
+func (a *asyncOperations) register() {
+	a.once.Do(func() {
+		tool := &mcp.Tool{
+			Name:        a.name,
+			Description: "Checks the status of a long-running operation using the status URL returned by a previous tool call.",
+			InputSchema: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"url": {Type: "string", Description: "Status URL returned by a previous tool call"},
+				},
+				Required: []string{"url"},
+			},
+		}
+		if a.cfg.enableAnnotations {
+			openWorld := true
+			tool.Annotations = &mcp.ToolAnnotations{
+				Title:          "Check operation status",
+				ReadOnlyHint:   true,
+				IdempotentHint: true,
+				OpenWorldHint:  &openWorld,
+			}
+		}
+		mcp.AddTool(a.server, tool, a.cfg.audited(a.name, a.handle))
+	})
+}
+
+func (a *asyncOperations) handle(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+	statusURL, _ := req.Params.Arguments["url"].(string)
+	key := sessionID(req) + "\x00" + statusURL
+	a.mu.Lock()
+	op, ok := a.urls[key]
+	a.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown operation status URL: %s", statusURL)
+	}
+
+	// The status is requested with the credentials the operation was called with
+	if op.auth != nil {
+		ctx = contextWithOperationAuth(ctx, op.auth)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	audit := auditRecordFrom(ctx)
+	audit.sent(hreq)
+	resp, err := a.client.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	audit.received(resp)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if finished(resp, body) {
+		a.forget(key)
+	}
+	if body, err = op.fields.filter(resp.Header.Get("Content-Type"), body, a.cfg.xmlToJSON); err != nil {
+		return nil, err
+	}
+	result := toolResult(resp, body, a.cfg)
+	// The status tool has no output schema, so omit structured content
+	result.StructuredContent = nil
+	return result, nil
+}
+
+// forget stops tracking a status URL, by session and URL.
+func (a *asyncOperations) forget(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.urls, key)
+	a.tracked = slices.DeleteFunc(a.tracked, func(k string) bool { return k == key })
+}
+
+// finished reports whether a status response says that a long-running operation has finished,
+// with a terminal status like "Succeeded" or "Failed", or that its status is gone.
+func finished(resp *http.Response, body []byte) bool {
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return true
+	}
+	var status struct {
+		Status string `json:"status"`
+	}
+	if resp.StatusCode >= 300 || json.Unmarshal(body, &status) != nil {
+		return false
+	}
+	return slices.Contains(terminalAsyncStatuses, strings.ToLower(status.Status))
+}
+
+// declaresAsyncOperation reports whether an operation declares an async operation header
+// in any of its responses, or is marked with the x-ms-long-running-operation extension.
+func declaresAsyncOperation(op *v3.Operation) bool {
+	if op == nil {
+		return false
+	}
+	if op.Extensions != nil {
+		if ext, ok := op.Extensions.Get("x-ms-long-running-operation"); ok && ext != nil && ext.Value == "true" {
+			return true
+		}
+	}
+	if op.Responses == nil || op.Responses.Codes == nil {
+		return false
+	}
+	for pair := op.Responses.Codes.First(); pair != nil; pair = pair.Next() {
+		resp := pair.Value()
+		if resp == nil || resp.Headers == nil {
+			continue
+		}
+		for header := resp.Headers.First(); header != nil; header = header.Next() {
+			for _, name := range asyncOperationHeaders {
+				if strings.EqualFold(header.Key(), name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
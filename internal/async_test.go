@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsAsyncOperation(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/jobs":
+			w.Header().Set("Operation-Location", "/operations/42")
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte(`{}`))
+		case "/operations/42":
+			_, _ = w.Write([]byte(`{"status":"Succeeded"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Jobs API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/jobs": {
+      "post": {"operationId": "startJob", "responses": {"202": {"description": "Accepted"}}}
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1, "status tool is registered on first use")
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "startJob", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 2)
+	statusURL := api.URL + "/operations/42"
+	note := result.Content[1].(*mcp.TextContent).Text
+	assert.Contains(t, note, "checkOperationStatus")
+	assert.Contains(t, note, statusURL)
+	assert.Contains(t, note, "5 seconds")
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "checkOperationStatus", Arguments: map[string]any{"url": statusURL}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "Succeeded")
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "checkOperationStatus", Arguments: map[string]any{"url": statusURL}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError, "URLs of finished operations are forgotten")
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "checkOperationStatus", Arguments: map[string]any{"url": "https://example.com/"}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError, "only URLs returned by the API can be checked")
+}
+
+func TestRegisterToolsAsyncOperationCredentials(t *testing.T) {
+	var mu sync.Mutex
+	var statusAuth []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/jobs":
+			w.Header().Set("Operation-Location", "/operations/42")
+			w.WriteHeader(http.StatusAccepted)
+		case "/exports":
+			w.Header().Set("Operation-Location", "https://attacker.example.com/operations/42")
+			w.WriteHeader(http.StatusAccepted)
+		case "/operations/42":
+			mu.Lock()
+			statusAuth = append(statusAuth, r.Header.Get("Authorization"))
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{"status":"Running"}`))
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Jobs API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/jobs": {"post": {"operationId": "startJob", "responses": {"202": {"description": "Accepted"}}}},
+    "/exports": {"post": {"operationId": "startExport", "responses": {"202": {"description": "Accepted"}}}}
+  }
+}`, api.URL)
+	config, err := ParseConfig([]byte(`
+auth:
+  - operations: [startJob]
+    bearer: job-token
+`))
+	require.NoError(t, err)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(),
+		WithConfig(config), WithAuthProvider(HeaderAuth{Name: "Authorization", Value: "Bearer user"})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "startExport", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	assert.Len(t, result.Content, 1, "status URLs on other hosts aren't tracked")
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "startJob", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.Len(t, result.Content, 2)
+	for range 2 {
+		result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "checkOperationStatus", Arguments: map[string]any{"url": api.URL + "/operations/42"}})
+		require.NoError(t, err)
+		require.False(t, result.IsError, "URLs of running operations are kept")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Bearer job-token", "Bearer job-token"}, statusAuth, "status is requested with the operation's credentials")
+}
+
+func TestAsyncOperationsLimit(t *testing.T) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	a := newAsyncOperations(server, nil, &registerToolsConfig{logger: slog.New(slog.DiscardHandler)}, asyncOperationToolName)
+	track := func(i int) {
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/jobs", nil)
+		resp := &http.Response{Header: http.Header{"Operation-Location": {fmt.Sprintf("/operations/%d", i)}}}
+		_, ok := a.track("session", req, resp, nil)
+		require.True(t, ok)
+	}
+	for i := range maxAsyncOperations + 1 {
+		track(i)
+	}
+	assert.Len(t, a.urls, maxAsyncOperations)
+	assert.Len(t, a.tracked, maxAsyncOperations)
+	assert.NotContains(t, a.urls, "session\x00https://api.example.com/operations/0", "the oldest URL is forgotten")
+}
+
+func TestRegisterToolsDeclaredAsyncOperation(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Jobs API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/jobs": {
+      "post": {
+        "operationId": "startJob",
+        "responses": {
+          "202": {
+            "description": "Accepted",
+            "headers": {"Azure-AsyncOperation": {"schema": {"type": "string"}}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), nil, WithToolPrefix("jobs_")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	assert.ElementsMatch(t, []string{"jobs_startJob", "jobs_checkOperationStatus"}, names)
+}
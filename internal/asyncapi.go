@@ -0,0 +1,404 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// asyncAPIDocument is an AsyncAPI document (https://www.asyncapi.com), version 2 or 3, decoded as generic values,
+// since only the parts that map to OpenAPI are used.
+type asyncAPIDocument struct {
+	root map[string]any
+	v3   bool
+}
+
+// asyncAPIOperation is an operation of an AsyncAPI document, on one of its channels.
+type asyncAPIOperation struct {
+	id       string
+	address  string
+	channel  map[string]any
+	op       map[string]any
+	messages []map[string]any
+	// sends is whether clients send the operation's messages, rather than receiving them:
+	// publish operations in AsyncAPI 2, and receive operations, those of the application receiving them, in AsyncAPI 3
+	sends bool
+}
+
+// IsAsyncAPI reports whether data is an AsyncAPI document rather than an OpenAPI spec.
+func IsAsyncAPI(data []byte) bool {
+	if !bytes.Contains(data, []byte("asyncapi")) {
+		return false
+	}
+	var doc struct {
+		AsyncAPI string `yaml:"asyncapi"`
+	}
+	return yaml.Unmarshal(data, &doc) == nil && doc.AsyncAPI != ""
+}
+
+// SpecFromAsyncAPI generates an OpenAPI spec, as JSON, for the operations of an AsyncAPI document
+// whose messages are exchanged over HTTP.
+//
+// Operations whose messages clients send (publish operations) become operations of the spec,
+// which send a message to the channel's address on the document's first HTTP server,
+// with the method of the operation's http binding, or POST.
+// Operations with mqtt bindings, for brokers that accept MQTT messages over HTTP,
+// take qos and retain query parameters.
+// Operations whose messages clients receive (subscribe operations) become webhooks,
+// which the API sends messages to.
+func SpecFromAsyncAPI(data []byte) ([]byte, error) {
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("error parsing AsyncAPI document: %w", err)
+	}
+	version, _ := root["asyncapi"].(string)
+	if version == "" {
+		return nil, fmt.Errorf("error parsing AsyncAPI document: no asyncapi version")
+	}
+	if !strings.HasPrefix(version, "2.") && !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported AsyncAPI version %s (expected 2.x or 3.x)", version)
+	}
+	doc := &asyncAPIDocument{root: root, v3: strings.HasPrefix(version, "3.")}
+
+	spec := map[string]any{"openapi": "3.1.0"}
+	info := map[string]any{"title": "AsyncAPI", "version": "0.0.0"}
+	if i, ok := root["info"].(map[string]any); ok {
+		for _, field := range []string{"title", "version", "description"} {
+			if v, ok := i[field]; ok {
+				info[field] = v
+			}
+		}
+	}
+	spec["info"] = info
+
+	server, protocol := doc.httpServer()
+	if server != nil {
+		spec["servers"] = []any{server}
+	}
+	// Schemas are JSON Schemas in both formats, so references to them are kept as they are
+	if components, ok := root["components"].(map[string]any); ok {
+		if schemas, ok := components["schemas"].(map[string]any); ok {
+			spec["components"] = map[string]any{"schemas": schemas}
+		}
+	}
+
+	paths := make(map[string]map[string]any)
+	webhooks := make(map[string]any)
+	for _, op := range doc.operations() {
+		if !op.sends {
+			webhooks[toolNameValue(op.id)] = map[string]any{"post": op.openAPIOperation(doc, "POST", "")}
+			continue
+		}
+		if server == nil {
+			return nil, fmt.Errorf("AsyncAPI operation %q: the document has no http or https server to send messages to", op.id)
+		}
+		method := "POST"
+		if binding, ok := doc.binding(op.op, "http"); ok {
+			if m, ok := binding["method"].(string); ok {
+				method = strings.ToUpper(m)
+			}
+		}
+		p := "/" + strings.TrimPrefix(op.address, "/")
+		if paths[p] == nil {
+			paths[p] = make(map[string]any)
+		}
+		paths[p][strings.ToLower(method)] = op.openAPIOperation(doc, method, protocol)
+	}
+	spec["paths"] = paths
+	if len(webhooks) > 0 {
+		spec["webhooks"] = webhooks
+	}
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+// resolve follows a value's local reference ($ref), like #/components/messages/userSignedUp, if it has one.
+// References to other documents are left as they are.
+func (d *asyncAPIDocument) resolve(v any) any {
+	for range 32 {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			return v
+		}
+		var target any = d.root
+		for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			if unescaped, err := url.PathUnescape(token); err == nil {
+				token = unescaped
+			}
+			obj, ok := target.(map[string]any)
+			if !ok {
+				return v
+			}
+			target = obj[token]
+		}
+		v = target
+	}
+	return v
+}
+
+// object returns a field of an object, with its reference resolved, if it's an object.
+func (d *asyncAPIDocument) object(m map[string]any, field string) map[string]any {
+	obj, _ := d.resolve(m[field]).(map[string]any)
+	return obj
+}
+
+// httpServer returns the OpenAPI server for the document's first HTTP server, by name, and its protocol.
+func (d *asyncAPIDocument) httpServer() (map[string]any, string) {
+	servers, _ := d.root["servers"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		s, _ := d.resolve(servers[name]).(map[string]any)
+		protocol, _ := s["protocol"].(string)
+		if protocol != "http" && protocol != "https" {
+			continue
+		}
+		var serverURL string
+		if d.v3 {
+			host, _ := s["host"].(string)
+			pathname, _ := s["pathname"].(string)
+			serverURL = protocol + "://" + host + pathname
+		} else {
+			serverURL, _ = s["url"].(string)
+			if !strings.Contains(serverURL, "://") {
+				serverURL = protocol + "://" + serverURL
+			}
+		}
+		server := map[string]any{"url": strings.TrimSuffix(serverURL, "/")}
+		if description, ok := s["description"].(string); ok {
+			server["description"] = description
+		}
+		// Server variables have the same form in both formats
+		if variables, ok := s["variables"].(map[string]any); ok {
+			resolved := make(map[string]any, len(variables))
+			for name, v := range variables {
+				variable, _ := d.resolve(v).(map[string]any)
+				if _, ok := variable["default"]; !ok {
+					if enum, ok := variable["enum"].([]any); ok && len(enum) > 0 {
+						variable["default"] = enum[0]
+					} else {
+						variable["default"] = ""
+					}
+				}
+				resolved[name] = variable
+			}
+			server["variables"] = resolved
+		}
+		return server, protocol
+	}
+	return nil, ""
+}
+
+// operations returns the document's operations, in order of their IDs.
+func (d *asyncAPIDocument) operations() []*asyncAPIOperation {
+	var ops []*asyncAPIOperation
+	channels, _ := d.root["channels"].(map[string]any)
+	if d.v3 {
+		operations, _ := d.root["operations"].(map[string]any)
+		for id, v := range operations {
+			op, _ := d.resolve(v).(map[string]any)
+			channel := d.object(op, "channel")
+			if op == nil || channel == nil {
+				continue
+			}
+			address, _ := channel["address"].(string)
+			action, _ := op["action"].(string)
+			o := &asyncAPIOperation{id: id, address: cmp.Or(address, id), channel: channel, op: op, sends: action == "receive"}
+			// Operations list the channel messages they use, or use them all
+			if refs, ok := op["messages"].([]any); ok {
+				for _, ref := range refs {
+					if m, ok := d.resolve(ref).(map[string]any); ok {
+						o.messages = append(o.messages, m)
+					}
+				}
+			} else if messages, ok := channel["messages"].(map[string]any); ok {
+				for _, name := range slices.Sorted(maps.Keys(messages)) {
+					if m, ok := d.resolve(messages[name]).(map[string]any); ok {
+						o.messages = append(o.messages, m)
+					}
+				}
+			}
+			ops = append(ops, o)
+		}
+	} else {
+		for address, v := range channels {
+			channel, _ := d.resolve(v).(map[string]any)
+			for _, action := range []string{"publish", "subscribe"} {
+				op := d.object(channel, action)
+				if op == nil {
+					continue
+				}
+				id, _ := op["operationId"].(string)
+				o := &asyncAPIOperation{id: cmp.Or(id, action+" "+address), address: address, channel: channel, op: op, sends: action == "publish"}
+				// Messages are a single message, or a choice of them
+				if message := d.object(op, "message"); message != nil {
+					if choices, ok := message["oneOf"].([]any); ok {
+						for _, choice := range choices {
+							if m, ok := d.resolve(choice).(map[string]any); ok {
+								o.messages = append(o.messages, m)
+							}
+						}
+					} else {
+						o.messages = append(o.messages, message)
+					}
+				}
+				ops = append(ops, o)
+			}
+		}
+	}
+	slices.SortFunc(ops, func(a, b *asyncAPIOperation) int { return strings.Compare(a.id, b.id) })
+	return ops
+}
+
+// binding returns the bindings of an operation, channel, or message for a protocol, like http or mqtt.
+func (d *asyncAPIDocument) binding(m map[string]any, protocol string) (map[string]any, bool) {
+	bindings := d.object(m, "bindings")
+	if bindings == nil {
+		return nil, false
+	}
+	binding, ok := d.resolve(bindings[protocol]).(map[string]any)
+	return binding, ok
+}
+
+// openAPIOperation returns the OpenAPI operation that sends or receives an operation's messages.
+// The channel's parameters are path parameters, and the messages' headers are header parameters.
+// The messages' payloads are the request body, which methods like GET don't send.
+func (o *asyncAPIOperation) openAPIOperation(d *asyncAPIDocument, method, protocol string) map[string]any {
+	operation := map[string]any{
+		"operationId": toolNameValue(o.id),
+		"responses":   map[string]any{"default": map[string]any{"description": "Response"}},
+	}
+	for _, field := range []string{"summary", "description"} {
+		op, _ := o.op[field].(string)
+		channel, _ := o.channel[field].(string)
+		if s := cmp.Or(op, channel); s != "" {
+			operation[field] = s
+		}
+	}
+	if tags, ok := o.op["tags"].([]any); ok {
+		var names []any
+		for _, tag := range tags {
+			if t, ok := d.resolve(tag).(map[string]any); ok && t["name"] != nil {
+				names = append(names, t["name"])
+			}
+		}
+		if len(names) > 0 {
+			operation["tags"] = names
+		}
+	}
+
+	var parameters []any
+	channelParams, _ := o.channel["parameters"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(channelParams)) {
+		param, _ := d.resolve(channelParams[name]).(map[string]any)
+		schema, ok := d.resolve(param["schema"]).(map[string]any)
+		if !ok {
+			// AsyncAPI 3 parameters are strings, with their allowed values alongside them
+			schema = map[string]any{"type": "string"}
+			for _, field := range []string{"enum", "default", "examples"} {
+				if v, ok := param[field]; ok {
+					schema[field] = v
+				}
+			}
+		}
+		p := map[string]any{"name": name, "in": "path", "required": true, "schema": schema}
+		if description, ok := param["description"].(string); ok {
+			p["description"] = description
+		}
+		parameters = append(parameters, p)
+	}
+	for _, message := range o.messages {
+		headers := d.object(message, "headers")
+		properties, _ := headers["properties"].(map[string]any)
+		required, _ := headers["required"].([]any)
+		for _, name := range slices.Sorted(maps.Keys(properties)) {
+			if slices.ContainsFunc(parameters, func(p any) bool { return p.(map[string]any)["name"] == name }) {
+				continue
+			}
+			parameters = append(parameters, map[string]any{
+				"name": name, "in": "header", "required": slices.Contains(required, any(name)), "schema": d.resolve(properties[name]),
+			})
+		}
+	}
+	if binding, ok := d.binding(o.op, "http"); ok {
+		query := d.object(binding, "query")
+		properties, _ := query["properties"].(map[string]any)
+		required, _ := query["required"].([]any)
+		for _, name := range slices.Sorted(maps.Keys(properties)) {
+			parameters = append(parameters, map[string]any{
+				"name": name, "in": "query", "required": slices.Contains(required, any(name)), "schema": d.resolve(properties[name]),
+			})
+		}
+	}
+	// Brokers that accept MQTT messages over HTTP take their quality of service and retain flag as query parameters
+	if binding, ok := d.binding(o.op, "mqtt"); ok && protocol != "" {
+		qos := map[string]any{"type": "integer", "enum": []any{0, 1, 2}}
+		if v, ok := binding["qos"]; ok {
+			qos["default"] = v
+		}
+		retain := map[string]any{"type": "boolean"}
+		if v, ok := binding["retain"]; ok {
+			retain["default"] = v
+		}
+		parameters = append(parameters,
+			map[string]any{"name": "qos", "in": "query", "schema": qos},
+			map[string]any{"name": "retain", "in": "query", "schema": retain})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	switch method {
+	case "GET", "HEAD", "DELETE", "OPTIONS":
+		return operation
+	}
+	if body := d.requestBody(o.messages); body != nil {
+		operation["requestBody"] = body
+	}
+	return operation
+}
+
+// requestBody returns the OpenAPI request body for a choice of messages, by content type,
+// or nil if none of them have a payload.
+func (d *asyncAPIDocument) requestBody(messages []map[string]any) map[string]any {
+	defaultContentType, _ := d.root["defaultContentType"].(string)
+	payloads := make(map[string][]any)
+	var contentTypes []string
+	for _, message := range messages {
+		payload := message["payload"]
+		if payload == nil {
+			continue
+		}
+		// Payloads of AsyncAPI 3 may be wrapped in a Multi Format Schema, giving their format
+		if wrapped, ok := d.resolve(payload).(map[string]any); ok && wrapped["schemaFormat"] != nil {
+			payload = wrapped["schema"]
+		}
+		contentType, _ := message["contentType"].(string)
+		contentType = cmp.Or(contentType, defaultContentType, "application/json")
+		if _, ok := payloads[contentType]; !ok {
+			contentTypes = append(contentTypes, contentType)
+		}
+		payloads[contentType] = append(payloads[contentType], payload)
+	}
+	if len(contentTypes) == 0 {
+		return nil
+	}
+	content := make(map[string]any)
+	for _, contentType := range contentTypes {
+		schema := payloads[contentType][0]
+		if len(payloads[contentType]) > 1 {
+			schema = map[string]any{"oneOf": payloads[contentType]}
+		}
+		content[contentType] = map[string]any{"schema": schema}
+	}
+	return map[string]any{"required": true, "content": content}
+}
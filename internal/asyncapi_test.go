@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecFromAsyncAPI2(t *testing.T) {
+	var received []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Correlation-Id")+" "+string(body))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer api.Close()
+
+	doc := `
+asyncapi: 2.6.0
+info:
+  title: Pet Events
+  version: 1.0.0
+servers:
+  broker:
+    url: ` + api.URL + `
+    protocol: http
+  kafka:
+    url: kafka.example.com:9092
+    protocol: kafka
+channels:
+  pets/{petId}/feedings:
+    parameters:
+      petId:
+        schema: {type: integer}
+    publish:
+      operationId: feedPet
+      summary: Record that a pet was fed
+      bindings:
+        http: {type: request, method: POST}
+      message:
+        $ref: '#/components/messages/feeding'
+  pets/adopted:
+    subscribe:
+      operationId: petAdopted
+      summary: A pet was adopted
+      message:
+        payload:
+          $ref: '#/components/schemas/pet'
+components:
+  messages:
+    feeding:
+      headers:
+        type: object
+        properties:
+          X-Correlation-Id: {type: string}
+      payload:
+        type: object
+        required: [food]
+        properties:
+          food: {type: string}
+          grams: {type: integer}
+  schemas:
+    pet:
+      type: object
+      properties:
+        id: {type: integer}
+        name: {type: string}
+`
+	require.True(t, IsAsyncAPI([]byte(doc)))
+	assert.False(t, IsAsyncAPI([]byte(`{"openapi": "3.1.0"}`)))
+
+	specData, err := SpecFromAsyncAPI([]byte(doc))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receiver, err := NewWebhookReceiver("127.0.0.1:0", "", nil)
+	require.NoError(t, err)
+	go func() { _ = receiver.Serve(ctx) }()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, specData, api.Client(), WithWebhooks(receiver)))
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	assert.Contains(t, names, "feedPet")
+	assert.NotContains(t, names, "petAdopted", "operations whose messages clients receive aren't tools")
+
+	resources, err := clientSession.ListResources(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, resources.Resources, 1)
+	assert.Equal(t, "emcee://webhooks/petAdopted", resources.Resources[0].URI)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "feedPet", Arguments: map[string]any{
+		"petId": 7, "food": "kibble", "grams": 100, "X-Correlation-Id": "abc",
+	}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, received, 1)
+	method, rest, _ := strings.Cut(received[0], " ")
+	assert.Equal(t, "POST", method)
+	p, rest, _ := strings.Cut(rest, " ")
+	assert.Equal(t, "/pets/7/feedings", p)
+	correlationID, body, _ := strings.Cut(rest, " ")
+	assert.Equal(t, "abc", correlationID)
+	assert.JSONEq(t, `{"food": "kibble", "grams": 100}`, body)
+}
+
+func TestSpecFromAsyncAPI3(t *testing.T) {
+	doc := `{
+  "asyncapi": "3.0.0",
+  "info": {"title": "Thermostats", "version": "2.0.0"},
+  "defaultContentType": "application/json",
+  "servers": {
+    "iot": {"host": "iot.example.com", "pathname": "/topics", "protocol": "https"}
+  },
+  "channels": {
+    "setpoint": {
+      "address": "devices/{deviceId}/setpoint",
+      "parameters": {"deviceId": {"description": "The thermostat's ID"}},
+      "messages": {"setpoint": {"payload": {"type": "object", "properties": {"celsius": {"type": "number"}}}}}
+    },
+    "readings": {
+      "address": "devices/readings",
+      "messages": {"reading": {"payload": {"type": "object"}}}
+    }
+  },
+  "operations": {
+    "setTemperature": {
+      "action": "receive",
+      "channel": {"$ref": "#/channels/setpoint"},
+      "bindings": {"mqtt": {"qos": 1}}
+    },
+    "onReading": {
+      "action": "send",
+      "channel": {"$ref": "#/channels/readings"}
+    }
+  }
+}`
+	specData, err := SpecFromAsyncAPI([]byte(doc))
+	require.NoError(t, err)
+
+	var spec struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths    map[string]map[string]map[string]any `json:"paths"`
+		Webhooks map[string]any                       `json:"webhooks"`
+	}
+	require.NoError(t, json.Unmarshal(specData, &spec))
+	require.Len(t, spec.Servers, 1)
+	assert.Equal(t, "https://iot.example.com/topics", spec.Servers[0].URL)
+	assert.Contains(t, spec.Webhooks, "onReading")
+
+	op := spec.Paths["/devices/{deviceId}/setpoint"]["post"]
+	require.NotNil(t, op)
+	params, err := json.Marshal(op["parameters"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+  {"name": "deviceId", "in": "path", "required": true, "description": "The thermostat's ID", "schema": {"type": "string"}},
+  {"name": "qos", "in": "query", "schema": {"type": "integer", "enum": [0, 1, 2], "default": 1}},
+  {"name": "retain", "in": "query", "schema": {"type": "boolean"}}
+]`, string(params), "brokers that accept MQTT messages over HTTP take qos and retain parameters")
+
+	tools, err := ListTools(context.Background(), specData)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "setTemperature", tools[0].Name)
+	assert.Contains(t, tools[0].InputSchema.Properties, "celsius")
+}
+
+func TestSpecFromAsyncAPIWithoutHTTPServer(t *testing.T) {
+	_, err := SpecFromAsyncAPI([]byte(`
+asyncapi: 2.6.0
+info: {title: Events, version: 1.0.0}
+servers:
+  kafka: {url: kafka.example.com:9092, protocol: kafka}
+channels:
+  events:
+    publish:
+      operationId: sendEvent
+      message: {payload: {type: object}}
+`))
+	assert.ErrorContains(t, err, "no http or https server")
+
+	_, err = SpecFromAsyncAPI([]byte("asyncapi: 1.2.0\n"))
+	assert.ErrorContains(t, err, "unsupported AsyncAPI version")
+}
@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// AuditLog records every tool call as a line of JSON,
+// so that operators can review exactly what was done against their API.
+type AuditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewAuditLog returns an audit log that writes records to w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{w: w}
+}
+
+// OpenAuditLog opens a file for appending audit records, creating it if it doesn't exist.
+// Only the file's owner can read it, since records may contain personal data returned by the API.
+func OpenAuditLog(name string) (*AuditLog, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	return NewAuditLog(f), nil
+}
+
+// Close closes the log's writer, if it's an io.Closer.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// WithAuditLog records every call to a generated tool in log,
+// with its arguments, secret arguments redacted, and the request it sent to the API.
+func WithAuditLog(log *AuditLog) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.auditLog = log }
+}
+
+// auditRecord is a line of the audit log.
+type auditRecord struct {
+	Time      time.Time      `json:"time"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	// RequestID is the correlation ID of the call, as logged and sent to the API
+	RequestID string `json:"requestId,omitempty"`
+	// Method and URL are those of the request sent to the API, if any
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Preview is true for calls that returned their request instead of sending it, like dry runs
+	Preview bool `json:"preview,omitempty"`
+	Status  int  `json:"status,omitempty"`
+	// LatencyMs is the time from sending the request until its response was read
+	LatencyMs    int64  `json:"latencyMs"`
+	ResponseSize int64  `json:"responseSize"`
+	IsError      bool   `json:"isError,omitempty"`
+	Error        string `json:"error,omitempty"`
+
+	secrets secretArguments
+	sentAt  time.Time
+}
+
+type auditRecordKey struct{}
+
+// auditRecordFrom returns the audit record of the tool call handled with ctx, or nil if calls aren't audited.
+func auditRecordFrom(ctx context.Context) *auditRecord {
+	r, _ := ctx.Value(auditRecordKey{}).(*auditRecord)
+	return r
+}
+
+// audited returns a tool handler that records each call it handles in the audit log.
+// The handler fills in the details of the request it sends using the record in its context.
+func (cfg *registerToolsConfig) audited(toolName string, handler mcp.ToolHandler) mcp.ToolHandler {
+	log := cfg.auditLog
+	if log == nil {
+		return handler
+	}
+	return func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+		record := &auditRecord{Time: time.Now().UTC(), Tool: toolName, secrets: cfg.secretArguments}
+		if req.Params != nil {
+			record.Arguments = cfg.secretArguments.redact(toolName, req.Params.Arguments)
+		}
+		result, err := handler(context.WithValue(ctx, auditRecordKey{}, record), req)
+		if err != nil {
+			record.IsError = true
+			record.Error = err.Error()
+		} else if result != nil {
+			record.IsError = result.IsError
+		}
+		if werr := log.write(record); werr != nil {
+			cfg.logger.Warn("error writing audit log", "tool", toolName, "error", werr)
+		}
+		return result, err
+	}
+}
+
+// write appends a record to the log.
+func (l *AuditLog) write(r *auditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(append(data, '\n'))
+	return err
+}
+
+// sent records a request as it's sent to the API, with the values of secret arguments and credentials in its query redacted.
+func (r *auditRecord) sent(req *http.Request) {
+	if r == nil {
+		return
+	}
+	r.sentAt = time.Now()
+	r.Method = req.Method
+	u := *req.URL
+	if query := u.Query(); len(query) > 0 {
+		for name := range query {
+			if r.secrets.contains(r.Tool, name) || isSecretName(name) {
+				query.Set(name, redactedValue)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	r.URL = u.String()
+}
+
+// previewed records a request that was returned instead of being sent.
+func (r *auditRecord) previewed(req *http.Request) {
+	if r == nil {
+		return
+	}
+	r.sent(req)
+	r.Preview = true
+}
+
+// received records the status of a response, and wraps its body to count the bytes read from it.
+// The latency is measured when the body is closed.
+func (r *auditRecord) received(resp *http.Response) {
+	if r == nil {
+		return
+	}
+	r.Status = resp.StatusCode
+	resp.Body = &auditedBody{ReadCloser: resp.Body, record: r}
+}
+
+// auditedBody counts the bytes read from a response body for its audit record.
+type auditedBody struct {
+	io.ReadCloser
+	record *auditRecord
+	closed bool
+}
+
+func (b *auditedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.record.ResponseSize += int64(n)
+	return n, err
+}
+
+func (b *auditedBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		b.record.LatencyMs = time.Since(b.record.sentAt).Milliseconds()
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithAuditLog(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/pets/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "1", "name": "Fido"}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {"get": {
+      "operationId": "getPet",
+      "parameters": [
+        {"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}},
+        {"name": "token", "in": "query", "schema": {"type": "string"}}
+      ],
+      "responses": {"200": {"description": "OK"}}
+    }}
+  }
+}`, api.URL)
+
+	var buf bytes.Buffer
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient,
+		WithAuditLog(NewAuditLog(&buf)),
+		WithSecretArguments("token"),
+		WithDryRunArgument(),
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	for _, args := range []map[string]any{
+		{"petId": "1", "token": "hunter2"},
+		{"petId": "missing"},
+		{"petId": "1", dryRunArgument: true},
+	} {
+		_, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: args})
+		require.NoError(t, err)
+	}
+
+	assert.NotContains(t, buf.String(), "hunter2", "secret arguments are redacted")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	var records []map[string]any
+	for _, line := range lines {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.NotEmpty(t, record["time"])
+		assert.Equal(t, "getPet", record["tool"])
+		records = append(records, record)
+	}
+
+	assert.Equal(t, map[string]any{"petId": "1", "token": redactedValue}, records[0]["arguments"])
+	assert.Equal(t, "GET", records[0]["method"])
+	assert.Equal(t, api.URL+"/pets/1?token=%5BREDACTED%5D", records[0]["url"])
+	assert.Equal(t, float64(http.StatusOK), records[0]["status"])
+	assert.Equal(t, float64(len(`{"id": "1", "name": "Fido"}`)), records[0]["responseSize"])
+	assert.Contains(t, records[0], "latencyMs")
+	assert.NotContains(t, records[0], "isError")
+
+	assert.Equal(t, float64(http.StatusNotFound), records[1]["status"])
+	assert.Equal(t, true, records[1]["isError"])
+
+	assert.Equal(t, true, records[2]["preview"])
+	assert.Equal(t, api.URL+"/pets/1", records[2]["url"])
+	assert.NotContains(t, records[2], "status", "previewed requests aren't sent")
+}
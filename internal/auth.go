@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// AuthProvider authenticates requests to the API.
+// Implement it to support authentication schemes other than the built-in ones.
+type AuthProvider interface {
+	// Apply adds credentials to a request.
+	Apply(ctx context.Context, req *http.Request) error
+	// Refresh renews credentials after the API rejects them.
+	// It returns ErrAuthNotRefreshable if the credentials can't be renewed.
+	Refresh(ctx context.Context) error
+}
+
+// ErrAuthNotRefreshable is returned by AuthProvider.Refresh for credentials that can't be renewed.
+var ErrAuthNotRefreshable = errors.New("credentials can't be refreshed")
+
+// WithAuthProvider authenticates every request to the API using provider.
+// When the API responds with 401 Unauthorized, the provider's credentials are refreshed
+// and the request is retried once.
+func WithAuthProvider(provider AuthProvider) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.auth = provider }
+}
+
+// AuthTransport is a RoundTripper that authenticates requests using an AuthProvider.
+type AuthTransport struct {
+	Base     http.RoundTripper
+	Provider AuthProvider
+}
+
+// RoundTrip authenticates the request, and retries it once with refreshed credentials if the API responds with 401.
+// Requests whose body can't be replayed aren't retried.
+func (t *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	authed := req.Clone(req.Context())
+	if err := t.Provider.Apply(req.Context(), authed); err != nil {
+		return nil, err
+	}
+	resp, err := base.RoundTrip(authed)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	if err := t.Provider.Refresh(req.Context()); err != nil {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.Provider.Apply(req.Context(), retry); err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(retry)
+}
+
+// authClient returns a copy of client that authenticates requests using provider.
+func authClient(client *http.Client, provider AuthProvider) *http.Client {
+	authed := *client
+	authed.Transport = &AuthTransport{Base: client.Transport, Provider: provider}
+	return &authed
+}
+
+// HeaderAuth sets a request header to a fixed value, like a bearer token.
+type HeaderAuth struct {
+	Name  string
+	Value string
+}
+
+// Apply sets the header.
+func (a HeaderAuth) Apply(ctx context.Context, req *http.Request) error {
+	req.Header.Set(a.Name, a.Value)
+	return nil
+}
+
+// Refresh returns ErrAuthNotRefreshable, since the value is fixed.
+func (a HeaderAuth) Refresh(ctx context.Context) error {
+	return ErrAuthNotRefreshable
+}
+
+// SecretHeaderAuth sets a request header to a value derived from a secret reference, like op://vault/item/field.
+// The reference is resolved on first use, and again when refreshed, so that rotated secrets are picked up.
+type SecretHeaderAuth struct {
+	Name      string
+	Reference string
+	// Format converts the secret into the header value. If nil, the secret is used as is.
+	Format func(secret string) string
+
+	mu    sync.Mutex
+	value string
+}
+
+// Apply sets the header, resolving the secret reference if it hasn't been resolved yet.
+func (a *SecretHeaderAuth) Apply(ctx context.Context, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.value == "" {
+		if err := a.resolve(ctx); err != nil {
+			return err
+		}
+	}
+	req.Header.Set(a.Name, a.value)
+	return nil
+}
+
+// Refresh resolves the secret reference again.
+func (a *SecretHeaderAuth) Refresh(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.resolve(ctx)
+}
+
+func (a *SecretHeaderAuth) resolve(ctx context.Context) error {
+	secret, _, err := ResolveSecretReference(ctx, a.Reference)
+	if err != nil {
+		return err
+	}
+	if a.Format != nil {
+		secret = a.Format(secret)
+	}
+	a.value = secret
+	return nil
+}
+
+// APIKeyAuth sends an API key in a header, query parameter, or cookie, as described by an apiKey security scheme.
+type APIKeyAuth struct {
+	// In is where the key is sent: "header", "query", or "cookie".
+	In    string
+	Name  string
+	Value string
+}
+
+// Apply adds the key to the request.
+func (a APIKeyAuth) Apply(ctx context.Context, req *http.Request) error {
+	switch a.In {
+	case "header":
+		req.Header.Set(a.Name, a.Value)
+	case "query":
+		q := req.URL.Query()
+		q.Set(a.Name, a.Value)
+		req.URL.RawQuery = q.Encode()
+	case "cookie":
+		req.AddCookie(&http.Cookie{Name: a.Name, Value: a.Value})
+	default:
+		return fmt.Errorf("unsupported API key location %q", a.In)
+	}
+	return nil
+}
+
+// Refresh returns ErrAuthNotRefreshable, since the key is fixed.
+func (a APIKeyAuth) Refresh(ctx context.Context) error {
+	return ErrAuthNotRefreshable
+}
+
+// WithAPIKey authenticates every request to the API with key,
+// sent where the spec's apiKey security scheme says: in a header, a query parameter, or a cookie.
+// Schemes required by the spec's top-level security requirements are preferred.
+// It's an error for the spec not to declare an apiKey security scheme.
+func WithAPIKey(key string) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.apiKey = key }
+}
+
+// apiKeyScheme returns the apiKey security scheme of a spec,
+// preferring one required by its top-level security requirements.
+func apiKeyScheme(doc *v3.Document) (*v3.SecurityScheme, bool) {
+	return securityScheme(doc, func(scheme *v3.SecurityScheme) bool {
+		return scheme.Type == "apiKey" && scheme.Name != "" && slices.Contains([]string{"header", "query", "cookie"}, scheme.In)
+	})
+}
+
+// securityScheme returns a security scheme of a spec that matches,
+// preferring one required by its top-level security requirements.
+func securityScheme(doc *v3.Document, match func(*v3.SecurityScheme) bool) (*v3.SecurityScheme, bool) {
+	if doc.Components == nil || doc.Components.SecuritySchemes == nil {
+		return nil, false
+	}
+	matches := func(scheme *v3.SecurityScheme) bool {
+		return scheme != nil && match(scheme)
+	}
+	for _, requirement := range doc.Security {
+		if requirement == nil || requirement.Requirements == nil {
+			continue
+		}
+		for name := range requirement.Requirements.KeysFromOldest() {
+			if scheme, ok := doc.Components.SecuritySchemes.Get(name); ok && matches(scheme) {
+				return scheme, true
+			}
+		}
+	}
+	for scheme := range doc.Components.SecuritySchemes.ValuesFromOldest() {
+		if matches(scheme) {
+			return scheme, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatingAuth is an AuthProvider whose token changes each time it's refreshed.
+type rotatingAuth struct {
+	version atomic.Int32
+}
+
+func (a *rotatingAuth) Apply(ctx context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer token-%d", a.version.Load()))
+	return nil
+}
+
+func (a *rotatingAuth) Refresh(ctx context.Context) error {
+	a.version.Add(1)
+	return nil
+}
+
+func TestAuthTransport(t *testing.T) {
+	var bodies []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer api.Close()
+
+	auth := &rotatingAuth{}
+	client := &http.Client{Transport: &AuthTransport{Provider: auth}}
+	req, err := http.NewRequest(http.MethodPost, api.URL, strings.NewReader(`{"name":"Fido"}`))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the request is retried with refreshed credentials")
+	assert.Equal(t, []string{`{"name":"Fido"}`, `{"name":"Fido"}`}, bodies, "the body is replayed")
+	assert.Empty(t, req.Header.Get("Authorization"), "the original request isn't modified")
+
+	client = &http.Client{Transport: &AuthTransport{Provider: HeaderAuth{Name: "Authorization", Value: "Bearer wrong"}}}
+	resp, err = client.Get(api.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "fixed credentials aren't retried")
+	assert.Len(t, bodies, 3)
+}
+
+func TestSecretHeaderAuth(t *testing.T) {
+	originalCommand := CommandContext
+	originalLookPath := LookPath
+	t.Cleanup(func() {
+		CommandContext = originalCommand
+		LookPath = originalLookPath
+	})
+	var secret atomic.Value
+	secret.Store("first")
+	LookPath = func(string) (string, error) { return "/usr/local/bin/op", nil }
+	CommandContext = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "echo", secret.Load().(string))
+	}
+
+	auth := &SecretHeaderAuth{Name: "X-API-Key", Reference: "op://vault/item/key", Format: strings.ToUpper}
+	req := httptest.NewRequest(http.MethodGet, "https://api.example.com", nil)
+	require.NoError(t, auth.Apply(context.Background(), req))
+	assert.Equal(t, "FIRST", req.Header.Get("X-API-Key"))
+
+	secret.Store("second")
+	require.NoError(t, auth.Apply(context.Background(), req))
+	assert.Equal(t, "FIRST", req.Header.Get("X-API-Key"), "the secret is resolved once")
+
+	require.NoError(t, auth.Refresh(context.Background()))
+	require.NoError(t, auth.Apply(context.Background(), req))
+	assert.Equal(t, "SECOND", req.Header.Get("X-API-Key"), "refreshing picks up a rotated secret")
+}
+
+func TestRegisterToolsWithAuthProvider(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}}
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithAuthProvider(&rotatingAuth{})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want func(r *http.Request) string
+	}{
+		{"header", func(r *http.Request) string { return r.Header.Get("X-API-Key") }},
+		{"query", func(r *http.Request) string { return r.URL.Query().Get("X-API-Key") }},
+		{"cookie", func(r *http.Request) string {
+			cookie, err := r.Cookie("X-API-Key")
+			if err != nil {
+				return ""
+			}
+			return cookie.Value
+		}},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "https://api.example.com/pets?limit=10", nil)
+		require.NoError(t, APIKeyAuth{In: tt.in, Name: "X-API-Key", Value: "secret"}.Apply(context.Background(), req))
+		assert.Equal(t, "secret", tt.want(req), tt.in)
+		assert.Equal(t, "10", req.URL.Query().Get("limit"), tt.in)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "https://api.example.com/pets", nil)
+	assert.Error(t, APIKeyAuth{In: "body", Name: "key", Value: "secret"}.Apply(context.Background(), req))
+}
+
+func TestRegisterToolsWithCookies(t *testing.T) {
+	var received *http.Request
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123", Path: "/"})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "security": [{"cookieKey": []}],
+  "components": {"securitySchemes": {
+    "headerKey": {"type": "apiKey", "in": "header", "name": "X-API-Key"},
+    "cookieKey": {"type": "apiKey", "in": "cookie", "name": "api_key"}
+  }},
+  "paths": {
+    "/login": {"post": {"operationId": "login", "responses": {"200": {"description": "OK"}}}},
+    "/pets": {"get": {
+      "operationId": "listPets",
+      "parameters": [{"name": "theme", "in": "cookie", "schema": {"type": "string"}}],
+      "responses": {"200": {"description": "OK"}}
+    }}
+  }
+}`, api.URL)
+
+	client, err := RetryableClient(RetryableClientOptions{Cookies: true})
+	require.NoError(t, err)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), client, WithAPIKey("secret")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	cookie := func(name string) string {
+		c, err := received.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return c.Value
+	}
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets", Arguments: map[string]any{"theme": "dark"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "dark", cookie("theme"), "cookie parameters are sent as cookies")
+	assert.Equal(t, "secret", cookie("api_key"), "the required apiKey scheme is used")
+	assert.Empty(t, received.Header.Get("X-API-Key"))
+	assert.Empty(t, cookie("session"))
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "login"})
+	require.NoError(t, err)
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", cookie("session"), "cookies set by the API are sent with later requests")
+	assert.Equal(t, "secret", cookie("api_key"))
+
+	noScheme := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}}
+}`, api.URL)
+	err = RegisterTools(mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil), []byte(noScheme), client, WithAPIKey("secret"))
+	assert.ErrorContains(t, err, "no apiKey security scheme")
+}
@@ -0,0 +1,270 @@
+package internal
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// AuthOverride sets the credentials sent with calls of the operations and paths it names,
+// in place of those given for every request, like a different token for admin endpoints.
+// Exactly one of Bearer, Basic, Raw, APIKey, and None is set, and each value can be a secret reference.
+type AuthOverride struct {
+	// Operations lists the operations, by ID, that the credentials are sent to.
+	Operations []string `yaml:"operations" json:"operations,omitempty"`
+	// Paths lists paths whose operations the credentials are sent to, including those of paths beneath them.
+	// Paths may contain wildcards, as in path.Match (e.g. "/admin/*").
+	Paths []string `yaml:"paths" json:"paths,omitempty"`
+	// Bearer is a token, sent in the Authorization header with the Bearer scheme.
+	Bearer string `yaml:"bearer" json:"bearer,omitempty"`
+	// Basic is user:pass, or its base64 encoding, sent in the Authorization header with the Basic scheme.
+	Basic string `yaml:"basic" json:"basic,omitempty"`
+	// Raw is the value of the Authorization header.
+	Raw string `yaml:"raw" json:"raw,omitempty"`
+	// APIKey is a key, sent where the spec's apiKey security scheme says.
+	APIKey string `yaml:"apiKey" json:"apiKey,omitempty"`
+	// Scheme names the apiKey security scheme of the spec that APIKey is sent as,
+	// for specs that declare several. If empty, it's the one --api-key uses.
+	Scheme string `yaml:"scheme" json:"scheme,omitempty"`
+	// None sends no credentials, for operations that must be called without them, like a public login endpoint.
+	None bool `yaml:"none" json:"none,omitempty"`
+}
+
+// validate checks that the override names operations or paths, and sets exactly one kind of credentials.
+func (o *AuthOverride) validate() error {
+	if len(o.Operations) == 0 && len(o.Paths) == 0 {
+		return fmt.Errorf("names no operations or paths")
+	}
+	for _, pattern := range o.Paths {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("invalid path %q", pattern)
+		}
+	}
+	set := 0
+	for _, value := range []string{o.Bearer, o.Basic, o.Raw, o.APIKey} {
+		if value != "" {
+			set++
+		}
+	}
+	if o.None {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("must set exactly one of bearer, basic, raw, apiKey, and none")
+	}
+	if o.Scheme != "" && o.APIKey == "" {
+		return fmt.Errorf("scheme is only used with apiKey")
+	}
+	return nil
+}
+
+// applies reports whether the override's credentials are sent with calls of an operation.
+func (o *AuthOverride) applies(p, operationID string) bool {
+	if operationID != "" && slices.Contains(o.Operations, operationID) {
+		return true
+	}
+	return slices.ContainsFunc(o.Paths, func(pattern string) bool { return matchPathPrefix(pattern, p) })
+}
+
+// operationAuths are the credentials a configuration file sets for operations and paths,
+// in the order they're listed, so that the first that applies to an operation is used.
+type operationAuths []operationAuthEntry
+
+type operationAuthEntry struct {
+	override *AuthOverride
+	provider AuthProvider
+}
+
+// operationAuths returns the credentials the configuration sets for operations and paths of a spec,
+// and the headers they're sent in, which dry runs redact.
+// It's an error for an API key to name a scheme the spec doesn't declare.
+func (c *Config) operationAuths(doc *v3.Document) (operationAuths, []string, error) {
+	if c == nil || len(c.Auth) == 0 {
+		return nil, nil, nil
+	}
+	var auths operationAuths
+	var headers []string
+	for i, o := range c.Auth {
+		var apply func(req *http.Request, secret string) error
+		switch {
+		case o.Bearer != "":
+			apply = authorizationHeader(func(token string) string { return "Bearer " + token })
+		case o.Basic != "":
+			apply = authorizationHeader(func(credentials string) string {
+				if strings.Contains(credentials, ":") {
+					credentials = base64.StdEncoding.EncodeToString([]byte(credentials))
+				}
+				return "Basic " + credentials
+			})
+		case o.Raw != "":
+			apply = authorizationHeader(func(value string) string { return value })
+		case o.APIKey != "":
+			scheme, ok := apiKeyScheme(doc)
+			if o.Scheme != "" {
+				scheme, ok = nil, false
+				if doc.Components != nil && doc.Components.SecuritySchemes != nil {
+					scheme, ok = doc.Components.SecuritySchemes.Get(o.Scheme)
+				}
+				if !ok || scheme == nil || scheme.Type != "apiKey" {
+					return nil, nil, fmt.Errorf("auth[%d]: the spec declares no apiKey security scheme %q", i, o.Scheme)
+				}
+			}
+			if !ok {
+				return nil, nil, fmt.Errorf("auth[%d]: an API key was provided, but the spec declares no apiKey security scheme", i)
+			}
+			in, name := scheme.In, scheme.Name
+			apply = func(req *http.Request, key string) error {
+				return APIKeyAuth{In: in, Name: name, Value: key}.Apply(req.Context(), req)
+			}
+			if in == "header" {
+				headers = append(headers, http.CanonicalHeaderKey(name))
+			}
+		}
+		value := o.Bearer + o.Basic + o.Raw + o.APIKey
+		auths = append(auths, operationAuthEntry{override: o, provider: &overrideAuth{value: value, apply: apply}})
+	}
+	return auths, headers, nil
+}
+
+// provider returns the provider of the credentials sent with calls of an operation, if the configuration sets any.
+func (a operationAuths) provider(p, operationID string) (AuthProvider, bool) {
+	for _, entry := range a {
+		if entry.override.applies(p, operationID) {
+			return entry.provider, true
+		}
+	}
+	return nil, false
+}
+
+// authorizationHeader returns a function that sets the Authorization header to a secret, converted by format.
+func authorizationHeader(format func(string) string) func(req *http.Request, secret string) error {
+	return func(req *http.Request, secret string) error {
+		req.Header.Set("Authorization", format(secret))
+		return nil
+	}
+}
+
+// overrideAuth applies the credentials of an AuthOverride.
+// A secret reference is resolved on first use, and again when refreshed, so that rotated secrets are picked up.
+type overrideAuth struct {
+	value string
+	// apply adds the credentials to a request. If nil, none are sent.
+	apply func(req *http.Request, secret string) error
+
+	mu     sync.Mutex
+	secret string
+}
+
+// Apply adds the credentials to a request, resolving the secret reference if it hasn't been resolved yet.
+func (a *overrideAuth) Apply(ctx context.Context, req *http.Request) error {
+	if a.apply == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.secret == "" {
+		if err := a.resolve(ctx); err != nil {
+			return err
+		}
+	}
+	return a.apply(req, a.secret)
+}
+
+// Refresh resolves the secret reference again, or returns ErrAuthNotRefreshable if the value isn't one.
+func (a *overrideAuth) Refresh(ctx context.Context) error {
+	if a.apply == nil || !IsSecretReference(a.value) {
+		return ErrAuthNotRefreshable
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.resolve(ctx)
+}
+
+func (a *overrideAuth) resolve(ctx context.Context) error {
+	if !IsSecretReference(a.value) {
+		a.secret = a.value
+		return nil
+	}
+	secret, _, err := ResolveSecretReference(ctx, a.value)
+	if err != nil {
+		return fmt.Errorf("error resolving credentials set by config: %w", err)
+	}
+	a.secret = secret
+	return nil
+}
+
+// operationAuthKey is the context key of the provider of the credentials the configuration sets for the operation a request calls.
+type operationAuthKey struct{}
+
+// contextWithOperationAuth returns a context for requests that call an operation whose credentials the configuration sets.
+func contextWithOperationAuth(ctx context.Context, provider AuthProvider) context.Context {
+	return context.WithValue(ctx, operationAuthKey{}, provider)
+}
+
+// overridesAuth reports whether a request calls an operation whose credentials the configuration sets.
+func overridesAuth(ctx context.Context) bool {
+	_, ok := ctx.Value(operationAuthKey{}).(AuthProvider)
+	return ok
+}
+
+// credentialHeaders returns the headers that hold credentials for a spec's API:
+// Authorization, and those of the spec's apiKey security schemes.
+func credentialHeaders(doc *v3.Document) []string {
+	headers := []string{"Authorization"}
+	if doc.Components != nil && doc.Components.SecuritySchemes != nil {
+		for scheme := range doc.Components.SecuritySchemes.ValuesFromOldest() {
+			if scheme != nil && scheme.Type == "apiKey" && scheme.In == "header" && scheme.Name != "" {
+				headers = append(headers, http.CanonicalHeaderKey(scheme.Name))
+			}
+		}
+	}
+	return headers
+}
+
+// operationAuth is an AuthProvider that applies the credentials the configuration sets for the operation a request calls.
+// Requests for other operations are sent with the credentials given for every request.
+type operationAuth struct{}
+
+// Apply adds the operation's credentials, if the configuration sets any.
+func (operationAuth) Apply(ctx context.Context, req *http.Request) error {
+	if provider, ok := ctx.Value(operationAuthKey{}).(AuthProvider); ok {
+		return provider.Apply(ctx, req)
+	}
+	return nil
+}
+
+// Refresh renews the operation's credentials, if the configuration sets any.
+func (operationAuth) Refresh(ctx context.Context) error {
+	if provider, ok := ctx.Value(operationAuthKey{}).(AuthProvider); ok {
+		return provider.Refresh(ctx)
+	}
+	return ErrAuthNotRefreshable
+}
+
+// overridableAuth applies a provider's credentials to requests for operations whose credentials the configuration doesn't set.
+type overridableAuth struct {
+	AuthProvider
+}
+
+// Apply adds the provider's credentials, unless the configuration sets the operation's credentials.
+func (a overridableAuth) Apply(ctx context.Context, req *http.Request) error {
+	if overridesAuth(ctx) {
+		return nil
+	}
+	return a.AuthProvider.Apply(ctx, req)
+}
+
+// Refresh renews the provider's credentials, unless the configuration sets the operation's credentials.
+func (a overridableAuth) Refresh(ctx context.Context) error {
+	if overridesAuth(ctx) {
+		return ErrAuthNotRefreshable
+	}
+	return a.AuthProvider.Refresh(ctx)
+}
@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthOverrides(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t.Setenv("EMCEE_TEST_ADMIN_TOKEN", "admin")
+	t.Setenv("EMCEE_TEST_ADMIN_KEY", "admin-key")
+
+	var mu sync.Mutex
+	var received []http.Header
+	acceptedAdminToken := "admin"
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.Header.Clone())
+		if auth := r.Header.Get("Authorization"); auth != "Bearer user" && auth != "Bearer "+acceptedAdminToken && auth != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	config, err := ParseConfig([]byte(`
+auth:
+  - operations: [deleteUser]
+    apiKey: env://EMCEE_TEST_ADMIN_KEY
+    scheme: AdminKey
+  - paths: [/admin]
+    bearer: env://EMCEE_TEST_ADMIN_TOKEN
+  - operations: [login]
+    none: true
+`))
+	require.NoError(t, err)
+
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "` + api.URL + `"}],
+  "components": {"securitySchemes": {
+    "AdminKey": {"type": "apiKey", "in": "header", "name": "X-Admin-Key"}
+  }},
+  "paths": {
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}},
+    "/admin/users": {"get": {"operationId": "listUsers", "responses": {"200": {"description": "OK"}}}},
+    "/admin/users/{id}": {"delete": {
+      "operationId": "deleteUser",
+      "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "responses": {"204": {"description": "Deleted"}}
+    }},
+    "/login": {"post": {"operationId": "login", "responses": {"200": {"description": "OK"}}}}
+  }
+}`)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, api.Client(),
+		WithConfig(config), WithAuthProvider(HeaderAuth{Name: "Authorization", Value: "Bearer user"}), WithDryRunArgument()))
+	clientSession := connectTestClient(t, ctx, server)
+
+	call := func(name string, args map[string]any) http.Header {
+		t.Helper()
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, err)
+		require.False(t, result.IsError, name)
+		mu.Lock()
+		defer mu.Unlock()
+		return received[len(received)-1]
+	}
+
+	assert.Equal(t, "Bearer user", call("listPets", nil).Get("Authorization"))
+	assert.Equal(t, "Bearer admin", call("listUsers", nil).Get("Authorization"), "paths beneath a path use its credentials")
+	header := call("deleteUser", map[string]any{"id": "1"})
+	assert.Equal(t, "admin-key", header.Get("X-Admin-Key"), "the first entry naming an operation is used")
+	assert.Empty(t, header.Get("Authorization"))
+	assert.Empty(t, call("login", nil).Get("Authorization"))
+
+	// A rotated secret is read again when the API rejects the old one
+	mu.Lock()
+	acceptedAdminToken = "rotated"
+	mu.Unlock()
+	t.Setenv("EMCEE_TEST_ADMIN_TOKEN", "rotated")
+	assert.Equal(t, "Bearer rotated", call("listUsers", nil).Get("Authorization"))
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "deleteUser", Arguments: map[string]any{"id": "1", dryRunArgument: true}})
+	require.NoError(t, err)
+	text := result.Content[0].(*mcp.TextContent).Text
+	assert.Contains(t, text, "X-Admin-Key")
+	assert.NotContains(t, text, "admin-key", "credentials set by config are redacted from dry runs")
+}
+
+func TestAuthOverridesWithStaticHeaders(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan http.Header, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	config, err := ParseConfig([]byte(`
+auth:
+  - paths: [/public]
+    none: true
+  - operations: [listUsers]
+    apiKey: admin-key
+`))
+	require.NoError(t, err)
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "` + api.URL + `"}],
+  "components": {"securitySchemes": {
+    "AdminKey": {"type": "apiKey", "in": "query", "name": "key"},
+    "Key": {"type": "apiKey", "in": "header", "name": "X-API-Key"}
+  }},
+  "paths": {
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}},
+    "/admin/users": {"get": {"operationId": "listUsers", "responses": {"200": {"description": "OK"}}}},
+    "/public/login": {"post": {"operationId": "login", "responses": {"200": {"description": "OK"}}}}
+  }
+}`)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, api.Client(), WithConfig(config),
+		WithHeader("Authorization", "Bearer global-secret"), WithHeader("X-API-Key", "global-key"), WithHeader("X-Version", "2")))
+	clientSession := connectTestClient(t, ctx, server)
+
+	call := func(name string) http.Header {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name})
+		require.NoError(t, err)
+		require.False(t, result.IsError, name)
+		return <-received
+	}
+
+	header := call("listPets")
+	assert.Equal(t, "Bearer global-secret", header.Get("Authorization"))
+	assert.Equal(t, "global-key", header.Get("X-API-Key"))
+	for _, name := range []string{"login", "listUsers"} {
+		header = call(name)
+		assert.Empty(t, header.Get("Authorization"), "credentials set by config replace those of static headers")
+		assert.Empty(t, header.Get("X-API-Key"))
+		assert.Equal(t, "2", header.Get("X-Version"), "other static headers are still sent")
+	}
+}
+
+func TestAuthOverrideValidation(t *testing.T) {
+	for _, tc := range []struct {
+		config, err string
+	}{
+		{"auth: [{bearer: x}]", "names no operations or paths"},
+		{"auth: [{paths: [admin], bearer: x}]", `invalid path "admin"`},
+		{"auth: [{paths: [/admin]}]", "must set exactly one of"},
+		{"auth: [{paths: [/admin], bearer: x, basic: y}]", "must set exactly one of"},
+		{"auth: [{paths: [/admin], bearer: x, scheme: AdminKey}]", "scheme is only used with apiKey"},
+	} {
+		_, err := ParseConfig([]byte(tc.config))
+		assert.ErrorContains(t, err, tc.err, tc.config)
+	}
+
+	config, err := ParseConfig([]byte("auth: [{operations: [listPets], apiKey: x, scheme: Missing}]"))
+	require.NoError(t, err)
+	spec := []byte(`{"openapi": "3.1.0", "info": {"title": "API", "version": "1"}, "servers": [{"url": "https://api.example.com"}], "paths": {}}`)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	err = RegisterTools(server, spec, nil, WithConfig(config))
+	assert.ErrorContains(t, err, `the spec declares no apiKey security scheme "Missing"`)
+}
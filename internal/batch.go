@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/sync/errgroup"
+)
+
+// CallBatchMethod is the experimental JSON-RPC method for executing several tool calls in one request.
+const CallBatchMethod = "tools/callBatch"
+
+const (
+	// maxBatchCalls is the maximum number of tool calls in a batch.
+	maxBatchCalls = 100
+	// batchConcurrency is the maximum number of tool calls executed at once.
+	batchConcurrency = 8
+)
+
+// callBatchParams are the parameters of a tools/callBatch request.
+type callBatchParams struct {
+	Calls []*mcp.CallToolParams `json:"calls"`
+}
+
+// callBatchResult is the result of a tools/callBatch request.
+// Results are in the same order as the calls in the request.
+type callBatchResult struct {
+	Results []callBatchEntry `json:"results"`
+}
+
+// callBatchEntry is the outcome of a single call in a batch:
+// either the tool's result, or the JSON-RPC error the call would have received on its own.
+type callBatchEntry struct {
+	Result *mcp.CallToolResult `json:"result,omitempty"`
+	Error  json.RawMessage     `json:"error,omitempty"`
+}
+
+// BatchCaller executes batches of independent tool calls concurrently.
+// Calls are made through in-process client sessions connected to the server,
+// so each call is handled exactly as if the client had made it directly,
+// including argument validation and middleware.
+type BatchCaller struct {
+	server *mcp.Server
+
+	once     sync.Once
+	sessions chan *mcp.ClientSession
+	err      error
+}
+
+// NewBatchCaller returns a BatchCaller that calls tools on server.
+func NewBatchCaller(server *mcp.Server) *BatchCaller {
+	return &BatchCaller{server: server}
+}
+
+// Handle handles a tools/callBatch request.
+func (b *BatchCaller) Handle(ctx context.Context, params json.RawMessage) (any, error) {
+	var p callBatchParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, invalidBatchError(err.Error())
+	}
+	if len(p.Calls) == 0 {
+		return nil, invalidBatchError("batch contains no calls")
+	}
+	if len(p.Calls) > maxBatchCalls {
+		return nil, invalidBatchError(fmt.Sprintf("batch contains %d calls (maximum %d)", len(p.Calls), maxBatchCalls))
+	}
+	b.once.Do(func() { b.err = b.connect(ctx) })
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	results := make([]callBatchEntry, len(p.Calls))
+	var g errgroup.Group
+	for i, call := range p.Calls {
+		if call == nil {
+			results[i].Error = wireError(invalidBatchError("call must be an object"))
+			continue
+		}
+		// Calls are attributed to the session of the client that sent the batch
+		if id := sessionFromContext(ctx); id != "" {
+			if call.Meta == nil {
+				call.Meta = mcp.Meta{}
+			}
+			call.Meta[sessionMetaKey] = id
+		}
+		g.Go(func() error {
+			var session *mcp.ClientSession
+			select {
+			case session = <-b.sessions:
+			case <-ctx.Done():
+				results[i].Error = wireError(rpcError(internalErrorCode, ctx.Err().Error(), errorData{Kind: errorKindCanceled, Operation: call.Name}))
+				return nil
+			}
+			defer func() { b.sessions <- session }()
+
+			result, err := session.CallTool(ctx, call)
+			if err != nil {
+				results[i].Error = wireError(err)
+				return nil
+			}
+			results[i].Result = result
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return &callBatchResult{Results: results}, nil
+}
+
+// connect creates the client sessions used to make calls.
+// Each session handles one call at a time, so there is one per concurrent call.
+func (b *BatchCaller) connect(ctx context.Context) error {
+	b.sessions = make(chan *mcp.ClientSession, batchConcurrency)
+	client := mcp.NewClient(&mcp.Implementation{Name: "emcee-batch", Version: "dev"}, nil)
+	for range batchConcurrency {
+		clientTransport, serverTransport := mcp.NewInMemoryTransports()
+		if _, err := b.server.Connect(ctx, serverTransport, nil); err != nil {
+			return fmt.Errorf("error connecting batch session: %w", err)
+		}
+		session, err := client.Connect(ctx, clientTransport, nil)
+		if err != nil {
+			return fmt.Errorf("error connecting batch session: %w", err)
+		}
+		b.sessions <- session
+	}
+	return nil
+}
+
+// invalidBatchError returns an "invalid params" error for a malformed tools/callBatch request.
+func invalidBatchError(detail string) error {
+	return rpcError(invalidParamsCode, fmt.Sprintf("%v: %s", errInvalidParams, detail),
+		errorData{Kind: errorKindInvalidParams, Operation: CallBatchMethod, Detail: detail})
+}
+
+// wireError encodes an error as a JSON-RPC error object, preserving its error code.
+func wireError(err error) json.RawMessage {
+	id, _ := jsonrpc.MakeID(int64(0))
+	data, encodeErr := jsonrpc.EncodeMessage(&jsonrpc.Response{ID: id, Error: err})
+	if encodeErr != nil {
+		return nil
+	}
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(data, &resp) != nil {
+		return nil
+	}
+	return resp.Error
+}
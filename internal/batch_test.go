@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchCaller(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":%q}`, r.URL.Path[len("/pets/"):])
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	in, inWriter := io.Pipe()
+	outReader, out := io.Pipe()
+	stdio := &Stdio{In: in, Out: out, Err: io.Discard}
+	stdio.Methods = map[string]MethodHandler{CallBatchMethod: NewBatchCaller(server).Handle}
+	serverSession, err := server.Connect(ctx, stdio.Transport(), nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+
+	responses := bufio.NewScanner(outReader)
+	responses.Buffer(nil, 1<<20)
+	send := func(msg string) {
+		_, err := inWriter.Write([]byte(msg + "\n"))
+		require.NoError(t, err)
+	}
+	receive := func(v any) {
+		require.True(t, responses.Scan())
+		require.NoError(t, json.Unmarshal(responses.Bytes(), v))
+	}
+
+	send(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"dev"}}}`)
+	var initialized struct {
+		Result struct {
+			Capabilities struct {
+				Experimental map[string]any `json:"experimental"`
+				Tools        map[string]any `json:"tools"`
+			} `json:"capabilities"`
+		} `json:"result"`
+	}
+	receive(&initialized)
+	assert.Contains(t, initialized.Result.Capabilities.Experimental, CallBatchMethod)
+	assert.NotNil(t, initialized.Result.Capabilities.Tools, "other capabilities are preserved")
+	send(`{"jsonrpc":"2.0","method":"notifications/initialized","params":{}}`)
+
+	send(`{"jsonrpc":"2.0","id":2,"method":"tools/callBatch","params":{"calls":[
+		{"name":"getPet","arguments":{"petId":"1"}},
+		{"name":"getPet","arguments":{}},
+		{"name":"getPet","arguments":{"petId":"2"}}
+	]}}`)
+	var batch struct {
+		ID     int `json:"id"`
+		Result struct {
+			Results []struct {
+				Result *struct {
+					StructuredContent map[string]any `json:"structuredContent"`
+				} `json:"result"`
+				Error *struct {
+					Code int `json:"code"`
+				} `json:"error"`
+			} `json:"results"`
+		} `json:"result"`
+	}
+	receive(&batch)
+	assert.Equal(t, 2, batch.ID)
+	require.Len(t, batch.Result.Results, 3)
+	require.NotNil(t, batch.Result.Results[0].Result)
+	assert.Equal(t, map[string]any{"id": "1"}, batch.Result.Results[0].Result.StructuredContent)
+	require.NotNil(t, batch.Result.Results[1].Error, "invalid arguments are reported for each call")
+	assert.Equal(t, -32602, batch.Result.Results[1].Error.Code)
+	require.NotNil(t, batch.Result.Results[2].Result)
+	assert.Equal(t, map[string]any{"id": "2"}, batch.Result.Results[2].Result.StructuredContent)
+
+	send(`{"jsonrpc":"2.0","id":3,"method":"tools/callBatch","params":{"calls":[]}}`)
+	var invalid struct {
+		Error struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	receive(&invalid)
+	assert.Equal(t, -32602, invalid.Error.Code)
+}
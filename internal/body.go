@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// requestBodyMediaTypes are the request body media types that can be encoded from tool arguments,
+// in order of preference. JSON also matches structured suffixes like application/merge-patch+json.
+var requestBodyMediaTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"multipart/form-data",
+	"text/plain",
+	"application/octet-stream",
+}
+
+// requestBody is the content of an operation's request body for the media type it's sent as.
+type requestBody struct {
+	mediaType string
+	schema    *base.Schema
+	content   *v3.MediaType
+}
+
+// operationRequestBody returns the request body of an operation for its preferred supported media type, if any.
+// Text and binary bodies without a schema are treated as a single string.
+func operationRequestBody(op *v3.Operation) *requestBody {
+	if op == nil || op.RequestBody == nil || op.RequestBody.Content == nil {
+		return nil
+	}
+	for _, preferred := range requestBodyMediaTypes {
+		for pair := op.RequestBody.Content.First(); pair != nil; pair = pair.Next() {
+			mediaType := pair.Key()
+			if baseMediaType(mediaType) != preferred && !(preferred == "application/json" && isJSONMediaType(mediaType)) {
+				continue
+			}
+			rb := &requestBody{mediaType: mediaType, content: pair.Value()}
+			if rb.content != nil && rb.content.Schema != nil {
+				rb.schema = mergeAllOf(rb.content.Schema.Schema())
+			}
+			if rb.schema == nil {
+				switch preferred {
+				case "text/plain":
+					rb.schema = &base.Schema{Type: []string{"string"}}
+				case "application/octet-stream":
+					rb.schema = &base.Schema{Type: []string{"string"}, Format: "binary"}
+				default:
+					continue
+				}
+			}
+			return rb
+		}
+	}
+	return nil
+}
+
+// baseMediaType returns a media type in lowercase without parameters.
+func baseMediaType(mediaType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+}
+
+// isBinarySchema reports whether a schema describes raw binary data,
+// which tool arguments carry as base64-encoded strings.
+func isBinarySchema(s *base.Schema) bool {
+	return s != nil && s.Format == "binary"
+}
+
+// encode encodes a request body value for the body's media type,
+// returning the encoded body and the value of its Content-Type header.
+func (rb *requestBody) encode(value any, objectStyle QueryObjectStyle) (io.Reader, string, error) {
+	switch baseMediaType(rb.mediaType) {
+	case "application/x-www-form-urlencoded":
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, "", fmt.Errorf("form body must be an object")
+		}
+		form := url.Values{}
+		for name, v := range fields {
+			setQueryValue(form, name, v, objectStyle)
+		}
+		return strings.NewReader(form.Encode()), rb.mediaType, nil
+	case "multipart/form-data":
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil, "", fmt.Errorf("multipart body must be an object")
+		}
+		return rb.encodeMultipart(fields)
+	case "text/plain":
+		s, ok := value.(string)
+		if !ok {
+			s = paramValueString(value)
+		}
+		return strings.NewReader(s), rb.mediaType, nil
+	case "application/octet-stream":
+		s, ok := value.(string)
+		if !ok {
+			return nil, "", fmt.Errorf("binary body must be a base64-encoded string")
+		}
+		data, err := decodeBase64(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("binary body: %w", err)
+		}
+		return bytes.NewReader(data), rb.mediaType, nil
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("marshal body: %w", err)
+		}
+		return bytes.NewReader(b), rb.mediaType, nil
+	}
+}
+
+// encodeMultipart encodes fields as multipart/form-data, in sorted order.
+// Binary fields are base64-decoded and sent as files, objects and arrays of non-binary values are sent as JSON,
+// and other values are sent as text.
+func (rb *requestBody) encodeMultipart(fields map[string]any) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		value := fields[name]
+		if value == nil {
+			continue
+		}
+		var propSchema *base.Schema
+		if rb.schema.Properties != nil {
+			if prop, ok := rb.schema.Properties.Get(name); ok && prop != nil {
+				propSchema = prop.Schema()
+			}
+		}
+		contentType := rb.partContentType(name)
+
+		switch {
+		case isBinarySchema(propSchema):
+			if err := writeFilePart(w, name, contentType, value); err != nil {
+				return nil, "", err
+			}
+		case propSchema != nil && propSchema.Items != nil && propSchema.Items.IsA() && isBinarySchema(propSchema.Items.A.Schema()):
+			files, ok := value.([]any)
+			if !ok {
+				return nil, "", fmt.Errorf("field %q must be an array of base64-encoded strings", name)
+			}
+			for _, file := range files {
+				if err := writeFilePart(w, name, contentType, file); err != nil {
+					return nil, "", err
+				}
+			}
+		default:
+			var data []byte
+			switch v := value.(type) {
+			case map[string]any, []any:
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, "", fmt.Errorf("marshal field %q: %w", name, err)
+				}
+				data = b
+				if contentType == "" {
+					contentType = "application/json"
+				}
+			default:
+				data = []byte(paramValueString(v))
+			}
+			h := make(textproto.MIMEHeader)
+			h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": name}))
+			if contentType != "" {
+				h.Set("Content-Type", contentType)
+			}
+			part, err := w.CreatePart(h)
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err := part.Write(data); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+// partContentType returns the content type declared for a multipart field by the media type's encoding object, if any.
+func (rb *requestBody) partContentType(name string) string {
+	if rb.content == nil || rb.content.Encoding == nil {
+		return ""
+	}
+	enc, ok := rb.content.Encoding.Get(name)
+	if !ok || enc == nil {
+		return ""
+	}
+	// The encoding object may list several content types; use the first
+	return strings.TrimSpace(strings.Split(enc.ContentType, ",")[0])
+}
+
+// writeFilePart writes a base64-encoded value as a file part named after its field.
+func writeFilePart(w *multipart.Writer, name, contentType string, value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field %q must be a base64-encoded string", name)
+	}
+	data, err := decodeBase64(s)
+	if err != nil {
+		return fmt.Errorf("field %q: %w", name, err)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": name, "filename": name}))
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
+
+// decodeBase64 decodes standard or URL-safe base64, with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	enc := base64.RawStdEncoding
+	if strings.ContainsAny(s, "-_") {
+		enc = base64.RawURLEncoding
+	}
+	data, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 data: %w", err)
+	}
+	return data, nil
+}
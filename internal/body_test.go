@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsNonJSONRequestBodies(t *testing.T) {
+	type observedRequest struct {
+		contentType string
+		body        []byte
+	}
+	observed := make(chan observedRequest, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		observed <- observedRequest{contentType: r.Header.Get("Content-Type"), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Upload API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/login": {
+      "post": {
+        "operationId": "login",
+        "requestBody": {
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {
+                "type": "object",
+                "required": ["username"],
+                "properties": {"username": {"type": "string"}, "scopes": {"type": "array", "items": {"type": "string"}}}
+              }
+            }
+          }
+        },
+        "responses": {"204": {"description": "OK"}}
+      }
+    },
+    "/files": {
+      "post": {
+        "operationId": "uploadFile",
+        "requestBody": {
+          "content": {
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "file": {"type": "string", "format": "binary"},
+                  "title": {"type": "string"},
+                  "metadata": {"type": "object"}
+                }
+              },
+              "encoding": {"file": {"contentType": "image/png"}}
+            }
+          }
+        },
+        "responses": {"204": {"description": "OK"}}
+      }
+    },
+    "/notes": {
+      "put": {
+        "operationId": "putNote",
+        "requestBody": {"content": {"text/plain": {}}},
+        "responses": {"204": {"description": "OK"}}
+      }
+    },
+    "/blobs": {
+      "put": {
+        "operationId": "putBlob",
+        "requestBody": {"required": true, "content": {"application/octet-stream": {}}},
+        "responses": {"204": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	schemas := make(map[string]*mcp.Tool)
+	for _, tool := range tools.Tools {
+		schemas[tool.Name] = tool
+	}
+	require.Contains(t, schemas, "uploadFile")
+	assert.Equal(t, "base64", schemas["uploadFile"].InputSchema.Properties["file"].ContentEncoding)
+	require.Contains(t, schemas, "putBlob")
+	assert.Equal(t, []string{"body"}, schemas["putBlob"].InputSchema.Required)
+	assert.Equal(t, "base64", schemas["putBlob"].InputSchema.Properties["body"].ContentEncoding)
+
+	call := func(name string, args map[string]any) observedRequest {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		return <-observed
+	}
+
+	req := call("login", map[string]any{"username": "ada", "scopes": []any{"read", "write"}})
+	assert.Equal(t, "application/x-www-form-urlencoded", req.contentType)
+	assert.Equal(t, "scopes=read%2Cwrite&username=ada", string(req.body))
+
+	png := []byte("\x89PNG\r\n")
+	req = call("uploadFile", map[string]any{
+		"file":     base64.StdEncoding.EncodeToString(png),
+		"title":    "Logo",
+		"metadata": map[string]any{"tag": "brand"},
+	})
+	mediaType, params, err := mime.ParseMediaType(req.contentType)
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/form-data", mediaType)
+	form, err := multipart.NewReader(bytes.NewReader(req.body), params["boundary"]).ReadForm(1 << 20)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Logo"}, form.Value["title"])
+	assert.Equal(t, []string{`{"tag":"brand"}`}, form.Value["metadata"])
+	require.Len(t, form.File["file"], 1)
+	assert.Equal(t, "image/png", form.File["file"][0].Header.Get("Content-Type"))
+	f, err := form.File["file"][0].Open()
+	require.NoError(t, err)
+	data, _ := io.ReadAll(f)
+	assert.Equal(t, png, data)
+
+	req = call("putNote", map[string]any{"body": "remember the milk"})
+	assert.Equal(t, "text/plain", req.contentType)
+	assert.Equal(t, "remember the milk", string(req.body))
+
+	req = call("putBlob", map[string]any{"body": base64.StdEncoding.EncodeToString([]byte{0, 1, 2})})
+	assert.Equal(t, "application/octet-stream", req.contentType)
+	assert.Equal(t, []byte{0, 1, 2}, req.body)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "putBlob", Arguments: map[string]any{"body": "not base64!"}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestDecodeBase64(t *testing.T) {
+	for _, s := range []string{"aGk/Pz4+", "aGk_Pz4-", "aGk/Pz4+\n"} {
+		data, err := decodeBase64(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, "hi??>>", string(data))
+	}
+	_, err := decodeBase64("*")
+	assert.Error(t, err)
+}
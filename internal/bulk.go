@@ -0,0 +1,258 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// bulkMetaKey is the key under which a summary of per-item results is included in a tool result's metadata.
+const bulkMetaKey = "emcee/bulk"
+
+// maxListedFailures is the maximum number of failed items described in a tool result's text.
+const maxListedFailures = 20
+
+// bulkSummary is a breakdown of the per-item results of a bulk operation or 207 Multi-Status response.
+type bulkSummary struct {
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Failures  []bulkFailure `json:"failures,omitempty"`
+}
+
+// bulkFailure describes an item that failed, by its position in the response.
+type bulkFailure struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// bulkItem is the outcome of a single item in a bulk response.
+type bulkItem struct {
+	id     string
+	status int
+	err    string
+}
+
+// parseBulkResponse returns a summary of the per-item results in a response body, or nil if it isn't a bulk response.
+// Multi-Status (207) responses may be JSON or WebDAV XML.
+// Other successful JSON responses are treated as bulk responses only if every item has an HTTP status,
+// either as a top-level array or in an array field like "items" or "results".
+func parseBulkResponse(status int, contentType string, body []byte) *bulkSummary {
+	var items []bulkItem
+	switch {
+	case isJSONMediaType(contentType) || (status == http.StatusMultiStatus && json.Valid(body)):
+		items = jsonBulkItems(body)
+	case status == http.StatusMultiStatus && strings.HasSuffix(baseMediaType(contentType), "xml"):
+		items = xmlBulkItems(body)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	s := &bulkSummary{}
+	for i, item := range items {
+		if item.status < 400 {
+			s.Succeeded++
+			continue
+		}
+		s.Failed++
+		s.Failures = append(s.Failures, bulkFailure{Index: i, ID: item.id, Status: item.status, Error: item.err})
+	}
+	return s
+}
+
+// String returns a description of the summary for the model.
+func (s *bulkSummary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Bulk result: %d of %d items succeeded, %d failed.", s.Succeeded, s.Succeeded+s.Failed, s.Failed)
+	if s.Failed == 0 {
+		return b.String()
+	}
+	b.WriteString("\nFailed items:")
+	for i, f := range s.Failures {
+		if i == maxListedFailures {
+			fmt.Fprintf(&b, "\n- and %d more", len(s.Failures)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n- item %d", f.Index)
+		if f.ID != "" {
+			fmt.Fprintf(&b, " (%s)", f.ID)
+		}
+		fmt.Fprintf(&b, ": status %d", f.Status)
+		if f.Error != "" {
+			fmt.Fprintf(&b, ": %s", f.Error)
+		}
+	}
+	b.WriteString("\nOnly the failed items need to be retried.")
+	return b.String()
+}
+
+// addBulkSummary attaches a summary of per-item results to a tool result for a bulk response.
+func addBulkSummary(result *mcp.CallToolResultFor[any], resp *http.Response, body []byte) {
+	if result.IsError {
+		return
+	}
+	s := parseBulkResponse(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	if s == nil {
+		return
+	}
+	result.Content = append(result.Content, &mcp.TextContent{Text: s.String()})
+	if result.Meta == nil {
+		result.Meta = mcp.Meta{}
+	}
+	result.Meta[bulkMetaKey] = s
+}
+
+// bulkArrayFields are the names of fields that commonly hold per-item results in bulk responses.
+var bulkArrayFields = []string{"items", "results", "responses", "operations", "data"}
+
+// jsonBulkItems returns the items of a JSON bulk response, or nil if any item has no status.
+func jsonBulkItems(body []byte) []bulkItem {
+	v, ok := decodeJSONNumbers(body)
+	if !ok {
+		return nil
+	}
+	var elements []any
+	switch v := v.(type) {
+	case []any:
+		elements = v
+	case map[string]any:
+		for _, field := range bulkArrayFields {
+			if arr, ok := v[field].([]any); ok {
+				elements = arr
+				break
+			}
+		}
+	}
+	if len(elements) == 0 {
+		return nil
+	}
+
+	items := make([]bulkItem, 0, len(elements))
+	for _, element := range elements {
+		obj, ok := element.(map[string]any)
+		if !ok {
+			return nil
+		}
+		// Unwrap items keyed by action, like Elasticsearch's {"index": {"_id": "1", "status": 201}}
+		if len(obj) == 1 {
+			for _, inner := range obj {
+				if innerObj, ok := inner.(map[string]any); ok {
+					obj = innerObj
+				}
+			}
+		}
+		status, ok := itemStatus(obj)
+		if !ok {
+			return nil
+		}
+		items = append(items, bulkItem{id: itemID(obj), status: status, err: itemError(obj)})
+	}
+	return items
+}
+
+// itemStatus returns the HTTP status of an item in a bulk response.
+func itemStatus(obj map[string]any) (int, bool) {
+	for _, field := range []string{"status", "statusCode", "status_code", "httpStatus", "code"} {
+		if status, ok := parseItemStatus(obj[field]); ok {
+			return status, true
+		}
+	}
+	return 0, false
+}
+
+// parseItemStatus parses an HTTP status given as a number, a numeric string, or a status line like "HTTP/1.1 404 Not Found".
+func parseItemStatus(v any) (int, bool) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+		if fields := strings.Fields(v); len(fields) >= 2 && strings.HasPrefix(fields[0], "HTTP/") {
+			s = fields[1]
+		}
+	default:
+		return 0, false
+	}
+	status, err := strconv.Atoi(s)
+	if err != nil || status < 100 || status > 599 {
+		return 0, false
+	}
+	return status, true
+}
+
+// itemID returns the identifier of an item in a bulk response, if it has one.
+func itemID(obj map[string]any) string {
+	for _, field := range []string{"id", "_id", "key", "href"} {
+		switch v := obj[field].(type) {
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		}
+	}
+	return ""
+}
+
+// itemError returns the error message of an item in a bulk response, if it has one.
+func itemError(obj map[string]any) string {
+	for _, field := range []string{"error", "message", "detail", "title"} {
+		switch v := obj[field].(type) {
+		case string:
+			return v
+		case map[string]any:
+			for _, nested := range []string{"message", "reason", "detail"} {
+				if s, ok := v[nested].(string); ok {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// multiStatus is a WebDAV multistatus response body (RFC 4918).
+type multiStatus struct {
+	Responses []struct {
+		Href     []string `xml:"href"`
+		Status   string   `xml:"status"`
+		PropStat []struct {
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+		Description string `xml:"responsedescription"`
+	} `xml:"response"`
+}
+
+// xmlBulkItems returns the items of a WebDAV multistatus response.
+// A response whose status is given per property is successful only if every property is.
+func xmlBulkItems(body []byte) []bulkItem {
+	var ms multiStatus
+	if err := xml.NewDecoder(bytes.NewReader(body)).Decode(&ms); err != nil {
+		return nil
+	}
+	items := make([]bulkItem, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		item := bulkItem{id: strings.Join(r.Href, ", "), err: strings.TrimSpace(r.Description)}
+		if status, ok := parseItemStatus(r.Status); ok {
+			item.status = status
+		}
+		for _, ps := range r.PropStat {
+			if status, ok := parseItemStatus(ps.Status); ok && status > item.status {
+				item.status = status
+			}
+		}
+		if item.status == 0 {
+			return nil
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBulkResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		want        *bulkSummary
+	}{
+		{
+			name:        "multi-status array",
+			status:      http.StatusMultiStatus,
+			contentType: "application/json",
+			body:        `[{"id":1,"status":201},{"id":2,"status":400,"error":{"message":"name is required"}},{"id":"c","status":"HTTP/1.1 409 Conflict","message":"duplicate"}]`,
+			want: &bulkSummary{Succeeded: 1, Failed: 2, Failures: []bulkFailure{
+				{Index: 1, ID: "2", Status: 400, Error: "name is required"},
+				{Index: 2, ID: "c", Status: 409, Error: "duplicate"},
+			}},
+		},
+		{
+			name:        "elasticsearch bulk",
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"took":30,"errors":true,"items":[{"index":{"_id":"1","status":201}},{"delete":{"_id":"2","status":404,"error":{"reason":"document missing"}}}]}`,
+			want: &bulkSummary{Succeeded: 1, Failed: 1, Failures: []bulkFailure{
+				{Index: 1, ID: "2", Status: 404, Error: "document missing"},
+			}},
+		},
+		{
+			name:        "webdav multistatus",
+			status:      http.StatusMultiStatus,
+			contentType: "application/xml",
+			body: `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response><d:href>/files/a.txt</d:href><d:status>HTTP/1.1 200 OK</d:status></d:response>
+  <d:response>
+    <d:href>/files/b.txt</d:href>
+    <d:propstat><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+    <d:propstat><d:status>HTTP/1.1 403 Forbidden</d:status></d:propstat>
+    <d:responsedescription>Read-only property</d:responsedescription>
+  </d:response>
+</d:multistatus>`,
+			want: &bulkSummary{Succeeded: 1, Failed: 1, Failures: []bulkFailure{
+				{Index: 1, ID: "/files/b.txt", Status: 403, Error: "Read-only property"},
+			}},
+		},
+		{
+			name:        "list of records",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `[{"id":1,"status":"active"},{"id":2,"status":"inactive"}]`,
+		},
+		{
+			name:        "partially statused items",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"results":[{"status":200},{"name":"no status"}]}`,
+		},
+		{
+			name:        "empty array",
+			status:      http.StatusMultiStatus,
+			contentType: "application/json",
+			body:        `[]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseBulkResponse(tt.status, tt.contentType, []byte(tt.body)))
+		})
+	}
+}
+
+func TestRegisterToolsSummarizesBulkResponses(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"results":[{"id":"a","status":201},{"id":"b","status":422,"detail":"email is invalid"}]}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "User API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/users/bulk": {"post": {"operationId": "createUsers", "responses": {"207": {"description": "Multi-Status"}}}}
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "createUsers"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 2)
+	assert.Equal(t, "Bulk result: 1 of 2 items succeeded, 1 failed.\nFailed items:\n- item 1 (b): status 422: email is invalid\nOnly the failed items need to be retried.",
+		result.Content[1].(*mcp.TextContent).Text)
+	assert.Equal(t, map[string]any{
+		"succeeded": float64(1),
+		"failed":    float64(1),
+		"failures":  []any{map[string]any{"index": float64(1), "id": "b", "status": float64(422), "error": "email is invalid"}},
+	}, result.Meta[bulkMetaKey])
+}
@@ -0,0 +1,374 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCachedBodySize is the size above which responses aren't cached.
+	maxCachedBodySize = 10 * 1024 * 1024 // 10MB
+	// maxMemoryCacheEntries is the most responses kept by a cache without a directory.
+	maxMemoryCacheEntries = 1000
+)
+
+// CacheOptions configures a cache of API responses.
+type CacheOptions struct {
+	// Dir is the directory responses are stored in, so they're kept between runs.
+	// If empty, responses are kept in memory.
+	Dir string
+	// TTL is how long responses without Cache-Control max-age or Expires headers are fresh.
+	// If zero, they're revalidated with their ETag or Last-Modified headers on every request,
+	// and not cached if they have neither.
+	TTL time.Duration
+}
+
+// CacheTransport is a RoundTripper that caches responses to GET requests,
+// honoring Cache-Control, Expires, ETag, and Last-Modified, like a private browser cache.
+// Fresh responses are returned without a request to the API,
+// and stale ones are revalidated with a conditional request when they have an ETag or Last-Modified header.
+// Stale responses are also returned when the API can't be reached or responds with a server error.
+// Successful requests with other methods evict the cached response for their URL.
+type CacheTransport struct {
+	Base http.RoundTripper
+
+	ttl   time.Duration
+	store cacheStore
+	now   func() time.Time
+}
+
+// NewCacheTransport returns a CacheTransport that stores responses as configured by opts.
+func NewCacheTransport(base http.RoundTripper, opts CacheOptions) (*CacheTransport, error) {
+	if opts.TTL < 0 {
+		return nil, fmt.Errorf("cache TTL must not be negative")
+	}
+	var store cacheStore = newMemoryCacheStore()
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+			return nil, fmt.Errorf("error creating cache directory: %w", err)
+		}
+		store = diskCacheStore(opts.Dir)
+	}
+	return &CacheTransport{Base: base, ttl: opts.TTL, store: store, now: time.Now}, nil
+}
+
+// cachedResponse is a response stored in a cache.
+type cachedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// Stored is when the response was received or last revalidated.
+	Stored time.Time `json:"stored"`
+	// Vary holds the values of the request headers named by the response's Vary header.
+	Vary map[string]string `json:"vary,omitempty"`
+}
+
+// RoundTrip returns a cached response if there's a fresh one, and otherwise sends the request, caching the response.
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	key := cacheKey(req)
+	if req.Method != http.MethodGet {
+		resp, err := base.RoundTrip(req)
+		if err == nil && req.Method != http.MethodHead && resp.StatusCode < 400 {
+			t.store.delete(key)
+		}
+		return resp, err
+	}
+	if hasCacheDirective(req.Header, "no-store") {
+		return base.RoundTrip(req)
+	}
+
+	cached, ok := t.store.get(key)
+	if ok && !cached.matches(req) {
+		cached, ok = nil, false
+	}
+	if ok && !hasCacheDirective(req.Header, "no-cache") && t.now().Before(cached.Stored.Add(t.lifetime(cached.Header))) {
+		return cached.response(req, t.now()), nil
+	}
+
+	// Revalidate a stale response, unless the request is already conditional
+	conditional := req
+	if ok && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		etag, lastModified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			conditional = req.Clone(req.Context())
+			if etag != "" {
+				conditional.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				conditional.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+	resp, err := base.RoundTrip(conditional)
+	if ok && (err != nil || resp.StatusCode >= 500) {
+		// A stale response is better than none while the API is unavailable
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		return cached.response(req, t.now()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if conditional != req && resp.StatusCode == http.StatusNotModified {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		for name, values := range resp.Header {
+			cached.Header[name] = values
+		}
+		cached.Stored = t.now()
+		t.store.set(key, cached)
+		return cached.response(req, t.now()), nil
+	}
+	if !t.storable(resp) {
+		return resp, nil
+	}
+	return t.save(key, req, resp)
+}
+
+// storable reports whether a response can be cached.
+// Streams of server-sent events aren't, since they're read as they arrive.
+func (t *CacheTransport) storable(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK || hasCacheDirective(resp.Header, "no-store") || resp.Header.Get("Vary") == "*" {
+		return false
+	}
+	if baseMediaType(resp.Header.Get("Content-Type")) == eventStreamMediaType {
+		return false
+	}
+	return t.lifetime(resp.Header) > 0 || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// lifetime returns how long a response is fresh after it's received:
+// its Cache-Control max-age, or the time from its Date to its Expires, or the cache's TTL.
+func (t *CacheTransport) lifetime(header http.Header) time.Duration {
+	if hasCacheDirective(header, "no-cache") {
+		return 0
+	}
+	if v, ok := cacheDirective(header, "max-age"); ok {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if v := header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		date, err := http.ParseTime(header.Get("Date"))
+		if err != nil {
+			date = t.now()
+		}
+		return expires.Sub(date)
+	}
+	return t.ttl
+}
+
+// save stores a response in the cache, and returns it with its body ready to be read again.
+// Bodies larger than maxCachedBodySize aren't stored.
+func (t *CacheTransport) save(key string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedBodySize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	cached := &cachedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone(), Body: body, Stored: t.now()}
+	for _, name := range varyHeaders(resp.Header) {
+		if cached.Vary == nil {
+			cached.Vary = make(map[string]string)
+		}
+		cached.Vary[name] = req.Header.Get(name)
+	}
+	t.store.set(key, cached)
+	return resp, nil
+}
+
+// matches reports whether a cached response was for a request with the same values of the headers it varies on.
+func (c *cachedResponse) matches(req *http.Request) bool {
+	for _, name := range varyHeaders(c.Header) {
+		if c.Vary[name] != req.Header.Get(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// response returns a copy of the cached response for a request, with its Age.
+func (c *cachedResponse) response(req *http.Request, now time.Time) *http.Response {
+	header := c.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(now.Sub(c.Stored).Seconds())))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", c.StatusCode, http.StatusText(c.StatusCode)),
+		StatusCode:    c.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
+
+// cacheKey returns the key of a request's cached response: a hash of its URL and credentials,
+// so that responses for one set of credentials are never returned for another.
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s", req.URL.String(), req.Header.Get("Authorization"), req.Header.Get("Cookie"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// varyHeaders returns the canonical names of the request headers a response varies on.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, v := range header.Values("Vary") {
+		for name := range strings.SplitSeq(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// cacheDirective returns the value of a Cache-Control directive.
+func cacheDirective(header http.Header, name string) (string, bool) {
+	for _, v := range header.Values("Cache-Control") {
+		for directive := range strings.SplitSeq(v, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if strings.EqualFold(key, name) {
+				return strings.Trim(value, `"`), true
+			}
+		}
+	}
+	return "", false
+}
+
+// hasCacheDirective reports whether a Cache-Control header has a directive.
+func hasCacheDirective(header http.Header, name string) bool {
+	_, ok := cacheDirective(header, name)
+	return ok
+}
+
+// cacheStore stores cached responses by key.
+type cacheStore interface {
+	get(key string) (*cachedResponse, bool)
+	set(key string, resp *cachedResponse)
+	delete(key string)
+}
+
+// memoryCacheStore keeps responses in memory, evicting an arbitrary one when it's full.
+type memoryCacheStore struct {
+	mu        sync.Mutex
+	responses map[string]*cachedResponse
+}
+
+func newMemoryCacheStore() *memoryCacheStore {
+	return &memoryCacheStore{responses: make(map[string]*cachedResponse)}
+}
+
+func (s *memoryCacheStore) get(key string) (*cachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp, ok := s.responses[key]
+	if !ok {
+		return nil, false
+	}
+	copied := *resp
+	copied.Header = resp.Header.Clone()
+	return &copied, true
+}
+
+func (s *memoryCacheStore) set(key string, resp *cachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.responses[key]; !ok && len(s.responses) >= maxMemoryCacheEntries {
+		for k := range s.responses {
+			delete(s.responses, k)
+			break
+		}
+	}
+	s.responses[key] = resp
+}
+
+func (s *memoryCacheStore) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.responses, key)
+}
+
+// diskCacheStore keeps responses as JSON files in a directory, named by key.
+// Files are replaced atomically, so concurrent processes sharing the directory never read partial entries.
+type diskCacheStore string
+
+func (s diskCacheStore) dir() string {
+	return string(s)
+}
+
+func (s diskCacheStore) path(key string) string {
+	return filepath.Join(s.dir(), key+".json")
+}
+
+func (s diskCacheStore) get(key string) (*cachedResponse, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var resp cachedResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+func (s diskCacheStore) set(key string, resp *cachedResponse) {
+	f, err := os.CreateTemp(s.dir(), key+".*.tmp")
+	if err != nil {
+		return
+	}
+	w := bufio.NewWriter(f)
+	err = json.NewEncoder(w).Encode(resp)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func (s diskCacheStore) delete(key string) {
+	_ = os.Remove(s.path(key))
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheTransport(t *testing.T) {
+	var requests atomic.Int32
+	var revalidated atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/max-age":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				revalidated.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		_, _ = io.WriteString(w, r.URL.Path+" "+r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+
+	now := time.Now()
+	newTransport := func(opts CacheOptions) *CacheTransport {
+		transport, err := NewCacheTransport(api.Client().Transport, opts)
+		require.NoError(t, err)
+		transport.now = func() time.Time { return now }
+		return transport
+	}
+	get := func(transport *CacheTransport, method, path, authorization string) (string, *http.Response) {
+		req, err := http.NewRequest(method, api.URL+path, nil)
+		require.NoError(t, err)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body), resp
+	}
+	// requested returns the number of requests to the API since it was last called
+	requested := func() int32 { return requests.Swap(0) }
+
+	transport := newTransport(CacheOptions{})
+
+	body, _ := get(transport, http.MethodGet, "/max-age", "")
+	assert.Equal(t, "/max-age ", body)
+	now = now.Add(30 * time.Second)
+	body, resp := get(transport, http.MethodGet, "/max-age", "")
+	assert.Equal(t, "/max-age ", body)
+	assert.Equal(t, "30", resp.Header.Get("Age"))
+	assert.EqualValues(t, 1, requested(), "fresh responses are reused")
+
+	get(transport, http.MethodGet, "/max-age", "Bearer other")
+	assert.EqualValues(t, 1, requested(), "responses aren't shared between credentials")
+
+	now = now.Add(time.Minute)
+	get(transport, http.MethodGet, "/max-age", "")
+	assert.EqualValues(t, 1, requested(), "stale responses are fetched again")
+
+	get(transport, http.MethodGet, "/etag", "")
+	body, _ = get(transport, http.MethodGet, "/etag", "")
+	assert.Equal(t, "/etag ", body)
+	assert.EqualValues(t, 2, requested())
+	assert.EqualValues(t, 1, revalidated.Load(), "responses with ETags are revalidated")
+
+	get(transport, http.MethodGet, "/no-store", "")
+	get(transport, http.MethodGet, "/no-store", "")
+	get(transport, http.MethodGet, "/plain", "")
+	get(transport, http.MethodGet, "/plain", "")
+	assert.EqualValues(t, 4, requested(), "responses that don't allow caching aren't cached")
+
+	transport = newTransport(CacheOptions{TTL: time.Minute, Dir: t.TempDir()})
+	get(transport, http.MethodGet, "/plain", "")
+	get(transport, http.MethodGet, "/plain", "")
+	assert.EqualValues(t, 1, requested(), "responses without freshness information are fresh for the TTL")
+
+	get(transport, http.MethodPost, "/plain", "")
+	get(transport, http.MethodGet, "/plain", "")
+	assert.EqualValues(t, 2, requested(), "requests with other methods evict cached responses")
+
+	transport = newTransport(CacheOptions{TTL: time.Minute, Dir: transport.store.(diskCacheStore).dir()})
+	body, _ = get(transport, http.MethodGet, "/plain", "")
+	assert.Equal(t, "/plain ", body)
+	assert.EqualValues(t, 0, requested(), "responses in the cache directory are kept between runs")
+
+	now = now.Add(2 * time.Minute)
+	api.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	body, resp = get(transport, http.MethodGet, "/plain", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/plain ", body, "stale responses are returned when the API fails")
+	assert.EqualValues(t, 1, requested())
+
+	_, err := NewCacheTransport(nil, CacheOptions{TTL: -time.Second})
+	assert.Error(t, err)
+}
+
+func TestCacheTransportDoesNotBufferStreams(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", eventStreamMediaType)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer api.Close()
+
+	transport, err := NewCacheTransport(api.Client().Transport, CacheOptions{TTL: time.Minute})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodGet, api.URL, nil)
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	line := make([]byte, len("data: 1"))
+	_, err = io.ReadFull(resp.Body, line)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(line), "data: 1"))
+}
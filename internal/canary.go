@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+)
+
+// CanaryOptions configures routing a share of tool calls to a new version of an API.
+type CanaryOptions struct {
+	// Spec is the OpenAPI specification of the new API version.
+	Spec []byte
+	// Percent is the percentage (0-100) of tool calls routed to the new version.
+	Percent float64
+	// Tools lists tools whose calls are always routed to the new version.
+	Tools []string
+}
+
+// WithCanary routes a share of tool calls to the API described by a new version of the spec.
+// Calls are matched to the new version by operationId, so only operations present in both versions are routed.
+// Read-only calls routed to the new version are also sent to the current version,
+// and any discrepancies between the two responses are logged.
+func WithCanary(opts CanaryOptions) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.canary = &opts }
+}
+
+// canary routes tool calls between the current and new versions of an API.
+type canary struct {
+	endpoints map[string]*endpoint // by operationId
+	percent   float64
+	tools     map[string]struct{}
+	logger    *slog.Logger
+	random    func() float64
+}
+
+func newCanary(opts CanaryOptions, cfg *registerToolsConfig) (*canary, error) {
+	if opts.Percent < 0 || opts.Percent > 100 {
+		return nil, fmt.Errorf("canary percent must be between 0 and 100")
+	}
+	model, baseURL, err := buildModel(opts.Spec, cfg.serverVars, specReferences{})
+	if err != nil {
+		return nil, fmt.Errorf("canary spec: %w", err)
+	}
+
+	c := &canary{
+		endpoints: make(map[string]*endpoint),
+		percent:   opts.Percent,
+		tools:     make(map[string]struct{}),
+		logger:    cfg.logger,
+		random:    rand.Float64,
+	}
+	for _, name := range opts.Tools {
+		c.tools[name] = struct{}{}
+	}
+	if model.Model.Paths == nil || model.Model.Paths.PathItems == nil {
+		return c, nil
+	}
+	for pair := model.Model.Paths.PathItems.First(); pair != nil; pair = pair.Next() {
+		ops, err := pathOperations(pair.Value())
+		if err != nil {
+			return nil, fmt.Errorf("canary spec: error parsing QUERY operation for %s: %w", pair.Key(), err)
+		}
+		for _, op := range ops {
+			if op.op.OperationId == "" {
+				continue
+			}
+			c.endpoints[op.op.OperationId] = &endpoint{
+				baseURL:  baseURL,
+				path:     pair.Key(),
+				method:   op.method,
+				pathItem: pair.Value(),
+				op:       op.op,
+				cfg:      cfg,
+			}
+		}
+	}
+	return c, nil
+}
+
+// route returns the new version's endpoint for a tool call, if the call should be routed there.
+func (c *canary) route(toolName, operationID string) (*endpoint, bool) {
+	ep, ok := c.endpoints[operationID]
+	if !ok {
+		return nil, false
+	}
+	if _, ok := c.tools[toolName]; ok {
+		return ep, true
+	}
+	if c.percent > 0 && c.random()*100 < c.percent {
+		return ep, true
+	}
+	return nil, false
+}
+
+// canaryResponse is the status and body of a response used for comparison.
+type canaryResponse struct {
+	status int
+	body   []byte
+	err    error
+}
+
+// shadow sends a call to the current version of the API in the background,
+// so that its response can be compared with the new version's.
+func (c *canary) shadow(ctx context.Context, client *http.Client, ep *endpoint, args map[string]any) <-chan canaryResponse {
+	ch := make(chan canaryResponse, 1)
+	go func() {
+		var r canaryResponse
+		defer func() { ch <- r }()
+
+		hreq, err := ep.newRequest(ctx, args)
+		if err != nil {
+			r.err = err
+			return
+		}
+		resp, err := client.Do(hreq)
+		if err != nil {
+			r.err = err
+			return
+		}
+		defer resp.Body.Close()
+		r.status = resp.StatusCode
+		r.body, r.err = io.ReadAll(resp.Body)
+	}()
+	return ch
+}
+
+// compare logs any discrepancy between the current and new versions' responses.
+func (c *canary) compare(toolName string, current canaryResponse, status int, body []byte) {
+	switch {
+	case current.err != nil:
+		c.logger.Warn("canary comparison failed", "tool", toolName, "error", current.err)
+	case current.status != status:
+		c.logger.Warn("canary status differs", "tool", toolName, "current", current.status, "canary", status)
+	case !equalBodies(current.body, body):
+		c.logger.Warn("canary response body differs", "tool", toolName, "currentBytes", len(current.body), "canaryBytes", len(body))
+	default:
+		c.logger.Debug("canary response matches", "tool", toolName)
+	}
+}
+
+// equalBodies compares response bodies, ignoring formatting differences when both are JSON.
+// Numbers are compared exactly, so large IDs that differ only beyond float64 precision aren't considered equal.
+func equalBodies(a, b []byte) bool {
+	if av, ok := decodeJSONNumbers(a); ok {
+		if bv, ok := decodeJSONNumbers(b); ok {
+			return reflect.DeepEqual(av, bv)
+		}
+	}
+	return bytes.Equal(a, b)
+}
+
+// decodeJSONNumbers decodes a single JSON value, with numbers decoded as json.Number.
+func decodeJSONNumbers(data []byte) (any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return nil, false
+	}
+	return v, true
+}
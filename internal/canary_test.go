@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithCanary(t *testing.T) {
+	var currentCalls, canaryCalls atomic.Int32
+	current := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		currentCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"Fido"}`))
+	}))
+	defer current.Close()
+	next := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		canaryCalls.Add(1)
+		assert.Equal(t, "/v2/pets/1", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"Rex"}`))
+	}))
+	defer next.Close()
+
+	specFor := func(serverURL, path string) string {
+		return fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    %q: {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/pets": {
+      "post": {"operationId": "createPet", "responses": {"201": {"description": "Created"}}}
+    }
+  }
+}`, serverURL, path)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(specFor(current.URL, "/pets/{petId}")), current.Client(),
+		WithLogger(logger),
+		WithCanary(CanaryOptions{
+			Spec:  []byte(specFor(next.URL, "/v2/pets/{petId}")),
+			Tools: []string{"getPet"},
+		}),
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": "1"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "Rex", "response comes from the canary")
+	assert.Equal(t, int32(1), canaryCalls.Load())
+	assert.Equal(t, int32(1), currentCalls.Load(), "read-only calls are shadowed to the current version")
+	assert.Contains(t, logs.String(), "canary response body differs")
+
+	// Calls to tools that aren't selected stay on the current version
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "createPet", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, int32(1), canaryCalls.Load())
+	assert.Equal(t, int32(2), currentCalls.Load())
+}
+
+func TestCanaryRoutePercent(t *testing.T) {
+	ep := &endpoint{}
+	c := &canary{
+		endpoints: map[string]*endpoint{"getPet": ep},
+		percent:   25,
+		tools:     map[string]struct{}{},
+	}
+
+	c.random = func() float64 { return 0.2 }
+	got, ok := c.route("getPet", "getPet")
+	assert.True(t, ok)
+	assert.Same(t, ep, got)
+
+	c.random = func() float64 { return 0.3 }
+	_, ok = c.route("getPet", "getPet")
+	assert.False(t, ok)
+
+	_, ok = c.route("missing", "missing")
+	assert.False(t, ok, "operations missing from the canary spec aren't routed")
+}
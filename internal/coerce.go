@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// coercionMiddleware normalizes humanized argument values in tools/call requests
+// (e.g. "1,5" or "March 3rd 2025") into the types and formats declared by each tool's input schema.
+// It runs before the SDK validates arguments, so values that would otherwise be rejected can be repaired.
+func coercionMiddleware(schemas map[string]*jsonschema.Schema) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if r, ok := req.(*mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]]); ok && r.Params != nil {
+				if schema, ok := schemas[r.Params.Name]; ok && len(r.Params.Arguments) > 0 {
+					if args, ok := coerceRawArguments(r.Params.Arguments, schema); ok {
+						r.Params.Arguments = args
+					}
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// coerceRawArguments decodes raw arguments, coerces them against schema, and re-encodes them.
+// It reports false if the arguments couldn't be decoded or nothing was changed.
+func coerceRawArguments(raw json.RawMessage, schema *jsonschema.Schema) (json.RawMessage, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var args map[string]any
+	if err := dec.Decode(&args); err != nil || args == nil {
+		return nil, false
+	}
+	changed := false
+	for name, value := range args {
+		prop, ok := schema.Properties[name]
+		if !ok || prop == nil {
+			continue
+		}
+		if v, ok := coerceValue(value, prop); ok {
+			args[name] = v
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, false
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+// coerceValue converts value to match schema, reporting whether it was changed.
+func coerceValue(value any, schema *jsonschema.Schema) (any, bool) {
+	switch v := value.(type) {
+	case string:
+		switch schema.Type {
+		case "integer":
+			if n, ok := parseHumanNumber(v, true); ok {
+				return n, true
+			}
+		case "number":
+			if n, ok := parseHumanNumber(v, false); ok {
+				return n, true
+			}
+		case "string":
+			if s, ok := parseHumanDate(v, schema.Format); ok && s != v {
+				return s, true
+			}
+		}
+	case []any:
+		if schema.Type != "array" || schema.Items == nil {
+			return value, false
+		}
+		changed := false
+		for i, item := range v {
+			if c, ok := coerceValue(item, schema.Items); ok {
+				v[i] = c
+				changed = true
+			}
+		}
+		return v, changed
+	}
+	return value, false
+}
+
+// parseHumanNumber parses numbers written with locale-specific separators,
+// such as "1,5", "1.234,5", "1,234.5", or "1 000".
+// The result is returned as a json.Number so that no precision is lost.
+func parseHumanNumber(s string, integer bool) (json.Number, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "+")
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "_", "", "'", "").Replace(s)
+	if s == "" {
+		return "", false
+	}
+
+	lastComma := strings.LastIndex(s, ",")
+	lastDot := strings.LastIndex(s, ".")
+	switch {
+	case lastComma >= 0 && lastDot >= 0:
+		// Whichever separator comes last is the decimal separator
+		if lastComma > lastDot {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case lastComma >= 0:
+		s = normalizeSeparator(s, ",", integer)
+	case lastDot >= 0:
+		s = normalizeSeparator(s, ".", integer)
+	}
+
+	if integer {
+		if i := strings.Index(s, "."); i >= 0 && strings.Trim(s[i+1:], "0") == "" {
+			s = s[:i]
+		}
+		if !integerPattern.MatchString(s) {
+			return "", false
+		}
+		return json.Number(s), true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return "", false
+	}
+	return json.Number(s), true
+}
+
+var integerPattern = regexp.MustCompile(`^-?[0-9]+$`)
+
+// normalizeSeparator resolves a single kind of separator as either a decimal or grouping separator.
+// A separator that appears more than once, or is followed by exactly three digits in an integer,
+// is treated as grouping. A lone comma is otherwise treated as a decimal separator.
+func normalizeSeparator(s, sep string, integer bool) string {
+	if strings.Count(s, sep) > 1 {
+		return strings.ReplaceAll(s, sep, "")
+	}
+	i := strings.Index(s, sep)
+	fraction := s[i+1:]
+	if len(fraction) == 3 && (integer || sep == ",") {
+		return strings.ReplaceAll(s, sep, "")
+	}
+	return strings.Replace(s, sep, ".", 1)
+}
+
+var ordinalSuffix = regexp.MustCompile(`(?i)\b(\d{1,2})(st|nd|rd|th)\b`)
+
+// humanDateLayouts are tried in order when parsing humanized dates.
+// Slash-separated dates are read month-first; dot-separated dates are read day-first.
+var humanDateLayouts = []string{
+	"2006-01-02",
+	"2006-1-2",
+	"2006/1/2",
+	"2006.1.2",
+	"January 2 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"Monday January 2 2006",
+	"Mon Jan 2 2006",
+	"Monday 2 January 2006",
+	"Mon 2 Jan 2006",
+	"1/2/2006",
+	"2/1/2006",
+	"2.1.2006",
+	"2-1-2006",
+}
+
+// humanDateTimeLayouts are tried before humanDateLayouts for date-time values.
+var humanDateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseHumanDate parses dates like "March 3rd 2025" or "03/03/2025"
+// and formats them according to the JSON Schema format ("date" or "date-time").
+// Values for other formats are left untouched.
+func parseHumanDate(s, format string) (string, bool) {
+	if format != "date" && format != "date-time" {
+		return "", false
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range humanDateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return formatDate(t, format), true
+		}
+	}
+
+	normalized := ordinalSuffix.ReplaceAllString(s, "$1")
+	normalized = strings.ReplaceAll(normalized, ",", " ")
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	for _, layout := range humanDateLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return formatDate(t, format), true
+		}
+	}
+	return "", false
+}
+
+func formatDate(t time.Time, format string) string {
+	if format == "date" {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHumanNumber(t *testing.T) {
+	tests := []struct {
+		input   string
+		integer bool
+		want    json.Number
+		wantOK  bool
+	}{
+		{input: "1,5", want: "1.5", wantOK: true},
+		{input: "1.5", want: "1.5", wantOK: true},
+		{input: "1,234.5", want: "1234.5", wantOK: true},
+		{input: "1.234,5", want: "1234.5", wantOK: true},
+		{input: "1,234", want: "1234", wantOK: true},
+		{input: "1 000 000", want: "1000000", wantOK: true},
+		{input: "+42", want: "42", wantOK: true},
+		{input: "1.000", integer: true, want: "1000", wantOK: true},
+		{input: "12.0", integer: true, want: "12", wantOK: true},
+		{input: "9007199254740993", integer: true, want: "9007199254740993", wantOK: true},
+		{input: "1,5", integer: true, wantOK: false},
+		{input: "abc", wantOK: false},
+		{input: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseHumanNumber(tt.input, tt.integer)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseHumanDate(t *testing.T) {
+	tests := []struct {
+		input  string
+		format string
+		want   string
+		wantOK bool
+	}{
+		{input: "March 3rd 2025", format: "date", want: "2025-03-03", wantOK: true},
+		{input: "Mar 3, 2025", format: "date", want: "2025-03-03", wantOK: true},
+		{input: "3 March 2025", format: "date", want: "2025-03-03", wantOK: true},
+		{input: "03/04/2025", format: "date", want: "2025-03-04", wantOK: true},
+		{input: "25/12/2025", format: "date", want: "2025-12-25", wantOK: true},
+		{input: "04.03.2025", format: "date", want: "2025-03-04", wantOK: true},
+		{input: "2025-03-03T10:30:00Z", format: "date", want: "2025-03-03", wantOK: true},
+		{input: "March 3rd 2025", format: "date-time", want: "2025-03-03T00:00:00Z", wantOK: true},
+		{input: "2025-03-03 10:30", format: "date-time", want: "2025-03-03T10:30:00Z", wantOK: true},
+		{input: "March 3rd 2025", format: "", wantOK: false},
+		{input: "not a date", format: "date", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+"/"+tt.format, func(t *testing.T) {
+			got, ok := parseHumanDate(tt.input, tt.format)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterToolsWithArgumentCoercion(t *testing.T) {
+	observed := make(chan map[string]any, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		body["count"] = r.URL.Query().Get("count")
+		observed <- body
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Coercion API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/orders": {
+      "post": {
+        "operationId": "createOrder",
+        "parameters": [
+          {"name": "count", "in": "query", "schema": {"type": "integer"}}
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {
+                  "amount": {"type": "number"},
+                  "due": {"type": "string", "format": "date"}
+                }
+              }
+            }
+          }
+        },
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithArgumentCoercion()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "createOrder",
+		Arguments: map[string]any{"count": "1.000", "amount": "1,5", "due": "March 3rd 2025"},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	body := <-observed
+	assert.Equal(t, "1000", body["count"])
+	assert.Equal(t, 1.5, body["amount"])
+	assert.Equal(t, "2025-03-03", body["due"])
+}
@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+)
+
+// lookupTTL is how long values fetched by a lookup are used for completions before they're fetched again.
+const lookupTTL = time.Minute
+
+// Lookup fetches the values an argument can take from another operation,
+// like the IDs of a user's zones for a zone_id argument, for completing the argument's values.
+type Lookup struct {
+	// Operation is the ID of the operation whose response lists the values, usually a GET.
+	Operation string `yaml:"operation" json:"operation"`
+	// Values is a JSONPath expression selecting the values in the operation's response (e.g. "$.result[*].id").
+	Values string `yaml:"values" json:"values"`
+}
+
+// validate checks that a lookup names an operation and has a valid JSONPath expression.
+func (l *Lookup) validate() error {
+	if l == nil || l.Operation == "" {
+		return fmt.Errorf("missing operation")
+	}
+	if _, err := jsonpath.NewPath(l.Values, config.WithPropertyNameExtension()); err != nil {
+		return fmt.Errorf("invalid values %q: %w", l.Values, err)
+	}
+	return nil
+}
+
+// lookups returns the lookups the configuration sets for the arguments of an operation.
+// It's an error for a lookup to name an argument the operation doesn't have.
+func (c *Config) lookups(operationID string, schema *jsonschema.Schema) (map[string]*Lookup, error) {
+	if c == nil || len(c.Lookups[operationID]) == 0 {
+		return nil, nil
+	}
+	lookups := c.Lookups[operationID]
+	for name := range lookups {
+		if _, ok := schema.Properties[name]; !ok {
+			return nil, fmt.Errorf("lookup for unknown argument %q of operation %q", name, operationID)
+		}
+	}
+	return lookups, nil
+}
+
+// argumentLookup is a lookup for the values of an argument, with the tool that calls its operation.
+type argumentLookup struct {
+	tool      string
+	arguments []string // the arguments of the lookup's tool, which are filled in from the completion's context
+	path      *jsonpath.JSONPath
+
+	// values are the values last fetched, until expires
+	values  []string
+	expires time.Time
+}
+
+// completionValues returns the values of an argument listed by its schema, or by the schema of its items:
+// the allowed values of an enum, followed by examples.
+func completionValues(prop *jsonschema.Schema) []string {
+	var values []string
+	for _, s := range []*jsonschema.Schema{prop, prop.Items} {
+		if s == nil {
+			continue
+		}
+		for _, v := range slices.Concat(s.Enum, s.Examples) {
+			if str, ok := completionValue(v); ok && !slices.Contains(values, str) {
+				values = append(values, str)
+			}
+		}
+	}
+	return values
+}
+
+// completionValue returns a scalar value as a string. Objects, arrays, and nulls can't be completed.
+func completionValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil, map[string]any, []any:
+		return "", false
+	case string:
+		return v, true
+	}
+	return paramValueString(v), true
+}
+
+// lookup returns the values an argument's lookup lists, fetching them if they haven't been fetched recently.
+// Arguments of the lookup's tool are filled in from the completion's context, like an account ID the values depend on.
+func (c *EnumCatalog) lookup(ctx context.Context, l *argumentLookup, known map[string]string) ([]string, error) {
+	args := make(map[string]any)
+	for _, name := range l.arguments {
+		if v, ok := known[name]; ok {
+			args[name] = v
+		}
+	}
+	cacheable := len(args) == 0
+	c.mu.RLock()
+	values, fresh := l.values, cacheable && time.Now().Before(l.expires)
+	c.mu.RUnlock()
+	if fresh {
+		return values, nil
+	}
+
+	session, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: l.tool, Arguments: args})
+	if err != nil {
+		return nil, err
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("lookup %s failed", l.tool)
+	}
+	data, err := json.Marshal(result.StructuredContent)
+	if result.StructuredContent == nil {
+		for _, content := range result.Content {
+			if text, ok := content.(*mcp.TextContent); ok {
+				data, err = []byte(text.Text), nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing lookup %s response: %w", l.tool, err)
+	}
+	values = nil
+	for _, node := range l.path.Query(&doc) {
+		if node.Kind == yaml.ScalarNode && node.Tag != "!!null" && !slices.Contains(values, node.Value) {
+			values = append(values, node.Value)
+		}
+	}
+	if cacheable {
+		c.mu.Lock()
+		l.values, l.expires = values, time.Now().Add(lookupTTL)
+		c.mu.Unlock()
+	}
+	return values, nil
+}
+
+// connect returns an in-process client session for calling lookup tools,
+// so that lookups are made exactly as if a client had called them, including middleware.
+func (c *EnumCatalog) connect(ctx context.Context) (*mcp.ClientSession, error) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	if c.session != nil {
+		return c.session, nil
+	}
+	if c.server == nil {
+		return nil, fmt.Errorf("no server for lookups")
+	}
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	if _, err := c.server.Connect(ctx, serverTransport, nil); err != nil {
+		return nil, fmt.Errorf("error connecting lookup session: %w", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "emcee-lookup", Version: "dev"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting lookup session: %w", err)
+	}
+	c.session = session
+	return session, nil
+}
+
+// matchCompletions adds the values that start with prefix, ignoring case, to a result, in order.
+func matchCompletions(result *mcp.CompleteResult, values []string, prefix string) {
+	prefix = strings.ToLower(prefix)
+	for _, v := range values {
+		if !strings.HasPrefix(strings.ToLower(v), prefix) {
+			continue
+		}
+		result.Completion.Total++
+		if len(result.Completion.Values) < maxCompletionValues {
+			result.Completion.Values = append(result.Completion.Values, v)
+		}
+	}
+}
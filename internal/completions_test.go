@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletions(t *testing.T) {
+	var lookups atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if account := r.URL.Query().Get("account"); account != "" {
+			fmt.Fprintf(w, `{"result": [{"id": "%s-zone"}]}`, account)
+			return
+		}
+		_, _ = w.Write([]byte(`{"result": [{"id": "zone-a"}, {"id": "zone-b"}, {"id": null}, {"id": "dns-1"}]}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Zone API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/zones": {
+      "get": {
+        "operationId": "listZones",
+        "parameters": [{"name": "account", "in": "query", "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/records": {
+      "get": {
+        "operationId": "listRecords",
+        "parameters": [
+          {"name": "zone_id", "in": "query", "schema": {"type": "string", "examples": ["zone-example"]}},
+          {"name": "type", "in": "query", "schema": {"type": "string", "enum": ["A", "AAAA", "CNAME"]}},
+          {"name": "ttl", "in": "query", "schema": {"type": "integer", "examples": [300, 3600]}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	config := &Config{Lookups: map[string]map[string]*Lookup{
+		"listRecords": {"zone_id": {Operation: "listZones", Values: "$.result[*].id"}},
+	}}
+	catalog := NewEnumCatalog(0)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, &mcp.ServerOptions{CompletionHandler: catalog.Complete})
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithEnumCatalog(catalog), WithConfig(config)))
+
+	tools, err := connectTestClient(t, ctx, server).ListTools(ctx, nil)
+	require.NoError(t, err)
+	for _, tool := range tools.Tools {
+		if tool.Name == "listRecords" {
+			assert.Len(t, tool.InputSchema.Properties["type"].Enum, 3, "enums are kept in input schemas")
+		}
+	}
+
+	complete := func(argument, value string, known map[string]string) []string {
+		params := &mcp.CompleteParams{
+			Ref:      &mcp.CompleteReference{Type: "ref/resource", URI: enumResourceURI("listRecords", argument)},
+			Argument: mcp.CompleteParamsArgument{Name: argument, Value: value},
+		}
+		if known != nil {
+			params.Context = &mcp.CompleteContext{Arguments: known}
+		}
+		result, err := catalog.Complete(ctx, &mcp.ServerRequest[*mcp.CompleteParams]{Params: params})
+		require.NoError(t, err)
+		return result.Completion.Values
+	}
+
+	assert.Equal(t, []string{"A", "AAAA"}, complete("type", "a", nil), "enum values")
+	assert.Equal(t, []string{"300", "3600"}, complete("ttl", "3", nil), "examples")
+
+	// Looked up values follow the values the spec lists, and are cached
+	assert.Equal(t, []string{"zone-example", "zone-a", "zone-b"}, complete("zone_id", "zone", nil))
+	assert.Equal(t, []string{"dns-1"}, complete("zone_id", "d", nil))
+	assert.EqualValues(t, 1, lookups.Load())
+
+	// Arguments of the lookup operation are filled in from the completion's context
+	assert.Equal(t, []string{"acct-zone"}, complete("zone_id", "acct", map[string]string{"account": "acct"}))
+	assert.EqualValues(t, 2, lookups.Load())
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, tt := range []struct {
+			lookups map[string]map[string]*Lookup
+			err     string
+		}{
+			{map[string]map[string]*Lookup{"listRecords": {"zone": {Operation: "listZones", Values: "$.result[*].id"}}}, `lookup for unknown argument "zone"`},
+			{map[string]map[string]*Lookup{"listRecords": {"zone_id": {Operation: "getZones", Values: "$.result[*].id"}}}, `unknown operation "getZones"`},
+		} {
+			server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+			err := RegisterTools(server, []byte(spec), nil, WithEnumCatalog(NewEnumCatalog(0)), WithConfig(&Config{Lookups: tt.lookups}))
+			assert.ErrorContains(t, err, tt.err)
+		}
+
+		_, err := ParseConfig([]byte("lookups: {listRecords: {zone_id: {operation: listZones, values: '$.result[*'}}}"))
+		assert.ErrorContains(t, err, "invalid lookup")
+		_, err = ParseConfig([]byte("lookups: {listRecords: {zone_id: {values: '$.result[*].id'}}}"))
+		assert.ErrorContains(t, err, "missing operation")
+	})
+}
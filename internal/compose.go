@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+// maxDescribedAlternatives bounds how many oneOf or anyOf alternatives are listed in a description.
+const maxDescribedAlternatives = 10
+
+// mergeAllOf returns a schema with the members of its allOf merged into it:
+// their properties, required properties, and other keywords the schema doesn't set itself.
+// Clients that don't understand allOf would otherwise miss them, like the required fields of a composed request body.
+// oneOf and anyOf alternatives of members are kept as alternatives of the merged schema.
+// The schema itself isn't modified.
+func mergeAllOf(s *base.Schema) *base.Schema {
+	return mergeAllOfDepth(s, 0)
+}
+
+func mergeAllOfDepth(s *base.Schema, depth int) *base.Schema {
+	if s == nil || len(s.AllOf) == 0 || depth > maxSchemaDepth {
+		return s
+	}
+	merged := *s
+	merged.AllOf = nil
+	merged.Properties = orderedmap.New[string, *base.SchemaProxy]()
+	merged.Required = nil
+
+	// Members are merged in order, followed by the schema's own keywords, which take precedence
+	members := make([]*base.Schema, 0, len(s.AllOf)+1)
+	for _, sp := range s.AllOf {
+		if sp == nil {
+			continue
+		}
+		if member := mergeAllOfDepth(sp.Schema(), depth+1); member != nil {
+			members = append(members, member)
+		}
+	}
+	own := *s
+	own.AllOf = nil
+	members = append(members, &own)
+
+	for _, member := range members {
+		if member.Properties != nil {
+			for prop := member.Properties.First(); prop != nil; prop = prop.Next() {
+				merged.Properties.Set(prop.Key(), prop.Value())
+			}
+		}
+		for _, r := range member.Required {
+			if !slices.Contains(merged.Required, r) {
+				merged.Required = append(merged.Required, r)
+			}
+		}
+		if member == &own {
+			continue
+		}
+		if len(merged.Type) == 0 {
+			merged.Type = member.Type
+		}
+		merged.Title = cmp.Or(merged.Title, member.Title)
+		merged.Description = cmp.Or(merged.Description, member.Description)
+		merged.Format = cmp.Or(merged.Format, member.Format)
+		merged.Pattern = cmp.Or(merged.Pattern, member.Pattern)
+		if len(merged.Enum) == 0 {
+			merged.Enum = member.Enum
+		}
+		merged.Items = firstNonNil(merged.Items, member.Items)
+		merged.AdditionalProperties = firstNonNil(merged.AdditionalProperties, member.AdditionalProperties)
+		merged.Minimum = firstNonNil(merged.Minimum, member.Minimum)
+		merged.Maximum = firstNonNil(merged.Maximum, member.Maximum)
+		merged.MinLength = firstNonNil(merged.MinLength, member.MinLength)
+		merged.MaxLength = firstNonNil(merged.MaxLength, member.MaxLength)
+		merged.MinItems = firstNonNil(merged.MinItems, member.MinItems)
+		merged.MaxItems = firstNonNil(merged.MaxItems, member.MaxItems)
+		merged.Default = firstNonNil(merged.Default, member.Default)
+		merged.Example = firstNonNil(merged.Example, member.Example)
+		merged.Nullable = firstNonNil(merged.Nullable, member.Nullable)
+		merged.ReadOnly = firstNonNil(merged.ReadOnly, member.ReadOnly)
+		merged.WriteOnly = firstNonNil(merged.WriteOnly, member.WriteOnly)
+		merged.OneOf = append(slices.Clip(merged.OneOf), member.OneOf...)
+		merged.AnyOf = append(slices.Clip(merged.AnyOf), member.AnyOf...)
+	}
+	if merged.Properties.Len() == 0 {
+		merged.Properties = nil
+	}
+	return &merged
+}
+
+// firstNonNil returns a if it isn't nil, and b otherwise.
+func firstNonNil[T any](a, b *T) *T {
+	if a != nil {
+		return a
+	}
+	return b
+}
+
+// describeAlternatives describes the oneOf or anyOf alternatives of a schema, one per line,
+// by their names and properties, so that models can tell which shapes a value can take.
+// It returns "" if the schema has no alternatives.
+func describeAlternatives(s *base.Schema) string {
+	if s == nil {
+		return ""
+	}
+	alternatives, intro := s.OneOf, "Must match exactly one of:"
+	if len(alternatives) == 0 {
+		alternatives, intro = s.AnyOf, "Must match at least one of:"
+	}
+	if len(alternatives) == 0 {
+		return ""
+	}
+	lines := []string{intro}
+	for i, sp := range alternatives {
+		if i == maxDescribedAlternatives {
+			lines = append(lines, fmt.Sprintf("- and %d more", len(alternatives)-i))
+			break
+		}
+		lines = append(lines, "- "+describeAlternative(sp))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeAlternative describes an alternative by its title or component name and its properties,
+// with required properties marked, or by its type if it doesn't have properties.
+func describeAlternative(sp *base.SchemaProxy) string {
+	if sp == nil {
+		return "any value"
+	}
+	s := mergeAllOf(sp.Schema())
+	if s == nil {
+		return "any value"
+	}
+	name := s.Title
+	if name == "" && sp.IsReference() {
+		name = path.Base(sp.GetReference())
+	}
+	var details string
+	if s.Properties != nil && s.Properties.Len() > 0 {
+		var props []string
+		for prop := s.Properties.First(); prop != nil; prop = prop.Next() {
+			if slices.Contains(s.Required, prop.Key()) {
+				props = append(props, prop.Key()+" (required)")
+			} else {
+				props = append(props, prop.Key())
+			}
+		}
+		details = "an object with " + strings.Join(props, ", ")
+	} else if len(s.Type) > 0 {
+		details = strings.Join(s.Type, " or ")
+	}
+	switch {
+	case name != "" && details != "":
+		return name + ": " + details
+	case name != "":
+		return name
+	case details != "":
+		return details
+	}
+	return "any value"
+}
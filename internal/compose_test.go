@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposedRequestBodies(t *testing.T) {
+	var received map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = nil
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`
+openapi: 3.1.0
+info: {title: Pet API, version: 1.0.0}
+servers: [{url: %q}]
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              allOf:
+                - $ref: '#/components/schemas/NewPet'
+                - type: object
+                  required: [owner]
+                  properties:
+                    owner: {type: string}
+                    id: {type: integer, readOnly: true}
+      responses: {"201": {description: Created}}
+  /payments:
+    post:
+      operationId: createPayment
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              oneOf:
+                - $ref: '#/components/schemas/Card'
+                - title: Bank account
+                  type: object
+                  required: [iban]
+                  properties:
+                    iban: {type: string}
+                    bic: {type: string}
+      responses: {"201": {description: Created}}
+components:
+  schemas:
+    NewPet:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string}
+        tag:
+          allOf:
+            - $ref: '#/components/schemas/Tag'
+          description: The pet's tag.
+    Tag:
+      type: string
+      enum: [cat, dog]
+    Card:
+      type: object
+      required: [number]
+      properties:
+        number: {type: string}
+`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	schemas := make(map[string]*mcp.Tool)
+	for _, tool := range tools.Tools {
+		schemas[tool.Name] = tool
+	}
+
+	t.Run("allOf", func(t *testing.T) {
+		require.Contains(t, schemas, "createPet")
+		pet := schemas["createPet"].InputSchema
+		// The members' properties are flattened into arguments, with the required ones of every member
+		assert.ElementsMatch(t, []string{"name", "tag", "owner"}, slices.Collect(maps.Keys(pet.Properties)))
+		assert.ElementsMatch(t, []string{"name", "owner"}, pet.Required)
+		tag := pet.Properties["tag"]
+		assert.Equal(t, "string", tag.Type)
+		assert.Equal(t, []any{"cat", "dog"}, tag.Enum)
+		assert.Contains(t, tag.Description, "The pet's tag.")
+		assert.Empty(t, tag.AllOf)
+
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+			Name:      "createPet",
+			Arguments: map[string]any{"name": "Rex", "tag": "dog", "owner": "Ada"},
+		})
+		require.NoError(t, err)
+		assert.False(t, result.IsError)
+		assert.Equal(t, map[string]any{"name": "Rex", "tag": "dog", "owner": "Ada"}, received)
+	})
+
+	t.Run("oneOf", func(t *testing.T) {
+		require.Contains(t, schemas, "createPayment")
+		body := schemas["createPayment"].InputSchema.Properties["body"]
+		require.NotNil(t, body)
+		assert.Len(t, body.OneOf, 2)
+		assert.Equal(t, "object", body.Type, "alternatives that share a type give the body that type")
+		assert.Equal(t, "Must match exactly one of:\n"+
+			"- Card: an object with number (required)\n"+
+			"- Bank account: an object with iban (required), bic", body.Description)
+	})
+}
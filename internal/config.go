@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the contents of an emcee configuration file, in YAML or JSON.
+type Config struct {
+	// DisabledOperations lists operation IDs that aren't exposed as tools.
+	DisabledOperations []string `yaml:"disabledOperations" json:"disabledOperations,omitempty"`
+	// DisabledEndpoints lists endpoints that aren't exposed as tools, as "METHOD /path" (e.g. "DELETE /pets/{petId}").
+	DisabledEndpoints []string `yaml:"disabledEndpoints" json:"disabledEndpoints,omitempty"`
+	// DisabledPaths lists paths whose operations aren't exposed as tools, including those of paths beneath them.
+	// Paths may contain wildcards, as in path.Match (e.g. "/admin/*").
+	DisabledPaths []string `yaml:"disabledPaths" json:"disabledPaths,omitempty"`
+	// Defaults sets default argument values by operation ID, like a fixed account ID or API version.
+	// Arguments with defaults are optional, and the default is used when a call omits them.
+	Defaults map[string]map[string]any `yaml:"defaults" json:"defaults,omitempty"`
+	// FixedArguments sets the values of arguments by name, for every operation that has them,
+	// like the organization or user whose data the server may access.
+	// They're removed from tools' input schemas, and values passed by calls are ignored.
+	FixedArguments map[string]any `yaml:"fixedArguments" json:"fixedArguments,omitempty"`
+	// Pagination maps the reserved _page and _limit arguments to the arguments each operation uses for pagination.
+	Pagination *Pagination `yaml:"pagination" json:"pagination,omitempty"`
+	// Prefetch lists GET operations, by ID, whose responses are fetched into the response cache at startup,
+	// like lists of countries or categories. Their required arguments must have defaults.
+	Prefetch []string `yaml:"prefetch" json:"prefetch,omitempty"`
+	// DestructiveOperations lists operations, by ID, that are annotated as destructive,
+	// and whose calls must be confirmed before they're sent, like a GET that triggers a deployment.
+	DestructiveOperations []string `yaml:"destructiveOperations" json:"destructiveOperations,omitempty"`
+	// Retry decides which failed requests to the API are retried.
+	// The --retry-status and --retry-unsafe-methods flags override its settings.
+	Retry *RetryPolicy `yaml:"retry" json:"retry,omitempty"`
+	// RateLimits sets budgets for requests to each host and calls of each operation, in requests per second.
+	RateLimits *RateLimits `yaml:"rateLimits" json:"rateLimits,omitempty"`
+	// Timeouts sets how long calls of each operation may take, in seconds, by operation ID,
+	// for slow operations that need longer than --timeout.
+	Timeouts map[string]float64 `yaml:"timeouts" json:"timeouts,omitempty"`
+	// Lookups sets operations that list the values of arguments, for completing them, by operation ID and argument name.
+	Lookups map[string]map[string]*Lookup `yaml:"lookups" json:"lookups,omitempty"`
+	// Workflows are tools that call several operations in turn, by tool name,
+	// passing arguments and results from earlier steps to later ones.
+	Workflows map[string]*Workflow `yaml:"workflows" json:"workflows,omitempty"`
+	// Signing signs every request to the API with an HMAC, for APIs that require signed requests.
+	Signing *RequestSigning `yaml:"signing" json:"signing,omitempty"`
+	// Auth sets the credentials of operations and paths, in place of those given for every request.
+	// The first that names an operation, or a path it's beneath, is used.
+	Auth []*AuthOverride `yaml:"auth" json:"auth,omitempty"`
+	// ResponseFields removes fields from JSON responses before they reach the model, like personal data or tokens.
+	ResponseFields *ResponseFields `yaml:"responseFields" json:"responseFields,omitempty"`
+}
+
+// LoadConfig reads a configuration file. Unknown fields are an error, so that typos don't go unnoticed.
+func LoadConfig(name string) (*Config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig parses the contents of a configuration file.
+func ParseConfig(data []byte) (*Config, error) {
+	c := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing config file: %w", err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validate checks that endpoints and path patterns are well-formed.
+func (c *Config) validate() error {
+	for _, endpoint := range c.DisabledEndpoints {
+		if _, _, ok := parseEndpoint(endpoint); !ok {
+			return fmt.Errorf("invalid disabled endpoint %q (expected METHOD /path)", endpoint)
+		}
+	}
+	for _, pattern := range c.DisabledPaths {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.HasPrefix(pattern, "/") {
+			return fmt.Errorf("invalid disabled path %q", pattern)
+		}
+	}
+	if _, ok := c.FixedArguments[""]; ok {
+		return fmt.Errorf("invalid fixed argument: empty name")
+	}
+	if c.Retry != nil {
+		if err := c.Retry.validate(); err != nil {
+			return err
+		}
+	}
+	if c.RateLimits != nil {
+		if err := c.RateLimits.validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateTimeouts(c.Timeouts); err != nil {
+		return err
+	}
+	for operationID, lookups := range c.Lookups {
+		for argument, lookup := range lookups {
+			if err := lookup.validate(); err != nil {
+				return fmt.Errorf("invalid lookup for argument %q of operation %q: %w", argument, operationID, err)
+			}
+		}
+	}
+	if c.Pagination != nil {
+		if err := c.Pagination.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Signing != nil {
+		if err := c.Signing.validate(); err != nil {
+			return err
+		}
+	}
+	for i, o := range c.Auth {
+		if o == nil {
+			return fmt.Errorf("invalid auth[%d]: empty", i)
+		}
+		if err := o.validate(); err != nil {
+			return fmt.Errorf("invalid auth[%d]: %w", i, err)
+		}
+	}
+	if c.ResponseFields != nil {
+		if err := c.ResponseFields.validate(); err != nil {
+			return fmt.Errorf("invalid responseFields: %w", err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Workflows)) {
+		if err := c.Workflows[name].validate(); err != nil {
+			return fmt.Errorf("invalid workflow %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WithConfig applies a configuration file's settings.
+// Disabled operations are neither listed nor callable.
+func WithConfig(c *Config) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.config = c }
+}
+
+// disables reports whether the configuration disables an operation.
+func (c *Config) disables(method, p, operationID string) bool {
+	if c == nil {
+		return false
+	}
+	for _, id := range c.DisabledOperations {
+		if id == operationID {
+			return true
+		}
+	}
+	for _, endpoint := range c.DisabledEndpoints {
+		if m, pattern, ok := parseEndpoint(endpoint); ok && strings.EqualFold(m, method) && matchPath(pattern, p) {
+			return true
+		}
+	}
+	return c.disablesPath(p)
+}
+
+// disablesPath reports whether the configuration disables every operation of a path.
+func (c *Config) disablesPath(p string) bool {
+	if c == nil {
+		return false
+	}
+	for _, pattern := range c.DisabledPaths {
+		if matchPathPrefix(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// argumentDefaults returns the default argument values the configuration sets for an operation,
+// and makes those arguments optional in the operation's input schema, recording each default there.
+// It's an error for a default to name an argument the operation doesn't have.
+func (c *Config) argumentDefaults(operationID string, schema *jsonschema.Schema) (map[string]any, error) {
+	if c == nil || len(c.Defaults[operationID]) == 0 {
+		return nil, nil
+	}
+	defaults := c.Defaults[operationID]
+	for name, value := range defaults {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			return nil, fmt.Errorf("config sets a default for %q, which isn't an argument of operation %q", name, operationID)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default for argument %q of operation %q: %w", name, operationID, err)
+		}
+		prop.Default = data
+		schema.Required = slices.DeleteFunc(schema.Required, func(r string) bool { return r == name })
+	}
+	return defaults, nil
+}
+
+// removeFixedArguments removes the arguments the configuration fixes from an operation's input schema,
+// so that the model can't set them, and reports whether there were any.
+func (c *Config) removeFixedArguments(schema *jsonschema.Schema) bool {
+	if c == nil || len(c.FixedArguments) == 0 {
+		return false
+	}
+	removed := false
+	for name := range c.FixedArguments {
+		if _, ok := schema.Properties[name]; !ok {
+			continue
+		}
+		delete(schema.Properties, name)
+		schema.Required = slices.DeleteFunc(schema.Required, func(r string) bool { return r == name })
+		removed = true
+	}
+	return removed
+}
+
+// removeFixedBodyProperties removes the arguments the configuration fixes from the schema of a request body
+// passed whole in a single argument, where they're set by the server too, and reports whether there were any.
+// They're removed from the top level of an object body, its alternatives, and the items of an array body.
+func (c *Config) removeFixedBodyProperties(schema *jsonschema.Schema) bool {
+	if schema == nil {
+		return false
+	}
+	removed := c.removeFixedArguments(schema)
+	for _, alt := range slices.Concat(schema.AllOf, schema.OneOf, schema.AnyOf) {
+		removed = c.removeFixedBodyProperties(alt) || removed
+	}
+	return c.removeFixedBodyProperties(schema.Items) || removed
+}
+
+// withDefaults returns a tool's arguments with defaults filled in for those the call omits.
+// Arguments passed by the call are never replaced.
+func withDefaults(args, defaults map[string]any) map[string]any {
+	if len(defaults) == 0 {
+		return args
+	}
+	merged := make(map[string]any, len(args)+len(defaults))
+	maps.Copy(merged, defaults)
+	maps.Copy(merged, args)
+	return merged
+}
+
+// parseEndpoint splits an endpoint of the form "METHOD /path".
+func parseEndpoint(endpoint string) (method, p string, ok bool) {
+	method, p, ok = strings.Cut(strings.TrimSpace(endpoint), " ")
+	p = strings.TrimSpace(p)
+	return method, p, ok && method != "" && strings.HasPrefix(p, "/")
+}
+
+// matchPath reports whether a path matches a pattern, ignoring trailing slashes.
+func matchPath(pattern, p string) bool {
+	pattern, p = strings.TrimSuffix(pattern, "/"), strings.TrimSuffix(p, "/")
+	if pattern == p {
+		return true
+	}
+	ok, err := path.Match(pattern, p)
+	return err == nil && ok
+}
+
+// matchPathPrefix reports whether a path or one of its ancestors matches a pattern.
+func matchPathPrefix(pattern, p string) bool {
+	for {
+		if matchPath(pattern, p) {
+			return true
+		}
+		i := strings.LastIndexByte(strings.TrimSuffix(p, "/"), '/')
+		if i <= 0 {
+			return false
+		}
+		p = p[:i]
+	}
+}
@@ -0,0 +1,554 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig([]byte(`
+disabledOperations: [deletePet]
+disabledEndpoints: ["post /pets"]
+disabledPaths: [/admin]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		DisabledOperations: []string{"deletePet"},
+		DisabledEndpoints:  []string{"post /pets"},
+		DisabledPaths:      []string{"/admin"},
+	}, c)
+
+	c, err = ParseConfig([]byte(`{"disabledOperations": ["deletePet"]}`))
+	require.NoError(t, err, "JSON is accepted")
+	assert.Equal(t, []string{"deletePet"}, c.DisabledOperations)
+
+	c, err = ParseConfig(nil)
+	require.NoError(t, err, "an empty file is an empty config")
+	assert.Equal(t, &Config{}, c)
+
+	_, err = ParseConfig([]byte(`disabledOperation: [deletePet]`))
+	assert.Error(t, err, "unknown fields are rejected")
+
+	_, err = ParseConfig([]byte(`disabledEndpoints: ["/pets"]`))
+	assert.ErrorContains(t, err, "expected METHOD /path")
+
+	_, err = ParseConfig([]byte(`disabledPaths: ["/admin/["]`))
+	assert.ErrorContains(t, err, "invalid disabled path")
+
+	c, err = ParseConfig([]byte("retry:\n  statusCodes: [429, 503]\n  unsafeMethods: true\n"))
+	require.NoError(t, err)
+	assert.Equal(t, &RetryPolicy{StatusCodes: []int{429, 503}, UnsafeMethods: true}, c.Retry)
+
+	_, err = ParseConfig([]byte(`retry: {statusCodes: [42]}`))
+	assert.ErrorContains(t, err, "invalid retry status code")
+
+	_, err = ParseConfig([]byte(`timeouts: {generateReport: 0}`))
+	assert.ErrorContains(t, err, "invalid timeout")
+}
+
+func TestLoadConfig(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "emcee.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("disabledPaths: [/admin]\n"), 0o600))
+	c, err := LoadConfig(name)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/admin"}, c.DisabledPaths)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestConfigDisables(t *testing.T) {
+	c := &Config{
+		DisabledOperations: []string{"deletePet"},
+		DisabledEndpoints:  []string{"post /pets", "PATCH /pets/*"},
+		DisabledPaths:      []string{"/admin", "/internal/*/debug"},
+	}
+	tests := []struct {
+		method, path, operationID string
+		want                      bool
+	}{
+		{"DELETE", "/pets/{petId}", "deletePet", true},
+		{"GET", "/pets/{petId}", "getPet", false},
+		{"POST", "/pets", "createPet", true},
+		{"POST", "/pets/", "createPetSlash", true},
+		{"GET", "/pets", "listPets", false},
+		{"PATCH", "/pets/{petId}", "updatePet", true},
+		{"GET", "/admin", "getAdmin", true},
+		{"GET", "/admin/users/{id}", "getAdminUser", true},
+		{"GET", "/administrators", "listAdministrators", false},
+		{"GET", "/internal/v1/debug/vars", "getDebugVars", true},
+		{"GET", "/internal/v1", "getInternal", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operationID, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.disables(tt.method, tt.path, tt.operationID))
+		})
+	}
+
+	var none *Config
+	assert.False(t, none.disables("DELETE", "/pets", "deletePet"))
+}
+
+func TestRegisterToolsWithConfig(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {
+      "get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}},
+      "post": {"operationId": "createPet", "responses": {"201": {"description": "Created"}}}
+    },
+    "/pets/{petId}": {
+      "delete": {
+        "operationId": "deletePet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"204": {"description": "Deleted"}}
+      }
+    },
+    "/admin/users": {
+      "get": {"operationId": "listUsers", "responses": {"200": {"description": "OK"}}}
+    }
+  }
+}`
+	config := &Config{
+		DisabledOperations: []string{"deletePet"},
+		DisabledEndpoints:  []string{"POST /pets"},
+		DisabledPaths:      []string{"/admin"},
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), nil, WithConfig(config)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	var names []string
+	for _, tool := range tools.Tools {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"listPets"}, names)
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "deletePet", Arguments: map[string]any{"petId": "1"}})
+	assert.Error(t, err, "disabled operations can't be called")
+}
+
+func TestRegisterToolsWithConfigDefaults(t *testing.T) {
+	var received *http.Request
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Invoice API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/accounts/{account_id}/invoices": {
+      "get": {
+        "operationId": "listInvoices",
+        "parameters": [
+          {"name": "account_id", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "api-version", "in": "query", "required": true, "schema": {"type": "string"}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer"}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+	config, err := ParseConfig([]byte(`
+defaults:
+  listInvoices:
+    account_id: acct_123
+    api-version: "2024-06-01"
+    limit: 10
+`))
+	require.NoError(t, err)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithConfig(config)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	assert.Empty(t, tools.Tools[0].InputSchema.Required, "arguments with defaults are optional")
+	assert.JSONEq(t, `"acct_123"`, string(tools.Tools[0].InputSchema.Properties["account_id"].Default))
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listInvoices", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "/accounts/acct_123/invoices", received.URL.Path)
+	assert.Equal(t, "2024-06-01", received.URL.Query().Get("api-version"))
+	assert.Equal(t, "10", received.URL.Query().Get("limit"))
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listInvoices", Arguments: map[string]any{"limit": 50}})
+	require.NoError(t, err)
+	assert.Equal(t, "50", received.URL.Query().Get("limit"), "arguments passed by the call take precedence")
+
+	config.Defaults["listInvoices"]["acount_id"] = "acct_123"
+	err = RegisterTools(mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil), []byte(spec), api.Client(), WithConfig(config))
+	assert.ErrorContains(t, err, `"acount_id", which isn't an argument of operation "listInvoices"`)
+}
+
+func TestRegisterToolsWithConfigFixedArguments(t *testing.T) {
+	var received *http.Request
+	var body map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Invoice API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/orgs/{org_id}/invoices": {
+      "parameters": [{"name": "org_id", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {
+        "operationId": "listInvoices",
+        "parameters": [{"name": "user_id", "in": "query", "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/notes": {
+      "post": {
+        "operationId": "createNote",
+        "requestBody": {"content": {"application/json": {"schema": {
+          "type": "object",
+          "properties": {"text": {"type": "string"}},
+          "additionalProperties": true
+        }}}},
+        "responses": {"201": {"description": "Created"}}
+      }
+    },
+    "/reports": {
+      "post": {
+        "operationId": "createReport",
+        "requestBody": {"required": true, "content": {"application/json": {"schema": {"oneOf": [
+          {"type": "object", "properties": {"org_id": {"type": "string"}, "name": {"type": "string"}}, "required": ["org_id", "name"]},
+          {"type": "object", "properties": {"org_id": {"type": "string"}, "template": {"type": "string"}}, "required": ["template"]}
+        ]}}}},
+        "responses": {"201": {"description": "Created"}}
+      }
+    },
+    "/reports/batch": {
+      "post": {
+        "operationId": "createReports",
+        "requestBody": {"content": {"application/json": {"schema": {
+          "type": "array",
+          "items": {"type": "object", "properties": {"org_id": {"type": "string"}, "name": {"type": "string"}}}
+        }}}},
+        "responses": {"201": {"description": "Created"}}
+      }
+    }
+  }
+}`, api.URL)
+	config, err := ParseConfig([]byte(`
+fixedArguments:
+  org_id: org_123
+  user_id: alice
+`))
+	require.NoError(t, err)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithConfig(config), WithResourceTemplates()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	for _, tool := range tools.Tools {
+		switch tool.Name {
+		case "listInvoices":
+			assert.NotContains(t, tool.InputSchema.Properties, "org_id", "fixed arguments aren't exposed")
+			assert.NotContains(t, tool.InputSchema.Properties, "user_id")
+			assert.Empty(t, tool.InputSchema.Required)
+		case "createReport":
+			require.Len(t, tool.InputSchema.Properties["body"].OneOf, 2)
+			for _, alt := range tool.InputSchema.Properties["body"].OneOf {
+				assert.NotContains(t, alt.Properties, "org_id", "fixed arguments aren't exposed in bodies passed whole")
+				assert.NotContains(t, alt.Required, "org_id")
+			}
+		case "createReports":
+			assert.NotContains(t, tool.InputSchema.Properties["body"].Items.Properties, "org_id")
+		}
+	}
+	templates, err := clientSession.ListResourceTemplates(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, templates.ResourceTemplates, "operations with fixed arguments aren't exposed as resource templates")
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listInvoices", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "/orgs/org_123/invoices", received.URL.Path)
+	assert.Equal(t, "alice", received.URL.Query().Get("user_id"))
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listInvoices", Arguments: map[string]any{"org_id": "org_456", "user_id": "bob"}})
+	require.NoError(t, err)
+	assert.Equal(t, "/orgs/org_123/invoices", received.URL.Path, "values passed by calls are ignored")
+	assert.Equal(t, "alice", received.URL.Query().Get("user_id"))
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "createNote", Arguments: map[string]any{"text": "hi", "org_id": "org_456"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"text": "hi"}, body, "fixed arguments aren't sent as additional body properties")
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "createReport", Arguments: map[string]any{
+		"body": map[string]any{"org_id": "other_tenant", "name": "x", "user_id": "bob"},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"org_id": "org_123", "name": "x"}, body, "fixed arguments are set in bodies passed whole")
+
+	var items []map[string]any
+	api.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&items)
+		w.WriteHeader(http.StatusCreated)
+	})
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "createReports", Arguments: map[string]any{
+		"body": []any{map[string]any{"org_id": "other_tenant", "name": "x"}, map[string]any{"name": "y"}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"org_id": "org_123", "name": "x"}, {"org_id": "org_123", "name": "y"}}, items, "and in each item of array bodies")
+}
+
+func TestRegisterToolsWithConfigPagination(t *testing.T) {
+	var received *http.Request
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "parameters": [
+          {"name": "offset", "in": "query", "schema": {"type": "integer"}},
+          {"name": "per_page", "in": "query", "schema": {"type": "integer", "maximum": 100}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/owners": {
+      "get": {
+        "operationId": "listOwners",
+        "parameters": [
+          {"name": "cursor", "in": "query", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+	config, err := ParseConfig([]byte(`
+pagination:
+  page: [page, offset, cursor]
+  limit: [limit, per_page]
+`))
+	require.NoError(t, err)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithConfig(config)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	schemas := map[string]*jsonschema.Schema{}
+	for _, tool := range tools.Tools {
+		schemas[tool.Name] = tool.InputSchema
+	}
+	require.Contains(t, schemas, "listPets")
+	assert.ElementsMatch(t, []string{"_page", "_limit"}, slices.Collect(maps.Keys(schemas["listPets"].Properties)))
+	assert.Equal(t, 100.0, *schemas["listPets"].Properties["_limit"].Maximum, "reserved arguments keep the schemas of the arguments they replace")
+	require.Contains(t, schemas, "listOwners")
+	assert.ElementsMatch(t, []string{"_page"}, slices.Collect(maps.Keys(schemas["listOwners"].Properties)))
+	assert.Equal(t, []string{"_page"}, schemas["listOwners"].Required)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets", Arguments: map[string]any{"_page": 40, "_limit": 20}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "40", received.URL.Query().Get("offset"))
+	assert.Equal(t, "20", received.URL.Query().Get("per_page"))
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listOwners", Arguments: map[string]any{"_page": "abc"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "abc", received.URL.Query().Get("cursor"))
+
+	_, err = ParseConfig([]byte(`pagination: {page: [""]}`))
+	assert.ErrorContains(t, err, "invalid pagination argument")
+}
+
+func TestRegisterToolsWithConfigPrefetch(t *testing.T) {
+	var requests atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`["CA", "JP", "US"]`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Reference API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/countries": {
+      "get": {
+        "operationId": "listCountries",
+        "parameters": [{"name": "locale", "in": "query", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      },
+      "post": {"operationId": "createCountry", "responses": {"201": {"description": "Created"}}}
+    }
+  }
+}`, api.URL)
+	config, err := ParseConfig([]byte(`
+prefetch: [listCountries]
+defaults:
+  listCountries:
+    locale: en
+`))
+	require.NoError(t, err)
+
+	client, err := RetryableClient(RetryableClientOptions{Cache: &CacheOptions{TTL: time.Minute}})
+	require.NoError(t, err)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), client, WithConfig(config)))
+	assert.Eventually(t, func() bool { return requests.Load() == 1 }, 2*time.Second, 10*time.Millisecond, "responses are prefetched at startup")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listCountries", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "JP")
+	assert.EqualValues(t, 1, requests.Load(), "prefetched responses are served from the cache")
+
+	config.Prefetch = []string{"createCountry"}
+	err = RegisterTools(mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil), []byte(spec), client, WithConfig(config))
+	assert.ErrorContains(t, err, "isn't a GET operation")
+
+	config, err = ParseConfig([]byte(`prefetch: [listCountries]`))
+	require.NoError(t, err)
+	err = RegisterTools(mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil), []byte(spec), client, WithConfig(config))
+	assert.ErrorContains(t, err, "requires arguments without defaults: [locale]")
+}
+
+func TestConfigTemplate(t *testing.T) {
+	c, err := ParseConfig([]byte(ConfigTemplate))
+	require.NoError(t, err)
+	assert.Empty(t, c.DisabledOperations, "the template has no effect until it's edited")
+
+	// Every setting is listed
+	configType := reflect.TypeFor[Config]()
+	for i := range configType.NumField() {
+		name, _, _ := strings.Cut(configType.Field(i).Tag.Get("yaml"), ",")
+		assert.Contains(t, ConfigTemplate, "\n"+name+":", "the template lists %s", name)
+	}
+}
+
+func TestConfigLint(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {"get": {
+      "operationId": "listPets",
+      "parameters": [{"name": "limit", "in": "query", "schema": {"type": "integer"}}],
+      "responses": {"200": {"description": "OK"}}
+    }},
+    "/admin/users": {"delete": {"operationId": "deleteUsers", "responses": {"204": {"description": "Deleted"}}}},
+    "/owners": {"post": {
+      "operationId": "createOwner",
+      "requestBody": {"content": {"application/json": {"schema": {"type": "object", "properties": {
+        "name": {"type": "string"},
+        "address": {"type": "object", "properties": {"name": {"type": "string"}}}
+      }}}}},
+      "responses": {"201": {"description": "Created"}}
+    }}
+  }
+}`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	valid := &Config{
+		DisabledOperations: []string{"deleteUsers"},
+		DisabledEndpoints:  []string{"DELETE /admin/users"},
+		DisabledPaths:      []string{"/admin"},
+		Defaults:           map[string]map[string]any{"listPets": {"limit": 10}},
+		FixedArguments:     map[string]any{"limit": 10},
+	}
+	problems, err := valid.Lint(ctx, []byte(spec))
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+
+	invalid := &Config{
+		DisabledOperations:    []string{"deletePets"},
+		DisabledEndpoints:     []string{"POST /pets"},
+		DisabledPaths:         []string{"/internal/*"},
+		Defaults:              map[string]map[string]any{"listPets": {"page": 1}},
+		DestructiveOperations: []string{"deploy"},
+		Lookups:               map[string]map[string]*Lookup{"listPets": {"owner": {Operation: "listOwners", Values: "$[*].id"}}},
+		FixedArguments:        map[string]any{"org_id": "org_123", "name": "Acme"},
+		ResponseFields:        &ResponseFields{Operations: map[string]*OperationResponseFields{"getPet": {}}},
+	}
+	problems, err = invalid.Lint(ctx, []byte(spec))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`disabledOperations: the spec has no operation "deletePets"`,
+		`destructiveOperations: the spec has no operation "deploy"`,
+		`lookups.listPets.owner.operation: the spec has no operation "listOwners"`,
+		`responseFields.operations: the spec has no operation "getPet"`,
+		`disabledEndpoints: the spec has no endpoint "POST /pets"`,
+		`disabledPaths: the spec has no path matching "/internal/*"`,
+		`fixedArguments: "name" can't be enforced in the request body of POST /owners, which has it below the top level`,
+		`fixedArguments: no operation of the spec has an argument "org_id"`,
+		`config sets a default for "page", which isn't an argument of operation "listPets"`,
+	}, problems)
+}
@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ConfigTemplate is a configuration file listing every setting, with examples, for `emcee config init`.
+// Settings are left empty, so the file has no effect until it's edited.
+const ConfigTemplate = `# emcee configuration file
+# Pass it to emcee with --config. Unknown fields are an error.
+
+# Operations that aren't exposed as tools, by operation ID
+disabledOperations: []
+#  - deletePet
+
+# Endpoints that aren't exposed as tools, as "METHOD /path"
+disabledEndpoints: []
+#  - POST /pets
+
+# Paths whose operations aren't exposed as tools, including those of paths beneath them.
+# Paths may contain wildcards (e.g. /internal/*/debug).
+disabledPaths: []
+#  - /admin
+
+# Default argument values by operation ID.
+# Arguments with defaults are optional, and the default is used when a call omits them.
+defaults: {}
+#  listInvoices:
+#    account_id: acct_123
+
+# Argument values set by the server for every operation that has the argument,
+# like the organization whose data may be accessed. They're removed from
+# tools' input schemas, and values passed by calls are ignored.
+fixedArguments: {}
+#  org_id: org_123
+
+# Arguments each operation uses for pagination, in order of preference,
+# exposed as the reserved _page and _limit arguments.
+pagination:
+  page: []
+  #  - page
+  #  - cursor
+  limit: []
+  #  - limit
+  #  - per_page
+  # Fields of responses with the next page's cursor or URL, and with each page's results,
+  # for the _fetchAll argument, which fetches up to maxPages pages (default 10)
+  next: []
+  #  - meta.next_cursor
+  items: []
+  #  - data
+  maxPages: 0
+
+# GET operations whose responses are fetched into the response cache at startup, by operation ID.
+# Their required arguments must have defaults.
+prefetch: []
+#  - listCountries
+
+# Operations that are annotated as destructive and always require confirmation, by operation ID
+destructiveOperations: []
+#  - triggerDeployment
+
+# Which failed requests are retried, up to --retries times.
+# Retries wait as long as a response's Retry-After header asks.
+retry:
+  # Response statuses that are retried (default 429 and 5XX statuses other than 501)
+  statusCodes: []
+  #  - 429
+  #  - 503
+  # Whether requests with methods that aren't idempotent, like POST and PATCH, are retried,
+  # which may repeat their effects
+  unsafeMethods: false
+
+# Budgets for requests, in requests per second, beyond the overall limit set by --rps
+rateLimits:
+  # By hostname
+  hosts: {}
+  #  api.example.com: 10
+  # By operation ID
+  operations: {}
+  #  createReport: 0.5
+
+# How long calls of slow operations may take, in seconds, instead of --timeout, by operation ID
+timeouts: {}
+#  generateReport: 300
+
+# Operations that list the values of arguments, for completing them, by operation ID and argument name.
+# Values is a JSONPath expression selecting the values in the lookup operation's response.
+lookups: {}
+#  getZone:
+#    zone_id:
+#      operation: listZones
+#      values: $.result[*].id
+
+# Tools that call several operations in turn, by tool name. Step arguments may contain templates,
+# JSONPath expressions in double braces selecting the tool's arguments and earlier steps' results.
+workflows: {}
+#  subscribeCustomer:
+#    description: Create a customer and subscribe them to a plan
+#    arguments:
+#      email: {type: string, required: true}
+#      plan: {type: string, required: true}
+#    steps:
+#      - operation: createCustomer
+#        arguments:
+#          email: "{{$.arguments.email}}"
+#      - operation: createSubscription
+#        arguments:
+#          customer: "{{$.steps.createCustomer.id}}"
+#          price: "{{$.arguments.plan}}"
+
+# Signs every request to the API with an HMAC, for APIs that require signed requests.
+# The message signed has placeholders for parts of the request: {method}, {host}, {path}, {query},
+# {body}, {bodySha256}, {timestamp}, and {header:Name}.
+signing: null
+#  secret: env://API_SIGNING_SECRET
+#  algorithm: sha256       # or sha512, sha1
+#  encoding: hex           # or base64
+#  header: X-Signature
+#  prefix: "sha256="
+#  timestampHeader: X-Timestamp
+#  timestampFormat: unix   # or unixMilli, rfc3339
+#  message: "{timestamp}.{method}.{path}.{body}"
+
+# Credentials of operations and paths, in place of those given for every request,
+# like a different token for admin endpoints. The first entry naming an operation,
+# by ID, or a path it's beneath is used. Each sets one of bearer, basic, raw,
+# apiKey (sent as the spec's apiKey security scheme, or the one named by scheme), or none.
+# Values can be secret references.
+auth: []
+#  - paths: [/admin]
+#    bearer: env://ADMIN_TOKEN
+#  - operations: [createReport]
+#    apiKey: env://REPORTS_API_KEY
+#    scheme: ReportsKey
+#  - operations: [login]
+#    none: true
+
+# Fields removed from JSON responses before they reach the model, by JSONPath.
+# An operation's entry, by operation ID, replaces deny: allow keeps only the fields
+# it selects, and deny removes fields. An empty entry keeps every field.
+responseFields: null
+#  deny:
+#    - $..ssn
+#    - $..access_token
+#  operations:
+#    getUser:
+#      allow: [$.id, $.name, $.email]
+`
+
+// Lint checks a configuration against a spec, and returns a description of each problem:
+// settings that name operations or endpoints the spec doesn't have, which are otherwise ignored,
+// and settings the spec's operations can't satisfy, which would keep emcee from starting.
+func (c *Config) Lint(ctx context.Context, specData []byte) ([]string, error) {
+	model, _, err := buildModel(specData, nil, specReferences{})
+	if err != nil {
+		return nil, err
+	}
+	operationIDs := make(map[string]struct{})
+	var endpoints []string
+	var eps []*endpoint
+	if model.Model.Paths != nil && model.Model.Paths.PathItems != nil {
+		for pair := model.Model.Paths.PathItems.First(); pair != nil; pair = pair.Next() {
+			ops, err := pathOperations(pair.Value())
+			if err != nil {
+				return nil, err
+			}
+			for _, op := range ops {
+				endpoints = append(endpoints, op.method+" "+pair.Key())
+				eps = append(eps, &endpoint{path: pair.Key(), method: op.method, pathItem: pair.Value(), op: op.op})
+				if op.op.OperationId != "" {
+					operationIDs[op.op.OperationId] = struct{}{}
+				}
+			}
+		}
+	}
+
+	var problems []string
+	checkOperations := func(setting string, ids []string) {
+		for _, id := range ids {
+			if _, ok := operationIDs[id]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: the spec has no operation %q", setting, id))
+			}
+		}
+	}
+	checkOperations("disabledOperations", c.DisabledOperations)
+	checkOperations("defaults", slices.Sorted(maps.Keys(c.Defaults)))
+	checkOperations("prefetch", c.Prefetch)
+	checkOperations("destructiveOperations", c.DestructiveOperations)
+	checkOperations("lookups", slices.Sorted(maps.Keys(c.Lookups)))
+	for _, id := range slices.Sorted(maps.Keys(c.Lookups)) {
+		for _, argument := range slices.Sorted(maps.Keys(c.Lookups[id])) {
+			if l := c.Lookups[id][argument]; l != nil {
+				checkOperations(fmt.Sprintf("lookups.%s.%s.operation", id, argument), []string{l.Operation})
+			}
+		}
+	}
+	if c.RateLimits != nil {
+		checkOperations("rateLimits.operations", slices.Sorted(maps.Keys(c.RateLimits.Operations)))
+	}
+	checkOperations("timeouts", slices.Sorted(maps.Keys(c.Timeouts)))
+	if c.ResponseFields != nil {
+		checkOperations("responseFields.operations", slices.Sorted(maps.Keys(c.ResponseFields.Operations)))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Workflows)) {
+		if w := c.Workflows[name]; w != nil {
+			for i, step := range w.Steps {
+				if step != nil {
+					checkOperations(fmt.Sprintf("workflows.%s.steps[%d].operation", name, i), []string{step.Operation})
+				}
+			}
+		}
+	}
+	for _, endpoint := range c.DisabledEndpoints {
+		method, pattern, _ := parseEndpoint(endpoint)
+		if !slices.ContainsFunc(endpoints, func(e string) bool {
+			m, p, _ := strings.Cut(e, " ")
+			return strings.EqualFold(m, method) && matchPath(pattern, p)
+		}) {
+			problems = append(problems, fmt.Sprintf("disabledEndpoints: the spec has no endpoint %q", endpoint))
+		}
+	}
+	checkPaths := func(setting string, patterns []string) {
+		for _, pattern := range patterns {
+			if !slices.ContainsFunc(endpoints, func(e string) bool {
+				_, p, _ := strings.Cut(e, " ")
+				return matchPathPrefix(pattern, p)
+			}) {
+				problems = append(problems, fmt.Sprintf("%s: the spec has no path matching %q", setting, pattern))
+			}
+		}
+	}
+	checkPaths("disabledPaths", c.DisabledPaths)
+	for i, o := range c.Auth {
+		if o != nil {
+			checkOperations(fmt.Sprintf("auth[%d].operations", i), o.Operations)
+			checkPaths(fmt.Sprintf("auth[%d].paths", i), o.Paths)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.FixedArguments)) {
+		if !slices.ContainsFunc(eps, func(ep *endpoint) bool { return ep.declares(name) }) {
+			problems = append(problems, fmt.Sprintf("fixedArguments: no operation of the spec has an argument %q", name))
+		}
+		// Fixed arguments are only set at the top level of request bodies, so values nested deeper are the model's
+		for _, ep := range eps {
+			if rb := operationRequestBody(ep.op); rb != nil && nestsProperty(rb.schema, name) {
+				problems = append(problems, fmt.Sprintf("fixedArguments: %q can't be enforced in the request body of %s %s, which has it below the top level", name, ep.method, ep.path))
+			}
+		}
+	}
+
+	// Generating the tools reports settings that don't fit the operations they name, like defaults for missing arguments
+	if _, err := ListTools(ctx, specData, WithConfig(c)); err != nil {
+		problems = append(problems, err.Error())
+	}
+	return problems, nil
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigResourceURI is the URI of the resource exposing the tool filters of the configuration file.
+const ConfigResourceURI = "emcee://config/filters"
+
+// ConfigUpdateMethod is the experimental JSON-RPC method for replacing the tool filters of the configuration file.
+const ConfigUpdateMethod = "config/update"
+
+// ToolFilters are the settings of a configuration file that decide which operations are exposed as tools.
+type ToolFilters struct {
+	DisabledOperations []string `json:"disabledOperations"`
+	DisabledEndpoints  []string `json:"disabledEndpoints"`
+	DisabledPaths      []string `json:"disabledPaths"`
+}
+
+// configUpdateParams are the parameters of a config/update request.
+type configUpdateParams struct {
+	Token   string       `json:"token"`
+	Filters *ToolFilters `json:"filters"`
+}
+
+// ConfigEditor lets an operator read and replace the tool filters of the configuration file while emcee runs,
+// so that tools can be curated interactively. Changes take effect at once, and are saved to the file.
+type ConfigEditor struct {
+	reloader *Reloader
+	path     string
+	token    string
+
+	mu     sync.Mutex
+	config *Config
+}
+
+// NewConfigEditor returns an editor of the configuration file at path, whose contents are config,
+// that reconfigures the tools of reloader. Updates must carry token.
+func NewConfigEditor(reloader *Reloader, path string, config *Config, token string) *ConfigEditor {
+	if config == nil {
+		config = &Config{}
+	}
+	return &ConfigEditor{reloader: reloader, path: path, token: token, config: config}
+}
+
+// filters returns the tool filters of the current configuration.
+func (e *ConfigEditor) filters() *ToolFilters {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return &ToolFilters{
+		DisabledOperations: nonNil(e.config.DisabledOperations),
+		DisabledEndpoints:  nonNil(e.config.DisabledEndpoints),
+		DisabledPaths:      nonNil(e.config.DisabledPaths),
+	}
+}
+
+// nonNil returns s, or an empty slice if s is nil, so that it's encoded as an empty JSON array.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// AddResource exposes the tool filters on server as a JSON resource at ConfigResourceURI.
+func (e *ConfigEditor) AddResource(server *mcp.Server) {
+	server.AddResource(&mcp.Resource{
+		URI:         ConfigResourceURI,
+		Name:        "Tool filters",
+		Description: "The operations, endpoints, and paths the configuration file disables, which can be replaced with config/update requests",
+		MIMEType:    "application/json",
+	}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+		data, err := json.MarshalIndent(e.filters(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{URI: ConfigResourceURI, MIMEType: "application/json", Text: string(data)}},
+		}, nil
+	})
+}
+
+// Handle handles a config/update request, replacing the tool filters and saving them to the configuration file.
+// If the tools can't be registered with the new filters, or the file can't be written, the previous filters stay in place.
+func (e *ConfigEditor) Handle(ctx context.Context, params json.RawMessage) (any, error) {
+	var p configUpdateParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, invalidConfigUpdateError(err.Error())
+	}
+	if subtle.ConstantTimeCompare([]byte(p.Token), []byte(e.token)) != 1 {
+		return nil, rpcError(invalidParamsCode, "unauthorized: invalid token",
+			errorData{Kind: errorKindUnauthorized, Operation: ConfigUpdateMethod})
+	}
+	if p.Filters == nil {
+		return nil, invalidConfigUpdateError("missing filters")
+	}
+
+	e.mu.Lock()
+	prev := e.config
+	next := *prev
+	next.DisabledOperations = p.Filters.DisabledOperations
+	next.DisabledEndpoints = p.Filters.DisabledEndpoints
+	next.DisabledPaths = p.Filters.DisabledPaths
+	if err := next.validate(); err != nil {
+		e.mu.Unlock()
+		return nil, invalidConfigUpdateError(err.Error())
+	}
+	if err := e.reloader.Reconfigure(&next); err != nil {
+		e.mu.Unlock()
+		return nil, invalidConfigUpdateError(err.Error())
+	}
+	if err := saveToolFilters(e.path, p.Filters); err != nil {
+		if restoreErr := e.reloader.Reconfigure(prev); restoreErr != nil {
+			err = fmt.Errorf("%w (and error restoring the previous filters: %v)", err, restoreErr)
+		}
+		e.mu.Unlock()
+		return nil, err
+	}
+	e.config = &next
+	e.mu.Unlock()
+
+	_ = e.reloader.server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: ConfigResourceURI})
+	return e.filters(), nil
+}
+
+// invalidConfigUpdateError returns an "invalid params" error for a config/update request that can't be applied.
+func invalidConfigUpdateError(detail string) error {
+	return rpcError(invalidParamsCode, fmt.Sprintf("%v: %s", errInvalidParams, detail),
+		errorData{Kind: errorKindInvalidParams, Operation: ConfigUpdateMethod, Detail: detail})
+}
+
+// saveToolFilters replaces the tool filters of the configuration file at name, keeping its other settings and its format.
+// Comments in YAML files are kept too. The file is replaced at once, so that it's never left half-written.
+func saveToolFilters(name string, filters *ToolFilters) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("error parsing config file: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("error parsing config file: expected a mapping")
+	}
+	setSequence(root, "disabledOperations", filters.DisabledOperations)
+	setSequence(root, "disabledEndpoints", filters.DisabledEndpoints)
+	setSequence(root, "disabledPaths", filters.DisabledPaths)
+
+	var b bytes.Buffer
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var compact bytes.Buffer
+		if err := writeJSON(&compact, root); err != nil {
+			return fmt.Errorf("error encoding config file: %w", err)
+		}
+		if err := json.Indent(&b, compact.Bytes(), "", "  "); err != nil {
+			return fmt.Errorf("error encoding config file: %w", err)
+		}
+		b.WriteByte('\n')
+	} else {
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(&doc); err != nil {
+			return fmt.Errorf("error encoding config file: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return fmt.Errorf("error encoding config file: %w", err)
+		}
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := f.Chmod(info.Mode().Perm()); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	return nil
+}
+
+// setSequence sets a key of a mapping node to a sequence of strings, adding it if it's missing,
+// or removes the key if values is empty.
+func setSequence(mapping *yaml.Node, key string, values []string) {
+	var seq *yaml.Node
+	if len(values) > 0 {
+		seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, v := range values {
+			seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+		}
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if seq == nil {
+			// Comments above a removed key stay in place, above the key that follows it
+			if comment := mapping.Content[i].HeadComment; comment != "" && i+2 < len(mapping.Content) {
+				next := mapping.Content[i+2]
+				next.HeadComment = strings.TrimSpace(comment + "\n" + next.HeadComment)
+			}
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		} else {
+			mapping.Content[i+1] = seq
+		}
+		return
+	}
+	if seq != nil {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, seq)
+	}
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigEditor(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	name := filepath.Join(t.TempDir(), "emcee.yaml")
+	require.NoError(t, os.WriteFile(name, []byte("# Tools for the Pet API\ndisabledOperations: [listOwners]\ntimeouts:\n  listPets: 30\n"), 0o600))
+	config, err := LoadConfig(name)
+	require.NoError(t, err)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	reloader := NewReloader(server, nil, WithConfig(config))
+	require.NoError(t, reloader.Load(reloadTestSpec(`
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}},
+    "/owners": {"get": {"operationId": "listOwners", "responses": {"200": {"description": "OK"}}}}`)))
+	editor := NewConfigEditor(reloader, name, config, "secret")
+	editor.AddResource(server)
+	clientSession := connectTestClient(t, ctx, server)
+
+	toolNames := func() []string {
+		tools, err := clientSession.ListTools(ctx, nil)
+		require.NoError(t, err)
+		var names []string
+		for _, tool := range tools.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"listPets"}, toolNames())
+
+	resource, err := clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: ConfigResourceURI})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"disabledOperations": ["listOwners"], "disabledEndpoints": [], "disabledPaths": []}`, resource.Contents[0].Text)
+
+	update := func(token string, filters string) (any, error) {
+		return editor.Handle(ctx, json.RawMessage(`{"token": "`+token+`", "filters": `+filters+`}`))
+	}
+	_, err = update("wrong", `{"disabledOperations": []}`)
+	assert.ErrorContains(t, err, "unauthorized")
+	_, err = update("secret", `{"disabledEndpoints": ["pets"]}`)
+	assert.ErrorIs(t, err, errInvalidParams)
+	assert.Equal(t, []string{"listPets"}, toolNames(), "rejected updates change nothing")
+
+	_, err = update("secret", `{"disabledPaths": ["/pets"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"listOwners"}, toolNames())
+
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.Equal(t, "# Tools for the Pet API\ntimeouts:\n  listPets: 30\ndisabledPaths:\n  - /pets\n", string(data))
+	saved, err := LoadConfig(name)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/pets"}, saved.DisabledPaths)
+	assert.Equal(t, 30.0, saved.Timeouts["listPets"], "other settings are kept")
+
+	resource, err = clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: ConfigResourceURI})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"disabledOperations": [], "disabledEndpoints": [], "disabledPaths": ["/pets"]}`, resource.Contents[0].Text)
+}
+
+func TestSaveToolFiltersJSON(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "emcee.json")
+	require.NoError(t, os.WriteFile(name, []byte(`{"disabledOperations": ["listOwners"], "timeouts": {"listPets": 30}}`), 0o600))
+	require.NoError(t, saveToolFilters(name, &ToolFilters{DisabledEndpoints: []string{"DELETE /pets/{petId}"}}))
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"timeouts": {"listPets": 30}, "disabledEndpoints": ["DELETE /pets/{petId}"]}`, string(data))
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// confirmArgument is the reserved argument carrying the token that confirms a call.
+const confirmArgument = "_confirm"
+
+// confirmationTTL is how long a confirmation token can be used after it's issued.
+const confirmationTTL = 5 * time.Minute
+
+// WithConfirmation requires POST, PUT, PATCH, and DELETE operations to be confirmed before they're sent.
+// The first call to one of their tools returns a preview of the request and a confirmation token,
+// and the request is only sent when the tool is called again with the same arguments and the token.
+// Operations the config file lists as destructive always require confirmation.
+func WithConfirmation() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.confirmation = true }
+}
+
+// requiresConfirmation reports whether calls to an operation must be confirmed before they're sent.
+func (cfg *registerToolsConfig) requiresConfirmation(method, operationID string) bool {
+	if cfg.config.marksDestructive(operationID) {
+		return true
+	}
+	if !cfg.confirmation {
+		return false
+	}
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+// marksDestructive reports whether the configuration lists an operation as destructive.
+func (c *Config) marksDestructive(operationID string) bool {
+	return c != nil && slices.Contains(c.DestructiveOperations, operationID)
+}
+
+// addConfirmArgument adds the reserved argument that confirms a call.
+// Tools with an argument of the same name are left alone.
+func addConfirmArgument(schema *jsonschema.Schema) {
+	if _, exists := schema.Properties[confirmArgument]; exists {
+		return
+	}
+	schema.Properties[confirmArgument] = &jsonschema.Schema{
+		Type:        "string",
+		Description: "The confirmation token returned by a previous call with the same arguments. Calls without it return a preview of the request instead of sending it",
+	}
+}
+
+// takeConfirmToken returns the token a tool call passes as its _confirm argument, and the call's other arguments.
+func takeConfirmToken(args map[string]any) (string, map[string]any) {
+	value, rest := takeArgument(args, confirmArgument)
+	token, _ := value.(string)
+	return token, rest
+}
+
+// confirmations holds the tokens issued for calls awaiting confirmation.
+// Each token confirms a single call, with the same tool and arguments as the call it was issued for,
+// made by the same client session.
+type confirmations struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]pendingConfirmation // by token
+}
+
+type pendingConfirmation struct {
+	call    string
+	expires time.Time
+}
+
+func newConfirmations() *confirmations {
+	return &confirmations{now: time.Now, pending: make(map[string]pendingConfirmation)}
+}
+
+// confirmationCall identifies a call by session, tool name, and arguments. Map keys are marshaled in sorted order,
+// so equivalent arguments produce the same key, and calls without arguments match calls with none.
+func confirmationCall(session, toolName string, args map[string]any) (string, error) {
+	if len(args) == 0 {
+		args = nil
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("error encoding arguments: %w", err)
+	}
+	return session + "\x00" + toolName + "\x00" + string(b), nil
+}
+
+// issue returns a new token confirming a call, and evicts expired tokens.
+func (c *confirmations) issue(session, toolName string, args map[string]any) (string, error) {
+	call, err := confirmationCall(session, toolName, args)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for token, p := range c.pending {
+		if !now.Before(p.expires) {
+			delete(c.pending, token)
+		}
+	}
+	token := rand.Text()
+	c.pending[token] = pendingConfirmation{call: call, expires: now.Add(confirmationTTL)}
+	return token, nil
+}
+
+// confirm reports whether a token confirms a call, and uses it up if it does.
+// Tokens for other calls are left unused, so that a call with the wrong arguments can be corrected.
+func (c *confirmations) confirm(token, session, toolName string, args map[string]any) bool {
+	call, err := confirmationCall(session, toolName, args)
+	if err != nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[token]
+	if !ok || p.call != call {
+		return false
+	}
+	delete(c.pending, token)
+	return c.now().Before(p.expires)
+}
+
+// confirmationResult returns a preview of a request awaiting confirmation,
+// with instructions for confirming it.
+func confirmationResult(preview *mcp.CallToolResultFor[any], toolName, token string) *mcp.CallToolResultFor[any] {
+	note := fmt.Sprintf("This request hasn't been sent. To send it, call %s again with the same arguments and %q: %q. The token expires in %s.",
+		toolName, confirmArgument, token, confirmationTTL)
+	preview.Content = append([]mcp.Content{&mcp.TextContent{Text: note}}, preview.Content...)
+	return preview
+}
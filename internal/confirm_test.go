@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithConfirmation(t *testing.T) {
+	var received []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}},
+    "/pets/{petId}": {"delete": {
+      "operationId": "deletePet",
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "responses": {"204": {"description": "Deleted"}}
+    }},
+    "/deploy": {"get": {"operationId": "deploy", "responses": {"200": {"description": "OK"}}}}
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	token := regexp.MustCompile(`"_confirm": "(\w+)"`)
+	preview := func(t *testing.T, clientSession *mcp.ClientSession, name string, args map[string]any) string {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 2)
+		match := token.FindStringSubmatch(result.Content[0].(*mcp.TextContent).Text)
+		require.NotNil(t, match, "the preview includes a confirmation token")
+		return match[1]
+	}
+
+	t.Run("destructive methods", func(t *testing.T) {
+		received = nil
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient, WithConfirmation()))
+		clientSession := connectTestClient(t, ctx, server)
+
+		tools, err := clientSession.ListTools(ctx, &mcp.ListToolsParams{})
+		require.NoError(t, err)
+		for _, tool := range tools.Tools {
+			_, ok := tool.InputSchema.Properties[confirmArgument]
+			assert.Equal(t, tool.Name == "deletePet", ok, "only destructive operations take %s: %s", confirmArgument, tool.Name)
+		}
+
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Equal(t, []string{"GET /pets"}, received, "read-only operations are sent right away")
+		received = nil
+
+		first := preview(t, clientSession, "deletePet", map[string]any{"petId": "1"})
+		assert.Empty(t, received, "the first call isn't sent")
+
+		result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "deletePet", Arguments: map[string]any{"petId": "2", confirmArgument: first}})
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "a token doesn't confirm a call with other arguments")
+		assert.Empty(t, received)
+
+		result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "deletePet", Arguments: map[string]any{"petId": "1", confirmArgument: first}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Equal(t, []string{"DELETE /pets/1"}, received)
+
+		result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "deletePet", Arguments: map[string]any{"petId": "1", confirmArgument: first}})
+		require.NoError(t, err)
+		assert.True(t, result.IsError, "a token confirms a single call")
+		assert.Len(t, received, 1)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		received = nil
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		config := &Config{DestructiveOperations: []string{"deploy"}}
+		require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient, WithConfig(config)))
+		clientSession := connectTestClient(t, ctx, server)
+
+		tools, err := clientSession.ListTools(ctx, &mcp.ListToolsParams{})
+		require.NoError(t, err)
+		for _, tool := range tools.Tools {
+			if tool.Name == "deploy" {
+				assert.False(t, tool.Annotations.ReadOnlyHint)
+				assert.True(t, *tool.Annotations.DestructiveHint)
+			}
+		}
+
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "deletePet", Arguments: map[string]any{"petId": "1"}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Equal(t, []string{"DELETE /pets/1"}, received, "other operations don't require confirmation without WithConfirmation")
+		received = nil
+
+		token := preview(t, clientSession, "deploy", nil)
+		assert.Empty(t, received)
+		result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "deploy", Arguments: map[string]any{confirmArgument: token}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Equal(t, []string{"GET /deploy"}, received)
+	})
+}
+
+func TestConfirmationsExpire(t *testing.T) {
+	now := time.Now()
+	c := newConfirmations()
+	c.now = func() time.Time { return now }
+
+	token, err := c.issue("", "deletePet", map[string]any{"petId": "1"})
+	require.NoError(t, err)
+	now = now.Add(confirmationTTL)
+	assert.False(t, c.confirm(token, "", "deletePet", map[string]any{"petId": "1"}))
+}
+
+func TestConfirmationsAreKeptPerSession(t *testing.T) {
+	c := newConfirmations()
+	args := map[string]any{"petId": "1"}
+	token, err := c.issue("a", "deletePet", args)
+	require.NoError(t, err)
+	assert.False(t, c.confirm(token, "b", "deletePet", args), "tokens can't be used by other sessions")
+	assert.True(t, c.confirm(token, "a", "deletePet", args))
+}
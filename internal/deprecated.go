@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"strconv"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+)
+
+// deprecatedNotice is prepended to the descriptions of tools for deprecated operations,
+// so that models prefer other tools to ones for endpoints being sunset.
+const deprecatedNotice = "DEPRECATED: This operation is deprecated and may be removed. Prefer other tools when they can do the same thing."
+
+// WithDeprecatedOperations registers tools for operations marked deprecated: true,
+// which are otherwise skipped. Their descriptions note that they're deprecated.
+func WithDeprecatedOperations() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.includeDeprecated = true }
+}
+
+// WithInternalOperations registers tools for operations marked x-internal: true,
+// on the operation or its path, which are otherwise skipped.
+func WithInternalOperations() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.includeInternal = true }
+}
+
+// isDeprecatedOperation reports whether an operation is marked deprecated: true.
+func isDeprecatedOperation(op *v3.Operation) bool {
+	return op != nil && op.Deprecated != nil && *op.Deprecated
+}
+
+// isInternalOperation reports whether an operation, or the path it's on, is marked x-internal: true.
+func isInternalOperation(item *v3.PathItem, op *v3.Operation) bool {
+	if op != nil && hasTrueExtension(op.Extensions, "x-internal") {
+		return true
+	}
+	return item != nil && hasTrueExtension(item.Extensions, "x-internal")
+}
+
+// hasTrueExtension reports whether an extension is set to true.
+func hasTrueExtension(extensions *orderedmap.Map[string, *yaml.Node], name string) bool {
+	if extensions == nil {
+		return false
+	}
+	node, ok := extensions.Get(name)
+	if !ok || node == nil {
+		return false
+	}
+	value, err := strconv.ParseBool(node.Value)
+	return err == nil && value
+}
+
+// deprecatedDescription returns a tool's description with a notice that its operation is deprecated.
+func deprecatedDescription(desc string) string {
+	if desc == "" {
+		return deprecatedNotice
+	}
+	return deprecatedNotice + "\n\n" + desc
+}
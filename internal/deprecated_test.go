@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecatedAndInternalOperations(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {
+      "get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}},
+      "post": {"operationId": "createPet", "description": "Creates a pet.", "deprecated": true, "responses": {"201": {"description": "Created"}}},
+      "delete": {"operationId": "purgePets", "x-internal": true, "responses": {"204": {"description": "Deleted"}}}
+    },
+    "/admin": {
+      "x-internal": true,
+      "get": {"operationId": "getAdmin", "responses": {"200": {"description": "OK"}}}
+    },
+    "/legacy": {
+      "get": {"operationId": "getLegacy", "x-internal": false, "deprecated": false, "responses": {"200": {"description": "OK"}}}
+    }
+  }
+}`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	descriptions := func(t *testing.T, opts ...RegisterToolsOption) map[string]string {
+		t.Helper()
+		tools, err := ListTools(ctx, []byte(spec), opts...)
+		require.NoError(t, err)
+		descriptions := make(map[string]string)
+		for _, tool := range tools {
+			descriptions[tool.Name] = tool.Description
+		}
+		return descriptions
+	}
+
+	t.Run("default", func(t *testing.T) {
+		tools := descriptions(t)
+		assert.ElementsMatch(t, []string{"listPets", "getLegacy"}, slices.Collect(maps.Keys(tools)))
+	})
+
+	t.Run("include deprecated", func(t *testing.T) {
+		tools := descriptions(t, WithDeprecatedOperations())
+		assert.ElementsMatch(t, []string{"listPets", "createPet", "getLegacy"}, slices.Collect(maps.Keys(tools)))
+		assert.Equal(t, deprecatedNotice+"\n\nCreates a pet.", tools["createPet"])
+		assert.NotContains(t, tools["listPets"], "DEPRECATED")
+	})
+
+	t.Run("include internal", func(t *testing.T) {
+		tools := descriptions(t, WithInternalOperations())
+		assert.ElementsMatch(t, []string{"listPets", "purgePets", "getAdmin", "getLegacy"}, slices.Collect(maps.Keys(tools)))
+	})
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// maxDescribedProperties bounds how many properties of a response are listed in a tool description.
+const maxDescribedProperties = 10
+
+// maxDescribedValueLength bounds the length of an example or default value in a tool description.
+const maxDescribedValueLength = 60
+
+// WithRichDescriptions appends details from the spec to each tool's description:
+// the examples, defaults, and allowed values of its arguments, and a summary of its successful response.
+// Small models often rely on descriptions more than on input schemas to call tools correctly.
+func WithRichDescriptions() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.richDescriptions = true }
+}
+
+// richDescription returns a tool's description with its arguments' examples, defaults, and allowed values,
+// and a summary of what the operation returns.
+// Parameter examples are taken from the parameters, and other examples from the input schema.
+func richDescription(desc string, schema *jsonschema.Schema, params []*v3.Parameter, op *v3.Operation) string {
+	var sections []string
+	if desc != "" {
+		sections = append(sections, desc)
+	}
+
+	examples := make(map[string]any)
+	for _, param := range params {
+		if param == nil {
+			continue
+		}
+		if v, ok := decodeNode(param.Example); ok {
+			examples[param.Name] = v
+			continue
+		}
+		if param.Examples != nil {
+			for pair := param.Examples.First(); pair != nil; pair = pair.Next() {
+				if pair.Value() == nil {
+					continue
+				}
+				if v, ok := decodeNode(pair.Value().Value); ok {
+					examples[param.Name] = v
+					break
+				}
+			}
+		}
+	}
+
+	var arguments []string
+	for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+		// Reserved arguments describe themselves
+		if strings.HasPrefix(name, "_") {
+			continue
+		}
+		prop := schema.Properties[name]
+		var details []string
+		if len(prop.Enum) > 0 {
+			values := make([]string, len(prop.Enum))
+			for i, v := range prop.Enum {
+				values[i] = describedValue(v)
+			}
+			details = append(details, "one of "+strings.Join(values, ", "))
+		}
+		if len(prop.Default) > 0 {
+			var v any
+			if err := json.Unmarshal(prop.Default, &v); err == nil {
+				details = append(details, "default "+describedValue(v))
+			}
+		}
+		if v, ok := examples[name]; ok {
+			details = append(details, "example "+describedValue(v))
+		} else if len(prop.Examples) > 0 {
+			details = append(details, "example "+describedValue(prop.Examples[0]))
+		}
+		if len(details) > 0 {
+			arguments = append(arguments, fmt.Sprintf("- %s: %s", name, strings.Join(details, "; ")))
+		}
+	}
+	if len(arguments) > 0 {
+		sections = append(sections, "Arguments:\n"+strings.Join(arguments, "\n"))
+	}
+
+	if s := successResponseSchema(op); s != nil {
+		if summary := describeSchema(convertSchema(s, responseDirection)); summary != "" {
+			sections = append(sections, fmt.Sprintf("Returns %s.", summary))
+		}
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// describeSchema summarizes a response schema, like "an array of objects with id (integer), name (string)".
+// It returns "" for schemas that say nothing about the response.
+func describeSchema(s *jsonschema.Schema) string {
+	switch schemaType(s) {
+	case "array":
+		if s.Items == nil {
+			return "an array"
+		}
+		item := describeSchema(s.Items)
+		if item == "" {
+			return "an array"
+		}
+		// "an object with..." becomes "an array of objects with..."
+		if rest, ok := strings.CutPrefix(item, "an object"); ok {
+			return "an array of objects" + rest
+		}
+		return "an array of " + strings.TrimPrefix(strings.TrimPrefix(item, "an "), "a ") + " values"
+	case "object":
+		if len(s.Properties) == 0 {
+			return "an object"
+		}
+		var props []string
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			if t := schemaType(s.Properties[name]); t != "" {
+				name = fmt.Sprintf("%s (%s)", name, t)
+			}
+			props = append(props, name)
+		}
+		if len(props) > maxDescribedProperties {
+			props = append(props[:maxDescribedProperties], fmt.Sprintf("and %d more", len(props)-maxDescribedProperties))
+		}
+		return "an object with " + strings.Join(props, ", ")
+	case "":
+		return ""
+	default:
+		t := schemaType(s)
+		if strings.ContainsAny(t[:1], "aeiou") {
+			return "an " + t
+		}
+		return "a " + t
+	}
+}
+
+// schemaType returns the type of a schema, like "string" or "string|null", or "" if it has none.
+// Schemas with properties but no type are objects.
+func schemaType(s *jsonschema.Schema) string {
+	switch {
+	case s == nil:
+		return ""
+	case s.Type != "":
+		return s.Type
+	case len(s.Types) > 0:
+		return strings.Join(s.Types, "|")
+	case len(s.Properties) > 0:
+		return "object"
+	}
+	return ""
+}
+
+// describedValue formats a value for a tool description as JSON, shortening long values.
+func describedValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(b)
+	if len(s) > maxDescribedValueLength {
+		s = truncateUTF8(s, maxDescribedValueLength) + "…"
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRichDescriptions(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "summary": "List pets",
+        "parameters": [
+          {"name": "limit", "in": "query", "example": 10, "schema": {"type": "integer", "default": 20}},
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["available", "sold"]}},
+          {"name": "tag", "in": "query", "examples": {"dog": {"value": "dog"}}, "schema": {"type": "string"}},
+          {"name": "q", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {
+          "type": "array",
+          "items": {"type": "object", "properties": {"id": {"type": "integer"}, "name": {"type": "string"}}}
+        }}}}}
+      },
+      "post": {
+        "operationId": "createPet",
+        "requestBody": {"content": {"application/json": {"schema": {
+          "type": "object",
+          "properties": {"name": {"type": "string", "examples": ["Fido"]}}
+        }}}},
+        "responses": {"201": {"description": "Created"}}
+      }
+    }
+  }
+}`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	descriptions := func(t *testing.T, opts ...RegisterToolsOption) map[string]string {
+		t.Helper()
+		tools, err := ListTools(ctx, []byte(spec), opts...)
+		require.NoError(t, err)
+		descriptions := make(map[string]string)
+		for _, tool := range tools {
+			descriptions[tool.Name] = tool.Description
+		}
+		return descriptions
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		got := descriptions(t)
+		assert.Equal(t, "List pets", got["listPets"])
+		assert.Empty(t, got["createPet"])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		got := descriptions(t, WithRichDescriptions())
+		assert.Equal(t, strings.Join([]string{
+			"List pets",
+			"",
+			"Arguments:",
+			"- limit: default 20; example 10",
+			`- status: one of "available", "sold"`,
+			`- tag: example "dog"`,
+			"",
+			"Returns an array of objects with id (integer), name (string).",
+		}, "\n"), got["listPets"])
+		assert.Equal(t, "Arguments:\n- name: example \"Fido\"", got["createPet"], "body properties use schema examples, and responses without content aren't summarized")
+	})
+}
+
+func TestDescribeSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema *jsonschema.Schema
+		want   string
+	}{
+		{"empty", &jsonschema.Schema{}, ""},
+		{"string", &jsonschema.Schema{Type: "string"}, "a string"},
+		{"integer", &jsonschema.Schema{Type: "integer"}, "an integer"},
+		{"nullable", &jsonschema.Schema{Types: []string{"string", "null"}}, "a string|null"},
+		{"array of strings", &jsonschema.Schema{Type: "array", Items: &jsonschema.Schema{Type: "string"}}, "an array of string values"},
+		{"untyped array", &jsonschema.Schema{Type: "array"}, "an array"},
+		{"object without properties", &jsonschema.Schema{Type: "object"}, "an object"},
+		{"untyped object", &jsonschema.Schema{Properties: map[string]*jsonschema.Schema{"id": {}}}, "an object with id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, describeSchema(tt.schema))
+		})
+	}
+
+	props := make(map[string]*jsonschema.Schema)
+	for _, name := range strings.Split("a b c d e f g h i j k l", " ") {
+		props[name] = &jsonschema.Schema{Type: "string"}
+	}
+	got := describeSchema(&jsonschema.Schema{Type: "object", Properties: props})
+	assert.True(t, strings.HasSuffix(got, "j (string), and 2 more"), got)
+}
+
+func TestDescribedValue(t *testing.T) {
+	assert.Equal(t, `"dog"`, describedValue("dog"))
+	assert.Equal(t, `{"a":1}`, describedValue(map[string]any{"a": 1}))
+	long := describedValue(strings.Repeat("é", 100))
+	assert.True(t, strings.HasSuffix(long, "…"))
+	assert.LessOrEqual(t, len(long), maxDescribedValueLength+len("…"))
+}
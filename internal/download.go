@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"mime"
+	"net/http"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxStoredResponses bounds how many responses are kept as resources.
+// When more are stored, the oldest is removed.
+const maxStoredResponses = 100
+
+// responseStore keeps responses too large to return inline as resources at emcee://responses/{id},
+// for clients to read when they need them.
+type responseStore struct {
+	server *mcp.Server
+
+	mu     sync.Mutex
+	stored []string // URIs of stored responses, oldest first
+}
+
+func newResponseStore(server *mcp.Server) *responseStore {
+	return &responseStore{server: server}
+}
+
+// add stores a response as a resource, removing the oldest stored response if there are too many,
+// and returns a link to it. Textual media types are stored as text, and others as blobs.
+func (s *responseStore) add(name, mimeType string, data []byte) *mcp.ResourceLink {
+	uri := "emcee://responses/" + rand.Text()
+	size := int64(len(data))
+	contents := &mcp.ResourceContents{URI: uri, MIMEType: mimeType}
+	if isTextMediaType(mimeType) {
+		contents.Text = string(data)
+	} else {
+		contents.Blob = data
+	}
+	s.server.AddResource(&mcp.Resource{
+		URI:      uri,
+		Name:     name,
+		MIMEType: mimeType,
+		Size:     size,
+	}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+	})
+
+	s.mu.Lock()
+	s.stored = append(s.stored, uri)
+	var evicted []string
+	if len(s.stored) > maxStoredResponses {
+		evicted = s.stored[:len(s.stored)-maxStoredResponses]
+		s.stored = append([]string(nil), s.stored[len(evicted):]...)
+	}
+	s.mu.Unlock()
+	if len(evicted) > 0 {
+		s.server.RemoveResources(evicted...)
+	}
+	return &mcp.ResourceLink{URI: uri, Name: name, MIMEType: mimeType, Size: &size}
+}
+
+// WithDownloadLinks returns file downloads, and binary responses larger than threshold bytes,
+// as links to resources that clients can read, instead of inline in tool results.
+// File downloads are responses with a Content-Disposition of attachment, like an exported report.
+func WithDownloadLinks(threshold int) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.downloadThreshold = threshold }
+}
+
+// downloads links file downloads and large binary responses to stored resources.
+type downloads struct {
+	threshold int
+	store     *responseStore
+}
+
+// result returns a tool result linking to a stored response,
+// or false if the response should be returned inline.
+// Error responses are always returned inline.
+func (d *downloads) result(resp *http.Response, body []byte, toolName string) (*mcp.CallToolResultFor[any], bool) {
+	if d == nil || resp.StatusCode >= 400 {
+		return nil, false
+	}
+	mimeType := baseMediaType(resp.Header.Get("Content-Type"))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	disposition, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	attachment := disposition == "attachment"
+	if !attachment && (isTextMediaType(mimeType) || len(body) <= d.threshold) {
+		return nil, false
+	}
+
+	name := params["filename"]
+	if name == "" {
+		name = toolName + " response"
+	}
+	link := d.store.add(name, mimeType, body)
+	kind := "response"
+	if attachment {
+		kind = "file download"
+	}
+	notice := fmt.Sprintf("The %s is %d bytes of %s, stored as the resource %s. Read the resource for its contents.", kind, len(body), mimeType, link.URI)
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: notice}, link}}, true
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithDownloadLinks(t *testing.T) {
+	archive := []byte(strings.Repeat("\x00\xff", 100))
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/archive":
+			w.Header().Set("Content-Type", "application/zip")
+			_, _ = w.Write(archive)
+		case "/icon":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/report":
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+			_, _ = w.Write([]byte("id,name\n1,Fido\n"))
+		default:
+			w.Header().Set("Content-Type", "application/zip")
+			http.Error(w, "gone", http.StatusGone)
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Files API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/archive": {"get": {"operationId": "getArchive", "responses": {"200": {"description": "OK"}}}},
+    "/icon": {"get": {"operationId": "getIcon", "responses": {"200": {"description": "OK"}}}},
+    "/report": {"get": {"operationId": "getReport", "responses": {"200": {"description": "OK"}}}},
+    "/gone": {"get": {"operationId": "getGone", "responses": {"200": {"description": "OK"}}}}
+  }
+}`, api.URL)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithDownloadLinks(100)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	session := connectTestClient(t, ctx, server)
+
+	call := func(name string) *mcp.CallToolResult {
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: map[string]any{}})
+		require.NoError(t, err)
+		return result
+	}
+	read := func(uri string) *mcp.ResourceContents {
+		result, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+		require.NoError(t, err)
+		require.Len(t, result.Contents, 1)
+		return result.Contents[0]
+	}
+
+	result := call("getArchive")
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 2)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "The response is 200 bytes of application/zip")
+	link, ok := result.Content[1].(*mcp.ResourceLink)
+	require.True(t, ok)
+	assert.Equal(t, "getArchive response", link.Name)
+	assert.Equal(t, "application/zip", link.MIMEType)
+	require.NotNil(t, link.Size)
+	assert.EqualValues(t, 200, *link.Size)
+	assert.Equal(t, archive, read(link.URI).Blob)
+
+	_, ok = call("getIcon").Content[0].(*mcp.ImageContent)
+	assert.True(t, ok, "binary responses under the threshold are inline")
+
+	result = call("getReport")
+	require.Len(t, result.Content, 2)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "The file download is")
+	link = result.Content[1].(*mcp.ResourceLink)
+	assert.Equal(t, "report.csv", link.Name)
+	assert.Equal(t, "id,name\n1,Fido\n", read(link.URI).Text, "text downloads are stored as text")
+
+	result = call("getGone")
+	assert.True(t, result.IsError)
+	assert.Len(t, result.Content, 1, "errors are inline")
+}
+
+func TestResponseStoreEviction(t *testing.T) {
+	store := newResponseStore(mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil))
+	first := store.add("listPets response", "application/json", []byte("[]"))
+	for range maxStoredResponses {
+		store.add("listPets response", "application/json", []byte("[]"))
+	}
+	assert.Len(t, store.stored, maxStoredResponses)
+	assert.NotContains(t, store.stored, first.URI)
+}
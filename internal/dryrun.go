@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// dryRunArgument is the reserved argument that makes a single tool call a dry run.
+const dryRunArgument = "_dryRun"
+
+// WithDryRun makes every tool call a dry run:
+// instead of sending the request to the API, the tool returns it as text,
+// with its method, URL, headers, and body, and credentials redacted.
+func WithDryRun() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.dryRun = true }
+}
+
+// WithDryRunArgument adds a reserved _dryRun argument to every tool,
+// which makes a call a dry run when it's true.
+func WithDryRunArgument() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.dryRunArgument = true }
+}
+
+// addDryRunArgument adds the reserved argument that makes a tool call a dry run.
+// Tools with an argument of the same name are left alone.
+func addDryRunArgument(schema *jsonschema.Schema) {
+	if _, exists := schema.Properties[dryRunArgument]; exists {
+		return
+	}
+	schema.Properties[dryRunArgument] = &jsonschema.Schema{
+		Type:        "boolean",
+		Description: "If true, return the HTTP request the call would send, without sending it",
+	}
+}
+
+// takeDryRun reports whether a tool call's _dryRun argument asks for a dry run, and returns its other arguments.
+func takeDryRun(args map[string]any) (bool, map[string]any) {
+	value, rest := takeArgument(args, dryRunArgument)
+	dryRun, _ := value.(bool)
+	return dryRun, rest
+}
+
+// dryRunTransport is a RoundTripper that responds to each request with a description of the request,
+// instead of sending it. Wrapped in the same transports as the client that sends requests,
+// it describes requests as the API would receive them, with credentials and static headers.
+type dryRunTransport struct {
+	// secrets are the names of headers and query parameters whose values are redacted, in canonical header form
+	secrets []string
+}
+
+func (t dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var b strings.Builder
+	u := *req.URL
+	if query := u.Query(); len(query) > 0 {
+		for name := range query {
+			if t.isSecret(name) {
+				query.Set(name, redactedValue)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	fmt.Fprintf(&b, "%s %s\n", req.Method, u.String())
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			if t.isSecret(name) {
+				value = redactedValue
+			}
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString("\n")
+		if mediaType := baseMediaType(req.Header.Get("Content-Type")); isTextMediaType(mediaType) || mediaType == "application/x-www-form-urlencoded" || strings.HasPrefix(mediaType, "multipart/") {
+			b.Write(body)
+			if !bytes.HasSuffix(body, []byte("\n")) {
+				b.WriteString("\n")
+			}
+		} else {
+			fmt.Fprintf(&b, "[%d bytes of %s]\n", len(body), mediaType)
+		}
+	}
+
+	text := b.String()
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(text)),
+		ContentLength: int64(len(text)),
+		Request:       req,
+	}, nil
+}
+
+// isSecret reports whether the value of a header or query parameter is redacted:
+// the API key, and anything isSecretName reports.
+func (t dryRunTransport) isSecret(name string) bool {
+	return slices.Contains(t.secrets, http.CanonicalHeaderKey(name)) || isSecretName(name)
+}
+
+// dryRunResult returns the description of a request that a client with a dryRunTransport responds with.
+func dryRunResult(client *http.Client, req *http.Request) (*mcp.CallToolResultFor[any], error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	text, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: string(text)}}}, nil
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsDryRun(t *testing.T) {
+	var requests int
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "components": {"securitySchemes": {"queryKey": {"type": "apiKey", "in": "query", "name": "key"}}},
+  "paths": {
+    "/pets/{petId}": {"put": {
+      "operationId": "updatePet",
+      "parameters": [
+        {"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}},
+        {"name": "notify", "in": "query", "schema": {"type": "boolean"}}
+      ],
+      "requestBody": {"content": {"application/json": {"schema": {"type": "object", "properties": {"name": {"type": "string"}}}}}},
+      "responses": {"200": {"description": "OK"}}
+    }},
+    "/pets/{petId}/photo": {"put": {
+      "operationId": "uploadPhoto",
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "requestBody": {"content": {"application/octet-stream": {}}},
+      "responses": {"200": {"description": "OK"}}
+    }}
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("every call", func(t *testing.T) {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient,
+			WithDryRun(),
+			WithAPIKey("secret"),
+			WithHeader("Authorization", "Bearer token"),
+			WithHeader("Accept-Version", "2"),
+		))
+		clientSession := connectTestClient(t, ctx, server)
+
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "updatePet", Arguments: map[string]any{
+			"petId":  "a b",
+			"notify": true,
+			"name":   "Fido",
+		}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text := result.Content[0].(*mcp.TextContent).Text
+		assert.Contains(t, text, "PUT "+api.URL+"/pets/a%20b?key=%5BREDACTED%5D&notify=true\n")
+		assert.Contains(t, text, "Accept-Version: 2\n")
+		assert.Contains(t, text, "Authorization: [REDACTED]\n")
+		assert.Contains(t, text, "Content-Type: application/json\n")
+		assert.Contains(t, text, "\n\n{\"name\":\"Fido\"}\n")
+		assert.NotContains(t, text, "secret")
+		assert.NotContains(t, text, "Bearer token")
+
+		result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "uploadPhoto", Arguments: map[string]any{
+			"petId": "1",
+			"body":  "iVBORw0KGgo=",
+		}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "[8 bytes of application/octet-stream]", "binary bodies are summarized")
+
+		assert.Zero(t, requests, "dry runs don't send requests")
+	})
+
+	t.Run("argument", func(t *testing.T) {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient, WithDryRunArgument()))
+		clientSession := connectTestClient(t, ctx, server)
+
+		tools, err := clientSession.ListTools(ctx, &mcp.ListToolsParams{})
+		require.NoError(t, err)
+		for _, tool := range tools.Tools {
+			assert.Contains(t, tool.InputSchema.Properties, dryRunArgument)
+			assert.NotContains(t, tool.InputSchema.Required, dryRunArgument)
+		}
+
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "updatePet", Arguments: map[string]any{
+			"petId":        "1",
+			dryRunArgument: true,
+		}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		text := result.Content[0].(*mcp.TextContent).Text
+		assert.Contains(t, text, "PUT "+api.URL+"/pets/1\n")
+		assert.NotContains(t, text, dryRunArgument)
+		assert.Zero(t, requests)
+
+		result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "updatePet", Arguments: map[string]any{
+			"petId":        "1",
+			dryRunArgument: false,
+		}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Equal(t, 1, requests, "calls without a dry run are sent")
+	})
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// elicitMethod is the method with which servers ask clients for input, in protocol revision 2025-06-18 and later.
+// The SDK doesn't send elicitation requests yet, so they're sent by the transport.
+const elicitMethod = "elicitation/create"
+
+// Elicitor asks clients for the required arguments that tools/call requests are missing, instead of failing the calls.
+// A client is only asked if it declared the elicitation capability when it initialized,
+// and if its streams are set up with the elicitor (see Stdio.Elicitor).
+// The client shows the user a form for the missing arguments,
+// and the call is completed with the values the user enters.
+type Elicitor struct {
+	mu      sync.Mutex
+	conns   map[string]*stdioConn       // by session ID
+	capable map[*mcp.ServerSession]bool // sessions of clients that declared the elicitation capability
+}
+
+// NewElicitor returns an elicitor with no connections.
+func NewElicitor() *Elicitor {
+	return &Elicitor{conns: make(map[string]*stdioConn), capable: make(map[*mcp.ServerSession]bool)}
+}
+
+// WithElicitation asks clients that support it for the required arguments their tool calls are missing.
+func WithElicitation(elicitor *Elicitor) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.elicitor = elicitor }
+}
+
+// register records the connection for a session, over which elicitation requests are sent.
+func (e *Elicitor) register(sessionID string, conn *stdioConn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.conns[sessionID] = conn
+}
+
+// unregister forgets the connection for a session, unless it's since been replaced.
+func (e *Elicitor) unregister(sessionID string, conn *stdioConn) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.conns[sessionID] == conn {
+		delete(e.conns, sessionID)
+	}
+}
+
+// conn returns the connection over which a session's client can be asked for input, if any.
+func (e *Elicitor) conn(session *mcp.ServerSession) *stdioConn {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.capable[session] {
+		return nil
+	}
+	return e.conns[session.ID()]
+}
+
+// elicitationResult is the result of an elicitation/create request.
+type elicitationResult struct {
+	Action  string         `json:"action"` // accept, decline, or cancel
+	Content map[string]any `json:"content,omitempty"`
+}
+
+// middleware records which clients support elicitation, and asks them for the required arguments
+// their tools/call requests are missing, before the arguments are validated.
+// Calls whose missing arguments can't be asked for, like objects, fail validation as usual.
+func (e *Elicitor) middleware(schemas map[string]*jsonschema.Schema, logger *slog.Logger) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			switch r := req.(type) {
+			case *mcp.ServerRequest[*mcp.InitializeParams]:
+				if r.Params != nil && r.Params.Capabilities != nil && r.Params.Capabilities.Elicitation != nil && r.Session != nil {
+					e.mu.Lock()
+					e.capable[r.Session] = true
+					e.mu.Unlock()
+					go func() {
+						_ = r.Session.Wait()
+						e.mu.Lock()
+						delete(e.capable, r.Session)
+						e.mu.Unlock()
+					}()
+				}
+			case *mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]]:
+				if r.Params == nil || r.Session == nil {
+					break
+				}
+				schema, ok := schemas[r.Params.Name]
+				if !ok {
+					break
+				}
+				conn := e.conn(r.Session)
+				if conn == nil {
+					break
+				}
+				if res := elicit(ctx, conn, r, schema, logger); res != nil {
+					return res, nil
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// elicit asks a client for the required arguments a call is missing, and adds the values the user enters to the call.
+// It returns a result for calls the user declines to complete, and nil otherwise.
+func elicit(ctx context.Context, conn *stdioConn, r *mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]], schema *jsonschema.Schema, logger *slog.Logger) mcp.Result {
+	args := map[string]any{}
+	if raw := r.Params.Arguments; len(raw) > 0 && !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil
+		}
+	}
+	requested, missing := elicitationSchema(schema, args)
+	if requested == nil {
+		return nil
+	}
+
+	data, err := conn.call(ctx, elicitMethod, map[string]any{
+		"message":         fmt.Sprintf("%s needs %s to continue.", r.Params.Name, strings.Join(missing, ", ")),
+		"requestedSchema": requested,
+	})
+	var result elicitationResult
+	if err == nil {
+		err = json.Unmarshal(data, &result)
+	}
+	if err != nil {
+		logger.Warn("error asking client for missing arguments", "tool", r.Params.Name, "error", err)
+		return nil
+	}
+	if result.Action != "accept" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("The user didn't provide the missing arguments for %s (%s), so the call wasn't made.", r.Params.Name, result.Action)}},
+			IsError: true,
+		}
+	}
+	for name, value := range result.Content {
+		if slices.Contains(missing, name) {
+			args[name] = value
+		}
+	}
+	if data, err := json.Marshal(args); err == nil {
+		r.Params.Arguments = data
+	}
+	return nil
+}
+
+// elicitationSchema returns the schema of the form asking for the required arguments missing from a call,
+// along with their names, in the order the tool's schema requires them.
+// Forms can only ask for strings, numbers, integers, and booleans,
+// so if any missing argument is of another type, or none are missing, the schema is nil.
+func elicitationSchema(schema *jsonschema.Schema, args map[string]any) (*jsonschema.Schema, []string) {
+	var missing []string
+	properties := make(map[string]*jsonschema.Schema)
+	for _, name := range schema.Required {
+		if _, ok := args[name]; ok {
+			continue
+		}
+		prop := schema.Properties[name]
+		if prop == nil {
+			return nil, nil
+		}
+		typ := prop.Type
+		if typ == "" {
+			// Nullable arguments have a list of types
+			types := slices.DeleteFunc(slices.Clone(prop.Types), func(t string) bool { return t == "null" })
+			if len(types) == 1 {
+				typ = types[0]
+			}
+		}
+		switch typ {
+		case "string", "number", "integer", "boolean":
+		default:
+			return nil, nil
+		}
+		properties[name] = &jsonschema.Schema{
+			Type:        typ,
+			Title:       prop.Title,
+			Description: prop.Description,
+			Enum:        prop.Enum,
+			Format:      prop.Format,
+			Minimum:     prop.Minimum,
+			Maximum:     prop.Maximum,
+			MinLength:   prop.MinLength,
+			MaxLength:   prop.MaxLength,
+		}
+		missing = append(missing, name)
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	return &jsonschema.Schema{Type: "object", Properties: properties, Required: missing}, missing
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElicitation(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": %q}`, r.URL.Path)
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "description": "The pet's ID", "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	elicitor := NewElicitor()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithElicitation(elicitor)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	type message struct {
+		ID     any             `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	// connect starts a session with a client with the given capabilities,
+	// returning functions for sending a message and reading the next one
+	connect := func(t *testing.T, capabilities string) (func(string), func() message) {
+		in, inWriter := io.Pipe()
+		outReader, out := io.Pipe()
+		serverSession, err := server.Connect(ctx, (&Stdio{In: in, Out: out, Err: io.Discard, Elicitor: elicitor}).Transport(), nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { serverSession.Close() })
+
+		lines := bufio.NewScanner(outReader)
+		send := func(line string) {
+			_, err := inWriter.Write([]byte(line + "\n"))
+			require.NoError(t, err)
+		}
+		read := func() message {
+			require.True(t, lines.Scan())
+			var msg message
+			require.NoError(t, json.Unmarshal(lines.Bytes(), &msg))
+			return msg
+		}
+		send(fmt.Sprintf(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":%s,"clientInfo":{"name":"test","version":"dev"}}}`, capabilities))
+		read()
+		send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+		return send, read
+	}
+
+	t.Run("accept", func(t *testing.T) {
+		send, read := connect(t, `{"elicitation":{}}`)
+		send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"getPet","arguments":{}}}`)
+
+		req := read()
+		assert.Equal(t, "elicitation/create", req.Method)
+		var params struct {
+			Message         string         `json:"message"`
+			RequestedSchema map[string]any `json:"requestedSchema"`
+		}
+		require.NoError(t, json.Unmarshal(req.Params, &params))
+		assert.Contains(t, params.Message, "petId")
+		assert.Equal(t, []any{"petId"}, params.RequestedSchema["required"])
+		assert.Equal(t, map[string]any{"type": "string", "description": "The pet's ID"}, params.RequestedSchema["properties"].(map[string]any)["petId"])
+
+		id, err := json.Marshal(req.ID)
+		require.NoError(t, err)
+		send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"action":"accept","content":{"petId":"42"}}}`, id))
+
+		resp := read()
+		assert.EqualValues(t, 1, resp.ID)
+		assert.Contains(t, string(resp.Result), `/pets/42`)
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		send, read := connect(t, `{"elicitation":{}}`)
+		send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"getPet"}}`)
+		id, err := json.Marshal(read().ID)
+		require.NoError(t, err)
+		send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"action":"decline"}}`, id))
+
+		var result mcp.CallToolResult
+		require.NoError(t, json.Unmarshal(read().Result, &result))
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "didn't provide the missing arguments")
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		// Clients that don't support elicitation get the usual error
+		send, read := connect(t, `{}`)
+		send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"getPet","arguments":{}}}`)
+		resp := read()
+		assert.EqualValues(t, 1, resp.ID)
+		assert.Contains(t, string(resp.Error), "petId: required argument is missing")
+	})
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+)
+
+// maxCompletionValues is the most values a completion/complete response may contain.
+const maxCompletionValues = 100
+
+// enumResourceURI returns the URI of the resource listing the allowed values of a tool argument.
+func enumResourceURI(toolName, argument string) string {
+	return "emcee://tools/" + url.PathEscape(toolName) + "/arguments/" + url.PathEscape(argument) + "/values"
+}
+
+// EnumCatalog holds the values of tool arguments for completion/complete:
+// the allowed values of enums, examples, and the values fetched by lookups the config file sets.
+// Enums too large to list in input schemas, like a list of every country or currency, are exposed as resources.
+// Completions refer to an argument by the URI of its values resource, whether or not the resource exists.
+type EnumCatalog struct {
+	maxValues int
+
+	mu      sync.RWMutex
+	server  *mcp.Server
+	values  map[string][]string        // by resource URI
+	lookups map[string]*argumentLookup // by resource URI
+
+	sessionMu sync.Mutex
+	session   *mcp.ClientSession
+}
+
+// NewEnumCatalog returns a catalog that moves enums with more than maxValues values out of input schemas.
+// If maxValues is 0, enums are left in input schemas, and the catalog only completes values.
+func NewEnumCatalog(maxValues int) *EnumCatalog {
+	return &EnumCatalog{maxValues: maxValues, values: make(map[string][]string), lookups: make(map[string]*argumentLookup)}
+}
+
+// WithEnumCatalog records the values of tool arguments in a catalog,
+// and moves enums with more values than the catalog allows out of input schemas.
+// Pass the catalog's Complete method to the server as its CompletionHandler to complete their values.
+func WithEnumCatalog(catalog *EnumCatalog) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.enumCatalog = catalog }
+}
+
+// add records the values of a tool's arguments for completion,
+// and moves the large enums of its arguments, or of the items of its array arguments,
+// out of its input schema and into resources, returning their URIs.
+// The description of each argument refers to its resource instead of listing the values.
+func (c *EnumCatalog) add(server *mcp.Server, toolName string, schema *jsonschema.Schema) ([]string, error) {
+	c.mu.Lock()
+	c.server = server
+	c.mu.Unlock()
+
+	var uris []string
+	for name, prop := range schema.Properties {
+		if values := completionValues(prop); len(values) > 0 {
+			c.mu.Lock()
+			c.values[enumResourceURI(toolName, name)] = values
+			c.mu.Unlock()
+		}
+		if c.maxValues <= 0 {
+			continue
+		}
+		enumSchema := prop
+		if len(prop.Enum) <= c.maxValues && prop.Items != nil {
+			enumSchema = prop.Items
+		}
+		if len(enumSchema.Enum) <= c.maxValues {
+			continue
+		}
+		uri := enumResourceURI(toolName, name)
+		if err := c.addResource(server, uri, toolName, name, enumSchema.Enum); err != nil {
+			return nil, err
+		}
+		enumSchema.Enum = nil
+		prop.Description = withoutAllowedValues(prop.Description)
+		note := fmt.Sprintf("One of the values listed by the resource %s", uri)
+		if prop.Description != "" {
+			note = prop.Description + " (" + note + ")"
+		}
+		prop.Description = note
+		uris = append(uris, uri)
+	}
+	return uris, nil
+}
+
+// addResource exposes the allowed values of an argument as a JSON array.
+func (c *EnumCatalog) addResource(server *mcp.Server, uri, toolName, argument string, enum []any) error {
+	data, err := json.MarshalIndent(enum, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding values of %s argument %q: %w", toolName, argument, err)
+	}
+
+	server.AddResource(&mcp.Resource{
+		URI:         uri,
+		Name:        toolName + " " + argument + " values",
+		Description: fmt.Sprintf("The %d allowed values of the %s argument of the %s tool", len(enum), argument, toolName),
+		MIMEType:    "application/json",
+		Size:        int64(len(data)),
+	}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/json", Text: string(data)}},
+		}, nil
+	})
+	return nil
+}
+
+// Complete answers completion/complete requests for the values of tool arguments, referred to by the URIs of their resources,
+// with the values that start with the argument's value, ignoring case:
+// the values the spec lists, in order, followed by those fetched by the argument's lookup.
+// It's suitable for use as mcp.ServerOptions.CompletionHandler.
+func (c *EnumCatalog) Complete(ctx context.Context, req *mcp.ServerRequest[*mcp.CompleteParams]) (*mcp.CompleteResult, error) {
+	result := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: []string{}}}
+	if req.Params == nil || req.Params.Ref == nil || req.Params.Ref.Type != "ref/resource" {
+		return result, nil
+	}
+	c.mu.RLock()
+	values := c.values[req.Params.Ref.URI]
+	lookup := c.lookups[req.Params.Ref.URI]
+	c.mu.RUnlock()
+
+	if lookup != nil {
+		var known map[string]string
+		if req.Params.Context != nil {
+			known = req.Params.Context.Arguments
+		}
+		looked, err := c.lookup(ctx, lookup, known)
+		if err != nil {
+			return nil, fmt.Errorf("error looking up values: %w", err)
+		}
+		values = slices.Concat(values, slices.DeleteFunc(slices.Clone(looked), func(v string) bool {
+			return slices.Contains(values, v)
+		}))
+	}
+	matchCompletions(result, values, req.Params.Argument.Value)
+	result.Completion.HasMore = result.Completion.Total > len(result.Completion.Values)
+	return result, nil
+}
+
+// addLookup records the lookup for an argument of a tool, made by calling lookupTool with arguments from the completion's context.
+func (c *EnumCatalog) addLookup(toolName, argument string, lookup *Lookup, lookupTool string, lookupSchema *jsonschema.Schema) error {
+	path, err := jsonpath.NewPath(lookup.Values, config.WithPropertyNameExtension())
+	if err != nil {
+		return fmt.Errorf("invalid lookup values %q: %w", lookup.Values, err)
+	}
+	l := &argumentLookup{tool: lookupTool, path: path}
+	if lookupSchema != nil {
+		l.arguments = slices.Sorted(maps.Keys(lookupSchema.Properties))
+	}
+	c.mu.Lock()
+	c.lookups[enumResourceURI(toolName, argument)] = l
+	c.mu.Unlock()
+	return nil
+}
+
+// withoutAllowedValues removes the list of allowed values that buildSchemaDescription appends to a description.
+func withoutAllowedValues(description string) string {
+	if strings.HasPrefix(description, "Allowed values: ") {
+		return ""
+	}
+	if i := strings.LastIndex(description, " (Allowed values: "); i >= 0 {
+		return description[:i]
+	}
+	return description
+}
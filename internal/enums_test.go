@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnumCatalog(t *testing.T) {
+	var countries []string
+	for a := 'A'; a <= 'Z'; a++ {
+		for b := 'A'; b <= 'Z'; b++ {
+			countries = append(countries, string([]rune{a, b}))
+		}
+	}
+	countriesJSON, err := json.Marshal(countries)
+	require.NoError(t, err)
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Address API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/addresses": {
+      "get": {
+        "operationId": "listAddresses",
+        "parameters": [
+          {"name": "country", "in": "query", "description": "Country code", "schema": {"type": "string", "enum": %s}},
+          {"name": "sort", "in": "query", "schema": {"type": "string", "enum": ["asc", "desc"]}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, countriesJSON)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	catalog := NewEnumCatalog(100)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, &mcp.ServerOptions{CompletionHandler: catalog.Complete})
+	require.NoError(t, RegisterTools(server, []byte(spec), nil, WithEnumCatalog(catalog)))
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	uri := "emcee://tools/listAddresses/arguments/country/values"
+	country := tools.Tools[0].InputSchema.Properties["country"]
+	assert.Empty(t, country.Enum, "large enums are left out of the input schema")
+	assert.Equal(t, "Country code (One of the values listed by the resource "+uri+")", country.Description)
+	assert.Len(t, tools.Tools[0].InputSchema.Properties["sort"].Enum, 2, "small enums are kept")
+
+	resource, err := clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+	require.NoError(t, err)
+	require.Len(t, resource.Contents, 1)
+	var values []string
+	require.NoError(t, json.Unmarshal([]byte(resource.Contents[0].Text), &values))
+	assert.Equal(t, countries, values)
+
+	// The client's Complete method can't decode results in this version of the SDK, so the handler is called directly
+	complete := func(value string) *mcp.CompleteResult {
+		result, err := catalog.Complete(ctx, &mcp.ServerRequest[*mcp.CompleteParams]{Params: &mcp.CompleteParams{
+			Ref:      &mcp.CompleteReference{Type: "ref/resource", URI: uri},
+			Argument: mcp.CompleteParamsArgument{Name: "country", Value: value},
+		}})
+		require.NoError(t, err)
+		return result
+	}
+	result := complete("g")
+	assert.Equal(t, 26, result.Completion.Total)
+	assert.Equal(t, "GA", result.Completion.Values[0])
+	assert.False(t, result.Completion.HasMore)
+	for _, v := range result.Completion.Values {
+		assert.True(t, strings.HasPrefix(v, "G"), v)
+	}
+
+	result = complete("")
+	assert.Equal(t, len(countries), result.Completion.Total)
+	assert.Len(t, result.Completion.Values, maxCompletionValues)
+	assert.True(t, result.Completion.HasMore)
+
+	result, err = catalog.Complete(ctx, &mcp.ServerRequest[*mcp.CompleteParams]{Params: &mcp.CompleteParams{
+		Ref:      &mcp.CompleteReference{Type: "ref/prompt", Name: "pets"},
+		Argument: mcp.CompleteParamsArgument{Name: "country", Value: "G"},
+	}})
+	require.NoError(t, err)
+	assert.Empty(t, result.Completion.Values)
+}
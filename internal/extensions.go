@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// toolExtensions are the x-mcp-* vendor extensions of an operation,
+// which let API authors curate how their operations appear as tools without a configuration file.
+type toolExtensions struct {
+	// name replaces the tool's generated name, before any prefix is added (x-mcp-name).
+	name string
+	// description replaces the operation's description (x-mcp-description).
+	description string
+	// disabled skips the operation, when set on it or its path (x-mcp-disabled).
+	disabled bool
+	// priority orders tools in tools/list, highest first (x-mcp-priority).
+	priority float64
+	// annotations override the tool's derived annotations (x-mcp-annotations).
+	annotations *annotationHints
+}
+
+// parseToolExtensions reads the x-mcp-* extensions of an operation and the path it's on.
+func parseToolExtensions(item *v3.PathItem, op *v3.Operation) (toolExtensions, error) {
+	var ext toolExtensions
+	if item != nil && hasTrueExtension(item.Extensions, "x-mcp-disabled") {
+		ext.disabled = true
+	}
+	if op == nil || op.Extensions == nil {
+		return ext, nil
+	}
+	for pair := op.Extensions.First(); pair != nil; pair = pair.Next() {
+		node := pair.Value()
+		if node == nil {
+			continue
+		}
+		switch pair.Key() {
+		case "x-mcp-name":
+			if node.Kind != yaml.ScalarNode || toolNameValue(node.Value) == "" {
+				return ext, fmt.Errorf("invalid x-mcp-name: expected a tool name")
+			}
+			ext.name = toolNameValue(node.Value)
+		case "x-mcp-description":
+			if node.Kind != yaml.ScalarNode {
+				return ext, fmt.Errorf("invalid x-mcp-description: expected a string")
+			}
+			ext.description = node.Value
+		case "x-mcp-disabled":
+			disabled, err := strconv.ParseBool(node.Value)
+			if node.Kind != yaml.ScalarNode || err != nil {
+				return ext, fmt.Errorf("invalid x-mcp-disabled: expected a boolean")
+			}
+			ext.disabled = ext.disabled || disabled
+		case "x-mcp-priority":
+			priority, err := strconv.ParseFloat(node.Value, 64)
+			if node.Kind != yaml.ScalarNode || err != nil {
+				return ext, fmt.Errorf("invalid x-mcp-priority %q: expected a number", node.Value)
+			}
+			ext.priority = priority
+		case "x-mcp-annotations":
+			hints, err := parseAnnotationHints(node)
+			if err != nil {
+				return ext, err
+			}
+			ext.annotations = hints
+		}
+	}
+	return ext, nil
+}
+
+// toolPriorityMiddleware orders the tools in tools/list results by their x-mcp-priority, highest first.
+// Tools without a priority, including those registered for other specs, have a priority of 0,
+// and tools with the same priority keep their order.
+func toolPriorityMiddleware(priorities map[string]float64) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			res, err := next(ctx, method, req)
+			if err != nil || method != "tools/list" {
+				return res, err
+			}
+			if result, ok := res.(*mcp.ListToolsResult); ok && result != nil {
+				slices.SortStableFunc(result.Tools, func(a, b *mcp.Tool) int {
+					return cmp.Compare(priorities[b.Name], priorities[a.Name])
+				})
+			}
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolExtensions(t *testing.T) {
+	spec := `
+openapi: 3.1.0
+info: {title: Pet API, version: 1.0.0}
+servers: [{url: https://api.example.com}]
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      description: Lists pets.
+      responses: {"200": {description: OK}}
+    post:
+      operationId: createPet
+      x-mcp-name: add pet
+      x-mcp-description: Adds a pet to the store.
+      x-mcp-priority: 10
+      responses: {"201": {description: Created}}
+  /pets/{petId}:
+    delete:
+      operationId: deletePet
+      x-mcp-disabled: true
+      responses: {"204": {description: Deleted}}
+    get:
+      x-mcp-name: getPet
+      x-mcp-priority: 5
+      responses: {"200": {description: OK}}
+  /admin:
+    x-mcp-disabled: true
+    get:
+      operationId: getAdmin
+      responses: {"200": {description: OK}}
+  /zoo:
+    get:
+      operationId: getZoo
+      x-mcp-priority: -1
+      responses: {"200": {description: OK}}
+`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tools, err := ListTools(ctx, []byte(spec), WithToolPrefix("pets_"))
+	require.NoError(t, err)
+	var names []string
+	descriptions := make(map[string]string)
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+		descriptions[tool.Name] = tool.Description
+	}
+	// Tools are ordered by priority, then by name
+	assert.Equal(t, []string{"pets_add_pet", "pets_getPet", "pets_listPets", "pets_getZoo"}, names)
+	assert.Equal(t, "Adds a pet to the store.", descriptions["pets_add_pet"])
+	assert.Equal(t, "Lists pets.", descriptions["pets_listPets"])
+
+	t.Run("invalid priority", func(t *testing.T) {
+		invalid := `
+openapi: 3.1.0
+info: {title: Pet API, version: 1.0.0}
+servers: [{url: https://api.example.com}]
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      x-mcp-priority: high
+      responses: {"200": {description: OK}}
+`
+		_, err := ListTools(ctx, []byte(invalid))
+		assert.ErrorContains(t, err, `operation "listPets": invalid x-mcp-priority "high": expected a number`)
+	})
+}
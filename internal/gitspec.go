@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// gitSpecPrefix marks a spec reference as a file in a Git repository.
+const gitSpecPrefix = "git+"
+
+// GitSpec is a reference to a spec file in a Git repository, at a branch, tag, or commit,
+// of the form git+https://host/org/repo.git#ref:path/to/openapi.yaml.
+type GitSpec struct {
+	// Repository is the URL of the repository, without the git+ prefix
+	Repository string
+	// Ref is the branch, tag, or commit the file is read at, or empty for the repository's default branch
+	Ref string
+	// Path is the path of the file in the repository
+	Path string
+}
+
+// GitSpecOptions configure how spec files are fetched from Git repositories.
+type GitSpecOptions struct {
+	// Proxy is the URL of the proxy Git connects to HTTP(S) repositories through
+	Proxy string
+	// Insecure skips verifying the TLS certificates of HTTPS repositories
+	Insecure bool
+}
+
+// IsGitSpec reports whether a spec reference is a file in a Git repository.
+func IsGitSpec(source string) bool {
+	return strings.HasPrefix(source, gitSpecPrefix)
+}
+
+// ParseGitSpec parses a spec reference of the form git+https://host/org/repo.git#ref:path/to/openapi.yaml.
+// The ref may be omitted (git+https://host/org/repo.git#openapi.yaml) to use the default branch.
+// Repositories may be fetched over https, http, ssh, or from the local file system with file.
+func ParseGitSpec(source string) (GitSpec, error) {
+	rest, ok := strings.CutPrefix(source, gitSpecPrefix)
+	if !ok {
+		return GitSpec{}, fmt.Errorf("invalid git spec reference %q: expected the prefix git+", source)
+	}
+	repository, fragment, ok := strings.Cut(rest, "#")
+	if !ok || fragment == "" {
+		return GitSpec{}, fmt.Errorf("invalid git spec reference %q: expected git+https://host/repo.git#ref:path", source)
+	}
+	scheme, _, _ := strings.Cut(repository, "://")
+	switch scheme {
+	case "https", "http", "ssh", "file":
+	default:
+		return GitSpec{}, fmt.Errorf("invalid git spec reference %q: unsupported scheme %q (expected https, http, ssh, or file)", source, scheme)
+	}
+	// Git doesn't allow colons in ref names, so the first one separates the ref from the path
+	ref, path, ok := strings.Cut(fragment, ":")
+	if !ok {
+		ref, path = "", fragment
+	}
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return GitSpec{}, fmt.Errorf("invalid git spec reference %q: missing file path", source)
+	}
+	return GitSpec{Repository: repository, Ref: ref, Path: path}, nil
+}
+
+// Read fetches the spec file with a shallow fetch of its ref into a temporary repository,
+// using git(1), so that credentials and SSH keys configured for Git are used.
+func (s GitSpec) Read(ctx context.Context, opts GitSpecOptions) ([]byte, error) {
+	if _, err := LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found in PATH: %w", err)
+	}
+	dir, err := os.MkdirTemp("", "emcee-spec-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary repository: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var config []string
+	if opts.Proxy != "" {
+		config = append(config, "-c", "http.proxy="+opts.Proxy)
+	}
+	if opts.Insecure {
+		config = append(config, "-c", "http.sslVerify=false")
+	}
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if _, err := runGit(ctx, "init", "--quiet", dir); err != nil {
+		return nil, err
+	}
+	fetch := append(append([]string{"-C", dir}, config...), "fetch", "--quiet", "--depth=1", "--no-tags", s.Repository, ref)
+	if _, err := runGit(ctx, fetch...); err != nil {
+		return nil, fmt.Errorf("error fetching %s from %s: %w", ref, s.Repository, err)
+	}
+	data, err := runGit(ctx, "-C", dir, "show", "FETCH_HEAD:"+s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s at %s from %s: %w", s.Path, ref, s.Repository, err)
+	}
+	return data, nil
+}
+
+// runGit runs git(1) without prompting for credentials, and returns its output,
+// or an error with what it wrote to standard error.
+func runGit(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && strings.TrimSpace(string(exitErr.Stderr)) != "" {
+			return nil, fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return output, nil
+}
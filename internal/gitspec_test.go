@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitSpec(t *testing.T) {
+	tests := []struct {
+		source  string
+		want    GitSpec
+		wantErr string
+	}{
+		{
+			source: "git+https://github.com/example/api.git#v1.2.0:openapi/openapi.yaml",
+			want:   GitSpec{Repository: "https://github.com/example/api.git", Ref: "v1.2.0", Path: "openapi/openapi.yaml"},
+		},
+		{
+			source: "git+ssh://git@github.com/example/api.git#feature/pets:/openapi.json",
+			want:   GitSpec{Repository: "ssh://git@github.com/example/api.git", Ref: "feature/pets", Path: "openapi.json"},
+		},
+		{
+			source: "git+file:///srv/api.git#openapi.yaml",
+			want:   GitSpec{Repository: "file:///srv/api.git", Path: "openapi.yaml"},
+		},
+		{source: "git+https://github.com/example/api.git", wantErr: "expected git+https://host/repo.git#ref:path"},
+		{source: "git+https://github.com/example/api.git#main:", wantErr: "missing file path"},
+		{source: "git+ftp://example.com/api.git#main:openapi.yaml", wantErr: `unsupported scheme "ftp"`},
+		{source: "https://github.com/example/api.git#main:openapi.yaml", wantErr: "expected the prefix git+"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := ParseGitSpec(tt.source)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGitSpecRead(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	write := func(content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Join(repo, "specs"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(repo, "specs", "openapi.yaml"), []byte(content), 0o644))
+	}
+
+	git("init", "--quiet", "--initial-branch=main")
+	write("version: 1\n")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	write("version: 2\n")
+	git("commit", "--quiet", "-am", "v2")
+
+	tests := []struct {
+		source  string
+		want    string
+		wantErr string
+	}{
+		{source: "git+file://" + repo + "#v1:specs/openapi.yaml", want: "version: 1\n"},
+		{source: "git+file://" + repo + "#main:specs/openapi.yaml", want: "version: 2\n"},
+		{source: "git+file://" + repo + "#specs/openapi.yaml", want: "version: 2\n"},
+		{source: "git+file://" + repo + "#v1:missing.yaml", wantErr: "error reading missing.yaml at v1"},
+		{source: "git+file://" + repo + "#v9:specs/openapi.yaml", wantErr: "error fetching v9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			spec, err := ParseGitSpec(tt.source)
+			require.NoError(t, err)
+			data, err := spec.Read(ctx, GitSpecOptions{})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+}
@@ -0,0 +1,519 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// harFile is an HTTP Archive (HAR), as saved by browsers' developer tools and HTTP proxies.
+// Only the parts used to infer operations are decoded.
+type harFile struct {
+	Log *struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	// ResourceType is the kind of request, as recorded by Chromium-based browsers (e.g. "fetch" or "image").
+	ResourceType string      `json:"_resourceType"`
+	Request      harRequest  `json:"request"`
+	Response     harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	QueryString []harParam   `json:"queryString"`
+	PostData    *harPostData `json:"postData"`
+}
+
+type harParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string     `json:"mimeType"`
+	Text     string     `json:"text"`
+	Params   []harParam `json:"params"`
+}
+
+type harResponse struct {
+	Status  int `json:"status"`
+	Content struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"content"`
+}
+
+// IsHAR reports whether data is an HTTP Archive (HAR) rather than an OpenAPI spec.
+func IsHAR(data []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return false
+	}
+	var har struct {
+		Log *struct {
+			Entries json.RawMessage `json:"entries"`
+		} `json:"log"`
+		OpenAPI string `json:"openapi"`
+	}
+	return json.Unmarshal(data, &har) == nil && har.OpenAPI == "" && har.Log != nil && har.Log.Entries != nil
+}
+
+// SpecFromHAR generates an OpenAPI spec, as JSON, for the API calls in an HTTP Archive (HAR),
+// for APIs without a spec of their own that have been used from a browser.
+//
+// Requests for pages, scripts, images, and the like are ignored, as are CORS preflight requests,
+// and only requests to the host that received the most API calls are kept.
+// Each method and path is an operation. Path segments that look like IDs become path parameters,
+// and the schemas of query parameters, request bodies, and responses are inferred from the values seen.
+func SpecFromHAR(data []byte) ([]byte, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("error parsing HAR file: %w", err)
+	}
+	if har.Log == nil {
+		return nil, fmt.Errorf("error parsing HAR file: no log")
+	}
+
+	// API calls are grouped by origin, and the origin with the most is the spec's server
+	byOrigin := make(map[string][]harEntry)
+	var origins []string
+	for _, entry := range har.Log.Entries {
+		u, err := url.Parse(entry.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !isAPIEntry(entry) {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if _, ok := byOrigin[origin]; !ok {
+			origins = append(origins, origin)
+		}
+		byOrigin[origin] = append(byOrigin[origin], entry)
+	}
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("HAR file has no API requests")
+	}
+	origin := origins[0]
+	for _, o := range origins[1:] {
+		if len(byOrigin[o]) > len(byOrigin[origin]) {
+			origin = o
+		}
+	}
+
+	// Requests with the same method and path template are calls of the same operation
+	type harOperation struct {
+		method, path string
+		params       []string // names of the path template's parameters
+		entries      []harEntry
+		values       [][]string // the values of each path parameter, by entry
+	}
+	operations := make(map[string]*harOperation)
+	for _, entry := range byOrigin[origin] {
+		u, _ := url.Parse(entry.Request.URL)
+		method := strings.ToUpper(entry.Request.Method)
+		template, names, values := harPathTemplate(u.EscapedPath())
+		key := method + " " + template
+		op, ok := operations[key]
+		if !ok {
+			op = &harOperation{method: method, path: template, params: names}
+			operations[key] = op
+		}
+		op.entries = append(op.entries, entry)
+		op.values = append(op.values, values)
+	}
+
+	paths := make(map[string]map[string]any)
+	operationIDs := make(map[string]bool)
+	for _, key := range slices.Sorted(maps.Keys(operations)) {
+		op := operations[key]
+		id := toolNameValue(strings.ToLower(op.method) + "/" + strings.NewReplacer("{", "", "}", "").Replace(op.path))
+		for n := 2; operationIDs[id]; n++ {
+			id = fmt.Sprintf("%s_%d", strings.TrimRight(id, "0123456789_"), n)
+		}
+		operationIDs[id] = true
+
+		var parameters []any
+		for i, name := range op.params {
+			var values []string
+			for _, v := range op.values {
+				values = append(values, v[i])
+			}
+			parameters = append(parameters, map[string]any{"name": name, "in": "path", "required": true, "schema": harValueSchema(values)})
+		}
+		parameters = append(parameters, harQueryParameters(op.entries)...)
+
+		operation := map[string]any{
+			"operationId": id,
+			"summary":     op.method + " " + op.path,
+			"description": fmt.Sprintf("Inferred from %d request(s) in a HAR capture.", len(op.entries)),
+			"responses":   harResponses(op.entries),
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if body := harRequestBody(op.entries); body != nil {
+			operation["requestBody"] = body
+		}
+		if paths[op.path] == nil {
+			paths[op.path] = make(map[string]any)
+		}
+		paths[op.path][strings.ToLower(op.method)] = operation
+	}
+
+	u, _ := url.Parse(origin)
+	spec := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       u.Host,
+			"version":     "0.0.0",
+			"description": fmt.Sprintf("Generated from %d API request(s) to %s in a HAR capture.", len(byOrigin[origin]), origin),
+		},
+		"servers": []any{map[string]any{"url": origin}},
+		"paths":   paths,
+	}
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+// isAPIEntry reports whether an entry in a HAR file is a call of an API,
+// rather than a request for a page or its resources, or a CORS preflight request.
+func isAPIEntry(entry harEntry) bool {
+	switch strings.ToUpper(entry.Request.Method) {
+	case "GET", "PUT", "POST", "DELETE", "PATCH", "HEAD":
+	default:
+		return false
+	}
+	switch entry.ResourceType {
+	case "xhr", "fetch":
+		return true
+	case "document", "stylesheet", "script", "image", "font", "media", "manifest", "websocket":
+		return false
+	}
+	if entry.Request.PostData != nil && isHARDataMediaType(entry.Request.PostData.MimeType) {
+		return true
+	}
+	return isHARDataMediaType(entry.Response.Content.MimeType)
+}
+
+// isHARDataMediaType reports whether a media type is one that APIs exchange data in.
+func isHARDataMediaType(mediaType string) bool {
+	mt := baseMediaType(mediaType)
+	return isJSONMediaType(mt) || mt == "application/xml" || mt == "text/xml" ||
+		mt == "application/x-www-form-urlencoded" || mt == "multipart/form-data"
+}
+
+// harVersionPattern matches path segments that are API versions, like v2, rather than IDs.
+var harVersionPattern = regexp.MustCompile(`^[vV]\d+(\.\d+)*$`)
+
+// harPathTemplate returns a path with the segments that look like IDs replaced by parameters,
+// the parameters' names, and the values they replaced.
+// Parameters are named for the segment before them, like {petId} in /pets/{petId}.
+func harPathTemplate(p string) (template string, names, values []string) {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		if segment == "" || !isIDSegment(segment) {
+			continue
+		}
+		name := "id"
+		if i > 0 && !strings.HasPrefix(segments[i-1], "{") && segments[i-1] != "" {
+			name = toolNameValue(singular(segments[i-1])) + "Id"
+		}
+		for n := 2; slices.Contains(names, name); n++ {
+			name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), n)
+		}
+		value, err := url.PathUnescape(segment)
+		if err != nil {
+			value = segment
+		}
+		names, values = append(names, name), append(values, value)
+		segments[i] = "{" + name + "}"
+	}
+	return cmp.Or(strings.Join(segments, "/"), "/"), names, values
+}
+
+// isIDSegment reports whether a path segment looks like an ID rather than the name of a collection or action:
+// a number, or a token with digits, like a UUID, that's long enough to be told apart from names like "oauth2".
+func isIDSegment(segment string) bool {
+	switch {
+	case segment == "" || harVersionPattern.MatchString(segment):
+		return false
+	case strings.Trim(segment, "0123456789") == "":
+		return true
+	}
+	return len(segment) >= 8 && strings.ContainsAny(segment, "0123456789")
+}
+
+// singular returns the singular of a plural collection name, like "pet" for "pets" or "category" for "categories".
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "ses") || strings.HasSuffix(name, "xes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// harQueryParameters returns the query parameters of an operation's requests.
+// Parameters are required if every request has them.
+func harQueryParameters(entries []harEntry) []any {
+	values := make(map[string][]string)
+	counts := make(map[string]int)
+	var names []string
+	for _, entry := range entries {
+		seen := make(map[string]bool)
+		for _, q := range entry.Request.QueryString {
+			if _, ok := values[q.Name]; !ok {
+				names = append(names, q.Name)
+			}
+			values[q.Name] = append(values[q.Name], q.Value)
+			if !seen[q.Name] {
+				seen[q.Name] = true
+				counts[q.Name]++
+			}
+		}
+	}
+	var parameters []any
+	for _, name := range names {
+		param := map[string]any{"name": name, "in": "query", "schema": harValueSchema(values[name])}
+		if counts[name] == len(entries) {
+			param["required"] = true
+		}
+		parameters = append(parameters, param)
+	}
+	return parameters
+}
+
+// harValueSchema returns the schema of the values of a path or query parameter,
+// which are integers, numbers, or booleans if every value is one.
+func harValueSchema(values []string) map[string]any {
+	isType := func(parse func(string) error) bool {
+		for _, v := range values {
+			if parse(v) != nil {
+				return false
+			}
+		}
+		return len(values) > 0
+	}
+	switch {
+	case isType(func(v string) error { _, err := strconv.ParseInt(v, 10, 64); return err }):
+		return map[string]any{"type": "integer"}
+	case isType(func(v string) error { _, err := strconv.ParseFloat(v, 64); return err }):
+		return map[string]any{"type": "number"}
+	case isType(func(v string) error { _, err := strconv.ParseBool(v); return err }):
+		return map[string]any{"type": "boolean"}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// harRequestBody returns the request body of an operation, inferred from the bodies of its requests, or nil if they have none.
+func harRequestBody(entries []harEntry) map[string]any {
+	samples := make(map[string][]any)
+	var mediaTypes []string
+	sent := 0
+	for _, entry := range entries {
+		data := entry.Request.PostData
+		if data == nil || (data.Text == "" && len(data.Params) == 0) {
+			continue
+		}
+		sent++
+		mt := cmp.Or(baseMediaType(data.MimeType), "application/octet-stream")
+		if _, ok := samples[mt]; !ok {
+			mediaTypes = append(mediaTypes, mt)
+			samples[mt] = nil
+		}
+		if sample, ok := harBodySample(mt, data); ok {
+			samples[mt] = append(samples[mt], sample)
+		}
+	}
+	if sent == 0 {
+		return nil
+	}
+	content := make(map[string]any)
+	for _, mt := range mediaTypes {
+		schema := inferSchema(samples[mt])
+		if len(samples[mt]) == 0 {
+			schema = map[string]any{"type": "string"}
+		}
+		content[mt] = map[string]any{"schema": schema}
+	}
+	return map[string]any{"required": sent == len(entries), "content": content}
+}
+
+// harBodySample returns the value of a request body: the decoded value of a JSON body,
+// or an object with the fields of a form.
+func harBodySample(mediaType string, data *harPostData) (any, bool) {
+	switch {
+	case isJSONMediaType(mediaType):
+		return decodeSample([]byte(data.Text))
+	case mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data":
+		fields := make(map[string]any)
+		for _, p := range data.Params {
+			fields[p.Name] = p.Value
+		}
+		if len(data.Params) == 0 && mediaType == "application/x-www-form-urlencoded" {
+			query, err := url.ParseQuery(data.Text)
+			if err != nil {
+				return nil, false
+			}
+			for name := range query {
+				fields[name] = query.Get(name)
+			}
+		}
+		return fields, true
+	}
+	return nil, false
+}
+
+// harResponses returns the responses of an operation, by status, with schemas inferred from the JSON responses received.
+func harResponses(entries []harEntry) map[string]any {
+	type harStatus struct {
+		mediaTypes []string
+		samples    map[string][]any
+	}
+	statuses := make(map[int]*harStatus)
+	for _, entry := range entries {
+		status := entry.Response.Status
+		if status == 0 {
+			// Requests that got no response, like those that were blocked or canceled
+			continue
+		}
+		s, ok := statuses[status]
+		if !ok {
+			s = &harStatus{samples: make(map[string][]any)}
+			statuses[status] = s
+		}
+		content := entry.Response.Content
+		mt := baseMediaType(content.MimeType)
+		if mt == "" || (content.Text == "" && status == http.StatusNoContent) {
+			continue
+		}
+		if _, ok := s.samples[mt]; !ok {
+			s.mediaTypes = append(s.mediaTypes, mt)
+			s.samples[mt] = nil
+		}
+		if !isJSONMediaType(mt) {
+			continue
+		}
+		text := []byte(content.Text)
+		if content.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(content.Text)
+			if err != nil {
+				continue
+			}
+			text = decoded
+		}
+		if sample, ok := decodeSample(text); ok {
+			s.samples[mt] = append(s.samples[mt], sample)
+		}
+	}
+
+	responses := make(map[string]any)
+	for status, s := range statuses {
+		response := map[string]any{"description": cmp.Or(http.StatusText(status), "Response")}
+		if len(s.mediaTypes) > 0 {
+			content := make(map[string]any)
+			for _, mt := range s.mediaTypes {
+				content[mt] = map[string]any{"schema": inferSchema(s.samples[mt])}
+			}
+			response["content"] = content
+		}
+		responses[strconv.Itoa(status)] = response
+	}
+	if len(responses) == 0 {
+		responses["default"] = map[string]any{"description": "Response"}
+	}
+	return responses
+}
+
+// decodeSample decodes a JSON value, keeping numbers as they're written,
+// so that integers can be told apart from other numbers.
+func decodeSample(data []byte) (any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// inferSchema returns a JSON schema that the given values all match.
+// Objects' properties are required if every object has them, and arrays' items are inferred from the items of every array.
+// Values of more than one type get a list of types. No values, or none but null, get an empty schema.
+func inferSchema(samples []any) map[string]any {
+	types := make(map[string]bool)
+	var objects []map[string]any
+	var items []any
+	for _, sample := range samples {
+		switch v := sample.(type) {
+		case nil:
+			types["null"] = true
+		case bool:
+			types["boolean"] = true
+		case json.Number:
+			if strings.ContainsAny(v.String(), ".eE") {
+				types["number"] = true
+			} else {
+				types["integer"] = true
+			}
+		case string:
+			types["string"] = true
+		case []any:
+			types["array"] = true
+			items = append(items, v...)
+		case map[string]any:
+			types["object"] = true
+			objects = append(objects, v)
+		}
+	}
+	if types["number"] {
+		delete(types, "integer")
+	}
+
+	schema := make(map[string]any)
+	if len(types) == 0 || (len(types) == 1 && types["null"]) {
+		return schema
+	}
+	if names := slices.Sorted(maps.Keys(types)); len(names) == 1 {
+		schema["type"] = names[0]
+	} else {
+		schema["type"] = names
+	}
+	if len(objects) > 0 {
+		properties := make(map[string]any)
+		var required []string
+		values := make(map[string][]any)
+		for _, object := range objects {
+			for name, value := range object {
+				values[name] = append(values[name], value)
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			properties[name] = inferSchema(values[name])
+			if len(values[name]) == len(objects) {
+				required = append(required, name)
+			}
+		}
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	if types["array"] && len(items) > 0 {
+		schema["items"] = inferSchema(items)
+	}
+	return schema
+}
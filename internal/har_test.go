@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHAR = `{
+  "log": {
+    "version": "1.2",
+    "entries": [
+      {
+        "_resourceType": "document",
+        "request": {"method": "GET", "url": "https://app.example.com/pets", "queryString": []},
+        "response": {"status": 200, "content": {"mimeType": "text/html", "text": "<html></html>"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://cdn.example.com/logo.png", "queryString": []},
+        "response": {"status": 200, "content": {"mimeType": "image/png"}}
+      },
+      {
+        "_resourceType": "fetch",
+        "request": {"method": "GET", "url": "https://api.example.com/v1/pets?limit=10&status=available", "queryString": [{"name": "limit", "value": "10"}, {"name": "status", "value": "available"}]},
+        "response": {"status": 200, "content": {"mimeType": "application/json; charset=utf-8", "text": "[{\"id\": 1, \"name\": \"Fido\"}, {\"id\": 2, \"name\": \"Rex\", \"tag\": \"dog\"}]"}}
+      },
+      {
+        "_resourceType": "fetch",
+        "request": {"method": "GET", "url": "https://api.example.com/v1/pets?limit=20", "queryString": [{"name": "limit", "value": "20"}]},
+        "response": {"status": 200, "content": {"mimeType": "application/json", "text": "[]"}}
+      },
+      {
+        "_resourceType": "fetch",
+        "request": {"method": "GET", "url": "https://api.example.com/v1/pets/1", "queryString": []},
+        "response": {"status": 200, "content": {"mimeType": "application/json", "encoding": "base64", "text": "eyJpZCI6IDEsICJuYW1lIjogIkZpZG8iLCAid2VpZ2h0IjogMTIuNX0="}}
+      },
+      {
+        "_resourceType": "fetch",
+        "request": {"method": "GET", "url": "https://api.example.com/v1/pets/42", "queryString": []},
+        "response": {"status": 404, "content": {"mimeType": "application/json", "text": "{\"error\": \"not found\"}"}}
+      },
+      {
+        "_resourceType": "fetch",
+        "request": {"method": "OPTIONS", "url": "https://api.example.com/v1/pets", "queryString": []},
+        "response": {"status": 204, "content": {"mimeType": ""}}
+      },
+      {
+        "_resourceType": "fetch",
+        "request": {
+          "method": "POST", "url": "https://api.example.com/v1/pets", "queryString": [],
+          "postData": {"mimeType": "application/json", "text": "{\"name\": \"Spot\", \"tags\": [\"new\"]}"}
+        },
+        "response": {"status": 201, "content": {"mimeType": "application/json", "text": "{\"id\": 3, \"name\": \"Spot\"}"}}
+      },
+      {
+        "_resourceType": "xhr",
+        "request": {"method": "DELETE", "url": "https://api.example.com/v1/owners/8f14e45f-ceea-4e7a-9b3c-0a1b2c3d4e5f/pets/3", "queryString": []},
+        "response": {"status": 204, "content": {"mimeType": "", "text": ""}}
+      }
+    ]
+  }
+}`
+
+func TestSpecFromHAR(t *testing.T) {
+	require.True(t, IsHAR([]byte(testHAR)))
+	assert.False(t, IsHAR([]byte(`{"openapi": "3.1.0", "info": {"title": "Pet API", "version": "1.0.0"}}`)))
+	assert.False(t, IsHAR([]byte("openapi: 3.1.0\n")))
+
+	specData, err := SpecFromHAR([]byte(testHAR))
+	require.NoError(t, err)
+
+	var spec struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(specData, &spec))
+	require.Len(t, spec.Servers, 1)
+	assert.Equal(t, "https://api.example.com", spec.Servers[0].URL, "the host with the most API calls is the server")
+	assert.ElementsMatch(t, []string{"/v1/pets", "/v1/pets/{petId}", "/v1/owners/{ownerId}/pets/{petId}"}, slices.Collect(maps.Keys(spec.Paths)))
+	assert.ElementsMatch(t, []string{"get", "post"}, slices.Collect(maps.Keys(spec.Paths["/v1/pets"])), "CORS preflight requests aren't operations")
+
+	assert.JSONEq(t, `{
+  "operationId": "get_v1_pets_petId",
+  "summary": "GET /v1/pets/{petId}",
+  "description": "Inferred from 2 request(s) in a HAR capture.",
+  "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "integer"}}],
+  "responses": {
+    "200": {"description": "OK", "content": {"application/json": {"schema": {
+      "type": "object",
+      "properties": {"id": {"type": "integer"}, "name": {"type": "string"}, "weight": {"type": "number"}},
+      "required": ["id", "name", "weight"]
+    }}}},
+    "404": {"description": "Not Found", "content": {"application/json": {"schema": {
+      "type": "object", "properties": {"error": {"type": "string"}}, "required": ["error"]
+    }}}}
+  }
+}`, string(spec.Paths["/v1/pets/{petId}"]["get"]))
+
+	tools, err := ListTools(context.Background(), specData)
+	require.NoError(t, err)
+	byName := make(map[string]ToolSummary)
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+	require.Contains(t, byName, "get_v1_pets")
+	list := byName["get_v1_pets"].InputSchema
+	assert.Equal(t, "integer", list.Properties["limit"].Type)
+	assert.Equal(t, "string", list.Properties["status"].Type)
+	assert.Equal(t, []string{"limit"}, list.Required, "query parameters every request has are required")
+
+	require.Contains(t, byName, "post_v1_pets")
+	create := byName["post_v1_pets"].InputSchema
+	assert.Equal(t, "string", create.Properties["name"].Type)
+	assert.Equal(t, "array", create.Properties["tags"].Type)
+
+	require.Contains(t, byName, "delete_v1_owners_ownerId_pets_petId")
+	assert.Equal(t, "DELETE", byName["delete_v1_owners_ownerId_pets_petId"].Method)
+}
+
+func TestSpecFromHARWithoutAPIRequests(t *testing.T) {
+	_, err := SpecFromHAR([]byte(`{"log": {"entries": [
+  {"_resourceType": "document", "request": {"method": "GET", "url": "https://example.com/"}, "response": {"status": 200, "content": {"mimeType": "text/html"}}}
+]}}`))
+	assert.ErrorContains(t, err, "no API requests")
+}
+
+func TestHARPathTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		path, template string
+	}{
+		{"/", "/"},
+		{"/v2/users/me", "/v2/users/me"},
+		{"/users/123", "/users/{userId}"},
+		{"/categories/7/items/9", "/categories/{categoryId}/items/{itemId}"},
+		{"/orders/ord_8Xk2pQ9z", "/orders/{orderId}"},
+		{"/auth/oauth2/token", "/auth/oauth2/token"},
+		{"/123/456", "/{id}/{id2}"},
+	} {
+		template, _, _ := harPathTemplate(tc.path)
+		assert.Equal(t, tc.template, template, tc.path)
+	}
+}
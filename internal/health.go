@@ -0,0 +1,206 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HealthMethod is the experimental JSON-RPC method for checking that the APIs behind the tools are reachable,
+// and that they accept the credentials emcee sends.
+const HealthMethod = "health/check"
+
+// DefaultHealthPath is the path of the health endpoint probed by a HealthChecker, relative to the API's server URL.
+const DefaultHealthPath = "/health"
+
+// healthProbeTimeout is the longest a probe waits for an API to respond.
+const healthProbeTimeout = 10 * time.Second
+
+// maxHealthProbeBytes is the most of a GET probe's response body read before it's discarded.
+const maxHealthProbeBytes = 64 << 10
+
+// HealthChecker probes the health endpoints of the APIs whose specs tools are registered for,
+// sending the same credentials and headers as tool calls.
+type HealthChecker struct {
+	path string
+
+	mu   sync.Mutex
+	apis map[string]*healthTarget // by spec location
+}
+
+// healthTarget is an API probed by a HealthChecker.
+type healthTarget struct {
+	spec    string
+	title   string
+	version string
+	openAPI string
+	baseURL string
+	client  *http.Client
+}
+
+// NewHealthChecker returns a checker that probes path on each API's server,
+// or DefaultHealthPath if path is empty.
+func NewHealthChecker(path string) *HealthChecker {
+	if path == "" {
+		path = DefaultHealthPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return &HealthChecker{path: path, apis: make(map[string]*healthTarget)}
+}
+
+// WithHealthChecker records the API of the spec in checker, so that its health can be checked.
+// Pass the checker's Handle method to the transport to answer health/check requests.
+func WithHealthChecker(checker *HealthChecker) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.healthChecker = checker }
+}
+
+// add records the API of a spec, replacing the one recorded for it before, if it was reloaded.
+func (h *HealthChecker) add(target *healthTarget) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.apis[target.spec] = target
+}
+
+// HealthReport is the result of a health check.
+type HealthReport struct {
+	// Healthy reports whether every API is reachable, accepts the credentials, and isn't failing.
+	Healthy bool        `json:"healthy"`
+	APIs    []APIHealth `json:"apis"`
+}
+
+// APIHealth is the health of one API.
+type APIHealth struct {
+	// Spec is the file path or URL the API's spec was read from, if it wasn't read from stdin.
+	Spec string `json:"spec,omitempty"`
+	// Title and Version are the title and version in the spec's info object.
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version,omitempty"`
+	// OpenAPI is the version of OpenAPI the spec is written in.
+	OpenAPI string `json:"openapi,omitempty"`
+	// URL is the health endpoint that was probed.
+	URL string `json:"url"`
+	// Reachable reports whether the API responded at all.
+	Reachable bool `json:"reachable"`
+	// Authorized reports whether the API accepted the credentials, or is unset if it couldn't be reached.
+	Authorized *bool `json:"authorized,omitempty"`
+	// Status is the status code of the API's response.
+	Status int `json:"status,omitempty"`
+	// LatencyMS is how long the API took to respond, in milliseconds.
+	LatencyMS int64 `json:"latencyMs"`
+	// Error is why the API couldn't be reached.
+	Error string `json:"error,omitempty"`
+}
+
+// healthy reports whether the API responded, accepted the credentials, and isn't failing.
+// A health endpoint the API doesn't have is no sign of trouble, since it still responded.
+func (a *APIHealth) healthy() bool {
+	return a.Reachable && a.Authorized != nil && *a.Authorized && a.Status < http.StatusInternalServerError
+}
+
+// Check probes the health endpoint of every API concurrently.
+func (h *HealthChecker) Check(ctx context.Context) *HealthReport {
+	h.mu.Lock()
+	targets := make([]*healthTarget, 0, len(h.apis))
+	for _, target := range h.apis {
+		targets = append(targets, target)
+	}
+	h.mu.Unlock()
+	slices.SortFunc(targets, func(a, b *healthTarget) int { return strings.Compare(a.spec, b.spec) })
+
+	report := &HealthReport{Healthy: true, APIs: make([]APIHealth, len(targets))}
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.APIs[i] = h.probe(ctx, target)
+		}()
+	}
+	wg.Wait()
+	for _, api := range report.APIs {
+		if !api.healthy() {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// probe sends a HEAD request to an API's health endpoint,
+// falling back to GET if the API doesn't allow HEAD.
+func (h *HealthChecker) probe(ctx context.Context, target *healthTarget) APIHealth {
+	health := APIHealth{
+		Spec:    target.spec,
+		Title:   target.title,
+		Version: target.version,
+		OpenAPI: target.openAPI,
+		URL:     target.baseURL + h.path,
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	status, err := sendHealthProbe(ctx, target.client, http.MethodHead, health.URL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = sendHealthProbe(ctx, target.client, http.MethodGet, health.URL)
+	}
+	health.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	authorized := status != http.StatusUnauthorized && status != http.StatusForbidden
+	health.Reachable = true
+	health.Authorized = &authorized
+	health.Status = status
+	return health
+}
+
+// sendHealthProbe sends a request to a health endpoint and returns the status code of the response.
+func sendHealthProbe(ctx context.Context, client *http.Client, method, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxHealthProbeBytes))
+	return resp.StatusCode, nil
+}
+
+// Handle handles a health/check request.
+func (h *HealthChecker) Handle(ctx context.Context, _ json.RawMessage) (any, error) {
+	return h.Check(ctx), nil
+}
+
+// Err returns an error describing the APIs in a report that aren't healthy, or nil if they all are.
+func (r *HealthReport) Err() error {
+	var problems []string
+	for _, api := range r.APIs {
+		if api.healthy() {
+			continue
+		}
+		switch {
+		case !api.Reachable:
+			problems = append(problems, fmt.Sprintf("%s is unreachable: %s", api.URL, api.Error))
+		case !*api.Authorized:
+			problems = append(problems, fmt.Sprintf("%s rejected the credentials (%d %s)", api.URL, api.Status, http.StatusText(api.Status)))
+		default:
+			problems = append(problems, fmt.Sprintf("%s is failing (%d %s)", api.URL, api.Status, http.StatusText(api.Status)))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("health check failed: %s", strings.Join(problems, "; "))
+}
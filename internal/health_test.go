@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var methods []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.Write([]byte(`{"status": "ok"}`))
+		}
+	}))
+	defer api.Close()
+
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "2.3.0"},
+  "servers": [{"url": "` + api.URL + `/v1"}],
+  "paths": {}
+}`)
+
+	t.Run("healthy", func(t *testing.T) {
+		methods = nil
+		checker := NewHealthChecker("healthz")
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, spec, api.Client(),
+			WithHealthChecker(checker),
+			WithSpecLocation("openapi.json"),
+			WithAuthProvider(HeaderAuth{Name: "Authorization", Value: "Bearer secret"})))
+
+		report := checker.Check(ctx)
+		require.NoError(t, report.Err())
+		assert.True(t, report.Healthy)
+		require.Len(t, report.APIs, 1)
+		health := report.APIs[0]
+		assert.Equal(t, "openapi.json", health.Spec)
+		assert.Equal(t, "Pet API", health.Title)
+		assert.Equal(t, "2.3.0", health.Version)
+		assert.Equal(t, "3.1.0", health.OpenAPI)
+		assert.Equal(t, api.URL+"/v1/healthz", health.URL)
+		assert.True(t, health.Reachable)
+		require.NotNil(t, health.Authorized)
+		assert.True(t, *health.Authorized)
+		assert.Equal(t, http.StatusOK, health.Status)
+		assert.Equal(t, []string{"HEAD /v1/healthz", "GET /v1/healthz"}, methods, "APIs that don't allow HEAD are probed with GET")
+
+		result, err := checker.Handle(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, report.Healthy, result.(*HealthReport).Healthy)
+	})
+
+	t.Run("credentials rejected", func(t *testing.T) {
+		checker := NewHealthChecker("")
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, spec, api.Client(),
+			WithHealthChecker(checker),
+			WithAuthProvider(HeaderAuth{Name: "Authorization", Value: "Bearer wrong"})))
+
+		report := checker.Check(ctx)
+		assert.False(t, report.Healthy)
+		require.Len(t, report.APIs, 1)
+		assert.True(t, report.APIs[0].Reachable)
+		require.NotNil(t, report.APIs[0].Authorized)
+		assert.False(t, *report.APIs[0].Authorized)
+		assert.ErrorContains(t, report.Err(), api.URL+"/v1/health rejected the credentials (401 Unauthorized)")
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		checker := NewHealthChecker("")
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Gone", "version": "1.0.0"},
+  "servers": [{"url": "`+closed.URL+`"}],
+  "paths": {}
+}`), nil, WithHealthChecker(checker)))
+
+		report := checker.Check(ctx)
+		assert.False(t, report.Healthy)
+		require.Len(t, report.APIs, 1)
+		assert.False(t, report.APIs[0].Reachable)
+		assert.Nil(t, report.APIs[0].Authorized)
+		assert.ErrorContains(t, report.Err(), "is unreachable")
+	})
+}
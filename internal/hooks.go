@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// RequestHook inspects or changes a request before it's sent to the API,
+// for example to sign it or to add a tenant header.
+// Returning an error fails the request without sending it.
+type RequestHook func(ctx context.Context, req *http.Request) error
+
+// ResponseHook inspects or changes a response from the API before a tool reads it.
+// Returning an error fails the request.
+type ResponseHook func(ctx context.Context, resp *http.Response) error
+
+// WithRequestHook runs hook on every request to the API, after credentials and headers are added,
+// so that it sees requests as they're sent. Hooks run in the order they're given.
+func WithRequestHook(hook RequestHook) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.requestHooks = append(cfg.requestHooks, hook) }
+}
+
+// WithResponseHook runs hook on every response from the API, before any other processing.
+// Hooks run in the order they're given.
+func WithResponseHook(hook ResponseHook) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.responseHooks = append(cfg.responseHooks, hook) }
+}
+
+// HookTransport is a RoundTripper that runs hooks on each request and its response.
+type HookTransport struct {
+	Base          http.RoundTripper
+	RequestHooks  []RequestHook
+	ResponseHooks []ResponseHook
+}
+
+// RoundTrip runs the request hooks on a copy of the request, sends it, and runs the response hooks on its response.
+func (t *HookTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if len(t.RequestHooks) > 0 {
+		req = req.Clone(req.Context())
+		for _, hook := range t.RequestHooks {
+			if err := hook(req.Context(), req); err != nil {
+				return nil, err
+			}
+		}
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range t.ResponseHooks {
+		if err := hook(req.Context(), resp); err != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// hookClient returns a copy of client that runs hooks on each request and its response.
+func hookClient(client *http.Client, requestHooks []RequestHook, responseHooks []ResponseHook) *http.Client {
+	hooked := *client
+	hooked.Transport = &HookTransport{Base: client.Transport, RequestHooks: requestHooks, ResponseHooks: responseHooks}
+	return &hooked
+}
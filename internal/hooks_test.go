@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithHooks(t *testing.T) {
+	var received *http.Request
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "Fido"}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {"/pets/{petId}": {"get": {
+    "operationId": "getPet",
+    "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+    "responses": {"200": {"description": "OK"}}
+  }}}
+}`, api.URL)
+
+	var order []string
+	sign := func(ctx context.Context, req *http.Request) error {
+		order = append(order, "sign")
+		// Request hooks see the credentials and headers emcee adds
+		req.Header.Set("X-Signature", "signed:"+req.Header.Get("Authorization")+":"+req.Header.Get("X-Tenant"))
+		return nil
+	}
+	reject := func(ctx context.Context, req *http.Request) error {
+		order = append(order, "reject")
+		if strings.HasSuffix(req.URL.Path, "/forbidden") {
+			return errors.New("pet is off limits")
+		}
+		return nil
+	}
+	rewrite := func(ctx context.Context, resp *http.Response) error {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		resp.Body = io.NopCloser(strings.NewReader(strings.ReplaceAll(string(body), "Fido", "Rex")))
+		return nil
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient,
+		WithAuthProvider(HeaderAuth{Name: "Authorization", Value: "Bearer token"}),
+		WithHeader("X-Tenant", "acme"),
+		WithRequestHook(sign),
+		WithRequestHook(reject),
+		WithResponseHook(rewrite),
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": "1"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, []string{"sign", "reject"}, order, "hooks run in the order they're given")
+	assert.Equal(t, "signed:Bearer token:acme", received.Header.Get("X-Signature"))
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "Rex", "response hooks can change responses")
+
+	received = nil
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": "forbidden"}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "pet is off limits")
+	assert.Nil(t, received, "a request hook's error keeps the request from being sent")
+}
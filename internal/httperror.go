@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// httpErrorMetaKey is the key of the status and body of an error response in a tool result's _meta.
+const httpErrorMetaKey = "emcee/httpError"
+
+// httpError describes an error response from the API, for clients to handle without parsing the result's text.
+type httpError struct {
+	Status int `json:"status"`
+	// Body is the response body, decoded if it's JSON, like an RFC 9457 problem detail
+	Body any `json:"body,omitempty"`
+}
+
+// httpErrorResult returns a tool error for an error response, so that the model can read the error and react to it.
+// The text has the status and the body, with JSON bodies indented, and the _meta has them for clients.
+func httpErrorResult(resp *http.Response, body []byte) *mcp.CallToolResultFor[any] {
+	status := fmt.Sprintf("%d", resp.StatusCode)
+	if text := http.StatusText(resp.StatusCode); text != "" {
+		status += " " + text
+	}
+	e := httpError{Status: resp.StatusCode}
+	var text string
+	var decoded any
+	if json.Unmarshal(body, &decoded) == nil {
+		e.Body = decoded
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			body = pretty.Bytes()
+		}
+	} else if len(body) > 0 {
+		e.Body = string(body)
+	}
+	if b := strings.TrimSpace(string(body)); b != "" {
+		text = fmt.Sprintf("Request failed with status %s:\n%s", status, b)
+	} else {
+		text = fmt.Sprintf("Request failed with status %s", status)
+	}
+	return &mcp.CallToolResultFor[any]{
+		Meta:    mcp.Meta{httpErrorMetaKey: e},
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+		IsError: true,
+	}
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		text   string
+		meta   httpError
+	}{
+		{
+			name:   "problem detail",
+			status: http.StatusNotFound,
+			body:   `{"type":"about:blank","title":"Not Found","detail":"No pet with ID 1"}`,
+			text:   "Request failed with status 404 Not Found:\n{\n  \"type\": \"about:blank\",\n  \"title\": \"Not Found\",\n  \"detail\": \"No pet with ID 1\"\n}",
+			meta:   httpError{Status: 404, Body: map[string]any{"type": "about:blank", "title": "Not Found", "detail": "No pet with ID 1"}},
+		},
+		{
+			name:   "text",
+			status: http.StatusServiceUnavailable,
+			body:   "upstream overloaded\n",
+			text:   "Request failed with status 503 Service Unavailable:\nupstream overloaded",
+			meta:   httpError{Status: 503, Body: "upstream overloaded\n"},
+		},
+		{
+			name:   "empty",
+			status: 599,
+			text:   "Request failed with status 599",
+			meta:   httpError{Status: 599},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := httpErrorResult(&http.Response{StatusCode: tt.status}, []byte(tt.body))
+			assert.True(t, result.IsError)
+			require.Len(t, result.Content, 1)
+			assert.Equal(t, tt.text, result.Content[0].(*mcp.TextContent).Text)
+			assert.Equal(t, tt.meta, result.Meta[httpErrorMetaKey])
+		})
+	}
+}
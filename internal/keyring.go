@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// resolveKeyringReference reads a secret from the operating system's keyring,
+// given a reference of the form service/account.
+// Either part may be percent-encoded, for names containing slashes or spaces.
+func resolveKeyringReference(ctx context.Context, reference string) (string, error) {
+	rawService, rawAccount, _ := strings.Cut(reference, "/")
+	service, err := url.PathUnescape(rawService)
+	if err != nil {
+		return "", fmt.Errorf("invalid keyring secret reference %q: %w", keyringReferencePrefix+reference, err)
+	}
+	account, err := url.PathUnescape(rawAccount)
+	if err != nil {
+		return "", fmt.Errorf("invalid keyring secret reference %q: %w", keyringReferencePrefix+reference, err)
+	}
+	if service == "" || account == "" {
+		return "", fmt.Errorf("invalid keyring secret reference %q: expected keyring://service/account", keyringReferencePrefix+reference)
+	}
+
+	secret, err := readKeyring(ctx, service, account)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from keyring: %w", err)
+	}
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("no secret in keyring for service %q and account %q", service, account)
+	}
+	return secret, nil
+}
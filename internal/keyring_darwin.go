@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityItemNotFound is the exit status of security(1) when no keychain item matches.
+const securityItemNotFound = 44
+
+// readKeyring reads a generic password from the macOS Keychain using security(1), which ships with macOS.
+func readKeyring(ctx context.Context, service, account string) (string, error) {
+	cmd := CommandContext(ctx, "/usr/bin/security", "find-generic-password", "-s", service, "-a", account, "-w")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.ExitCode() == securityItemNotFound {
+				return "", fmt.Errorf("no Keychain item for service %q and account %q", service, account)
+			}
+			return "", fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// writeKeyring adds a generic password to the macOS Keychain using security(1), replacing any with the same service and account.
+// The command is given on stdin, with the password hex-encoded, so that it doesn't appear in the process list.
+func writeKeyring(ctx context.Context, service, account, secret string) error {
+	cmd := CommandContext(ctx, "/usr/bin/security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		securityQuote(service), securityQuote(account), hex.EncodeToString([]byte(secret))))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("%s", message)
+		}
+		return err
+	}
+	return nil
+}
+
+// securityQuote quotes an argument of a command given to security -i, which splits commands like a shell.
+func securityQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
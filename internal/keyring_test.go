@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveKeyringReference(t *testing.T) {
+	for _, input := range []string{"keyring://", "keyring://api.example.com", "keyring://api.example.com/", "keyring:///alice", "keyring://api%zz/alice"} {
+		_, isSecret, err := ResolveSecretReference(context.Background(), input)
+		assert.True(t, isSecret)
+		assert.ErrorContains(t, err, "invalid keyring secret reference", input)
+	}
+
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the Keychain and Credential Manager aren't mocked")
+	}
+	originalCommand := CommandContext
+	originalLookPath := LookPath
+	t.Cleanup(func() {
+		CommandContext = originalCommand
+		LookPath = originalLookPath
+	})
+	LookPath = func(string) (string, error) { return "/usr/bin/secret-tool", nil }
+	var args []string
+	CommandContext = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		args = append([]string{name}, arg...)
+		if arg[len(arg)-1] == "bob" {
+			return exec.CommandContext(ctx, "false")
+		}
+		return exec.CommandContext(ctx, "echo", "s3cret")
+	}
+
+	secret, isSecret, err := ResolveSecretReference(context.Background(), "keyring://My%20API/alice")
+	require.NoError(t, err)
+	assert.True(t, isSecret)
+	assert.Equal(t, "s3cret", secret)
+	assert.Equal(t, []string{"secret-tool", "lookup", "service", "My API", "username", "alice"}, args)
+
+	_, _, err = ResolveSecretReference(context.Background(), "keyring://My%20API/bob")
+	assert.ErrorContains(t, err, `no secret for service "My API" and username "bob"`)
+}
@@ -0,0 +1,46 @@
+//go:build !darwin && !windows
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// readKeyring reads a secret from the Secret Service (GNOME Keyring, KWallet) using secret-tool(1) from libsecret.
+// Secrets are looked up by service and username attributes, as stored by `secret-tool store` and most keyring libraries.
+func readKeyring(ctx context.Context, service, account string) (string, error) {
+	if _, err := LookPath("secret-tool"); err != nil {
+		return "", fmt.Errorf("secret-tool (libsecret) not found in PATH: %w", err)
+	}
+	cmd := CommandContext(ctx, "secret-tool", "lookup", "service", service, "username", account)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && strings.TrimSpace(string(exitErr.Stderr)) != "" {
+			return "", fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("no secret for service %q and username %q: %w", service, account, err)
+	}
+	return string(output), nil
+}
+
+// writeKeyring stores a secret in the Secret Service using secret-tool(1), replacing any with the same service and username.
+// The secret is given on stdin, so that it doesn't appear in the process list.
+func writeKeyring(ctx context.Context, service, account, secret string) error {
+	if _, err := LookPath("secret-tool"); err != nil {
+		return fmt.Errorf("secret-tool (libsecret) not found in PATH: %w", err)
+	}
+	cmd := CommandContext(ctx, "secret-tool", "store", "--label="+service+" "+account, "service", service, "username", account)
+	cmd.Stdin = strings.NewReader(secret)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("%s", message)
+		}
+		return fmt.Errorf("error storing secret for service %q and username %q: %w", service, account, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+)
+
+const (
+	// credTypeGeneric is CRED_TYPE_GENERIC, the type of credentials stored by applications.
+	credTypeGeneric = 1
+	// credPersistLocalMachine is CRED_PERSIST_LOCAL_MACHINE, which keeps a credential across logon sessions on this computer.
+	credPersistLocalMachine = 2
+	// credMaxCredentialBlobSize is CRED_MAX_CREDENTIAL_BLOB_SIZE, the most bytes a credential can hold.
+	credMaxCredentialBlobSize = 5 * 512
+	// errorNotFound is ERROR_NOT_FOUND, returned when no credential matches.
+	errorNotFound = syscall.Errno(1168)
+)
+
+// credential is the CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// readKeyring reads a generic credential from the Windows Credential Manager.
+// Credentials are looked up by the target name service:account, as stored by most keyring libraries.
+func readKeyring(ctx context.Context, service, account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	ok, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if ok == 0 {
+		if errors.Is(err, errorNotFound) {
+			return "", fmt.Errorf("no credential with target %q", service+":"+account)
+		}
+		return "", err
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+// writeKeyring stores a generic credential in the Windows Credential Manager with the target name service:account,
+// replacing any with the same target name.
+func writeKeyring(ctx context.Context, service, account, secret string) error {
+	if len(secret) > credMaxCredentialBlobSize {
+		return fmt.Errorf("secret is %d bytes, more than the %d the Credential Manager can store", len(secret), credMaxCredentialBlobSize)
+	}
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+	if ok, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0); ok == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ResponseLimitStrategy is how a tool result is shortened when its response is too large.
+type ResponseLimitStrategy string
+
+const (
+	// ResponseLimitTruncate cuts the response to the limit, and appends a notice saying it was cut.
+	ResponseLimitTruncate ResponseLimitStrategy = "truncate"
+	// ResponseLimitFields returns only selected fields of a JSON response,
+	// truncating it if they're still too large.
+	ResponseLimitFields ResponseLimitStrategy = "fields"
+	// ResponseLimitResource stores the full response as a resource, and returns a link to it.
+	ResponseLimitResource ResponseLimitStrategy = "resource"
+)
+
+// ParseResponseLimitStrategy parses the name of a response limit strategy.
+func ParseResponseLimitStrategy(s string) (ResponseLimitStrategy, error) {
+	switch strategy := ResponseLimitStrategy(s); strategy {
+	case ResponseLimitTruncate, ResponseLimitFields, ResponseLimitResource:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown response limit strategy %q (expected %q, %q, or %q)", s, ResponseLimitTruncate, ResponseLimitFields, ResponseLimitResource)
+}
+
+// ResponseLimitOptions configures the size limit for tool results.
+type ResponseLimitOptions struct {
+	// MaxBytes is the most bytes of text a tool result may contain.
+	MaxBytes int
+	// Strategy is how results over the limit are shortened. If empty, they're truncated.
+	Strategy ResponseLimitStrategy
+	// Fields are the fields kept by ResponseLimitFields, as dotted paths like "id" or "owner.name".
+	// Fields of arrays apply to each of their elements.
+	Fields []string
+}
+
+// WithResponseLimit shortens tool results whose text is larger than a limit,
+// so that large API responses don't fill the model's context.
+// Tools have no output schemas then, since shortened results have no structured content.
+func WithResponseLimit(opts ResponseLimitOptions) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.responseLimit = &opts }
+}
+
+// responseLimiter shortens oversized tool results.
+type responseLimiter struct {
+	maxBytes int
+	strategy ResponseLimitStrategy
+	fields   [][]string
+	store    *responseStore
+}
+
+func newResponseLimiter(store *responseStore, opts ResponseLimitOptions) (*responseLimiter, error) {
+	if opts.MaxBytes <= 0 {
+		return nil, fmt.Errorf("response limit must be positive")
+	}
+	l := &responseLimiter{maxBytes: opts.MaxBytes, strategy: opts.Strategy, store: store}
+	if l.strategy == "" {
+		l.strategy = ResponseLimitTruncate
+	}
+	if _, err := ParseResponseLimitStrategy(string(l.strategy)); err != nil {
+		return nil, err
+	}
+	for _, field := range opts.Fields {
+		if field = strings.TrimSpace(field); field != "" {
+			l.fields = append(l.fields, strings.Split(field, "."))
+		}
+	}
+	if l.strategy == ResponseLimitFields && len(l.fields) == 0 {
+		return nil, fmt.Errorf("the %q response limit strategy requires fields to keep", ResponseLimitFields)
+	}
+	return l, nil
+}
+
+// limit returns a tool result whose text fits the limit, or the result itself if it already does.
+// Shortened results have no structured content, which would be as large as the text it replaces.
+func (l *responseLimiter) limit(result *mcp.CallToolResultFor[any], toolName, contentType string) *mcp.CallToolResultFor[any] {
+	if l == nil {
+		return result
+	}
+	var text strings.Builder
+	for _, content := range result.Content {
+		if c, ok := content.(*mcp.TextContent); ok {
+			text.WriteString(c.Text)
+		}
+	}
+	size := text.Len()
+	if size <= l.maxBytes {
+		return result
+	}
+
+	limited := &mcp.CallToolResultFor[any]{IsError: result.IsError, Meta: result.Meta}
+	for _, content := range result.Content {
+		if _, ok := content.(*mcp.TextContent); !ok {
+			limited.Content = append(limited.Content, content)
+		}
+	}
+	switch {
+	case l.strategy == ResponseLimitResource && !result.IsError:
+		mimeType := baseMediaType(contentType)
+		if mimeType == "" {
+			mimeType = "text/plain"
+		}
+		link := l.store.add(toolName+" response", mimeType, []byte(text.String()))
+		notice := fmt.Sprintf("The response is %d bytes, which is more than the limit of %d, so it was stored as the resource %s. Read the resource for the full response.", size, l.maxBytes, link.URI)
+		limited.Content = append(limited.Content, &mcp.TextContent{Text: notice}, link)
+		return limited
+	case l.strategy == ResponseLimitFields && !result.IsError:
+		selected, ok := l.selectFields(text.String())
+		if !ok {
+			return l.truncated(limited, text.String())
+		}
+		notice := fmt.Sprintf("[The response is %d bytes, which is more than the limit of %d, so only these fields are shown: %s]", size, l.maxBytes, l.fieldNames())
+		if len(selected) > l.maxBytes {
+			return l.truncated(limited, selected)
+		}
+		limited.Content = append(limited.Content, &mcp.TextContent{Text: selected + "\n\n" + notice})
+		return limited
+	}
+	return l.truncated(limited, text.String())
+}
+
+// truncated adds text to a result, cut to the limit, with a notice saying how much was cut.
+func (l *responseLimiter) truncated(result *mcp.CallToolResultFor[any], text string) *mcp.CallToolResultFor[any] {
+	cut := truncateUTF8(text, l.maxBytes)
+	notice := fmt.Sprintf("[Response truncated to %d of %d bytes. Use the tool's arguments to request less data, like a smaller page.]", len(cut), len(text))
+	result.Content = append(result.Content, &mcp.TextContent{Text: cut + "\n\n" + notice})
+	return result
+}
+
+// selectFields returns a JSON response with only the selected fields, or false if it isn't JSON.
+func (l *responseLimiter) selectFields(text string) (string, bool) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", false
+	}
+	var selected any
+	for _, path := range l.fields {
+		selected = mergeField(selected, v, path)
+	}
+	if selected == nil {
+		selected = map[string]any{}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(selected); err != nil {
+		return "", false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+// fieldNames returns the selected fields as a comma-separated list.
+func (l *responseLimiter) fieldNames() string {
+	names := make([]string, len(l.fields))
+	for i, path := range l.fields {
+		names[i] = strings.Join(path, ".")
+	}
+	return strings.Join(names, ", ")
+}
+
+// mergeField copies the field at path from src into dst, and returns dst.
+// Paths apply to each element of arrays, so that selecting "id" from a list of objects keeps the ID of each.
+func mergeField(dst, src any, path []string) any {
+	switch s := src.(type) {
+	case []any:
+		d, _ := dst.([]any)
+		if d == nil {
+			d = make([]any, len(s))
+		}
+		for i, elem := range s {
+			d[i] = mergeField(d[i], elem, path)
+		}
+		return d
+	case map[string]any:
+		if len(path) == 0 {
+			return s
+		}
+		d, _ := dst.(map[string]any)
+		if d == nil {
+			d = make(map[string]any)
+		}
+		value, ok := s[path[0]]
+		if !ok {
+			return d
+		}
+		if len(path) == 1 {
+			d[path[0]] = value
+		} else {
+			d[path[0]] = mergeField(d[path[0]], value, path[1:])
+		}
+		return d
+	}
+	if len(path) == 0 {
+		return src
+	}
+	return dst
+}
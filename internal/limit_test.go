@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithResponseLimit(t *testing.T) {
+	pets := make([]map[string]any, 50)
+	for i := range pets {
+		pets[i] = map[string]any{"id": i, "name": fmt.Sprintf("Pet %d", i), "bio": strings.Repeat("a good pet ", 10), "owner": map[string]any{"name": "Alice", "email": "alice@example.com"}}
+	}
+	body, err := json.Marshal(pets)
+	require.NoError(t, err)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pets":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(body)
+		case "/pet":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(pets[0])
+		case "/status":
+			_, _ = w.Write([]byte("ok"))
+		default:
+			http.Error(w, strings.Repeat("not found ", 1000), http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}},
+    "/pet": {"get": {"operationId": "getPet", "responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {
+      "type": "object", "properties": {"id": {"type": "integer"}, "name": {"type": "string"}}
+    }}}}}}},
+    "/status": {"get": {"operationId": "getStatus", "responses": {"200": {"description": "OK"}}}},
+    "/missing": {"get": {"operationId": "getMissing", "responses": {"200": {"description": "OK"}}}}
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	connect := func(t *testing.T, opts ResponseLimitOptions) *mcp.ClientSession {
+		t.Helper()
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithResponseLimit(opts)))
+		return connectTestClient(t, ctx, server)
+	}
+	call := func(t *testing.T, session *mcp.ClientSession, name string) *mcp.CallToolResult {
+		t.Helper()
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: name})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("truncate", func(t *testing.T) {
+		session := connect(t, ResponseLimitOptions{MaxBytes: 1000})
+		result := call(t, session, "listPets")
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text := result.Content[0].(*mcp.TextContent).Text
+		assert.Contains(t, text, "[Response truncated to 1000 of ")
+		assert.Less(t, len(text), 1200)
+		assert.Nil(t, result.StructuredContent)
+
+		result = call(t, session, "getStatus")
+		assert.Equal(t, "ok", result.Content[0].(*mcp.TextContent).Text, "results within the limit are unchanged")
+
+		result = call(t, session, "getMissing")
+		assert.True(t, result.IsError, "errors stay errors")
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "[Response truncated")
+	})
+
+	t.Run("output schemas", func(t *testing.T) {
+		session := connect(t, ResponseLimitOptions{MaxBytes: 100})
+		tools, err := session.ListTools(ctx, nil)
+		require.NoError(t, err)
+		for _, tool := range tools.Tools {
+			assert.Nil(t, tool.OutputSchema, "tools whose results may be shortened declare no output schema, which would require structured content")
+		}
+		result := call(t, session, "getPet")
+		require.False(t, result.IsError)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "[Response truncated")
+		assert.Nil(t, result.StructuredContent)
+	})
+
+	t.Run("fields", func(t *testing.T) {
+		session := connect(t, ResponseLimitOptions{MaxBytes: 5000, Strategy: ResponseLimitFields, Fields: []string{"id", "owner.name"}})
+		result := call(t, session, "listPets")
+		require.False(t, result.IsError)
+		text := result.Content[0].(*mcp.TextContent).Text
+		selected, notice, ok := strings.Cut(text, "\n\n")
+		require.True(t, ok)
+		assert.Contains(t, notice, "only these fields are shown: id, owner.name")
+		var got []map[string]any
+		require.NoError(t, json.Unmarshal([]byte(selected), &got))
+		require.Len(t, got, 50)
+		assert.Equal(t, map[string]any{"id": float64(7), "owner": map[string]any{"name": "Alice"}}, got[7])
+
+		// Errors, and fields that are still too large, are truncated
+		result = call(t, session, "getMissing")
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "[Response truncated")
+	})
+
+	t.Run("resource", func(t *testing.T) {
+		session := connect(t, ResponseLimitOptions{MaxBytes: 1000, Strategy: ResponseLimitResource})
+		result := call(t, session, "listPets")
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 2)
+		assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "stored as the resource emcee://responses/")
+		link, ok := result.Content[1].(*mcp.ResourceLink)
+		require.True(t, ok)
+		assert.Equal(t, "application/json", link.MIMEType)
+
+		resource, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: link.URI})
+		require.NoError(t, err)
+		require.Len(t, resource.Contents, 1)
+		var got []map[string]any
+		require.NoError(t, json.Unmarshal([]byte(resource.Contents[0].Text), &got))
+		assert.Len(t, got, 50, "the resource has the full response")
+	})
+}
+
+func TestNewResponseLimiter(t *testing.T) {
+	store := newResponseStore(mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil))
+	_, err := newResponseLimiter(store, ResponseLimitOptions{})
+	assert.Error(t, err)
+	_, err = newResponseLimiter(store, ResponseLimitOptions{MaxBytes: 10, Strategy: "summarize"})
+	assert.ErrorContains(t, err, "unknown response limit strategy")
+	_, err = newResponseLimiter(store, ResponseLimitOptions{MaxBytes: 10, Strategy: ResponseLimitFields})
+	assert.ErrorContains(t, err, "requires fields")
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// linksMetaKey is the key under which the hypermedia links in a response are included in a tool result's metadata.
+const linksMetaKey = "emcee/links"
+
+// maxLinks is the maximum number of links included in a tool result's metadata.
+const maxLinks = 50
+
+// responseLink is a hypermedia link in a response, like those in HAL's _links or JSON:API's links,
+// which tells the model where it can go next.
+type responseLink struct {
+	Rel       string `json:"rel"`
+	Href      string `json:"href"`
+	Title     string `json:"title,omitempty"`
+	Templated bool   `json:"templated,omitempty"`
+	// Resource is the URI of the link as an MCP resource, if it matches one of the spec's resource templates.
+	Resource string `json:"resource,omitempty"`
+}
+
+// parseLinks returns the links at the top level of a JSON response body,
+// from a HAL "_links" object or a JSON:API "links" object, ordered by relation.
+// Link values that aren't URLs or link objects, like JSON:API's null pagination links, are ignored.
+func parseLinks(body []byte) []responseLink {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+	raw, ok := doc["_links"]
+	if !ok {
+		raw = doc["links"]
+	}
+	var rels map[string]any
+	if err := json.Unmarshal(raw, &rels); err != nil {
+		return nil
+	}
+
+	var links []responseLink
+	for rel, v := range rels {
+		if rel == "curies" {
+			continue
+		}
+		values, ok := v.([]any)
+		if !ok {
+			values = []any{v}
+		}
+		for _, value := range values {
+			if l, ok := parseLink(rel, value); ok {
+				links = append(links, l)
+			}
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Rel != links[j].Rel {
+			return links[i].Rel < links[j].Rel
+		}
+		return links[i].Href < links[j].Href
+	})
+	if len(links) > maxLinks {
+		links = links[:maxLinks]
+	}
+	return links
+}
+
+// parseLink returns a link from a URL or a link object with an href.
+func parseLink(rel string, v any) (responseLink, bool) {
+	switch v := v.(type) {
+	case string:
+		return responseLink{Rel: rel, Href: v}, v != ""
+	case map[string]any:
+		href, _ := v["href"].(string)
+		title, _ := v["title"].(string)
+		templated, _ := v["templated"].(bool)
+		return responseLink{Rel: rel, Href: href, Title: title, Templated: templated}, href != ""
+	}
+	return responseLink{}, false
+}
+
+// hypermediaLinks attaches the links in responses to tool results,
+// mapping links to the spec's resource templates so that the model can read them as resources.
+type hypermediaLinks struct {
+	baseURL   string
+	templates []*resourceTemplate
+}
+
+// add attaches the links in a JSON response to a tool result.
+// Relative links are resolved against the URL of the request.
+func (h *hypermediaLinks) add(result *mcp.CallToolResultFor[any], resp *http.Response, body []byte) {
+	if result.IsError || !isJSONMediaType(resp.Header.Get("Content-Type")) {
+		return
+	}
+	links := parseLinks(body)
+	if len(links) == 0 {
+		return
+	}
+	for i := range links {
+		if links[i].Templated {
+			continue
+		}
+		if resp.Request != nil && resp.Request.URL != nil {
+			if ref, err := url.Parse(links[i].Href); err == nil {
+				links[i].Href = resp.Request.URL.ResolveReference(ref).String()
+			}
+		}
+		links[i].Resource = h.resource(links[i].Href)
+	}
+	if result.Meta == nil {
+		result.Meta = mcp.Meta{}
+	}
+	result.Meta[linksMetaKey] = links
+}
+
+// resource returns the resource URI for a link to the API, or "" if it doesn't match a resource template.
+func (h *hypermediaLinks) resource(href string) string {
+	rest, ok := strings.CutPrefix(href, h.baseURL+"/")
+	if !ok {
+		return ""
+	}
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	uri := resourceTemplateScheme + rest
+	for _, t := range h.templates {
+		if _, ok := t.match(uri); ok {
+			return uri
+		}
+	}
+	return ""
+}
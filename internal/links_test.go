@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLinks(t *testing.T) {
+	hal := `{
+  "name": "Fido",
+  "_links": {
+    "self": {"href": "/pets/1"},
+    "curies": [{"name": "acme", "href": "https://docs.example.com/{rel}", "templated": true}],
+    "owners": [{"href": "/owners/2", "title": "Alice"}, {"href": "/owners/1", "title": "Bob"}],
+    "search": {"href": "/pets{?species}", "templated": true}
+  }
+}`
+	assert.Equal(t, []responseLink{
+		{Rel: "owners", Href: "/owners/1", Title: "Bob"},
+		{Rel: "owners", Href: "/owners/2", Title: "Alice"},
+		{Rel: "search", Href: "/pets{?species}", Templated: true},
+		{Rel: "self", Href: "/pets/1"},
+	}, parseLinks([]byte(hal)))
+
+	jsonAPI := `{
+  "data": [{"type": "pets", "id": "1"}],
+  "links": {
+    "self": "https://api.example.com/pets?page=2",
+    "next": {"href": "https://api.example.com/pets?page=3", "title": "Next page"},
+    "prev": null
+  }
+}`
+	assert.Equal(t, []responseLink{
+		{Rel: "next", Href: "https://api.example.com/pets?page=3", Title: "Next page"},
+		{Rel: "self", Href: "https://api.example.com/pets?page=2"},
+	}, parseLinks([]byte(jsonAPI)))
+
+	assert.Nil(t, parseLinks([]byte(`{"links": ["https://example.com"]}`)), "a list of URLs isn't a links object")
+	assert.Nil(t, parseLinks([]byte(`[{"_links": {"self": {"href": "/pets/1"}}}]`)))
+}
+
+func TestResponseLinks(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/hal+json")
+		_, _ = w.Write([]byte(`{
+  "name": "Fido",
+  "_links": {
+    "self": {"href": "/v1/pets/1"},
+    "owner": {"href": "/v1/owners/2"},
+    "toys": {"href": "/v1/pets/1/toys?page=1"},
+    "docs": {"href": "https://docs.example.com/pets"}
+  }
+}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "%s/v1"}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/owners/{ownerId}": {
+      "get": {
+        "operationId": "getOwner",
+        "parameters": [{"name": "ownerId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithResourceTemplates()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": "1"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, []any{
+		map[string]any{"rel": "docs", "href": "https://docs.example.com/pets"},
+		map[string]any{"rel": "owner", "href": api.URL + "/v1/owners/2", "resource": "api://owners/2"},
+		map[string]any{"rel": "self", "href": api.URL + "/v1/pets/1", "resource": "api://pets/1"},
+		map[string]any{"rel": "toys", "href": api.URL + "/v1/pets/1/toys?page=1"},
+	}, result.Meta[linksMetaKey])
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Listen listens for MCP clients at an address:
+// a Unix domain socket, as unix:///path/to/sock, or a Windows named pipe, as npipe:////./pipe/name.
+// A socket file left behind by a server that's no longer running is replaced,
+// but listening fails if another server is accepting connections on it.
+func Listen(address string) (net.Listener, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", address, err)
+	}
+	path := u.Path
+	if u.Host != "" {
+		// unix://relative/path is read as a host, but means a path
+		path = u.Host + u.Path
+	}
+	if path == "" {
+		return nil, fmt.Errorf("invalid listen address %q: missing path", address)
+	}
+	switch u.Scheme {
+	case "unix":
+		return listenUnix(path)
+	case "npipe":
+		return listenPipe(strings.ReplaceAll(path, "/", `\`))
+	default:
+		return nil, fmt.Errorf("invalid listen address %q: expected unix:///path/to/sock or npipe:////./pipe/name", address)
+	}
+}
+
+// listenUnix listens on a Unix domain socket, replacing a stale socket file.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another server is listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("error removing stale socket %s: %w", path, err)
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// Serve accepts connections from ln and serves each as a separate MCP session,
+// with newline-delimited JSON-RPC messages as over stdio, until ctx is canceled.
+// Each session has a random ID, so that state kept for a client isn't shared with other clients.
+// configure is called with the streams of each connection, to set up extension methods and replays.
+// When ctx is canceled, the listener is closed, and every open session is shut down as by Run,
+// giving the requests in flight until timeout to finish.
+func Serve(ctx context.Context, server *mcp.Server, ln net.Listener, configure func(*Stdio), timeout time.Duration) error {
+	sessionCtx, abort := abortable(ctx, server)
+	defer abort()
+	var (
+		mu       sync.Mutex
+		sessions = make(map[*mcp.ServerSession]chan error) // closed when the session ends
+		wg       sync.WaitGroup
+	)
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				mu.Lock()
+				open := maps.Clone(sessions)
+				mu.Unlock()
+				errs := make(chan error, len(open))
+				for session, done := range open {
+					go func() { errs <- drain(session, done, timeout, abort) }()
+				}
+				var drainErr error
+				for range open {
+					drainErr = cmp.Or(drainErr, <-errs)
+				}
+				wg.Wait()
+				return cmp.Or(drainErr, ctx.Err())
+			}
+			wg.Wait()
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("error accepting connection: %w", err)
+		}
+
+		stdio := &Stdio{In: conn, Out: conn, Err: io.Discard, SessionID: rand.Text()}
+		if configure != nil {
+			configure(stdio)
+		}
+		session, err := server.Connect(sessionCtx, stdio.Transport(), nil)
+		if err != nil {
+			conn.Close()
+			continue
+		}
+		done := make(chan error)
+		mu.Lock()
+		sessions[session] = done
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = session.Wait()
+			mu.Lock()
+			delete(sessions, session)
+			mu.Unlock()
+			close(done)
+		}()
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package internal
+
+import (
+	"fmt"
+	"net"
+)
+
+// listenPipe returns an error, since named pipes are only available on Windows.
+func listenPipe(name string) (net.Listener, error) {
+	return nil, fmt.Errorf("named pipes are only supported on Windows; use a unix:// socket instead")
+}
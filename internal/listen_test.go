@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeUnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, which a test's temporary directory can exceed
+	dir, err := os.MkdirTemp("", "emcee")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "mcp.sock")
+
+	// A socket left behind by a server that's no longer running is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := Listen("unix://" + path)
+	require.NoError(t, err)
+
+	_, err = Listen("unix://" + path)
+	assert.ErrorContains(t, err, "another server is listening")
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	server.AddTool(&mcp.Tool{Name: "ping", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "pong"}}}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	serveCtx, stop := context.WithCancel(ctx)
+	served := make(chan error, 1)
+	go func() { served <- Serve(serveCtx, server, ln, nil, DefaultShutdownTimeout) }()
+
+	// Several clients are served at once, each in its own session
+	var sessions []*mcp.ClientSession
+	for range 2 {
+		conn, err := net.Dial("unix", path)
+		require.NoError(t, err)
+		stdio := &Stdio{In: conn, Out: conn, Err: io.Discard}
+		client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "dev"}, nil)
+		session, err := client.Connect(ctx, stdio.Transport(), nil)
+		require.NoError(t, err)
+		defer session.Close()
+		sessions = append(sessions, session)
+	}
+	for _, session := range sessions {
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "ping"})
+		require.NoError(t, err)
+		assert.Equal(t, "pong", result.Content[0].(*mcp.TextContent).Text)
+	}
+
+	stop()
+	assert.ErrorIs(t, <-served, context.Canceled)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "socket is removed when the server stops")
+}
+
+func TestListenInvalidAddress(t *testing.T) {
+	for _, address := range []string{"tcp://localhost:8080", "unix://", "/tmp/mcp.sock"} {
+		_, err := Listen(address)
+		assert.ErrorContains(t, err, "invalid listen address", address)
+	}
+}
@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipeW    = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe    = kernel32.NewProc("ConnectNamedPipe")
+	procGetOverlappedResult = kernel32.NewProc("GetOverlappedResult")
+)
+
+const (
+	// pipeAccessDuplex is PIPE_ACCESS_DUPLEX, for pipes that are read and written by both ends.
+	pipeAccessDuplex = 0x00000003
+	// fileFlagFirstPipeInstance is FILE_FLAG_FIRST_PIPE_INSTANCE, which fails if another server created the pipe.
+	fileFlagFirstPipeInstance = 0x00080000
+	// pipeUnlimitedInstances is PIPE_UNLIMITED_INSTANCES.
+	pipeUnlimitedInstances = 255
+	// pipeBufferSize is the size of the input and output buffers of each pipe instance.
+	pipeBufferSize = 64 * 1024
+	// errorPipeConnected is ERROR_PIPE_CONNECTED, returned when a client connected before ConnectNamedPipe was called.
+	errorPipeConnected = syscall.Errno(535)
+)
+
+// pipeListener accepts connections to a named pipe, creating a new instance of the pipe for each client.
+// Instances are opened for overlapped I/O, so that reads and writes of a connection don't block each other.
+type pipeListener struct {
+	name string
+
+	mu        sync.Mutex
+	next      syscall.Handle // the instance waiting for the next client
+	accepting bool           // whether Accept is waiting for a client to connect to next
+	closed    bool
+}
+
+// listenPipe listens on a named pipe, like \\.\pipe\emcee.
+func listenPipe(name string) (net.Listener, error) {
+	h, err := createPipeInstance(name, true)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %w", name, err)
+	}
+	return &pipeListener{name: name, next: h}, nil
+}
+
+// createPipeInstance creates an instance of a named pipe.
+// Creating the first instance fails if another server has already created the pipe.
+func createPipeInstance(name string, first bool) (syscall.Handle, error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	flags := uint32(pipeAccessDuplex | syscall.FILE_FLAG_OVERLAPPED)
+	if first {
+		flags |= fileFlagFirstPipeInstance
+	}
+	r, _, err := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(p)),
+		uintptr(flags),
+		0, // PIPE_TYPE_BYTE | PIPE_READMODE_BYTE | PIPE_WAIT
+		pipeUnlimitedInstances,
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		0,
+	)
+	if syscall.Handle(r) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, err
+	}
+	return syscall.Handle(r), nil
+}
+
+// Accept implements the net.Listener interface.
+// It waits for a client to connect to the pending instance of the pipe, then creates another instance for the next client.
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	h := l.next
+	l.accepting = true
+	l.mu.Unlock()
+
+	err := connectPipe(h)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.accepting = false
+	if err != nil {
+		return nil, err
+	}
+	if l.closed {
+		// The connection was made by Close to stop waiting
+		syscall.CloseHandle(h)
+		return nil, net.ErrClosed
+	}
+	next, err := createPipeInstance(l.name, false)
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, fmt.Errorf("error creating pipe instance: %w", err)
+	}
+	l.next = next
+	return &pipeConn{File: os.NewFile(uintptr(h), l.name), addr: pipeAddr(l.name)}, nil
+}
+
+// connectPipe waits for a client to connect to an instance of a pipe.
+func connectPipe(h syscall.Handle) error {
+	// Without an event, the pipe handle itself is signaled when a client connects
+	overlapped := &syscall.Overlapped{}
+	r, _, err := procConnectNamedPipe.Call(uintptr(h), uintptr(unsafe.Pointer(overlapped)))
+	if r != 0 || errors.Is(err, errorPipeConnected) {
+		return nil
+	}
+	if !errors.Is(err, syscall.ERROR_IO_PENDING) {
+		return fmt.Errorf("error connecting pipe: %w", err)
+	}
+	var n uint32
+	if r, _, err := procGetOverlappedResult.Call(uintptr(h), uintptr(unsafe.Pointer(overlapped)), uintptr(unsafe.Pointer(&n)), 1); r == 0 {
+		return fmt.Errorf("error connecting pipe: %w", err)
+	}
+	return nil
+}
+
+// Close implements the net.Listener interface.
+// A pending Accept is released by connecting to the pipe, since waiting for a client can't otherwise be interrupted.
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	h, accepting := l.next, l.accepting
+	l.mu.Unlock()
+
+	if !accepting {
+		return syscall.CloseHandle(h)
+	}
+	if p, err := syscall.UTF16PtrFromString(l.name); err == nil {
+		if client, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0); err == nil {
+			syscall.CloseHandle(client)
+		}
+	}
+	return nil
+}
+
+// Addr implements the net.Listener interface.
+func (l *pipeListener) Addr() net.Addr { return pipeAddr(l.name) }
+
+// pipeConn is a client's connection to an instance of a named pipe.
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
+
+// pipeAddr is the name of a named pipe.
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+)
+
+// NewLogHandler returns a handler that writes records at level and above to w,
+// as text ("text" or "") or as JSON lines ("json") for ingesting by log pipelines.
+// Records logged with the context of a tool call include the tool's name and the client session, if any,
+// unless the record already has attributes with those keys.
+// The values of secret query parameters are redacted from URLs in records, like those of requests being retried.
+func NewLogHandler(w io.Writer, format string, level slog.Leveler) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	switch format {
+	case "", "text":
+		return &contextHandler{slog.NewTextHandler(w, opts)}, nil
+	case "json":
+		return &contextHandler{slog.NewJSONHandler(w, opts)}, nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: expected text or json", format)
+	}
+}
+
+type logAttrsKey struct{}
+
+// withLogAttrs returns a context whose records include attrs, when logged with a handler from NewLogHandler.
+func withLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	return context.WithValue(ctx, logAttrsKey{}, append(slices.Clip(prev), attrs...))
+}
+
+// contextHandler adds the attributes of a record's context to the record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	if len(attrs) > 0 {
+		present := make(map[string]bool, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			present[a.Key] = true
+			return true
+		})
+		r = r.Clone()
+		for _, a := range attrs {
+			if !present[a.Key] {
+				r.AddAttrs(a)
+			}
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr redacts the secrets in URLs in the value of a log attribute.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Any().(type) {
+	case string:
+		a.Value = slog.StringValue(redactURLs(v))
+	case error:
+		a.Value = slog.StringValue(redactURLs(v.Error()))
+	case *url.URL:
+		if redacted, ok := redactURL(v); ok {
+			a.Value = slog.StringValue(redacted)
+		}
+	case url.URL:
+		if redacted, ok := redactURL(&v); ok {
+			a.Value = slog.StringValue(redacted)
+		}
+	}
+	return a
+}
@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := NewLogHandler(&buf, "json", slog.LevelInfo)
+	require.NoError(t, err)
+	logger := slog.New(handler).With("version", "dev")
+
+	ctx := withLogAttrs(context.Background(), slog.String("tool", "getPet"), slog.String("session", "abc"))
+	logger.DebugContext(ctx, "not logged")
+	logger.InfoContext(ctx, "calling tool", "status", 200)
+	logger.WarnContext(ctx, "notice", "tool", "listPets")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var first, second map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	assert.Equal(t, "calling tool", first["msg"])
+	assert.Equal(t, "getPet", first["tool"])
+	assert.Equal(t, "abc", first["session"])
+	assert.Equal(t, "dev", first["version"])
+	assert.EqualValues(t, 200, first["status"])
+	assert.Equal(t, "listPets", second["tool"], "attributes of the record take precedence")
+
+	buf.Reset()
+	handler, err = NewLogHandler(&buf, "text", slog.LevelInfo)
+	require.NoError(t, err)
+	slog.New(handler).InfoContext(ctx, "calling tool")
+	assert.Contains(t, buf.String(), "tool=getPet session=abc")
+
+	// Secrets in the query of URLs are redacted
+	buf.Reset()
+	u, err := url.Parse("https://api.example.com/pets?api_key=abc123")
+	require.NoError(t, err)
+	slog.New(handler).Info("performing request", "url", u, "error", errors.New(`Get "https://api.example.com/pets?token=abc123": EOF`))
+	assert.NotContains(t, buf.String(), "abc123")
+
+	_, err = NewLogHandler(&buf, "xml", slog.LevelInfo)
+	assert.ErrorContains(t, err, `invalid log format "xml"`)
+}
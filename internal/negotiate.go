@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// NegotiateAuth authenticates requests with SPNEGO (HTTP Negotiate), as intranet APIs behind
+// Windows Integrated Authentication expect, using the Kerberos tickets in the host's credential cache,
+// like those obtained with kinit.
+type NegotiateAuth struct {
+	// SPN is the service principal name tickets are requested for. If empty, it's HTTP/ and the API's host name.
+	SPN string
+	// ConfigPath is the path of the Kerberos configuration file.
+	// If empty, it's the path in KRB5_CONFIG, or /etc/krb5.conf.
+	ConfigPath string
+	// CachePath is the path of the credential cache.
+	// If empty, it's the path in KRB5CCNAME, or /tmp/krb5cc_ followed by the user's ID.
+	CachePath string
+
+	mu     sync.Mutex
+	client *client.Client
+}
+
+// Apply sets the Authorization header to a SPNEGO token for the API, loading the credential cache on first use.
+func (a *NegotiateAuth) Apply(ctx context.Context, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.client == nil {
+		if err := a.load(); err != nil {
+			return err
+		}
+	}
+	if err := spnego.SetSPNEGOHeader(a.client, req, a.SPN); err != nil {
+		return fmt.Errorf("error negotiating Kerberos authentication: %w", err)
+	}
+	return nil
+}
+
+// Refresh loads the credential cache again, picking up tickets renewed since it was loaded.
+func (a *NegotiateAuth) Refresh(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.load()
+}
+
+func (a *NegotiateAuth) load() error {
+	configPath := a.ConfigPath
+	if configPath == "" {
+		configPath = os.Getenv("KRB5_CONFIG")
+	}
+	if configPath == "" {
+		configPath = "/etc/krb5.conf"
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("error reading Kerberos configuration %s: %w", configPath, err)
+	}
+	cachePath, err := negotiateCachePath(a.CachePath)
+	if err != nil {
+		return err
+	}
+	cache, err := credentials.LoadCCache(cachePath)
+	if err != nil {
+		return fmt.Errorf("error reading Kerberos credential cache %s (run kinit to get a ticket): %w", cachePath, err)
+	}
+	cl, err := client.NewFromCCache(cache, cfg, client.DisablePAFXFAST(true))
+	if err != nil {
+		return fmt.Errorf("error reading Kerberos credentials: %w", err)
+	}
+	if a.client != nil {
+		a.client.Destroy()
+	}
+	a.client = cl
+	return nil
+}
+
+// negotiateCachePath returns the path of a credential cache file, from KRB5CCNAME if path is empty.
+// Only caches stored in files can be read; others, like the KCM and KEYRING caches some systems default to, can't.
+func negotiateCachePath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv("KRB5CCNAME")
+	}
+	if path == "" {
+		uid := os.Getuid()
+		if uid < 0 {
+			return "", fmt.Errorf("no Kerberos credential cache: set KRB5CCNAME to the path of one")
+		}
+		return fmt.Sprintf("/tmp/krb5cc_%d", uid), nil
+	}
+	kind, rest, ok := strings.Cut(path, ":")
+	switch {
+	case !ok || strings.Contains(kind, "/"):
+		return path, nil
+	case kind == "FILE":
+		return rest, nil
+	default:
+		return "", fmt.Errorf("unsupported Kerberos credential cache %q: only FILE caches can be read (e.g. run kinit -c FILE:/tmp/krb5cc_emcee and set KRB5CCNAME to the same)", path)
+	}
+}
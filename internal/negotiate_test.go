@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateCachePath(t *testing.T) {
+	t.Setenv("KRB5CCNAME", "")
+	if os.Getuid() >= 0 {
+		path, err := negotiateCachePath("")
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid()), path)
+	}
+
+	t.Setenv("KRB5CCNAME", "FILE:/tmp/krb5cc_test")
+	path, err := negotiateCachePath("")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/krb5cc_test", path)
+
+	path, err = negotiateCachePath("/var/run/krb5cc")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/run/krb5cc", path, "a path given explicitly is used over KRB5CCNAME")
+
+	_, err = negotiateCachePath("KEYRING:persistent:1000")
+	assert.ErrorContains(t, err, "only FILE caches can be read")
+}
+
+func TestNegotiateAuthWithoutTicket(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "krb5.conf")
+	require.NoError(t, os.WriteFile(configPath, []byte(`[libdefaults]
+  default_realm = EXAMPLE.COM
+[realms]
+  EXAMPLE.COM = {
+    kdc = kdc.example.com
+  }
+`), 0o644))
+
+	auth := &NegotiateAuth{ConfigPath: configPath, CachePath: filepath.Join(dir, "krb5cc")}
+	req, err := http.NewRequest(http.MethodGet, "https://intranet.example.com/api", nil)
+	require.NoError(t, err)
+	err = auth.Apply(context.Background(), req)
+	assert.ErrorContains(t, err, "run kinit to get a ticket")
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	auth = &NegotiateAuth{ConfigPath: filepath.Join(dir, "missing.conf")}
+	assert.ErrorContains(t, auth.Refresh(context.Background()), "error reading Kerberos configuration")
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// NotFoundCacheOptions configures caching of 404 responses for read-only tools.
+type NotFoundCacheOptions struct {
+	// TTL is how long a 404 response is reused for identical calls.
+	TTL time.Duration
+	// Tools lists the tools whose 404 responses are cached. If empty, all read-only tools are cached.
+	Tools []string
+}
+
+// WithNotFoundCache reuses 404 responses from read-only tools for identical calls made within a short TTL,
+// so that an agent retrying a bad identifier in a loop doesn't repeatedly hit the API.
+func WithNotFoundCache(opts NotFoundCacheOptions) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.notFoundCache = &opts }
+}
+
+// notFoundCache holds 404 results by tool name and arguments.
+type notFoundCache struct {
+	ttl   time.Duration
+	tools map[string]struct{}
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]notFoundEntry
+}
+
+type notFoundEntry struct {
+	result  *mcp.CallToolResultFor[any]
+	expires time.Time
+}
+
+func newNotFoundCache(opts NotFoundCacheOptions) (*notFoundCache, error) {
+	if opts.TTL <= 0 {
+		return nil, fmt.Errorf("not found cache TTL must be positive")
+	}
+	c := &notFoundCache{
+		ttl:     opts.TTL,
+		now:     time.Now,
+		entries: make(map[string]notFoundEntry),
+	}
+	if len(opts.Tools) > 0 {
+		c.tools = make(map[string]struct{})
+		for _, name := range opts.Tools {
+			c.tools[name] = struct{}{}
+		}
+	}
+	return c, nil
+}
+
+// applies reports whether calls to a tool are cached.
+func (c *notFoundCache) applies(toolName, method string) bool {
+	if !isReadOnlyMethod(method) {
+		return false
+	}
+	if c.tools == nil {
+		return true
+	}
+	_, ok := c.tools[toolName]
+	return ok
+}
+
+// key identifies a call by tool name and arguments. Map keys are marshaled in sorted order,
+// so equivalent arguments produce the same key.
+func (c *notFoundCache) key(toolName string, args map[string]any) (string, bool) {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	return toolName + "\x00" + string(b), true
+}
+
+// get returns a cached 404 result for a call, if one hasn't expired.
+func (c *notFoundCache) get(key string) (*mcp.CallToolResultFor[any], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// put caches a 404 result for a call, and evicts expired entries.
+func (c *notFoundCache) put(key string, result *mcp.CallToolResultFor[any]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = notFoundEntry{result: result, expires: now.Add(c.ttl)}
+}
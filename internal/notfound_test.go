@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithNotFoundCache(t *testing.T) {
+	var calls atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/pets/1" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"Fido"}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      },
+      "delete": {
+        "operationId": "deletePet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"204": {"description": "Deleted"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithNotFoundCache(NotFoundCacheOptions{TTL: time.Minute})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	call := func(name, petID string) *mcp.CallToolResult {
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: map[string]any{"petId": petID}})
+		require.NoError(t, err)
+		return result
+	}
+
+	for range 3 {
+		assert.True(t, call("getPet", "404").IsError)
+	}
+	assert.EqualValues(t, 1, calls.Load(), "repeated 404s are served from the cache")
+
+	assert.False(t, call("getPet", "1").IsError)
+	assert.False(t, call("getPet", "1").IsError)
+	assert.EqualValues(t, 3, calls.Load(), "successful responses aren't cached")
+
+	call("deletePet", "404")
+	call("deletePet", "404")
+	assert.EqualValues(t, 5, calls.Load(), "only read-only tools are cached")
+}
+
+func TestNotFoundCacheExpiry(t *testing.T) {
+	c, err := newNotFoundCache(NotFoundCacheOptions{TTL: time.Second, Tools: []string{"getPet"}})
+	require.NoError(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	assert.True(t, c.applies("getPet", "GET"))
+	assert.False(t, c.applies("listPets", "GET"))
+	assert.False(t, c.applies("getPet", "POST"))
+
+	key, ok := c.key("getPet", map[string]any{"petId": "404", "expand": true})
+	require.True(t, ok)
+	same, _ := c.key("getPet", map[string]any{"expand": true, "petId": "404"})
+	assert.Equal(t, key, same)
+
+	result := &mcp.CallToolResultFor[any]{IsError: true}
+	c.put(key, result)
+	cached, ok := c.get(key)
+	assert.True(t, ok)
+	assert.Same(t, result, cached)
+
+	now = now.Add(time.Second)
+	_, ok = c.get(key)
+	assert.False(t, ok)
+
+	_, err = newNotFoundCache(NotFoundCacheOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// responseNotices are warnings and deprecation notices sent by the API in response headers.
+type responseNotices struct {
+	// Warnings are the texts of Warning headers (RFC 9111).
+	Warnings []string `json:"warnings,omitempty"`
+	// Deprecation is when the endpoint was or will be deprecated (RFC 9745).
+	// It's empty if the API didn't say when, or if the endpoint isn't deprecated.
+	Deprecation string `json:"deprecation,omitempty"`
+	// Deprecated reports whether the API sent a Deprecation header.
+	Deprecated bool `json:"deprecated,omitempty"`
+	// Sunset is when the endpoint is expected to stop responding (RFC 8594).
+	Sunset string `json:"sunset,omitempty"`
+	// Links are URLs of documentation about the deprecation or sunset.
+	Links []string `json:"links,omitempty"`
+}
+
+// noticesMetaKey is the key of response notices in a tool result's _meta.
+const noticesMetaKey = "emcee/notices"
+
+// parseResponseNotices returns the notices in response headers, or nil if there are none.
+func parseResponseNotices(h http.Header) *responseNotices {
+	n := &responseNotices{}
+	for _, v := range h.Values("Warning") {
+		n.Warnings = append(n.Warnings, parseWarnings(v)...)
+	}
+	if v := strings.TrimSpace(h.Get("Deprecation")); v != "" && !strings.EqualFold(v, "false") {
+		n.Deprecated = true
+		if !strings.EqualFold(v, "true") {
+			n.Deprecation = parseNoticeDate(v)
+		}
+	}
+	if v := strings.TrimSpace(h.Get("Sunset")); v != "" {
+		n.Sunset = parseNoticeDate(v)
+	}
+	for _, v := range h.Values("Link") {
+		for _, link := range strings.Split(v, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			if !ok {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(name, "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "deprecation") || strings.EqualFold(rel, "sunset") {
+						n.Links = append(n.Links, strings.Trim(strings.TrimSpace(target), "<>"))
+						break
+					}
+				}
+			}
+		}
+	}
+	if len(n.Warnings) == 0 && !n.Deprecated && n.Sunset == "" {
+		return nil
+	}
+	return n
+}
+
+// parseWarnings returns the texts of the warnings in a Warning header value,
+// which has the form: code agent "text" ["date"], ...
+func parseWarnings(v string) []string {
+	var warnings []string
+	for _, warning := range splitQuoted(v, ',') {
+		start := strings.IndexByte(warning, '"')
+		if start < 0 {
+			continue
+		}
+		end := closingQuote(warning, start)
+		if end < 0 {
+			continue
+		}
+		text, err := strconv.Unquote(warning[start : end+1])
+		if err != nil {
+			text = warning[start+1 : end]
+		}
+		warnings = append(warnings, text)
+	}
+	return warnings
+}
+
+// splitQuoted splits s at each occurrence of sep outside of a quoted string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			if end := closingQuote(s, i); end >= 0 {
+				i = end
+			}
+		case sep:
+			parts = append(parts, s[:i])
+			s, i = s[i+1:], -1
+		}
+	}
+	return append(parts, s)
+}
+
+// closingQuote returns the index of the quote closing the quoted string starting at start, or -1.
+func closingQuote(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseNoticeDate converts a structured field date (@1688169599) or HTTP date to RFC 3339.
+// Values in other formats are returned unchanged.
+func parseNoticeDate(v string) string {
+	if strings.HasPrefix(v, "@") {
+		if secs, err := strconv.ParseInt(v[1:], 10, 64); err == nil {
+			return time.Unix(secs, 0).UTC().Format(time.RFC3339)
+		}
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t.UTC().Format(time.RFC3339)
+	}
+	return v
+}
+
+// String describes the notices for inclusion in a tool result.
+func (n *responseNotices) String() string {
+	var parts []string
+	switch {
+	case n.Deprecated && n.Deprecation != "":
+		parts = append(parts, fmt.Sprintf("This API endpoint is deprecated as of %s.", n.Deprecation))
+	case n.Deprecated:
+		parts = append(parts, "This API endpoint is deprecated.")
+	}
+	if n.Sunset != "" {
+		subject := "This API endpoint"
+		if n.Deprecated {
+			subject = "It"
+		}
+		parts = append(parts, fmt.Sprintf("%s is scheduled to stop working on %s.", subject, n.Sunset))
+	}
+	if len(n.Links) > 0 {
+		parts = append(parts, fmt.Sprintf("See %s for details.", strings.Join(n.Links, ", ")))
+	}
+	for _, w := range n.Warnings {
+		parts = append(parts, fmt.Sprintf("Warning: %s", w))
+	}
+	return "API notice: " + strings.Join(parts, " ")
+}
+
+// noticeLog records which notices have been logged, so that repeated calls don't flood the log.
+type noticeLog struct {
+	seen sync.Map
+}
+
+// add attaches the notices in a response to a tool result, and logs them the first time they're seen for a tool.
+func (l *noticeLog) add(result *mcp.CallToolResultFor[any], resp *http.Response, toolName string, logger *slog.Logger) {
+	n := parseResponseNotices(resp.Header)
+	if n == nil {
+		return
+	}
+	text := n.String()
+	result.Content = append(result.Content, &mcp.TextContent{Text: text})
+	if result.Meta == nil {
+		result.Meta = mcp.Meta{}
+	}
+	result.Meta[noticesMetaKey] = n
+	if _, logged := l.seen.LoadOrStore(toolName+"\x00"+text, struct{}{}); !logged {
+		logger.Warn("API returned notice", "tool", toolName, "deprecated", n.Deprecated, "sunset", n.Sunset, "warnings", n.Warnings)
+	}
+}
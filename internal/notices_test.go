@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResponseNotices(t *testing.T) {
+	assert.Nil(t, parseResponseNotices(http.Header{}))
+
+	h := http.Header{}
+	h.Add("Warning", `299 api.example.com "Use /v2/pets, this is going away" "Sat, 01 Jul 2023 00:00:00 GMT", 199 - "Miscellaneous \"warning\""`)
+	h.Set("Deprecation", "@1688169600")
+	h.Set("Sunset", "Mon, 01 Jan 2024 00:00:00 GMT")
+	h.Add("Link", `<https://api.example.com/changelog>; rel="deprecation"; type="text/html", <https://api.example.com/v2>; rel="successor-version"`)
+
+	n := parseResponseNotices(h)
+	require.NotNil(t, n)
+	assert.Equal(t, []string{"Use /v2/pets, this is going away", `Miscellaneous "warning"`}, n.Warnings)
+	assert.True(t, n.Deprecated)
+	assert.Equal(t, "2023-07-01T00:00:00Z", n.Deprecation)
+	assert.Equal(t, "2024-01-01T00:00:00Z", n.Sunset)
+	assert.Equal(t, []string{"https://api.example.com/changelog"}, n.Links)
+	assert.Equal(t, "API notice: This API endpoint is deprecated as of 2023-07-01T00:00:00Z. It is scheduled to stop working on 2024-01-01T00:00:00Z. "+
+		"See https://api.example.com/changelog for details. Warning: Use /v2/pets, this is going away Warning: Miscellaneous \"warning\"", n.String())
+
+	n = parseResponseNotices(http.Header{"Deprecation": {"true"}})
+	require.NotNil(t, n)
+	assert.True(t, n.Deprecated)
+	assert.Empty(t, n.Deprecation)
+}
+
+func TestRegisterToolsReportsResponseNotices(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", "Mon, 01 Jan 2024 00:00:00 GMT")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}
+  }
+}`, api.URL)
+
+	var logs bytes.Buffer
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	for range 2 {
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 2)
+		assert.Equal(t, "API notice: This API endpoint is deprecated. It is scheduled to stop working on 2024-01-01T00:00:00Z.", result.Content[1].(*mcp.TextContent).Text)
+		assert.Equal(t, map[string]any{"deprecated": true, "sunset": "2024-01-01T00:00:00Z"}, result.Meta[noticesMetaKey])
+	}
+	assert.Equal(t, 1, strings.Count(logs.String(), "API returned notice"), "notices are logged once per tool")
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// rawArgumentsKey is the context key for the raw arguments of a tools/call request.
+type rawArgumentsKey struct{}
+
+// rawArgumentsMiddleware records the raw arguments of tools/call requests in the context,
+// so that tool handlers can recover numbers exactly as the client sent them.
+// The SDK decodes arguments into float64, which can't represent integers above 2^53,
+// corrupting large IDs like those generated by Twitter's Snowflake.
+// It runs after validation and coercion, so the recorded arguments are the ones that were checked.
+func rawArgumentsMiddleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if r, ok := req.(*mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]]); ok && r.Params != nil && len(r.Params.Arguments) > 0 {
+				ctx = context.WithValue(ctx, rawArgumentsKey{}, r.Params.Arguments)
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// preciseArguments returns a tool's arguments with numbers decoded as json.Number,
+// using the raw arguments recorded in ctx.
+// Values the client didn't send, such as defaults applied by the SDK, are kept as is.
+// If there are no raw arguments, args is returned unchanged.
+func preciseArguments(ctx context.Context, args map[string]any) map[string]any {
+	raw, ok := ctx.Value(rawArgumentsKey{}).(json.RawMessage)
+	if !ok {
+		return args
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var precise map[string]any
+	if err := dec.Decode(&precise); err != nil || precise == nil {
+		return args
+	}
+	for name, value := range args {
+		if _, ok := precise[name]; !ok {
+			precise[name] = value
+		}
+	}
+	return precise
+}
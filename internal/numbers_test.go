@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsPreservesLargeNumbers(t *testing.T) {
+	var gotPath, gotQuery, gotBody string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":1234567890123456789,"parent":{"id":9007199254740993}}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Post API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/posts/{postId}/replies": {
+      "post": {
+        "operationId": "replyToPost",
+        "parameters": [
+          {"name": "postId", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}},
+          {"name": "since", "in": "query", "schema": {"type": "integer", "format": "int64"}}
+        ],
+        "requestBody": {"content": {"application/json": {"schema": {
+          "type": "object",
+          "properties": {
+            "quoteId": {"type": "integer", "format": "int64"},
+            "weight": {"type": "number", "default": 0.5}
+          }
+        }}}},
+        "responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "object"}}}}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name: "replyToPost",
+		Arguments: json.RawMessage(`{
+			"postId": 1234567890123456789,
+			"since": 9007199254740993,
+			"quoteId": 1152921504606846977
+		}`),
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError, "%v", result.Content)
+
+	assert.Equal(t, "/posts/1234567890123456789/replies", gotPath)
+	assert.Equal(t, "since=9007199254740993", gotQuery)
+	assert.JSONEq(t, `{"quoteId":1152921504606846977,"weight":0.5}`, gotBody, "defaults are still applied")
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	assert.Contains(t, text, "1234567890123456789")
+	assert.Contains(t, text, "9007199254740993")
+	structured, err := json.Marshal(result.StructuredContent)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1234567890123456789,"parent":{"id":9007199254740993}}`, string(structured))
+}
+
+func TestEqualBodiesComparesNumbersExactly(t *testing.T) {
+	assert.True(t, equalBodies([]byte(`{"id": 1234567890123456789}`), []byte(`{"id":1234567890123456789}`)))
+	assert.False(t, equalBodies([]byte(`{"id":1234567890123456789}`), []byte(`{"id":1234567890123456788}`)))
+	assert.False(t, equalBodies([]byte(`{} {}`), []byte(`{}`)))
+}
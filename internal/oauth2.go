@@ -0,0 +1,389 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const (
+	// oauth2ExpiryLeeway is how long before an access token expires that it's renewed,
+	// so that it doesn't expire while a request is on its way.
+	oauth2ExpiryLeeway = 30 * time.Second
+	// oauth2RefreshInterval is how soon after renewing an access token it can be renewed again.
+	// Calls that fail at once with the same expired token renew it only once,
+	// which matters for providers that revoke a refresh token once it's been used.
+	oauth2RefreshInterval = 5 * time.Second
+	// maxOAuth2ResponseBytes is the most of a token endpoint's response read.
+	maxOAuth2ResponseBytes = 1 << 20
+	// oauth2KeyringService is the keyring service that tokens saved by emcee login are stored under,
+	// with the API's server URL as the account.
+	oauth2KeyringService = "emcee"
+	// oauth2KeyringTimeout is how long reading saved tokens from the keyring at startup may take.
+	oauth2KeyringTimeout = 10 * time.Second
+)
+
+// OAuth2Auth authenticates requests with OAuth 2.0 access tokens obtained from a refresh token.
+// Access tokens are renewed before they expire, and when the API rejects one with 401 Unauthorized.
+// If the provider rotates refresh tokens, the new refresh token is kept,
+// and saved to the keyring or state file, if there is one, so that it outlives the process.
+type OAuth2Auth struct {
+	// TokenURL is the provider's token endpoint.
+	// If empty, it's the refreshUrl or tokenUrl of the spec's oauth2 security scheme.
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	// RefreshToken is exchanged for access tokens, unless the keyring or state file has a newer one.
+	RefreshToken string
+	Scopes       []string
+	// StatePath is the path of a file the latest refresh token is saved to, and read from at startup.
+	StatePath string
+	// Keyring is the account, under the emcee service of the OS keyring, that tokens are saved to and read from,
+	// in place of the state file. Logins saved by LoginOAuth2 also keep the client credentials and token URL there.
+	Keyring string
+	// Client sends requests to the token endpoint. If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	mu          sync.Mutex
+	loaded      bool
+	accessToken string
+	expiry      time.Time
+	refreshedAt time.Time
+}
+
+// oauth2State is the contents of an OAuth2Auth's state file, or of its keyring entry.
+// Only the keyring keeps the client credentials and token URL.
+type oauth2State struct {
+	RefreshToken string `json:"refresh_token"`
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	TokenURL     string `json:"token_url,omitempty"`
+}
+
+// oauth2Token is a token endpoint's successful response.
+type oauth2Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// oauth2Error is a token endpoint's error response.
+type oauth2Error struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// WithOAuth2 authenticates every request to the API with access tokens from auth.
+// If auth has no token URL, the one in the spec's oauth2 security scheme is used,
+// and it's an error for the spec not to declare one.
+func WithOAuth2(auth *OAuth2Auth) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.oauth2 = auth }
+}
+
+// Apply sets the Authorization header to a bearer access token, renewing it first if it's missing or about to expire.
+func (a *OAuth2Auth) Apply(ctx context.Context, req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.load(ctx); err != nil {
+		return err
+	}
+	if a.accessToken == "" || (!a.expiry.IsZero() && time.Now().Add(oauth2ExpiryLeeway).After(a.expiry)) {
+		if err := a.refresh(ctx); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+a.accessToken)
+	return nil
+}
+
+// Refresh exchanges the refresh token for a new access token,
+// unless the access token was renewed a moment ago by another call.
+func (a *OAuth2Auth) Refresh(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.accessToken != "" && time.Since(a.refreshedAt) < oauth2RefreshInterval {
+		return nil
+	}
+	return a.refresh(ctx)
+}
+
+// discover sets the token URL from a spec's oauth2 security scheme, if it isn't set,
+// resolving a relative URL against the API's server URL.
+func (a *OAuth2Auth) discover(doc *v3.Document, baseURL string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.TokenURL != "" {
+		return nil
+	}
+	flow, ok := oauth2Flow(doc)
+	if !ok {
+		return fmt.Errorf("OAuth 2.0 credentials were provided without a token URL, but the spec declares no oauth2 security scheme with one")
+	}
+	tokenURL := flow.TokenUrl
+	if flow.RefreshUrl != "" {
+		tokenURL = flow.RefreshUrl
+	}
+	var err error
+	a.TokenURL, err = resolveOAuth2URL(baseURL, tokenURL)
+	return err
+}
+
+// resolveOAuth2URL resolves a URL of a spec's oauth2 security scheme, which may be relative to the API's server URL.
+func resolveOAuth2URL(baseURL, rawURL string) (string, error) {
+	base, err := url.Parse(baseURL + "/")
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid OAuth 2.0 URL %q: %w", rawURL, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// savedOAuth2Login returns the OAuth2Auth for the tokens emcee login saved for an API to the keyring,
+// if the spec declares an authorization code flow, which is what emcee login runs.
+// It returns nil if there's no such flow or no saved tokens.
+// The keyring is only read if emcee login recorded a login for the API.
+func savedOAuth2Login(doc *v3.Document, baseURL string, client *http.Client, logger *slog.Logger) *OAuth2Auth {
+	if _, _, ok := authorizationCodeFlow(doc); !ok {
+		return nil
+	}
+	if !slices.Contains(oauth2Logins(), baseURL) {
+		logger.Info("no saved OAuth 2.0 login for API; run emcee login with its spec to log in", "url", baseURL)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), oauth2KeyringTimeout)
+	defer cancel()
+	auth := &OAuth2Auth{Keyring: baseURL, Client: client}
+	if err := auth.load(ctx); err != nil || auth.RefreshToken == "" {
+		logger.Info("no saved OAuth 2.0 login for API; run emcee login with its spec to log in", "url", baseURL, "error", err)
+		return nil
+	}
+	logger.Debug("using saved OAuth 2.0 login", "url", baseURL)
+	return auth
+}
+
+// oauth2Flow returns the flow of a spec's oauth2 security scheme that has a token URL,
+// preferring the authorization code flow, whose tokens are the ones refresh tokens come with.
+func oauth2Flow(doc *v3.Document) (*v3.OAuthFlow, bool) {
+	flows := func(scheme *v3.SecurityScheme) []*v3.OAuthFlow {
+		if scheme.Flows == nil {
+			return nil
+		}
+		f := scheme.Flows
+		return []*v3.OAuthFlow{f.AuthorizationCode, f.Password, f.ClientCredentials}
+	}
+	scheme, ok := securityScheme(doc, func(scheme *v3.SecurityScheme) bool {
+		if scheme.Type != "oauth2" {
+			return false
+		}
+		for _, flow := range flows(scheme) {
+			if flow != nil && flow.TokenUrl != "" {
+				return true
+			}
+		}
+		return false
+	})
+	if !ok {
+		return nil, false
+	}
+	for _, flow := range flows(scheme) {
+		if flow != nil && flow.TokenUrl != "" {
+			return flow, true
+		}
+	}
+	return nil, false
+}
+
+// load reads the saved state, once. The caller must hold a.mu.
+func (a *OAuth2Auth) load(ctx context.Context) error {
+	if a.loaded {
+		return nil
+	}
+	if err := a.loadState(ctx); err != nil {
+		return err
+	}
+	a.loaded = true
+	return nil
+}
+
+// refresh exchanges the refresh token for an access token. The caller must hold a.mu.
+func (a *OAuth2Auth) refresh(ctx context.Context) error {
+	if err := a.load(ctx); err != nil {
+		return err
+	}
+	if a.TokenURL == "" {
+		return fmt.Errorf("no OAuth 2.0 token URL")
+	}
+	if a.RefreshToken == "" {
+		return fmt.Errorf("no OAuth 2.0 refresh token")
+	}
+
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {a.RefreshToken}}
+	if a.ClientID != "" {
+		form.Set("client_id", a.ClientID)
+	}
+	if a.ClientSecret != "" {
+		form.Set("client_secret", a.ClientSecret)
+	}
+	if len(a.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.Scopes, " "))
+	}
+	token, err := a.requestToken(ctx, form)
+	if err != nil {
+		return err
+	}
+
+	a.accessToken = token.AccessToken
+	a.refreshedAt = time.Now()
+	a.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		a.expiry = a.refreshedAt.Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	if token.RefreshToken != "" && token.RefreshToken != a.RefreshToken {
+		a.RefreshToken = token.RefreshToken
+		if err := a.saveState(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestToken sends a request to the token endpoint and decodes its response.
+func (a *OAuth2Auth) requestToken(ctx context.Context, form url.Values) (*oauth2Token, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting OAuth 2.0 access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxOAuth2ResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error reading OAuth 2.0 token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e oauth2Error
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			if e.Description != "" {
+				return nil, fmt.Errorf("OAuth 2.0 token request failed: %s (%s)", e.Error, e.Description)
+			}
+			return nil, fmt.Errorf("OAuth 2.0 token request failed: %s", e.Error)
+		}
+		return nil, fmt.Errorf("OAuth 2.0 token request failed: %s", resp.Status)
+	}
+	var token oauth2Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid OAuth 2.0 token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("invalid OAuth 2.0 token response: no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return nil, fmt.Errorf("unsupported OAuth 2.0 token type %q", token.TokenType)
+	}
+	return &token, nil
+}
+
+// loadState reads the refresh token saved in the keyring or state file, which is newer than the one given, if there is one.
+// Client credentials and the token URL saved in the keyring are used if they aren't set.
+func (a *OAuth2Auth) loadState(ctx context.Context) error {
+	var state oauth2State
+	switch {
+	case a.Keyring != "":
+		secret, err := readKeyring(ctx, oauth2KeyringService, a.Keyring)
+		if err != nil {
+			return fmt.Errorf("error reading OAuth 2.0 tokens from keyring: %w", err)
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(secret)), &state); err != nil {
+			return fmt.Errorf("invalid OAuth 2.0 tokens in keyring for %s: %w", a.Keyring, err)
+		}
+	case a.StatePath != "":
+		data, err := os.ReadFile(a.StatePath)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading OAuth 2.0 state file: %w", err)
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return fmt.Errorf("invalid OAuth 2.0 state file %s: %w", a.StatePath, err)
+		}
+	default:
+		return nil
+	}
+	if state.RefreshToken != "" {
+		a.RefreshToken = state.RefreshToken
+	}
+	if a.ClientID == "" && a.ClientSecret == "" {
+		a.ClientID, a.ClientSecret = state.ClientID, state.ClientSecret
+	}
+	if a.TokenURL == "" {
+		a.TokenURL = state.TokenURL
+	}
+	return nil
+}
+
+// saveState writes the refresh token to the keyring, or to the state file, replacing it at once,
+// so that a crash never leaves it half written. The file is readable only by its owner.
+func (a *OAuth2Auth) saveState(ctx context.Context) error {
+	if a.Keyring != "" {
+		data, err := json.Marshal(oauth2State{RefreshToken: a.RefreshToken, ClientID: a.ClientID, ClientSecret: a.ClientSecret, TokenURL: a.TokenURL})
+		if err != nil {
+			return err
+		}
+		if err := writeKeyring(ctx, oauth2KeyringService, a.Keyring, string(data)); err != nil {
+			return fmt.Errorf("error saving OAuth 2.0 tokens to keyring: %w", err)
+		}
+		return nil
+	}
+	if a.StatePath == "" {
+		return nil
+	}
+	data, err := json.Marshal(oauth2State{RefreshToken: a.RefreshToken})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.StatePath), 0o700); err != nil {
+		return fmt.Errorf("error saving OAuth 2.0 state file: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(a.StatePath), "."+filepath.Base(a.StatePath)+".*")
+	if err != nil {
+		return fmt.Errorf("error saving OAuth 2.0 state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving OAuth 2.0 state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving OAuth 2.0 state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), a.StatePath); err != nil {
+		return fmt.Errorf("error saving OAuth 2.0 state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuth2Auth(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	refreshToken := "rt-0"
+	accessToken := ""
+	issued := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, r.ParseForm())
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != refreshToken || r.Form.Get("client_id") != "emcee" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant", "error_description": "refresh token is invalid"}`))
+			return
+		}
+		// Refresh tokens are rotated, and each can only be used once
+		issued++
+		accessToken = fmt.Sprintf("at-%d", issued)
+		refreshToken = fmt.Sprintf("rt-%d", issued)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": accessToken, "token_type": "Bearer", "expires_in": 3600, "refresh_token": refreshToken,
+		})
+	})
+	mux.HandleFunc("GET /api/pets", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if accessToken == "" || r.Header.Get("Authorization") != "Bearer "+accessToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	})
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "` + api.URL + `/api"}],
+  "components": {"securitySchemes": {"oauth": {"type": "oauth2", "flows": {"authorizationCode": {
+    "authorizationUrl": "/oauth/authorize", "tokenUrl": "/oauth/token", "scopes": {}
+  }}}}},
+  "security": [{"oauth": []}],
+  "paths": {"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}}
+}`)
+	statePath := filepath.Join(t.TempDir(), "emcee", "oauth2.json")
+	auth := &OAuth2Auth{ClientID: "emcee", RefreshToken: "rt-0", StatePath: statePath, Client: api.Client()}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, api.Client(), WithOAuth2(auth)))
+	assert.Equal(t, api.URL+"/oauth/token", auth.TokenURL, "the token URL is resolved against the server URL")
+	clientSession := connectTestClient(t, ctx, server)
+
+	savedRefreshToken := func() string {
+		data, err := os.ReadFile(statePath)
+		require.NoError(t, err)
+		var state oauth2State
+		require.NoError(t, json.Unmarshal(data, &state))
+		return state.RefreshToken
+	}
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, "rt-1", savedRefreshToken(), "rotated refresh tokens are saved")
+	info, err := os.Stat(statePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// The API revokes the access token
+	mu.Lock()
+	accessToken = "revoked"
+	mu.Unlock()
+	auth.mu.Lock()
+	auth.refreshedAt = time.Time{}
+	auth.mu.Unlock()
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+	require.NoError(t, err)
+	require.False(t, result.IsError, "a rejected access token is renewed and the call retried")
+	assert.Equal(t, "rt-2", savedRefreshToken())
+
+	// A later run starts from the saved refresh token, rather than the one it was given, which was used up
+	restarted := &OAuth2Auth{TokenURL: auth.TokenURL, ClientID: "emcee", RefreshToken: "rt-0", StatePath: statePath, Client: api.Client()}
+	req, err := http.NewRequest(http.MethodGet, api.URL+"/api/pets", nil)
+	require.NoError(t, err)
+	require.NoError(t, restarted.Apply(ctx, req))
+	assert.Equal(t, "Bearer at-3", req.Header.Get("Authorization"))
+
+	stale := &OAuth2Auth{TokenURL: auth.TokenURL, ClientID: "emcee", RefreshToken: "rt-0", Client: api.Client()}
+	assert.ErrorContains(t, stale.Apply(ctx, req), "invalid_grant (refresh token is invalid)")
+}
+
+func TestOAuth2AuthWithoutTokenURL(t *testing.T) {
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {}
+}`)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	err := RegisterTools(server, spec, nil, WithOAuth2(&OAuth2Auth{RefreshToken: "rt"}))
+	assert.ErrorContains(t, err, "no oauth2 security scheme")
+}
@@ -0,0 +1,351 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// DefaultOAuth2CallbackAddress is the address LoginOAuth2 receives the authorization callback at,
+// a free port on the loopback interface, as RFC 8252 recommends for native apps.
+const DefaultOAuth2CallbackAddress = "127.0.0.1:0"
+
+// oauth2CallbackPath is the path of the redirect URI the provider sends the browser back to.
+const oauth2CallbackPath = "/callback"
+
+// OAuth2LoginOptions configures LoginOAuth2.
+type OAuth2LoginOptions struct {
+	// ClientID identifies emcee to the provider, as registered with it.
+	ClientID string
+	// ClientSecret is sent with the token request, for providers that require one even with PKCE.
+	ClientSecret string
+	// Scopes are requested for the tokens.
+	// If empty, they're the scopes the spec's security requirements name for the scheme.
+	Scopes []string
+	// AuthorizationURL and TokenURL are the provider's endpoints.
+	// If empty, they're those of the spec's authorization code flow.
+	AuthorizationURL string
+	TokenURL         string
+	// Listen is the address the callback is received at (default DefaultOAuth2CallbackAddress).
+	// The redirect URI is http:// followed by this address, with the port chosen if it's 0, and /callback.
+	Listen string
+	// Open opens the authorization URL in a browser. If nil, the system's default browser is opened.
+	Open func(ctx context.Context, url string) error
+	// Out is where the authorization URL is printed, for when a browser can't be opened. If nil, it isn't printed.
+	Out io.Writer
+	// Client sends the token request. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// oauth2Callback is the result of the authorization request, as the provider redirected the browser to the callback with it.
+type oauth2Callback struct {
+	code string
+	err  error
+}
+
+// LoginOAuth2 runs the OAuth 2.0 authorization code flow with PKCE for the API of a spec:
+// it opens the provider's authorization page in a browser, receives the code the provider redirects back with
+// on a loopback callback, and exchanges it for tokens. The refresh token is saved to the OS keyring,
+// with the client credentials and token URL, for the API's server URL,
+// which RegisterTools uses when no other credentials are given. It returns the server URL.
+// Server variables and references are resolved as opts say.
+func LoginOAuth2(ctx context.Context, specData []byte, login OAuth2LoginOptions, opts ...RegisterToolsOption) (string, error) {
+	cfg := &registerToolsConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+	model, baseURL, err := buildModel(specData, cfg.serverVars, cfg.refs)
+	if err != nil {
+		return "", err
+	}
+	if login.ClientID == "" {
+		return "", fmt.Errorf("no OAuth 2.0 client ID")
+	}
+
+	authorizationURL, tokenURL, scopes := login.AuthorizationURL, login.TokenURL, login.Scopes
+	if name, flow, ok := authorizationCodeFlow(&model.Model); ok {
+		if authorizationURL == "" {
+			authorizationURL = flow.AuthorizationUrl
+		}
+		if tokenURL == "" {
+			tokenURL = flow.TokenUrl
+		}
+		if len(scopes) == 0 {
+			scopes = requiredScopes(&model.Model, name)
+		}
+	}
+	if authorizationURL == "" || tokenURL == "" {
+		return "", fmt.Errorf("the spec declares no oauth2 security scheme with an authorization code flow; pass its authorization and token URLs")
+	}
+	if authorizationURL, err = resolveOAuth2URL(baseURL, authorizationURL); err != nil {
+		return "", err
+	}
+	if tokenURL, err = resolveOAuth2URL(baseURL, tokenURL); err != nil {
+		return "", err
+	}
+	authURL, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid OAuth 2.0 authorization URL %q: %w", authorizationURL, err)
+	}
+
+	listen := login.Listen
+	if listen == "" {
+		listen = DefaultOAuth2CallbackAddress
+	}
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", fmt.Errorf("invalid callback address %q: %w", listen, err)
+	}
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return "", fmt.Errorf("error listening for OAuth 2.0 callback on %s: %w", listen, err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	redirectURI := "http://" + net.JoinHostPort(host, port) + oauth2CallbackPath
+
+	// The verifier proves the token request comes from whoever made the authorization request,
+	// and the state that the callback is for it
+	verifier := rand.Text() + rand.Text()
+	challenge := sha256.Sum256([]byte(verifier))
+	state := rand.Text()
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", login.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, " "))
+	}
+	authURL.RawQuery = query.Encode()
+
+	callbacks := make(chan oauth2Callback, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+oauth2CallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("state") != state {
+			http.Error(w, "This login link has expired. Run emcee login again.", http.StatusBadRequest)
+			return
+		}
+		var callback oauth2Callback
+		switch {
+		case params.Get("error") != "":
+			callback.err = fmt.Errorf("OAuth 2.0 authorization failed: %s", params.Get("error"))
+			if description := params.Get("error_description"); description != "" {
+				callback.err = fmt.Errorf("OAuth 2.0 authorization failed: %s (%s)", params.Get("error"), description)
+			}
+			http.Error(w, "Authorization failed. You can close this window.", http.StatusForbidden)
+		case params.Get("code") == "":
+			callback.err = fmt.Errorf("OAuth 2.0 authorization failed: no code in callback")
+			http.Error(w, "Authorization failed. You can close this window.", http.StatusBadRequest)
+		default:
+			callback.code = params.Get("code")
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, "You're logged in to emcee. You can close this window.\n")
+		}
+		select {
+		case callbacks <- callback:
+		default:
+		}
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	if login.Out != nil {
+		fmt.Fprintf(login.Out, "Open this URL in a browser to log in, if one doesn't open:\n\n  %s\n\n", authURL)
+	}
+	open := login.Open
+	if open == nil {
+		open = openBrowser
+	}
+	if err := open(ctx, authURL.String()); err != nil && login.Out != nil {
+		fmt.Fprintf(login.Out, "Couldn't open a browser: %v\n", err)
+	}
+
+	var callback oauth2Callback
+	select {
+	case callback = <-callbacks:
+	case <-ctx.Done():
+		return "", fmt.Errorf("waiting for OAuth 2.0 authorization: %w", ctx.Err())
+	}
+	if callback.err != nil {
+		return "", callback.err
+	}
+
+	auth := &OAuth2Auth{TokenURL: tokenURL, ClientID: login.ClientID, ClientSecret: login.ClientSecret, Keyring: baseURL, Client: login.Client}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {callback.code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {login.ClientID},
+		"code_verifier": {verifier},
+	}
+	if login.ClientSecret != "" {
+		form.Set("client_secret", login.ClientSecret)
+	}
+	token, err := auth.requestToken(ctx, form)
+	if err != nil {
+		return "", err
+	}
+	if token.RefreshToken == "" {
+		return "", fmt.Errorf("the provider issued no refresh token, so the login can't outlive the access token (some providers issue one only with a scope like offline_access)")
+	}
+	auth.RefreshToken = token.RefreshToken
+	if err := auth.saveState(ctx); err != nil {
+		return "", err
+	}
+	if err := recordOAuth2Login(baseURL); err != nil {
+		return "", err
+	}
+	return baseURL, nil
+}
+
+// oauth2LoginsFile returns the file listing the server URLs LoginOAuth2 saved tokens for,
+// so that the keyring is only read for APIs with a saved login.
+// It holds no secrets, which stay in the keyring.
+func oauth2LoginsFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "emcee", "logins"), nil
+}
+
+// oauth2Logins returns the server URLs LoginOAuth2 saved tokens for.
+func oauth2Logins() []string {
+	name, err := oauth2LoginsFile()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(data))
+}
+
+// HasOAuth2Logins reports whether LoginOAuth2 saved tokens for any API.
+func HasOAuth2Logins() bool {
+	return len(oauth2Logins()) > 0
+}
+
+// recordOAuth2Login adds a server URL to the list of those LoginOAuth2 saved tokens for.
+func recordOAuth2Login(baseURL string) error {
+	if slices.Contains(oauth2Logins(), baseURL) {
+		return nil
+	}
+	name, err := oauth2LoginsFile()
+	if err != nil {
+		return fmt.Errorf("error recording OAuth 2.0 login: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+		return fmt.Errorf("error recording OAuth 2.0 login: %w", err)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error recording OAuth 2.0 login: %w", err)
+	}
+	if _, err := fmt.Fprintln(f, baseURL); err != nil {
+		f.Close()
+		return fmt.Errorf("error recording OAuth 2.0 login: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error recording OAuth 2.0 login: %w", err)
+	}
+	return nil
+}
+
+// WithOAuth2Login authenticates requests to APIs with the tokens saved for them by LoginOAuth2,
+// if the spec declares an authorization code flow and a login was saved for its server URL.
+// Saved tokens aren't used if other credentials are given, including an API key or an Authorization header.
+func WithOAuth2Login() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.oauth2Login = true }
+}
+
+// authorizationCodeFlow returns the name of a spec's oauth2 security scheme with an authorization code flow, and the flow,
+// preferring a scheme required by its top-level security requirements.
+func authorizationCodeFlow(doc *v3.Document) (string, *v3.OAuthFlow, bool) {
+	scheme, ok := securityScheme(doc, func(scheme *v3.SecurityScheme) bool {
+		return scheme.Type == "oauth2" && scheme.Flows != nil && scheme.Flows.AuthorizationCode != nil &&
+			scheme.Flows.AuthorizationCode.AuthorizationUrl != "" && scheme.Flows.AuthorizationCode.TokenUrl != ""
+	})
+	if !ok {
+		return "", nil, false
+	}
+	for name, s := range doc.Components.SecuritySchemes.FromOldest() {
+		if s == scheme {
+			return name, scheme.Flows.AuthorizationCode, true
+		}
+	}
+	return "", nil, false
+}
+
+// requiredScopes returns the scopes of a security scheme that the spec's security requirements name,
+// at the top level and for each operation.
+func requiredScopes(doc *v3.Document, name string) []string {
+	var scopes []string
+	add := func(requirements []*base.SecurityRequirement) {
+		for _, requirement := range requirements {
+			if requirement == nil || requirement.Requirements == nil {
+				continue
+			}
+			if required, ok := requirement.Requirements.Get(name); ok {
+				scopes = append(scopes, required...)
+			}
+		}
+	}
+	add(doc.Security)
+	if doc.Paths != nil && doc.Paths.PathItems != nil {
+		for item := range doc.Paths.PathItems.ValuesFromOldest() {
+			ops, _ := pathOperations(item)
+			for _, op := range ops {
+				add(op.op.Security)
+			}
+		}
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
+
+// openBrowser opens a URL in the system's default browser.
+func openBrowser(ctx context.Context, url string) error {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		name = "open"
+	case "windows":
+		name, args = "rundll32", []string{"url.dll,FileProtocolHandler"}
+	default:
+		name = "xdg-open"
+	}
+	if _, err := LookPath(name); err != nil {
+		return errors.New(name + " not found in PATH")
+	}
+	cmd := CommandContext(context.WithoutCancel(ctx), name, append(args, url)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
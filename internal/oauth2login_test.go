@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginOAuth2(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the Keychain and Credential Manager aren't mocked")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Logins are recorded in the user's config directory
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// The keyring is a file, stored to and looked up with secret-tool
+	keyring := filepath.Join(t.TempDir(), "keyring")
+	originalCommand := CommandContext
+	originalLookPath := LookPath
+	t.Cleanup(func() {
+		CommandContext = originalCommand
+		LookPath = originalLookPath
+	})
+	LookPath = func(string) (string, error) { return "/usr/bin/secret-tool", nil }
+	var keyringArgs [][]string
+	CommandContext = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+		keyringArgs = append(keyringArgs, append([]string{name}, arg...))
+		if arg[0] == "store" {
+			return exec.CommandContext(ctx, "sh", "-c", `cat > "$0"`, keyring)
+		}
+		return exec.CommandContext(ctx, "cat", keyring)
+	}
+
+	var challenge, redirectURI string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		switch r.Form.Get("grant_type") {
+		case "authorization_code":
+			verified := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+			if r.Form.Get("code") != "abc" || r.Form.Get("redirect_uri") != redirectURI ||
+				base64.RawURLEncoding.EncodeToString(verified[:]) != challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token": "at-1", "token_type": "Bearer", "expires_in": 3600, "refresh_token": "rt-1"}`))
+		case "refresh_token":
+			if r.Form.Get("refresh_token") != "rt-1" || r.Form.Get("client_id") != "emcee" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error": "invalid_grant"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token": "at-2", "token_type": "Bearer", "expires_in": 3600}`))
+		}
+	})
+	mux.HandleFunc("GET /api/pets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	})
+	api := httptest.NewServer(mux)
+	defer api.Close()
+
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "` + api.URL + `/api"}],
+  "components": {"securitySchemes": {"oauth": {"type": "oauth2", "flows": {"authorizationCode": {
+    "authorizationUrl": "/oauth/authorize", "tokenUrl": "/oauth/token",
+    "scopes": {"pets:read": "Read pets", "pets:write": "Write pets", "admin": "Everything"}
+  }}}}},
+  "security": [{"oauth": ["pets:read"]}],
+  "paths": {"/pets": {
+    "get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}},
+    "post": {"operationId": "createPet", "security": [{"oauth": ["pets:write"]}], "responses": {"201": {"description": "Created"}}}
+  }}
+}`)
+
+	// The keyring isn't read for APIs without a saved login
+	assert.False(t, HasOAuth2Logins())
+	require.NoError(t, RegisterTools(mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil), spec, api.Client(), WithOAuth2Login()))
+	assert.Empty(t, keyringArgs)
+
+	login := OAuth2LoginOptions{
+		ClientID: "emcee",
+		Client:   api.Client(),
+		Open: func(ctx context.Context, authorizationURL string) error {
+			u, err := url.Parse(authorizationURL)
+			require.NoError(t, err)
+			assert.Equal(t, api.URL+"/oauth/authorize", u.Scheme+"://"+u.Host+u.Path)
+			query := u.Query()
+			assert.Equal(t, "code", query.Get("response_type"))
+			assert.Equal(t, "emcee", query.Get("client_id"))
+			assert.Equal(t, "S256", query.Get("code_challenge_method"))
+			assert.Equal(t, "pets:read pets:write", query.Get("scope"), "the scopes the spec requires are requested")
+			challenge, redirectURI = query.Get("code_challenge"), query.Get("redirect_uri")
+
+			// The provider redirects the browser back to the callback
+			resp, err := http.Get(redirectURI + "?code=abc&state=forged")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "callbacks for other authorization requests are refused")
+			resp, err = http.Get(redirectURI + "?code=abc&state=" + url.QueryEscape(query.Get("state")))
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			return nil
+		},
+	}
+	account, err := LoginOAuth2(ctx, spec, login)
+	require.NoError(t, err)
+	assert.Equal(t, api.URL+"/api", account)
+	assert.Equal(t, []string{"secret-tool", "store", "--label=emcee " + account, "service", "emcee", "username", account}, keyringArgs[0])
+	data, err := os.ReadFile(keyring)
+	require.NoError(t, err)
+	var state oauth2State
+	require.NoError(t, json.Unmarshal(data, &state))
+	assert.Equal(t, oauth2State{RefreshToken: "rt-1", ClientID: "emcee", TokenURL: api.URL + "/oauth/token"}, state)
+	assert.True(t, HasOAuth2Logins())
+	assert.Equal(t, []string{account}, oauth2Logins())
+
+	// The server uses the saved login
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, api.Client(), WithOAuth2Login()))
+	clientSession := connectTestClient(t, ctx, server)
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	// Saved logins aren't looked up when other credentials are given
+	keyringArgs = nil
+	server = mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, api.Client(), WithOAuth2Login(), WithHeader("Authorization", "Bearer x")))
+	assert.Empty(t, keyringArgs)
+
+	login.Open = func(ctx context.Context, authorizationURL string) error {
+		u, err := url.Parse(authorizationURL)
+		require.NoError(t, err)
+		query := u.Query()
+		resp, err := http.Get(query.Get("redirect_uri") + "?error=access_denied&state=" + url.QueryEscape(query.Get("state")))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return nil
+	}
+	_, err = LoginOAuth2(ctx, spec, login)
+	assert.ErrorContains(t, err, "OAuth 2.0 authorization failed: access_denied")
+}
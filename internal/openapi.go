@@ -2,16 +2,21 @@ package internal
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"net/http"
-	"net/url"
-	"path"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -27,7 +32,51 @@ import (
 type RegisterToolsOption func(*registerToolsConfig)
 
 type registerToolsConfig struct {
-	enableAnnotations bool
+	enableAnnotations   bool
+	enableOutputSchemas bool
+	toolPrefix          string
+	toolNameFormat      ToolNameFormat
+	coerceArguments     bool
+	canary              *CanaryOptions
+	serverVars          map[string]string
+	queryObjectStyle    QueryObjectStyle
+	trailingSlashes     bool
+	rawPaths            bool
+	schemaResources     bool
+	notFoundCache       *NotFoundCacheOptions
+	secretArguments     secretArguments
+	secretValues        *secretValues
+	prompts             bool
+	resourceTemplates   bool
+	config              *Config
+	headers             http.Header
+	auth                AuthProvider
+	apiKey              string
+	oauth2              *OAuth2Auth
+	oauth2Login         bool
+	operationAuths      operationAuths
+	responseTransform   ResponseTransform
+	enumCatalog         *EnumCatalog
+	elicitor            *Elicitor
+	dryRun              bool
+	dryRunArgument      bool
+	maxTimeout          time.Duration
+	webhooks            *WebhookReceiver
+	subscriptions       *Subscriptions
+	confirmation        bool
+	richDescriptions    bool
+	refs                specReferences
+	includeDeprecated   bool
+	includeInternal     bool
+	responseLimit       *ResponseLimitOptions
+	xmlToJSON           bool
+	downloadThreshold   int
+	auditLog            *AuditLog
+	healthChecker       *HealthChecker
+	requestHooks        []RequestHook
+	responseHooks       []ResponseHook
+	requestIDHeader     string
+	logger              *slog.Logger
 }
 
 // WithoutAnnotations disables attaching REST-aware MCP ToolAnnotations for generated tools.
@@ -35,73 +84,302 @@ func WithoutAnnotations() RegisterToolsOption {
 	return func(cfg *registerToolsConfig) { cfg.enableAnnotations = false }
 }
 
+// WithLogger sets the logger used for diagnostics. By default, nothing is logged.
+func WithLogger(logger *slog.Logger) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.logger = logger }
+}
+
+// WithoutOutputSchemas disables generating MCP output schemas and structured content from response schemas.
+func WithoutOutputSchemas() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.enableOutputSchemas = false }
+}
+
+// WithServerVariables sets values for variables in the spec's server URL, overriding their defaults.
+func WithServerVariables(vars map[string]string) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.serverVars = vars }
+}
+
+// WithQueryObjectStyle sets how object-valued query parameters are serialized.
+func WithQueryObjectStyle(style QueryObjectStyle) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.queryObjectStyle = style }
+}
+
+// WithSchemaResources exposes each tool's input and output schemas
+// as an MCP resource at emcee://tools/{name}/schema.
+func WithSchemaResources() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.schemaResources = true }
+}
+
+// WithToolPrefix prepends prefix to every generated tool name.
+// This avoids collisions when a client connects to several servers that share operationIds.
+func WithToolPrefix(prefix string) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.toolPrefix = prefix }
+}
+
+// WithArgumentCoercion enables normalization of humanized argument values
+// (e.g. "1,5" or "March 3rd 2025") into the numeric and date formats declared by each tool's input schema.
+func WithArgumentCoercion() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.coerceArguments = true }
+}
+
 // RegisterTools parses the given OpenAPI specification and registers tools on the provided MCP server.
 // All HTTP calls are executed using the provided http.Client. If the client is nil, http.DefaultClient is used.
-// By default, REST-aware MCP ToolAnnotations are attached to each tool. Pass options to change behavior.
+// By default, REST-aware MCP ToolAnnotations are attached to each tool,
+// and output schemas are derived from JSON response schemas. Pass options to change behavior.
 func RegisterTools(server *mcp.Server, specData []byte, client *http.Client, opts ...RegisterToolsOption) error {
+	reg, err := registerTools(server, specData, client, opts...)
+	if err != nil {
+		return err
+	}
+	server.AddReceivingMiddleware(reg.middleware...)
+	return nil
+}
+
+// registration records the features added to a server for a spec,
+// so that they can be replaced when the spec changes.
+type registration struct {
+	tools             []string
+	prompts           []string
+	resources         []string
+	resourceTemplates []string
+	// endpoints are the operations called by the spec's tools, as "METHOD /path", by tool name
+	endpoints map[string]string
+	// middleware is the receiving middleware for the spec's tools, in the order it runs.
+	middleware []mcp.Middleware
+}
+
+// registerTools adds tools, prompts, and resources for a spec to a server,
+// and returns what was added along with the middleware the tools need.
+// The middleware isn't added to the server.
+func registerTools(server *mcp.Server, specData []byte, client *http.Client, opts ...RegisterToolsOption) (*registration, error) {
 	if len(specData) == 0 {
-		return fmt.Errorf("no OpenAPI spec data provided")
+		return nil, fmt.Errorf("no OpenAPI spec data provided")
 	}
 	if server == nil {
-		return fmt.Errorf("server is nil")
+		return nil, fmt.Errorf("server is nil")
 	}
 	if client == nil {
 		client = http.DefaultClient
 	}
 
 	// Defaults
-	cfg := &registerToolsConfig{enableAnnotations: true}
+	cfg := &registerToolsConfig{enableAnnotations: true, enableOutputSchemas: true}
 	for _, opt := range opts {
 		if opt != nil {
 			opt(cfg)
 		}
 	}
+	if cfg.logger == nil {
+		cfg.logger = slog.New(slog.DiscardHandler)
+	}
+	if cfg.secretArguments == nil {
+		cfg.secretArguments = make(secretArguments)
+	}
+	// Prefetched responses only stay around if the client caches them
+	caching := cachesResponses(client)
+	// Tokens for saved logins are requested as they'd be without the API's credentials and headers
+	tokenClient := client
+	// Credentials are recorded as they're sent, for redacting them from errors
+	cfg.secretValues = &secretValues{}
+	client = recordingClient(client, cfg.secretValues)
+	// Dry runs go through the same credentials and static headers as real requests,
+	// so they describe requests as they'd be sent
+	var dryRun dryRunTransport
+	dryRunClient := &http.Client{Transport: &dryRun}
+	// Requests are signed as they're sent, after hooks and credentials change them
+	if cfg.config != nil && cfg.config.Signing != nil {
+		client = signingClient(client, cfg.config.Signing)
+		dryRunClient = signingClient(dryRunClient, cfg.config.Signing)
+		dryRun.secrets = append(dryRun.secrets, http.CanonicalHeaderKey(cfg.config.Signing.header()))
+	}
+	// Hooks run closest to the API, so that they see requests as they're sent.
+	// Dry runs show what request hooks change, but get no response to hook.
+	if len(cfg.requestHooks) > 0 || len(cfg.responseHooks) > 0 {
+		client = hookClient(client, cfg.requestHooks, cfg.responseHooks)
+		dryRunClient = hookClient(dryRunClient, cfg.requestHooks, nil)
+	}
 
-	doc, err := libopenapi.NewDocument(specData)
+	model, baseURL, err := buildModel(specData, cfg.serverVars, cfg.refs)
 	if err != nil {
-		return fmt.Errorf("error parsing OpenAPI spec: %w", err)
+		return nil, err
 	}
-	model, errs := doc.BuildV3Model()
-	if len(errs) > 0 {
-		return fmt.Errorf("error building OpenAPI model: %v", errs[0])
+	// Credentials the config sets for operations are sent in place of those given for every request,
+	// including those given as static headers
+	overridable := func(provider AuthProvider) AuthProvider {
+		if cfg.config != nil && len(cfg.config.Auth) > 0 {
+			return overridableAuth{provider}
+		}
+		return provider
+	}
+	// Credentials are applied before static headers, so they take precedence
+	if len(cfg.headers) > 0 {
+		var credentials []string
+		if cfg.config != nil && len(cfg.config.Auth) > 0 {
+			credentials = credentialHeaders(&model.Model)
+		}
+		client = headerClient(client, cfg.headers, credentials)
+		dryRunClient = headerClient(dryRunClient, cfg.headers, credentials)
+	}
+	if cfg.auth != nil {
+		client = authClient(client, overridable(cfg.auth))
+		dryRunClient = authClient(dryRunClient, overridable(cfg.auth))
+	}
+	if cfg.apiKey != "" {
+		scheme, ok := apiKeyScheme(&model.Model)
+		if !ok {
+			return nil, fmt.Errorf("an API key was provided, but the spec declares no apiKey security scheme")
+		}
+		apiKey := overridable(APIKeyAuth{In: scheme.In, Name: scheme.Name, Value: cfg.apiKey})
+		client = authClient(client, apiKey)
+		dryRunClient = authClient(dryRunClient, apiKey)
+		dryRun.secrets = append(dryRun.secrets, http.CanonicalHeaderKey(scheme.Name))
+	}
+	oauth2 := cfg.oauth2
+	if oauth2 == nil && cfg.oauth2Login && cfg.auth == nil && cfg.apiKey == "" && cfg.headers.Get("Authorization") == "" {
+		oauth2 = savedOAuth2Login(&model.Model, baseURL, tokenClient, cfg.logger)
+	}
+	if oauth2 != nil {
+		if err := oauth2.discover(&model.Model, baseURL); err != nil {
+			return nil, err
+		}
+		client = authClient(client, overridable(oauth2))
+		dryRunClient = authClient(dryRunClient, overridable(oauth2))
+	}
+	var overriddenHeaders []string
+	if cfg.operationAuths, overriddenHeaders, err = cfg.config.operationAuths(&model.Model); err != nil {
+		return nil, err
+	}
+	if len(cfg.operationAuths) > 0 {
+		client = authClient(client, operationAuth{})
+		dryRunClient = authClient(dryRunClient, operationAuth{})
+		dryRun.secrets = append(dryRun.secrets, overriddenHeaders...)
+	}
+	if cfg.healthChecker != nil {
+		target := &healthTarget{spec: cfg.refs.location, openAPI: model.Model.Version, baseURL: baseURL, client: client}
+		if model.Model.Info != nil {
+			target.title, target.version = model.Model.Info.Title, model.Model.Info.Version
+		}
+		cfg.healthChecker.add(target)
 	}
 
-	if len(model.Model.Servers) == 0 || model.Model.Servers[0].URL == "" {
-		return fmt.Errorf("OpenAPI spec must include at least one server URL")
+	var cn *canary
+	if cfg.canary != nil {
+		if cn, err = newCanary(*cfg.canary, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	var notFound *notFoundCache
+	if cfg.notFoundCache != nil {
+		if notFound, err = newNotFoundCache(*cfg.notFoundCache); err != nil {
+			return nil, err
+		}
+	}
+	confirms := newConfirmations()
+	store := newResponseStore(server)
+	var limiter *responseLimiter
+	if cfg.responseLimit != nil {
+		if limiter, err = newResponseLimiter(store, *cfg.responseLimit); err != nil {
+			return nil, err
+		}
+		// Shortened results have no structured content, which tools with output schemas must return
+		cfg.enableOutputSchemas = false
+	}
+	var downloaded *downloads
+	if cfg.downloadThreshold > 0 {
+		downloaded = &downloads{threshold: cfg.downloadThreshold, store: store}
 	}
-	baseURL := strings.TrimSuffix(model.Model.Servers[0].URL, "/")
 
 	// Iterate operations and register tools.
+	reg := &registration{endpoints: make(map[string]string)}
+	// Webhooks are received whether or not the spec has paths, since they're all some specs declare
+	if cfg.webhooks != nil && model.Model.Webhooks != nil {
+		reg.resources = append(reg.resources, cfg.webhooks.addWebhooks(server, model.Model.Webhooks)...)
+	}
 	if model.Model.Paths == nil || model.Model.Paths.PathItems == nil {
-		return nil
+		return reg, nil
+	}
+
+	// Map generated tool names back to their operationIds to detect collisions
+	operationIDs := make(map[string]string)
+	// Input schemas by tool name, used by middleware that inspects arguments
+	inputSchemas := make(map[string]*jsonschema.Schema)
+	// Tracks long-running operations; set after all operation tools are named
+	var async *asyncOperations
+	declaresAsync := false
+	notices := &noticeLog{}
+	prompts := &tagPrompts{}
+	// Lookups set by the config file, resolved to tools once every operation's tool is named
+	type pendingLookup struct {
+		tool, argument string
+		lookup         *Lookup
 	}
+	var lookups []pendingLookup
+	// Links in responses are mapped to resource templates as they're added
+	links := &hypermediaLinks{baseURL: baseURL}
+	// Responses fetched into the cache once every tool is registered
+	var prefetches []*prefetchRequest
+	// x-mcp-priority of tools that set one, by tool name
+	priorities := make(map[string]float64)
+	// Tools by operation ID, for the steps of workflows
+	operationTools := make(map[string]*mcp.Tool)
 
 	for pair := model.Model.Paths.PathItems.First(); pair != nil; pair = pair.Next() {
 		p := pair.Key()
 		item := pair.Value()
-		queryOp, err := queryOperation(item)
+		ops, err := pathOperations(item)
 		if err != nil {
-			return fmt.Errorf("error parsing QUERY operation for %s: %w", p, err)
-		}
-		ops := []struct {
-			method string
-			op     *v3.Operation
-		}{
-			{"GET", item.Get},
-			{"QUERY", queryOp},
-			{"POST", item.Post},
-			{"PUT", item.Put},
-			{"DELETE", item.Delete},
-			{"PATCH", item.Patch},
+			return nil, fmt.Errorf("error parsing QUERY operation for %s: %w", p, err)
 		}
 		for _, op := range ops {
-			if op.op == nil || op.op.OperationId == "" {
+			operationID := op.op.OperationId
+			if operationID == "" {
+				operationID = op.method + " " + p
+			}
+			ext, err := parseToolExtensions(item, op.op)
+			if err != nil {
+				return nil, fmt.Errorf("operation %q: %w", operationID, err)
+			}
+			// Operations without IDs can only be named by formats that don't use them, or by x-mcp-name
+			if op.op.OperationId == "" && ext.name == "" && !cfg.toolNameFormat.namesWithoutOperationID() {
+				continue
+			}
+			if ext.disabled || cfg.config.disables(op.method, p, op.op.OperationId) {
+				cfg.logger.Debug("skipping disabled operation", "operation", operationID)
+				continue
+			}
+			deprecated := isDeprecatedOperation(op.op)
+			if deprecated && !cfg.includeDeprecated {
+				cfg.logger.Debug("skipping deprecated operation", "operation", operationID)
+				continue
+			}
+			if !cfg.includeInternal && isInternalOperation(item, op.op) {
+				cfg.logger.Debug("skipping internal operation", "operation", operationID)
 				continue
 			}
-			toolName := getToolName(op.op.OperationId)
-			desc := op.op.Description
-			if desc == "" {
-				desc = op.op.Summary
+			name := ext.name
+			if name == "" {
+				name = cfg.toolNameFormat.name(op.method, p, op.op)
+			}
+			toolName := getToolName(cfg.toolPrefix, name)
+			if existing, ok := operationIDs[toolName]; ok {
+				return nil, fmt.Errorf("tool name %q for operation %q collides with operation %q", toolName, operationID, existing)
+			}
+			operationIDs[toolName] = operationID
+			if declaresAsyncOperation(op.op) {
+				declaresAsync = true
+			}
+			desc := cmp.Or(ext.description, op.op.Description, op.op.Summary)
+			// The tool says where the API should send the operation's callbacks
+			if cfg.webhooks != nil {
+				uris, note := cfg.webhooks.addCallbacks(server, operationID, op.op)
+				reg.resources = append(reg.resources, uris...)
+				if note != "" {
+					desc = strings.TrimSpace(desc + "\n\n" + note)
+				}
+			}
+			if ext.priority != 0 {
+				priorities[toolName] = ext.priority
 			}
 
 			// Build input schema
@@ -130,230 +408,606 @@ func RegisterTools(server *mcp.Server, specData []byte, client *http.Client, opt
 				}
 			}
 
-			// Request body (application/json)
-			if op.op.RequestBody != nil && op.op.RequestBody.Content != nil {
-				if mediaType, ok := op.op.RequestBody.Content.Get("application/json"); ok && mediaType != nil {
-					if mediaType.Schema != nil && mediaType.Schema.Schema() != nil {
-						if s := mediaType.Schema.Schema(); s.Properties != nil {
-							for prop := s.Properties.First(); prop != nil; prop = prop.Next() {
-								propName := prop.Key()
-								// Skip body properties that collide with parameter names
-								if _, exists := paramNames[propName]; exists {
-									continue
-								}
-								propSchema := prop.Value().Schema()
-								if propSchema == nil {
-									continue
-								}
-								// Skip readOnly properties
-								if propSchema.ReadOnly != nil && *propSchema.ReadOnly {
-									continue
-								}
-								sch := &jsonschema.Schema{Type: typeOfSchema(propSchema)}
-								sch.Description = buildSchemaDescription("", propSchema)
-								schema.Properties[propName] = sch
-							}
-							if s.Required != nil {
-								for _, r := range s.Required {
-									// Skip required fields that collide with parameter names
-									if _, exists := paramNames[r]; exists {
-										continue
-									}
-									// Skip required fields that are readOnly
-									if prop, exists := s.Properties.Get(r); exists && prop != nil && prop.Schema() != nil {
-										if prop.Schema().ReadOnly != nil && *prop.Schema().ReadOnly {
-											continue
-										}
-									}
-									schema.Required = append(schema.Required, r)
-								}
-							}
+			// Request body
+			fixedBody := false
+			if rb := operationRequestBody(op.op); rb != nil {
+				bs := rb.schema
+				if isFlattenableBody(bs) {
+					// Expose top-level body properties as arguments
+					for prop := bs.Properties.First(); prop != nil; prop = prop.Next() {
+						propName := prop.Key()
+						// Skip body properties that collide with parameter names
+						if _, exists := paramNames[propName]; exists {
+							continue
+						}
+						propSchema := prop.Value().Schema()
+						if propSchema == nil {
+							continue
 						}
+						// Skip readOnly properties
+						if propSchema.ReadOnly != nil && *propSchema.ReadOnly {
+							continue
+						}
+						sch := convertSchema(propSchema, requestDirection)
+						sch.Description = buildSchemaDescription("", propSchema)
+						schema.Properties[propName] = sch
+					}
+					for _, r := range bs.Required {
+						// Skip required fields that collide with parameter names or are readOnly
+						if sch, exists := schema.Properties[r]; !exists || sch.ReadOnly {
+							continue
+						}
+						if _, exists := paramNames[r]; exists {
+							continue
+						}
+						schema.Required = append(schema.Required, r)
+					}
+					if additional := additionalBodyProperties(bs); additional != nil {
+						schema.AdditionalProperties = additional
+					}
+				} else if name := bodyArgumentName(paramNames); name != "" {
+					// Pass the whole body as a single argument
+					sch := convertSchema(bs, requestDirection)
+					if sch.Description == "" {
+						sch.Description = op.op.RequestBody.Description
 					}
+					// Alternatives are spelled out, since models often overlook them in schemas
+					if alternatives := describeAlternatives(bs); alternatives != "" {
+						sch.Description = strings.TrimSpace(sch.Description + "\n\n" + alternatives)
+					}
+					// Arguments the config fixes are set in the body by the server too
+					fixedBody = cfg.config.removeFixedBodyProperties(sch)
+					schema.Properties[name] = sch
+					if op.op.RequestBody.Required != nil && *op.op.RequestBody.Required {
+						schema.Required = append(schema.Required, name)
+					}
+				}
+			}
+
+			// Streaming operations take reserved arguments bounding how much of the stream is read
+			streaming := isEventStreamOperation(op.op)
+			if streaming {
+				addStreamArguments(schema)
+			}
+			if cfg.dryRunArgument {
+				addDryRunArgument(schema)
+			}
+			if cfg.maxTimeout > 0 {
+				addTimeoutArgument(schema, cfg.maxTimeout)
+			}
+			// Destructive operations take a reserved argument confirming a previewed call
+			confirming := cfg.requiresConfirmation(op.method, op.op.OperationId)
+			if confirming {
+				addConfirmArgument(schema)
+			}
+
+			if err := sanitizeSchema(schema); err != nil {
+				cfg.logger.Warn("skipping tool with invalid input schema", "tool", toolName, "error", err)
+				continue
+			}
+
+			// Arguments with defaults in the config file are optional, and filled in when a call omits them
+			defaults, err := cfg.config.argumentDefaults(op.op.OperationId, schema)
+			if err != nil {
+				return nil, err
+			}
+			// Arguments the config fixes are set by the server, so the model can't choose them
+			fixed := cfg.config.removeFixedArguments(schema) || fixedBody
+			// Fields the config removes from responses never reach the model
+			fields, err := cfg.config.responseFilter(op.op.OperationId)
+			if err != nil {
+				return nil, err
+			}
+
+			// Reserved pagination arguments stand in for whatever the operation calls its pagination arguments
+			pagination := cfg.config.paginationArguments(schema)
+			fetchesAll := pagination.fetchesAll(cfg.config)
+
+			// Enums too large to list in the input schema are exposed as resources instead
+			if cfg.enumCatalog != nil {
+				uris, err := cfg.enumCatalog.add(server, toolName, schema)
+				if err != nil {
+					return nil, err
+				}
+				reg.resources = append(reg.resources, uris...)
+
+				argumentLookups, err := cfg.config.lookups(op.op.OperationId, schema)
+				if err != nil {
+					return nil, err
+				}
+				for _, argument := range slices.Sorted(maps.Keys(argumentLookups)) {
+					lookups = append(lookups, pendingLookup{tool: toolName, argument: argument, lookup: argumentLookups[argument]})
 				}
 			}
 
+			if cfg.richDescriptions {
+				desc = richDescription(desc, schema, slices.Concat(item.Parameters, op.op.Parameters), op.op)
+			}
+			if deprecated {
+				desc = deprecatedDescription(desc)
+			}
+
 			tool := &mcp.Tool{
 				Name:        toolName,
 				Description: desc,
 				InputSchema: schema,
 			}
+			inputSchemas[toolName] = schema
+			if op.op.OperationId != "" {
+				operationTools[op.op.OperationId] = tool
+			}
+			prompts.add(op.op.Tags, promptOperation{tool: toolName, method: op.method, path: p, summary: op.op.Summary, required: schema.Required})
+			cfg.secretArguments.addSchema(toolName, schema)
+			if cfg.enableOutputSchemas {
+				tool.OutputSchema = outputSchema(op.op)
+			}
 
 			if cfg.enableAnnotations {
-				// Derive MCP ToolAnnotations from REST conventions
-				title := op.op.Summary
-				if title == "" {
-					title = fmt.Sprintf("%s %s", op.method, p)
-				}
-				openWorld := true
-				destructiveTrue := true
-				ann := &mcp.ToolAnnotations{
-					Title:         title,
-					OpenWorldHint: &openWorld,
-				}
-				switch op.method {
-				case "GET", "QUERY":
-					ann.ReadOnlyHint = true
-					ann.IdempotentHint = true
-				case "POST":
-					ann.ReadOnlyHint = false
-					ann.IdempotentHint = false
-					ann.DestructiveHint = &destructiveTrue
-				case "PUT":
-					ann.ReadOnlyHint = false
-					ann.IdempotentHint = true
-					ann.DestructiveHint = &destructiveTrue
-				case "PATCH":
-					ann.ReadOnlyHint = false
-					ann.IdempotentHint = false
-					ann.DestructiveHint = &destructiveTrue
-				case "DELETE":
-					ann.ReadOnlyHint = false
-					ann.IdempotentHint = true
-					ann.DestructiveHint = &destructiveTrue
-				}
-				tool.Annotations = ann
-			}
-
-			// Capture for handler
-			method := op.method
-			operation := op.op
-			pathItem := item
-			pathTemplate := p
-
-			mcp.AddTool(server, tool, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
-				// Build URL
-				base, err := url.Parse(baseURL)
-				if err != nil {
-					return nil, fmt.Errorf("invalid base URL: %w", err)
-				}
-				p := pathTemplate
-				if !strings.HasPrefix(p, "/") {
-					p = "/" + p
-				}
-				p = path.Clean(p)
-				u := &url.URL{Scheme: base.Scheme, Host: base.Host}
-				if base.Path != "" {
-					basePath := path.Clean(base.Path)
-					u.Path = "/" + strings.TrimPrefix(path.Join(basePath, p), "/")
-				} else {
-					u.Path = p
-				}
-				if u.Scheme == "" {
-					u.Scheme = "http"
-				}
-
-				q := url.Values{}
-				headers := make(http.Header)
-				var bodyParams map[string]any
-				// Track parameter names applied to URL/query/headers
-				usedParamNames := make(map[string]struct{})
-
-				// Path item parameters
-				if pathItem.Parameters != nil {
-					for _, param := range pathItem.Parameters {
-						applyParam(param, req.Params.Arguments, u, q, headers)
-						if param != nil {
-							usedParamNames[param.Name] = struct{}{}
-						}
-					}
+				tool.Annotations = toolAnnotations(op.method, p, item, op.op, ext, cfg.config)
+			}
+
+			if cfg.schemaResources {
+				if err := addSchemaResource(server, tool); err != nil {
+					return nil, fmt.Errorf("error adding schema resource for %s: %w", toolName, err)
 				}
-				// Operation parameters
-				if operation.Parameters != nil {
-					for _, param := range operation.Parameters {
-						applyParam(param, req.Params.Arguments, u, q, headers)
-						if param != nil {
-							usedParamNames[param.Name] = struct{}{}
-						}
+				reg.resources = append(reg.resources, schemaResourceURI(toolName))
+			}
+
+			ep := &endpoint{baseURL: baseURL, path: p, method: op.method, pathItem: item, op: op.op, cfg: cfg}
+			pr, err := cfg.config.prefetchRequest(ep, schema, defaults)
+			if err != nil {
+				return nil, err
+			}
+			if pr != nil {
+				prefetches = append(prefetches, pr)
+			}
+			if cfg.resourceTemplates && !fixed {
+				if t, ok := addResourceTemplate(server, client, ep, fields, toolName, desc); ok {
+					reg.resourceTemplates = append(reg.resourceTemplates, t.uriTemplate)
+					if cfg.subscriptions != nil {
+						cfg.subscriptions.addTemplate(server, t)
 					}
+					links.templates = append(links.templates, t)
+				}
+			}
+
+			reg.tools = append(reg.tools, toolName)
+			reg.endpoints[toolName] = op.method + " " + p
+			mcp.AddTool(server, tool, cfg.audited(toolName, cfg.redacted(func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+				args := withDefaults(pagination.arguments(preciseArguments(ctx, req.Params.Arguments)), defaults)
+				// Records logged for the call include the tool, its correlation ID, and the client session
+				requestID := newRequestID()
+				ctx = withLogAttrs(ctx, slog.String("tool", toolName), slog.String("request_id", requestID))
+				if id := sessionID(req); id != "" {
+					ctx = withLogAttrs(ctx, slog.String("session", id))
 				}
+				cfg.logger.DebugContext(ctx, "calling tool", "arguments", cfg.secretArguments.redact(toolName, args))
 
-				// Request body
-				if operation.RequestBody != nil && operation.RequestBody.Content != nil {
-					if mediaType, ok := operation.RequestBody.Content.Get("application/json"); ok && mediaType != nil {
-						if mediaType.Schema != nil && mediaType.Schema.Schema() != nil {
-							if s := mediaType.Schema.Schema(); s.Properties != nil {
-								bodyParams = make(map[string]any)
-								for prop := s.Properties.First(); prop != nil; prop = prop.Next() {
-									name := prop.Key()
-									// Skip colliding names so path/query/header take precedence
-									if _, exists := usedParamNames[name]; exists {
-										continue
-									}
-									propSchema := prop.Value().Schema()
-									// Skip readOnly properties in request body
-									if propSchema != nil && propSchema.ReadOnly != nil && *propSchema.ReadOnly {
-										continue
-									}
-									if v, ok := req.Params.Arguments[name]; ok {
-										bodyParams[name] = v
-									}
-								}
-							}
-						}
+				// A dry run returns the request the call would send, instead of sending it
+				dryRun := cfg.dryRun
+				if cfg.dryRunArgument {
+					var requested bool
+					requested, args = takeDryRun(args)
+					dryRun = dryRun || requested
+				}
+				var confirmToken string
+				if confirming {
+					confirmToken, args = takeConfirmToken(args)
+				}
+				// Calls that fetch every page of results follow pagination once the first page is received
+				var fetchAll bool
+				if fetchesAll {
+					fetchAll, args = takeFetchAll(args)
+				}
+
+				// Slow operations may be given longer than the client's timeout, by the config or the call
+				reqCtx := ctx
+				timeout := cfg.config.operationTimeout(ep.op.OperationId)
+				if cfg.maxTimeout > 0 {
+					var requested time.Duration
+					if requested, args = takeTimeout(args, cfg.maxTimeout); requested > 0 {
+						timeout = requested
 					}
 				}
+				if timeout > 0 {
+					var cancel context.CancelFunc
+					reqCtx, cancel = context.WithTimeout(contextWithCallTimeout(ctx, timeout), timeout)
+					defer cancel()
+				}
 
-				if len(q) > 0 {
-					u.RawQuery = q.Encode()
+				// The request context of a streaming call ends when the stream has been read for long enough
+				var stream *streamLimits
+				if streaming {
+					limits, rest := takeStreamLimits(args)
+					stream, args = &limits, rest
+					var cancel context.CancelFunc
+					reqCtx, cancel = context.WithTimeout(reqCtx, limits.maxDuration)
+					defer cancel()
 				}
 
-				var reqBody io.Reader
-				if len(bodyParams) > 0 {
-					b, err := json.Marshal(bodyParams)
-					if err != nil {
-						return nil, fmt.Errorf("marshal body: %w", err)
+				// Calls wait for their operation's rate limit, if it has one
+				if ep.op.OperationId != "" {
+					reqCtx = contextWithOperation(reqCtx, ep.op.OperationId)
+				}
+
+				var notFoundKey string
+				if notFound != nil && !dryRun && notFound.applies(toolName, ep.method) {
+					if key, ok := notFound.key(toolName, args); ok {
+						if result, ok := notFound.get(key); ok {
+							cfg.logger.Debug("using cached not found response", "tool", toolName)
+							return reusedResult(result), nil
+						}
+						notFoundKey = key
+					}
+				}
+
+				target := ep
+				var shadow <-chan canaryResponse
+				if cn != nil {
+					if alt, ok := cn.route(toolName, ep.op.OperationId); ok {
+						cfg.logger.Debug("routing call to canary", "tool", toolName)
+						target = alt
+						if isReadOnlyMethod(ep.method) && stream == nil && !dryRun {
+							shadow = cn.shadow(ctx, client, ep, args)
+						}
 					}
-					reqBody = bytes.NewReader(b)
 				}
 
-				hreq, err := http.NewRequest(method, u.String(), reqBody)
+				hreq, err := target.newRequest(reqCtx, args)
 				if err != nil {
 					return nil, err
 				}
-				for k, vs := range headers {
-					for _, v := range vs {
-						hreq.Header.Add(k, v)
-					}
+				if stream != nil && hreq.Header.Get("Accept") == "" {
+					hreq.Header.Set("Accept", eventStreamMediaType)
 				}
-				if reqBody != nil {
-					hreq.Header.Set("Content-Type", "application/json")
+				cfg.setRequestID(hreq, requestID)
+				audit := auditRecordFrom(ctx)
+				if audit != nil {
+					audit.RequestID = requestID
+				}
+				if dryRun {
+					audit.previewed(hreq)
+					return dryRunResult(dryRunClient, hreq)
+				}
+				// A call awaiting confirmation returns a preview of its request, and a token for confirming it
+				if confirming {
+					if confirmToken == "" {
+						token, err := confirms.issue(sessionID(req), toolName, args)
+						if err != nil {
+							return nil, err
+						}
+						audit.previewed(hreq)
+						preview, err := dryRunResult(dryRunClient, hreq)
+						if err != nil {
+							return nil, err
+						}
+						return confirmationResult(preview, toolName, token), nil
+					}
+					if !confirms.confirm(confirmToken, sessionID(req), toolName, args) {
+						return nil, fmt.Errorf("invalid or expired confirmation token for these arguments; call %s without %s for a new one", toolName, confirmArgument)
+					}
 				}
 
+				progress := newProgressReporter(ctx, req)
+				stopWaiting := progress.waiting()
+				audit.sent(hreq)
 				resp, err := client.Do(hreq)
+				stopWaiting()
 				if err != nil {
 					return nil, err
 				}
+				audit.received(resp)
+				var pagesNote string
+				if fetchAll && stream == nil && resp.StatusCode < 300 {
+					follower := &pageFollower{pagination: cfg.config.Pagination, page: pagination[pageArgument], send: func(ctx context.Context, args map[string]any) (*http.Response, error) {
+						hreq, err := target.newRequest(ctx, args)
+						if err != nil {
+							return nil, err
+						}
+						cfg.setRequestID(hreq, requestID)
+						return client.Do(hreq)
+					}}
+					if resp, pagesNote, err = follower.follow(reqCtx, resp, args); err != nil {
+						return nil, err
+					}
+				}
 				defer resp.Body.Close()
-				body, err := io.ReadAll(resp.Body)
-				if err != nil {
+				origin := newProvenance(resp, time.Now())
+				var result *mcp.CallToolResultFor[any]
+				var body []byte
+				if stream != nil && resp.StatusCode < 400 && baseMediaType(resp.Header.Get("Content-Type")) == eventStreamMediaType {
+					events, stopped := readEvents(reqCtx, progress.body(resp), *stream)
+					if err := fields.applyEvents(events); err != nil {
+						return nil, err
+					}
+					result = eventStreamResult(events, stopped)
+				} else if body, err = io.ReadAll(progress.body(resp)); err != nil {
+					return nil, err
+				}
+				progress.done()
+				if shadow != nil {
+					cn.compare(toolName, <-shadow, resp.StatusCode, body)
+				}
+				if body, err = fields.filter(resp.Header.Get("Content-Type"), body, cfg.xmlToJSON); err != nil {
 					return nil, err
 				}
-				if resp.StatusCode >= 400 {
-					return &mcp.CallToolResultFor[any]{
-						Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Request failed with status %d: %s", resp.StatusCode, string(body))}},
-						IsError: true,
-					}, nil
-				}
-				ct := resp.Header.Get("Content-Type")
-				var content mcp.Content
-				switch {
-				case strings.HasPrefix(ct, "image/"):
-					content = &mcp.ImageContent{Data: body, MIMEType: ct}
-				case strings.Contains(ct, "application/json"):
-					var pretty bytes.Buffer
-					if json.Indent(&pretty, body, "", "  ") == nil {
-						body = pretty.Bytes()
+				if result == nil {
+					result, _ = downloaded.result(resp, body, toolName)
+				}
+				if result == nil {
+					result = toolResult(resp, body, cfg)
+				}
+				result = limiter.limit(result, toolName, resp.Header.Get("Content-Type"))
+				origin.annotate(result, resp)
+				notices.add(result, resp, toolName, cfg.logger)
+				addBulkSummary(result, resp, body)
+				links.add(result, resp, body)
+				if notFoundKey != "" && resp.StatusCode == http.StatusNotFound {
+					notFound.put(notFoundKey, result)
+				}
+				if pagesNote != "" {
+					result.Content = append(result.Content, &mcp.TextContent{Text: pagesNote})
+				}
+				if async != nil && !result.IsError {
+					if note, ok := async.track(sessionID(req), hreq, resp, fields); ok {
+						result.Content = append(result.Content, &mcp.TextContent{Text: note})
 					}
-					content = &mcp.TextContent{Text: string(body)}
-				default:
-					content = &mcp.TextContent{Text: string(body)}
 				}
-				return &mcp.CallToolResultFor[any]{Content: []mcp.Content{content}}, nil
-			})
+				return result, nil
+			})))
 		}
 	}
-	return nil
+
+	// The status tool is registered up front when the spec declares long-running operations,
+	// and otherwise on first use. It's skipped if an operation already uses its name.
+	asyncToolName := getToolName(cfg.toolPrefix, asyncOperationToolName)
+	if _, exists := operationIDs[asyncToolName]; !exists {
+		async = newAsyncOperations(server, client, cfg, asyncToolName)
+		reg.tools = append(reg.tools, asyncToolName)
+		if declaresAsync {
+			async.register()
+		}
+	}
+
+	for _, l := range lookups {
+		lookupTool := ""
+		for name, id := range operationIDs {
+			if id == l.lookup.Operation {
+				lookupTool = name
+				break
+			}
+		}
+		if lookupTool == "" {
+			return nil, fmt.Errorf("lookup for %s argument %q: unknown operation %q", l.tool, l.argument, l.lookup.Operation)
+		}
+		if err := cfg.enumCatalog.addLookup(l.tool, l.argument, l.lookup, lookupTool, inputSchemas[lookupTool]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Workflows in the config file call the tools of several operations in turn
+	if cfg.config != nil && len(cfg.config.Workflows) > 0 {
+		runner := &workflowRunner{server: server}
+		for _, name := range slices.Sorted(maps.Keys(cfg.config.Workflows)) {
+			tool, workflow, err := newWorkflowTool(getToolName(cfg.toolPrefix, name), cfg.config.Workflows[name], operationTools, runner)
+			if err != nil {
+				return nil, err
+			}
+			if slices.Contains(reg.tools, tool.Name) {
+				return nil, fmt.Errorf("workflow %q collides with tool %q", name, tool.Name)
+			}
+			if !cfg.enableAnnotations {
+				tool.Annotations = nil
+			}
+			inputSchemas[tool.Name] = tool.InputSchema
+			cfg.secretArguments.addSchema(tool.Name, tool.InputSchema)
+			reg.tools = append(reg.tools, tool.Name)
+			mcp.AddTool(server, tool, cfg.audited(tool.Name, cfg.redacted(workflow.call)))
+		}
+	}
+
+	if cfg.prompts {
+		var title string
+		if model.Model.Info != nil {
+			title = model.Model.Info.Title
+		}
+		reg.prompts = prompts.register(server, title, model.Model.Tags, cfg.toolPrefix)
+	}
+
+	// Results are transformed last, after every other middleware has seen them
+	if cfg.responseTransform != nil {
+		reg.middleware = append(reg.middleware, responseTransformMiddleware(cfg.responseTransform, reg.tools))
+	}
+
+	if len(priorities) > 0 {
+		reg.middleware = append(reg.middleware, toolPriorityMiddleware(priorities))
+	}
+
+	// Missing arguments are asked for, then arguments are coerced, then validated, then recorded as sent
+	validate, err := validationMiddleware(inputSchemas)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.elicitor != nil {
+		reg.middleware = append(reg.middleware, cfg.elicitor.middleware(inputSchemas, cfg.logger))
+	}
+	if cfg.coerceArguments {
+		reg.middleware = append(reg.middleware, coercionMiddleware(inputSchemas))
+	}
+	reg.middleware = append(reg.middleware, validate, rawArgumentsMiddleware())
+
+	if len(prefetches) > 0 {
+		if caching {
+			go prefetch(context.Background(), client, prefetches, cfg.logger)
+		} else {
+			cfg.logger.Warn("not prefetching responses, since they aren't cached")
+		}
+	}
+	return reg, nil
+}
+
+// isReadOnlyMethod reports whether an HTTP method is safe, per RFC 9110.
+func isReadOnlyMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "QUERY":
+		return true
+	}
+	return false
+}
+
+// buildModel parses an OpenAPI specification and returns its model along with the base URL of its first server.
+// Server variables are resolved using serverVars, falling back to their defaults,
+// and references to other documents as refs allows.
+func buildModel(specData []byte, serverVars map[string]string, refs specReferences) (*libopenapi.DocumentModel[v3.Document], string, error) {
+	docConfig, err := refs.documentConfiguration()
+	if err != nil {
+		return nil, "", err
+	}
+	doc, err := libopenapi.NewDocumentWithConfiguration(specData, docConfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("error parsing OpenAPI spec: %w", err)
+	}
+	model, errs := doc.BuildV3Model()
+	if len(errs) > 0 {
+		return nil, "", fmt.Errorf("error building OpenAPI model: %v", errs[0])
+	}
+
+	if len(model.Model.Servers) == 0 || model.Model.Servers[0].URL == "" {
+		return nil, "", fmt.Errorf("OpenAPI spec must include at least one server URL")
+	}
+	baseURL, err := resolveServerURL(model.Model.Servers[0], serverVars)
+	if err != nil {
+		return nil, "", err
+	}
+	return model, strings.TrimSuffix(baseURL, "/"), nil
+}
+
+var serverVariablePattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// resolveServerURL substitutes server variables in a server URL,
+// using the provided values if present and the variables' defaults otherwise.
+func resolveServerURL(server *v3.Server, values map[string]string) (string, error) {
+	var err error
+	resolved := serverVariablePattern.ReplaceAllStringFunc(server.URL, func(match string) string {
+		name := match[1 : len(match)-1]
+		var variable *v3.ServerVariable
+		if server.Variables != nil {
+			variable, _ = server.Variables.Get(name)
+		}
+		value, ok := values[name]
+		if !ok {
+			if variable == nil || variable.Default == "" {
+				if err == nil {
+					err = fmt.Errorf("no value for server variable %q", name)
+				}
+				return match
+			}
+			value = variable.Default
+		}
+		if variable != nil && len(variable.Enum) > 0 && !slices.Contains(variable.Enum, value) && err == nil {
+			err = fmt.Errorf("invalid value %q for server variable %q (allowed values: %s)", value, name, strings.Join(variable.Enum, ", "))
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// pathOperation is an operation of a path item along with its HTTP method.
+type pathOperation struct {
+	method string
+	op     *v3.Operation
+}
+
+// pathOperations returns the operations defined on a path item, in a stable order.
+func pathOperations(item *v3.PathItem) ([]pathOperation, error) {
+	queryOp, err := queryOperation(item)
+	if err != nil {
+		return nil, err
+	}
+	candidates := []pathOperation{
+		{"GET", item.Get},
+		{"QUERY", queryOp},
+		{"POST", item.Post},
+		{"PUT", item.Put},
+		{"DELETE", item.Delete},
+		{"PATCH", item.Patch},
+	}
+	var ops []pathOperation
+	for _, op := range candidates {
+		if op.op != nil {
+			ops = append(ops, op)
+		}
+	}
+	return ops, nil
+}
+
+// toolResult converts an upstream HTTP response and its body into an MCP tool result.
+func toolResult(resp *http.Response, body []byte, cfg *registerToolsConfig) *mcp.CallToolResultFor[any] {
+	if resp.StatusCode >= 400 {
+		return httpErrorResult(resp, body)
+	}
+	ct := resp.Header.Get("Content-Type")
+	var content mcp.Content
+	var structured any
+	switch {
+	case strings.HasPrefix(ct, "image/"):
+		content = &mcp.ImageContent{Data: body, MIMEType: ct}
+	case cfg.xmlToJSON && isXMLMediaType(ct):
+		// XML that can't be parsed is returned as is
+		if converted, err := xmlToJSON(body); err == nil {
+			body = converted
+		}
+		content = &mcp.TextContent{Text: string(body)}
+	case isJSONMediaType(ct):
+		if cfg.enableOutputSchemas {
+			structured = structuredContent(body)
+		}
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			body = pretty.Bytes()
+		}
+		content = &mcp.TextContent{Text: string(body)}
+	case strings.HasPrefix(ct, "audio/"):
+		content = &mcp.AudioContent{Data: body, MIMEType: ct}
+	case !isTextMediaType(baseMediaType(ct)) || (ct == "" && !utf8.Valid(body)):
+		// Binary responses are returned as blobs, instead of as text that would garble them
+		content = blobContent(resp, body)
+	default:
+		content = &mcp.TextContent{Text: string(body)}
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{content}, StructuredContent: structured}
+}
+
+// blobContent returns a binary response as an embedded resource,
+// identified by the URL it was requested from, without its query, which may hold credentials.
+func blobContent(resp *http.Response, body []byte) *mcp.EmbeddedResource {
+	mimeType := baseMediaType(resp.Header.Get("Content-Type"))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	var uri string
+	if resp.Request != nil && resp.Request.URL != nil {
+		u := *resp.Request.URL
+		u.RawQuery, u.Fragment, u.User = "", "", nil
+		uri = u.String()
+	}
+	return &mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: uri, MIMEType: mimeType, Blob: body}}
+}
+
+// structuredContent decodes a JSON response body for use as MCP structured content.
+// Only JSON objects are returned; other values yield nil.
+// Numbers are preserved exactly rather than being converted to float64.
+func structuredContent(body []byte) any {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v map[string]any
+	if err := dec.Decode(&v); err != nil || v == nil {
+		return nil
+	}
+	return v
 }
 
 func queryOperation(item *v3.PathItem) (*v3.Operation, error) {
@@ -402,16 +1056,20 @@ func operationFromNode(item *v3.PathItem, node *yaml.Node) (*v3.Operation, error
 	return v3.NewOperation(op), nil
 }
 
+// addParamToSchema adds a parameter to a tool's input schema as a property, converting its schema in full,
+// so that its enum, bounds, and item constraints are listed for clients and checked by argument validation.
 func addParamToSchema(schema *jsonschema.Schema, param *v3.Parameter) {
-	if param == nil || param.Schema == nil {
+	if param == nil {
 		return
 	}
-	ps := &jsonschema.Schema{Type: typeOfSchema(param.Schema.Schema())}
-	if s := param.Schema.Schema(); s != nil {
-		ps.Description = buildSchemaDescription(param.Description, s)
-		if s.Pattern != "" {
-			ps.Pattern = s.Pattern
+	s := parameterSchema(param)
+	ps := &jsonschema.Schema{Type: typeOfSchema(s), Description: param.Description}
+	if s != nil {
+		ps = convertSchema(s, requestDirection)
+		if ps.Type == "" && len(ps.Types) == 0 {
+			ps.Type = typeOfSchema(s)
 		}
+		ps.Description = buildSchemaDescription(param.Description, s)
 	}
 	schema.Properties[param.Name] = ps
 	if param.Required != nil && *param.Required {
@@ -419,6 +1077,23 @@ func addParamToSchema(schema *jsonschema.Schema, param *v3.Parameter) {
 	}
 }
 
+// parameterSchema returns the schema of a parameter's value, from its schema or,
+// for parameters described by content, the schema of its first media type.
+// It returns nil for parameters without either, whose values are strings.
+func parameterSchema(param *v3.Parameter) *base.Schema {
+	if param.Schema != nil {
+		return param.Schema.Schema()
+	}
+	if param.Content != nil {
+		for pair := param.Content.First(); pair != nil; pair = pair.Next() {
+			if mt := pair.Value(); mt != nil && mt.Schema != nil {
+				return mt.Schema.Schema()
+			}
+		}
+	}
+	return nil
+}
+
 func typeOfSchema(s *base.Schema) string {
 	if s == nil || len(s.Type) == 0 {
 		return "string"
@@ -438,6 +1113,11 @@ func buildSchemaDescription(paramDesc string, paramSchema *base.Schema) string {
 	var enumValues []string
 	if len(paramSchema.Enum) > 0 {
 		enumValues = getEnumValues(paramSchema.Enum)
+	} else if paramSchema.Items != nil && paramSchema.Items.IsA() {
+		// Arrays of enums list the values their items allow
+		if items := paramSchema.Items.A.Schema(); items != nil {
+			enumValues = getEnumValues(items.Enum)
+		}
 	}
 	if len(enumValues) > 0 {
 		if description != "" {
@@ -460,84 +1140,24 @@ func getEnumValues(enum []*yaml.Node) []string {
 	return values
 }
 
-func getToolName(operationId string) string {
-	if len(operationId) <= 64 {
-		return operationId
+func getToolName(prefix, operationId string) string {
+	name := prefix + operationId
+	if len(name) <= 64 {
+		return name
 	}
-	hash := sha256.Sum256([]byte(operationId))
+	hash := sha256.Sum256([]byte(name))
 	shortHash := base64.RawURLEncoding.EncodeToString(hash[:])[:8]
-	return operationId[:55] + "_" + shortHash
-}
-
-func applyParam(param *v3.Parameter, args map[string]any, u *url.URL, q url.Values, headers http.Header) {
-	if param == nil {
-		return
-	}
-	value, ok := args[param.Name]
-	if !ok {
-		return
-	}
-	switch param.In {
-	case "path":
-		val := fmt.Sprint(value)
-		u.Path = strings.ReplaceAll(u.Path, "{"+param.Name+"}", pathSegmentEscape(val))
-	case "query":
-		switch v := value.(type) {
-		case []any:
-			strs := make([]string, len(v))
-			for i, it := range v {
-				strs[i] = fmt.Sprint(it)
-			}
-			q.Set(param.Name, strings.Join(strs, ","))
-		default:
-			q.Set(param.Name, fmt.Sprint(value))
-		}
-	case "header":
-		headers.Add(param.Name, fmt.Sprint(value))
-	}
+	return truncateUTF8(name, 55) + "_" + shortHash
 }
 
-// pathSegmentEscape preserves valid URL segment characters per RFC 3986.
-func pathSegmentEscape(s string) string {
-	hexCount := 0
-	for i := 0; i < len(s); i++ {
-		if shouldEscape(s[i]) {
-			hexCount++
-		}
-	}
-	if hexCount == 0 {
+// truncateUTF8 returns s cut to at most n bytes, at the start of a character,
+// so that multi-byte characters aren't split.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
 		return s
 	}
-	var buf [3]byte
-	t := make([]byte, len(s)+2*hexCount)
-	j := 0
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if shouldEscape(c) {
-			buf[0] = '%'
-			buf[1] = "0123456789ABCDEF"[c>>4]
-			buf[2] = "0123456789ABCDEF"[c&15]
-			t[j] = buf[0]
-			t[j+1] = buf[1]
-			t[j+2] = buf[2]
-			j += 3
-		} else {
-			t[j] = c
-			j++
-		}
-	}
-	return string(t)
-}
-
-func shouldEscape(c byte) bool {
-	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
-		return false
-	}
-	switch c {
-	case '-', '.', '_', '~':
-		return false
-	case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '@':
-		return false
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
 	}
-	return true
+	return s[:n]
 }
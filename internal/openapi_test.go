@@ -7,14 +7,33 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// connectTestClient connects an in-memory client session to server and closes both when the test ends.
+func connectTestClient(t *testing.T, ctx context.Context, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "dev"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { clientSession.Close() })
+
+	return clientSession
+}
+
 func TestRegisterToolsSupportsNativeQueryOperation(t *testing.T) {
 	testRegisterToolsSupportsQuery(t, "3.2.0", "query")
 }
@@ -115,3 +134,209 @@ func testRegisterToolsSupportsQuery(t *testing.T, openAPIVersion, operationKey s
 	assert.Equal(t, "QUERY", obs.method)
 	assert.Equal(t, map[string]any{"q": "emcee"}, obs.body)
 }
+
+func TestRegisterToolsWithToolPrefix(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Prefix API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/items": {
+      "get": {"operationId": "list", "responses": {"200": {"description": "OK"}}}
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithToolPrefix("myapi_")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	assert.Equal(t, "myapi_list", tools.Tools[0].Name)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "myapi_list", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 1)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "/items")
+}
+
+func TestRegisterToolsParameterSchemas(t *testing.T) {
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "parameters": [
+          {"name": "status", "in": "query", "description": "Status of the pets", "schema": {"type": "string", "enum": ["available", "sold"]}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 100, "default": 20}},
+          {"name": "tags", "in": "query", "schema": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tools, err := connectTestClient(t, ctx, server).ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+
+	// Parameters keep the constraints of their schemas, so that clients see them and calls are validated against them
+	props := tools.Tools[0].InputSchema.Properties
+	assert.Equal(t, []any{"available", "sold"}, props["status"].Enum)
+	assert.Contains(t, props["status"].Description, "Status of the pets")
+	assert.Equal(t, "integer", props["limit"].Type)
+	assert.Equal(t, 1.0, *props["limit"].Minimum)
+	assert.Equal(t, 100.0, *props["limit"].Maximum)
+	assert.JSONEq(t, "20", string(props["limit"].Default))
+	require.NotNil(t, props["tags"].Items)
+	assert.Equal(t, "^[a-z]+$", props["tags"].Items.Pattern)
+}
+
+func TestGetToolName(t *testing.T) {
+	assert.Equal(t, "listPets", getToolName("", "listPets"))
+	assert.Equal(t, "api_listPets", getToolName("api_", "listPets"))
+
+	long := getToolName("prefix_", strings.Repeat("a", 60))
+	assert.Len(t, long, 64)
+	assert.True(t, strings.HasPrefix(long, "prefix_"))
+
+	unicode := getToolName("", strings.Repeat("猫", 30))
+	assert.True(t, utf8.ValidString(unicode), "multi-byte characters aren't split")
+	assert.LessOrEqual(t, len(unicode), 64)
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	assert.Equal(t, "short", truncateUTF8("short", 10))
+	assert.Equal(t, "abc", truncateUTF8("abcdef", 3))
+	assert.Equal(t, "a", truncateUTF8("a猫", 3), "multi-byte characters aren't split")
+	assert.Equal(t, "a猫", truncateUTF8("a猫", 4))
+}
+
+func TestResolveServerURL(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Regional API", "version": "1.0.0"},
+  "servers": [{
+    "url": "https://{region}.api.example.com/{version}",
+    "variables": {
+      "region": {"default": "us", "enum": ["us", "eu"]},
+      "version": {"default": "v1"}
+    }
+  }],
+  "paths": {}
+}`
+
+	_, baseURL, err := buildModel([]byte(spec), nil, specReferences{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://us.api.example.com/v1", baseURL)
+
+	_, baseURL, err = buildModel([]byte(spec), map[string]string{"region": "eu", "version": "v2"}, specReferences{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://eu.api.example.com/v2", baseURL)
+
+	_, _, err = buildModel([]byte(spec), map[string]string{"region": "ap"}, specReferences{})
+	assert.ErrorContains(t, err, `invalid value "ap" for server variable "region"`)
+
+	undeclared := strings.Replace(spec, `"https://{region}`, `"https://{tenant}.{region}`, 1)
+	_, _, err = buildModel([]byte(undeclared), nil, specReferences{})
+	assert.ErrorContains(t, err, `no value for server variable "tenant"`)
+
+	_, baseURL, err = buildModel([]byte(undeclared), map[string]string{"tenant": "acme"}, specReferences{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://acme.us.api.example.com/v1", baseURL)
+}
+
+func TestRegisterToolsReturnsBinaryContent(t *testing.T) {
+	pdf := []byte("%PDF-1.7\x00\xff\xfe")
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/audio":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			_, _ = w.Write([]byte{0xff, 0xfb, 0x90})
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/document":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write(pdf)
+		case "/untyped":
+			w.Header()["Content-Type"] = nil
+			_, _ = w.Write([]byte{0x00, 0xff})
+		default:
+			w.Header().Set("Content-Type", "application/yaml")
+			_, _ = w.Write([]byte("name: Fido\n"))
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Media API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/audio": {"get": {"operationId": "getAudio", "responses": {"200": {"description": "OK"}}}},
+    "/image": {"get": {"operationId": "getImage", "responses": {"200": {"description": "OK"}}}},
+    "/document": {"get": {"operationId": "getDocument", "responses": {"200": {"description": "OK"}}}},
+    "/untyped": {"get": {"operationId": "getUntyped", "responses": {"200": {"description": "OK"}}}},
+    "/yaml": {"get": {"operationId": "getYAML", "responses": {"200": {"description": "OK"}}}}
+  }
+}`, api.URL)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	call := func(name string) mcp.Content {
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: map[string]any{}})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		return result.Content[0]
+	}
+
+	audio, ok := call("getAudio").(*mcp.AudioContent)
+	require.True(t, ok)
+	assert.Equal(t, "audio/mpeg", audio.MIMEType)
+	assert.Equal(t, []byte{0xff, 0xfb, 0x90}, audio.Data)
+
+	image, ok := call("getImage").(*mcp.ImageContent)
+	require.True(t, ok)
+	assert.Equal(t, "image/png", image.MIMEType)
+
+	document, ok := call("getDocument").(*mcp.EmbeddedResource)
+	require.True(t, ok)
+	assert.Equal(t, api.URL+"/document", document.Resource.URI)
+	assert.Equal(t, "application/pdf", document.Resource.MIMEType)
+	assert.Equal(t, pdf, document.Resource.Blob)
+
+	untyped, ok := call("getUntyped").(*mcp.EmbeddedResource)
+	require.True(t, ok, "responses without a media type that aren't UTF-8 are binary")
+	assert.Equal(t, "application/octet-stream", untyped.Resource.MIMEType)
+
+	text, ok := call("getYAML").(*mcp.TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "name: Fido\n", text.Text)
+}
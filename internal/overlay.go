@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"github.com/speakeasy-api/jsonpath/pkg/overlay"
+	"gopkg.in/yaml.v3"
+)
+
+// Overlay is an OpenAPI Overlay document (https://spec.openapis.org/overlay/v1.0.0.html),
+// whose actions patch a spec before tools are generated for it:
+// updating descriptions, removing paths, or renaming operations in specs that can't be edited.
+type Overlay struct {
+	name    string
+	overlay *overlay.Overlay
+}
+
+// LoadOverlay reads an overlay file, in YAML or JSON, and checks that it's valid,
+// including the JSONPath expressions its actions target.
+func LoadOverlay(name string) (*Overlay, error) {
+	o, err := overlay.Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading overlay %s: %w", name, err)
+	}
+	if err := o.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid overlay %s: %w", name, err)
+	}
+	for i, action := range o.Actions {
+		if _, err := jsonpath.NewPath(action.Target, config.WithPropertyNameExtension()); err != nil {
+			return nil, fmt.Errorf("invalid overlay %s: action %d target %q: %w", name, i, action.Target, err)
+		}
+	}
+	return &Overlay{name: name, overlay: o}, nil
+}
+
+// ApplyOverlays applies overlays to a spec, in order, and returns the patched spec as YAML.
+// Actions whose targets match nothing in the spec have no effect.
+func ApplyOverlays(specData []byte, overlays ...*Overlay) ([]byte, error) {
+	if len(overlays) == 0 {
+		return specData, nil
+	}
+	var root yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(specData)).Decode(&root); errors.Is(err, io.EOF) {
+		return specData, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI spec: %w", err)
+	}
+	for _, o := range overlays {
+		if err := o.overlay.ApplyTo(&root); err != nil {
+			return nil, fmt.Errorf("error applying overlay %s: %w", o.name, err)
+		}
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, fmt.Errorf("error applying overlays: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("error applying overlays: %w", err)
+	}
+	return buf.Bytes(), nil
+}
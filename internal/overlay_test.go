@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyOverlays(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {"get": {"operationId": "listPets", "description": "List pets", "responses": {"200": {"description": "OK"}}}},
+    "/admin": {"get": {"operationId": "getAdmin", "responses": {"200": {"description": "OK"}}}}
+  }
+}`
+	dir := t.TempDir()
+	writeOverlay := func(t *testing.T, name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	rename, err := LoadOverlay(writeOverlay(t, "rename.yaml", `
+overlay: 1.0.0
+info: {title: Rename, version: 1.0.0}
+actions:
+  - target: $.paths['/pets'].get
+    update:
+      operationId: pets_list
+      description: Lists the pets in the store, newest first.
+  - target: $.paths['/admin']
+    remove: true
+`))
+	require.NoError(t, err)
+	// Overlays are applied in order, so later ones see the changes of earlier ones
+	describe, err := LoadOverlay(writeOverlay(t, "describe.json", `{
+  "overlay": "1.0.0",
+  "info": {"title": "Describe", "version": "1.0.0"},
+  "actions": [{"target": "$.paths[?(@.get.operationId == 'pets_list')].get", "update": {"summary": "List pets"}}]
+}`))
+	require.NoError(t, err)
+
+	patched, err := ApplyOverlays([]byte(spec), rename, describe)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tools, err := ListTools(ctx, patched)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "pets_list", tools[0].Name)
+	assert.Equal(t, "Lists the pets in the store, newest first.", tools[0].Description)
+	assert.Contains(t, string(patched), `"summary": "List pets"`)
+
+	unchanged, err := ApplyOverlays([]byte(spec))
+	require.NoError(t, err)
+	assert.Equal(t, spec, string(unchanged), "specs are unchanged without overlays")
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := LoadOverlay(writeOverlay(t, "version.yaml", `
+overlay: 2.0.0
+info: {title: Invalid, version: 1.0.0}
+actions: [{target: $.info, update: {title: Pets}}]
+`))
+		assert.ErrorContains(t, err, "overlay version must be 1.0.0")
+
+		_, err = LoadOverlay(writeOverlay(t, "target.yaml", `
+overlay: 1.0.0
+info: {title: Invalid, version: 1.0.0}
+actions: [{target: "$.paths[", remove: true}]
+`))
+		assert.ErrorContains(t, err, `action 0 target "$.paths["`)
+
+		_, err = LoadOverlay(filepath.Join(dir, "missing.yaml"))
+		assert.ErrorContains(t, err, "error reading overlay")
+	})
+}
@@ -0,0 +1,343 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+const (
+	// pageArgument is the reserved argument selecting a page of results, whatever the API calls it.
+	pageArgument = "_page"
+	// limitArgument is the reserved argument bounding the number of results, whatever the API calls it.
+	limitArgument = "_limit"
+	// fetchAllArgument is the reserved argument that makes a call follow pagination and return every page's results.
+	fetchAllArgument = "_fetchAll"
+
+	// defaultMaxPages is how many pages _fetchAll fetches, unless the configuration says otherwise.
+	defaultMaxPages = 10
+)
+
+// Pagination maps the reserved _page and _limit arguments to the parameters an API uses for pagination,
+// so that every tool pages through results the same way.
+// Each lists candidate argument names in order of preference, like ["page", "offset", "cursor"];
+// a tool's reserved argument stands in for the first candidate the operation has.
+type Pagination struct {
+	Page  []string `yaml:"page" json:"page,omitempty"`
+	Limit []string `yaml:"limit" json:"limit,omitempty"`
+	// Next lists the fields of responses that hold the value of the next page's _page argument,
+	// like a cursor or the URL of the next page, as dotted paths like "meta.next_cursor".
+	// When it or Items is set, tools with a _page argument also get a _fetchAll argument, which follows pages for the model.
+	// Without it, page numbers are counted up, and offsets are advanced by the number of results.
+	Next []string `yaml:"next" json:"next,omitempty"`
+	// Items lists the fields of responses that hold each page's results, as dotted paths like "data".
+	// Responses that are arrays are the results themselves.
+	Items []string `yaml:"items" json:"items,omitempty"`
+	// MaxPages is the most pages a call with _fetchAll fetches (default 10).
+	MaxPages int `yaml:"maxPages" json:"maxPages,omitempty"`
+}
+
+// validate checks that argument names and field paths aren't empty.
+func (p *Pagination) validate() error {
+	for _, name := range slices.Concat(p.Page, p.Limit) {
+		if name == "" {
+			return fmt.Errorf("invalid pagination argument: name is empty")
+		}
+	}
+	for _, field := range slices.Concat(p.Next, p.Items) {
+		if field == "" || slices.Contains(strings.Split(field, "."), "") {
+			return fmt.Errorf("invalid pagination field %q", field)
+		}
+	}
+	if p.MaxPages < 0 {
+		return fmt.Errorf("invalid pagination maxPages %d: must be positive", p.MaxPages)
+	}
+	return nil
+}
+
+// paginationArguments maps the reserved pagination arguments of a tool to the arguments they stand in for.
+type paginationArguments map[string]string
+
+// paginationArguments replaces the arguments an operation uses for pagination with the reserved arguments,
+// and returns the mapping from one to the other.
+// The reserved arguments keep the schemas of the arguments they replace, including whether they're required.
+// Operations with arguments named like the reserved arguments keep them.
+func (c *Config) paginationArguments(schema *jsonschema.Schema) paginationArguments {
+	if c == nil || c.Pagination == nil {
+		return nil
+	}
+	var mapped paginationArguments
+	for _, reserved := range []struct {
+		name       string
+		candidates []string
+	}{
+		{pageArgument, c.Pagination.Page},
+		{limitArgument, c.Pagination.Limit},
+	} {
+		i := slices.IndexFunc(reserved.candidates, func(name string) bool { return schema.Properties[name] != nil })
+		if i < 0 {
+			continue
+		}
+		if _, exists := schema.Properties[reserved.name]; exists {
+			continue
+		}
+		name := reserved.candidates[i]
+		schema.Properties[reserved.name] = schema.Properties[name]
+		delete(schema.Properties, name)
+		if j := slices.Index(schema.Required, name); j >= 0 {
+			schema.Required[j] = reserved.name
+		}
+		if mapped == nil {
+			mapped = paginationArguments{}
+		}
+		mapped[reserved.name] = name
+	}
+	// Tools that can be paged through can also fetch every page, if the configuration says how
+	if mapped.fetchesAll(c) {
+		if _, exists := schema.Properties[fetchAllArgument]; !exists {
+			schema.Properties[fetchAllArgument] = &jsonschema.Schema{
+				Type:        "boolean",
+				Description: fmt.Sprintf("If true, fetch every page of results, up to %d pages, and return them together", c.Pagination.maxPages()),
+			}
+		}
+	}
+	return mapped
+}
+
+// maxPages returns the most pages a call with _fetchAll fetches.
+func (p *Pagination) maxPages() int {
+	if p.MaxPages > 0 {
+		return p.MaxPages
+	}
+	return defaultMaxPages
+}
+
+// arguments returns a copy of a tool call's arguments with the reserved pagination arguments
+// renamed to the arguments they stand in for.
+func (p paginationArguments) arguments(args map[string]any) map[string]any {
+	if len(p) == 0 {
+		return args
+	}
+	renamed := make(map[string]any, len(args))
+	for name, value := range args {
+		if target, ok := p[name]; ok {
+			name = target
+		}
+		renamed[name] = value
+	}
+	return renamed
+}
+
+// fetchesAll reports whether a tool's calls can fetch every page of results with the _fetchAll argument.
+func (p paginationArguments) fetchesAll(c *Config) bool {
+	_, ok := p[pageArgument]
+	return ok && c.Pagination != nil && (len(c.Pagination.Next) > 0 || len(c.Pagination.Items) > 0)
+}
+
+// takeFetchAll reports whether a call's _fetchAll argument asks for every page of results, and returns its other arguments.
+func takeFetchAll(args map[string]any) (bool, map[string]any) {
+	value, rest := takeArgument(args, fetchAllArgument)
+	fetchAll, _ := value.(bool)
+	return fetchAll, rest
+}
+
+// pageFollower follows the pages of a tool call's results.
+type pageFollower struct {
+	pagination *Pagination
+	// page is the argument the operation uses for pagination, which _page stands in for
+	page string
+	// send sends the request for a page, with the given arguments
+	send func(ctx context.Context, args map[string]any) (*http.Response, error)
+}
+
+// follow fetches the pages after the first, whose response is resp, and returns a response
+// whose body has the results of every page in the first page's body, in place of its own.
+// It stops at the last page, or when it has fetched as many pages as it may,
+// and returns a note saying how many pages it fetched, and how to get more.
+// If a page after the first fails, its response is returned instead.
+func (f *pageFollower) follow(ctx context.Context, resp *http.Response, args map[string]any) (*http.Response, string, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	combined, itemsPath, items, ok := f.decodePage(body)
+	if !ok {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, "[Not all pages were fetched, since the results couldn't be found in the response]", nil
+	}
+
+	pages, last, current := 1, combined, args[f.page]
+	for n := len(items); ; {
+		next, ok := f.nextPage(last, current, n)
+		if !ok || n == 0 {
+			break
+		}
+		if pages == f.pagination.maxPages() {
+			return f.combined(resp, combined, itemsPath, items, last),
+				fmt.Sprintf("[Fetched %d pages with %d results, the most %s fetches. Call again with %s set to %v for more.]", pages, len(items), fetchAllArgument, pageArgument, next), nil
+		}
+		pageArgs := make(map[string]any, len(args))
+		for name, v := range args {
+			pageArgs[name] = v
+		}
+		pageArgs[f.page] = next
+		pageResp, err := f.send(ctx, pageArgs)
+		if err != nil {
+			return nil, "", err
+		}
+		if pageResp.StatusCode >= 300 {
+			return pageResp, fmt.Sprintf("[Fetching page %d of the results failed]", pages+1), nil
+		}
+		body, err := io.ReadAll(pageResp.Body)
+		pageResp.Body.Close()
+		if err != nil {
+			return nil, "", err
+		}
+		// Pages without results are past the last one
+		decoded, _, pageItems, ok := f.decodePage(body)
+		if !ok || len(pageItems) == 0 {
+			break
+		}
+		pages, last, current, n = pages+1, decoded, next, len(pageItems)
+		items = append(items, pageItems...)
+	}
+	return f.combined(resp, combined, itemsPath, items, nil), fmt.Sprintf("[Fetched all %d pages, with %d results]", pages, len(items)), nil
+}
+
+// decodePage decodes the body of a page of results, and returns it with the path of its results and the results themselves.
+func (f *pageFollower) decodePage(body []byte) (any, []string, []any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, nil, nil, false
+	}
+	if items, ok := v.([]any); ok {
+		return v, nil, items, true
+	}
+	for _, field := range f.pagination.Items {
+		path := strings.Split(field, ".")
+		if items, ok := fieldAt(v, path).([]any); ok {
+			return v, path, items, true
+		}
+	}
+	return nil, nil, nil, false
+}
+
+// nextPage returns the value of the page argument for the page after one whose response is page,
+// which was fetched with the value current and had n results, or false if it's the last page.
+func (f *pageFollower) nextPage(page, current any, n int) (any, bool) {
+	if len(f.pagination.Next) > 0 {
+		for _, field := range f.pagination.Next {
+			switch next := fieldAt(page, strings.Split(field, ".")).(type) {
+			case string:
+				if next == "" {
+					continue
+				}
+				// Links to the next page carry its page argument in their query
+				if u, err := url.Parse(next); err == nil && u.IsAbs() {
+					if value := u.Query().Get(f.page); value != "" {
+						return value, true
+					}
+					continue
+				}
+				return next, true
+			case json.Number:
+				return next, true
+			}
+		}
+		return nil, false
+	}
+	// Without a field for the next page, pages are numbered, or offsets count results
+	var n0 int64
+	switch v := current.(type) {
+	case json.Number:
+		n0, _ = v.Int64()
+	case int64:
+		n0 = v
+	case float64:
+		n0 = int64(v)
+	case string:
+		n0, _ = strconv.ParseInt(v, 10, 64)
+	case nil:
+		if !isOffsetArgument(f.page) {
+			n0 = 1
+		}
+	}
+	if isOffsetArgument(f.page) {
+		return n0 + int64(n), true
+	}
+	return n0 + 1, true
+}
+
+// isOffsetArgument reports whether a pagination argument counts results, rather than pages.
+func isOffsetArgument(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "offset") || strings.Contains(name, "skip")
+}
+
+// combined returns a response like first, whose body is the first page with its results replaced by items.
+// The fields for the next page are those of the last page fetched, or removed if there are no more pages.
+func (f *pageFollower) combined(first *http.Response, page any, itemsPath []string, items []any, last any) *http.Response {
+	var v any = items
+	if itemsPath != nil {
+		v = setField(page, itemsPath, items)
+		for _, field := range f.pagination.Next {
+			path := strings.Split(field, ".")
+			v = setField(v, path, fieldAt(last, path))
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v) // decoded values always encode
+	resp := *first
+	resp.Header = first.Header.Clone()
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = int64(buf.Len())
+	resp.Body = io.NopCloser(&buf)
+	return &resp
+}
+
+// fieldAt returns the value of the field at a path in a decoded JSON object, or nil if it has none.
+func fieldAt(v any, path []string) any {
+	for _, name := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+// setField sets the field at a path in a decoded JSON object, and returns the object.
+// Setting a field to nil removes it. Paths through values other than objects are left alone.
+func setField(v any, path []string, value any) any {
+	m, ok := v.(map[string]any)
+	if !ok || len(path) == 0 {
+		return v
+	}
+	if len(path) == 1 {
+		if value == nil {
+			delete(m, path[0])
+		} else {
+			m[path[0]] = value
+		}
+		return m
+	}
+	if _, ok := m[path[0]].(map[string]any); ok {
+		m[path[0]] = setField(m[path[0]], path[1:], value)
+	}
+	return m
+}
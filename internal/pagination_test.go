@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsFetchAll(t *testing.T) {
+	var requests int
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/pets":
+			// Three pages of two pets, linked by cursors
+			cursor, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+			next := "null"
+			if cursor < 4 {
+				next = strconv.Quote(strconv.Itoa(cursor + 2))
+			}
+			fmt.Fprintf(w, `{"data": [{"id": %d}, {"id": %d}], "meta": {"next_cursor": %s, "total": 6}}`, cursor+1, cursor+2, next)
+		case "/owners":
+			// Five owners, as arrays of up to two
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			owners := []int{}
+			for id := offset + 1; id <= min(offset+2, 5); id++ {
+				owners = append(owners, id)
+			}
+			_ = json.NewEncoder(w).Encode(owners)
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {"get": {
+      "operationId": "listPets",
+      "parameters": [{"name": "cursor", "in": "query", "schema": {"type": "string"}}],
+      "responses": {"200": {"description": "OK"}}
+    }},
+    "/owners": {"get": {
+      "operationId": "listOwners",
+      "parameters": [{"name": "offset", "in": "query", "schema": {"type": "integer"}}],
+      "responses": {"200": {"description": "OK"}}
+    }},
+    "/pets/{petId}": {"get": {
+      "operationId": "getPet",
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "responses": {"200": {"description": "OK"}}
+    }}
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	connect := func(t *testing.T, config string) *mcp.ClientSession {
+		t.Helper()
+		c, err := ParseConfig([]byte(config))
+		require.NoError(t, err)
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithConfig(c)))
+		return connectTestClient(t, ctx, server)
+	}
+	call := func(t *testing.T, clientSession *mcp.ClientSession, name string, args map[string]any) (string, string) {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		require.Len(t, result.Content, 2)
+		return result.Content[0].(*mcp.TextContent).Text, result.Content[1].(*mcp.TextContent).Text
+	}
+
+	t.Run("cursors", func(t *testing.T) {
+		clientSession := connect(t, `
+pagination:
+  page: [cursor, offset]
+  next: [meta.next_cursor]
+  items: [data]
+`)
+		tools, err := clientSession.ListTools(ctx, nil)
+		require.NoError(t, err)
+		for _, tool := range tools.Tools {
+			if tool.Name == "getPet" {
+				assert.NotContains(t, tool.InputSchema.Properties, fetchAllArgument, "tools without pagination can't fetch every page")
+			} else {
+				assert.Contains(t, tool.InputSchema.Properties, fetchAllArgument, tool.Name)
+			}
+		}
+
+		requests = 0
+		text, note := call(t, clientSession, "listPets", map[string]any{fetchAllArgument: true})
+		assert.JSONEq(t, `{"data": [{"id": 1}, {"id": 2}, {"id": 3}, {"id": 4}, {"id": 5}, {"id": 6}], "meta": {"total": 6}}`, text)
+		assert.Contains(t, note, "Fetched all 3 pages, with 6 results")
+		assert.Equal(t, 3, requests)
+
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+		require.NoError(t, err)
+		require.Len(t, result.Content, 1, "calls without _fetchAll return a single page")
+	})
+
+	t.Run("max pages", func(t *testing.T) {
+		clientSession := connect(t, `
+pagination:
+  page: [cursor]
+  next: [meta.next_cursor]
+  items: [data]
+  maxPages: 2
+`)
+		requests = 0
+		text, note := call(t, clientSession, "listPets", map[string]any{fetchAllArgument: true})
+		assert.JSONEq(t, `{"data": [{"id": 1}, {"id": 2}, {"id": 3}, {"id": 4}], "meta": {"next_cursor": "4", "total": 6}}`, text)
+		assert.Contains(t, note, "Fetched 2 pages with 4 results")
+		assert.Contains(t, note, "_page set to 4")
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("offsets", func(t *testing.T) {
+		clientSession := connect(t, `
+pagination:
+  page: [offset]
+  items: [results]
+`)
+		requests = 0
+		text, note := call(t, clientSession, "listOwners", map[string]any{fetchAllArgument: true})
+		assert.JSONEq(t, `[1, 2, 3, 4, 5]`, text)
+		assert.Contains(t, note, "Fetched all 3 pages, with 5 results")
+		assert.Equal(t, 4, requests, "offsets advance until a page has no results")
+	})
+}
+
+func TestParseConfigPagination(t *testing.T) {
+	_, err := ParseConfig([]byte("pagination:\n  next: [meta..cursor]\n"))
+	assert.ErrorContains(t, err, "invalid pagination field")
+
+	_, err = ParseConfig([]byte("pagination:\n  maxPages: -1\n"))
+	assert.ErrorContains(t, err, "invalid pagination maxPages")
+}
@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// prefetchTimeout bounds how long prefetching each operation's response may take.
+const prefetchTimeout = time.Minute
+
+// prefetchRequest is a request for a GET operation whose response is fetched at startup.
+type prefetchRequest struct {
+	operationID string
+	ep          *endpoint
+	args        map[string]any
+}
+
+// prefetchRequest returns the request prefetching an operation's response, if the configuration lists it.
+// Only GET operations whose required arguments all have defaults can be prefetched.
+func (c *Config) prefetchRequest(ep *endpoint, schema *jsonschema.Schema, defaults map[string]any) (*prefetchRequest, error) {
+	operationID := ep.op.OperationId
+	if c == nil || !slices.Contains(c.Prefetch, operationID) {
+		return nil, nil
+	}
+	if ep.method != http.MethodGet {
+		return nil, fmt.Errorf("config prefetches operation %q, which isn't a GET operation", operationID)
+	}
+	if len(schema.Required) > 0 {
+		return nil, fmt.Errorf("config prefetches operation %q, which requires arguments without defaults: %v", operationID, schema.Required)
+	}
+	return &prefetchRequest{operationID: operationID, ep: ep, args: defaults}, nil
+}
+
+// prefetch sends prefetch requests concurrently, so that their responses are in the client's cache
+// by the time a tool asks for them. Failures are logged, since the responses can still be fetched on demand.
+func prefetch(ctx context.Context, client *http.Client, requests []*prefetchRequest, logger *slog.Logger) {
+	var wg sync.WaitGroup
+	for _, r := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, prefetchTimeout)
+			defer cancel()
+			req, err := r.ep.newRequest(ctx, r.args)
+			if err != nil {
+				logger.Warn("error prefetching response", "operation", r.operationID, "error", err)
+				return
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				logger.Warn("error prefetching response", "operation", r.operationID, "error", err)
+				return
+			}
+			defer resp.Body.Close()
+			// The response is only cached once its body has been read
+			_, _ = io.Copy(io.Discard, resp.Body)
+			logger.Debug("prefetched response", "operation", r.operationID, "status", resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+}
+
+// cachesResponses reports whether a client caches responses, so that prefetching them is worthwhile.
+func cachesResponses(client *http.Client) bool {
+	_, ok := client.Transport.(*CacheTransport)
+	return ok
+}
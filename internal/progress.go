@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressInterval is the minimum time between progress notifications for a tool call.
+var progressInterval = time.Second
+
+// progressReporter sends progress notifications for a tool call whose request included a progress token.
+// Progress is measured in bytes of the response body received, so it's reported against
+// the response's Content-Length when the API provides one.
+// While waiting for the API to respond, notifications are sent periodically so that the client knows the call is still active.
+// All methods are no-ops on a nil progressReporter.
+type progressReporter struct {
+	ctx     context.Context
+	session *mcp.ServerSession
+	token   any
+
+	mu       sync.Mutex
+	progress float64
+	total    float64
+	last     time.Time
+}
+
+// newProgressReporter returns a progressReporter for a tool call, or nil if the client didn't request progress.
+func newProgressReporter(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) *progressReporter {
+	if req == nil || req.Session == nil || req.Params == nil {
+		return nil
+	}
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return nil
+	}
+	return &progressReporter{ctx: ctx, session: req.Session, token: token}
+}
+
+// waiting sends notifications while waiting for a response from the API, until the returned function is called.
+func (p *progressReporter) waiting() (stop func()) {
+	if p == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		start := time.Now()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-p.ctx.Done():
+				return
+			case now := <-ticker.C:
+				p.notify(fmt.Sprintf("Waiting for response (%s elapsed)", now.Sub(start).Round(time.Second)), true)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// body returns a reader for a response body that reports progress as it's read.
+func (p *progressReporter) body(resp *http.Response) io.Reader {
+	if p == nil {
+		return resp.Body
+	}
+	p.mu.Lock()
+	if resp.ContentLength > 0 {
+		p.total = float64(resp.ContentLength)
+	}
+	p.mu.Unlock()
+	return &progressBody{r: resp.Body, p: p}
+}
+
+// done sends a final notification once the response body has been read.
+func (p *progressReporter) done() {
+	if p == nil {
+		return
+	}
+	p.notify("Received response", true)
+}
+
+// notify sends a progress notification, unless one was sent within the progress interval and force is false.
+func (p *progressReporter) notify(message string, force bool) {
+	p.mu.Lock()
+	now := time.Now()
+	if !force && now.Sub(p.last) < progressInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.last = now
+	params := &mcp.ProgressNotificationParams{
+		ProgressToken: p.token,
+		Progress:      p.progress,
+		Total:         p.total,
+		Message:       message,
+	}
+	p.mu.Unlock()
+	// Notifications are best effort, and shouldn't cause the call to fail
+	_ = p.session.NotifyProgress(p.ctx, params)
+}
+
+// progressBody is a response body that reports progress as it's read.
+type progressBody struct {
+	r io.Reader
+	p *progressReporter
+}
+
+func (b *progressBody) Read(buf []byte) (int, error) {
+	n, err := b.r.Read(buf)
+	if n > 0 {
+		b.p.mu.Lock()
+		b.p.progress += float64(n)
+		b.p.mu.Unlock()
+		b.p.notify("Receiving response", false)
+	}
+	return n, err
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsReportsProgress(t *testing.T) {
+	interval := progressInterval
+	progressInterval = 10 * time.Millisecond
+	defer func() { progressInterval = interval }()
+
+	body := `{"report":"` + strings.Repeat("x", 4096) + `"}`
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		for i := 0; i < len(body); i += 1024 {
+			_, _ = w.Write([]byte(body[i:min(i+1024, len(body))]))
+			w.(http.Flusher).Flush()
+			time.Sleep(15 * time.Millisecond)
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Reports API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/report": {"get": {"operationId": "getReport", "responses": {"200": {"description": "OK"}}}}
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	notifications := make(chan *mcp.ProgressNotificationParams, 100)
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "dev"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(ctx context.Context, req *mcp.ClientRequest[*mcp.ProgressNotificationParams]) {
+			notifications <- req.Params
+		},
+	})
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer clientSession.Close()
+
+	// SetProgressToken doesn't store the token when Meta is nil, so set it directly
+	params := &mcp.CallToolParams{Name: "getReport", Meta: mcp.Meta{"progressToken": "report-1"}}
+	result, err := clientSession.CallTool(ctx, params)
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	var received []*mcp.ProgressNotificationParams
+	for {
+		select {
+		case n := <-notifications:
+			received = append(received, n)
+			if n.Message != "Received response" {
+				continue
+			}
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for final progress notification")
+		}
+		break
+	}
+
+	require.GreaterOrEqual(t, len(received), 3)
+	assert.Contains(t, received[0].Message, "Waiting for response")
+	last := received[len(received)-1]
+	assert.Equal(t, "report-1", last.ProgressToken)
+	assert.EqualValues(t, len(body), last.Progress)
+	assert.EqualValues(t, len(body), last.Total)
+	for i := 1; i < len(received); i++ {
+		assert.GreaterOrEqual(t, received[i].Progress, received[i-1].Progress)
+	}
+}
+
+func TestProgressReporterWithoutToken(t *testing.T) {
+	var p *progressReporter
+	p.waiting()()
+	p.done()
+	assert.Nil(t, newProgressReporter(context.Background(), &mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]{
+		Params: &mcp.CallToolParamsFor[map[string]any]{Name: "getReport"},
+	}))
+}
@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	"gopkg.in/yaml.v3"
+)
+
+// promptGoalArgument is the optional prompt argument describing what the user wants to accomplish.
+const promptGoalArgument = "goal"
+
+// WithPrompts registers a prompt for each tag in the spec,
+// which summarizes the tag's operations and walks the model through using them.
+// A tag's x-prompt extension replaces the generated workflow,
+// either as a string or as an object with name, description, and text fields.
+func WithPrompts() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.prompts = true }
+}
+
+// promptOperation is a tool listed in a tag's prompt.
+type promptOperation struct {
+	tool     string
+	method   string
+	path     string
+	summary  string
+	required []string
+}
+
+// tagPrompts collects operations by tag, in the order tags are first used.
+type tagPrompts struct {
+	order []string
+	ops   map[string][]promptOperation
+}
+
+func (t *tagPrompts) add(tags []string, op promptOperation) {
+	if t.ops == nil {
+		t.ops = make(map[string][]promptOperation)
+	}
+	for _, tag := range tags {
+		if _, ok := t.ops[tag]; !ok {
+			t.order = append(t.order, tag)
+		}
+		t.ops[tag] = append(t.ops[tag], op)
+	}
+}
+
+// promptExtension is the value of a tag's x-prompt extension.
+type promptExtension struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Text        string `yaml:"text"`
+}
+
+// parsePromptExtension decodes an x-prompt extension, which is either a string or an object.
+func parsePromptExtension(node *yaml.Node) (promptExtension, bool) {
+	var ext promptExtension
+	if node == nil {
+		return ext, false
+	}
+	if node.Kind == yaml.ScalarNode {
+		ext.Text = node.Value
+		return ext, ext.Text != ""
+	}
+	if err := node.Decode(&ext); err != nil {
+		return ext, false
+	}
+	return ext, true
+}
+
+// register adds a prompt for each tag with at least one operation, and returns the names of the prompts.
+// apiTitle is the title of the API, and tags are the tag definitions from the spec, which may be incomplete.
+func (t *tagPrompts) register(server *mcp.Server, apiTitle string, tags []*base.Tag, prefix string) []string {
+	definitions := make(map[string]*base.Tag, len(tags))
+	for _, tag := range tags {
+		if tag != nil {
+			definitions[tag.Name] = tag
+		}
+	}
+	if apiTitle == "" {
+		apiTitle = "the API"
+	}
+
+	var names []string
+	for _, name := range t.order {
+		ops := t.ops[name]
+		def := definitions[name]
+
+		var ext promptExtension
+		if def != nil && def.Extensions != nil {
+			if node, ok := def.Extensions.Get("x-prompt"); ok {
+				ext, _ = parsePromptExtension(node)
+			}
+		}
+
+		prompt := &mcp.Prompt{
+			Name:        getToolName(prefix, promptName(name)),
+			Title:       name,
+			Description: ext.Description,
+			Arguments: []*mcp.PromptArgument{{
+				Name:        promptGoalArgument,
+				Description: "What you want to accomplish",
+			}},
+		}
+		if ext.Name != "" {
+			prompt.Name = getToolName(prefix, ext.Name)
+		}
+		if prompt.Description == "" && def != nil {
+			prompt.Description = firstSentence(def.Description)
+		}
+		if prompt.Description == "" {
+			prompt.Description = fmt.Sprintf("Work with the %s operations of %s", name, apiTitle)
+		}
+
+		text := promptText(apiTitle, name, def, ops, ext.Text)
+		names = append(names, prompt.Name)
+		server.AddPrompt(prompt, func(ctx context.Context, session *mcp.ServerSession, params *mcp.GetPromptParams) (*mcp.GetPromptResult, error) {
+			text := text
+			if goal := strings.TrimSpace(params.Arguments[promptGoalArgument]); goal != "" {
+				text += "\n\nThe user wants to: " + goal
+			}
+			return &mcp.GetPromptResult{
+				Description: prompt.Description,
+				Messages:    []*mcp.PromptMessage{{Role: "user", Content: &mcp.TextContent{Text: text}}},
+			}, nil
+		})
+	}
+	return names
+}
+
+// promptName converts a tag name into a prompt name, replacing characters other than letters, digits, hyphens, and underscores.
+func promptName(tag string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(tag))
+	if name == "" {
+		return "_"
+	}
+	return name
+}
+
+// firstSentence returns the first sentence or line of a description.
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i+1]
+	}
+	return s
+}
+
+// promptText builds the text of a tag's prompt, listing its tools and describing a workflow for using them.
+// If workflow is empty, one is generated from the HTTP methods of the operations.
+func promptText(apiTitle, tag string, def *base.Tag, ops []promptOperation, workflow string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You can use the following tools to work with the %s operations of %s.\n", tag, apiTitle)
+	if def != nil && strings.TrimSpace(def.Description) != "" {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(def.Description))
+	}
+
+	b.WriteString("\nTools:\n")
+	for _, op := range ops {
+		fmt.Fprintf(&b, "- %s (%s %s)", op.tool, op.method, op.path)
+		if op.summary != "" {
+			fmt.Fprintf(&b, ": %s", op.summary)
+		}
+		if len(op.required) > 0 {
+			fmt.Fprintf(&b, " [requires %s]", strings.Join(op.required, ", "))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\nWorkflow:\n")
+	if workflow = strings.TrimSpace(workflow); workflow != "" {
+		b.WriteString(workflow)
+	} else {
+		b.WriteString(generatedWorkflow(ops))
+	}
+
+	if def != nil && def.ExternalDocs != nil && def.ExternalDocs.URL != "" {
+		fmt.Fprintf(&b, "\n\nFor more information, see %s", def.ExternalDocs.URL)
+	}
+	return b.String()
+}
+
+// generatedWorkflow describes a general workflow for a set of operations:
+// look things up with read-only tools first, then make changes, confirming destructive changes with the user.
+func generatedWorkflow(ops []promptOperation) string {
+	var reads, writes, deletes []string
+	for _, op := range ops {
+		switch {
+		case isReadOnlyMethod(op.method):
+			reads = append(reads, op.tool)
+		case op.method == "DELETE":
+			deletes = append(deletes, op.tool)
+		default:
+			writes = append(writes, op.tool)
+		}
+	}
+
+	var steps []string
+	if len(reads) > 0 {
+		steps = append(steps, fmt.Sprintf("Look up the information you need with %s. These tools don't change anything.", strings.Join(reads, ", ")))
+	}
+	if len(writes) > 0 {
+		steps = append(steps, fmt.Sprintf("Make changes with %s, using identifiers from earlier results rather than guessing them.", strings.Join(writes, ", ")))
+	}
+	if len(deletes) > 0 {
+		steps = append(steps, fmt.Sprintf("Confirm with the user before calling %s, since deletions can't be undone.", strings.Join(deletes, ", ")))
+	}
+	steps = append(steps, "If a call fails, read the error, correct the arguments, and try again.")
+
+	var b strings.Builder
+	for i, step := range steps {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%d. %s", i+1, step)
+	}
+	return b.String()
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithPrompts(t *testing.T) {
+	spec := `openapi: 3.1.0
+info:
+  title: Pet Store
+  version: 1.0.0
+servers:
+  - url: https://api.example.com
+tags:
+  - name: pets
+    description: Everything about your pets. Cats, dogs, and more.
+    externalDocs:
+      url: https://docs.example.com/pets
+  - name: store orders
+    x-prompt:
+      name: ordering
+      description: Place and track orders
+      text: Check inventory with getInventory before placing an order.
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List all pets
+      tags: [pets]
+      responses: {"200": {description: OK}}
+    post:
+      operationId: createPet
+      summary: Create a pet
+      tags: [pets]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name]
+              properties:
+                name: {type: string}
+      responses: {"201": {description: Created}}
+  /pets/{petId}:
+    delete:
+      operationId: deletePet
+      tags: [pets]
+      parameters:
+        - {name: petId, in: path, required: true, schema: {type: string}}
+      responses: {"204": {description: Deleted}}
+  /store/inventory:
+    get:
+      operationId: getInventory
+      tags: [store orders]
+      responses: {"200": {description: OK}}
+  /health:
+    get:
+      operationId: getHealth
+      responses: {"200": {description: OK}}
+`
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), nil, WithPrompts()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+	list, err := clientSession.ListPrompts(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, list.Prompts, 2, "untagged operations have no prompt")
+	names := map[string]*mcp.Prompt{}
+	for _, p := range list.Prompts {
+		names[p.Name] = p
+	}
+	require.Contains(t, names, "pets")
+	assert.Equal(t, "Everything about your pets.", names["pets"].Description)
+	require.Contains(t, names, "ordering")
+	assert.Equal(t, "Place and track orders", names["ordering"].Description)
+	assert.Equal(t, "store orders", names["ordering"].Title)
+
+	result, err := clientSession.GetPrompt(ctx, &mcp.GetPromptParams{Name: "pets", Arguments: map[string]string{"goal": "adopt a cat"}})
+	require.NoError(t, err)
+	require.Len(t, result.Messages, 1)
+	text := result.Messages[0].Content.(*mcp.TextContent).Text
+	assert.Contains(t, text, "work with the pets operations of Pet Store")
+	assert.Contains(t, text, "- listPets (GET /pets): List all pets\n")
+	assert.Contains(t, text, "- createPet (POST /pets): Create a pet [requires name]\n")
+	assert.Contains(t, text, "- deletePet (DELETE /pets/{petId}) [requires petId]\n")
+	assert.Contains(t, text, "1. Look up the information you need with listPets.")
+	assert.Contains(t, text, "Confirm with the user before calling deletePet")
+	assert.Contains(t, text, "see https://docs.example.com/pets")
+	assert.Contains(t, text, "The user wants to: adopt a cat")
+
+	result, err = clientSession.GetPrompt(ctx, &mcp.GetPromptParams{Name: "ordering"})
+	require.NoError(t, err)
+	text = result.Messages[0].Content.(*mcp.TextContent).Text
+	assert.Contains(t, text, "Workflow:\nCheck inventory with getInventory before placing an order.")
+	assert.NotContains(t, text, "The user wants to")
+}
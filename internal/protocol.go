@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Protocol revisions that changed what results may contain, as negotiated in the initialize request.
+// Revisions are dates, so they're ordered when compared as strings.
+const (
+	// protocolAnnotations added tool annotations and the completions capability.
+	protocolAnnotations = "2025-03-26"
+	// protocolStructuredContent added output schemas, structured content, titles, and resource links.
+	protocolStructuredContent = "2025-06-18"
+)
+
+// NegotiateProtocol adapts a server's results to the protocol revision negotiated with each client,
+// leaving out features that clients of an earlier revision don't know about,
+// like the tool annotations and output schemas of later revisions.
+// The SDK answers the initialize request with the client's revision if it's supported,
+// and with the latest revision otherwise; clients of that revision get results as they are.
+// Call it once per server, after registering tools, so that its middleware sees the results of all other middleware.
+func NegotiateProtocol(server *mcp.Server) {
+	n := &protocolNegotiator{}
+	server.AddReceivingMiddleware(n.middleware)
+}
+
+// protocolNegotiator records the protocol revision of each client session.
+type protocolNegotiator struct {
+	versions sync.Map // by *mcp.ServerSession
+}
+
+func (n *protocolNegotiator) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		res, err := next(ctx, method, req)
+		if err != nil || res == nil {
+			return res, err
+		}
+		session, _ := req.GetSession().(*mcp.ServerSession)
+		if session == nil {
+			return res, err
+		}
+		if result, ok := res.(*mcp.InitializeResult); ok {
+			n.versions.Store(session, result.ProtocolVersion)
+			go func() {
+				_ = session.Wait()
+				n.versions.Delete(session)
+			}()
+		}
+		version, ok := n.versions.Load(session)
+		if !ok {
+			return res, err
+		}
+		return adaptResult(res, version.(string)), nil
+	}
+}
+
+// adaptResult returns a copy of a result without the features of revisions later than version.
+// Results are shared between sessions, like the registered tools in tools/list, so they're copied rather than changed.
+func adaptResult(res mcp.Result, version string) mcp.Result {
+	annotations := version >= protocolAnnotations
+	structured := version >= protocolStructuredContent
+	if annotations && structured {
+		return res
+	}
+	switch r := res.(type) {
+	case *mcp.InitializeResult:
+		if !annotations && r.Capabilities != nil && r.Capabilities.Completions != nil {
+			adapted, capabilities := *r, *r.Capabilities
+			capabilities.Completions = nil
+			adapted.Capabilities = &capabilities
+			return &adapted
+		}
+	case *mcp.ListToolsResult:
+		adapted := *r
+		adapted.Tools = make([]*mcp.Tool, len(r.Tools))
+		for i, tool := range r.Tools {
+			t := *tool
+			if !annotations {
+				t.Annotations = nil
+			}
+			t.OutputSchema = nil
+			t.Title = ""
+			adapted.Tools[i] = &t
+		}
+		return &adapted
+	case *mcp.CallToolResult:
+		adapted := *r
+		adapted.StructuredContent = nil
+		// Resource links are described in the text of the results that have them
+		adapted.Content = slices.DeleteFunc(slices.Clone(r.Content), func(c mcp.Content) bool {
+			_, ok := c.(*mcp.ResourceLink)
+			return ok
+		})
+		return &adapted
+	case *mcp.ListPromptsResult:
+		adapted := *r
+		adapted.Prompts = make([]*mcp.Prompt, len(r.Prompts))
+		for i, prompt := range r.Prompts {
+			p := *prompt
+			p.Title = ""
+			adapted.Prompts[i] = &p
+		}
+		return &adapted
+	case *mcp.ListResourcesResult:
+		adapted := *r
+		adapted.Resources = make([]*mcp.Resource, len(r.Resources))
+		for i, resource := range r.Resources {
+			r := *resource
+			r.Title = ""
+			adapted.Resources[i] = &r
+		}
+		return &adapted
+	case *mcp.ListResourceTemplatesResult:
+		adapted := *r
+		adapted.ResourceTemplates = make([]*mcp.ResourceTemplate, len(r.ResourceTemplates))
+		for i, template := range r.ResourceTemplates {
+			t := *template
+			t.Title = ""
+			adapted.ResourceTemplates[i] = &t
+		}
+		return &adapted
+	}
+	return res
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "Fido"}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pet": {
+      "get": {
+        "operationId": "getPet",
+        "summary": "Get the pet",
+        "responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {"type": "object", "properties": {"name": {"type": "string"}}}}}}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+	NegotiateProtocol(server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// connect starts a session with a client that requests a protocol revision,
+	// returning the revision the server agreed to and a function for making requests
+	connect := func(t *testing.T, version string) (string, func(method, params string) map[string]any) {
+		in, inWriter := io.Pipe()
+		outReader, out := io.Pipe()
+		serverSession, err := server.Connect(ctx, (&Stdio{In: in, Out: out, Err: io.Discard}).Transport(), nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { serverSession.Close() })
+
+		responses := bufio.NewScanner(outReader)
+		responses.Buffer(nil, 1<<20)
+		var id int
+		request := func(method, params string) map[string]any {
+			id++
+			_, err := fmt.Fprintf(inWriter, `{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`+"\n", id, method, params)
+			require.NoError(t, err)
+			require.True(t, responses.Scan())
+			var resp struct {
+				Result map[string]any `json:"result"`
+			}
+			require.NoError(t, json.Unmarshal(responses.Bytes(), &resp))
+			return resp.Result
+		}
+		initialized := request("initialize", fmt.Sprintf(`{"protocolVersion":%q,"capabilities":{},"clientInfo":{"name":"test","version":"dev"}}`, version))
+		_, err = inWriter.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n"))
+		require.NoError(t, err)
+		return initialized["protocolVersion"].(string), request
+	}
+
+	t.Run("earlier revisions", func(t *testing.T) {
+		version, request := connect(t, "2024-11-05")
+		assert.Equal(t, "2024-11-05", version)
+		tools := request("tools/list", `{}`)["tools"].([]any)
+		require.Len(t, tools, 1)
+		tool := tools[0].(map[string]any)
+		assert.Equal(t, "getPet", tool["name"])
+		assert.NotContains(t, tool, "annotations")
+		assert.NotContains(t, tool, "outputSchema")
+		assert.NotContains(t, tool, "title")
+
+		result := request("tools/call", `{"name":"getPet"}`)
+		assert.NotContains(t, result, "structuredContent")
+		assert.Contains(t, result["content"].([]any)[0].(map[string]any)["text"], "Fido")
+	})
+
+	t.Run("annotations without structured content", func(t *testing.T) {
+		version, request := connect(t, "2025-03-26")
+		assert.Equal(t, "2025-03-26", version)
+		tool := request("tools/list", `{}`)["tools"].([]any)[0].(map[string]any)
+		assert.Contains(t, tool, "annotations")
+		assert.NotContains(t, tool, "outputSchema")
+	})
+
+	t.Run("latest revision", func(t *testing.T) {
+		// Clients of unsupported revisions are answered with the latest revision
+		version, request := connect(t, "2099-01-01")
+		assert.Equal(t, "2025-06-18", version)
+		tool := request("tools/list", `{}`)["tools"].([]any)[0].(map[string]any)
+		assert.Contains(t, tool, "annotations")
+		assert.Contains(t, tool, "outputSchema")
+		assert.Contains(t, request("tools/call", `{"name":"getPet"}`), "structuredContent")
+	})
+}
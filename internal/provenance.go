@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// provenanceMetaKey is the key of a content block's provenance in its _meta.
+const provenanceMetaKey = "emcee/provenance"
+
+// Cache statuses of content.
+const (
+	// cacheMiss is content fetched from the API for this call.
+	cacheMiss = "miss"
+	// cacheUpstream is content the API served from an HTTP cache, such as a CDN.
+	cacheUpstream = "upstream"
+	// cacheLocal is content reused by emcee from an earlier call.
+	cacheLocal = "emcee"
+)
+
+// externalTrust labels content that comes from outside emcee's trust boundary.
+// Clients shouldn't follow instructions found in it.
+const externalTrust = "external"
+
+// provenance describes where and when content returned by a tool or resource was retrieved.
+type provenance struct {
+	// Origin is the host of the API that returned the content.
+	Origin string `json:"origin"`
+	// RetrievedAt is when the response was received, in RFC 3339 format.
+	RetrievedAt string `json:"retrievedAt"`
+	// Cache is whether the content was fetched for this call ("miss"),
+	// served by an HTTP cache in front of the API ("upstream"), or reused by emcee ("emcee").
+	Cache string `json:"cache"`
+	// Trust is the trust boundary of the content, which is always "external" for API responses.
+	Trust string `json:"trust"`
+}
+
+// newProvenance returns the provenance of an API response received at a given time.
+func newProvenance(resp *http.Response, retrievedAt time.Time) *provenance {
+	p := &provenance{
+		RetrievedAt: retrievedAt.UTC().Format(time.RFC3339),
+		Cache:       cacheMiss,
+		Trust:       externalTrust,
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		p.Origin = resp.Request.URL.Host
+	}
+	if servedFromCache(resp.Header) {
+		p.Cache = cacheUpstream
+	}
+	return p
+}
+
+// servedFromCache reports whether response headers indicate that an HTTP cache served the response,
+// using Cache-Status (RFC 9211), X-Cache, or a nonzero Age (RFC 9111).
+func servedFromCache(h http.Header) bool {
+	for _, v := range h.Values("Cache-Status") {
+		for _, entry := range splitQuoted(v, ',') {
+			for _, param := range strings.Split(entry, ";")[1:] {
+				if strings.EqualFold(strings.TrimSpace(param), "hit") {
+					return true
+				}
+			}
+		}
+	}
+	for _, v := range h.Values("X-Cache") {
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(v)), "HIT") {
+			return true
+		}
+	}
+	if age, err := strconv.Atoi(strings.TrimSpace(h.Get("Age"))); err == nil && age > 0 {
+		return true
+	}
+	return false
+}
+
+// annotate labels each content block of a result with its provenance.
+// The block's lastModified annotation is the response's Last-Modified date if it has one,
+// and otherwise the time the response was retrieved.
+func (p *provenance) annotate(result *mcp.CallToolResultFor[any], resp *http.Response) {
+	lastModified := p.RetrievedAt
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		lastModified = t.UTC().Format(time.RFC3339)
+	}
+	for _, c := range result.Content {
+		switch c := c.(type) {
+		case *mcp.TextContent:
+			c.Meta = withProvenance(c.Meta, p)
+			c.Annotations = withLastModified(c.Annotations, lastModified)
+		case *mcp.ImageContent:
+			c.Meta = withProvenance(c.Meta, p)
+			c.Annotations = withLastModified(c.Annotations, lastModified)
+		}
+	}
+}
+
+// withProvenance returns a copy of meta with a provenance added.
+func withProvenance(meta mcp.Meta, p *provenance) mcp.Meta {
+	m := make(mcp.Meta, len(meta)+1)
+	for k, v := range meta {
+		m[k] = v
+	}
+	m[provenanceMetaKey] = p
+	return m
+}
+
+// withLastModified returns a copy of annotations with a lastModified time.
+func withLastModified(a *mcp.Annotations, lastModified string) *mcp.Annotations {
+	annotations := &mcp.Annotations{}
+	if a != nil {
+		*annotations = *a
+	}
+	annotations.LastModified = lastModified
+	return annotations
+}
+
+// reusedResult returns a copy of a result that emcee reuses from an earlier call,
+// with the provenance of its content marked as cached by emcee.
+// The original result is left unchanged, since it may be reused again.
+func reusedResult(result *mcp.CallToolResultFor[any]) *mcp.CallToolResultFor[any] {
+	reused := *result
+	reused.Content = make([]mcp.Content, len(result.Content))
+	for i, c := range result.Content {
+		switch c := c.(type) {
+		case *mcp.TextContent:
+			copied := *c
+			copied.Meta = reusedMeta(c.Meta)
+			reused.Content[i] = &copied
+		case *mcp.ImageContent:
+			copied := *c
+			copied.Meta = reusedMeta(c.Meta)
+			reused.Content[i] = &copied
+		default:
+			reused.Content[i] = c
+		}
+	}
+	return &reused
+}
+
+// reusedMeta returns a copy of meta whose provenance is marked as cached by emcee.
+func reusedMeta(meta mcp.Meta) mcp.Meta {
+	p, ok := meta[provenanceMetaKey].(*provenance)
+	if !ok {
+		return meta
+	}
+	copied := *p
+	copied.Cache = cacheLocal
+	return withProvenance(meta, &copied)
+}
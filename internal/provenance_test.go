@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServedFromCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{"no cache headers", http.Header{}, false},
+		{"cache-status hit", http.Header{"Cache-Status": {`ExampleCDN; hit, "origin"; fwd=uri-miss`}}, true},
+		{"cache-status miss", http.Header{"Cache-Status": {"ExampleCDN; fwd=uri-miss; stored"}}, false},
+		{"x-cache hit", http.Header{"X-Cache": {"Hit from cloudfront"}}, true},
+		{"x-cache miss", http.Header{"X-Cache": {"MISS"}}, false},
+		{"nonzero age", http.Header{"Age": {"120"}}, true},
+		{"zero age", http.Header{"Age": {"0"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, servedFromCache(tt.header))
+		})
+	}
+}
+
+func TestRegisterToolsAnnotatesProvenance(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pets/1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		w.Header().Set("Age", "30")
+		_, _ = w.Write([]byte(`{"name":"Fido"}`))
+	}))
+	defer api.Close()
+	apiURL, err := url.Parse(api.URL)
+	require.NoError(t, err)
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithNotFoundCache(NotFoundCacheOptions{TTL: time.Minute})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	provenanceOf := func(petID string) (map[string]any, *mcp.Annotations) {
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": petID}})
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Content)
+		content := result.Content[0].(*mcp.TextContent)
+		p, ok := content.Meta[provenanceMetaKey].(map[string]any)
+		require.True(t, ok, "content has provenance")
+		return p, content.Annotations
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	p, annotations := provenanceOf("1")
+	assert.Equal(t, apiURL.Host, p["origin"])
+	assert.Equal(t, cacheUpstream, p["cache"])
+	assert.Equal(t, externalTrust, p["trust"])
+	retrievedAt, err := time.Parse(time.RFC3339, p["retrievedAt"].(string))
+	require.NoError(t, err)
+	assert.False(t, retrievedAt.Before(before))
+	require.NotNil(t, annotations)
+	assert.Equal(t, "2015-10-21T07:28:00Z", annotations.LastModified)
+
+	p, annotations = provenanceOf("404")
+	assert.Equal(t, cacheMiss, p["cache"])
+	require.NotNil(t, annotations)
+	assert.Equal(t, p["retrievedAt"], annotations.LastModified, "without Last-Modified, content is dated by retrieval")
+
+	cached, _ := provenanceOf("404")
+	assert.Equal(t, cacheLocal, cached["cache"])
+	assert.Equal(t, p["retrievedAt"], cached["retrievedAt"])
+
+	again, _ := provenanceOf("404")
+	assert.Equal(t, cacheLocal, again["cache"], "reusing a result doesn't change the cached copy")
+}
@@ -0,0 +1,179 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimits sets budgets for requests to the API, in requests per second,
+// beyond the overall limit set by --rps.
+type RateLimits struct {
+	// Hosts limits requests to each host, by hostname (e.g. api.example.com).
+	Hosts map[string]float64 `yaml:"hosts" json:"hosts,omitempty"`
+	// Operations limits the calls of each operation, by operation ID.
+	Operations map[string]float64 `yaml:"operations" json:"operations,omitempty"`
+}
+
+// validate checks that every limit is positive.
+func (l *RateLimits) validate() error {
+	for host, limit := range l.Hosts {
+		if limit <= 0 {
+			return fmt.Errorf("invalid rate limit %v for host %q: must be positive", limit, host)
+		}
+	}
+	for id, limit := range l.Operations {
+		if limit <= 0 {
+			return fmt.Errorf("invalid rate limit %v for operation %q: must be positive", limit, id)
+		}
+	}
+	return nil
+}
+
+// limiter allows requests at a steady rate, with bursts of up to a second's worth of requests,
+// and can be paused, like when the API asks clients to back off.
+type limiter struct {
+	*rate.Limiter
+
+	mu     sync.Mutex
+	paused time.Time // requests wait until this time
+}
+
+// newLimiter returns a limiter for rps requests per second, or for any number if rps is infinite.
+// Bursts are as large as one second's worth of requests, and at least one.
+func newLimiter(rps float64) *limiter {
+	if math.IsInf(rps, 1) {
+		return &limiter{Limiter: rate.NewLimiter(rate.Inf, 0)}
+	}
+	return &limiter{Limiter: rate.NewLimiter(rate.Limit(rps), int(math.Max(1, math.Floor(rps))))}
+}
+
+// pause keeps requests from being sent until a time.
+func (l *limiter) pause(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// wait waits until the limiter allows a request, or ctx is done.
+// Waiting requests reserve their turns, so that they're served in order.
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	paused := time.Until(l.paused)
+	l.mu.Unlock()
+	r := l.Reserve()
+	delay := max(r.Delay(), paused)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
+
+// operationKey is the context key of the ID of the operation a request calls.
+type operationKey struct{}
+
+// contextWithOperation returns a context for requests that call an operation, for per-operation rate limits.
+func contextWithOperation(ctx context.Context, operationID string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operationID)
+}
+
+// RateLimitTransport is a RoundTripper that waits for each request's share of its rate limits:
+// the overall limit, the limit for its host, and the limit for its operation.
+// A 429 Too Many Requests response with a Retry-After header pauses requests to its host until then.
+type RateLimitTransport struct {
+	Base http.RoundTripper
+
+	overall    *limiter
+	hostLimits map[string]float64
+	operations map[string]*limiter
+
+	mu    sync.Mutex
+	hosts map[string]*limiter
+}
+
+// NewRateLimitTransport returns a transport limiting requests to rps requests per second overall (0 for no limit),
+// and to the budgets set by limits, if any.
+func NewRateLimitTransport(base http.RoundTripper, rps float64, limits *RateLimits) (*RateLimitTransport, error) {
+	t := &RateLimitTransport{Base: base, hosts: make(map[string]*limiter), operations: make(map[string]*limiter)}
+	if rps > 0 {
+		t.overall = newLimiter(rps)
+	}
+	if limits != nil {
+		if err := limits.validate(); err != nil {
+			return nil, err
+		}
+		t.hostLimits = make(map[string]float64, len(limits.Hosts))
+		for host, limit := range limits.Hosts {
+			t.hostLimits[strings.ToLower(host)] = limit
+		}
+		for id, limit := range limits.Operations {
+			t.operations[id] = newLimiter(limit)
+		}
+	}
+	return t, nil
+}
+
+// host returns the limiter for a host. Hosts without limits share an unlimited budget,
+// but get a limiter anyway, so that a 429 response can pause them.
+func (t *RateLimitTransport) host(name string) *limiter {
+	name = strings.ToLower(name)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.hosts[name]
+	if !ok {
+		limit, ok := t.hostLimits[name]
+		if !ok {
+			limit = math.Inf(1)
+		}
+		b = newLimiter(limit)
+		t.hosts[name] = b
+	}
+	return b
+}
+
+// RoundTrip waits for the request's rate limits, sends it,
+// and pauses its host if the API responds that there have been too many requests.
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	ctx := req.Context()
+	host := t.host(req.URL.Hostname())
+	limiters := []*limiter{t.overall, host}
+	if id, ok := ctx.Value(operationKey{}).(string); ok {
+		limiters = append(limiters, t.operations[id])
+	}
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		if err := l.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			host.pause(time.Now().Add(wait))
+		}
+	}
+	return resp, err
+}
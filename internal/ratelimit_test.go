@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/busy" {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer api.Close()
+
+	_, err := NewRateLimitTransport(nil, 0, &RateLimits{Hosts: map[string]float64{"api.example.com": 0}})
+	assert.ErrorContains(t, err, "must be positive")
+
+	transport, err := NewRateLimitTransport(api.Client().Transport, 0, &RateLimits{Operations: map[string]float64{"createReport": 1}})
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	get := func(ctx context.Context, path string) time.Duration {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, api.URL+path, nil)
+		require.NoError(t, err)
+		start := time.Now()
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return time.Since(start)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for range 5 {
+		assert.Less(t, get(ctx, "/pets"), 500*time.Millisecond, "hosts without limits aren't limited")
+	}
+
+	report := contextWithOperation(ctx, "createReport")
+	get(report, "/reports")
+	short, cancelShort := context.WithTimeout(report, 100*time.Millisecond)
+	defer cancelShort()
+	req, err := http.NewRequestWithContext(short, http.MethodGet, api.URL+"/reports", nil)
+	require.NoError(t, err)
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded, "calls wait for their operation's budget")
+
+	get(ctx, "/busy")
+	assert.GreaterOrEqual(t, get(ctx, "/pets"), 500*time.Millisecond, "a 429 response pauses its host")
+}
+
+func TestRetryableClientRetriesTooManyRequests(t *testing.T) {
+	var calls atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer api.Close()
+
+	client, err := RetryableClient(RetryableClientOptions{Retries: 2, Timeout: 5 * time.Second, RPS: 100})
+	require.NoError(t, err)
+	resp, err := client.Post(api.URL, "application/json", nil)
+	require.NoError(t, err, "the last 429 response is returned")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.EqualValues(t, 3, calls.Load(), "429 responses are retried whatever the method")
+}
@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// redactedValue replaces the value of a secret argument wherever arguments are recorded.
+const redactedValue = "[REDACTED]"
+
+// WithSecretArguments marks tool arguments whose values are redacted from logs.
+// Each name is either an argument name, which applies to every tool, or tool.argument for a single tool.
+// Names may be glob patterns, like *token* or createUser.*password*, matching names as path.Match does, ignoring case.
+// Arguments declared with `format: password` are always treated as secret.
+// Secret arguments are still sent to the API as usual.
+func WithSecretArguments(names ...string) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) {
+		if cfg.secretArguments == nil {
+			cfg.secretArguments = make(secretArguments)
+		}
+		for _, name := range names {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.secretArguments[name] = struct{}{}
+			}
+		}
+	}
+}
+
+// secretArguments is a set of argument names, optionally qualified by tool name, whose values are secret.
+type secretArguments map[string]struct{}
+
+// addSchema marks the arguments of a tool that its input schema declares as passwords.
+func (s secretArguments) addSchema(toolName string, schema *jsonschema.Schema) {
+	for name, prop := range schema.Properties {
+		if prop != nil && prop.Format == "password" {
+			s[toolName+"."+name] = struct{}{}
+		}
+	}
+}
+
+// contains reports whether an argument of a tool is secret.
+func (s secretArguments) contains(toolName, name string) bool {
+	return s.matches(name) || s.matches(toolName+"."+name)
+}
+
+// matches reports whether a name, qualified or not, is secret, either listed or matching a pattern.
+// Patterns match qualified names only if they're qualified too, since * doesn't match dots.
+func (s secretArguments) matches(name string) bool {
+	if _, ok := s[name]; ok {
+		return true
+	}
+	for pattern := range s {
+		if strings.ContainsAny(pattern, "*?[") && strings.Count(pattern, ".") == strings.Count(name, ".") {
+			if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// redact returns a copy of a tool's arguments with the values of secret arguments replaced.
+// Unqualified names also match keys of nested objects, such as the fields of a request body passed as a single argument.
+// The original arguments are not modified.
+func (s secretArguments) redact(toolName string, args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	redacted := make(map[string]any, len(args))
+	for name, value := range args {
+		if s.contains(toolName, name) {
+			redacted[name] = redactedValue
+			continue
+		}
+		redacted[name] = s.redactNested(value)
+	}
+	return redacted
+}
+
+func (s secretArguments) redactNested(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, val := range v {
+			if s.matches(key) {
+				redacted[key] = redactedValue
+				continue
+			}
+			redacted[key] = s.redactNested(val)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, val := range v {
+			redacted[i] = s.redactNested(val)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// isSecretName reports whether the value of a header, query parameter, or cookie is redacted:
+// credentials, and anything named like a key, token, secret, or password.
+func isSecretName(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization", "Cookie":
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, word := range []string{"key", "token", "secret", "password", "session", "signature"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// minSecretLength is the length of the shortest credential value that's redacted,
+// so that short values, like a cookie set to "1", don't redact unrelated text.
+const minSecretLength = 8
+
+// secretValues is the set of credentials sent to the API, which are redacted from error messages returned to clients.
+// Credentials are recorded as they're sent, so that those of every auth provider are covered, even ones that change.
+type secretValues struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// add records the value of a credential, along with the token of an Authorization header value like "Bearer token".
+func (s *secretValues) add(value string) {
+	values := []string{value}
+	if scheme, token, ok := strings.Cut(value, " "); ok && !strings.Contains(scheme, "=") {
+		values = append(values, token)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range values {
+		if len(v) >= minSecretLength && !slices.Contains(s.values, v) {
+			s.values = append(s.values, v)
+		}
+	}
+}
+
+// redact replaces the recorded credentials in text, and the values of secret query parameters in URLs.
+func (s *secretValues) redact(text string) string {
+	text = redactURLs(text)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.values {
+		text = strings.ReplaceAll(text, v, redactedValue)
+	}
+	return text
+}
+
+// recordingClient returns a copy of client that records the credentials of its requests in secrets.
+// It's closest to the API, so that it sees credentials as they're sent.
+func recordingClient(client *http.Client, secrets *secretValues) *http.Client {
+	recording := *client
+	recording.Transport = &secretRecorder{Base: client.Transport, Secrets: secrets}
+	return &recording
+}
+
+// secretRecorder is a RoundTripper that records the values of secret headers, query parameters, and cookies.
+type secretRecorder struct {
+	Base    http.RoundTripper
+	Secrets *secretValues
+}
+
+func (t *secretRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for name, values := range req.Header {
+		if name == "Cookie" || !isSecretName(name) {
+			continue
+		}
+		for _, v := range values {
+			t.Secrets.add(v)
+		}
+	}
+	for _, c := range req.Cookies() {
+		t.Secrets.add(c.Value)
+	}
+	for name, values := range req.URL.Query() {
+		if isSecretName(name) {
+			for _, v := range values {
+				t.Secrets.add(v)
+			}
+		}
+	}
+	return base.RoundTrip(req)
+}
+
+// urlPattern matches URLs in text, like those in the messages of errors from an HTTP client.
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// redactURLs replaces the values of secret query parameters, and the passwords of user info, in the URLs in text.
+// URLs without secrets are left as they are.
+func redactURLs(text string) string {
+	return urlPattern.ReplaceAllStringFunc(text, func(s string) string {
+		u, err := url.Parse(s)
+		if err != nil {
+			return s
+		}
+		if redacted, ok := redactURL(u); ok {
+			return redacted
+		}
+		return s
+	})
+}
+
+// redactURL returns a URL with the values of secret query parameters and the password of its user info replaced,
+// reporting whether it had any.
+func redactURL(u *url.URL) (string, bool) {
+	redacted := *u
+	changed := false
+	if query := u.Query(); len(query) > 0 {
+		for name := range query {
+			if isSecretName(name) {
+				query.Set(name, redactedValue)
+				changed = true
+			}
+		}
+		if changed {
+			redacted.RawQuery = query.Encode()
+		}
+	}
+	if _, ok := u.User.Password(); ok {
+		redacted.User = url.UserPassword(u.User.Username(), redactedValue)
+		changed = true
+	}
+	return redacted.String(), changed
+}
+
+// redacted returns a tool handler whose errors, and error results, have credentials and secret query parameters redacted,
+// since error messages and the bodies of error responses may repeat the requests that caused them.
+func (cfg *registerToolsConfig) redacted(handler mcp.ToolHandler) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+		result, err := handler(ctx, req)
+		if err != nil {
+			return result, &redactedError{err: err, message: cfg.secretValues.redact(err.Error())}
+		}
+		if result != nil && result.IsError {
+			for _, c := range result.Content {
+				if text, ok := c.(*mcp.TextContent); ok {
+					text.Text = cfg.secretValues.redact(text.Text)
+				}
+			}
+		}
+		return result, nil
+	}
+}
+
+// redactedError is an error whose message has secrets redacted.
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string { return e.message }
+func (e *redactedError) Unwrap() error { return e.err }
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel"
+)
+
+// WithSpecLocation sets the file path or URL the spec was read from,
+// so that $refs to other files (e.g. ./schemas/pet.yaml) are resolved relative to it.
+// References to files are resolved for specs read from files.
+// Specs read from URLs, or with references to URLs, also need WithRemoteRefs.
+func WithSpecLocation(location string) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.refs.location = location }
+}
+
+// WithRemoteRefs resolves $refs to URLs (e.g. https://example.com/schemas/pet.yaml),
+// and relative references in specs read from URLs, by fetching them.
+func WithRemoteRefs() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.refs.remote = true }
+}
+
+// specReferences decides how references to other documents in a spec are resolved.
+// The zero value only resolves references within the spec.
+type specReferences struct {
+	// location is the file path or URL the spec was read from, if known.
+	location string
+	// remote allows references to be fetched from URLs.
+	remote bool
+}
+
+// documentConfiguration returns the libopenapi configuration that resolves the references.
+// libopenapi's errors and warnings are discarded, since stdout may be the MCP transport;
+// references that can't be resolved are reported when the model is built.
+func (r specReferences) documentConfiguration() (*datamodel.DocumentConfiguration, error) {
+	config := &datamodel.DocumentConfiguration{
+		Logger:                slog.New(slog.DiscardHandler),
+		AllowRemoteReferences: r.remote,
+	}
+	switch {
+	case r.location == "" || IsGitSpec(r.location):
+	case strings.HasPrefix(r.location, "http://") || strings.HasPrefix(r.location, "https://"):
+		if !r.remote {
+			break
+		}
+		u, err := url.Parse(r.location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec URL %q: %w", r.location, err)
+		}
+		// Relative references are resolved against the directory of the spec
+		u.Path = path.Dir(u.Path)
+		u.RawQuery, u.Fragment = "", ""
+		config.BaseURL = u
+	default:
+		abs, err := filepath.Abs(r.location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec path %q: %w", r.location, err)
+		}
+		config.BasePath = filepath.Dir(abs)
+		config.SpecFilePath = filepath.Base(abs)
+		config.AllowFileReferences = true
+	}
+	return config, nil
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const multiFileSpec = `
+openapi: 3.1.0
+info: {title: Pet API, version: 1.0.0}
+servers: [{url: https://api.example.com}]
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: ./schemas/pet.yaml
+      responses: {"201": {description: Created}}
+`
+
+var multiFileSchemas = map[string]string{
+	"schemas/pet.yaml": `
+type: object
+required: [name]
+properties:
+  name: {type: string}
+  tag: {$ref: ./tag.yaml}
+`,
+	"schemas/tag.yaml": `
+type: string
+description: A tag.
+`,
+}
+
+func TestSpecReferences(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assertResolved := func(t *testing.T, tools []ToolSummary) {
+		t.Helper()
+		require.Len(t, tools, 1)
+		assert.Equal(t, []string{"name"}, tools[0].InputSchema.Required)
+		require.Contains(t, tools[0].InputSchema.Properties, "tag")
+		assert.Equal(t, "A tag.", tools[0].InputSchema.Properties["tag"].Description)
+	}
+
+	t.Run("files", func(t *testing.T) {
+		dir := t.TempDir()
+		specPath := filepath.Join(dir, "openapi.yaml")
+		require.NoError(t, os.WriteFile(specPath, []byte(multiFileSpec), 0o644))
+		for name, schema := range multiFileSchemas {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(schema), 0o644))
+		}
+
+		tools, err := ListTools(ctx, []byte(multiFileSpec), WithSpecLocation(specPath))
+		require.NoError(t, err)
+		assertResolved(t, tools)
+
+		_, err = ListTools(ctx, []byte(multiFileSpec))
+		assert.ErrorContains(t, err, "./schemas/pet.yaml", "references can't be resolved without the spec's location")
+	})
+
+	t.Run("URLs", func(t *testing.T) {
+		api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			schema, ok := multiFileSchemas[r.URL.Path[len("/specs/"):]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/yaml")
+			w.Write([]byte(schema))
+		}))
+		defer api.Close()
+		specURL := api.URL + "/specs/openapi.yaml"
+
+		tools, err := ListTools(ctx, []byte(multiFileSpec), WithSpecLocation(specURL), WithRemoteRefs())
+		require.NoError(t, err)
+		assertResolved(t, tools)
+
+		_, err = ListTools(ctx, []byte(multiFileSpec), WithSpecLocation(specURL))
+		assert.Error(t, err, "remote references are only fetched when allowed")
+	})
+}
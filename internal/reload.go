@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Reloader registers tools for successive versions of a spec,
+// replacing the tools, prompts, and resources registered for the previous version.
+// Clients are notified of the changes with list_changed notifications.
+type Reloader struct {
+	server *mcp.Server
+	client *http.Client
+	opts   []RegisterToolsOption
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	spec    []byte
+	sum     [sha256.Size]byte
+	current atomic.Pointer[registration]
+}
+
+// NewReloader returns a Reloader that registers tools on server, using client and opts as RegisterTools does.
+func NewReloader(server *mcp.Server, client *http.Client, opts ...RegisterToolsOption) *Reloader {
+	cfg := &registerToolsConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+	if cfg.logger == nil {
+		cfg.logger = slog.New(slog.DiscardHandler)
+	}
+	r := &Reloader{server: server, client: client, opts: opts, logger: cfg.logger}
+	server.AddReceivingMiddleware(r.middleware)
+	return r
+}
+
+// Load registers tools for a version of the spec, removing those of the previous version that no longer exist.
+// If the spec is invalid, the previous version stays in place and an error is returned.
+func (r *Reloader) Load(specData []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Check the spec against a scratch server, so that a bad version doesn't leave tools half-registered
+	if _, err := registerTools(mcp.NewServer(&mcp.Implementation{Name: "emcee"}, nil), specData, r.client, r.opts...); err != nil {
+		return err
+	}
+	reg, err := registerTools(r.server, specData, r.client, r.opts...)
+	if err != nil {
+		return err
+	}
+	r.replace(reg)
+	r.spec = specData
+	r.sum = sha256.Sum256(specData)
+	return nil
+}
+
+// Reconfigure registers tools for the current version of the spec again with a new configuration,
+// which later versions are loaded with too.
+// If the configuration can't be applied, the previous one stays in place and an error is returned.
+func (r *Reloader) Reconfigure(config *Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current.Load() == nil {
+		return fmt.Errorf("no spec is loaded")
+	}
+	opts := append(slices.Clip(r.opts), WithConfig(config))
+	if _, err := registerTools(mcp.NewServer(&mcp.Implementation{Name: "emcee"}, nil), r.spec, r.client, opts...); err != nil {
+		return err
+	}
+	reg, err := registerTools(r.server, r.spec, r.client, opts...)
+	if err != nil {
+		return err
+	}
+	r.opts = opts
+	r.replace(reg)
+	return nil
+}
+
+// replace makes reg the current registration, removing what the previous one registered that reg doesn't.
+func (r *Reloader) replace(reg *registration) {
+	if prev := r.current.Swap(reg); prev != nil {
+		r.server.RemoveTools(removed(prev.tools, reg.tools)...)
+		r.server.RemovePrompts(removed(prev.prompts, reg.prompts)...)
+		r.server.RemoveResources(removed(prev.resources, reg.resources)...)
+		r.server.RemoveResourceTemplates(removed(prev.resourceTemplates, reg.resourceTemplates)...)
+	}
+}
+
+// removed returns the names in prev that aren't in next.
+func removed(prev, next []string) []string {
+	var names []string
+	for _, name := range prev {
+		if !slices.Contains(next, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// middleware runs the receiving middleware of the current version of the spec.
+func (r *Reloader) middleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		h := next
+		if reg := r.current.Load(); reg != nil {
+			for _, mw := range slices.Backward(reg.middleware) {
+				h = mw(h)
+			}
+		}
+		return h(ctx, method, req)
+	}
+}
+
+// loaded reports whether a spec is the version last loaded.
+func (r *Reloader) loaded(sum [sha256.Size]byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current.Load() != nil && sum == r.sum
+}
+
+// Watch reads the spec every interval until ctx is done, and loads it when it changes.
+// Errors reading or loading the spec are logged, and the current version stays in place.
+// An invalid version is only reported once.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, read func(context.Context) ([]byte, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var invalid [sha256.Size]byte
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		specData, err := read(ctx)
+		if err != nil {
+			r.logger.Warn("error reading spec for reload", "error", err)
+			continue
+		}
+		sum := sha256.Sum256(specData)
+		if sum == invalid || r.loaded(sum) {
+			continue
+		}
+		if err := r.Load(specData); err != nil {
+			r.logger.Warn("error reloading spec", "error", err)
+			invalid = sum
+			continue
+		}
+		r.logger.Info("reloaded spec")
+	}
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reloadTestSpec(paths string) []byte {
+	return []byte(fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {%s}
+}`, paths))
+}
+
+func TestReloader(t *testing.T) {
+	v1 := reloadTestSpec(`
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}},
+    "/owners": {"get": {"operationId": "listOwners", "responses": {"200": {"description": "OK"}}}}`)
+	v2 := reloadTestSpec(`
+    "/pets": {"get": {
+      "operationId": "listPets",
+      "parameters": [{"name": "species", "in": "query", "required": true, "schema": {"type": "string"}}],
+      "responses": {"200": {"description": "OK"}}
+    }},
+    "/stores": {"get": {"operationId": "listStores", "responses": {"200": {"description": "OK"}}}}`)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	reloader := NewReloader(server, nil)
+	require.NoError(t, reloader.Load(v1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+	var notifications atomic.Int32
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "dev"}, &mcp.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *mcp.ClientRequest[*mcp.ToolListChangedParams]) {
+			notifications.Add(1)
+		},
+	})
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer clientSession.Close()
+
+	toolNames := func() []string {
+		tools, err := clientSession.ListTools(ctx, nil)
+		require.NoError(t, err)
+		var names []string
+		for _, tool := range tools.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"listPets", "listOwners"}, toolNames())
+
+	require.NoError(t, reloader.Load(v2))
+	assert.ElementsMatch(t, []string{"listPets", "listStores"}, toolNames())
+	assert.Eventually(t, func() bool { return notifications.Load() > 0 }, time.Second, 10*time.Millisecond)
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets", Arguments: map[string]any{}})
+	assert.ErrorContains(t, err, "species", "arguments are validated against the new version")
+
+	assert.Error(t, reloader.Load([]byte(`{"openapi": "3.1.0"}`)))
+	assert.ElementsMatch(t, []string{"listPets", "listStores"}, toolNames(), "an invalid version is ignored")
+}
+
+func TestReloaderWatch(t *testing.T) {
+	var version atomic.Int32
+	specs := [][]byte{
+		reloadTestSpec(`"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}`),
+		reloadTestSpec(`"/stores": {"get": {"operationId": "listStores", "responses": {"200": {"description": "OK"}}}}`),
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	reloader := NewReloader(server, nil)
+	require.NoError(t, reloader.Load(specs[0]))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond, func(context.Context) ([]byte, error) {
+		return specs[version.Load()], nil
+	})
+
+	clientSession := connectTestClient(t, ctx, server)
+	version.Store(1)
+	assert.Eventually(t, func() bool {
+		tools, err := clientSession.ListTools(ctx, nil)
+		return err == nil && len(tools.Tools) == 1 && tools.Tools[0].Name == "listStores"
+	}, 2*time.Second, 10*time.Millisecond)
+}
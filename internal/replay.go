@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxRecentRequests is the number of requests per connection whose responses are kept for replay.
+const maxRecentRequests = 64
+
+// recentRequests remembers the most recent requests on a connection, and their responses,
+// so that a retransmitted request can be answered without being handled again.
+type recentRequests struct {
+	mu      sync.Mutex
+	entries map[jsonrpc.ID]*recentRequest
+	order   []jsonrpc.ID
+}
+
+type recentRequest struct {
+	method   string
+	params   json.RawMessage
+	response *jsonrpc.Response // nil while the request is in flight
+}
+
+func newRecentRequests() *recentRequests {
+	return &recentRequests{entries: make(map[jsonrpc.ID]*recentRequest)}
+}
+
+// lookup returns the earlier request with the same ID, method, and params as req, if there is one.
+// Otherwise, req is remembered, evicting the oldest request if necessary.
+func (r *recentRequests) lookup(req *jsonrpc.Request) (*recentRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.entries[req.ID]; ok && entry.method == req.Method && bytes.Equal(entry.params, req.Params) {
+		return entry, true
+	}
+	if _, ok := r.entries[req.ID]; !ok {
+		if len(r.order) == maxRecentRequests {
+			delete(r.entries, r.order[0])
+			r.order = r.order[1:]
+		}
+		r.order = append(r.order, req.ID)
+	}
+	r.entries[req.ID] = &recentRequest{method: req.Method, params: req.Params}
+	return nil, false
+}
+
+// respond records the response to a remembered request.
+func (r *recentRequests) respond(resp *jsonrpc.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.entries[resp.ID]; ok && entry.response == nil {
+		entry.response = resp
+	}
+}
+
+// cached returns the response to a remembered request, if it has been answered.
+func (r *recentRequests) cached(entry *recentRequest) (*jsonrpc.Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return entry.response, entry.response != nil
+}
+
+// ReadOnlyTools identifies calls to a server's read-only tools,
+// whose responses can be replayed when a client retransmits a request.
+// Tools are looked up through an in-process client session connected to the server,
+// so tools added or removed after the server starts are taken into account.
+type ReadOnlyTools struct {
+	server *mcp.Server
+
+	mu      sync.Mutex
+	session *mcp.ClientSession
+}
+
+// NewReadOnlyTools returns a ReadOnlyTools for the tools of server.
+func NewReadOnlyTools(server *mcp.Server) *ReadOnlyTools {
+	return &ReadOnlyTools{server: server}
+}
+
+// Replayable reports whether a request is a call to a tool annotated as read-only.
+// It's suitable for use as Stdio.Replayable.
+func (r *ReadOnlyTools) Replayable(ctx context.Context, method string, params json.RawMessage) bool {
+	if method != "tools/call" {
+		return false
+	}
+	var call struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil || call.Name == "" {
+		return false
+	}
+	session, err := r.connect(ctx)
+	if err != nil {
+		return false
+	}
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return false
+		}
+		if tool.Name == call.Name {
+			return tool.Annotations != nil && tool.Annotations.ReadOnlyHint
+		}
+	}
+	return false
+}
+
+// connect returns the client session used to look up tools, connecting it on first use.
+func (r *ReadOnlyTools) connect(ctx context.Context) (*mcp.ClientSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.session != nil {
+		return r.session, nil
+	}
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	if _, err := r.server.Connect(ctx, serverTransport, nil); err != nil {
+		return nil, fmt.Errorf("error connecting tool lookup session: %w", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "emcee-replay", Version: "dev"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting tool lookup session: %w", err)
+	}
+	r.session = session
+	return session, nil
+}
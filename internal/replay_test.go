@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdioReplay(t *testing.T) {
+	in, inWriter := io.Pipe()
+	outReader, out := io.Pipe()
+	stdio := &Stdio{In: in, Out: out, Err: io.Discard}
+	stdio.Replayable = func(ctx context.Context, method string, params json.RawMessage) bool {
+		return method == "tools/call"
+	}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	lines := bufio.NewScanner(outReader)
+	send := func(msg string) {
+		go func() { _, _ = inWriter.Write([]byte(msg + "\n")) }()
+	}
+	// Read continuously, as the server does, since retransmitted requests are answered while reading
+	requests := make(chan *jsonrpc.Request)
+	go func() {
+		for {
+			msg, err := conn.Read(context.Background())
+			if err != nil {
+				close(requests)
+				return
+			}
+			requests <- msg.(*jsonrpc.Request)
+		}
+	}()
+	read := func() *jsonrpc.Request {
+		req, ok := <-requests
+		require.True(t, ok)
+		return req
+	}
+	respond := func(req *jsonrpc.Request, result string) {
+		go func() {
+			assert.NoError(t, conn.Write(context.Background(), &jsonrpc.Response{ID: req.ID, Result: json.RawMessage(result)}))
+		}()
+		require.True(t, lines.Scan())
+	}
+
+	call := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"getPet","arguments":{"petId":"1"}}}`
+	send(call)
+	respond(read(), `{"content":[{"type":"text","text":"Fido"}]}`)
+
+	// A retransmitted request is answered with the original response, without reaching the server
+	send(call)
+	require.True(t, lines.Scan())
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"Fido"}]}}`, lines.Text())
+
+	// A reused ID with different params is a new request
+	send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"getPet","arguments":{"petId":"2"}}}`)
+	assert.Equal(t, "tools/call", read().Method)
+
+	// Requests that aren't replayable are handled again
+	send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	respond(read(), `{}`)
+	send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	assert.Equal(t, "ping", read().Method)
+}
+
+func TestReadOnlyTools(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {
+      "get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}},
+      "post": {"operationId": "createPet", "responses": {"201": {"description": "Created"}}}
+    }
+  }
+}`
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tools := NewReadOnlyTools(server)
+	assert.True(t, tools.Replayable(ctx, "tools/call", json.RawMessage(`{"name":"listPets"}`)))
+	assert.False(t, tools.Replayable(ctx, "tools/call", json.RawMessage(`{"name":"createPet"}`)))
+	assert.False(t, tools.Replayable(ctx, "tools/call", json.RawMessage(`{"name":"missing"}`)))
+	assert.False(t, tools.Replayable(ctx, "resources/read", json.RawMessage(`{"uri":"api://pets"}`)))
+}
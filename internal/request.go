@@ -0,0 +1,591 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// endpoint binds an OpenAPI operation to the base URL and path template it is served from.
+type endpoint struct {
+	baseURL  string
+	path     string
+	method   string
+	pathItem *v3.PathItem
+	op       *v3.Operation
+	cfg      *registerToolsConfig
+}
+
+// newRequest builds the upstream HTTP request for a tool call with the given arguments.
+func (e *endpoint) newRequest(ctx context.Context, args map[string]any) (*http.Request, error) {
+	args = e.withFixedArguments(args)
+
+	// Build URL
+	base, err := url.Parse(e.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	u := &url.URL{Scheme: base.Scheme, Host: base.Host, RawPath: e.requestPath(base.EscapedPath())}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+
+	q := url.Values{}
+	headers := make(http.Header)
+	// Track parameter names applied to URL/query/headers
+	usedParamNames := make(map[string]struct{})
+
+	// Path item parameters
+	if e.pathItem.Parameters != nil {
+		for _, param := range e.pathItem.Parameters {
+			if err := applyParam(param, args, u, q, headers, e.cfg); err != nil {
+				return nil, err
+			}
+			if param != nil {
+				usedParamNames[param.Name] = struct{}{}
+			}
+		}
+	}
+	// Operation parameters
+	if e.op.Parameters != nil {
+		for _, param := range e.op.Parameters {
+			if err := applyParam(param, args, u, q, headers, e.cfg); err != nil {
+				return nil, err
+			}
+			if param != nil {
+				usedParamNames[param.Name] = struct{}{}
+			}
+		}
+	}
+
+	// Request body
+	var body any
+	rb := operationRequestBody(e.op)
+	if rb != nil {
+		bs := rb.schema
+		if isFlattenableBody(bs) {
+			bodyParams := make(map[string]any)
+			for prop := bs.Properties.First(); prop != nil; prop = prop.Next() {
+				name := prop.Key()
+				// Skip colliding names so path/query/header take precedence
+				if _, exists := usedParamNames[name]; exists {
+					continue
+				}
+				propSchema := prop.Value().Schema()
+				// Skip readOnly properties in request body
+				if propSchema != nil && propSchema.ReadOnly != nil && *propSchema.ReadOnly {
+					continue
+				}
+				if v, ok := args[name]; ok {
+					bodyParams[name] = v
+				}
+			}
+			// Pass through undeclared arguments when the body allows additional properties
+			if additionalBodyProperties(bs) != nil {
+				for name, v := range args {
+					if _, exists := usedParamNames[name]; exists {
+						continue
+					}
+					if _, declared := bs.Properties.Get(name); declared {
+						continue
+					}
+					bodyParams[name] = v
+				}
+			}
+			if len(bodyParams) > 0 {
+				body = bodyParams
+			}
+		} else if name := bodyArgumentName(usedParamNames); name != "" {
+			if v, ok := args[name]; ok {
+				body = e.withFixedBody(v, bs)
+			}
+		}
+	}
+
+	if len(q) > 0 {
+		u.RawQuery = q.Encode()
+	}
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", u.RawPath, err)
+	}
+
+	var reqBody io.Reader
+	var contentType string
+	if body != nil {
+		reqBody, contentType, err = rb.encode(body, e.cfg.queryObjectStyle)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Credentials the config sets for the operation are applied as the request is sent, in place of the usual ones
+	if provider, ok := e.cfg.operationAuths.provider(e.path, e.op.OperationId); ok {
+		ctx = contextWithOperationAuth(ctx, provider)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, e.method, u.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range headers {
+		for _, v := range vs {
+			hreq.Header.Add(k, v)
+		}
+	}
+	if reqBody != nil {
+		hreq.Header.Set("Content-Type", contentType)
+	}
+	return hreq, nil
+}
+
+// applyParam adds the value of a parameter in args to the request's escaped path, query, headers, or cookies.
+// Values are sent as UTF-8, escaped as needed; header values with control characters are an error.
+func applyParam(param *v3.Parameter, args map[string]any, u *url.URL, q url.Values, headers http.Header, cfg *registerToolsConfig) error {
+	if param == nil {
+		return nil
+	}
+	value, ok := args[param.Name]
+	if !ok {
+		return nil
+	}
+	// Parameters described by content instead of a schema are serialized as JSON
+	if param.Schema == nil && param.Content != nil {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for parameter %q: %w", param.Name, err)
+		}
+		value = string(b)
+	}
+	switch param.In {
+	case "path":
+		escape := pathSegmentEscape
+		if cfg.rawPaths {
+			escape = encodedPathSegmentEscape
+		}
+		u.RawPath = strings.ReplaceAll(u.RawPath, "{"+param.Name+"}", escape(styledValue(param, value)))
+	case "query":
+		setQueryParam(q, param, value, cfg.queryObjectStyle)
+	case "header":
+		val := styledValue(param, value)
+		if !validHeaderValue(val) {
+			return fmt.Errorf("invalid value for header parameter %q: control characters aren't allowed", param.Name)
+		}
+		headers.Add(param.Name, val)
+	case "cookie":
+		// Values that can't be sent as is, like those with non-ASCII characters, are percent-encoded
+		c := &http.Cookie{Name: param.Name, Value: styledValue(param, value)}
+		if c.Valid() != nil {
+			c.Value = url.PathEscape(c.Value)
+		}
+		// Cookies share a single header, like those added by http.Request.AddCookie
+		cookie := c.String()
+		if cookie == "" {
+			return nil
+		}
+		if existing := headers.Get("Cookie"); existing != "" {
+			cookie = existing + "; " + cookie
+		}
+		headers.Set("Cookie", cookie)
+	}
+	return nil
+}
+
+// styledValue serializes the value of a path, header, or cookie parameter as its style and explode declare:
+// simple (a,b), label (.a.b), or matrix (;id=a,b).
+// Objects are written as alternating property names and values (R,100,G,200),
+// or as name=value pairs when exploded (R=100,G=200).
+func styledValue(param *v3.Parameter, value any) string {
+	explode := param.IsExploded()
+	var items []string
+	_, isObject := value.(map[string]any)
+	switch v := value.(type) {
+	case []any:
+		for _, it := range v {
+			items = append(items, paramValueString(it))
+		}
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			if v[key] == nil {
+				continue
+			}
+			if explode {
+				items = append(items, key+"="+paramValueString(v[key]))
+			} else {
+				items = append(items, key, paramValueString(v[key]))
+			}
+		}
+	default:
+		items = []string{paramValueString(value)}
+	}
+
+	switch param.Style {
+	case "label":
+		if explode {
+			return "." + strings.Join(items, ".")
+		}
+		return "." + strings.Join(items, ",")
+	case "matrix":
+		if !explode {
+			return ";" + param.Name + "=" + strings.Join(items, ",")
+		}
+		if isObject {
+			return ";" + strings.Join(items, ";")
+		}
+		var b strings.Builder
+		for _, item := range items {
+			b.WriteString(";" + param.Name + "=" + item)
+		}
+		return b.String()
+	}
+	return strings.Join(items, ",")
+}
+
+// paramValueString returns a scalar argument as it's written in a parameter.
+// Numbers are written in full, since JSON numbers are decoded as floats (12345678, not 1.2345678e+07),
+// and nested arrays and objects are written as JSON.
+func paramValueString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case []any, map[string]any:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// validHeaderValue reports whether s can be sent as a header value.
+// Control characters other than tab aren't allowed, but non-ASCII characters are sent as UTF-8.
+func validHeaderValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < ' ' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// setQueryParam adds a query parameter to q, serialized as its style and explode declare:
+// form (the default), spaceDelimited, pipeDelimited, or deepObject.
+// Exploded arrays, as with the default form style, are sent as repeated parameters (ids=1&ids=2),
+// and other arrays are joined with the style's delimiter (ids=1,2 or ids=1|2).
+// Objects of parameters that don't declare a style or explode are serialized using objectStyle.
+func setQueryParam(q url.Values, param *v3.Parameter, value any, objectStyle QueryObjectStyle) {
+	// Only the form style is exploded by default
+	explode := param.IsExploded() || param.Explode == nil && (param.Style == "" || param.Style == "form")
+	delimiter := ","
+	switch param.Style {
+	case "spaceDelimited":
+		delimiter = " "
+	case "pipeDelimited":
+		delimiter = "|"
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if !explode {
+			q.Set(param.Name, queryValueString(v, delimiter))
+			return
+		}
+		q.Del(param.Name)
+		for _, it := range v {
+			q.Add(param.Name, queryValueString(it, ","))
+		}
+	case map[string]any:
+		switch {
+		case param.Style == "deepObject":
+			setQueryObject(q, param.Name, v, QueryObjectStyleBracket)
+		case param.Style == "" && param.Explode == nil:
+			setQueryValue(q, param.Name, v, objectStyle)
+		case explode:
+			// Exploded objects send each property as a parameter of its own (name=Ada&age=3)
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				if v[key] != nil {
+					q.Set(key, queryValueString(v[key], ","))
+				}
+			}
+		default:
+			// Other objects alternate property names and values (filter=name,Ada,age,3)
+			var parts []string
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				if v[key] != nil {
+					parts = append(parts, key, queryValueString(v[key], ","))
+				}
+			}
+			q.Set(param.Name, strings.Join(parts, delimiter))
+		}
+	default:
+		q.Set(param.Name, paramValueString(value))
+	}
+}
+
+// queryValueString returns a value as it's written in a query parameter,
+// with the items of arrays joined by delimiter.
+func queryValueString(value any, delimiter string) string {
+	v, ok := value.([]any)
+	if !ok {
+		return paramValueString(value)
+	}
+	strs := make([]string, len(v))
+	for i, it := range v {
+		strs[i] = paramValueString(it)
+	}
+	return strings.Join(strs, delimiter)
+}
+
+// setQueryValue adds a query parameter or form field to q.
+// Objects are serialized using objectStyle, and array values are joined with commas.
+func setQueryValue(q url.Values, name string, value any, objectStyle QueryObjectStyle) {
+	switch v := value.(type) {
+	case map[string]any:
+		style := objectStyle
+		if style == "" {
+			style = QueryObjectStyleBracket
+		}
+		setQueryObject(q, name, v, style)
+	case []any:
+		q.Set(name, queryValueString(v, ","))
+	default:
+		q.Set(name, paramValueString(value))
+	}
+}
+
+// QueryObjectStyle is a convention for serializing object-valued query parameters.
+type QueryObjectStyle string
+
+const (
+	// QueryObjectStyleBracket serializes objects using bracket notation (filter[name]=x),
+	// which is equivalent to OpenAPI's deepObject style.
+	QueryObjectStyleBracket QueryObjectStyle = "bracket"
+	// QueryObjectStyleDot serializes objects using dotted notation (filter.name=x).
+	QueryObjectStyleDot QueryObjectStyle = "dot"
+)
+
+// ParseQueryObjectStyle parses the name of a query object serialization style.
+func ParseQueryObjectStyle(s string) (QueryObjectStyle, error) {
+	switch style := QueryObjectStyle(s); style {
+	case QueryObjectStyleBracket, QueryObjectStyleDot:
+		return style, nil
+	}
+	return "", fmt.Errorf("unknown query object style %q (expected %q or %q)", s, QueryObjectStyleBracket, QueryObjectStyleDot)
+}
+
+// setQueryObject adds the properties of an object-valued query parameter to q,
+// recursing into nested objects and joining array values with commas.
+func setQueryObject(q url.Values, prefix string, obj map[string]any, style QueryObjectStyle) {
+	for key, value := range obj {
+		var name string
+		switch style {
+		case QueryObjectStyleDot:
+			name = prefix + "." + key
+		default:
+			name = prefix + "[" + key + "]"
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			setQueryObject(q, name, v, style)
+		case []any:
+			q.Set(name, queryValueString(v, ","))
+		case nil:
+			continue
+		default:
+			q.Set(name, paramValueString(value))
+		}
+	}
+}
+
+// WithTrailingSlashes keeps the trailing slashes of paths in the spec, like /pets/,
+// for APIs that distinguish them from the same paths without.
+// Otherwise, paths are cleaned, which removes them.
+func WithTrailingSlashes() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.trailingSlashes = true }
+}
+
+// WithRawPaths sends paths as the spec writes them, for APIs that are strict about their form:
+// repeated slashes, dot segments, and trailing slashes are kept,
+// and percent-encoded characters in path parameter values, like %2F, are sent as they are instead of being escaped again.
+func WithRawPaths() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.rawPaths = true }
+}
+
+// requestPath returns the escaped path of a request to the endpoint, beneath the escaped path of its base URL,
+// with its parameters left to be substituted.
+func (e *endpoint) requestPath(basePath string) string {
+	p := e.path
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	if e.cfg.rawPaths {
+		return strings.TrimSuffix(basePath, "/") + p
+	}
+	trailingSlash := e.cfg.trailingSlashes && len(p) > 1 && strings.HasSuffix(p, "/")
+	p = path.Clean(p)
+	if trailingSlash {
+		p += "/"
+	}
+	if basePath != "" {
+		p = strings.TrimSuffix(path.Clean(basePath), "/") + p
+	}
+	return p
+}
+
+// withFixedArguments returns a call's arguments with the values the configuration fixes in place of those the call passed.
+// Fixed arguments the operation doesn't declare are removed, so that they can't be sent as additional body properties.
+func (e *endpoint) withFixedArguments(args map[string]any) map[string]any {
+	if e.cfg == nil || e.cfg.config == nil || len(e.cfg.config.FixedArguments) == 0 {
+		return args
+	}
+	fixed := make(map[string]any, len(args))
+	maps.Copy(fixed, args)
+	for name, value := range e.cfg.config.FixedArguments {
+		if e.declares(name) {
+			fixed[name] = value
+		} else {
+			delete(fixed, name)
+		}
+	}
+	return fixed
+}
+
+// withFixedBody returns a request body passed whole in a single argument with the values the configuration fixes
+// in place of those the call passed, at the top level of an object body, or of each item of an array body.
+// Fixed arguments the body's schema doesn't declare there are removed, as they are from flattened bodies.
+func (e *endpoint) withFixedBody(body any, s *base.Schema) any {
+	if e.cfg == nil || e.cfg.config == nil || len(e.cfg.config.FixedArguments) == 0 {
+		return body
+	}
+	switch v := body.(type) {
+	case map[string]any:
+		fixed := maps.Clone(v)
+		for name, value := range e.cfg.config.FixedArguments {
+			if declaresProperty(s, name) {
+				fixed[name] = value
+			} else {
+				delete(fixed, name)
+			}
+		}
+		return fixed
+	case []any:
+		items := itemsSchema(s)
+		fixed := make([]any, len(v))
+		for i, item := range v {
+			fixed[i] = e.withFixedBody(item, items)
+		}
+		return fixed
+	}
+	return body
+}
+
+// declares reports whether an operation has a parameter or request body property with a name.
+// Properties of request bodies passed whole in a single argument count, as do those of their items.
+func (e *endpoint) declares(name string) bool {
+	for _, params := range [][]*v3.Parameter{e.pathItem.Parameters, e.op.Parameters} {
+		if slices.ContainsFunc(params, func(param *v3.Parameter) bool { return param != nil && param.Name == name }) {
+			return true
+		}
+	}
+	rb := operationRequestBody(e.op)
+	if rb == nil {
+		return false
+	}
+	if isFlattenableBody(rb.schema) {
+		_, ok := rb.schema.Properties.Get(name)
+		return ok
+	}
+	s := rb.schema
+	for depth := 0; s != nil && depth <= maxSchemaDepth; depth++ {
+		if declaresProperty(s, name) {
+			return true
+		}
+		s = itemsSchema(s)
+	}
+	return false
+}
+
+// encodedPathSegmentEscape is like pathSegmentEscape, but leaves percent-encoded characters as they are.
+func encodedPathSegmentEscape(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '%')
+		if i < 0 {
+			b.WriteString(pathSegmentEscape(s))
+			break
+		}
+		b.WriteString(pathSegmentEscape(s[:i]))
+		if i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			b.WriteString(s[i : i+3])
+			s = s[i+3:]
+		} else {
+			b.WriteString("%25")
+			s = s[i+1:]
+		}
+	}
+	return b.String()
+}
+
+// isHex reports whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// pathSegmentEscape preserves valid URL segment characters per RFC 3986.
+func pathSegmentEscape(s string) string {
+	hexCount := 0
+	for i := 0; i < len(s); i++ {
+		if shouldEscape(s[i]) {
+			hexCount++
+		}
+	}
+	if hexCount == 0 {
+		return s
+	}
+	var buf [3]byte
+	t := make([]byte, len(s)+2*hexCount)
+	j := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if shouldEscape(c) {
+			buf[0] = '%'
+			buf[1] = "0123456789ABCDEF"[c>>4]
+			buf[2] = "0123456789ABCDEF"[c&15]
+			t[j] = buf[0]
+			t[j+1] = buf[1]
+			t[j+2] = buf[2]
+			j += 3
+		} else {
+			t[j] = c
+			j++
+		}
+	}
+	return string(t)
+}
+
+func shouldEscape(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return false
+	}
+	switch c {
+	case '-', '.', '_', '~':
+		return false
+	case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':', '@':
+		return false
+	}
+	return true
+}
@@ -0,0 +1,494 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pb33f/libopenapi"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetQueryObject(t *testing.T) {
+	obj := map[string]any{
+		"name":  "x",
+		"owner": map[string]any{"id": "42"},
+		"tags":  []any{"a", "b"},
+		"skip":  nil,
+	}
+
+	q := url.Values{}
+	setQueryObject(q, "filter", obj, QueryObjectStyleBracket)
+	assert.Equal(t, url.Values{
+		"filter[name]":      {"x"},
+		"filter[owner][id]": {"42"},
+		"filter[tags]":      {"a,b"},
+	}, q)
+
+	q = url.Values{}
+	setQueryObject(q, "filter", obj, QueryObjectStyleDot)
+	assert.Equal(t, url.Values{
+		"filter.name":     {"x"},
+		"filter.owner.id": {"42"},
+		"filter.tags":     {"a,b"},
+	}, q)
+}
+
+func TestSetQueryParam(t *testing.T) {
+	explode, noExplode := true, false
+	tags := []any{"a", "b"}
+	color := map[string]any{"R": 100, "G": 200}
+	tests := []struct {
+		name  string
+		param *v3.Parameter
+		value any
+		want  string
+	}{
+		{name: "form arrays are exploded by default", param: &v3.Parameter{Name: "tag"}, value: tags, want: "tag=a&tag=b"},
+		{name: "form arrays without explode", param: &v3.Parameter{Name: "tag", Style: "form", Explode: &noExplode}, value: tags, want: "tag=a%2Cb"},
+		{name: "space delimited arrays", param: &v3.Parameter{Name: "tag", Style: "spaceDelimited"}, value: tags, want: "tag=a+b"},
+		{name: "pipe delimited arrays", param: &v3.Parameter{Name: "tag", Style: "pipeDelimited"}, value: tags, want: "tag=a%7Cb"},
+		{name: "exploded pipe delimited arrays", param: &v3.Parameter{Name: "tag", Style: "pipeDelimited", Explode: &explode}, value: tags, want: "tag=a&tag=b"},
+		{name: "deep objects", param: &v3.Parameter{Name: "color", Style: "deepObject", Explode: &explode}, value: color, want: "color%5BG%5D=200&color%5BR%5D=100"},
+		{name: "exploded form objects", param: &v3.Parameter{Name: "color", Style: "form", Explode: &explode}, value: color, want: "G=200&R=100"},
+		{name: "form objects without explode", param: &v3.Parameter{Name: "color", Style: "form", Explode: &noExplode}, value: color, want: "color=G%2C200%2CR%2C100"},
+		{name: "objects without a style use the object style", param: &v3.Parameter{Name: "color"}, value: color, want: "color.G=200&color.R=100"},
+		{name: "primitives", param: &v3.Parameter{Name: "limit", Style: "form"}, value: 10, want: "limit=10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{}
+			setQueryParam(q, tt.param, tt.value, QueryObjectStyleDot)
+			assert.Equal(t, tt.want, q.Encode())
+		})
+	}
+}
+
+func TestParseQueryObjectStyle(t *testing.T) {
+	style, err := ParseQueryObjectStyle("dot")
+	require.NoError(t, err)
+	assert.Equal(t, QueryObjectStyleDot, style)
+
+	_, err = ParseQueryObjectStyle("pipe")
+	assert.Error(t, err)
+}
+
+func TestRegisterToolsQueryObjectStyle(t *testing.T) {
+	observed := make(chan url.Values, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observed <- r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Search API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/items": {
+      "get": {
+        "operationId": "listItems",
+        "parameters": [{"name": "filter", "in": "query", "schema": {"type": "object"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	for _, tt := range []struct {
+		opts []RegisterToolsOption
+		want url.Values
+	}{
+		{want: url.Values{"filter[name]": {"x"}}},
+		{opts: []RegisterToolsOption{WithQueryObjectStyle(QueryObjectStyleDot)}, want: url.Values{"filter.name": {"x"}}},
+	} {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), tt.opts...))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		clientSession := connectTestClient(t, ctx, server)
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+			Name:      "listItems",
+			Arguments: map[string]any{"filter": map[string]any{"name": "x"}},
+		})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Equal(t, tt.want, <-observed)
+	}
+}
+
+func TestRegisterToolsPaths(t *testing.T) {
+	observed := make(chan string, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observed <- r.RequestURI
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Project API", "version": "1.0.0"},
+  "servers": [{"url": "%s/api/v4/"}],
+  "paths": {
+    "/projects/{id}/": {
+      "get": {
+        "operationId": "getProject",
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/files//{name}": {
+      "get": {
+        "operationId": "getFile",
+        "parameters": [{"name": "name", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	for _, tt := range []struct {
+		opts []RegisterToolsOption
+		tool string
+		id   string
+		want string
+	}{
+		{tool: "getProject", id: "group/project", want: "/api/v4/projects/group%2Fproject"},
+		{tool: "getProject", id: "group%2Fproject", want: "/api/v4/projects/group%252Fproject"},
+		{tool: "getFile", id: "a b", want: "/api/v4/files/a%20b"},
+		{opts: []RegisterToolsOption{WithTrailingSlashes()}, tool: "getProject", id: "42", want: "/api/v4/projects/42/"},
+		{opts: []RegisterToolsOption{WithTrailingSlashes()}, tool: "getFile", id: "a", want: "/api/v4/files/a"},
+		{opts: []RegisterToolsOption{WithRawPaths()}, tool: "getProject", id: "group%2Fproject", want: "/api/v4/projects/group%2Fproject/"},
+		{opts: []RegisterToolsOption{WithRawPaths()}, tool: "getProject", id: "100%", want: "/api/v4/projects/100%25/"},
+		{opts: []RegisterToolsOption{WithRawPaths()}, tool: "getFile", id: "a", want: "/api/v4/files//a"},
+	} {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), tt.opts...))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		clientSession := connectTestClient(t, ctx, server)
+		args := map[string]any{"id": tt.id, "name": tt.id}
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: tt.tool, Arguments: args})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		assert.Equal(t, tt.want, <-observed)
+	}
+}
+
+func TestNewRequestEncodesArguments(t *testing.T) {
+	doc, err := libopenapi.NewDocument([]byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Echo API", "version": "1.0.0"},
+  "paths": {
+    "/echo/{segment}": {
+      "post": {
+        "operationId": "echo",
+        "parameters": [
+          {"name": "segment", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "q", "in": "query", "schema": {"type": "string"}},
+          {"name": "X-Value", "in": "header", "schema": {"type": "string"}},
+          {"name": "value", "in": "cookie", "schema": {"type": "string"}}
+        ],
+        "requestBody": {"content": {"application/json": {"schema": {"type": "object", "properties": {"text": {"type": "string"}}}}}},
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`))
+	require.NoError(t, err)
+	model, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+	item, ok := model.Model.Paths.PathItems.Get("/echo/{segment}")
+	require.True(t, ok)
+	ep := &endpoint{baseURL: "https://api.example.com/v1", path: "/echo/{segment}", method: http.MethodPost, pathItem: item, op: item.Post, cfg: &registerToolsConfig{}}
+
+	// Every argument arrives at the API as it was passed, whatever characters it has
+	roundTrips := func(s string) bool {
+		args := map[string]any{"segment": s, "q": s, "value": s, "text": s}
+		if validHeaderValue(s) {
+			args["X-Value"] = s
+		}
+		req, err := ep.newRequest(context.Background(), args)
+		if err != nil {
+			t.Logf("%q: %v", s, err)
+			return false
+		}
+		// Parse the URL as the API would receive it
+		u, err := url.Parse(req.URL.String())
+		if err != nil || u.Path != "/v1/echo/"+s || u.Query().Get("q") != s {
+			t.Logf("%q: URL %s", s, req.URL)
+			return false
+		}
+		if validHeaderValue(s) && req.Header.Get("X-Value") != s {
+			t.Logf("%q: header %q", s, req.Header.Get("X-Value"))
+			return false
+		}
+		cookie, err := req.Cookie("value")
+		if err != nil {
+			return false
+		}
+		if unescaped, err := url.PathUnescape(cookie.Value); cookie.Value != s && (err != nil || unescaped != s) {
+			t.Logf("%q: cookie %q", s, cookie.Value)
+			return false
+		}
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Text != s {
+			return false
+		}
+		return true
+	}
+	require.NoError(t, quick.Check(roundTrips, &quick.Config{MaxCount: 500}))
+	for _, s := range []string{"🐶", "東京/渋谷", "café au lait", "a%2Fb", "100%", "?#&=+", "👩‍👩‍👧‍👦", "\u202e"} {
+		assert.True(t, roundTrips(s), s)
+	}
+
+	_, err = ep.newRequest(context.Background(), map[string]any{"segment": "x", "X-Value": "a\r\nX-Injected: 1"})
+	assert.ErrorContains(t, err, "control characters")
+}
+
+func TestTypedParameters(t *testing.T) {
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Report API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/reports/{id}/{range}": {
+      "get": {
+        "operationId": "getReport",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}},
+          {"name": "range", "in": "path", "required": true, "style": "label", "schema": {"type": "array", "items": {"type": "number"}}},
+          {"name": "fields", "in": "query", "schema": {"type": "array", "items": {"type": "string", "enum": ["name", "total"]}}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 20}},
+          {"name": "X-Dry-Run", "in": "header", "schema": {"type": "boolean"}},
+          {"name": "X-Tags", "in": "header", "schema": {"type": "array", "items": {"type": "string"}}},
+          {"name": "filter", "in": "query", "content": {"application/json": {"schema": {"type": "object", "properties": {"owner": {"type": "string"}}}}}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tools, err := ListTools(ctx, spec)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	props := tools[0].InputSchema.Properties
+	assert.Equal(t, "integer", props["id"].Type)
+	assert.Equal(t, "int64", props["id"].Format)
+	assert.Equal(t, "array", props["range"].Type)
+	assert.Equal(t, "number", props["range"].Items.Type)
+	assert.Equal(t, []any{"name", "total"}, props["fields"].Items.Enum)
+	assert.Equal(t, "Allowed values: name, total", props["fields"].Description)
+	assert.JSONEq(t, "20", string(props["limit"].Default))
+	assert.Equal(t, "boolean", props["X-Dry-Run"].Type)
+	assert.Equal(t, "object", props["filter"].Type)
+
+	doc, err := libopenapi.NewDocument(spec)
+	require.NoError(t, err)
+	model, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+	item, ok := model.Model.Paths.PathItems.Get("/reports/{id}/{range}")
+	require.True(t, ok)
+	ep := &endpoint{baseURL: "https://api.example.com", path: "/reports/{id}/{range}", method: http.MethodGet, pathItem: item, op: item.Get, cfg: &registerToolsConfig{}}
+
+	// Arguments are decoded from JSON, so numbers arrive as floats
+	req, err := ep.newRequest(ctx, map[string]any{
+		"id":        float64(12345678901),
+		"range":     []any{0.5, float64(1000000)},
+		"fields":    []any{"name", "total"},
+		"limit":     float64(50),
+		"X-Dry-Run": true,
+		"X-Tags":    []any{"a", "b"},
+		"filter":    map[string]any{"owner": "ada"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/reports/12345678901/.0.5,1000000", req.URL.EscapedPath())
+	assert.Equal(t, url.Values{
+		"fields": {"name", "total"},
+		"limit":  {"50"},
+		"filter": {`{"owner":"ada"}`},
+	}, req.URL.Query())
+	assert.Equal(t, "true", req.Header.Get("X-Dry-Run"))
+	assert.Equal(t, "a,b", req.Header.Get("X-Tags"))
+}
+
+func TestStyledValue(t *testing.T) {
+	explode := true
+	tests := []struct {
+		param *v3.Parameter
+		value any
+		want  string
+	}{
+		{param: &v3.Parameter{Name: "id"}, value: []any{"a", "b"}, want: "a,b"},
+		{param: &v3.Parameter{Name: "id"}, value: map[string]any{"R": 100.0, "G": 200.0}, want: "G,200,R,100"},
+		{param: &v3.Parameter{Name: "id", Explode: &explode}, value: map[string]any{"R": 100.0, "G": 200.0}, want: "G=200,R=100"},
+		{param: &v3.Parameter{Name: "id", Style: "label"}, value: "5", want: ".5"},
+		{param: &v3.Parameter{Name: "id", Style: "label", Explode: &explode}, value: []any{"a", "b"}, want: ".a.b"},
+		{param: &v3.Parameter{Name: "id", Style: "matrix"}, value: []any{"a", "b"}, want: ";id=a,b"},
+		{param: &v3.Parameter{Name: "id", Style: "matrix", Explode: &explode}, value: []any{"a", "b"}, want: ";id=a;id=b"},
+		{param: &v3.Parameter{Name: "id", Style: "matrix", Explode: &explode}, value: map[string]any{"R": 100.0}, want: ";R=100"},
+		{param: &v3.Parameter{Name: "id"}, value: 1.5e-7, want: "0.00000015"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, styledValue(tt.param, tt.value), "%s %s %v", tt.param.Style, tt.param.Name, tt.value)
+	}
+}
+
+func TestRegisterToolsNestedRequestBody(t *testing.T) {
+	observed := make(chan string, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		observed <- string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Orders API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/orders": {
+      "post": {
+        "operationId": "createOrder",
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["customer"],
+                "properties": {
+                  "customer": {
+                    "type": "object",
+                    "required": ["name"],
+                    "properties": {"name": {"type": "string"}}
+                  },
+                  "items": {
+                    "type": "array",
+                    "items": {
+                      "type": "object",
+                      "properties": {"sku": {"type": "string"}, "quantity": {"type": "integer"}}
+                    }
+                  }
+                },
+                "additionalProperties": {"type": "string"}
+              }
+            }
+          }
+        },
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/payments": {
+      "post": {
+        "operationId": "createPayment",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "oneOf": [
+                  {"type": "object", "required": ["card"], "properties": {"card": {"type": "string", "pattern": "^(?=\\d)\\d+$"}}},
+                  {"type": "object", "required": ["iban"], "properties": {"iban": {"type": "string"}}}
+                ]
+              }
+            }
+          }
+        },
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	schemas := make(map[string]*mcp.Tool)
+	for _, tool := range tools.Tools {
+		schemas[tool.Name] = tool
+	}
+	require.Contains(t, schemas, "createOrder")
+	order := schemas["createOrder"].InputSchema
+	assert.Equal(t, []string{"customer"}, order.Required)
+	assert.Equal(t, []string{"name"}, order.Properties["customer"].Required)
+	assert.Equal(t, "object", order.Properties["items"].Items.Type)
+	require.NotNil(t, order.AdditionalProperties)
+	assert.Equal(t, "string", order.AdditionalProperties.Type)
+
+	require.Contains(t, schemas, "createPayment", "invalid patterns are dropped rather than skipping the tool")
+	payment := schemas["createPayment"].InputSchema
+	assert.Equal(t, []string{"body"}, payment.Required)
+	assert.Len(t, payment.Properties["body"].OneOf, 2)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name: "createOrder",
+		Arguments: map[string]any{
+			"customer": map[string]any{"name": "Ada"},
+			"items":    []any{map[string]any{"sku": "A1", "quantity": 2}},
+			"note":     "leave at door",
+		},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.JSONEq(t, `{"customer": {"name": "Ada"}, "items": [{"sku": "A1", "quantity": 2}], "note": "leave at door"}`, <-observed)
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "createOrder",
+		Arguments: map[string]any{"customer": map[string]any{}},
+	})
+	assert.ErrorContains(t, err, "missing properties", "nested required properties are validated")
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "createPayment",
+		Arguments: map[string]any{"body": map[string]any{"iban": "DE89370400440532013000"}},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.JSONEq(t, `{"iban": "DE89370400440532013000"}`, <-observed)
+}
+
+func TestSanitizeSchema(t *testing.T) {
+	var schema jsonschema.Schema
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "type": "object",
+  "properties": {
+    "code": {"type": "string", "pattern": "^(?!x)"},
+    "count": {"type": "integer", "default": "ten"}
+  }
+}`), &schema))
+
+	require.NoError(t, sanitizeSchema(&schema))
+	assert.Empty(t, schema.Properties["code"].Pattern)
+	assert.Nil(t, schema.Properties["count"].Default)
+}
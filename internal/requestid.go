@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"crypto/rand"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the header that carries the correlation ID of a tool call to the API.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// WithRequestIDHeader sends the correlation ID of each tool call to the API in the named header,
+// so that the API's logs can be matched with emcee's log and audit log, which record the ID too.
+// A request that already has the header, because its operation declares it as a parameter, keeps its value.
+func WithRequestIDHeader(name string) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.requestIDHeader = http.CanonicalHeaderKey(name) }
+}
+
+// newRequestID returns a random correlation ID for a tool call.
+func newRequestID() string {
+	return rand.Text()
+}
+
+// setRequestID adds a tool call's correlation ID to its request to the API, if it's configured to be sent.
+func (cfg *registerToolsConfig) setRequestID(req *http.Request, id string) {
+	if cfg.requestIDHeader == "" || req.Header.Get(cfg.requestIDHeader) != "" {
+		return
+	}
+	req.Header.Set(cfg.requestIDHeader, id)
+}
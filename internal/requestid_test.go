@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	received := make(chan string, 1)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-Correlation-Id")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {"/pets": {"get": {
+    "operationId": "listPets",
+    "parameters": [{"name": "X-Correlation-Id", "in": "header", "schema": {"type": "string"}}],
+    "responses": {"200": {"description": "OK"}}
+  }}}
+}`, api.URL)
+
+	var logs bytes.Buffer
+	handler, err := NewLogHandler(&logs, "json", slog.LevelDebug)
+	require.NoError(t, err)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient,
+		WithRequestIDHeader("x-correlation-id"),
+		WithLogger(slog.New(handler)),
+	))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	clientSession := connectTestClient(t, ctx, server)
+
+	// Each call has a correlation ID of its own, which is logged and sent to the API
+	var ids []string
+	for range 2 {
+		logs.Reset()
+		_, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets"})
+		require.NoError(t, err)
+		id := <-received
+		require.NotEmpty(t, id)
+		var record map[string]any
+		require.NoError(t, json.NewDecoder(&logs).Decode(&record))
+		assert.Equal(t, "calling tool", record["msg"])
+		assert.Equal(t, id, record["request_id"])
+		assert.Equal(t, "listPets", record["tool"])
+		ids = append(ids, id)
+	}
+	assert.NotEqual(t, ids[0], ids[1])
+
+	// A value given for a declared header parameter is kept
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets", Arguments: map[string]any{"X-Correlation-Id": "abc"}})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", <-received)
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// schemaResourceURI returns the URI of the resource describing a tool's schemas.
+func schemaResourceURI(toolName string) string {
+	return "emcee://tools/" + url.PathEscape(toolName) + "/schema"
+}
+
+// toolSchemas is the content of a tool schema resource.
+type toolSchemas struct {
+	InputSchema  *jsonschema.Schema `json:"inputSchema"`
+	OutputSchema *jsonschema.Schema `json:"outputSchema,omitempty"`
+}
+
+// addSchemaResource exposes a tool's input and output schemas as a JSON resource,
+// so that clients can validate arguments or generate forms before calling the tool.
+func addSchemaResource(server *mcp.Server, tool *mcp.Tool) error {
+	data, err := json.MarshalIndent(toolSchemas{InputSchema: tool.InputSchema, OutputSchema: tool.OutputSchema}, "", "  ")
+	if err != nil {
+		return err
+	}
+	uri := schemaResourceURI(tool.Name)
+	server.AddResource(&mcp.Resource{
+		URI:         uri,
+		Name:        tool.Name + " schema",
+		Description: "JSON Schemas for the arguments and results of the " + tool.Name + " tool",
+		MIMEType:    "application/schema+json",
+		Size:        int64(len(data)),
+	}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/schema+json", Text: string(data)}},
+		}, nil
+	})
+	return nil
+}
+
+// WithResourceTemplates exposes GET operations with path parameters as MCP resource templates,
+// so that clients can read resources like api://pets/{petId} directly.
+// Operations that require query or header parameters are skipped.
+func WithResourceTemplates() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.resourceTemplates = true }
+}
+
+// resourceTemplateScheme is the URI scheme of resources read from API operations.
+const resourceTemplateScheme = "api://"
+
+// resourceTemplate is the URI template for the resources of an operation,
+// along with a pattern matching its URIs and the path parameter for each capture group.
+type resourceTemplate struct {
+	uriTemplate string
+	pattern     *regexp.Regexp
+	params      []string
+	// read reads a resource of the template, once it's added to a server
+	read func(ctx context.Context, uri string) (*mcp.ReadResourceResult, error)
+}
+
+// newResourceTemplate returns the resource template for an operation path,
+// or false if the path has no parameters.
+func newResourceTemplate(p string) (*resourceTemplate, bool) {
+	t := &resourceTemplate{}
+	var uri, pattern strings.Builder
+	uri.WriteString(resourceTemplateScheme)
+	pattern.WriteString("^" + regexp.QuoteMeta(resourceTemplateScheme))
+	rest := strings.TrimPrefix(p, "/")
+	for {
+		loc := serverVariablePattern.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+		uri.WriteString(rest[:loc[0]])
+		pattern.WriteString(regexp.QuoteMeta(rest[:loc[0]]))
+		name := rest[loc[2]:loc[3]]
+		fmt.Fprintf(&uri, "{%s}", templateVarName(name, len(t.params)))
+		pattern.WriteString("([^/?#]+)")
+		t.params = append(t.params, name)
+		rest = rest[loc[1]:]
+	}
+	if len(t.params) == 0 {
+		return nil, false
+	}
+	uri.WriteString(rest)
+	pattern.WriteString(regexp.QuoteMeta(rest) + "$")
+	t.uriTemplate = uri.String()
+	t.pattern = regexp.MustCompile(pattern.String())
+	return t, true
+}
+
+// templateVarName converts a parameter name into a URI template variable name (RFC 6570),
+// which may only contain letters, digits, and underscores.
+func templateVarName(name string, index int) string {
+	v := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	if v == "" {
+		v = fmt.Sprintf("param%d", index)
+	}
+	return v
+}
+
+// match returns the path parameters for a resource URI, or false if the URI doesn't match the template.
+func (t *resourceTemplate) match(uri string) (map[string]any, bool) {
+	m := t.pattern.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, false
+	}
+	args := make(map[string]any, len(t.params))
+	for i, name := range t.params {
+		value, err := url.PathUnescape(m[i+1])
+		if err != nil {
+			return nil, false
+		}
+		args[name] = value
+	}
+	return args, true
+}
+
+// requiresOnlyPathParams reports whether every required parameter of an operation is a path parameter.
+func requiresOnlyPathParams(item *v3.PathItem, op *v3.Operation) bool {
+	for _, param := range slices.Concat(item.Parameters, op.Parameters) {
+		if param != nil && param.In != "path" && param.Required != nil && *param.Required {
+			return false
+		}
+	}
+	return true
+}
+
+// addResourceTemplate exposes a GET operation as a resource template, and returns the template.
+// Reading a resource performs the GET request and returns the response body,
+// as text for textual content types and as a blob otherwise.
+func addResourceTemplate(server *mcp.Server, client *http.Client, ep *endpoint, fields *responseFilter, name, description string) (*resourceTemplate, bool) {
+	if ep.method != "GET" || !requiresOnlyPathParams(ep.pathItem, ep.op) {
+		return nil, false
+	}
+	t, ok := newResourceTemplate(ep.path)
+	if !ok {
+		return nil, false
+	}
+	var mimeType string
+	if successResponseSchema(ep.op) != nil {
+		mimeType = "application/json"
+	}
+	t.read = func(ctx context.Context, uri string) (*mcp.ReadResourceResult, error) {
+		args, ok := t.match(uri)
+		if !ok {
+			return nil, mcp.ResourceNotFoundError(uri)
+		}
+		hreq, err := ep.newRequest(ctx, args)
+		if err != nil {
+			return nil, rpcError(invalidParamsCode, err.Error(), errorData{Kind: errorKindInvalidArguments, Operation: name, Detail: err.Error()})
+		}
+		resp, err := client.Do(hreq)
+		if err != nil {
+			return nil, rpcError(internalErrorCode, err.Error(), errorData{Kind: errorKindUnreachable, Operation: name, Detail: err.Error()})
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, rpcError(internalErrorCode, err.Error(), errorData{Kind: errorKindUnreachable, Operation: name, Detail: err.Error()})
+		}
+		if body, err = fields.filter(resp.Header.Get("Content-Type"), body, false); err != nil {
+			return nil, rpcError(internalErrorCode, err.Error(), errorData{Kind: errorKindUnreachable, Operation: name, Detail: err.Error()})
+		}
+		switch {
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+			return nil, mcp.ResourceNotFoundError(uri)
+		case resp.StatusCode >= 400:
+			return nil, rpcError(internalErrorCode, fmt.Sprintf("request failed with status %d", resp.StatusCode),
+				errorData{Kind: errorKindHTTP, Operation: name, HTTPStatus: resp.StatusCode, Detail: string(body)})
+		}
+
+		contents := &mcp.ResourceContents{
+			URI:      uri,
+			MIMEType: baseMediaType(resp.Header.Get("Content-Type")),
+			Meta:     mcp.Meta{provenanceMetaKey: newProvenance(resp, time.Now())},
+		}
+		if isTextMediaType(contents.MIMEType) {
+			contents.Text = string(body)
+		} else {
+			contents.Blob = body
+		}
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+	}
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: t.uriTemplate,
+		Name:        name,
+		Description: description,
+		MIMEType:    mimeType,
+	}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+		return t.read(ctx, req.Params.URI)
+	})
+	return t, true
+}
+
+// isTextMediaType reports whether a media type is textual, including JSON, XML, and YAML.
+func isTextMediaType(mediaType string) bool {
+	switch mediaType {
+	case "", "application/javascript", "application/yaml", "application/x-yaml", "application/x-ndjson":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/") || isJSONMediaType(mediaType) || isXMLMediaType(mediaType)
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWithSchemaResources(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {"application/json": {"schema": {"type": "object", "properties": {"name": {"type": "string"}}}}}
+          }
+        }
+      }
+    }
+  }
+}`
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), nil, WithSchemaResources()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	resources, err := clientSession.ListResources(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, resources.Resources, 1)
+	assert.Equal(t, "emcee://tools/getPet/schema", resources.Resources[0].URI)
+
+	result, err := clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: "emcee://tools/getPet/schema"})
+	require.NoError(t, err)
+	require.Len(t, result.Contents, 1)
+
+	var schemas struct {
+		InputSchema struct {
+			Properties map[string]any `json:"properties"`
+			Required   []string       `json:"required"`
+		} `json:"inputSchema"`
+		OutputSchema struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"outputSchema"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Contents[0].Text), &schemas))
+	assert.Contains(t, schemas.InputSchema.Properties, "petId")
+	assert.Equal(t, []string{"petId"}, schemas.InputSchema.Required)
+	assert.Contains(t, schemas.OutputSchema.Properties, "name")
+}
+
+func TestNewResourceTemplate(t *testing.T) {
+	tmpl, ok := newResourceTemplate("/users/{user-id}/files/{path}.json")
+	require.True(t, ok)
+	assert.Equal(t, "api://users/{user_id}/files/{path}.json", tmpl.uriTemplate)
+
+	args, ok := tmpl.match("api://users/42/files/a%20b.json")
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{"user-id": "42", "path": "a b"}, args)
+
+	_, ok = tmpl.match("api://users/42/files/a/b.json")
+	assert.False(t, ok)
+
+	_, ok = newResourceTemplate("/pets")
+	assert.False(t, ok, "paths without parameters aren't templates")
+}
+
+func TestRegisterToolsWithResourceTemplates(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pets/1":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"name":"Fido"}`))
+		case "/pets/1/photo":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}},
+    "/pets/{petId}": {
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "get": {"operationId": "getPet", "summary": "Get a pet", "responses": {"200": {"description": "OK"}}},
+      "delete": {"operationId": "deletePet", "responses": {"204": {"description": "Deleted"}}}
+    },
+    "/pets/{petId}/photo": {
+      "get": {
+        "operationId": "getPetPhoto",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    },
+    "/pets/{petId}/visits": {
+      "get": {
+        "operationId": "listVisits",
+        "parameters": [
+          {"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}},
+          {"name": "since", "in": "query", "required": true, "schema": {"type": "string"}}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithResourceTemplates()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	templates, err := clientSession.ListResourceTemplates(ctx, nil)
+	require.NoError(t, err)
+	var uris []string
+	for _, tmpl := range templates.ResourceTemplates {
+		uris = append(uris, tmpl.URITemplate)
+	}
+	assert.ElementsMatch(t, []string{"api://pets/{petId}", "api://pets/{petId}/photo"}, uris)
+
+	result, err := clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: "api://pets/1"})
+	require.NoError(t, err)
+	require.Len(t, result.Contents, 1)
+	assert.Equal(t, "api://pets/1", result.Contents[0].URI)
+	assert.Equal(t, "application/json", result.Contents[0].MIMEType)
+	assert.JSONEq(t, `{"name":"Fido"}`, result.Contents[0].Text)
+
+	result, err = clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: "api://pets/1/photo"})
+	require.NoError(t, err)
+	require.Len(t, result.Contents, 1)
+	assert.Equal(t, "image/png", result.Contents[0].MIMEType)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, result.Contents[0].Blob)
+
+	_, err = clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: "api://pets/2"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,306 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ResponseFields removes fields from JSON responses before they reach the model,
+// like personal data or tokens, selecting them with JSONPath expressions.
+type ResponseFields struct {
+	// Deny lists expressions selecting the fields removed from every operation's responses (e.g. "$..ssn").
+	Deny []string `yaml:"deny" json:"deny,omitempty"`
+	// Operations sets the fields of operations' responses, by operation ID, in place of Deny.
+	// An empty entry keeps every field of an operation's responses.
+	Operations map[string]*OperationResponseFields `yaml:"operations" json:"operations,omitempty"`
+}
+
+// OperationResponseFields sets the fields kept in an operation's responses.
+type OperationResponseFields struct {
+	// Allow lists expressions selecting the only fields kept, along with everything they contain.
+	// Objects and array items that contain none of them are removed.
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+	// Deny lists expressions selecting fields removed, after those that aren't allowed.
+	Deny []string `yaml:"deny" json:"deny,omitempty"`
+}
+
+// validate checks that every expression is valid JSONPath.
+func (f *ResponseFields) validate() error {
+	check := func(setting string, exprs []string) error {
+		for _, expr := range exprs {
+			if _, err := jsonpath.NewPath(expr, config.WithPropertyNameExtension()); err != nil {
+				return fmt.Errorf("invalid %s expression %q: %w", setting, expr, err)
+			}
+		}
+		return nil
+	}
+	if err := check("deny", f.Deny); err != nil {
+		return err
+	}
+	for _, id := range slices.Sorted(maps.Keys(f.Operations)) {
+		if o := f.Operations[id]; o != nil {
+			if err := check(fmt.Sprintf("operations.%s.allow", id), o.Allow); err != nil {
+				return err
+			}
+			if err := check(fmt.Sprintf("operations.%s.deny", id), o.Deny); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// responseFilter removes fields from an operation's JSON responses.
+type responseFilter struct {
+	allow, deny []*jsonpath.JSONPath
+}
+
+// responseFilter returns the filter of an operation's responses,
+// or nil if the configuration removes none of their fields.
+func (c *Config) responseFilter(operationID string) (*responseFilter, error) {
+	if c == nil || c.ResponseFields == nil {
+		return nil, nil
+	}
+	var allow []string
+	deny := c.ResponseFields.Deny
+	if o, ok := c.ResponseFields.Operations[operationID]; ok && operationID != "" {
+		allow, deny = nil, nil
+		if o != nil {
+			allow, deny = o.Allow, o.Deny
+		}
+	}
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	compile := func(exprs []string) ([]*jsonpath.JSONPath, error) {
+		paths := make([]*jsonpath.JSONPath, 0, len(exprs))
+		for _, expr := range exprs {
+			p, err := jsonpath.NewPath(expr, config.WithPropertyNameExtension())
+			if err != nil {
+				return nil, fmt.Errorf("invalid response field expression %q: %w", expr, err)
+			}
+			paths = append(paths, p)
+		}
+		return paths, nil
+	}
+	f := &responseFilter{}
+	var err error
+	if f.allow, err = compile(allow); err != nil {
+		return nil, err
+	}
+	if f.deny, err = compile(deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// filter returns a response body without the fields the filter removes, if it's JSON,
+// or XML that's converted to JSON when convertXML is set, in which case the JSON is returned.
+// Bodies labeled as JSON are filtered, as are text bodies, or bodies without a content type, that parse as JSON.
+// Other bodies, like images, downloads, and error pages, have no fields to remove, and are returned as is.
+// It's an error for a body labeled as JSON not to parse, so that fields are never let through unfiltered.
+func (f *responseFilter) filter(contentType string, body []byte, convertXML bool) ([]byte, error) {
+	if f == nil {
+		return body, nil
+	}
+	switch {
+	case isJSONMediaType(contentType):
+		return f.apply(body)
+	case convertXML && isXMLMediaType(contentType):
+		// XML that can't be parsed is returned as is, as it would be without the filter
+		converted, err := xmlToJSON(body)
+		if err != nil {
+			return body, nil
+		}
+		if converted, err = f.apply(converted); err != nil {
+			return nil, err
+		}
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, converted, "", "  ") == nil {
+			converted = pretty.Bytes()
+		}
+		return converted, nil
+	case isTextMediaType(baseMediaType(contentType)) && json.Valid(body):
+		return f.apply(body)
+	}
+	return body, nil
+}
+
+// apply returns a JSON body without the fields the filter removes.
+// Bodies that have none of them are returned as is.
+// It's an error for the body not to be JSON, so that fields are never let through unfiltered.
+func (f *responseFilter) apply(body []byte) ([]byte, error) {
+	if f == nil || len(bytes.TrimSpace(body)) == 0 {
+		return body, nil
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("error filtering response fields: the response isn't valid JSON")
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("error filtering response fields: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return body, nil
+	}
+	root := doc.Content[0]
+
+	removed := false
+	if len(f.allow) > 0 {
+		allowed := selectedNodes(f.allow, &doc)
+		if !allowed[root] && !allowed[&doc] {
+			keepAllowed(root, allowed)
+			removed = true
+		}
+	}
+	if len(f.deny) > 0 {
+		denied := selectedNodes(f.deny, &doc)
+		if denied[root] || denied[&doc] {
+			return []byte("null"), nil
+		}
+		removed = removeDenied(root, denied) || removed
+	}
+	if !removed {
+		return body, nil
+	}
+	var b bytes.Buffer
+	if err := writeJSON(&b, root); err != nil {
+		return nil, fmt.Errorf("error filtering response fields: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// applyEvents removes the fields the filter removes from the data of events read from a stream.
+// Data that isn't JSON, like a closing [DONE], is kept as is.
+func (f *responseFilter) applyEvents(events []serverSentEvent) error {
+	for i := range events {
+		data, err := f.filter("", events[i].Data, false)
+		if err != nil {
+			return err
+		}
+		events[i].Data = data
+	}
+	return nil
+}
+
+// selectedNodes returns the set of nodes any of the expressions select.
+func selectedNodes(paths []*jsonpath.JSONPath, doc *yaml.Node) map[*yaml.Node]bool {
+	selected := make(map[*yaml.Node]bool)
+	for _, p := range paths {
+		for _, n := range p.Query(doc) {
+			selected[n] = true
+		}
+	}
+	return selected
+}
+
+// keepAllowed removes the fields and items of a node that aren't allowed and contain nothing allowed,
+// and reports whether the node is allowed or contains something allowed.
+func keepAllowed(n *yaml.Node, allowed map[*yaml.Node]bool) bool {
+	if allowed[n] {
+		return true
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if keepAllowed(n.Content[i+1], allowed) {
+				content = append(content, n.Content[i], n.Content[i+1])
+			}
+		}
+		n.Content = content
+		return len(content) > 0
+	case yaml.SequenceNode:
+		n.Content = slices.DeleteFunc(n.Content, func(item *yaml.Node) bool { return !keepAllowed(item, allowed) })
+		return len(n.Content) > 0
+	}
+	return false
+}
+
+// removeDenied removes the denied fields and items of a node, at any depth, and reports whether it removed any.
+func removeDenied(n *yaml.Node, denied map[*yaml.Node]bool) bool {
+	removed := false
+	switch n.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if denied[n.Content[i+1]] {
+				removed = true
+				continue
+			}
+			removed = removeDenied(n.Content[i+1], denied) || removed
+			content = append(content, n.Content[i], n.Content[i+1])
+		}
+		n.Content = content
+	case yaml.SequenceNode:
+		var content []*yaml.Node
+		for _, item := range n.Content {
+			if denied[item] {
+				removed = true
+				continue
+			}
+			removed = removeDenied(item, denied) || removed
+			content = append(content, item)
+		}
+		n.Content = content
+	}
+	return removed
+}
+
+// writeJSON encodes a node decoded from JSON as JSON again, keeping the order of object fields
+// and numbers as they were written.
+func writeJSON(b *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			b.Write(key)
+			b.WriteByte(':')
+			if err := writeJSON(b, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSON(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case yaml.ScalarNode:
+		switch {
+		case n.Tag == "!!null":
+			b.WriteString("null")
+		case (n.Tag == "!!bool" || n.Tag == "!!int" || n.Tag == "!!float") && json.Valid([]byte(n.Value)):
+			b.WriteString(n.Value)
+		default:
+			value, err := json.Marshal(n.Value)
+			if err != nil {
+				return err
+			}
+			b.Write(value)
+		}
+	default:
+		return fmt.Errorf("unexpected node kind %v", n.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFilter(t *testing.T) {
+	body := []byte(`{"id": 1, "name": "Alice", "ssn": "123-45-6789", "balance": 12.50,
+  "contacts": [{"email": "a@example.com", "phone": "555-0100"}, {"phone": "555-0101"}],
+  "session": {"access_token": "secret", "expires": null, "active": true}}`)
+
+	for _, tc := range []struct {
+		name        string
+		allow, deny []string
+		want        string
+	}{
+		{"deny", nil, []string{"$..ssn", "$..email", "$.session.access_token"},
+			`{"id":1,"name":"Alice","balance":12.50,"contacts":[{"phone":"555-0100"},{"phone":"555-0101"}],"session":{"expires":null,"active":true}}`},
+		{"deny array items", nil, []string{"$.contacts[0]"},
+			`{"id":1,"name":"Alice","ssn":"123-45-6789","balance":12.50,"contacts":[{"phone":"555-0101"}],"session":{"access_token":"secret","expires":null,"active":true}}`},
+		{"allow", []string{"$.id", "$.name", "$.contacts[*].email"}, nil,
+			`{"id":1,"name":"Alice","contacts":[{"email":"a@example.com"}]}`},
+		{"allow and deny", []string{"$.name", "$.session"}, []string{"$..access_token"},
+			`{"name":"Alice","session":{"expires":null,"active":true}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &Config{ResponseFields: &ResponseFields{Operations: map[string]*OperationResponseFields{"getUser": {Allow: tc.allow, Deny: tc.deny}}}}
+			f, err := config.responseFilter("getUser")
+			require.NoError(t, err)
+			filtered, err := f.apply(body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(filtered))
+		})
+	}
+
+	f, err := (&Config{ResponseFields: &ResponseFields{Deny: []string{"$..ssn"}}}).responseFilter("listPets")
+	require.NoError(t, err)
+	unchanged := []byte(`[{"id": 1}]`)
+	filtered, err := f.apply(unchanged)
+	require.NoError(t, err)
+	assert.Equal(t, unchanged, filtered, "bodies without removed fields are returned as is")
+	_, err = f.apply([]byte(`{"ssn": `))
+	assert.ErrorContains(t, err, "error filtering response fields", "bodies that can't be filtered aren't let through")
+}
+
+func TestResponseFilterEvents(t *testing.T) {
+	f, err := (&Config{ResponseFields: &ResponseFields{Deny: []string{"$..token"}}}).responseFilter("watch")
+	require.NoError(t, err)
+	events, _ := readEvents(context.Background(), strings.NewReader("data: {\"id\": 1, \"token\": \"t1\"}\n\ndata: ping\n\n"), streamLimits{maxEvents: 10})
+	require.NoError(t, f.applyEvents(events))
+	require.Len(t, events, 2)
+	assert.JSONEq(t, `{"id": 1}`, string(events[0].Data))
+	assert.JSONEq(t, `"ping"`, string(events[1].Data))
+}
+
+func TestRegisterToolsWithResponseFields(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/users/me":
+			w.Write([]byte(`{"id": "u1", "email": "me@example.com", "token": "t1"}`))
+		case "/users/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`{"id": "plain", "email": "plain@example.com"}`))
+		case "/users/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(`email: text@example.com`))
+		case "/users/broken":
+			w.Write([]byte(`{"email": "broken@example.com"`))
+		case "/users/xml":
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<user><id>xml</id><email>xml@example.com</email></user>`))
+		case "/users/avatar":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n"))
+		case "/users/down":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`<h1>Bad Gateway</h1>`))
+		case "/jobs":
+			w.Header().Set("Operation-Location", "/jobs/1/status")
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id": "1"}`))
+		case "/jobs/1/status":
+			w.Write([]byte(`{"status": "done", "token": "t2"}`))
+		case "/users/u2":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "forbidden", "email": "u2@example.com"}`))
+		default:
+			w.Write([]byte(`[{"id": "u1", "email": "me@example.com", "token": "t1"}]`))
+		}
+	}))
+	defer api.Close()
+
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "User API", "version": "1.0.0"},
+  "servers": [{"url": "` + api.URL + `"}],
+  "paths": {
+    "/users": {"get": {"operationId": "listUsers", "responses": {"200": {"description": "OK"}}}},
+    "/jobs": {"post": {"operationId": "startJob", "responses": {"202": {"description": "Accepted"}}}},
+    "/users/me": {"get": {"operationId": "getCurrentUser", "responses": {"200": {"description": "OK"}}}},
+    "/users/{id}": {"get": {
+      "operationId": "getUser",
+      "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "responses": {"200": {"description": "OK"}}
+    }}
+  }
+}`)
+	config, err := ParseConfig([]byte(`
+responseFields:
+  deny: [$..email, $..token]
+  operations:
+    getCurrentUser:
+      deny: [$.token]
+`))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, api.Client(), WithConfig(config), WithResourceTemplates(), WithXMLToJSON()))
+	clientSession := connectTestClient(t, ctx, server)
+
+	text := func(name string, args map[string]any) string {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, err)
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+	assert.JSONEq(t, `[{"id": "u1"}]`, text("listUsers", nil))
+	assert.JSONEq(t, `{"id": "u1", "email": "me@example.com"}`, text("getCurrentUser", nil), "an operation's entry replaces the global denylist")
+	assert.NotContains(t, text("getUser", map[string]any{"id": "u2"}), "u2@example.com", "error responses are filtered too")
+	assert.JSONEq(t, `{"id": "plain"}`, text("getUser", map[string]any{"id": "plain"}), "JSON is filtered whatever its content type")
+	assert.Equal(t, `email: text@example.com`, text("getUser", map[string]any{"id": "text"}), "text that isn't JSON has no fields to remove")
+	assert.JSONEq(t, `{"user": {"id": "xml"}}`, text("getUser", map[string]any{"id": "xml"}), "XML converted to JSON is filtered")
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getUser", Arguments: map[string]any{"id": "broken"}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError, "JSON that can't be filtered isn't let through")
+	assert.NotContains(t, result.Content[0].(*mcp.TextContent).Text, "broken@example.com")
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getUser", Arguments: map[string]any{"id": "avatar"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, []byte("\x89PNG\r\n"), result.Content[0].(*mcp.ImageContent).Data, "binary responses are returned as is")
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getUser", Arguments: map[string]any{"id": "down"}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "502", "error pages keep their status")
+
+	text("startJob", nil)
+	assert.NotContains(t, text("checkOperationStatus", map[string]any{"url": api.URL + "/jobs/1/status"}), "t2", "status responses are filtered too")
+
+	resource, err := clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: "api://users/u1"})
+	require.NoError(t, err)
+	assert.NotContains(t, resource.Contents[0].Text, "email", "resources are filtered too")
+
+	_, err = ParseConfig([]byte("responseFields: {deny: ['$[']}"))
+	assert.ErrorContains(t, err, `invalid responseFields: invalid deny expression "$["`)
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// RetryPolicy decides which failed requests to the API are retried.
+// The zero value retries idempotent requests that fail to connect, or that get a 429 or 5XX status other than 501.
+type RetryPolicy struct {
+	// StatusCodes lists the response statuses that are retried.
+	// If empty, 429 Too Many Requests and 5XX statuses other than 501 Not Implemented are retried.
+	StatusCodes []int `yaml:"statusCodes" json:"statusCodes,omitempty"`
+	// UnsafeMethods retries requests whose methods aren't idempotent, like POST and PATCH,
+	// which may repeat their effects if the API received an earlier attempt.
+	UnsafeMethods bool `yaml:"unsafeMethods" json:"unsafeMethods,omitempty"`
+}
+
+// validate checks that the retried statuses are HTTP statuses.
+func (p *RetryPolicy) validate() error {
+	for _, code := range p.StatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid retry status code %d", code)
+		}
+	}
+	return nil
+}
+
+// retryMethodKey is the context key of the method of a request being retried,
+// which isn't otherwise available to the retry policy when a request fails to connect.
+type retryMethodKey struct{}
+
+// retryMethodTransport records the method of each request in its context for the retry policy.
+type retryMethodTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *retryMethodTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.Base.RoundTrip(req.WithContext(context.WithValue(req.Context(), retryMethodKey{}, req.Method)))
+}
+
+// checkRetry reports whether a request should be retried, for use as retryablehttp.Client.CheckRetry.
+func (p RetryPolicy) checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	// A 429 response means the API didn't act on the request, so it's safe to retry whatever its method
+	tooManyRequests := resp != nil && resp.StatusCode == http.StatusTooManyRequests
+	if method, ok := ctx.Value(retryMethodKey{}).(string); ok && !p.UnsafeMethods && !tooManyRequests && !isIdempotentMethod(method) {
+		return false, nil
+	}
+	if err != nil || len(p.StatusCodes) == 0 {
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+	return slices.Contains(p.StatusCodes, resp.StatusCode), nil
+}
+
+// isIdempotentMethod reports whether an HTTP method is idempotent, per RFC 9110,
+// so that repeating a request has the same effect as sending it once.
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE", "QUERY":
+		return true
+	}
+	return false
+}
+
+// retryBackoff returns how long to wait before retrying a request, for use as retryablehttp.Client.Backoff.
+// It waits as long as a response's Retry-After header asks, whatever its status,
+// and otherwise backs off exponentially.
+func retryBackoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return wait
+		}
+	}
+	return retryablehttp.DefaultBackoff(min, max, attemptNum, nil)
+}
+
+// retryErrorHandler returns the last response of a request that's still rate limited after its retries,
+// so that the tool reports the API's 429 response instead of failing without one.
+// Other requests that run out of retries fail, as they do by default.
+func retryErrorHandler(resp *http.Response, err error, numTries int) (*http.Response, error) {
+	if err == nil && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return resp, nil
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("giving up after %d attempt(s): %w", numTries, err)
+	}
+	return nil, fmt.Errorf("giving up after %d attempt(s)", numTries)
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an HTTP date, into how long to wait from now.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
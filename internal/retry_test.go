@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var calls atomic.Int32
+	status := atomic.Int32{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// Retries are immediate, so that the test doesn't wait for the backoff
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer api.Close()
+
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		method    string
+		status    int
+		wantCalls int32
+	}{
+		{name: "default retries 503", method: http.MethodGet, status: 503, wantCalls: 3},
+		{name: "default retries 429", method: http.MethodDelete, status: 429, wantCalls: 3},
+		{name: "default doesn't retry 501", method: http.MethodGet, status: 501, wantCalls: 1},
+		{name: "default doesn't retry 404", method: http.MethodGet, status: 404, wantCalls: 1},
+		{name: "default doesn't retry POST", method: http.MethodPost, status: 503, wantCalls: 1},
+		{name: "unsafe methods", policy: RetryPolicy{UnsafeMethods: true}, method: http.MethodPost, status: 503, wantCalls: 3},
+		{name: "status codes", policy: RetryPolicy{StatusCodes: []int{409}}, method: http.MethodPut, status: 409, wantCalls: 3},
+		{name: "status codes replace defaults", policy: RetryPolicy{StatusCodes: []int{409}}, method: http.MethodGet, status: 503, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			status.Store(int32(tt.status))
+			client, err := RetryableClient(RetryableClientOptions{Retries: 2, Timeout: 5 * time.Second, RetryPolicy: tt.policy})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(tt.method, api.URL, strings.NewReader("{}"))
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+
+	_, err := RetryableClient(RetryableClientOptions{RetryPolicy: RetryPolicy{StatusCodes: []int{1000}}})
+	assert.ErrorContains(t, err, "invalid retry status code 1000")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: " 0 ", want: 0, wantOK: true},
+		{value: "Mon, 03 Mar 2025 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{value: "Mon, 03 Mar 2025 11:00:00 GMT", want: 0, wantOK: true},
+		{value: "-1"},
+		{value: "soon"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		assert.Equal(t, tt.wantOK, ok, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+}
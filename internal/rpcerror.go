@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"encoding/json"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+)
+
+// JSON-RPC error codes used by emcee.
+const (
+	invalidParamsCode = -32602
+	internalErrorCode = -32603
+)
+
+// Kinds of failure, as reported in the data of JSON-RPC errors.
+const (
+	// errorKindInvalidArguments is a tool call or resource read whose arguments don't match the operation.
+	errorKindInvalidArguments = "invalid_arguments"
+	// errorKindInvalidParams is a request whose params are malformed.
+	errorKindInvalidParams = "invalid_params"
+	// errorKindUnreachable is an API that couldn't be reached, or whose response couldn't be read.
+	errorKindUnreachable = "upstream_unreachable"
+	// errorKindHTTP is an API that responded with an error status.
+	errorKindHTTP = "http_error"
+	// errorKindCanceled is a request that was canceled before it completed.
+	errorKindCanceled = "canceled"
+	// errorKindUnauthorized is a request that lacks the token its method requires.
+	errorKindUnauthorized = "unauthorized"
+)
+
+// errorData is the data of every JSON-RPC error sent by emcee,
+// so that clients can handle failures without parsing error messages.
+type errorData struct {
+	// Kind is the kind of failure, like "invalid_arguments" or "http_error".
+	Kind string `json:"kind"`
+	// Operation is the tool or method whose request failed.
+	Operation string `json:"operation,omitempty"`
+	// HTTPStatus is the status of the API's response, for HTTP errors.
+	HTTPStatus int `json:"httpStatus,omitempty"`
+	// Detail describes the failure, like the invalid fields of a call or the body of an error response.
+	Detail string `json:"detail,omitempty"`
+}
+
+// rpcError returns an error that's sent to the client as a JSON-RPC error with the given code, message, and data.
+// The SDK doesn't export its error type, so the error is obtained by decoding one from the wire.
+func rpcError(code int64, message string, data errorData) error {
+	type wireError struct {
+		Code    int64     `json:"code"`
+		Message string    `json:"message"`
+		Data    errorData `json:"data"`
+	}
+	msg, err := json.Marshal(struct {
+		JSONRPC string    `json:"jsonrpc"`
+		ID      int       `json:"id"`
+		Error   wireError `json:"error"`
+	}{"2.0", 0, wireError{code, message, data}})
+	if err != nil {
+		panic(err) // errorData always marshals
+	}
+	decoded, err := jsonrpc.DecodeMessage(msg)
+	if err != nil {
+		panic(err)
+	}
+	return decoded.(*jsonrpc.Response).Error
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCError(t *testing.T) {
+	err := rpcError(invalidParamsCode, "invalid params: bad", errorData{Kind: errorKindInvalidArguments, Operation: "getPet", Detail: "bad"})
+	assert.EqualError(t, err, "invalid params: bad")
+	assert.ErrorIs(t, err, errInvalidParams)
+	assert.JSONEq(t, `{
+		"code": -32602,
+		"message": "invalid params: bad",
+		"data": {"kind": "invalid_arguments", "operation": "getPet", "detail": "bad"}
+	}`, string(wireError(err)))
+}
+
+// rawCall sends a request to server over a raw JSON-RPC connection and returns the error object of its response.
+func rawCall(t *testing.T, ctx context.Context, server *mcp.Server, method string, params any) map[string]any {
+	t.Helper()
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { serverSession.Close() })
+	conn, err := clientTransport.Connect(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	send := func(id any, method string, params any) {
+		data, err := json.Marshal(params)
+		require.NoError(t, err)
+		req := &jsonrpc.Request{Method: method, Params: data}
+		if id != nil {
+			req.ID, err = jsonrpc.MakeID(id)
+			require.NoError(t, err)
+		}
+		require.NoError(t, conn.Write(ctx, req))
+	}
+	send(float64(1), "initialize", map[string]any{"protocolVersion": "2025-06-18", "capabilities": map[string]any{}, "clientInfo": map[string]any{"name": "raw", "version": "dev"}})
+	_, err = conn.Read(ctx)
+	require.NoError(t, err)
+	send(nil, "notifications/initialized", map[string]any{})
+	send(float64(2), method, params)
+
+	msg, err := conn.Read(ctx)
+	require.NoError(t, err)
+	resp, ok := msg.(*jsonrpc.Response)
+	require.True(t, ok)
+	require.Error(t, resp.Error)
+	var wire map[string]any
+	require.NoError(t, json.Unmarshal(wireError(resp.Error), &wire))
+	return wire
+}
+
+func TestErrorDataOnTheWire(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database is down", http.StatusServiceUnavailable)
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithResourceTemplates()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wire := rawCall(t, ctx, server, "tools/call", map[string]any{"name": "getPet", "arguments": map[string]any{}})
+	assert.Equal(t, float64(invalidParamsCode), wire["code"])
+	assert.Equal(t, map[string]any{
+		"kind":      errorKindInvalidArguments,
+		"operation": "getPet",
+		"detail":    "- petId: required argument is missing",
+	}, wire["data"])
+
+	wire = rawCall(t, ctx, server, "resources/read", map[string]any{"uri": "api://pets/1"})
+	assert.Equal(t, float64(internalErrorCode), wire["code"])
+	assert.Equal(t, map[string]any{
+		"kind":       errorKindHTTP,
+		"operation":  "getPet",
+		"httpStatus": float64(http.StatusServiceUnavailable),
+		"detail":     "database is down\n",
+	}, wire["data"])
+}
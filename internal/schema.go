@@ -0,0 +1,440 @@
+package internal
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSchemaDepth bounds recursion when converting OpenAPI schemas,
+// which protects against circular references.
+const maxSchemaDepth = 8
+
+// schemaDirection determines which properties are omitted when converting a schema.
+type schemaDirection int
+
+const (
+	// requestDirection omits readOnly properties.
+	requestDirection schemaDirection = iota
+	// responseDirection omits writeOnly properties.
+	responseDirection
+)
+
+// convertSchema converts an OpenAPI schema into an equivalent JSON Schema.
+func convertSchema(s *base.Schema, dir schemaDirection) *jsonschema.Schema {
+	return convertSchemaDepth(s, dir, 0)
+}
+
+func convertSchemaDepth(s *base.Schema, dir schemaDirection, depth int) *jsonschema.Schema {
+	if s == nil || depth > maxSchemaDepth {
+		return &jsonschema.Schema{}
+	}
+	s = mergeAllOf(s)
+
+	js := &jsonschema.Schema{
+		Title:       s.Title,
+		Description: s.Description,
+		Format:      s.Format,
+		Pattern:     s.Pattern,
+		MultipleOf:  s.MultipleOf,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		MinLength:   intPtr(s.MinLength),
+		MaxLength:   intPtr(s.MaxLength),
+		MinItems:    intPtr(s.MinItems),
+		MaxItems:    intPtr(s.MaxItems),
+		Required:    s.Required,
+	}
+	if s.Deprecated != nil {
+		js.Deprecated = *s.Deprecated
+	}
+	if s.ReadOnly != nil {
+		js.ReadOnly = *s.ReadOnly
+	}
+	if s.WriteOnly != nil {
+		js.WriteOnly = *s.WriteOnly
+	}
+	if s.UniqueItems != nil {
+		js.UniqueItems = *s.UniqueItems
+	}
+	// Binary data is passed in arguments as base64-encoded strings
+	if dir == requestDirection && isBinarySchema(s) {
+		js.Format, js.ContentEncoding = "", "base64"
+	}
+
+	// Types, including OpenAPI 3.0 nullable
+	types := append([]string(nil), s.Type...)
+	if s.Nullable != nil && *s.Nullable && len(types) > 0 {
+		types = append(types, "null")
+	}
+	switch len(types) {
+	case 0:
+	case 1:
+		js.Type = types[0]
+	default:
+		js.Types = types
+	}
+
+	// OpenAPI 3.0 uses booleans for exclusive bounds; 3.1 uses numbers
+	if s.ExclusiveMinimum != nil {
+		if s.ExclusiveMinimum.IsB() {
+			js.ExclusiveMinimum = &s.ExclusiveMinimum.B
+		} else if s.ExclusiveMinimum.A && s.Minimum != nil {
+			js.ExclusiveMinimum, js.Minimum = s.Minimum, nil
+		}
+	}
+	if s.ExclusiveMaximum != nil {
+		if s.ExclusiveMaximum.IsB() {
+			js.ExclusiveMaximum = &s.ExclusiveMaximum.B
+		} else if s.ExclusiveMaximum.A && s.Maximum != nil {
+			js.ExclusiveMaximum, js.Maximum = s.Maximum, nil
+		}
+	}
+
+	for _, node := range s.Enum {
+		if v, ok := decodeNode(node); ok {
+			js.Enum = append(js.Enum, v)
+		}
+	}
+	if v, ok := decodeNode(s.Default); ok {
+		if b, err := json.Marshal(v); err == nil {
+			js.Default = b
+		}
+	}
+	if v, ok := decodeNode(s.Const); ok {
+		js.Const = &v
+	}
+	if v, ok := decodeNode(s.Example); ok {
+		js.Examples = append(js.Examples, v)
+	}
+	for _, node := range s.Examples {
+		if v, ok := decodeNode(node); ok {
+			js.Examples = append(js.Examples, v)
+		}
+	}
+
+	if s.Items != nil {
+		if s.Items.IsA() {
+			js.Items = convertSchemaProxy(s.Items.A, dir, depth+1)
+		} else if !s.Items.B {
+			js.Items = falseSchema()
+		}
+	}
+	if s.AdditionalProperties != nil {
+		if s.AdditionalProperties.IsA() {
+			js.AdditionalProperties = convertSchemaProxy(s.AdditionalProperties.A, dir, depth+1)
+		} else if !s.AdditionalProperties.B {
+			js.AdditionalProperties = falseSchema()
+		}
+	}
+
+	if s.Properties != nil {
+		js.Properties = make(map[string]*jsonschema.Schema)
+		omitted := make(map[string]struct{})
+		for prop := s.Properties.First(); prop != nil; prop = prop.Next() {
+			ps := prop.Value().Schema()
+			if ps != nil && omitProperty(ps, dir) {
+				omitted[prop.Key()] = struct{}{}
+				continue
+			}
+			js.Properties[prop.Key()] = convertSchemaProxy(prop.Value(), dir, depth+1)
+		}
+		if len(omitted) > 0 {
+			var required []string
+			for _, r := range js.Required {
+				if _, ok := omitted[r]; !ok {
+					required = append(required, r)
+				}
+			}
+			js.Required = required
+		}
+	}
+
+	for _, sp := range s.AllOf {
+		js.AllOf = append(js.AllOf, convertSchemaProxy(sp, dir, depth+1))
+	}
+	for _, sp := range s.OneOf {
+		js.OneOf = append(js.OneOf, convertSchemaProxy(sp, dir, depth+1))
+	}
+	for _, sp := range s.AnyOf {
+		js.AnyOf = append(js.AnyOf, convertSchemaProxy(sp, dir, depth+1))
+	}
+	// Alternatives that share a type give the schema that type, for clients that require one
+	if js.Type == "" && len(js.Types) == 0 {
+		js.Type = alternativesType(slices.Concat(js.OneOf, js.AnyOf))
+	}
+	if s.Not != nil {
+		js.Not = convertSchemaProxy(s.Not, dir, depth+1)
+	}
+
+	return js
+}
+
+func convertSchemaProxy(sp *base.SchemaProxy, dir schemaDirection, depth int) *jsonschema.Schema {
+	if sp == nil {
+		return &jsonschema.Schema{}
+	}
+	return convertSchemaDepth(sp.Schema(), dir, depth)
+}
+
+// alternativesType returns the type shared by every alternative, or "" if they don't share one.
+func alternativesType(alternatives []*jsonschema.Schema) string {
+	if len(alternatives) == 0 {
+		return ""
+	}
+	t := alternatives[0].Type
+	for _, alt := range alternatives[1:] {
+		if alt.Type != t {
+			return ""
+		}
+	}
+	return t
+}
+
+// omitProperty reports whether a property should be left out of a schema for the given direction.
+func omitProperty(s *base.Schema, dir schemaDirection) bool {
+	switch dir {
+	case requestDirection:
+		return s.ReadOnly != nil && *s.ReadOnly
+	case responseDirection:
+		return s.WriteOnly != nil && *s.WriteOnly
+	}
+	return false
+}
+
+// falseSchema returns a schema that matches nothing.
+func falseSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{Not: &jsonschema.Schema{}}
+}
+
+func intPtr(i *int64) *int {
+	if i == nil {
+		return nil
+	}
+	v := int(*i)
+	return &v
+}
+
+// decodeNode decodes a YAML node into a JSON-compatible value.
+func decodeNode(node *yaml.Node) (any, bool) {
+	if node == nil {
+		return nil, false
+	}
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// isFlattenableBody reports whether a request body schema is a plain object
+// whose properties can be exposed as top-level tool arguments.
+// Bodies using composition, or that aren't objects with properties, are passed as a single argument instead.
+func isFlattenableBody(s *base.Schema) bool {
+	if s.Properties == nil || s.Properties.Len() == 0 {
+		return false
+	}
+	if len(s.AllOf) > 0 || len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		return false
+	}
+	return len(s.Type) == 0 || slices.Contains(s.Type, "object")
+}
+
+// additionalBodyProperties returns the schema for undeclared properties of a flattened request body,
+// or nil if the body doesn't explicitly allow them.
+func additionalBodyProperties(s *base.Schema) *jsonschema.Schema {
+	if s.AdditionalProperties == nil {
+		return nil
+	}
+	if s.AdditionalProperties.IsA() {
+		return convertSchemaProxy(s.AdditionalProperties.A, requestDirection, 1)
+	}
+	if s.AdditionalProperties.B {
+		return &jsonschema.Schema{}
+	}
+	return nil
+}
+
+// bodyArgument is the name of the argument holding a request body that isn't flattened into top-level arguments.
+const bodyArgument = "body"
+
+// bodyArgumentName returns the argument name for an unflattened request body,
+// avoiding collisions with parameter names. It returns "" if no name is available.
+func bodyArgumentName(paramNames map[string]struct{}) string {
+	for _, name := range []string{bodyArgument, "requestBody", "_body"} {
+		if _, exists := paramNames[name]; !exists {
+			return name
+		}
+	}
+	return ""
+}
+
+// declaresProperty reports whether an object schema, or any of its alternatives, has a property with a name.
+func declaresProperty(s *base.Schema, name string) bool {
+	return declaresPropertyDepth(s, name, 0)
+}
+
+func declaresPropertyDepth(s *base.Schema, name string, depth int) bool {
+	if s == nil || depth > maxSchemaDepth {
+		return false
+	}
+	s = mergeAllOf(s)
+	if s.Properties != nil {
+		if _, ok := s.Properties.Get(name); ok {
+			return true
+		}
+	}
+	return slices.ContainsFunc(slices.Concat(s.OneOf, s.AnyOf), func(sp *base.SchemaProxy) bool {
+		return sp != nil && declaresPropertyDepth(sp.Schema(), name, depth+1)
+	})
+}
+
+// itemsSchema returns the schema of an array schema's items, or nil if it has none.
+func itemsSchema(s *base.Schema) *base.Schema {
+	if s == nil || s.Items == nil || !s.Items.IsA() || s.Items.A == nil {
+		return nil
+	}
+	return s.Items.A.Schema()
+}
+
+// nestsProperty reports whether a request body schema has a property with a name below its top level,
+// that is, in an object within the body or within each of its items, rather than in the body or its items themselves.
+func nestsProperty(s *base.Schema, name string) bool {
+	return nestsPropertyDepth(s, name, 0)
+}
+
+func nestsPropertyDepth(s *base.Schema, name string, depth int) bool {
+	if s == nil || depth > maxSchemaDepth {
+		return false
+	}
+	s = mergeAllOf(s)
+	if s.Properties != nil {
+		for prop := s.Properties.First(); prop != nil; prop = prop.Next() {
+			if hasPropertyDepth(prop.Value().Schema(), name, depth+1) {
+				return true
+			}
+		}
+	}
+	for _, sp := range slices.Concat(s.OneOf, s.AnyOf) {
+		if sp != nil && nestsPropertyDepth(sp.Schema(), name, depth+1) {
+			return true
+		}
+	}
+	return nestsPropertyDepth(itemsSchema(s), name, depth+1)
+}
+
+// hasPropertyDepth reports whether a schema has a property with a name at any depth.
+func hasPropertyDepth(s *base.Schema, name string, depth int) bool {
+	if s == nil || depth > maxSchemaDepth {
+		return false
+	}
+	return declaresPropertyDepth(s, name, depth) || hasPropertyDepth(itemsSchema(s), name, depth+1) || nestsPropertyDepth(s, name, depth)
+}
+
+// sanitizeSchema removes keywords that would prevent a schema from resolving:
+// patterns that aren't valid Go regular expressions, and defaults that don't validate against their schema.
+// Specs in the wild commonly contain both, which would otherwise prevent a tool from being registered.
+func sanitizeSchema(s *jsonschema.Schema) error {
+	walkSchema(s, func(s *jsonschema.Schema) {
+		if s.Pattern != "" {
+			if _, err := regexp.Compile(s.Pattern); err != nil {
+				s.Pattern = ""
+			}
+		}
+		for pattern := range s.PatternProperties {
+			if _, err := regexp.Compile(pattern); err != nil {
+				delete(s.PatternProperties, pattern)
+			}
+		}
+	})
+	opts := &jsonschema.ResolveOptions{ValidateDefaults: true}
+	if _, err := s.Resolve(opts); err == nil {
+		return nil
+	}
+	walkSchema(s, func(s *jsonschema.Schema) { s.Default = nil })
+	_, err := s.Resolve(opts)
+	return err
+}
+
+// walkSchema calls f for s and each of its subschemas.
+func walkSchema(s *jsonschema.Schema, f func(*jsonschema.Schema)) {
+	if s == nil {
+		return
+	}
+	f(s)
+	for _, child := range s.Properties {
+		walkSchema(child, f)
+	}
+	for _, child := range s.PatternProperties {
+		walkSchema(child, f)
+	}
+	for _, list := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf, s.PrefixItems} {
+		for _, child := range list {
+			walkSchema(child, f)
+		}
+	}
+	for _, child := range []*jsonschema.Schema{s.Items, s.AdditionalProperties, s.Not, s.If, s.Then, s.Else, s.Contains} {
+		walkSchema(child, f)
+	}
+}
+
+// isJSONMediaType reports whether a media type is JSON, including structured suffixes like application/geo+json.
+func isJSONMediaType(mediaType string) bool {
+	mediaType = baseMediaType(mediaType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// successResponseSchema returns the JSON schema of the first successful (2XX) response for an operation,
+// falling back to the default response.
+func successResponseSchema(op *v3.Operation) *base.Schema {
+	if op == nil || op.Responses == nil {
+		return nil
+	}
+	if op.Responses.Codes != nil {
+		for pair := op.Responses.Codes.First(); pair != nil; pair = pair.Next() {
+			if !strings.HasPrefix(pair.Key(), "2") {
+				continue
+			}
+			if s := jsonContentSchema(pair.Value()); s != nil {
+				return s
+			}
+		}
+	}
+	return jsonContentSchema(op.Responses.Default)
+}
+
+func jsonContentSchema(resp *v3.Response) *base.Schema {
+	if resp == nil || resp.Content == nil {
+		return nil
+	}
+	for pair := resp.Content.First(); pair != nil; pair = pair.Next() {
+		if !isJSONMediaType(pair.Key()) || pair.Value() == nil || pair.Value().Schema == nil {
+			continue
+		}
+		if s := pair.Value().Schema.Schema(); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// outputSchema returns the MCP output schema for an operation, if its successful response is a JSON object.
+// MCP requires output schemas to describe objects, so array and scalar responses have no output schema.
+func outputSchema(op *v3.Operation) *jsonschema.Schema {
+	s := successResponseSchema(op)
+	if s == nil {
+		return nil
+	}
+	js := convertSchema(s, responseDirection)
+	if js.Type != "object" && !(js.Type == "" && len(js.Types) == 0 && js.Properties != nil) {
+		return nil
+	}
+	js.Type, js.Types = "object", nil
+	return js
+}
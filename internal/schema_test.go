@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/pb33f/libopenapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertSchema(t *testing.T) {
+	spec := `{
+  "openapi": "3.0.3",
+  "info": {"title": "Schema API", "version": "1.0.0"},
+  "paths": {},
+  "components": {
+    "schemas": {
+      "Pet": {
+        "type": "object",
+        "required": ["id", "name", "secret"],
+        "properties": {
+          "id": {"type": "integer", "format": "int64", "readOnly": true},
+          "name": {"type": "string", "minLength": 1},
+          "secret": {"type": "string", "writeOnly": true},
+          "age": {"type": "integer", "minimum": 0, "exclusiveMinimum": true},
+          "status": {"type": "string", "enum": ["available", "sold"], "default": "available"},
+          "nickname": {"type": "string", "nullable": true},
+          "tags": {"type": "array", "items": {"type": "string"}},
+          "attributes": {"type": "object", "additionalProperties": false}
+        }
+      }
+    }
+  }
+}`
+	doc, err := libopenapi.NewDocument([]byte(spec))
+	require.NoError(t, err)
+	model, errs := doc.BuildV3Model()
+	require.Empty(t, errs)
+	pet, ok := model.Model.Components.Schemas.Get("Pet")
+	require.True(t, ok)
+
+	request := convertSchema(pet.Schema(), requestDirection)
+	assert.Equal(t, "object", request.Type)
+	assert.NotContains(t, request.Properties, "id")
+	assert.Contains(t, request.Properties, "secret")
+	assert.Equal(t, []string{"name", "secret"}, request.Required)
+	assert.Equal(t, 1, *request.Properties["name"].MinLength)
+	assert.Equal(t, 0.0, *request.Properties["age"].ExclusiveMinimum)
+	assert.Nil(t, request.Properties["age"].Minimum)
+	assert.Equal(t, []any{"available", "sold"}, request.Properties["status"].Enum)
+	assert.JSONEq(t, `"available"`, string(request.Properties["status"].Default))
+	assert.Equal(t, []string{"string", "null"}, request.Properties["nickname"].Types)
+	assert.Equal(t, "string", request.Properties["tags"].Items.Type)
+	assert.NotNil(t, request.Properties["attributes"].AdditionalProperties.Not)
+
+	response := convertSchema(pet.Schema(), responseDirection)
+	assert.Contains(t, response.Properties, "id")
+	assert.Equal(t, "int64", response.Properties["id"].Format)
+	assert.NotContains(t, response.Properties, "secret")
+	assert.Equal(t, []string{"id", "name"}, response.Required)
+}
+
+func TestRegisterToolsOutputSchema(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/pets/1":
+			_, _ = w.Write([]byte(`{"id": 1, "name": "Fido"}`))
+		default:
+			_, _ = w.Write([]byte(`[{"id": 1, "name": "Fido"}]`))
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {
+      "get": {
+        "operationId": "listPets",
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}}}
+          }
+        }
+      }
+    },
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Pet": {
+        "type": "object",
+        "required": ["id", "name"],
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string"}
+        }
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	schemas := make(map[string]*mcp.Tool)
+	for _, tool := range tools.Tools {
+		schemas[tool.Name] = tool
+	}
+	require.NotNil(t, schemas["getPet"].OutputSchema)
+	assert.Equal(t, "object", schemas["getPet"].OutputSchema.Type)
+	assert.Contains(t, schemas["getPet"].OutputSchema.Properties, "name")
+	assert.Nil(t, schemas["listPets"].OutputSchema, "array responses have no output schema")
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": "1"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	structured, err := json.Marshal(result.StructuredContent)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 1, "name": "Fido"}`, string(structured))
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "listPets", Arguments: map[string]any{}})
+	require.NoError(t, err)
+	assert.Nil(t, result.StructuredContent)
+}
@@ -2,9 +2,13 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -15,10 +19,47 @@ var (
 	LookPath = exec.LookPath
 )
 
-// ResolveSecretReference attempts to resolve a 1Password secret reference (e.g. op://vault/item/field)
+// Prefixes of secret references.
+const (
+	onePasswordReferencePrefix = "op://"
+	envReferencePrefix         = "env://"
+	fileReferencePrefix        = "file://"
+	vaultReferencePrefix       = "vault://"
+	keyringReferencePrefix     = "keyring://"
+)
+
+// IsSecretReference reports whether a value is a secret reference, rather than a secret itself.
+func IsSecretReference(value string) bool {
+	return strings.HasPrefix(value, onePasswordReferencePrefix) ||
+		strings.HasPrefix(value, envReferencePrefix) ||
+		strings.HasPrefix(value, fileReferencePrefix) ||
+		strings.HasPrefix(value, vaultReferencePrefix) ||
+		strings.HasPrefix(value, keyringReferencePrefix)
+}
+
+// ResolveSecretReference attempts to resolve a secret reference, which is one of:
+//   - a 1Password secret reference (e.g. op://vault/item/field)
+//   - an environment variable (e.g. env://API_TOKEN)
+//   - a file containing the secret (e.g. file:///run/secrets/api-token)
+//   - a HashiCorp Vault secret (e.g. vault://secret/api#token)
+//   - a secret in the operating system's keyring (e.g. keyring://api.example.com/alice)
+//
 // Returns the resolved value and whether it was a secret reference
 func ResolveSecretReference(ctx context.Context, value string) (string, bool, error) {
-	if !strings.HasPrefix(value, "op://") {
+	switch {
+	case strings.HasPrefix(value, envReferencePrefix):
+		secret, err := resolveEnvReference(strings.TrimPrefix(value, envReferencePrefix))
+		return secret, true, err
+	case strings.HasPrefix(value, fileReferencePrefix):
+		secret, err := resolveFileReference(strings.TrimPrefix(value, fileReferencePrefix))
+		return secret, true, err
+	case strings.HasPrefix(value, vaultReferencePrefix):
+		secret, err := resolveVaultReference(ctx, strings.TrimPrefix(value, vaultReferencePrefix))
+		return secret, true, err
+	case strings.HasPrefix(value, keyringReferencePrefix):
+		secret, err := resolveKeyringReference(ctx, strings.TrimPrefix(value, keyringReferencePrefix))
+		return secret, true, err
+	case !strings.HasPrefix(value, onePasswordReferencePrefix):
 		return value, false, nil
 	}
 
@@ -41,3 +82,128 @@ func ResolveSecretReference(ctx context.Context, value string) (string, bool, er
 	// Trim any whitespace/newlines from the output
 	return strings.TrimSpace(string(output)), true, nil
 }
+
+// resolveEnvReference reads a secret from an environment variable, which must be set and not empty.
+func resolveEnvReference(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("environment variable reference has no variable name")
+	}
+	secret := strings.TrimSpace(os.Getenv(name))
+	if secret == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return secret, nil
+}
+
+// resolveFileReference reads a secret from a file, trimming surrounding whitespace.
+// Paths are absolute (file:///run/secrets/token) or relative to the working directory (file://token.txt).
+func resolveFileReference(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("file reference has no path")
+	}
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from file: %w", err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
+
+// resolveVaultReference reads a field of a secret from HashiCorp Vault,
+// given a reference of the form mount/path#field.
+// The server and token are read from the VAULT_ADDR and VAULT_TOKEN environment variables,
+// and the namespace, if any, from VAULT_NAMESPACE.
+// Secrets are read from a KV version 2 engine, falling back to version 1.
+func resolveVaultReference(ctx context.Context, reference string) (string, error) {
+	location, field, _ := strings.Cut(reference, "#")
+	mount, path, _ := strings.Cut(location, "/")
+	if mount == "" || path == "" || field == "" {
+		return "", fmt.Errorf("invalid Vault secret reference %q: expected vault://mount/path#field", vaultReferencePrefix+reference)
+	}
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR must be set to read secrets from Vault")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN must be set to read secrets from Vault")
+	}
+
+	base := strings.TrimSuffix(addr, "/") + "/v1/" + mount + "/"
+	data, err := readVaultSecret(ctx, base+"data/"+path, token)
+	if errors.Is(err, errVaultSecretNotFound) {
+		data, err = readVaultSecret(ctx, base+path, token)
+	} else if err == nil {
+		// KV version 2 nests the secret's fields alongside its metadata
+		var v2 struct {
+			Data map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(data, &v2); err != nil {
+			return "", fmt.Errorf("failed to read secret from Vault: %w", err)
+		}
+		return vaultField(v2.Data, field)
+	}
+	if errors.Is(err, errVaultSecretNotFound) {
+		return "", fmt.Errorf("%w: %s", err, location)
+	} else if err != nil {
+		return "", err
+	}
+
+	var v1 map[string]any
+	if err := json.Unmarshal(data, &v1); err != nil {
+		return "", fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	return vaultField(v1, field)
+}
+
+var errVaultSecretNotFound = errors.New("secret not found in Vault")
+
+// readVaultSecret returns the data of the secret at url.
+func readVaultSecret(ctx context.Context, url, token string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errVaultSecretNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to read secret from Vault: %s %s", resp.Status, strings.Join(body.Errors, "; "))
+	}
+	return body.Data, nil
+}
+
+// vaultField returns the value of a field of a Vault secret, which must be a string.
+func vaultField(data map[string]any, field string) (string, error) {
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("secret in Vault has no field %q", field)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %q of secret in Vault isn't a string", field)
+	}
+	return secret, nil
+}
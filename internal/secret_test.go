@@ -2,8 +2,15 @@ package internal
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestResolveSecretReference(t *testing.T) {
@@ -104,3 +111,97 @@ func TestResolveSecretReference(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveEnvAndFileReferences(t *testing.T) {
+	t.Setenv("EMCEE_TEST_TOKEN", "env-secret")
+	t.Setenv("EMCEE_TEST_EMPTY", "")
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("file-secret\n"), 0o600))
+
+	tests := []struct {
+		name      string
+		input     string
+		wantValue string
+		wantErr   bool
+	}{
+		{name: "environment variable", input: "env://EMCEE_TEST_TOKEN", wantValue: "env-secret"},
+		{name: "unset environment variable", input: "env://EMCEE_TEST_MISSING", wantErr: true},
+		{name: "empty environment variable", input: "env://EMCEE_TEST_EMPTY", wantErr: true},
+		{name: "missing variable name", input: "env://", wantErr: true},
+		{name: "file", input: "file://" + path, wantValue: "file-secret"},
+		{name: "missing file", input: "file://" + path + ".missing", wantErr: true},
+		{name: "missing path", input: "file://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, IsSecretReference(tt.input))
+			got, isSecret, err := ResolveSecretReference(context.Background(), tt.input)
+			assert.True(t, isSecret)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValue, got)
+		})
+	}
+}
+
+func TestResolveVaultReference(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/api":
+			_, _ = w.Write([]byte(`{"data": {"data": {"token": "kv2-secret", "port": 8080}, "metadata": {"version": 3}}}`))
+		case "/v1/legacy/api":
+			_, _ = w.Write([]byte(`{"data": {"token": "kv1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "s.token")
+
+	tests := []struct {
+		name      string
+		input     string
+		wantValue string
+		wantErr   string
+	}{
+		{name: "KV version 2", input: "vault://secret/api#token", wantValue: "kv2-secret"},
+		{name: "KV version 1", input: "vault://legacy/api#token", wantValue: "kv1-secret"},
+		{name: "missing secret", input: "vault://secret/missing#token", wantErr: "secret not found in Vault"},
+		{name: "missing field", input: "vault://secret/api#password", wantErr: `no field "password"`},
+		{name: "non-string field", input: "vault://secret/api#port", wantErr: "isn't a string"},
+		{name: "missing field name", input: "vault://secret/api", wantErr: "expected vault://mount/path#field"},
+		{name: "missing path", input: "vault://secret#token", wantErr: "expected vault://mount/path#field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isSecret, err := ResolveSecretReference(context.Background(), tt.input)
+			assert.True(t, isSecret)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantValue, got)
+		})
+	}
+
+	t.Setenv("VAULT_TOKEN", "s.wrong")
+	_, _, err := ResolveSecretReference(context.Background(), "vault://secret/api#token")
+	assert.ErrorContains(t, err, "permission denied")
+
+	t.Setenv("VAULT_ADDR", "")
+	_, _, err = ResolveSecretReference(context.Background(), "vault://secret/api#token")
+	assert.ErrorContains(t, err, "VAULT_ADDR")
+}
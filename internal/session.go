@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionMetaKey is the _meta key of a tool call that carries the session of the client that made it.
+// Calls in a tools/callBatch request are made through in-process sessions of their own,
+// so the batch caller adds the session of the client that sent the batch.
+const sessionMetaKey = "emcee/session"
+
+type sessionContextKey struct{}
+
+// contextWithSession returns a context for handling requests from the client session with the given ID.
+func contextWithSession(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, id)
+}
+
+// sessionFromContext returns the ID of the client session whose request is being handled, if any.
+func sessionFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionContextKey{}).(string)
+	return id
+}
+
+// sessionID returns the ID of the client session that made a tool call,
+// for keeping state like confirmation tokens from being shared between clients.
+// Sessions over stdio and in-process sessions have no ID of their own,
+// so calls in them are attributed to the session in their _meta, if any.
+// Clients of sessions with IDs can't claim to be another session.
+func sessionID(req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) string {
+	if req == nil {
+		return ""
+	}
+	if req.Session != nil {
+		if id := req.Session.ID(); id != "" {
+			return id
+		}
+	}
+	if req.Params != nil {
+		if id, ok := req.Params.Meta[sessionMetaKey].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
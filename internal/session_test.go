@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionID(t *testing.T) {
+	dir, err := os.MkdirTemp("", "emcee")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "mcp.sock")
+	ln, err := Listen("unix://" + path)
+	require.NoError(t, err)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	server.AddTool(&mcp.Tool{Name: "whoami", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: sessionID(req)}}}, nil
+	})
+	batchCaller := NewBatchCaller(server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go func() {
+		_ = Serve(ctx, server, ln, func(s *Stdio) {
+			s.Methods = map[string]MethodHandler{CallBatchMethod: batchCaller.Handle}
+		}, DefaultShutdownTimeout)
+	}()
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	responses := bufio.NewScanner(conn)
+	call := func(msg string) json.RawMessage {
+		_, err := conn.Write([]byte(msg + "\n"))
+		require.NoError(t, err)
+		require.True(t, responses.Scan())
+		var resp struct {
+			Result json.RawMessage `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(responses.Bytes(), &resp))
+		return resp.Result
+	}
+	call(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"dev"}}}`)
+	_, err = conn.Write([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n"))
+	require.NoError(t, err)
+
+	// A client can't claim to be another session
+	var direct mcp.CallToolResult
+	require.NoError(t, json.Unmarshal(call(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami","_meta":{"emcee/session":"other"}}}`), &direct))
+	id := direct.Content[0].(*mcp.TextContent).Text
+	assert.NotEmpty(t, id)
+	assert.NotEqual(t, "other", id)
+
+	// Calls in a batch are attributed to the session that sent it
+	var batch struct {
+		Results []struct {
+			Result struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"result"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(call(`{"jsonrpc":"2.0","id":3,"method":"tools/callBatch","params":{"calls":[{"name":"whoami"}]}}`), &batch))
+	require.Len(t, batch.Results, 1)
+	assert.Equal(t, id, batch.Results[0].Result.Content[0].Text)
+
+	// Other clients have sessions of their own
+	other, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	stdio := &Stdio{In: other, Out: other, Err: io.Discard}
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "dev"}, nil)
+	session, err := client.Connect(ctx, stdio.Transport(), nil)
+	require.NoError(t, err)
+	defer session.Close()
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "whoami"})
+	require.NoError(t, err)
+	otherID := result.Content[0].(*mcp.TextContent).Text
+	assert.NotEmpty(t, otherID)
+	assert.NotEqual(t, id, otherID)
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// DefaultShutdownTimeout is how long requests in flight at shutdown are given to finish, unless set otherwise.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Run serves a client over a transport until the client disconnects or ctx is done.
+// When ctx is done, the session stops accepting requests, answering new calls with an error,
+// and the requests in flight are given until timeout to finish and have their responses written
+// before the session is closed. Requests still in flight after that are canceled,
+// along with the API requests they're waiting on.
+//
+// Unlike mcp.Server.Run, canceling ctx doesn't cancel the requests in flight right away.
+// Run returns ctx.Err() once the session is closed after ctx is done,
+// or an error saying requests were canceled if they didn't finish in time.
+func Run(ctx context.Context, server *mcp.Server, transport mcp.Transport, timeout time.Duration) error {
+	sessionCtx, abort := abortable(ctx, server)
+	defer abort()
+	session, err := server.Connect(sessionCtx, transport, nil)
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	if err := drain(session, done, timeout, abort); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// abortable returns the context for serving a session of a server until ctx is done,
+// and a function that cancels the session's requests in flight.
+// Requests are canceled by abort, rather than by ctx, so they can finish after ctx is done.
+func abortable(ctx context.Context, server *mcp.Server) (context.Context, context.CancelFunc) {
+	if _, loaded := abortableServers.LoadOrStore(server, true); !loaded {
+		server.AddReceivingMiddleware(abortMiddleware)
+	}
+	aborted, abort := context.WithCancel(context.Background())
+	return context.WithValue(context.WithoutCancel(ctx), abortKey{}, aborted), abort
+}
+
+// abortableServers records the servers that abortMiddleware was added to.
+var abortableServers sync.Map // by *mcp.Server
+
+type abortKey struct{}
+
+// abortMiddleware cancels requests when their session is aborted.
+// The SDK doesn't cancel requests when the context of their session is done,
+// but the context's values are passed on to them, including the one abortable sets.
+func abortMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if aborted, ok := ctx.Value(abortKey{}).(context.Context); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(ctx)
+			defer cancel()
+			defer context.AfterFunc(aborted, cancel)()
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// drain closes a session once its requests in flight finish and their responses are written,
+// canceling the requests if they haven't finished within timeout.
+// done receives the result of waiting for the session.
+func drain(session *mcp.ServerSession, done <-chan error, timeout time.Duration, abort context.CancelFunc) error {
+	// Closing the session answers new calls with an error, and waits for the requests in flight
+	go session.Close()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		abort()
+		<-done
+		return fmt.Errorf("canceled requests still in flight %v after shutdown began", timeout)
+	}
+}
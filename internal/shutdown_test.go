@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunShutdown(t *testing.T) {
+	// start runs a server over pipes, returning functions for sending a message and reading the next one,
+	// and a channel receiving the result of Run.
+	// started is signaled when a call to the server's slow tool begins, which then waits for release or cancellation.
+	start := func(t *testing.T, ctx context.Context, timeout time.Duration, started chan<- struct{}, release <-chan struct{}) (func(string), func() map[string]any, <-chan error) {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		server.AddTool(&mcp.Tool{Name: "slow", InputSchema: &jsonschema.Schema{Type: "object"}}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+			started <- struct{}{}
+			select {
+			case <-release:
+				return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: "done"}}}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+
+		in, inWriter := io.Pipe()
+		outReader, out := io.Pipe()
+		ran := make(chan error, 1)
+		go func() { ran <- Run(ctx, server, (&Stdio{In: in, Out: out, Err: io.Discard}).Transport(), timeout) }()
+
+		lines := bufio.NewScanner(outReader)
+		send := func(line string) {
+			_, err := inWriter.Write([]byte(line + "\n"))
+			require.NoError(t, err)
+		}
+		read := func() map[string]any {
+			require.True(t, lines.Scan())
+			var msg map[string]any
+			require.NoError(t, json.Unmarshal(lines.Bytes(), &msg))
+			return msg
+		}
+		send(`{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"dev"}}}`)
+		read()
+		send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+		return send, read, ran
+	}
+	call := func(id int) string {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"slow"}}`, id)
+	}
+
+	t.Run("drains requests in flight", func(t *testing.T) {
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		send, read, ran := start(t, ctx, 5*time.Second, started, release)
+		send(call(1))
+		<-started
+		cancel()
+
+		// New requests are refused once shutdown begins, while the call in flight finishes.
+		// Requests are handled one at a time, so a request sent before then would wait for the call instead.
+		time.Sleep(50 * time.Millisecond)
+		send(call(2))
+		refused := read()
+		assert.EqualValues(t, 2, refused["id"])
+		assert.Contains(t, refused, "error")
+
+		close(release)
+		finished := read()
+		assert.EqualValues(t, 1, finished["id"])
+		assert.Contains(t, fmt.Sprint(finished["result"]), "done")
+		assert.ErrorIs(t, <-ran, context.Canceled)
+	})
+
+	t.Run("cancels requests after the timeout", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		send, read, ran := start(t, ctx, 50*time.Millisecond, started, nil)
+		send(call(1))
+		<-started
+		cancel()
+
+		resp := read()
+		assert.EqualValues(t, 1, resp["id"])
+		assert.ErrorContains(t, <-ran, "canceled requests still in flight")
+	})
+}
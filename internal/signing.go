@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestSigning signs every request to the API with an HMAC, for APIs that require signed requests,
+// like a signature of a timestamp and the body in an X-Signature header.
+type RequestSigning struct {
+	// Secret is the signing key, or a secret reference like env://API_SIGNING_SECRET.
+	Secret string `yaml:"secret" json:"secret,omitempty"`
+	// Algorithm is the HMAC's hash function: sha256 (the default), sha512, or sha1.
+	Algorithm string `yaml:"algorithm" json:"algorithm,omitempty"`
+	// Encoding is how the signature is written: hex (the default) or base64.
+	Encoding string `yaml:"encoding" json:"encoding,omitempty"`
+	// Header is the header the signature is sent in (default X-Signature).
+	Header string `yaml:"header" json:"header,omitempty"`
+	// Prefix is written before the signature in the header, like "sha256=".
+	Prefix string `yaml:"prefix" json:"prefix,omitempty"`
+	// TimestampHeader, if set, is the header the time the request was signed is sent in.
+	TimestampHeader string `yaml:"timestampHeader" json:"timestampHeader,omitempty"`
+	// TimestampFormat is how the time is written: unix, in seconds (the default), unixMilli, or rfc3339.
+	TimestampFormat string `yaml:"timestampFormat" json:"timestampFormat,omitempty"`
+	// Message is the text that's signed, with placeholders for parts of the request:
+	// {method}, {host}, {path}, {query} (with parameters sorted by name), {body}, {bodySha256} (the body's hex SHA-256 digest),
+	// {timestamp}, and {header:Name}. It defaults to "{timestamp}{body}" with a timestamp header, and "{body}" without one.
+	Message string `yaml:"message" json:"message,omitempty"`
+}
+
+// signingAlgorithms are the hash functions of the HMACs requests can be signed with, by name.
+var signingAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"sha1":   sha1.New,
+}
+
+// signingPlaceholders are the placeholders of a signed message, other than {header:Name}.
+var signingPlaceholders = []string{"method", "host", "path", "query", "body", "bodySha256", "timestamp"}
+
+// validate checks that the algorithm, encoding, and timestamp format are known,
+// and that the message's placeholders are.
+func (s *RequestSigning) validate() error {
+	if s.Secret == "" {
+		return fmt.Errorf("invalid signing: secret is empty")
+	}
+	if _, ok := signingAlgorithms[s.algorithm()]; !ok {
+		return fmt.Errorf("invalid signing algorithm %q (expected sha256, sha512, or sha1)", s.Algorithm)
+	}
+	switch s.Encoding {
+	case "", "hex", "base64":
+	default:
+		return fmt.Errorf("invalid signing encoding %q (expected hex or base64)", s.Encoding)
+	}
+	switch s.TimestampFormat {
+	case "", "unix", "unixMilli", "rfc3339":
+	default:
+		return fmt.Errorf("invalid signing timestampFormat %q (expected unix, unixMilli, or rfc3339)", s.TimestampFormat)
+	}
+	placeholders, err := messagePlaceholders(s.message())
+	if err != nil {
+		return fmt.Errorf("invalid signing message: %w", err)
+	}
+	for _, placeholder := range placeholders {
+		if name, ok := strings.CutPrefix(placeholder, "header:"); ok {
+			if name == "" {
+				return fmt.Errorf("invalid signing message: placeholder {header:} has no header name")
+			}
+			continue
+		}
+		if !slices.Contains(signingPlaceholders, placeholder) {
+			return fmt.Errorf("invalid signing message: unknown placeholder {%s}", placeholder)
+		}
+		if placeholder == "timestamp" && s.TimestampHeader == "" {
+			return fmt.Errorf("invalid signing message: {timestamp} requires a timestampHeader")
+		}
+	}
+	return nil
+}
+
+func (s *RequestSigning) algorithm() string {
+	if s.Algorithm == "" {
+		return "sha256"
+	}
+	return s.Algorithm
+}
+
+func (s *RequestSigning) header() string {
+	if s.Header == "" {
+		return "X-Signature"
+	}
+	return s.Header
+}
+
+func (s *RequestSigning) message() string {
+	switch {
+	case s.Message != "":
+		return s.Message
+	case s.TimestampHeader != "":
+		return "{timestamp}{body}"
+	default:
+		return "{body}"
+	}
+}
+
+// timestamp writes the time a request was signed in the configured format.
+func (s *RequestSigning) timestamp(t time.Time) string {
+	switch s.TimestampFormat {
+	case "unixMilli":
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	case "rfc3339":
+		return t.UTC().Format(time.RFC3339)
+	default:
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+}
+
+// messagePlaceholders returns the names of the placeholders in a message, in order.
+func messagePlaceholders(message string) ([]string, error) {
+	var placeholders []string
+	for rest := message; ; {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			return placeholders, nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in %q", message)
+		}
+		placeholders = append(placeholders, rest[start+1:start+end])
+		rest = rest[start+end+1:]
+	}
+}
+
+// SigningTransport is a RoundTripper that signs requests as a RequestSigning says.
+type SigningTransport struct {
+	Base    http.RoundTripper
+	Signing *RequestSigning
+	// Now returns the time requests are signed at. If nil, time.Now is used.
+	Now func() time.Time
+
+	mu  sync.Mutex
+	key []byte
+}
+
+// RoundTrip signs a copy of the request and sends it.
+func (t *SigningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	key, err := t.signingKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := req.Clone(req.Context())
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		rc := req.Body
+		if req.GetBody != nil {
+			if rc, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body to sign: %w", err)
+		}
+		signed.Body = io.NopCloser(bytes.NewReader(body))
+		signed.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+
+	now := time.Now
+	if t.Now != nil {
+		now = t.Now
+	}
+	timestamp := t.Signing.timestamp(now())
+	if t.Signing.TimestampHeader != "" {
+		signed.Header.Set(t.Signing.TimestampHeader, timestamp)
+	}
+
+	mac := hmac.New(signingAlgorithms[t.Signing.algorithm()], key)
+	io.WriteString(mac, signingMessage(t.Signing.message(), signed, body, timestamp))
+	sum := mac.Sum(nil)
+	signature := hex.EncodeToString(sum)
+	if t.Signing.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(sum)
+	}
+	signed.Header.Set(t.Signing.header(), t.Signing.Prefix+signature)
+	return base.RoundTrip(signed)
+}
+
+// signingKey returns the signing key, resolving the secret reference on first use.
+func (t *SigningTransport) signingKey(req *http.Request) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.key != nil {
+		return t.key, nil
+	}
+	secret := t.Signing.Secret
+	if IsSecretReference(secret) {
+		var err error
+		if secret, _, err = ResolveSecretReference(req.Context(), secret); err != nil {
+			return nil, fmt.Errorf("error resolving signing secret: %w", err)
+		}
+	}
+	t.key = []byte(secret)
+	return t.key, nil
+}
+
+// signingMessage fills in the placeholders of a message with the parts of a request.
+func signingMessage(message string, req *http.Request, body []byte, timestamp string) string {
+	var b strings.Builder
+	for rest := message; ; {
+		start := strings.IndexByte(rest, '{')
+		end := strings.IndexByte(rest[max(start, 0):], '}')
+		if start < 0 || end < 0 {
+			b.WriteString(rest)
+			return b.String()
+		}
+		b.WriteString(rest[:start])
+		switch placeholder := rest[start+1 : start+end]; placeholder {
+		case "method":
+			b.WriteString(req.Method)
+		case "host":
+			b.WriteString(req.URL.Host)
+		case "path":
+			b.WriteString(req.URL.EscapedPath())
+		case "query":
+			b.WriteString(req.URL.Query().Encode())
+		case "body":
+			b.Write(body)
+		case "bodySha256":
+			digest := sha256.Sum256(body)
+			b.WriteString(hex.EncodeToString(digest[:]))
+		case "timestamp":
+			b.WriteString(timestamp)
+		default:
+			name, _ := strings.CutPrefix(placeholder, "header:")
+			b.WriteString(req.Header.Get(name))
+		}
+		rest = rest[start+end+1:]
+	}
+}
+
+// signingClient returns a copy of client that signs its requests.
+func signingClient(client *http.Client, signing *RequestSigning) *http.Client {
+	signed := *client
+	signed.Transport = &SigningTransport{Base: client.Transport, Signing: signing}
+	return &signed
+}
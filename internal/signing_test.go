@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestSigning(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	t.Setenv("EMCEE_TEST_SIGNING_SECRET", "s3cret")
+
+	var verified []bool
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(r.Header.Get("X-Timestamp") + "." + r.Method + "." + r.URL.Path + "." + string(body)))
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		verified = append(verified, r.Header.Get("X-Hub-Sig") == expected)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer api.Close()
+
+	config, err := ParseConfig([]byte(`
+signing:
+  secret: env://EMCEE_TEST_SIGNING_SECRET
+  header: X-Hub-Sig
+  prefix: "sha256="
+  timestampHeader: X-Timestamp
+  message: "{timestamp}.{method}.{path}.{body}"
+`))
+	require.NoError(t, err)
+
+	spec := []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "` + api.URL + `"}],
+  "paths": {
+    "/pets": {"post": {
+      "operationId": "createPet",
+      "requestBody": {"content": {"application/json": {"schema": {"type": "object", "properties": {"name": {"type": "string"}}}}}},
+      "responses": {"200": {"description": "OK"}}
+    }}
+  }
+}`)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, spec, api.Client(), WithConfig(config), WithDryRunArgument()))
+	clientSession := connectTestClient(t, ctx, server)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "createPet", Arguments: map[string]any{"name": "Rex"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Equal(t, []bool{true}, verified)
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "createPet", Arguments: map[string]any{"name": "Rex", dryRunArgument: true}})
+	require.NoError(t, err)
+	text := result.Content[0].(*mcp.TextContent).Text
+	assert.Contains(t, text, "X-Timestamp", "dry runs show the requests as they'd be signed")
+	assert.NotContains(t, text, "sha256=", "signatures are redacted from dry runs")
+	assert.Len(t, verified, 1)
+}
+
+func TestSigningTransport(t *testing.T) {
+	var header http.Header
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer api.Close()
+
+	signing := &RequestSigning{Secret: "key", Encoding: "base64", Message: "{method} {path}?{query} {bodySha256} {header:X-Tenant}"}
+	require.NoError(t, signing.validate())
+	client := &http.Client{Transport: &SigningTransport{Signing: signing}}
+	req, err := http.NewRequest(http.MethodGet, api.URL+"/pets?b=2&a=1", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Tenant", "acme")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	emptyDigest := sha256.Sum256(nil)
+	message := "GET /pets?a=1&b=2 " + hex.EncodeToString(emptyDigest[:]) + " acme"
+	assert.Equal(t, message, signingMessage(signing.message(), req, nil, ""), "query parameters are signed in order")
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte(message))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(mac.Sum(nil)), header.Get("X-Signature"))
+	assert.Empty(t, req.Header.Get("X-Signature"), "the original request isn't changed")
+}
+
+func TestRequestSigningValidation(t *testing.T) {
+	for _, tc := range []struct {
+		config, err string
+	}{
+		{"signing: {algorithm: sha256}", "secret is empty"},
+		{"signing: {secret: x, algorithm: md5}", `invalid signing algorithm "md5"`},
+		{"signing: {secret: x, encoding: base32}", `invalid signing encoding "base32"`},
+		{"signing: {secret: x, timestampFormat: iso}", `invalid signing timestampFormat "iso"`},
+		{`signing: {secret: x, message: "{verb}"}`, "unknown placeholder {verb}"},
+		{`signing: {secret: x, message: "{body"}`, "unclosed placeholder"},
+		{`signing: {secret: x, message: "{timestamp}{body}"}`, "requires a timestampHeader"},
+	} {
+		_, err := ParseConfig([]byte(tc.config))
+		assert.ErrorContains(t, err, tc.err, tc.config)
+	}
+	_, err := ParseConfig([]byte(`signing: {secret: x, timestampHeader: X-Timestamp, message: "{timestamp}\n{header:Date}"}`))
+	assert.NoError(t, err)
+}
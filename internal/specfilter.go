@@ -0,0 +1,250 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// prunedComponentKinds are the kinds of components that are only used through references,
+// and so can be dropped when nothing refers to them.
+// Other kinds, like securitySchemes, are referred to by name and always kept.
+var prunedComponentKinds = map[string]bool{
+	"schemas":       true,
+	"responses":     true,
+	"parameters":    true,
+	"examples":      true,
+	"requestBodies": true,
+	"headers":       true,
+	"links":         true,
+	"callbacks":     true,
+	"pathItems":     true,
+}
+
+// componentRefPattern matches local references to components, like "#/components/schemas/Pet".
+var componentRefPattern = regexp.MustCompile(`"#/components/([^/"]+)/([^/"]+)`)
+
+// FilterSpec reads a JSON OpenAPI spec incrementally, keeping only the paths and operations that the config doesn't disable,
+// and the components they refer to. It's used for specs too large to load whole:
+// each path item is decoded on its own, and the result is usually a small fraction of the original.
+// A nil config keeps every path.
+func FilterSpec(r io.Reader, c *Config) ([]byte, error) {
+	br := bufio.NewReader(r)
+	if !startsWithObject(br) {
+		return nil, fmt.Errorf("only JSON specs can be filtered")
+	}
+	dec := json.NewDecoder(br)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	values := map[string]json.RawMessage{}
+	var components map[string]map[string]json.RawMessage
+	var paths bytes.Buffer
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "paths":
+			if err := filterPaths(dec, c, &paths); err != nil {
+				return nil, err
+			}
+			values[key] = paths.Bytes()
+		case "components":
+			if err := dec.Decode(&components); err != nil {
+				return nil, fmt.Errorf("error decoding components: %w", err)
+			}
+		default:
+			var v json.RawMessage
+			if err := dec.Decode(&v); err != nil {
+				return nil, fmt.Errorf("error decoding %s: %w", key, err)
+			}
+			values[key] = v
+		}
+		fields = append(fields, key)
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	// Keep the components reachable from everything else in the spec
+	if components != nil {
+		var kept []json.RawMessage
+		for _, v := range values {
+			kept = append(kept, v)
+		}
+		pruned, err := json.Marshal(reachableComponents(components, kept))
+		if err != nil {
+			return nil, err
+		}
+		values["components"] = pruned
+	}
+
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, key := range fields {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(values[key])
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// startsWithObject reports whether the first non-whitespace byte of r begins a JSON object.
+func startsWithObject(r *bufio.Reader) bool {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case 0xEF: // byte order mark
+			_, _ = r.Discard(2)
+			continue
+		}
+		_ = r.UnreadByte()
+		return b == '{'
+	}
+}
+
+// filterPaths copies the paths object from dec to out, omitting disabled paths and operations.
+func filterPaths(dec *json.Decoder, c *Config, out *bytes.Buffer) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	out.WriteByte('{')
+	first := true
+	for dec.More() {
+		p, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		var item map[string]json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("error decoding path %s: %w", p, err)
+		}
+		kept := 0
+		if _, ok := item["$ref"]; ok && !c.disablesPath(p) {
+			kept++ // the operations of a referenced path item are filtered when it's resolved
+		}
+		for method, raw := range item {
+			if !isOperationField(method) {
+				continue
+			}
+			var op struct {
+				OperationID string `json:"operationId"`
+			}
+			_ = json.Unmarshal(raw, &op)
+			if c.disables(strings.ToUpper(method), p, op.OperationID) {
+				delete(item, method)
+				continue
+			}
+			kept++
+		}
+		if kept == 0 {
+			continue
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if !first {
+			out.WriteByte(',')
+		}
+		first = false
+		name, _ := json.Marshal(p)
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(data)
+	}
+	out.WriteByte('}')
+	return expectDelim(dec, '}')
+}
+
+// isOperationField reports whether a path item field is an operation.
+func isOperationField(name string) bool {
+	switch strings.ToLower(name) {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace", "query", "x-query":
+		return true
+	}
+	return false
+}
+
+// reachableComponents returns the components referred to, directly or indirectly, by roots.
+func reachableComponents(components map[string]map[string]json.RawMessage, roots []json.RawMessage) map[string]map[string]json.RawMessage {
+	kept := make(map[string]map[string]json.RawMessage, len(components))
+	for kind, byName := range components {
+		if !prunedComponentKinds[kind] {
+			kept[kind] = byName
+			for _, v := range byName {
+				roots = append(roots, v)
+			}
+		}
+	}
+
+	queue := roots
+	for len(queue) > 0 {
+		data := queue[0]
+		queue = queue[1:]
+		for _, m := range componentRefPattern.FindAllSubmatch(data, -1) {
+			kind, name := string(m[1]), unescapePointer(string(m[2]))
+			v, ok := components[kind][name]
+			if !ok || !prunedComponentKinds[kind] {
+				continue
+			}
+			if _, seen := kept[kind][name]; seen {
+				continue
+			}
+			if kept[kind] == nil {
+				kept[kind] = map[string]json.RawMessage{}
+			}
+			kept[kind][name] = v
+			queue = append(queue, v)
+		}
+	}
+	return kept
+}
+
+// unescapePointer decodes a JSON Pointer reference token (RFC 6901).
+func unescapePointer(s string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(s)
+}
+
+// objectKey reads the next key of an object.
+func objectKey(dec *json.Decoder) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", fmt.Errorf("error reading spec: %w", err)
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", fmt.Errorf("error reading spec: expected object key, got %v", tok)
+	}
+	return key, nil
+}
+
+// expectDelim reads the next token, which must be the given delimiter.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("error reading spec: %w", err)
+	}
+	if tok != delim {
+		return fmt.Errorf("error reading spec: expected %v, got %v", delim, tok)
+	}
+	return nil
+}
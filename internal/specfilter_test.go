@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterSpec(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {
+      "get": {"operationId": "listPets", "responses": {"200": {"$ref": "#/components/responses/PetList"}}},
+      "post": {"operationId": "createPet", "requestBody": {"$ref": "#/components/requestBodies/NewPet"}, "responses": {"201": {"description": "Created"}}}
+    },
+    "/admin/users": {
+      "get": {"operationId": "listUsers", "responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}}}}
+    },
+    "/shared": {"$ref": "#/components/pathItems/Shared"}
+  },
+  "components": {
+    "schemas": {
+      "Pet": {"type": "object", "properties": {"owner": {"$ref": "#/components/schemas/Owner"}, "id": {"type": "integer", "maximum": 12345678901234567890}}},
+      "Owner": {"type": "object"},
+      "User": {"type": "object"},
+      "NewPet": {"type": "object"},
+      "a/b": {"type": "string"}
+    },
+    "responses": {
+      "PetList": {"description": "OK", "content": {"application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Pet"}}}}}
+    },
+    "requestBodies": {
+      "NewPet": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/NewPet"}}}}
+    },
+    "pathItems": {
+      "Shared": {"get": {"operationId": "getShared", "responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/a~1b"}}}}}}}
+    },
+    "securitySchemes": {
+      "apiKey": {"type": "apiKey", "in": "header", "name": "X-API-Key"}
+    }
+  }
+}`
+
+	data, err := FilterSpec(strings.NewReader(spec), &Config{
+		DisabledOperations: []string{"createPet"},
+		DisabledPaths:      []string{"/admin"},
+	})
+	require.NoError(t, err)
+
+	var filtered struct {
+		OpenAPI    string                                `json:"openapi"`
+		Info       map[string]any                        `json:"info"`
+		Servers    []any                                 `json:"servers"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components map[string]map[string]json.RawMessage `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &filtered))
+	assert.Equal(t, "3.1.0", filtered.OpenAPI)
+	assert.Equal(t, "Pet API", filtered.Info["title"])
+	assert.Len(t, filtered.Servers, 1)
+
+	assert.Len(t, filtered.Paths, 2)
+	assert.Contains(t, filtered.Paths["/pets"], "get")
+	assert.NotContains(t, filtered.Paths["/pets"], "post")
+	assert.Contains(t, filtered.Paths, "/shared")
+
+	keys := func(m map[string]json.RawMessage) []string {
+		var names []string
+		for name := range m {
+			names = append(names, name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"Pet", "Owner", "a/b"}, keys(filtered.Components["schemas"]))
+	assert.ElementsMatch(t, []string{"PetList"}, keys(filtered.Components["responses"]))
+	assert.NotContains(t, filtered.Components, "requestBodies")
+	assert.ElementsMatch(t, []string{"apiKey"}, keys(filtered.Components["securitySchemes"]), "security schemes are always kept")
+	assert.Contains(t, string(filtered.Components["schemas"]["Pet"]), "12345678901234567890", "numbers are copied exactly")
+}
+
+func TestFilterSpecWithoutConfig(t *testing.T) {
+	spec := `{"openapi": "3.1.0", "info": {"title": "API", "version": "1"}, "paths": {"/pets": {"get": {"operationId": "listPets"}}}}`
+	data, err := FilterSpec(strings.NewReader(spec), nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, spec, string(data))
+}
+
+func TestFilterSpecRejectsYAML(t *testing.T) {
+	_, err := FilterSpec(strings.NewReader("openapi: 3.1.0\n"), nil)
+	assert.ErrorContains(t, err, "only JSON specs")
+
+	_, err = FilterSpec(strings.NewReader(`{"paths": [`), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// LoadSpecs reads several OpenAPI specs concurrently, and registers tools for each on server as soon as it's read.
+// A spec that can't be read or registered is skipped with a warning, without affecting the others;
+// an error is returned only if no spec could be loaded.
+//
+// If timeout is positive, LoadSpecs returns once it elapses, even if some specs are still loading.
+// Those specs continue loading in the background, and their tools are added when they're ready,
+// so a slow spec delays only its own tools rather than the whole server.
+func LoadSpecs(ctx context.Context, server *mcp.Server, client *http.Client, sources []string, read func(ctx context.Context, source string) ([]byte, error), timeout time.Duration, opts ...RegisterToolsOption) error {
+	if len(sources) == 0 {
+		return fmt.Errorf("no OpenAPI specs provided")
+	}
+	cfg := &registerToolsConfig{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(cfg)
+		}
+	}
+	logger := cfg.logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	type outcome struct {
+		source string
+		err    error
+	}
+	outcomes := make(chan outcome, len(sources))
+	loader := &specLoader{server: server, client: client, opts: opts, logger: logger, owners: make(map[string]string)}
+	for _, source := range sources {
+		go func() {
+			data, err := read(ctx, source)
+			if err == nil {
+				err = loader.register(source, data)
+			}
+			outcomes <- outcome{source, err}
+		}()
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	var loaded int
+	var errs []error
+	for range sources {
+		select {
+		case o := <-outcomes:
+			if o.err != nil {
+				logger.Warn("skipping spec", "source", o.source, "error", o.err)
+				errs = append(errs, fmt.Errorf("%s: %w", o.source, o.err))
+				continue
+			}
+			loaded++
+		case <-deadline:
+			pending := len(sources) - loaded - len(errs)
+			logger.Warn("startup timeout elapsed before all specs loaded; continuing in the background", "pending", pending)
+			go func() {
+				for range pending {
+					if o := <-outcomes; o.err != nil {
+						logger.Warn("skipping spec", "source", o.source, "error", o.err)
+					} else {
+						logger.Info("loaded spec after startup", "source", o.source)
+					}
+				}
+			}()
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if loaded == 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// specLoader registers tools for specs loaded concurrently,
+// and warns when two specs define a tool with the same name.
+type specLoader struct {
+	server *mcp.Server
+	client *http.Client
+	opts   []RegisterToolsOption
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	owners map[string]string // source of each tool, by name
+}
+
+func (l *specLoader) register(source string, data []byte) error {
+	reg, err := registerTools(l.server, data, l.client, append(slices.Clip(l.opts), WithSpecLocation(source))...)
+	if err != nil {
+		return fmt.Errorf("error registering tools: %w", err)
+	}
+	l.server.AddReceivingMiddleware(reg.middleware...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, name := range reg.tools {
+		if owner, ok := l.owners[name]; ok && owner != source {
+			l.logger.Warn("tool defined by more than one spec; only one is available", "tool", name, "sources", []string{owner, source})
+		}
+		l.owners[name] = source
+	}
+	l.logger.Info("loaded spec", "source", source, "tools", len(reg.tools))
+	return nil
+}
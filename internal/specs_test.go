@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSpecs(t *testing.T) {
+	specs := map[string][]byte{
+		"pets.json":   reloadTestSpec(`"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}`),
+		"stores.json": reloadTestSpec(`"/stores": {"get": {"operationId": "listStores", "responses": {"200": {"description": "OK"}}}}`),
+		"broken.json": []byte(`{"openapi": "3.1.0"}`),
+	}
+	slow := make(chan struct{})
+	read := func(ctx context.Context, source string) ([]byte, error) {
+		if source == "stores.json" {
+			<-slow
+		}
+		data, ok := specs[source]
+		if !ok {
+			return nil, fmt.Errorf("spec file does not exist: %s", source)
+		}
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	err := LoadSpecs(ctx, server, nil, []string{"pets.json", "stores.json", "broken.json", "missing.json"}, read, 50*time.Millisecond)
+	require.NoError(t, err, "broken and missing specs are skipped")
+
+	clientSession := connectTestClient(t, ctx, server)
+	toolNames := func() []string {
+		tools, err := clientSession.ListTools(ctx, nil)
+		require.NoError(t, err)
+		var names []string
+		for _, tool := range tools.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"listPets"}, toolNames(), "the slow spec doesn't delay startup")
+
+	close(slow)
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"listPets", "listStores"}, toolNames())
+	}, 2*time.Second, 10*time.Millisecond, "the slow spec's tools are added once it's loaded")
+
+	server = mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	err = LoadSpecs(ctx, server, nil, []string{"broken.json", "missing.json"}, read, 0)
+	assert.ErrorContains(t, err, "broken.json")
+	assert.ErrorContains(t, err, "missing.json")
+}
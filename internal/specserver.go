@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// specServerPrefix is the path the spec files are served under.
+// Relative $refs in specs at the root of a server, like /openapi.yaml, aren't resolved,
+// so the files are served from a directory.
+const specServerPrefix = "/specs/"
+
+// SpecServer serves the spec files in a directory over HTTP,
+// so that a spec split across files can be read from a URL,
+// with its relative $refs resolved against the URL.
+// Files are read for each request, so edits are served as soon as they're saved.
+type SpecServer struct {
+	dir    string
+	files  http.Handler
+	logger *slog.Logger
+}
+
+// NewSpecServer returns a server for the spec files in dir.
+func NewSpecServer(dir string, logger *slog.Logger) (*SpecServer, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading spec directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	files := http.StripPrefix(strings.TrimSuffix(specServerPrefix, "/"), http.FileServerFS(os.DirFS(dir)))
+	return &SpecServer{dir: dir, files: files, logger: logger}, nil
+}
+
+// Specs returns the URL paths of the specs in the directory, like /specs/openapi.yaml:
+// the JSON and YAML files that are OpenAPI, Swagger, or AsyncAPI documents,
+// rather than parts of one, like a file of schemas.
+func (s *SpecServer) Specs() ([]string, error) {
+	var specs []string
+	err := filepath.WalkDir(s.dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != s.dir && isHiddenFile(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isSpecFileName(name) {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		var doc struct {
+			OpenAPI  string `yaml:"openapi"`
+			Swagger  string `yaml:"swagger"`
+			AsyncAPI string `yaml:"asyncapi"`
+		}
+		if yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc) != nil || (doc.OpenAPI == "" && doc.Swagger == "" && doc.AsyncAPI == "") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, name)
+		if err != nil {
+			return err
+		}
+		specs = append(specs, specServerPrefix+filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing specs: %w", err)
+	}
+	slices.Sort(specs)
+	return specs, nil
+}
+
+// ServeHTTP serves the directory's spec files, and the files they refer to, under /specs/,
+// with YAML and JSON content types.
+// Only GET and HEAD requests are allowed, and hidden files, like .env, aren't served.
+// Responses aren't cached, so that a spec read again gets its latest changes.
+func (s *SpecServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := path.Clean("/" + r.URL.Path)
+	if !strings.HasPrefix(name, specServerPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	if slices.ContainsFunc(strings.Split(name, "/"), isHiddenFile) {
+		http.NotFound(w, r)
+		return
+	}
+	s.logger.Debug("serving spec file", "path", name)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		w.Header().Set("Content-Type", "application/yaml")
+	case ".json":
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	s.files.ServeHTTP(w, r)
+}
+
+// isSpecFileName reports whether a file's name has a JSON or YAML extension.
+func isSpecFileName(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// isHiddenFile reports whether a file or directory is hidden, like .git.
+func isHiddenFile(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
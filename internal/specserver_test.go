@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte(multiFileSpec), 0o644))
+	for name, schema := range multiFileSchemas {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(schema), 0o644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("API_KEY=secret\n"), 0o644))
+
+	specServer, err := NewSpecServer(dir, nil)
+	require.NoError(t, err)
+	server := httptest.NewServer(specServer)
+	defer server.Close()
+
+	specs, err := specServer.Specs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/specs/openapi.yaml"}, specs, "files of schemas aren't specs")
+
+	tools, err := ListTools(ctx, []byte(multiFileSpec), WithSpecLocation(server.URL+specs[0]), WithRemoteRefs())
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	require.Contains(t, tools[0].InputSchema.Properties, "tag")
+	assert.Equal(t, "A tag.", tools[0].InputSchema.Properties["tag"].Description)
+
+	resp, err := http.Get(server.URL + "/specs/schemas/pet.yaml")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/yaml", resp.Header.Get("Content-Type"))
+
+	resp, err = http.Get(server.URL + "/specs/.env")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode, "hidden files aren't served")
+
+	resp, err = http.Post(server.URL+specs[0], "application/yaml", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	_, err = NewSpecServer(filepath.Join(dir, "openapi.yaml"), nil)
+	assert.ErrorContains(t, err, "not a directory")
+}
@@ -0,0 +1,682 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Stdio is the set of standard streams used by the server.
+// Protocol messages written to Out and log output written to Stderr share a lock,
+// so they can never interleave, even if a client redirects stderr to the same pipe as stdout.
+type Stdio struct {
+	In  io.ReadCloser
+	Out io.Writer
+	Err io.Writer
+
+	// Methods handles JSON-RPC methods that aren't part of MCP, keyed by method name.
+	// Requests for these methods are answered by the transport instead of the server,
+	// and the methods are advertised to clients as experimental capabilities.
+	Methods map[string]MethodHandler
+
+	// Replayable reports whether a request may be answered with the response to an earlier request
+	// with the same ID, method, and params, instead of being handled again.
+	// Clients retransmit requests after a hiccup, and replaying the response keeps the retransmitted request
+	// from repeating its work. A retransmitted request that's still in flight is answered by the original's response.
+	// If nil, every request is handled.
+	Replayable func(ctx context.Context, method string, params json.RawMessage) bool
+
+	// SessionID identifies the client session, when the streams are one of several connections to the server.
+	// State kept for a client, like confirmation tokens, isn't shared with sessions with other IDs.
+	SessionID string
+
+	// Elicitor, if set, asks the client for the required arguments its tool calls are missing,
+	// sending elicitation requests over the streams.
+	Elicitor *Elicitor
+
+	// MaxMessageBytes, if positive, is the size of the largest message read from In.
+	// A larger message is answered with an "invalid request" error without being read in full,
+	// and reading resumes with the message after it, whether or not it spans several lines.
+	// Batches count as a single message.
+	MaxMessageBytes int
+
+	// MaxPendingWrites, if positive, is how many messages may wait to be written to Out at once.
+	// While a client isn't reading its output, further writes wait for room until their context is done,
+	// so that a slow client holds up the requests answering it, rather than growing a backlog of messages.
+	MaxPendingWrites int
+
+	mu sync.Mutex
+}
+
+// Default limits on the messages exchanged over a Stdio.
+const (
+	DefaultMaxMessageBytes  = 16 << 20
+	DefaultMaxPendingWrites = 64
+)
+
+// MethodHandler handles a request for a JSON-RPC method, returning a result to be encoded as JSON.
+type MethodHandler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// NewStdio returns the process's standard streams.
+func NewStdio() *Stdio {
+	return &Stdio{In: os.Stdin, Out: os.Stdout, Err: os.Stderr}
+}
+
+// Stderr returns a writer for log output. Each call to Write is completed
+// before any protocol message is written, and vice versa.
+func (s *Stdio) Stderr() io.Writer {
+	return &stdioWriter{stdio: s, w: s.Err}
+}
+
+// Transport returns an MCP transport that reads newline-delimited JSON-RPC messages from In and writes them to Out.
+// Batches of messages are accepted as JSON arrays, and their responses are written together as an array.
+// Incoming messages are framed by decoding complete JSON values, so a message may span several lines
+// or share one with the next, and braces, quotes, and newlines in strings don't affect framing.
+func (s *Stdio) Transport() mcp.Transport {
+	return &stdioTransport{stdio: s}
+}
+
+// write writes p to w in full while holding the stream lock.
+func (s *Stdio) write(w io.Writer, p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return w.Write(p)
+}
+
+type stdioWriter struct {
+	stdio *Stdio
+	w     io.Writer
+}
+
+func (w *stdioWriter) Write(p []byte) (int, error) {
+	return w.stdio.write(w.w, p)
+}
+
+type stdioTransport struct {
+	stdio *Stdio
+}
+
+// Connect implements the mcp.Transport interface.
+func (t *stdioTransport) Connect(context.Context) (mcp.Connection, error) {
+	return newStdioConn(t.stdio), nil
+}
+
+// stdioConn is an MCP connection over a Stdio.
+// Each outgoing message is encoded in full and written, along with its trailing newline, in a single write.
+// Closing the connection waits for a write in progress to finish, so a message is never cut short on shutdown.
+type stdioConn struct {
+	stdio    *Stdio
+	incoming <-chan stdioMessage
+
+	// ctx is canceled when the connection is closed, and is used by method handlers
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// initializeID is the ID of the initialize request, whose response advertises extension methods
+	initializeMu sync.Mutex
+	initializeID jsonrpc.ID
+
+	// batches tracks calls that arrived in a batch, by request ID
+	batchMu sync.Mutex
+	batches map[jsonrpc.ID]batchSlot
+
+	// recent holds the most recent requests, for replaying responses to retransmitted requests
+	recent *recentRequests
+
+	// calls holds the requests sent to the client that are awaiting responses, by request ID
+	callsMu sync.Mutex
+	calls   map[jsonrpc.ID]chan *jsonrpc.Response
+	lastID  int
+
+	// pending has room for each message that may wait to be written, if the number is limited
+	pending chan struct{}
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	isClosed  bool // guarded by stdio.mu
+}
+
+type stdioMessage struct {
+	msg jsonrpc.Message
+	err error
+}
+
+// stdioBatch collects the responses to a batch of requests,
+// which are written together once every call in the batch has been answered.
+// Responses are in the same order as the calls in the batch.
+type stdioBatch struct {
+	responses []json.RawMessage
+	pending   int
+}
+
+// invalidRequestCode is the JSON-RPC "invalid request" error code.
+const invalidRequestCode = -32600
+
+func newStdioConn(stdio *Stdio) *stdioConn {
+	ctx, cancel := context.WithCancel(contextWithSession(context.Background(), stdio.SessionID))
+	c := &stdioConn{
+		stdio:   stdio,
+		ctx:     ctx,
+		cancel:  cancel,
+		closed:  make(chan struct{}),
+		batches: make(map[jsonrpc.ID]batchSlot),
+		recent:  newRecentRequests(),
+		calls:   make(map[jsonrpc.ID]chan *jsonrpc.Response),
+	}
+	if stdio.Elicitor != nil {
+		stdio.Elicitor.register(stdio.SessionID, c)
+	}
+	if stdio.MaxPendingWrites > 0 {
+		c.pending = make(chan struct{}, stdio.MaxPendingWrites)
+	}
+	incoming := make(chan stdioMessage)
+	c.incoming = incoming
+	// Read in a separate goroutine so that Read can return as soon as the connection is closed,
+	// since reads from stdin can't portably be interrupted.
+	go func() {
+		in := &messageReader{r: stdio.In, max: stdio.MaxMessageBytes}
+		dec := in.decoder()
+		for {
+			var raw json.RawMessage
+			var msgs []stdioMessage
+			err := dec.Decode(&raw)
+			switch {
+			case errors.Is(err, errMessageTooLarge):
+				_ = c.writeLine(invalidRequest(fmt.Sprintf("message exceeds %d bytes", stdio.MaxMessageBytes)))
+				if err := in.skipValue(); err != nil {
+					msgs = []stdioMessage{{err: err}}
+				} else {
+					dec = in.decoder()
+				}
+			case err != nil:
+				msgs = []stdioMessage{{err: err}}
+			default:
+				in.decoded(dec.InputOffset())
+				msgs = c.decode(raw)
+			}
+			for _, m := range msgs {
+				select {
+				case incoming <- m:
+				case <-c.closed:
+					return
+				}
+				if m.err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return c
+}
+
+// errMessageTooLarge is returned by a messageReader when a message exceeds its limit.
+var errMessageTooLarge = errors.New("message too large")
+
+// messageReader limits the size of the messages decoded from a stream.
+// Bytes are read for a message only while it's within the limit, so an oversized message is never buffered in full.
+type messageReader struct {
+	r   io.Reader
+	max int
+
+	read    int64  // bytes read by the current decoder
+	start   int64  // offset of the end of the last decoded message, where the next one starts
+	current []byte // bytes read since start, for finding the end of an oversized message
+	pending []byte // bytes to read before r, left over from skipping a message
+}
+
+// decoder returns a decoder for the messages that follow.
+func (m *messageReader) decoder() *json.Decoder {
+	m.read, m.start, m.current = 0, 0, nil
+	return json.NewDecoder(m)
+}
+
+// decoded records that a message has been decoded, ending at offset.
+func (m *messageReader) decoded(offset int64) {
+	if m.max > 0 {
+		m.current = append(m.current[:0], m.current[offset-m.start:]...)
+	}
+	m.start = offset
+}
+
+// Read implements io.Reader, failing once the message being decoded exceeds the limit.
+func (m *messageReader) Read(p []byte) (int, error) {
+	if m.max > 0 {
+		room := int64(m.max) - (m.read - m.start)
+		if room <= 0 {
+			return 0, errMessageTooLarge
+		}
+		if int64(len(p)) > room {
+			p = p[:room]
+		}
+	}
+	var n int
+	var err error
+	if len(m.pending) > 0 {
+		n = copy(p, m.pending)
+		m.pending = m.pending[n:]
+	} else {
+		n, err = m.r.Read(p)
+	}
+	m.read += int64(n)
+	if m.max > 0 {
+		m.current = append(m.current, p[:n]...)
+	}
+	return n, err
+}
+
+// skipValue discards the rest of an oversized message, up to the end of its JSON value,
+// so that reading resumes with the message after it, even if the message spans several lines.
+func (m *messageReader) skipValue() error {
+	var end jsonValueEnd
+	chunk := m.current
+	m.current = nil
+	buf := make([]byte, 32*1024)
+	for {
+		if i := end.scan(chunk); i >= 0 {
+			m.pending = append(bytes.Clone(chunk[i:]), m.pending...)
+			return nil
+		}
+		chunk = m.pending
+		m.pending = nil
+		if len(chunk) == 0 {
+			n, err := m.r.Read(buf)
+			if n == 0 && err != nil {
+				return err
+			}
+			chunk = buf[:n]
+		}
+	}
+}
+
+// jsonValueEnd finds the end of a JSON value in its bytes, read a chunk at a time, without decoding it.
+type jsonValueEnd struct {
+	started  bool
+	scalar   bool // a number or literal, which ends at the next delimiter
+	inString bool
+	escaped  bool
+	depth    int
+}
+
+// scan returns the offset in p just past the end of the value, or -1 if the value doesn't end in p.
+func (e *jsonValueEnd) scan(p []byte) int {
+	for i, c := range p {
+		switch {
+		case e.inString:
+			switch {
+			case e.escaped:
+				e.escaped = false
+			case c == '\\':
+				e.escaped = true
+			case c == '"':
+				e.inString = false
+				if e.depth == 0 {
+					return i + 1
+				}
+			}
+		case !e.started:
+			switch c {
+			case ' ', '\t', '\r', '\n':
+				continue
+			case '{', '[':
+				e.depth++
+			case '"':
+				e.inString = true
+			default:
+				e.scalar = true
+			}
+			e.started = true
+		case e.scalar:
+			if bytes.IndexByte([]byte(" \t\r\n{}[],\""), c) >= 0 {
+				return i
+			}
+		default:
+			switch c {
+			case '"':
+				e.inString = true
+			case '{', '[':
+				e.depth++
+			case '}', ']':
+				e.depth--
+				if e.depth == 0 {
+					return i + 1
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// decode decodes a single message or a batch of messages.
+// Calls in a batch are tracked so that their responses can be written together,
+// and invalid messages in a batch are answered with errors without ending the connection.
+func (c *stdioConn) decode(raw json.RawMessage) []stdioMessage {
+	if trimmed := bytes.TrimLeft(raw, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '[' {
+		msg, err := jsonrpc.DecodeMessage(raw)
+		return []stdioMessage{{msg: msg, err: err}}
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(raw, &elements); err != nil {
+		return []stdioMessage{{err: err}}
+	}
+	if len(elements) == 0 {
+		_ = c.writeLine(invalidRequest("empty batch"))
+		return nil
+	}
+
+	var msgs []stdioMessage
+	batch := &stdioBatch{}
+	c.batchMu.Lock()
+	for _, element := range elements {
+		msg, err := jsonrpc.DecodeMessage(element)
+		if err != nil {
+			batch.responses = append(batch.responses, invalidRequest(err.Error()))
+			continue
+		}
+		if req, ok := msg.(*jsonrpc.Request); ok && req.IsCall() {
+			// A response to a reused ID couldn't be told apart from the response to the original request
+			if _, ok := c.batches[req.ID]; ok {
+				batch.responses = append(batch.responses, invalidRequest(fmt.Sprintf("duplicate request ID %v", req.ID.Raw())))
+				continue
+			}
+			c.batches[req.ID] = batchSlot{batch: batch, index: len(batch.responses)}
+			batch.responses = append(batch.responses, nil)
+			batch.pending++
+		}
+		msgs = append(msgs, stdioMessage{msg: msg})
+	}
+	c.batchMu.Unlock()
+
+	if batch.pending == 0 && len(batch.responses) > 0 {
+		// Nothing in the batch needs a response from the server
+		if data, err := json.Marshal(batch.responses); err == nil {
+			_ = c.writeLine(data)
+		}
+	}
+	return msgs
+}
+
+// batchSlot is the position of a call's response in a batch.
+type batchSlot struct {
+	batch *stdioBatch
+	index int
+}
+
+// updateBatch records the response to a call that arrived in a batch.
+// It reports whether the call was part of a batch, along with the encoded batch response if it's now complete.
+func (c *stdioConn) updateBatch(id jsonrpc.ID, data []byte) ([]byte, bool) {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	slot, ok := c.batches[id]
+	if !ok {
+		return nil, false
+	}
+	delete(c.batches, id)
+	slot.batch.responses[slot.index] = data
+	if slot.batch.pending--; slot.batch.pending > 0 {
+		return nil, true
+	}
+	batch, err := json.Marshal(slot.batch.responses)
+	if err != nil {
+		return nil, true
+	}
+	return batch, true
+}
+
+// invalidRequest returns an "invalid request" error response with a null ID,
+// for a message whose ID can't be determined.
+func invalidRequest(message string) json.RawMessage {
+	type wireError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	data, _ := json.Marshal(struct {
+		JSONRPC string    `json:"jsonrpc"`
+		ID      any       `json:"id"`
+		Error   wireError `json:"error"`
+	}{"2.0", nil, wireError{invalidRequestCode, message}})
+	return data
+}
+
+// SessionID implements the mcp.Connection interface.
+// Connections over the process's standard streams have no session ID.
+func (c *stdioConn) SessionID() string { return c.stdio.SessionID }
+
+// Read implements the mcp.Connection interface.
+// Requests for extension methods are handled in the background rather than returned.
+func (c *stdioConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	for {
+		var m stdioMessage
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case m = <-c.incoming:
+		case <-c.closed:
+			return nil, io.EOF
+		}
+		if m.err != nil {
+			return nil, m.err
+		}
+		if resp, ok := m.msg.(*jsonrpc.Response); ok && c.deliver(resp) {
+			continue
+		}
+		if req, ok := m.msg.(*jsonrpc.Request); ok && req.IsCall() {
+			if handler, ok := c.stdio.Methods[req.Method]; ok {
+				go c.handleMethod(req, handler)
+				continue
+			}
+			if req.Method == "initialize" {
+				c.initializeMu.Lock()
+				c.initializeID = req.ID
+				c.initializeMu.Unlock()
+			}
+			if c.replay(ctx, req) {
+				continue
+			}
+		}
+		return m.msg, nil
+	}
+}
+
+// replay reports whether a request is a retransmission that's answered with the response to the original request.
+// Requests in a batch are always handled, since their responses are written with the rest of the batch.
+func (c *stdioConn) replay(ctx context.Context, req *jsonrpc.Request) bool {
+	if c.stdio.Replayable == nil || c.inBatch(req.ID) {
+		return false
+	}
+	entry, ok := c.recent.lookup(req)
+	if !ok || !c.stdio.Replayable(ctx, req.Method, req.Params) {
+		return false
+	}
+	if resp, ok := c.recent.cached(entry); ok {
+		go func() { _ = c.Write(c.ctx, resp) }()
+	}
+	return true
+}
+
+// call sends a request to the client and waits for its response.
+// Requests are sent by the transport for methods the SDK doesn't support, like elicitation/create,
+// and their responses are kept from the SDK, which wouldn't recognize their IDs.
+func (c *stdioConn) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	c.callsMu.Lock()
+	c.lastID++
+	id, err := jsonrpc.MakeID(fmt.Sprintf("emcee-%d", c.lastID))
+	if err != nil {
+		c.callsMu.Unlock()
+		return nil, err
+	}
+	ch := make(chan *jsonrpc.Response, 1)
+	c.calls[id] = ch
+	c.callsMu.Unlock()
+	defer func() {
+		c.callsMu.Lock()
+		delete(c.calls, id)
+		c.callsMu.Unlock()
+	}()
+
+	if err := c.Write(ctx, &jsonrpc.Request{ID: id, Method: method, Params: data}); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.closed:
+		return nil, mcp.ErrConnectionClosed
+	}
+}
+
+// deliver passes the response to a request sent by call to the caller waiting for it,
+// reporting whether the response was for such a request.
+func (c *stdioConn) deliver(resp *jsonrpc.Response) bool {
+	c.callsMu.Lock()
+	defer c.callsMu.Unlock()
+	ch, ok := c.calls[resp.ID]
+	if ok {
+		ch <- resp
+		delete(c.calls, resp.ID)
+	}
+	return ok
+}
+
+// inBatch reports whether a call arrived in a batch.
+func (c *stdioConn) inBatch(id jsonrpc.ID) bool {
+	c.batchMu.Lock()
+	defer c.batchMu.Unlock()
+	_, ok := c.batches[id]
+	return ok
+}
+
+// handleMethod responds to a request for an extension method.
+func (c *stdioConn) handleMethod(req *jsonrpc.Request, handler MethodHandler) {
+	resp := &jsonrpc.Response{ID: req.ID}
+	result, err := handler(c.ctx, req.Params)
+	if err == nil {
+		resp.Result, err = json.Marshal(result)
+	}
+	resp.Error = err
+	_ = c.Write(c.ctx, resp)
+}
+
+// advertiseMethods adds extension methods to the experimental capabilities in the response to an initialize request.
+func (c *stdioConn) advertiseMethods(resp *jsonrpc.Response) {
+	c.initializeMu.Lock()
+	isInitialize := c.initializeID.IsValid() && resp.ID == c.initializeID
+	c.initializeMu.Unlock()
+	if !isInitialize || len(c.stdio.Methods) == 0 || resp.Error != nil {
+		return
+	}
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return
+	}
+	var capabilities map[string]json.RawMessage
+	if err := json.Unmarshal(result["capabilities"], &capabilities); err != nil || capabilities == nil {
+		capabilities = make(map[string]json.RawMessage)
+	}
+	var experimental map[string]json.RawMessage
+	if err := json.Unmarshal(capabilities["experimental"], &experimental); err != nil || experimental == nil {
+		experimental = make(map[string]json.RawMessage)
+	}
+	for method := range c.stdio.Methods {
+		experimental[method] = json.RawMessage(`{}`)
+	}
+	var err error
+	if capabilities["experimental"], err = json.Marshal(experimental); err != nil {
+		return
+	}
+	if result["capabilities"], err = json.Marshal(capabilities); err != nil {
+		return
+	}
+	if data, err := json.Marshal(result); err == nil {
+		resp.Result = data
+	}
+}
+
+// Write implements the mcp.Connection interface.
+// If the number of pending writes is limited, Write waits for room until ctx is done.
+func (c *stdioConn) Write(ctx context.Context, msg jsonrpc.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.pending != nil {
+		select {
+		case c.pending <- struct{}{}:
+			defer func() { <-c.pending }()
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.closed:
+			return mcp.ErrConnectionClosed
+		}
+	}
+	if resp, ok := msg.(*jsonrpc.Response); ok {
+		c.advertiseMethods(resp)
+		if c.stdio.Replayable != nil {
+			c.recent.respond(resp)
+		}
+	}
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return fmt.Errorf("marshaling message: %w", err)
+	}
+	if resp, ok := msg.(*jsonrpc.Response); ok {
+		if batch, ok := c.updateBatch(resp.ID, data); ok {
+			if batch == nil {
+				return nil
+			}
+			data = batch
+		}
+	}
+	return c.writeLine(data)
+}
+
+// writeLine writes an encoded message followed by a newline.
+func (c *stdioConn) writeLine(data []byte) error {
+	data = append(data, '\n')
+
+	c.stdio.mu.Lock()
+	defer c.stdio.mu.Unlock()
+	if c.isClosed {
+		return mcp.ErrConnectionClosed
+	}
+	_, err := c.stdio.Out.Write(data)
+	return err
+}
+
+// Close implements the mcp.Connection interface.
+// Output is synced if it is a file, and input is closed to release the reader goroutine.
+func (c *stdioConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.stdio.mu.Lock()
+		c.isClosed = true
+		if f, ok := c.stdio.Out.(*os.File); ok {
+			// Pipes and terminals can't be synced, so errors are ignored
+			_ = f.Sync()
+		}
+		c.stdio.mu.Unlock()
+		c.cancel()
+		close(c.closed)
+		if c.stdio.Elicitor != nil {
+			c.stdio.Elicitor.unregister(c.stdio.SessionID, c)
+		}
+		err = c.stdio.In.Close()
+	})
+	return err
+}
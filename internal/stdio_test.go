@@ -0,0 +1,345 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingWriter records each call to Write, failing the test if two calls overlap.
+type recordingWriter struct {
+	t      *testing.T
+	mu     sync.Mutex
+	busy   bool
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.busy {
+		w.t.Error("concurrent writes to shared stream")
+	}
+	w.busy = true
+	w.mu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.busy = false
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestStdioDoesNotInterleaveOutput(t *testing.T) {
+	// Simulate a client that redirects stderr to the same pipe as stdout
+	shared := &recordingWriter{t: t}
+	in, inWriter := io.Pipe()
+	defer inWriter.Close()
+	stdio := &Stdio{In: in, Out: shared, Err: shared}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	large := strings.Repeat("x", 64*1024)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			msg, err := jsonrpc.DecodeMessage(fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%d,"result":{"text":%q}}`, i, large))
+			require.NoError(t, err)
+			assert.NoError(t, conn.Write(context.Background(), msg))
+		}()
+		go func() {
+			defer wg.Done()
+			fmt.Fprintf(stdio.Stderr(), "log line %d\n", i)
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, shared.writes, 40)
+	for _, w := range shared.writes {
+		require.True(t, strings.HasSuffix(w, "\n"), "each write is a complete line")
+		if strings.HasPrefix(w, "log line") {
+			continue
+		}
+		assert.True(t, json.Valid([]byte(w)), "each message is written whole")
+	}
+}
+
+func TestStdioReadAndClose(t *testing.T) {
+	var out bytes.Buffer
+	in, inWriter := io.Pipe()
+	stdio := &Stdio{In: in, Out: &out, Err: io.Discard}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+
+	go func() {
+		_, _ = inWriter.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n"))
+	}()
+	msg, err := conn.Read(context.Background())
+	require.NoError(t, err)
+	req, ok := msg.(*jsonrpc.Request)
+	require.True(t, ok)
+	assert.Equal(t, "ping", req.Method)
+
+	resp := &jsonrpc.Response{ID: req.ID, Result: json.RawMessage(`{}`)}
+	require.NoError(t, conn.Write(context.Background(), resp))
+	assert.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{}}`+"\n", out.String())
+
+	require.NoError(t, conn.Close())
+	assert.ErrorIs(t, conn.Write(context.Background(), resp), mcp.ErrConnectionClosed)
+	_, err = conn.Read(context.Background())
+	assert.Error(t, err)
+}
+
+func TestStdioUnicode(t *testing.T) {
+	var out bytes.Buffer
+	in, inWriter := io.Pipe()
+	stdio := &Stdio{In: in, Out: &out, Err: io.Discard}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Write a byte at a time, so that multi-byte characters are split across reads
+	text := "東京 🐶🏳️‍🌈 café \u2028 Ω"
+	go func() {
+		for _, b := range fmt.Appendf(nil, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"text":%q}}}`+"\n", text) {
+			_, _ = inWriter.Write([]byte{b})
+		}
+	}()
+	msg, err := conn.Read(context.Background())
+	require.NoError(t, err)
+	req, ok := msg.(*jsonrpc.Request)
+	require.True(t, ok)
+	var params struct {
+		Arguments struct {
+			Text string `json:"text"`
+		} `json:"arguments"`
+	}
+	require.NoError(t, json.Unmarshal(req.Params, &params))
+	assert.Equal(t, text, params.Arguments.Text)
+
+	result, err := json.Marshal(map[string]string{"text": text})
+	require.NoError(t, err)
+	require.NoError(t, conn.Write(context.Background(), &jsonrpc.Response{ID: req.ID, Result: result}))
+	line, err := bufio.NewReader(&out).ReadBytes('\n')
+	require.NoError(t, err)
+	assert.Equal(t, 1, bytes.Count(line, []byte("\n")), "line separators in strings don't break framing")
+	var resp struct {
+		Result struct {
+			Text string `json:"text"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(line, &resp))
+	assert.Equal(t, text, resp.Result.Text)
+}
+
+func TestStdioBatch(t *testing.T) {
+	in, inWriter := io.Pipe()
+	outReader, out := io.Pipe()
+	stdio := &Stdio{In: in, Out: out, Err: io.Discard}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	lines := bufio.NewScanner(outReader)
+	send := func(msg string) {
+		go func() { _, _ = inWriter.Write([]byte(msg + "\n")) }()
+	}
+
+	send(`[
+		{"jsonrpc":"2.0","id":1,"method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":"two","method":"tools/list"},
+		{"foo":"bar"},
+		{"jsonrpc":"2.0","id":1,"method":"ping"}
+	]`)
+	var calls []*jsonrpc.Request
+	for range 3 {
+		msg, err := conn.Read(context.Background())
+		require.NoError(t, err)
+		if req := msg.(*jsonrpc.Request); req.IsCall() {
+			calls = append(calls, req)
+		}
+	}
+	require.Len(t, calls, 2)
+
+	// Respond out of order; the batch response is written once all calls are answered
+	go func() {
+		for i := len(calls) - 1; i >= 0; i-- {
+			assert.NoError(t, conn.Write(context.Background(), &jsonrpc.Response{ID: calls[i].ID, Result: json.RawMessage(`{}`)}))
+		}
+	}()
+	require.True(t, lines.Scan())
+	var batch []map[string]any
+	require.NoError(t, json.Unmarshal(lines.Bytes(), &batch))
+	require.Len(t, batch, 4)
+	assert.Equal(t, float64(1), batch[0]["id"])
+	assert.Equal(t, "two", batch[1]["id"])
+	for _, resp := range batch[2:] {
+		assert.Contains(t, resp, "id")
+		assert.Nil(t, resp["id"])
+		assert.Equal(t, float64(invalidRequestCode), resp["error"].(map[string]any)["code"])
+	}
+
+	// An empty batch is answered with a single error, and the connection stays open
+	send(`[]`)
+	require.True(t, lines.Scan())
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"empty batch"}}`, lines.Text())
+
+	// A batch of notifications gets no response
+	send(`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`)
+	msg, err := conn.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "notifications/initialized", msg.(*jsonrpc.Request).Method)
+
+	// Single messages are written as is
+	send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	msg, err = conn.Read(context.Background())
+	require.NoError(t, err)
+	go func() {
+		assert.NoError(t, conn.Write(context.Background(), &jsonrpc.Response{ID: msg.(*jsonrpc.Request).ID, Result: json.RawMessage(`{}`)}))
+	}()
+	require.True(t, lines.Scan())
+	assert.Equal(t, `{"jsonrpc":"2.0","id":3,"result":{}}`, lines.Text())
+}
+
+func FuzzStdioFraming(f *testing.F) {
+	for _, text := range []string{"", `}{`, `"}{"`, `\\"`, "{\n}", `[{"id":1}]`, `\u007d`, "\u2028\u2029"} {
+		f.Add(text, uint8(0))
+		f.Add(text, uint8(1))
+		f.Add(text, uint8(2))
+	}
+	f.Fuzz(func(t *testing.T, text string, separator uint8) {
+		if !utf8.ValidString(text) {
+			t.Skip("invalid UTF-8 is replaced when encoded")
+		}
+
+		// Messages are framed the same whether they're on lines of their own, run together, or pretty-printed
+		var in bytes.Buffer
+		for i := range 2 {
+			msg := map[string]any{"jsonrpc": "2.0", "id": i, "method": "echo", "params": map[string]string{"text": text}}
+			var data []byte
+			var err error
+			switch separator % 3 {
+			case 0:
+				data, err = json.Marshal(msg)
+				data = append(data, '\n')
+			case 1:
+				data, err = json.Marshal(msg)
+			case 2:
+				data, err = json.MarshalIndent(msg, "", "\t")
+			}
+			require.NoError(t, err)
+			in.Write(data)
+		}
+		stdio := &Stdio{In: io.NopCloser(&in), Out: io.Discard, Err: io.Discard}
+
+		conn, err := stdio.Transport().Connect(context.Background())
+		require.NoError(t, err)
+		defer conn.Close()
+		for i := range 2 {
+			msg, err := conn.Read(context.Background())
+			require.NoError(t, err)
+			req, ok := msg.(*jsonrpc.Request)
+			require.True(t, ok)
+			assert.Equal(t, int64(i), req.ID.Raw())
+			var params struct {
+				Text string `json:"text"`
+			}
+			require.NoError(t, json.Unmarshal(req.Params, &params))
+			assert.Equal(t, text, params.Text)
+		}
+	})
+}
+
+func TestStdioMaxMessageBytes(t *testing.T) {
+	in, inWriter := io.Pipe()
+	outReader, out := io.Pipe()
+	stdio := &Stdio{In: in, Out: out, Err: io.Discard, MaxMessageBytes: 100}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// An oversized message is answered with an error, and the message on the next line is read
+	go func() {
+		_, _ = fmt.Fprintf(inWriter, `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":%q}}`+"\n", strings.Repeat("x", 1024*1024))
+		_, _ = inWriter.Write([]byte(`{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"))
+	}()
+	lines := bufio.NewScanner(outReader)
+	require.True(t, lines.Scan())
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"message exceeds 100 bytes"}}`, lines.Text())
+
+	msg, err := conn.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ping", msg.(*jsonrpc.Request).Method)
+}
+
+func TestStdioMaxMessageBytesMultiline(t *testing.T) {
+	in, inWriter := io.Pipe()
+	outReader, out := io.Pipe()
+	stdio := &Stdio{In: in, Out: out, Err: io.Discard, MaxMessageBytes: 100}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// A pretty-printed oversized message is skipped to its end, even with brackets and newlines in its strings
+	go func() {
+		params, _ := json.MarshalIndent(map[string]any{"text": "} ]\n{\"x\": [" + strings.Repeat("x", 1024*1024), "lines": []string{"a", "b"}}, "  ", "  ")
+		_, _ = fmt.Fprintf(inWriter, "{\n  \"jsonrpc\": \"2.0\",\n  \"id\": 1,\n  \"method\": \"echo\",\n  \"params\": %s\n}", params)
+		_, _ = inWriter.Write([]byte(` {"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"))
+	}()
+	lines := bufio.NewScanner(outReader)
+	require.True(t, lines.Scan())
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"message exceeds 100 bytes"}}`, lines.Text())
+
+	msg, err := conn.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "ping", msg.(*jsonrpc.Request).Method)
+}
+
+func TestStdioMaxPendingWrites(t *testing.T) {
+	in, inWriter := io.Pipe()
+	defer inWriter.Close()
+	outReader, out := io.Pipe()
+	stdio := &Stdio{In: in, Out: out, Err: io.Discard, MaxPendingWrites: 1}
+
+	conn, err := stdio.Transport().Connect(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The first write waits for the client to read it, holding up the next
+	id, err := jsonrpc.MakeID(float64(1))
+	require.NoError(t, err)
+	resp := &jsonrpc.Response{ID: id, Result: json.RawMessage(`{}`)}
+	written := make(chan error, 1)
+	go func() { written <- conn.Write(context.Background(), resp) }()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, conn.Write(ctx, resp), context.DeadlineExceeded)
+
+	lines := bufio.NewScanner(outReader)
+	require.True(t, lines.Scan())
+	assert.NoError(t, <-written)
+}
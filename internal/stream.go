@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+const (
+	// maxEventsArgument is the reserved argument bounding the number of events read from a stream.
+	maxEventsArgument = "_max_events"
+	// maxDurationArgument is the reserved argument bounding how long a stream is read, in seconds.
+	maxDurationArgument = "_max_duration"
+
+	defaultMaxEvents   = 100
+	defaultMaxDuration = 10 * time.Second
+)
+
+// eventStreamMediaType is the media type of server-sent events.
+const eventStreamMediaType = "text/event-stream"
+
+// isEventStreamOperation reports whether any successful response of an operation is a stream of server-sent events.
+func isEventStreamOperation(op *v3.Operation) bool {
+	if op == nil || op.Responses == nil || op.Responses.Codes == nil {
+		return false
+	}
+	for pair := op.Responses.Codes.First(); pair != nil; pair = pair.Next() {
+		if !strings.HasPrefix(pair.Key(), "2") || pair.Value() == nil || pair.Value().Content == nil {
+			continue
+		}
+		for content := pair.Value().Content.First(); content != nil; content = content.Next() {
+			if baseMediaType(content.Key()) == eventStreamMediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addStreamArguments adds the reserved arguments that bound how much of a stream a tool call reads.
+// Arguments that collide with the operation's own parameters are left alone.
+func addStreamArguments(schema *jsonschema.Schema) {
+	minimum := 1.0
+	if _, exists := schema.Properties[maxEventsArgument]; !exists {
+		schema.Properties[maxEventsArgument] = &jsonschema.Schema{
+			Type:        "integer",
+			Minimum:     &minimum,
+			Default:     json.RawMessage(fmt.Sprint(defaultMaxEvents)),
+			Description: "Maximum number of events to read from the stream before returning",
+		}
+	}
+	if _, exists := schema.Properties[maxDurationArgument]; !exists {
+		schema.Properties[maxDurationArgument] = &jsonschema.Schema{
+			Type:        "number",
+			Minimum:     &minimum,
+			Default:     json.RawMessage(fmt.Sprint(defaultMaxDuration.Seconds())),
+			Description: "Maximum number of seconds to read from the stream before returning",
+		}
+	}
+}
+
+// streamLimits bounds how much of a stream of server-sent events a tool call reads.
+type streamLimits struct {
+	maxEvents   int
+	maxDuration time.Duration
+}
+
+// takeStreamLimits returns the limits requested by a tool call's reserved arguments,
+// along with a copy of the arguments without them, so they aren't sent to the API.
+func takeStreamLimits(args map[string]any) (streamLimits, map[string]any) {
+	limits := streamLimits{maxEvents: defaultMaxEvents, maxDuration: defaultMaxDuration}
+	rest := make(map[string]any, len(args))
+	for name, value := range args {
+		switch name {
+		case maxEventsArgument:
+			if n, ok := argumentNumber(value); ok && n >= 1 {
+				limits.maxEvents = int(n)
+			}
+		case maxDurationArgument:
+			if n, ok := argumentNumber(value); ok && n > 0 {
+				limits.maxDuration = time.Duration(n * float64(time.Second))
+			}
+		default:
+			rest[name] = value
+		}
+	}
+	return limits, rest
+}
+
+// takeArgument returns the value of a tool call's reserved argument, or nil if the call doesn't pass it,
+// along with a copy of the arguments without it, so it isn't sent to the API.
+func takeArgument(args map[string]any, name string) (any, map[string]any) {
+	value, ok := args[name]
+	if !ok {
+		return nil, args
+	}
+	rest := make(map[string]any, len(args))
+	for n, v := range args {
+		if n != name {
+			rest[n] = v
+		}
+	}
+	return value, rest
+}
+
+// argumentNumber returns the value of a numeric argument.
+func argumentNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// serverSentEvent is an event read from a text/event-stream response.
+// Data that is valid JSON is included as is; other data is included as a string.
+type serverSentEvent struct {
+	Event string          `json:"event,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// readEvents reads server-sent events from r until the stream ends, maxEvents events have been read, or ctx is done.
+// It reports why reading stopped.
+func readEvents(ctx context.Context, r io.Reader, limits streamLimits) ([]serverSentEvent, string) {
+	var events []serverSentEvent
+	var event serverSentEvent
+	var data []string
+	hasData := false
+
+	lines := bufio.NewScanner(r)
+	lines.Buffer(nil, 1<<20)
+	for lines.Scan() {
+		line := strings.TrimSuffix(lines.Text(), "\r")
+		if line == "" {
+			// A blank line dispatches the event
+			if hasData {
+				event.Data = eventData(strings.Join(data, "\n"))
+				events = append(events, event)
+				if len(events) >= limits.maxEvents {
+					return events, fmt.Sprintf("Stopped after %d events (%s)", len(events), maxEventsArgument)
+				}
+			}
+			event, data, hasData = serverSentEvent{ID: event.ID}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// Comment, commonly used as a keep-alive
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				event.ID = value
+			}
+		}
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return events, fmt.Sprintf("Stopped after %s (%s)", limits.maxDuration, maxDurationArgument)
+	}
+	if err := lines.Err(); err != nil {
+		return events, fmt.Sprintf("Stream interrupted: %v", err)
+	}
+	return events, "Stream ended"
+}
+
+// eventData returns the data of an event as JSON.
+func eventData(data string) json.RawMessage {
+	trimmed := bytes.TrimSpace([]byte(data))
+	if len(trimmed) > 0 && json.Valid(trimmed) {
+		return trimmed
+	}
+	encoded, _ := json.Marshal(data)
+	return encoded
+}
+
+// eventStreamResult converts events read from a stream into a tool result.
+func eventStreamResult(events []serverSentEvent, stopped string) *mcp.CallToolResultFor[any] {
+	if events == nil {
+		events = []serverSentEvent{}
+	}
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return &mcp.CallToolResultFor[any]{Content: []mcp.Content{&mcp.TextContent{Text: err.Error()}}, IsError: true}
+	}
+	return &mcp.CallToolResultFor[any]{Content: []mcp.Content{
+		&mcp.TextContent{Text: string(data)},
+		&mcp.TextContent{Text: fmt.Sprintf("%s. Events received: %d.", stopped, len(events))},
+	}}
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadEvents(t *testing.T) {
+	stream := ": keep-alive\n\n" +
+		"event: price\nid: 1\ndata: {\"symbol\":\"ACME\",\"price\":12.5}\n\n" +
+		"data: first line\r\ndata: second line\r\n\r\n" +
+		"event: empty\n\n" +
+		"data:no space\n\n"
+	limits := streamLimits{maxEvents: 10, maxDuration: time.Second}
+
+	events, stopped := readEvents(context.Background(), strings.NewReader(stream), limits)
+	assert.Equal(t, "Stream ended", stopped)
+	require.Len(t, events, 3)
+	assert.Equal(t, serverSentEvent{Event: "price", ID: "1", Data: json.RawMessage(`{"symbol":"ACME","price":12.5}`)}, events[0])
+	assert.Equal(t, serverSentEvent{ID: "1", Data: json.RawMessage(`"first line\nsecond line"`)}, events[1], "the last event ID carries over")
+	assert.JSONEq(t, `"no space"`, string(events[2].Data))
+
+	limits.maxEvents = 2
+	events, stopped = readEvents(context.Background(), strings.NewReader(stream), limits)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "Stopped after 2 events (_max_events)", stopped)
+}
+
+func TestRegisterToolsReadsEventStreams(t *testing.T) {
+	var queries []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "event: tick\ndata: {\"n\":%d}\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Ticker API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/ticks": {
+      "get": {
+        "operationId": "streamTicks",
+        "parameters": [{"name": "symbol", "in": "query", "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK", "content": {"text/event-stream": {"schema": {"type": "string"}}}}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	assert.Contains(t, tools.Tools[0].InputSchema.Properties, maxEventsArgument)
+	assert.Contains(t, tools.Tools[0].InputSchema.Properties, maxDurationArgument)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "streamTicks",
+		Arguments: map[string]any{"symbol": "ACME", maxEventsArgument: 3},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 2)
+	var events []serverSentEvent
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &events))
+	require.Len(t, events, 3)
+	assert.Equal(t, "tick", events[2].Event)
+	assert.JSONEq(t, `{"n":2}`, string(events[2].Data))
+	assert.Equal(t, "Stopped after 3 events (_max_events). Events received: 3.", result.Content[1].(*mcp.TextContent).Text)
+
+	start := time.Now()
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "streamTicks",
+		Arguments: map[string]any{maxEventsArgument: 1000, maxDurationArgument: 1},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Less(t, time.Since(start), 3*time.Second)
+	assert.Contains(t, result.Content[1].(*mcp.TextContent).Text, "Stopped after 1s (_max_duration)")
+
+	assert.Equal(t, []string{"symbol=ACME", ""}, queries, "reserved arguments aren't sent to the API")
+}
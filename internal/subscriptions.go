@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Subscriptions handles clients' subscriptions to resources:
+// those listing the events of webhooks and callbacks, which are updated as events arrive,
+// and those read from GET operations, which are polled for changes.
+type Subscriptions struct {
+	webhooks     *WebhookReceiver
+	pollInterval time.Duration
+	logger       *slog.Logger
+
+	mu        sync.Mutex
+	templates map[string]*polledTemplate // by URI template
+	polls     map[string]*resourcePoll   // by URI
+}
+
+// polledTemplate is a resource template whose resources can be polled, and the server it's added to.
+type polledTemplate struct {
+	server   *mcp.Server
+	template *resourceTemplate
+}
+
+// resourcePoll polls a resource for as long as sessions are subscribed to it.
+type resourcePoll struct {
+	sessions map[*mcp.ServerSession]bool
+	stop     context.CancelFunc
+}
+
+// NewSubscriptions returns the subscriptions to the resources of webhooks and callbacks received by webhooks, if it isn't nil,
+// and, if pollInterval is positive, to resources read from GET operations, which are read at that interval.
+func NewSubscriptions(webhooks *WebhookReceiver, pollInterval time.Duration, logger *slog.Logger) *Subscriptions {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Subscriptions{
+		webhooks:     webhooks,
+		pollInterval: pollInterval,
+		logger:       logger,
+		templates:    make(map[string]*polledTemplate),
+		polls:        make(map[string]*resourcePoll),
+	}
+}
+
+// WithSubscriptions lets clients subscribe to the resources of a spec's resource templates,
+// which are read at the subscriptions' poll interval to find out when they change.
+func WithSubscriptions(s *Subscriptions) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.subscriptions = s }
+}
+
+// addTemplate makes the resources of a template available to subscribe to.
+// Templates added again, like when the spec is reloaded, replace the earlier one.
+func (s *Subscriptions) addTemplate(server *mcp.Server, t *resourceTemplate) {
+	if s.pollInterval <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.templates[t.uriTemplate] = &polledTemplate{server: server, template: t}
+}
+
+// template returns the template of a resource that can be polled.
+func (s *Subscriptions) template(uri string) (*polledTemplate, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.templates {
+		if _, ok := t.template.match(uri); ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Subscribe is the server's SubscribeHandler.
+// Subscribing to a resource read from a GET operation starts polling it, unless it's polled already.
+func (s *Subscriptions) Subscribe(ctx context.Context, req *mcp.ServerRequest[*mcp.SubscribeParams]) error {
+	uri := req.Params.URI
+	if s.webhooks != nil && s.webhooks.subscribes(uri) {
+		return nil
+	}
+	t, ok := s.template(uri)
+	if !ok {
+		return fmt.Errorf("resource %s doesn't support subscriptions", uri)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if poll, ok := s.polls[uri]; ok {
+		poll.sessions[req.Session] = true
+		return nil
+	}
+	ctx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	poll := &resourcePoll{sessions: map[*mcp.ServerSession]bool{req.Session: true}, stop: stop}
+	s.polls[uri] = poll
+	go s.poll(ctx, t, uri, poll)
+	return nil
+}
+
+// Unsubscribe is the server's UnsubscribeHandler.
+// Polling a resource stops once no session is subscribed to it.
+func (s *Subscriptions) Unsubscribe(ctx context.Context, req *mcp.ServerRequest[*mcp.UnsubscribeParams]) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if poll, ok := s.polls[req.Params.URI]; ok {
+		delete(poll.sessions, req.Session)
+		if len(poll.sessions) == 0 {
+			poll.stop()
+			delete(s.polls, req.Params.URI)
+		}
+	}
+	return nil
+}
+
+// Close stops polling every resource.
+func (s *Subscriptions) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for uri, poll := range s.polls {
+		poll.stop()
+		delete(s.polls, uri)
+	}
+}
+
+// poll reads a resource at the poll interval until ctx is done,
+// and notifies subscribers whenever its contents differ from the last time it was read.
+// The first read, when the subscription starts, is what later reads are compared with.
+func (s *Subscriptions) poll(ctx context.Context, t *polledTemplate, uri string, poll *resourcePoll) {
+	logger := s.logger.With("resource", uri)
+	last, err := s.read(ctx, t, uri)
+	if err != nil {
+		logger.Debug("error reading subscribed resource", "error", err)
+	}
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// Sessions that ended without unsubscribing are subscribed no longer
+		if !s.connected(t.server, uri, poll) {
+			return
+		}
+		// Templates replaced by reloading the spec read from the new version
+		if current, ok := s.template(uri); ok {
+			t = current
+		}
+		sum, err := s.read(ctx, t, uri)
+		if err != nil {
+			logger.Debug("error reading subscribed resource", "error", err)
+			continue
+		}
+		if bytes.Equal(sum, last) {
+			continue
+		}
+		last = sum
+		logger.Debug("subscribed resource changed")
+		_ = t.server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	}
+}
+
+// read returns a digest of a resource's contents, or of its absence if the API no longer has it.
+// Metadata isn't included, since it records when the resource was read.
+func (s *Subscriptions) read(ctx context.Context, t *polledTemplate, uri string) ([]byte, error) {
+	result, err := t.template.read(ctx, uri)
+	if err != nil {
+		if isResourceNotFound(err) {
+			return []byte{}, nil
+		}
+		return nil, err
+	}
+	h := sha256.New()
+	for _, c := range result.Contents {
+		fmt.Fprintf(h, "%s\n%d\n", c.MIMEType, len(c.Text)+len(c.Blob))
+		h.Write([]byte(c.Text))
+		h.Write(c.Blob)
+	}
+	return h.Sum(nil), nil
+}
+
+// connected forgets the sessions subscribed to a resource that are no longer connected to the server,
+// and reports whether any are left. Polling stops when none are.
+func (s *Subscriptions) connected(server *mcp.Server, uri string, poll *resourcePoll) bool {
+	sessions := slices.Collect(server.Sessions())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for session := range poll.sessions {
+		if !slices.Contains(sessions, session) {
+			delete(poll.sessions, session)
+		}
+	}
+	if len(poll.sessions) > 0 {
+		return true
+	}
+	if s.polls[uri] == poll {
+		delete(s.polls, uri)
+	}
+	poll.stop()
+	return false
+}
+
+// isResourceNotFound reports whether err is a JSON-RPC error saying that a resource doesn't exist.
+// The SDK doesn't export its error type, so the error's code is found by encoding it.
+func isResourceNotFound(err error) bool {
+	var wire struct {
+		Code int64 `json:"code"`
+	}
+	data, jsonErr := json.Marshal(err)
+	return jsonErr == nil && json.Unmarshal(data, &wire) == nil && wire.Code == mcp.CodeResourceNotFound
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionsPolling(t *testing.T) {
+	var name atomic.Value
+	name.Store("Fido")
+	var requests atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id": 1, "name": %q}`, name.Load())
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {"get": {
+      "operationId": "getPet",
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "responses": {"200": {"description": "OK"}}
+    }}
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	subscriptions := NewSubscriptions(nil, 10*time.Millisecond, nil)
+	defer subscriptions.Close()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, &mcp.ServerOptions{
+		SubscribeHandler:   subscriptions.Subscribe,
+		UnsubscribeHandler: subscriptions.Unsubscribe,
+	})
+	require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient, WithResourceTemplates(), WithSubscriptions(subscriptions)))
+
+	updated := make(chan string, 10)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "dev"}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(ctx context.Context, req *mcp.ClientRequest[*mcp.ResourceUpdatedNotificationParams]) {
+			updated <- req.Params.URI
+		},
+	})
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer clientSession.Close()
+
+	err = clientSession.Subscribe(ctx, &mcp.SubscribeParams{URI: "emcee://tools/getPet/schema"})
+	assert.Error(t, err, "only resources read from GET operations are polled")
+
+	require.NoError(t, clientSession.Subscribe(ctx, &mcp.SubscribeParams{URI: "api://pets/1"}))
+
+	// Unchanged responses send no notifications
+	for requests.Load() < 3 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case uri := <-updated:
+		t.Fatalf("unexpected notification for %s", uri)
+	default:
+	}
+
+	name.Store("Rex")
+	select {
+	case uri := <-updated:
+		assert.Equal(t, "api://pets/1", uri)
+	case <-ctx.Done():
+		t.Fatal("no resource updated notification")
+	}
+
+	// Polling stops once no one is subscribed
+	require.NoError(t, clientSession.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: "api://pets/1"}))
+	time.Sleep(20 * time.Millisecond)
+	stopped := requests.Load()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, stopped, requests.Load())
+}
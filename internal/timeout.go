@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// timeoutArgument is the reserved argument that gives a single tool call more or less time.
+const timeoutArgument = "_timeoutSeconds"
+
+// WithTimeoutArgument adds a reserved _timeoutSeconds argument to every tool,
+// which sets how long a call may take, up to max.
+func WithTimeoutArgument(max time.Duration) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.maxTimeout = max }
+}
+
+// addTimeoutArgument adds the reserved argument that sets how long a tool call may take.
+// Tools with an argument of the same name are left alone.
+func addTimeoutArgument(schema *jsonschema.Schema, max time.Duration) {
+	if _, exists := schema.Properties[timeoutArgument]; exists {
+		return
+	}
+	minimum, maximum := 0.0, max.Seconds()
+	schema.Properties[timeoutArgument] = &jsonschema.Schema{
+		Type:             "number",
+		ExclusiveMinimum: &minimum,
+		Maximum:          &maximum,
+		Description:      "Number of seconds to wait for the API to respond, for slow operations",
+	}
+}
+
+// takeTimeout returns the timeout a tool call requests with its _timeoutSeconds argument, capped at max,
+// and the call's other arguments.
+func takeTimeout(args map[string]any, max time.Duration) (time.Duration, map[string]any) {
+	value, rest := takeArgument(args, timeoutArgument)
+	n, ok := argumentNumber(value)
+	if !ok || n <= 0 {
+		return 0, rest
+	}
+	return min(time.Duration(n*float64(time.Second)), max), rest
+}
+
+// operationTimeout returns how long the configuration gives calls of an operation, or 0 if it doesn't say.
+func (c *Config) operationTimeout(operationID string) time.Duration {
+	if c == nil || operationID == "" {
+		return 0
+	}
+	return time.Duration(c.Timeouts[operationID] * float64(time.Second))
+}
+
+// callTimeoutKey is the context key of how long a tool call may take.
+type callTimeoutKey struct{}
+
+// contextWithCallTimeout returns a context for requests of a tool call that may take longer than the client's timeout.
+func contextWithCallTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, callTimeoutKey{}, timeout)
+}
+
+// timeoutTransport is a RoundTripper that bounds how long each attempt takes,
+// including reading its response body, like http.Client.Timeout does.
+// Requests of a tool call with its own timeout are given that long instead.
+type timeoutTransport struct {
+	Base    http.RoundTripper
+	Timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	timeout := t.Timeout
+	if d, ok := req.Context().Value(callTimeoutKey{}).(time.Duration); ok {
+		timeout = d
+	}
+	if timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose is a response body that cancels its request's context when it's closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// validateTimeouts checks that every operation's timeout is positive.
+func validateTimeouts(timeouts map[string]float64) error {
+	for id, seconds := range timeouts {
+		if seconds <= 0 {
+			return fmt.Errorf("invalid timeout %v for operation %q: must be positive", seconds, id)
+		}
+	}
+	return nil
+}
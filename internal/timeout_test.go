@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsTimeouts(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status": "done"}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Reports API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/reports": {"post": {"operationId": "generateReport", "responses": {"200": {"description": "OK"}}}},
+    "/status": {"get": {"operationId": "getStatus", "responses": {"200": {"description": "OK"}}}}
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client, err := RetryableClient(RetryableClientOptions{Retries: 0, Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	call := func(t *testing.T, clientSession *mcp.ClientSession, name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("config", func(t *testing.T) {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		config := &Config{Timeouts: map[string]float64{"generateReport": 2}}
+		require.NoError(t, RegisterTools(server, []byte(spec), client, WithConfig(config)))
+		clientSession := connectTestClient(t, ctx, server)
+
+		result := call(t, clientSession, "generateReport", nil)
+		assert.False(t, result.IsError, "operations with a timeout in the config get longer than the client's timeout")
+
+		result = call(t, clientSession, "getStatus", nil)
+		assert.True(t, result.IsError, "other operations keep the client's timeout")
+	})
+
+	t.Run("argument", func(t *testing.T) {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+		require.NoError(t, RegisterTools(server, []byte(spec), client, WithTimeoutArgument(time.Second)))
+		clientSession := connectTestClient(t, ctx, server)
+
+		tools, err := clientSession.ListTools(ctx, nil)
+		require.NoError(t, err)
+		for _, tool := range tools.Tools {
+			require.Contains(t, tool.InputSchema.Properties, timeoutArgument, tool.Name)
+			assert.Equal(t, 1.0, *tool.InputSchema.Properties[timeoutArgument].Maximum)
+		}
+
+		result := call(t, clientSession, "getStatus", map[string]any{timeoutArgument: 0.5})
+		assert.False(t, result.IsError, "calls may ask for longer than the client's timeout")
+
+		result = call(t, clientSession, "getStatus", map[string]any{timeoutArgument: 0.1})
+		assert.True(t, result.IsError, "calls time out after the time they ask for")
+
+		result = call(t, clientSession, "getStatus", nil)
+		assert.True(t, result.IsError, "calls without the argument keep the client's timeout")
+	})
+}
+
+func TestTakeTimeout(t *testing.T) {
+	timeout, rest := takeTimeout(map[string]any{timeoutArgument: 30.0, "id": "1"}, time.Minute)
+	assert.Equal(t, 30*time.Second, timeout)
+	assert.Equal(t, map[string]any{"id": "1"}, rest)
+
+	timeout, _ = takeTimeout(map[string]any{timeoutArgument: 3600.0}, time.Minute)
+	assert.Equal(t, time.Minute, timeout, "timeouts are capped at the maximum")
+
+	timeout, rest = takeTimeout(map[string]any{"id": "1"}, time.Minute)
+	assert.Zero(t, timeout)
+	assert.Equal(t, map[string]any{"id": "1"}, rest)
+}
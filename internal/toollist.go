@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ToolSummary describes a tool generated for a spec, along with the operation it calls.
+type ToolSummary struct {
+	Name        string             `json:"name"`
+	Method      string             `json:"method,omitempty"`
+	Path        string             `json:"path,omitempty"`
+	Description string             `json:"description,omitempty"`
+	InputSchema *jsonschema.Schema `json:"inputSchema"`
+}
+
+// ListTools returns the tools generated for a spec, in the order servers list them, without calling the API.
+// Options apply as they do to RegisterTools, so that the list reflects the same configuration.
+func ListTools(ctx context.Context, specData []byte, opts ...RegisterToolsOption) ([]ToolSummary, error) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "emcee", Version: "dev"}, nil)
+	reg, err := registerTools(server, specData, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	server.AddReceivingMiddleware(reg.middleware...)
+
+	// Tools are listed by a client, so that they're described exactly as they would be to one
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tools: %w", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "emcee-tools", Version: "dev"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error listing tools: %w", err)
+	}
+	defer session.Close()
+
+	var summaries []ToolSummary
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("error listing tools: %w", err)
+		}
+		summary := ToolSummary{Name: tool.Name, Description: tool.Description, InputSchema: tool.InputSchema}
+		summary.Method, summary.Path, _ = strings.Cut(reg.endpoints[tool.Name], " ")
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListTools(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {"get": {
+      "operationId": "listPets",
+      "summary": "List pets",
+      "parameters": [{"name": "limit", "in": "query", "schema": {"type": "integer"}}],
+      "responses": {"200": {"description": "OK"}}
+    }},
+    "/pets/{petId}": {"delete": {
+      "operationId": "deletePet",
+      "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+      "responses": {"204": {"description": "Deleted"}}
+    }}
+  }
+}`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tools, err := ListTools(ctx, []byte(spec), WithToolPrefix("pets_"), WithConfig(&Config{DisabledOperations: []string{"deletePet"}}))
+	require.NoError(t, err)
+	require.Len(t, tools, 1, "disabled operations aren't listed")
+	assert.Equal(t, "pets_listPets", tools[0].Name)
+	assert.Equal(t, "GET", tools[0].Method)
+	assert.Equal(t, "/pets", tools[0].Path)
+	assert.Contains(t, tools[0].Description, "List pets")
+	require.NotNil(t, tools[0].InputSchema)
+	assert.Contains(t, tools[0].InputSchema.Properties, "limit")
+
+	_, err = ListTools(ctx, []byte(`not a spec`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+)
+
+// ToolNameFormat is a template for the names of generated tools,
+// like "{tag}_{operationId}" or "{method}_{path}".
+// Its placeholders are replaced with parts of each operation:
+//   - {operationId}: the operation's ID
+//   - {tag}: the operation's first tag
+//   - {method}: the operation's method, in lowercase
+//   - {path}: the operation's path, with its segments joined by underscores (e.g. pets_petId)
+type ToolNameFormat string
+
+// toolNamePlaceholder matches a placeholder in a tool name format.
+var toolNamePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// ParseToolNameFormat parses a tool name format.
+// Formats must contain {operationId} or {path}, so that each operation gets its own name.
+func ParseToolNameFormat(s string) (ToolNameFormat, error) {
+	unique := false
+	for _, match := range toolNamePlaceholder.FindAllStringSubmatch(s, -1) {
+		switch match[1] {
+		case "operationId", "path":
+			unique = true
+		case "tag", "method":
+		default:
+			return "", fmt.Errorf("invalid tool name format %q: unknown placeholder %s (expected {operationId}, {tag}, {method}, or {path})", s, match[0])
+		}
+	}
+	if !unique {
+		return "", fmt.Errorf("invalid tool name format %q: must contain {operationId} or {path}", s)
+	}
+	return ToolNameFormat(s), nil
+}
+
+// WithToolNameFormat names tools using a format, like "{tag}_{operationId}", instead of by operation ID.
+// A tool prefix is prepended to the formatted name.
+func WithToolNameFormat(format ToolNameFormat) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.toolNameFormat = format }
+}
+
+// name returns the name of an operation's tool, before any prefix is added.
+// Values are reduced to letters, digits, underscores, hyphens, and dots, which every client accepts,
+// and placeholders with no value, like {tag} for an untagged operation, are dropped along with their separator.
+func (f ToolNameFormat) name(method, p string, op *v3.Operation) string {
+	if f == "" {
+		return op.OperationId
+	}
+	name := toolNamePlaceholder.ReplaceAllStringFunc(string(f), func(placeholder string) string {
+		var value string
+		switch placeholder {
+		case "{operationId}":
+			value = toolNameValue(op.OperationId)
+		case "{tag}":
+			if len(op.Tags) > 0 {
+				value = toolNameValue(op.Tags[0])
+			}
+		case "{method}":
+			value = strings.ToLower(method)
+		case "{path}":
+			value = toolNameValue(strings.NewReplacer("{", "", "}", "").Replace(p))
+		}
+		if value == "" {
+			return emptyPlaceholder
+		}
+		return value
+	})
+	return strings.Trim(emptyPlaceholderAndSeparator.ReplaceAllString(name, ""), "_-.")
+}
+
+// namesWithoutOperationID reports whether the format names operations without using their IDs,
+// so that operations without IDs can have tools too.
+func (f ToolNameFormat) namesWithoutOperationID() bool {
+	return f != "" && !strings.Contains(string(f), "{operationId}")
+}
+
+// emptyPlaceholder marks where a placeholder with no value was, so that it's removed with the separator after it.
+const emptyPlaceholder = "\x00"
+
+var (
+	invalidToolNameChars         = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+	emptyPlaceholderAndSeparator = regexp.MustCompile(`\x00[_.-]?`)
+)
+
+// toolNameValue replaces runs of characters that aren't allowed in tool names with underscores.
+func toolNameValue(s string) string {
+	return strings.Trim(invalidToolNameChars.ReplaceAllString(s, "_"), "_")
+}
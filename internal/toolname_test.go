@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseToolNameFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr string
+	}{
+		{format: "{operationId}"},
+		{format: "{tag}_{operationId}"},
+		{format: "{method}_{path}"},
+		{format: "api.{tag}.{path}"},
+		{format: "{tag}_{method}", wantErr: "must contain {operationId} or {path}"},
+		{format: "tools", wantErr: "must contain {operationId} or {path}"},
+		{format: "{summary}_{operationId}", wantErr: "unknown placeholder {summary}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			format, err := ParseToolNameFormat(tt.format)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, ToolNameFormat(tt.format), format)
+		})
+	}
+}
+
+func TestToolNameFormat(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {
+    "/pets": {
+      "get": {"operationId": "listPets", "tags": ["pets"], "responses": {"200": {"description": "OK"}}},
+      "post": {"tags": ["pet store"], "responses": {"201": {"description": "Created"}}}
+    },
+    "/pets/{petId}": {"delete": {"operationId": "deletePet", "responses": {"204": {"description": "Deleted"}}}}
+  }
+}`
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	names := func(t *testing.T, opts ...RegisterToolsOption) []string {
+		t.Helper()
+		tools, err := ListTools(ctx, []byte(spec), opts...)
+		require.NoError(t, err)
+		var names []string
+		for _, tool := range tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	t.Run("default", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"listPets", "deletePet"}, names(t))
+	})
+
+	t.Run("tag and operation ID", func(t *testing.T) {
+		// Operations without a tag drop the placeholder and its separator
+		assert.ElementsMatch(t, []string{"pets_listPets", "deletePet"}, names(t, WithToolNameFormat("{tag}_{operationId}")))
+	})
+
+	t.Run("method and path", func(t *testing.T) {
+		// Operations without IDs get tools when the format doesn't need them
+		assert.ElementsMatch(t, []string{"get_pets", "post_pets", "delete_pets_petId"}, names(t, WithToolNameFormat("{method}_{path}")))
+	})
+
+	t.Run("sanitized values with prefix", func(t *testing.T) {
+		got := names(t, WithToolNameFormat("{tag}.{method}_{path}"), WithToolPrefix("api_"))
+		assert.ElementsMatch(t, []string{"api_pets.get_pets", "api_pet_store.post_pets", "api_delete_pets_petId"}, got)
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		_, err := ListTools(ctx, []byte(spec), WithToolNameFormat("{path}"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `tool name "pets" for operation "POST /pets" collides with operation "listPets"`)
+	})
+}
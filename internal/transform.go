@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ResponseTransform rewrites text returned by a tool before it reaches the client,
+// for example to translate it or to scrub personal data.
+// It's called with the name of the tool, and returns the replacement text.
+type ResponseTransform func(ctx context.Context, toolName, text string) (string, error)
+
+// WithResponseTransform applies transform to the results of every generated tool:
+// to each text content, and to each string in the structured content, so the two stay consistent.
+// If transform returns an error, the call fails with an error result instead of returning untransformed text.
+func WithResponseTransform(transform ResponseTransform) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.responseTransform = transform }
+}
+
+// responseTransformMiddleware applies a transform to the results of tools/call requests for the named tools.
+// Tools registered for other specs on the same server are left to their own middleware.
+func responseTransformMiddleware(transform ResponseTransform, tools []string) mcp.Middleware {
+	names := make(map[string]struct{}, len(tools))
+	for _, name := range tools {
+		names[name] = struct{}{}
+	}
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			res, err := next(ctx, method, req)
+			r, ok := req.(*mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]])
+			if err != nil || !ok || r.Params == nil {
+				return res, err
+			}
+			if _, ok := names[r.Params.Name]; !ok {
+				return res, err
+			}
+			result, ok := res.(*mcp.CallToolResult)
+			if !ok || result == nil {
+				return res, err
+			}
+			transformed, err := transformResult(ctx, transform, r.Params.Name, result)
+			if err != nil {
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error transforming response: %v", err)}},
+					IsError: true,
+				}, nil
+			}
+			return transformed, nil
+		}
+	}
+}
+
+// transformResult returns a copy of a tool result with a transform applied to its text and structured content.
+// The result itself isn't modified, since it may be cached and returned again.
+func transformResult(ctx context.Context, transform ResponseTransform, toolName string, result *mcp.CallToolResult) (*mcp.CallToolResult, error) {
+	transformed := *result
+	transformed.Content = make([]mcp.Content, len(result.Content))
+	for i, content := range result.Content {
+		text, ok := content.(*mcp.TextContent)
+		if !ok {
+			transformed.Content[i] = content
+			continue
+		}
+		copied := *text
+		var err error
+		if copied.Text, err = transform(ctx, toolName, text.Text); err != nil {
+			return nil, err
+		}
+		transformed.Content[i] = &copied
+	}
+	if result.StructuredContent != nil {
+		var err error
+		if transformed.StructuredContent, err = transformStrings(ctx, transform, toolName, result.StructuredContent); err != nil {
+			return nil, err
+		}
+	}
+	return &transformed, nil
+}
+
+// transformStrings returns a copy of a decoded JSON value with a transform applied to every string.
+// Object keys are left as is, so the value keeps the shape declared by the tool's output schema.
+func transformStrings(ctx context.Context, transform ResponseTransform, toolName string, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return transform(ctx, toolName, v)
+	case map[string]any:
+		transformed := make(map[string]any, len(v))
+		for key, elem := range v {
+			var err error
+			if transformed[key], err = transformStrings(ctx, transform, toolName, elem); err != nil {
+				return nil, err
+			}
+		}
+		return transformed, nil
+	case []any:
+		transformed := make([]any, len(v))
+		for i, elem := range v {
+			var err error
+			if transformed[i], err = transformStrings(ctx, transform, toolName, elem); err != nil {
+				return nil, err
+			}
+		}
+		return transformed, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseTransform(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name": "Fido", "owner": {"email": "alice@example.com"}, "tags": ["good dog"]}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets/{petId}": {
+      "get": {
+        "operationId": "getPet",
+        "parameters": [{"name": "petId", "in": "path", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK", "content": {"application/json": {"schema": {
+          "type": "object",
+          "properties": {
+            "name": {"type": "string"},
+            "owner": {"type": "object", "properties": {"email": {"type": "string"}}},
+            "tags": {"type": "array", "items": {"type": "string"}}
+          }
+        }}}}}
+      }
+    }
+  }
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var tools []string
+	scrub := func(ctx context.Context, toolName, text string) (string, error) {
+		tools = append(tools, toolName)
+		return strings.ReplaceAll(text, "alice@example.com", "[email]"), nil
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithResponseTransform(scrub)))
+	clientSession := connectTestClient(t, ctx, server)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": "1"}})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	require.Len(t, result.Content, 1)
+	text := result.Content[0].(*mcp.TextContent).Text
+	assert.Contains(t, text, "[email]")
+	assert.NotContains(t, text, "alice@example.com")
+	assert.Equal(t, map[string]any{
+		"name":  "Fido",
+		"owner": map[string]any{"email": "[email]"},
+		"tags":  []any{"good dog"},
+	}, result.StructuredContent, "strings in structured content are transformed too")
+	assert.Contains(t, tools, "getPet")
+
+	failing := func(ctx context.Context, toolName, text string) (string, error) {
+		return "", errors.New("translation service unavailable")
+	}
+	server = mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithResponseTransform(failing)))
+	clientSession = connectTestClient(t, ctx, server)
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{Name: "getPet", Arguments: map[string]any{"petId": "1"}})
+	require.NoError(t, err)
+	assert.True(t, result.IsError, "untransformed text is never returned")
+	assert.Contains(t, result.Content[0].(*mcp.TextContent).Text, "translation service unavailable")
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// errInvalidParams is the JSON-RPC "invalid params" error (-32602).
+// The SDK doesn't export its error values, so it's obtained by decoding a wire error;
+// wrapping it preserves the error code in the response sent to the client.
+var errInvalidParams = func() error {
+	msg, err := jsonrpc.DecodeMessage([]byte(`{"jsonrpc":"2.0","id":0,"error":{"code":-32602,"message":"invalid params"}}`))
+	if err != nil {
+		panic(err)
+	}
+	return msg.(*jsonrpc.Response).Error
+}()
+
+// argumentValidator checks tools/call arguments against a tool's input schema,
+// reporting every invalid field rather than only the first.
+type argumentValidator struct {
+	required   []string
+	properties map[string]*jsonschema.Resolved
+	additional *jsonschema.Resolved
+}
+
+func newArgumentValidator(schema *jsonschema.Schema) (*argumentValidator, error) {
+	v := &argumentValidator{
+		required:   schema.Required,
+		properties: make(map[string]*jsonschema.Resolved),
+	}
+	for name, prop := range schema.Properties {
+		resolved, err := prop.Resolve(nil)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", name, err)
+		}
+		v.properties[name] = resolved
+	}
+	if schema.AdditionalProperties != nil {
+		resolved, err := schema.AdditionalProperties.Resolve(nil)
+		if err != nil {
+			return nil, fmt.Errorf("additional properties: %w", err)
+		}
+		v.additional = resolved
+	}
+	return v, nil
+}
+
+// validate returns a message for each invalid field, sorted by field name.
+func (v *argumentValidator) validate(args map[string]any) []string {
+	var problems []string
+	for _, name := range v.required {
+		if _, ok := args[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: required argument is missing", name))
+		}
+	}
+	for name, value := range args {
+		resolved, ok := v.properties[name]
+		if !ok {
+			resolved = v.additional
+		}
+		if resolved == nil {
+			continue
+		}
+		if err := resolved.Validate(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", name, strings.TrimPrefix(err.Error(), "validating root: ")))
+		}
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+// validationMiddleware rejects tools/call requests whose arguments don't match the tool's input schema
+// with an "invalid params" error listing each invalid field, so malformed requests never reach the API.
+func validationMiddleware(schemas map[string]*jsonschema.Schema) (mcp.Middleware, error) {
+	validators := make(map[string]*argumentValidator, len(schemas))
+	for name, schema := range schemas {
+		v, err := newArgumentValidator(schema)
+		if err != nil {
+			return nil, fmt.Errorf("tool %q: %w", name, err)
+		}
+		validators[name] = v
+	}
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if r, ok := req.(*mcp.ServerRequest[*mcp.CallToolParamsFor[json.RawMessage]]); ok && r.Params != nil {
+				if v, ok := validators[r.Params.Name]; ok {
+					if err := validateRawArguments(r.Params.Name, r.Params.Arguments, v); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return next(ctx, method, req)
+		}
+	}, nil
+}
+
+func validateRawArguments(toolName string, raw json.RawMessage, v *argumentValidator) error {
+	args := map[string]any{}
+	if len(raw) > 0 && !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return rpcError(invalidParamsCode, fmt.Sprintf("%v: arguments for tool %q must be an object", errInvalidParams, toolName),
+				errorData{Kind: errorKindInvalidArguments, Operation: toolName, Detail: "arguments must be an object"})
+		}
+	}
+	problems := v.validate(args)
+	if len(problems) == 0 {
+		return nil
+	}
+	detail := strings.Join(problems, "\n- ")
+	return rpcError(invalidParamsCode, fmt.Sprintf("%v: invalid arguments for tool %q:\n- %s", errInvalidParams, toolName, detail),
+		errorData{Kind: errorKindInvalidArguments, Operation: toolName, Detail: "- " + detail})
+}
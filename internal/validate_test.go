@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsValidatesArguments(t *testing.T) {
+	var calls atomic.Int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/pets": {
+      "post": {
+        "operationId": "createPet",
+        "parameters": [
+          {"name": "status", "in": "query", "schema": {"type": "string", "enum": ["available", "sold"]}},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1, "maximum": 100}}
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": ["name"],
+                "properties": {
+                  "name": {"type": "string", "pattern": "^[a-z]+$"},
+                  "age": {"type": "integer"}
+                }
+              }
+            }
+          }
+        },
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, api.URL)
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	clientSession := connectTestClient(t, ctx, server)
+
+	_, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "createPet",
+		Arguments: map[string]any{"status": "lost", "limit": 500, "age": "two"},
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errInvalidParams), "expected invalid params error, got %v", err)
+	assert.ErrorContains(t, err, `invalid arguments for tool "createPet"`)
+	assert.ErrorContains(t, err, "age: type:")
+	assert.ErrorContains(t, err, "limit: maximum:")
+	assert.ErrorContains(t, err, "name: required argument is missing")
+	assert.ErrorContains(t, err, "status: enum:")
+
+	_, err = clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "createPet",
+		Arguments: map[string]any{"name": "Fido"},
+	})
+	assert.ErrorContains(t, err, "name: pattern:")
+	assert.Zero(t, calls.Load(), "invalid requests aren't sent to the API")
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "createPet",
+		Arguments: map[string]any{"name": "fido", "status": "sold", "limit": 10},
+	})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.EqualValues(t, 1, calls.Load())
+}
@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+)
+
+const (
+	// maxWebhookEvents is how many of the most recent events are kept for each webhook or callback.
+	maxWebhookEvents = 100
+	// maxWebhookEventBytes is the largest event body that's accepted.
+	maxWebhookEventBytes = 1 << 20
+	// webhookResourcePrefix is the start of the URIs of the resources listing received events.
+	webhookResourcePrefix = "emcee://"
+)
+
+// WebhookReceiver receives the webhooks and callbacks that specs declare, over HTTP,
+// and exposes the events it receives as resources that clients can subscribe to.
+type WebhookReceiver struct {
+	ln      net.Listener
+	baseURL string
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	routes map[string]*webhookRoute // by path, like /webhooks/newPet
+}
+
+// webhookRoute is a webhook or callback, and the events received for it.
+type webhookRoute struct {
+	server  *mcp.Server
+	uri     string
+	methods []string
+	events  []webhookEvent
+}
+
+// webhookEvent is a request the API sent to a webhook or callback.
+// Bodies that are valid JSON are included as is; other bodies are included as a string.
+type webhookEvent struct {
+	ReceivedAt time.Time         `json:"receivedAt"`
+	Method     string            `json:"method"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       any               `json:"body,omitempty"`
+}
+
+// NewWebhookReceiver listens for webhooks and callbacks at addr, like localhost:8081.
+// If publicURL is set, it's the URL given to the API for reaching the receiver, like a tunnel to addr.
+func NewWebhookReceiver(addr, publicURL string, logger *slog.Logger) (*WebhookReceiver, error) {
+	if publicURL != "" {
+		u, err := url.Parse(publicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q: expected an http or https URL", publicURL)
+		}
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error listening for webhooks: %w", err)
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &WebhookReceiver{
+		ln:      ln,
+		baseURL: strings.TrimSuffix(cmp.Or(publicURL, "http://"+ln.Addr().String()), "/"),
+		logger:  logger,
+		routes:  make(map[string]*webhookRoute),
+	}, nil
+}
+
+// WithWebhooks receives the webhooks and callbacks a spec declares with receiver,
+// and exposes the events received for each as a resource, like emcee://webhooks/newPet.
+func WithWebhooks(receiver *WebhookReceiver) RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.webhooks = receiver }
+}
+
+// Serve answers requests to the receiver until ctx is done.
+func (r *WebhookReceiver) Serve(ctx context.Context) error {
+	srv := &http.Server{Handler: r, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	if err := srv.Serve(r.ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error receiving webhooks: %w", err)
+	}
+	return nil
+}
+
+// Close stops listening for webhooks.
+func (r *WebhookReceiver) Close() error {
+	return r.ln.Close()
+}
+
+// subscribes reports whether a resource lists the events of a webhook or callback,
+// whose subscribers the server notifies when events arrive.
+func (r *WebhookReceiver) subscribes(uri string) bool {
+	_, ok := r.route(uri)
+	return ok
+}
+
+// route returns the route whose events are the resource with a URI.
+func (r *WebhookReceiver) route(uri string) (*webhookRoute, bool) {
+	p, ok := strings.CutPrefix(uri, webhookResourcePrefix)
+	if !ok {
+		return nil, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	route, ok := r.routes["/"+p]
+	return route, ok
+}
+
+// add registers a webhook or callback at a path, and the resource listing its events.
+// Events already received for the path are kept, so that they survive reloading the spec.
+// It returns the URL the API sends events to, and the URI of the resource.
+func (r *WebhookReceiver) add(server *mcp.Server, p, name, description string, item *v3.PathItem) (string, string) {
+	uri := webhookResourcePrefix + strings.TrimPrefix(p, "/")
+	var methods []string
+	if ops, err := pathOperations(item); err == nil {
+		for _, op := range ops {
+			methods = append(methods, op.method)
+		}
+	}
+	r.mu.Lock()
+	route, ok := r.routes[p]
+	if !ok {
+		route = &webhookRoute{uri: uri}
+		r.routes[p] = route
+	}
+	route.server, route.methods = server, methods
+	r.mu.Unlock()
+
+	endpoint := r.baseURL + p
+	server.AddResource(&mcp.Resource{
+		URI:         uri,
+		Name:        name,
+		Description: strings.TrimSpace(fmt.Sprintf("Events the API sent to %s, most recent last. %s", endpoint, description)),
+		MIMEType:    "application/json",
+	}, func(ctx context.Context, req *mcp.ServerRequest[*mcp.ReadResourceParams]) (*mcp.ReadResourceResult, error) {
+		r.mu.Lock()
+		events := route.events
+		if events == nil {
+			events = []webhookEvent{}
+		}
+		data, err := json.MarshalIndent(events, "", "  ")
+		r.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: "application/json", Text: string(data)}},
+		}, nil
+	})
+	return endpoint, uri
+}
+
+// addWebhooks registers the webhooks a spec declares, and returns the URIs of their resources.
+func (r *WebhookReceiver) addWebhooks(server *mcp.Server, webhooks *orderedmap.Map[string, *v3.PathItem]) []string {
+	var uris []string
+	for pair := webhooks.First(); pair != nil; pair = pair.Next() {
+		name, item := pair.Key(), pair.Value()
+		if item == nil {
+			continue
+		}
+		_, uri := r.add(server, "/webhooks/"+url.PathEscape(name), name+" webhook", pathItemSummary(item), item)
+		uris = append(uris, uri)
+	}
+	return uris
+}
+
+// addCallbacks registers the callbacks of an operation, and returns the URIs of their resources,
+// along with a description of where the API should send each, for the operation's tool description.
+func (r *WebhookReceiver) addCallbacks(server *mcp.Server, operationID string, op *v3.Operation) ([]string, string) {
+	if op.Callbacks == nil {
+		return nil, ""
+	}
+	var uris []string
+	var notes []string
+	for pair := op.Callbacks.First(); pair != nil; pair = pair.Next() {
+		name, callback := pair.Key(), pair.Value()
+		if callback == nil || callback.Expression == nil {
+			continue
+		}
+		// Expressions of the same callback usually differ only in how the API finds the URL, so they share a route
+		var item *v3.PathItem
+		for expr := callback.Expression.First(); expr != nil && item == nil; expr = expr.Next() {
+			item = expr.Value()
+		}
+		if item == nil {
+			continue
+		}
+		p := "/callbacks/" + url.PathEscape(operationID) + "/" + url.PathEscape(name)
+		endpoint, uri := r.add(server, p, operationID+" "+name+" callback", pathItemSummary(item), item)
+		uris = append(uris, uri)
+		notes = append(notes, fmt.Sprintf("To receive the %s callback, give the API the URL %s. Received events are listed in the resource %s.", name, endpoint, uri))
+	}
+	return uris, strings.Join(notes, "\n")
+}
+
+// pathItemSummary returns the description of a webhook or callback's first operation.
+func pathItemSummary(item *v3.PathItem) string {
+	ops, err := pathOperations(item)
+	if err != nil || len(ops) == 0 {
+		return ""
+	}
+	return cmp.Or(ops[0].op.Summary, ops[0].op.Description)
+}
+
+func (r *WebhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	route, ok := r.routes[req.URL.EscapedPath()]
+	r.mu.Unlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	if len(route.methods) > 0 && !slices.Contains(route.methods, req.Method) {
+		w.Header().Set("Allow", strings.Join(route.methods, ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookEventBytes))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	event := webhookEvent{ReceivedAt: time.Now().UTC(), Method: req.Method, Headers: make(map[string]string)}
+	for name := range req.Header {
+		if isSecretName(name) {
+			event.Headers[name] = redactedValue
+		} else {
+			event.Headers[name] = req.Header.Get(name)
+		}
+	}
+	if json.Valid(body) {
+		event.Body = json.RawMessage(body)
+	} else if len(body) > 0 {
+		event.Body = string(body)
+	}
+
+	r.mu.Lock()
+	route.events = append(route.events, event)
+	if len(route.events) > maxWebhookEvents {
+		route.events = append([]webhookEvent(nil), route.events[len(route.events)-maxWebhookEvents:]...)
+	}
+	server, uri := route.server, route.uri
+	r.mu.Unlock()
+
+	r.logger.Debug("received webhook", "resource", uri, "method", req.Method)
+	_ = server.ResourceUpdated(req.Context(), &mcp.ResourceUpdatedNotificationParams{URI: uri})
+	w.WriteHeader(http.StatusNoContent)
+}
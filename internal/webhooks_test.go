@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWebhooks(t *testing.T) {
+	spec := `{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "webhooks": {
+    "newPet": {"post": {"summary": "A pet was added", "responses": {"200": {"description": "OK"}}}}
+  },
+  "paths": {
+    "/subscriptions": {"post": {
+      "operationId": "createSubscription",
+      "summary": "Subscribe to events",
+      "callbacks": {"onEvent": {"{$request.body#/callbackUrl}": {"post": {"responses": {"200": {"description": "OK"}}}}}},
+      "responses": {"201": {"description": "Created"}}
+    }}
+  }
+}`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	receiver, err := NewWebhookReceiver("127.0.0.1:0", "", nil)
+	require.NoError(t, err)
+	go func() { _ = receiver.Serve(ctx) }()
+
+	subscriptions := NewSubscriptions(receiver, 0, nil)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, &mcp.ServerOptions{
+		SubscribeHandler:   subscriptions.Subscribe,
+		UnsubscribeHandler: subscriptions.Unsubscribe,
+	})
+	require.NoError(t, RegisterTools(server, []byte(spec), http.DefaultClient, WithWebhooks(receiver), WithSubscriptions(subscriptions)))
+
+	updated := make(chan string, 1)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "dev"}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(ctx context.Context, req *mcp.ClientRequest[*mcp.ResourceUpdatedNotificationParams]) {
+			updated <- req.Params.URI
+		},
+	})
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	require.NoError(t, err)
+	defer serverSession.Close()
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer clientSession.Close()
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	assert.Contains(t, tools.Tools[0].Description, receiver.baseURL+"/callbacks/createSubscription/onEvent")
+	assert.Contains(t, tools.Tools[0].Description, "emcee://callbacks/createSubscription/onEvent")
+
+	resources, err := clientSession.ListResources(ctx, nil)
+	require.NoError(t, err)
+	var uris []string
+	for _, r := range resources.Resources {
+		uris = append(uris, r.URI)
+	}
+	assert.ElementsMatch(t, []string{"emcee://webhooks/newPet", "emcee://callbacks/createSubscription/onEvent"}, uris)
+
+	require.NoError(t, clientSession.Subscribe(ctx, &mcp.SubscribeParams{URI: "emcee://webhooks/newPet"}))
+	err = clientSession.Subscribe(ctx, &mcp.SubscribeParams{URI: "emcee://tools/unknown"})
+	assert.Error(t, err, "only webhooks and callbacks can be subscribed to")
+
+	req, err := http.NewRequest(http.MethodPost, receiver.baseURL+"/webhooks/newPet", strings.NewReader(`{"id": 1, "name": "Fido"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature", "sha256=abc")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	select {
+	case uri := <-updated:
+		assert.Equal(t, "emcee://webhooks/newPet", uri)
+	case <-ctx.Done():
+		t.Fatal("no resource updated notification")
+	}
+
+	result, err := clientSession.ReadResource(ctx, &mcp.ReadResourceParams{URI: "emcee://webhooks/newPet"})
+	require.NoError(t, err)
+	var events []webhookEvent
+	require.NoError(t, json.Unmarshal([]byte(result.Contents[0].Text), &events))
+	require.Len(t, events, 1)
+	assert.Equal(t, http.MethodPost, events[0].Method)
+	assert.Equal(t, map[string]any{"id": 1.0, "name": "Fido"}, events[0].Body)
+	assert.Equal(t, redactedValue, events[0].Headers["X-Signature"])
+
+	resp, err = http.Get(receiver.baseURL + "/webhooks/newPet")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode, "webhooks only accept the methods the spec declares")
+
+	resp, err = http.Post(receiver.baseURL+"/webhooks/unknown", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,420 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath"
+	"github.com/speakeasy-api/jsonpath/pkg/jsonpath/config"
+	"gopkg.in/yaml.v3"
+)
+
+// workflowTemplatePattern matches the templates in the arguments of workflow steps, like {{$.steps.createCustomer.id}}.
+var workflowTemplatePattern = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// Workflow is a tool, defined in the config file, that calls several operations in turn,
+// like creating a customer and then subscribing them to a plan.
+type Workflow struct {
+	// Description describes the tool to the model.
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Arguments are the tool's arguments, by name.
+	Arguments map[string]*WorkflowArgument `yaml:"arguments" json:"arguments,omitempty"`
+	// Steps are the operations the tool calls, in order. The tool stops at the first step that fails.
+	Steps []*WorkflowStep `yaml:"steps" json:"steps"`
+}
+
+// WorkflowArgument is an argument of a workflow's tool.
+type WorkflowArgument struct {
+	// Type is the argument's JSON Schema type, "string" by default.
+	Type        string `yaml:"type" json:"type,omitempty"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	Required    bool   `yaml:"required" json:"required,omitempty"`
+}
+
+// WorkflowStep is a call of an operation in a workflow.
+type WorkflowStep struct {
+	// Name is how later steps refer to the step's result. It's the step's operation ID by default.
+	Name string `yaml:"name" json:"name,omitempty"`
+	// Operation is the ID of the operation the step calls.
+	Operation string `yaml:"operation" json:"operation"`
+	// Arguments are the arguments the operation is called with.
+	// Strings may contain templates: JSONPath expressions in double braces selecting the workflow's arguments
+	// and the results of earlier steps, like "{{$.arguments.email}}" or "{{$.steps.createCustomer.id}}".
+	// A string that's only a template is replaced by the value it selects, whatever its type,
+	// and arguments whose templates select nothing are left out.
+	Arguments map[string]any `yaml:"arguments" json:"arguments,omitempty"`
+}
+
+// name returns the name of the step's result.
+func (s *WorkflowStep) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Operation
+}
+
+// validate checks that a workflow has steps, that they're named uniquely, and that their templates are valid.
+func (w *Workflow) validate() error {
+	if w == nil || len(w.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	for _, name := range slices.Sorted(maps.Keys(w.Arguments)) {
+		if arg := w.Arguments[name]; arg != nil {
+			switch arg.Type {
+			case "", "string", "integer", "number", "boolean", "array", "object":
+			default:
+				return fmt.Errorf("argument %q has invalid type %q", name, arg.Type)
+			}
+		}
+	}
+	names := make(map[string]bool)
+	for i, step := range w.Steps {
+		if step == nil || step.Operation == "" {
+			return fmt.Errorf("step %d has no operation", i+1)
+		}
+		if names[step.name()] {
+			return fmt.Errorf("more than one step is named %q", step.name())
+		}
+		names[step.name()] = true
+		if err := validateTemplates(step.Arguments); err != nil {
+			return fmt.Errorf("step %q: %w", step.name(), err)
+		}
+	}
+	return nil
+}
+
+// validateTemplates checks that the templates in a step's argument values are valid JSONPath expressions.
+func validateTemplates(value any) error {
+	switch v := value.(type) {
+	case string:
+		for _, m := range workflowTemplatePattern.FindAllStringSubmatch(v, -1) {
+			if _, err := jsonpath.NewPath(m[1], config.WithPropertyNameExtension()); err != nil {
+				return fmt.Errorf("invalid template %q: %w", m[0], err)
+			}
+		}
+	case map[string]any:
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			if err := validateTemplates(v[name]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := validateTemplates(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// inputSchema returns the input schema of a workflow's tool.
+func (w *Workflow) inputSchema() *jsonschema.Schema {
+	schema := &jsonschema.Schema{Type: "object", Properties: make(map[string]*jsonschema.Schema)}
+	for _, name := range slices.Sorted(maps.Keys(w.Arguments)) {
+		arg := w.Arguments[name]
+		if arg == nil {
+			arg = &WorkflowArgument{}
+		}
+		schema.Properties[name] = &jsonschema.Schema{Type: cmp.Or(arg.Type, "string"), Description: arg.Description}
+		if arg.Required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// workflowRunner calls the steps of workflows through an in-process client session connected to the server,
+// so each step is handled exactly as if the client had called its tool, including argument validation and middleware.
+type workflowRunner struct {
+	server *mcp.Server
+
+	mu      sync.Mutex
+	session *mcp.ClientSession
+}
+
+// connect returns the client session used to call steps, connecting it on first use.
+func (r *workflowRunner) connect(ctx context.Context) (*mcp.ClientSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.session != nil {
+		return r.session, nil
+	}
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	if _, err := r.server.Connect(ctx, serverTransport, nil); err != nil {
+		return nil, fmt.Errorf("error connecting workflow session: %w", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "emcee-workflow", Version: "dev"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting workflow session: %w", err)
+	}
+	r.session = session
+	return session, nil
+}
+
+// workflowTool is a workflow whose steps have been matched with the tools of their operations.
+type workflowTool struct {
+	name     string
+	workflow *Workflow
+	steps    []string // the tool each step calls
+	runner   *workflowRunner
+}
+
+// newWorkflowTool returns the tool of a workflow, given the tools of operations by operation ID.
+// Steps can't call operations that need confirmation, since there's no one to confirm them.
+func newWorkflowTool(name string, w *Workflow, operations map[string]*mcp.Tool, runner *workflowRunner) (*mcp.Tool, *workflowTool, error) {
+	t := &workflowTool{name: name, workflow: w, runner: runner}
+	var annotations []*mcp.ToolAnnotations
+	for _, step := range w.Steps {
+		tool, ok := operations[step.Operation]
+		if !ok {
+			return nil, nil, fmt.Errorf("workflow %q: step %q calls unknown or disabled operation %q", name, step.name(), step.Operation)
+		}
+		if _, ok := tool.InputSchema.Properties[confirmArgument]; ok {
+			return nil, nil, fmt.Errorf("workflow %q: step %q calls operation %q, which requires confirmation", name, step.name(), step.Operation)
+		}
+		t.steps = append(t.steps, tool.Name)
+		annotations = append(annotations, tool.Annotations)
+	}
+
+	desc := w.Description
+	if desc == "" {
+		var ops []string
+		for _, step := range w.Steps {
+			ops = append(ops, step.Operation)
+		}
+		desc = "Calls " + strings.Join(ops, ", then ") + "."
+	}
+	return &mcp.Tool{
+		Name:        name,
+		Description: desc,
+		InputSchema: w.inputSchema(),
+		Annotations: workflowAnnotations(name, annotations),
+	}, t, nil
+}
+
+// workflowAnnotations returns the annotations of a workflow's tool, given those of its steps' tools.
+// A workflow is read-only or idempotent only if all its steps are, and destructive if any are.
+func workflowAnnotations(name string, steps []*mcp.ToolAnnotations) *mcp.ToolAnnotations {
+	ann := &mcp.ToolAnnotations{Title: name, ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: boolPtr(true)}
+	for _, step := range steps {
+		if step == nil {
+			return nil
+		}
+		ann.ReadOnlyHint = ann.ReadOnlyHint && step.ReadOnlyHint
+		ann.IdempotentHint = ann.IdempotentHint && step.IdempotentHint
+		// Tools that make changes are destructive unless they say otherwise
+		if !step.ReadOnlyHint && (step.DestructiveHint == nil || *step.DestructiveHint) {
+			ann.DestructiveHint = boolPtr(true)
+		}
+	}
+	if ann.ReadOnlyHint {
+		ann.DestructiveHint = nil
+	} else if ann.DestructiveHint == nil {
+		ann.DestructiveHint = boolPtr(false)
+	}
+	return ann
+}
+
+// call is the handler of a workflow's tool. It calls each step's tool in turn,
+// and returns the result of each step, by name, or the result of the first step that fails.
+func (t *workflowTool) call(ctx context.Context, req *mcp.ServerRequest[*mcp.CallToolParamsFor[map[string]any]]) (*mcp.CallToolResultFor[any], error) {
+	args := preciseArguments(ctx, req.Params.Arguments)
+	session, err := t.runner.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]any)
+	for i, step := range t.workflow.Steps {
+		stepArgs, err := resolveStepArguments(step.Arguments, map[string]any{"arguments": args, "steps": results})
+		if err != nil {
+			return nil, fmt.Errorf("step %q of workflow %s: %w", step.name(), t.name, err)
+		}
+		params := &mcp.CallToolParams{Name: t.steps[i], Arguments: stepArgs}
+		// Steps are attributed to the session of the client that called the workflow
+		if id := sessionID(req); id != "" {
+			params.Meta = mcp.Meta{sessionMetaKey: id}
+		}
+		result, err := session.CallTool(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("step %q of workflow %s: %w", step.name(), t.name, err)
+		}
+		if result.IsError {
+			return failedStepResult(t, i, result, results), nil
+		}
+		results[step.name()] = stepResultValue(result)
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResultFor[any]{
+		Content:           []mcp.Content{&mcp.TextContent{Text: string(data)}},
+		StructuredContent: results,
+	}, nil
+}
+
+// failedStepResult returns the result of a workflow that stopped at a failed step:
+// the step's own result, along with the results of the steps before it, whose effects weren't undone.
+func failedStepResult(t *workflowTool, i int, result *mcp.CallToolResult, results map[string]any) *mcp.CallToolResultFor[any] {
+	step := t.workflow.Steps[i]
+	content := []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Workflow %s stopped at step %d of %d, %q (%s), which failed:", t.name, i+1, len(t.steps), step.name(), t.steps[i])}}
+	content = append(content, result.Content...)
+	if len(results) > 0 {
+		if data, err := json.Marshal(results); err == nil {
+			content = append(content, &mcp.TextContent{Text: "The steps before it succeeded, and their effects weren't undone:\n" + string(data)})
+		}
+	}
+	return &mcp.CallToolResultFor[any]{Content: content, IsError: true}
+}
+
+// stepResultValue returns the value of a step's result that later steps select from:
+// its structured content, or else its text, parsed as JSON if it is.
+func stepResultValue(result *mcp.CallToolResult) any {
+	if result.StructuredContent != nil {
+		return result.StructuredContent
+	}
+	for _, content := range result.Content {
+		if text, ok := content.(*mcp.TextContent); ok {
+			var v any
+			if json.Unmarshal([]byte(text.Text), &v) == nil {
+				return v
+			}
+			return text.Text
+		}
+	}
+	return nil
+}
+
+// resolveStepArguments returns a step's arguments with their templates replaced by the values they select from data.
+func resolveStepArguments(arguments map[string]any, data map[string]any) (map[string]any, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(encoded, &doc); err != nil {
+		return nil, err
+	}
+	resolved := make(map[string]any, len(arguments))
+	for name, value := range arguments {
+		v, ok, err := resolveTemplates(value, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", name, err)
+		}
+		if ok {
+			resolved[name] = v
+		}
+	}
+	return resolved, nil
+}
+
+// resolveTemplates replaces the templates in a value, and reports whether they all selected something.
+// Object fields and array items whose templates select nothing are left out.
+func resolveTemplates(value any, doc *yaml.Node) (any, bool, error) {
+	switch v := value.(type) {
+	case string:
+		return resolveString(v, doc)
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+		for name, field := range v {
+			r, ok, err := resolveTemplates(field, doc)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				resolved[name] = r
+			}
+		}
+		return resolved, true, nil
+	case []any:
+		resolved := make([]any, 0, len(v))
+		for _, item := range v {
+			r, ok, err := resolveTemplates(item, doc)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				resolved = append(resolved, r)
+			}
+		}
+		return resolved, true, nil
+	}
+	return value, true, nil
+}
+
+// resolveString replaces the templates in a string. A string that's only a template is replaced by the value it selects:
+// a single value, or an array of them if it selects more than one. Otherwise, selected values are written into the string,
+// with scalars as they are and other values as JSON.
+func resolveString(s string, doc *yaml.Node) (any, bool, error) {
+	matches := workflowTemplatePattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, true, nil
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		nodes, err := selectNodes(s[matches[0][2]:matches[0][3]], doc)
+		if err != nil || len(nodes) == 0 {
+			return nil, false, err
+		}
+		values := make([]any, len(nodes))
+		for i, node := range nodes {
+			if err := node.Decode(&values[i]); err != nil {
+				return nil, false, err
+			}
+		}
+		if len(values) == 1 {
+			return values[0], true, nil
+		}
+		return values, true, nil
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+		nodes, err := selectNodes(s[m[2]:m[3]], doc)
+		if err != nil || len(nodes) == 0 {
+			return nil, false, err
+		}
+		for i, node := range nodes {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			if node.Kind == yaml.ScalarNode {
+				b.WriteString(node.Value)
+				continue
+			}
+			var v any
+			if err := node.Decode(&v); err != nil {
+				return nil, false, err
+			}
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, false, err
+			}
+			b.Write(data)
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String(), true, nil
+}
+
+// selectNodes returns the nodes a template's JSONPath expression selects, leaving out nulls.
+func selectNodes(expr string, doc *yaml.Node) ([]*yaml.Node, error) {
+	path, err := jsonpath.NewPath(expr, config.WithPropertyNameExtension())
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", expr, err)
+	}
+	return slices.DeleteFunc(path.Query(doc), func(n *yaml.Node) bool { return n.Tag == "!!null" }), nil
+}
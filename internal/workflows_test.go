@@ -0,0 +1,172 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterToolsWorkflows(t *testing.T) {
+	var subscriptions []map[string]any
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/customers":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"id": "cus_1", "email": %q}`, body["email"])
+		case "/subscriptions":
+			if body["price"] == "unknown" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error": "no such price"}`)
+				return
+			}
+			subscriptions = append(subscriptions, body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id": "sub_1", "status": "active"}`)
+		}
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Billing API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {
+    "/customers": {"post": {
+      "operationId": "createCustomer",
+      "requestBody": {"required": true, "content": {"application/json": {"schema": {
+        "type": "object", "required": ["email"], "properties": {"email": {"type": "string"}}
+      }}}},
+      "responses": {"201": {"description": "Created"}}
+    }},
+    "/subscriptions": {"post": {
+      "operationId": "createSubscription",
+      "requestBody": {"required": true, "content": {"application/json": {"schema": {
+        "type": "object", "required": ["customer", "price"],
+        "properties": {"customer": {"type": "string"}, "price": {"type": "string"}, "metadata": {"type": "object"}}
+      }}}},
+      "responses": {"201": {"description": "Created"}}
+    }}
+  }
+}`, api.URL)
+
+	c, err := ParseConfig([]byte(`
+workflows:
+  subscribeCustomer:
+    description: Create a customer and subscribe them to a plan
+    arguments:
+      email: {type: string, required: true}
+      plan: {type: string, required: true}
+    steps:
+      - operation: createCustomer
+        name: customer
+        arguments:
+          email: "{{$.arguments.email}}"
+      - operation: createSubscription
+        arguments:
+          customer: "{{$.steps.customer.id}}"
+          price: "{{$.arguments.plan}}"
+          metadata:
+            source: "signup for {{$.steps.customer.email}}"
+            referrer: "{{$.arguments.referrer}}"
+`))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), WithConfig(c)))
+	clientSession := connectTestClient(t, ctx, server)
+
+	tools, err := clientSession.ListTools(ctx, nil)
+	require.NoError(t, err)
+	var workflow *mcp.Tool
+	for _, tool := range tools.Tools {
+		if tool.Name == "subscribeCustomer" {
+			workflow = tool
+		}
+	}
+	require.NotNil(t, workflow)
+	assert.Equal(t, "Create a customer and subscribe them to a plan", workflow.Description)
+	assert.ElementsMatch(t, []string{"email", "plan"}, workflow.InputSchema.Required)
+	require.NotNil(t, workflow.Annotations)
+	assert.False(t, workflow.Annotations.ReadOnlyHint)
+	assert.True(t, *workflow.Annotations.DestructiveHint)
+
+	result, err := clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "subscribeCustomer",
+		Arguments: map[string]any{"email": "ann@example.com", "plan": "price_pro"},
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.JSONEq(t, `{
+  "customer": {"id": "cus_1", "email": "ann@example.com"},
+  "createSubscription": {"id": "sub_1", "status": "active"}
+}`, result.Content[0].(*mcp.TextContent).Text)
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, map[string]any{
+		"customer": "cus_1",
+		"price":    "price_pro",
+		"metadata": map[string]any{"source": "signup for ann@example.com"},
+	}, subscriptions[0], "arguments whose templates select nothing are left out")
+
+	result, err = clientSession.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "subscribeCustomer",
+		Arguments: map[string]any{"email": "bob@example.com", "plan": "unknown"},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	var text string
+	for _, content := range result.Content {
+		text += content.(*mcp.TextContent).Text + "\n"
+	}
+	assert.Contains(t, text, `stopped at step 2 of 2, "createSubscription"`)
+	assert.Contains(t, text, "no such price")
+	assert.Contains(t, text, `"email":"bob@example.com"`, "the results of earlier steps are included")
+}
+
+func TestRegisterToolsWorkflowUnknownOperation(t *testing.T) {
+	c, err := ParseConfig([]byte(`
+workflows:
+  adopt:
+    steps:
+      - operation: adoptPet
+`))
+	require.NoError(t, err)
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+	err = RegisterTools(server, []byte(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": "https://api.example.com"}],
+  "paths": {"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}}
+}`), http.DefaultClient, WithConfig(c))
+	assert.ErrorContains(t, err, `unknown or disabled operation "adoptPet"`)
+}
+
+func TestParseConfigWorkflows(t *testing.T) {
+	for _, tc := range []struct {
+		name, config, err string
+	}{
+		{"no steps", "workflows:\n  adopt: {}\n", "no steps"},
+		{"no operation", "workflows:\n  adopt:\n    steps: [{name: first}]\n", "step 1 has no operation"},
+		{"duplicate names", "workflows:\n  adopt:\n    steps: [{operation: getPet}, {operation: getPet}]\n", `more than one step is named "getPet"`},
+		{"invalid type", "workflows:\n  adopt:\n    arguments: {petId: {type: uuid}}\n    steps: [{operation: getPet}]\n", `invalid type "uuid"`},
+		{"invalid template", "workflows:\n  adopt:\n    steps: [{operation: getPet, arguments: {petId: \"{{$..[}}\"}}]\n", "invalid template"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.config))
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// WithXMLToJSON converts XML responses to JSON, so that models don't have to read XML.
+// Elements become objects keyed by their children's names, with attributes prefixed with "@",
+// and text alongside attributes or children under "#text". Repeated elements become arrays.
+func WithXMLToJSON() RegisterToolsOption {
+	return func(cfg *registerToolsConfig) { cfg.xmlToJSON = true }
+}
+
+// isXMLMediaType reports whether a media type is XML, including structured suffixes like application/atom+xml.
+func isXMLMediaType(mediaType string) bool {
+	mediaType = baseMediaType(mediaType)
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// xmlToJSON converts an XML document to indented JSON, as an object with its root element's name as the only key.
+func xmlToJSON(data []byte) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	// Documents in other encodings are read as is, which keeps ASCII intact
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, errors.New("XML document has no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			value, err := xmlElement(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			return json.MarshalIndent(map[string]any{start.Name.Local: value}, "", "  ")
+		}
+	}
+}
+
+// xmlElement converts the element that starts with start, reading tokens up to its end.
+// Elements with only text become strings, and empty elements become null.
+func xmlElement(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	obj := make(map[string]any)
+	for _, attr := range start.Attr {
+		// Namespace declarations aren't data
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		obj["@"+attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	children := false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			value, err := xmlElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			children = true
+			name := t.Name.Local
+			switch existing := obj[name].(type) {
+			case nil:
+				if _, ok := obj[name]; ok {
+					obj[name] = []any{nil, value}
+				} else {
+					obj[name] = value
+				}
+			case []any:
+				obj[name] = append(existing, value)
+			default:
+				obj[name] = []any{existing, value}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(obj) == 0 && !children {
+				if s == "" {
+					return nil, nil
+				}
+				return s, nil
+			}
+			if s != "" {
+				obj["#text"] = s
+			}
+			return obj, nil
+		}
+	}
+}
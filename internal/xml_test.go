@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMLToJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "attributes and repeated elements",
+			xml:  `<?xml version="1.0"?><pets count="2"><pet id="1">Fido</pet><pet id="2">Rex</pet></pets>`,
+			want: `{"pets": {"@count": "2", "pet": [{"#text": "Fido", "@id": "1"}, {"#text": "Rex", "@id": "2"}]}}`,
+		},
+		{
+			name: "nested elements",
+			xml:  "<pet>\n  <name>Fido</name>\n  <owner><name>Alice</name></owner>\n  <tag/>\n</pet>",
+			want: `{"pet": {"name": "Fido", "owner": {"name": "Alice"}, "tag": null}}`,
+		},
+		{
+			name: "namespaces",
+			xml:  `<a:pets xmlns:a="urn:pets" xmlns="urn:default"><a:pet a:id="1">Fido</a:pet></a:pets>`,
+			want: `{"pets": {"pet": {"#text": "Fido", "@id": "1"}}}`,
+		},
+		{
+			name: "empty repeated elements",
+			xml:  `<list><item/><item>x</item><item/></list>`,
+			want: `{"list": {"item": [null, "x", null]}}`,
+		},
+		{
+			name: "text only",
+			xml:  `<message>hello &amp; welcome</message>`,
+			want: `{"message": "hello & welcome"}`,
+		},
+		{name: "not XML", xml: `{"name": "Fido"}`, wantErr: true},
+		{name: "unclosed", xml: `<pets><pet>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := xmlToJSON([]byte(tt.xml))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestRegisterToolsWithXMLToJSON(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		_, _ = w.Write([]byte(`<pet id="1"><name>Fido</name></pet>`))
+	}))
+	defer api.Close()
+
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {"/pets/1": {"get": {"operationId": "getPet", "responses": {"200": {"description": "OK"}}}}}
+}`, api.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, convert := range []bool{false, true} {
+		t.Run(fmt.Sprint(convert), func(t *testing.T) {
+			var opts []RegisterToolsOption
+			if convert {
+				opts = append(opts, WithXMLToJSON())
+			}
+			server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "dev"}, nil)
+			require.NoError(t, RegisterTools(server, []byte(spec), api.Client(), opts...))
+			session := connectTestClient(t, ctx, server)
+
+			result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "getPet"})
+			require.NoError(t, err)
+			require.False(t, result.IsError)
+			text := result.Content[0].(*mcp.TextContent).Text
+			if convert {
+				assert.JSONEq(t, `{"pet": {"@id": "1", "name": "Fido"}}`, text)
+			} else {
+				assert.Equal(t, `<pet id="1"><name>Fido</name></pet>`, text)
+			}
+		})
+	}
+}
@@ -42,6 +42,8 @@ func TestIntegration(t *testing.T) {
 
 	// Perform MCP handshake (initialize + initialized), then list tools
 	scanner := bufio.NewScanner(stdout)
+	// Parameter schemas carry their enums, so the tools/list response is larger than the scanner's default 64 KiB
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
 	// initialize
 	initReq := map[string]any{
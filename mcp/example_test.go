@@ -0,0 +1,27 @@
+package mcp_test
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/mattt/emcee/mcp"
+)
+
+func ExampleNewServer() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	server, err := mcp.NewServer(ctx,
+		mcp.WithSpecURL("https://api.weather.gov/openapi.json"),
+		mcp.WithHeader("User-Agent", "(myweatherapp.com, contact@myweatherapp.com)"),
+		mcp.WithToolPrefix("weather_"),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := server.RunStdio(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,345 @@
+// Package mcp serves the operations of an OpenAPI specification as Model Context Protocol tools.
+// It's the library behind the emcee command, for Go programs that embed the bridge
+// instead of running the binary.
+//
+// Create a Server with the spec and the options for calling its API,
+// then run it over stdio with RunStdio, for several clients at once with Serve,
+// or over any MCP transport with Run:
+//
+//	server, err := mcp.NewServer(ctx,
+//		mcp.WithSpecURL("https://api.weather.gov/openapi.json"),
+//		mcp.WithAuth("Bearer " + token),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	log.Fatal(server.RunStdio(ctx))
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattt/emcee/internal"
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Server is an MCP server whose tools call the operations of an OpenAPI specification.
+type Server struct {
+	server *sdk.Server
+
+	name     string
+	version  string
+	specURL  string
+	specData []byte
+	client   *http.Client
+	logger   *slog.Logger
+	opts     []internal.RegisterToolsOption
+
+	// Extension methods are shared by the sessions of every client
+	batchCaller   *internal.BatchCaller
+	health        *internal.HealthChecker
+	readOnlyTools *internal.ReadOnlyTools
+	elicitor      *internal.Elicitor
+
+	healthPath string
+
+	shutdownTimeout  time.Duration
+	maxMessageBytes  int
+	maxPendingWrites int
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server) error
+
+// NewServer returns a server for the spec given by WithSpecURL or WithSpecData.
+// A spec URL is downloaded with ctx before NewServer returns, so that errors in the spec are reported up front.
+// Requests to the API are retried up to 3 times and time out after 60 seconds, unless WithClient is given.
+func NewServer(ctx context.Context, opts ...ServerOption) (*Server, error) {
+	s := &Server{
+		name:             "emcee",
+		version:          "dev",
+		shutdownTimeout:  internal.DefaultShutdownTimeout,
+		maxMessageBytes:  internal.DefaultMaxMessageBytes,
+		maxPendingWrites: internal.DefaultMaxPendingWrites,
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if s.logger == nil {
+		s.logger = slog.New(slog.DiscardHandler)
+	}
+	if s.client == nil {
+		client, err := internal.RetryableClient(internal.RetryableClientOptions{Retries: 3, Timeout: 60 * time.Second})
+		if err != nil {
+			return nil, fmt.Errorf("error creating client: %w", err)
+		}
+		s.client = client
+	}
+
+	switch {
+	case s.specURL != "" && s.specData != nil:
+		return nil, errors.New("a spec URL and spec data can't both be given")
+	case s.specURL != "":
+		data, err := s.downloadSpec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.specData = data
+	case s.specData == nil:
+		return nil, errors.New("no spec given (use WithSpecURL or WithSpecData)")
+	}
+
+	// Argument values are completed from the enums and examples of the spec
+	catalog := internal.NewEnumCatalog(0)
+	s.server = sdk.NewServer(&sdk.Implementation{Name: s.name, Version: s.version}, &sdk.ServerOptions{CompletionHandler: catalog.Complete})
+	// The APIs behind the tools are probed by health/check requests
+	s.health = internal.NewHealthChecker(s.healthPath)
+	regOpts := append(s.opts, internal.WithLogger(s.logger), internal.WithEnumCatalog(catalog), internal.WithHealthChecker(s.health))
+	if err := internal.RegisterTools(s.server, s.specData, s.client, regOpts...); err != nil {
+		return nil, fmt.Errorf("error registering tools: %w", err)
+	}
+	internal.NegotiateProtocol(s.server)
+	s.batchCaller = internal.NewBatchCaller(s.server)
+	s.readOnlyTools = internal.NewReadOnlyTools(s.server)
+	return s, nil
+}
+
+// downloadSpec downloads the spec from its URL, using the server's client.
+func (s *Server) downloadSpec(ctx context.Context) ([]byte, error) {
+	s.logger.Info("reading spec from URL", "url", s.specURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.specURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading spec: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("error downloading spec: %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading spec from %s: %w", s.specURL, err)
+	}
+	return data, nil
+}
+
+// WithSpecURL downloads the spec from an HTTP(S) URL.
+func WithSpecURL(specURL string) ServerOption {
+	return func(s *Server) error {
+		if !strings.HasPrefix(specURL, "http://") && !strings.HasPrefix(specURL, "https://") {
+			return fmt.Errorf("invalid spec URL %q: expected an http or https URL", specURL)
+		}
+		s.specURL = specURL
+		return nil
+	}
+}
+
+// WithSpecData uses a spec that's already been read, in JSON or YAML.
+func WithSpecData(data []byte) ServerOption {
+	return func(s *Server) error {
+		if len(data) == 0 {
+			return errors.New("spec data is empty")
+		}
+		s.specData = data
+		return nil
+	}
+}
+
+// WithClient makes requests to the API, and downloads the spec, with client.
+func WithClient(client *http.Client) ServerOption {
+	return func(s *Server) error {
+		if client == nil {
+			return errors.New("client is nil")
+		}
+		s.client = client
+		return nil
+	}
+}
+
+// WithAuth sets the Authorization header of every request to the API to value, like "Bearer token".
+func WithAuth(value string) ServerOption {
+	return func(s *Server) error {
+		s.opts = append(s.opts, internal.WithAuthProvider(internal.HeaderAuth{Name: "Authorization", Value: value}))
+		return nil
+	}
+}
+
+// WithHeader adds a header to every request to the API. Headers with the same name accumulate.
+func WithHeader(name, value string) ServerOption {
+	return func(s *Server) error {
+		s.opts = append(s.opts, internal.WithHeader(name, value))
+		return nil
+	}
+}
+
+// WithRequestIDHeader sends the correlation ID of each tool call to the API in the named header,
+// like X-Request-Id, so that the API's logs can be matched with the server's.
+// By default, the ID is only logged.
+func WithRequestIDHeader(name string) ServerOption {
+	return func(s *Server) error {
+		s.opts = append(s.opts, internal.WithRequestIDHeader(name))
+		return nil
+	}
+}
+
+// WithRequestHook runs hook on every request to the API, after credentials and headers are added,
+// so that it can change requests as they're sent, for example to sign them or to add a tenant header.
+// Returning an error fails the tool call without sending the request. Hooks run in the order they're given.
+func WithRequestHook(hook func(ctx context.Context, req *http.Request) error) ServerOption {
+	return func(s *Server) error {
+		s.opts = append(s.opts, internal.WithRequestHook(hook))
+		return nil
+	}
+}
+
+// WithResponseHook runs hook on every response from the API before the tool reads it,
+// so that it can inspect or change the response, for example to verify a signature.
+// Returning an error fails the tool call. Hooks run in the order they're given.
+func WithResponseHook(hook func(ctx context.Context, resp *http.Response) error) ServerOption {
+	return func(s *Server) error {
+		s.opts = append(s.opts, internal.WithResponseHook(hook))
+		return nil
+	}
+}
+
+// WithToolPrefix prepends prefix to the name of every tool, like "weather_".
+func WithToolPrefix(prefix string) ServerOption {
+	return func(s *Server) error {
+		s.opts = append(s.opts, internal.WithToolPrefix(prefix))
+		return nil
+	}
+}
+
+// WithToolNameFormat names tools with a template, like "{tag}_{operationId}" or "{method}_{path}",
+// instead of by operation ID. The placeholders are {operationId}, {tag}, {method}, and {path}.
+func WithToolNameFormat(format string) ServerOption {
+	return func(s *Server) error {
+		f, err := internal.ParseToolNameFormat(format)
+		if err != nil {
+			return err
+		}
+		s.opts = append(s.opts, internal.WithToolNameFormat(f))
+		return nil
+	}
+}
+
+// WithElicitation asks clients that support elicitation for the required arguments a tool call is missing,
+// instead of failing the call. Clients are only asked when they're served with RunStdio or Serve.
+func WithElicitation() ServerOption {
+	return func(s *Server) error {
+		s.elicitor = internal.NewElicitor()
+		s.opts = append(s.opts, internal.WithElicitation(s.elicitor))
+		return nil
+	}
+}
+
+// WithShutdownTimeout sets how long requests in flight when RunStdio or Serve is canceled
+// are given to finish before they're canceled too. The default is 10 seconds.
+func WithShutdownTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) error {
+		if timeout <= 0 {
+			return fmt.Errorf("invalid shutdown timeout %v: expected a positive duration", timeout)
+		}
+		s.shutdownTimeout = timeout
+		return nil
+	}
+}
+
+// WithMessageLimits limits the size of the messages read from clients to maxBytes,
+// answering larger messages with an error, and the number of messages waiting to be written to a client to maxPending,
+// holding up the requests answering a client that isn't reading its output.
+// Either limit may be 0 for no limit. The defaults are 16 MiB and 64 messages.
+func WithMessageLimits(maxBytes, maxPending int) ServerOption {
+	return func(s *Server) error {
+		if maxBytes < 0 || maxPending < 0 {
+			return fmt.Errorf("invalid message limits %d and %d: expected 0 or more", maxBytes, maxPending)
+		}
+		s.maxMessageBytes = maxBytes
+		s.maxPendingWrites = maxPending
+		return nil
+	}
+}
+
+// WithHealthPath sets the path of the API's health endpoint, relative to its server URL,
+// which health/check requests probe. The default is "/health".
+func WithHealthPath(path string) ServerOption {
+	return func(s *Server) error {
+		s.healthPath = path
+		return nil
+	}
+}
+
+// WithLogger logs to logger. By default, nothing is logged.
+func WithLogger(logger *slog.Logger) ServerOption {
+	return func(s *Server) error {
+		s.logger = logger
+		return nil
+	}
+}
+
+// WithImplementation sets the name and version the server reports to clients.
+// By default, they're "emcee" and "dev".
+func WithImplementation(name, version string) ServerOption {
+	return func(s *Server) error {
+		s.name, s.version = name, version
+		return nil
+	}
+}
+
+// MCPServer returns the underlying server from the MCP Go SDK,
+// for adding tools, prompts, or resources of your own alongside the spec's.
+func (s *Server) MCPServer() *sdk.Server {
+	return s.server
+}
+
+// Run serves clients over a transport until ctx is done or the client disconnects.
+func (s *Server) Run(ctx context.Context, transport sdk.Transport) error {
+	return s.server.Run(ctx, transport)
+}
+
+// RunStdio serves a client over the process's standard input and output, as the emcee command does,
+// until ctx is done or the client disconnects.
+// Besides MCP, the transport answers tools/callBatch requests for calling several tools at once
+// and health/check requests for probing the API's health endpoint,
+// and answers retransmitted calls to read-only tools without calling the API again.
+// When ctx is done, the server stops accepting requests, and returns once those in flight finish,
+// canceling them if they take longer than the shutdown timeout (see WithShutdownTimeout).
+func (s *Server) RunStdio(ctx context.Context) error {
+	stdio := internal.NewStdio()
+	s.configure(stdio)
+	return internal.Run(ctx, s.server, stdio.Transport(), s.shutdownTimeout)
+}
+
+// Serve serves every client that connects to ln, each in a session of its own,
+// until ctx is done. Clients exchange newline-delimited JSON-RPC messages as over stdio,
+// with the same extensions as RunStdio. State kept for a client, like confirmation tokens, isn't shared with other clients.
+// When ctx is done, the listener is closed, and every open session is closed once its requests in flight finish,
+// as by RunStdio.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	return internal.Serve(ctx, s.server, ln, s.configure, s.shutdownTimeout)
+}
+
+// configure adds the extension methods, replays, and elicitation of the emcee command to a client's streams.
+func (s *Server) configure(stdio *internal.Stdio) {
+	stdio.Methods = map[string]internal.MethodHandler{
+		internal.CallBatchMethod: s.batchCaller.Handle,
+		internal.HealthMethod:    s.health.Handle,
+	}
+	stdio.Replayable = s.readOnlyTools.Replayable
+	stdio.Elicitor = s.elicitor
+	stdio.MaxMessageBytes = s.maxMessageBytes
+	stdio.MaxPendingWrites = s.maxPendingWrites
+}
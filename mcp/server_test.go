@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdk "github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServer(t *testing.T) {
+	var authorization, tenant string
+	mux := http.NewServeMux()
+	api := httptest.NewServer(mux)
+	defer api.Close()
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}}
+}`, api.URL)
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(spec))
+	})
+	mux.HandleFunc("/pets", func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		tenant = r.Header.Get("X-Tenant")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"name": "Fido"}]`))
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server, err := NewServer(ctx,
+		WithSpecURL(api.URL+"/openapi.json"),
+		WithAuth("Bearer token"),
+		WithToolPrefix("pets_"),
+		WithImplementation("pets", "1.0.0"),
+		WithRequestHook(func(ctx context.Context, req *http.Request) error {
+			req.Header.Set("X-Tenant", "acme")
+			return nil
+		}),
+		WithResponseHook(func(ctx context.Context, resp *http.Response) error {
+			resp.Header.Set("Content-Type", "application/json")
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	clientTransport, serverTransport := sdk.NewInMemoryTransports()
+	go func() { _ = server.Run(ctx, serverTransport) }()
+	client := sdk.NewClient(&sdk.Implementation{Name: "test", Version: "dev"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	require.NoError(t, err)
+	defer session.Close()
+
+	result, err := session.CallTool(ctx, &sdk.CallToolParams{Name: "pets_listPets"})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+	assert.Contains(t, result.Content[0].(*sdk.TextContent).Text, "Fido")
+	assert.Equal(t, "Bearer token", authorization)
+	assert.Equal(t, "acme", tenant)
+
+	_, err = NewServer(ctx, WithSpecData([]byte(spec)))
+	assert.NoError(t, err)
+	_, err = NewServer(ctx)
+	assert.ErrorContains(t, err, "no spec given")
+	_, err = NewServer(ctx, WithSpecURL("./openapi.json"))
+	assert.ErrorContains(t, err, "invalid spec URL")
+	_, err = NewServer(ctx, WithSpecURL(api.URL+"/missing.json"))
+	assert.ErrorContains(t, err, "404")
+	_, err = NewServer(ctx, WithSpecData([]byte(spec)), WithToolNameFormat("{tag}"))
+	assert.ErrorContains(t, err, "invalid tool name format")
+
+	canceled, cancelSpec := context.WithCancel(ctx)
+	cancelSpec()
+	_, err = NewServer(canceled, WithSpecURL(api.URL+"/openapi.json"))
+	assert.ErrorIs(t, err, context.Canceled, "the spec is downloaded with the given context")
+}
+
+func TestServe(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"name": "Fido"}]`))
+	}))
+	defer api.Close()
+	spec := fmt.Sprintf(`{
+  "openapi": "3.1.0",
+  "info": {"title": "Pet API", "version": "1.0.0"},
+  "servers": [{"url": %q}],
+  "paths": {"/pets": {"get": {"operationId": "listPets", "responses": {"200": {"description": "OK"}}}}}
+}`, api.URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	server, err := NewServer(ctx, WithSpecData([]byte(spec)))
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, ln) }()
+
+	for range 2 {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"dev"}}}` + "\n"))
+		require.NoError(t, err)
+		r := bufio.NewReader(conn)
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Contains(t, line, `"serverInfo":{"name":"emcee"`)
+		assert.Contains(t, line, `"health/check"`, "extension methods are advertised")
+
+		_, err = conn.Write([]byte(`{"jsonrpc":"2.0","id":2,"method":"health/check"}` + "\n"))
+		require.NoError(t, err)
+		line, err = r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Contains(t, line, `"healthy":true`)
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-served, context.Canceled)
+}